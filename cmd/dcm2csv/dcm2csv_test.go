@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestReport(t *testing.T) {
+	columns := splitColumns("PatientID, (0008,0060),ImageType")
+	if !reflect.DeepEqual(columns, []string{"PatientID", "(0008,0060)", "ImageType"}) {
+		t.Errorf("Wrong columns: %q", columns)
+	}
+
+	dir := t.TempDir()
+	for i, modality := range []string{"CT", "MR"} {
+		df := &dcmdump.DicomFile{}
+		df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+		df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY"})
+		df.SetElement("00080060", "CS", modality)
+		df.SetElement("00100020", "LO", "P1")
+		f, err := os.Create(filepath.Join(dir, string(rune('a'+i))+".dcm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := options{columns: columns, tsv: true, join: "|"}
+	o.filter, _ = dcmdump.ParseFilter(`Modality=="CT"`)
+	var buf bytes.Buffer
+	err := run(&buf, []string{dir}, o, func(path string, err error) { t.Errorf("%s: %s", path, err) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "Path\tPatientID\t(0008,0060)\tImageType\n" + filepath.Join(dir, "a.dcm") + "\tP1\tCT\tORIGINAL|PRIMARY\n"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	o.parquet = true
+	err = run(&buf, []string{dir}, o, func(path string, err error) { t.Errorf("%s: %s", path, err) })
+	if err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("PAR1")) || !bytes.HasSuffix(buf.Bytes(), []byte("PAR1")) {
+		t.Errorf("Wrong Parquet output, %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package main is a dcm2csv command writing a table of selected elements of
+// DICOM files as CSV, TSV or Apache Parquet, a row per file and a column per
+// tag path.
+//
+//	dcm2csv [options] <dicom-file-or-dir>...
+//
+// Columns are tag paths, see dcmdump.ParseSelector, separated by commas.
+// Directories are walked recursively, files that aren't DICOM are skipped:
+//
+//	dcm2csv -tags PatientID,StudyDate,Modality,RequestedProcedureCodeSequence[0].CodeValue -filter 'Modality=="CT"' archive/ > ct.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `usage: dcm2csv [options] <dicom-file-or-dir>...
+
+Write a row per DICOM file with the values of the given tags as CSV, TSV or
+Parquet.
+
+options:
+`
+
+type options struct {
+	columns  []string
+	tsv      bool
+	parquet  bool
+	join     string
+	noHeader bool
+	filter   *dcmdump.Filter
+}
+
+func main() {
+	o := options{}
+	var tags, filter string
+	fs := flag.NewFlagSet("dcm2csv", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&tags, "tags", "", "columns as tag paths separated by commas, as in PatientID,(0008,0020)")
+	fs.BoolVar(&o.tsv, "tsv", false, "separate columns with tabs")
+	fs.BoolVar(&o.parquet, "parquet", false, "write Apache Parquet, typed by the VR of each tag")
+	fs.StringVar(&o.join, "join", `\`, "separator of multiple values, Parquet uses repeated columns")
+	fs.BoolVar(&o.noHeader, "no-header", false, "skip the header row")
+	fs.StringVar(&filter, "filter", "", `only include files matching an expression, as in Modality=="CT"`)
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 || tags == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	o.columns = splitColumns(tags)
+	var err error
+	if filter != "" {
+		o.filter, err = dcmdump.ParseFilter(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "dcm2csv: %s: %s\n", filter, err)
+			os.Exit(2)
+		}
+	}
+	status := 0
+	err = run(os.Stdout, fs.Args(), o, func(path string, err error) {
+		fmt.Fprintf(os.Stderr, "E: %s: %s\n", path, err)
+		status = 1
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dcm2csv: %s\n", err)
+		os.Exit(2)
+	}
+	os.Exit(status)
+}
+
+// splitColumns splits a list of tag paths at the commas outside of
+// (gggg,eeee) tags.
+func splitColumns(s string) []string {
+	columns := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				columns = append(columns, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(columns, strings.TrimSpace(s[start:]))
+}
+
+// run writes the report of the files and trees at paths to w.
+func run(w io.Writer, paths []string, o options, failed func(path string, err error)) error {
+	if o.parquet {
+		return runParquet(w, paths, o, failed)
+	}
+	r, err := dcmdump.NewReport(w, o.columns)
+	if err != nil {
+		return err
+	}
+	if o.tsv {
+		r.Comma = '\t'
+	}
+	r.Join = o.join
+	r.NoHeader = o.noHeader
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			failed(path, err)
+			continue
+		}
+		err = r.WriteDir(path, o.filter, failed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runParquet(w io.Writer, paths []string, o options, failed func(path string, err error)) error {
+	p, err := dcmdump.NewParquetWriter(w, o.columns)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			failed(path, err)
+			continue
+		}
+		err = p.WriteDir(path, o.filter, failed)
+		if err != nil {
+			return err
+		}
+	}
+	return p.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestExport(t *testing.T) {
+	frames, err := parseFrames("1,3-4")
+	if err != nil || !reflect.DeepEqual(frames, []int{1, 3, 4}) {
+		t.Errorf("Wrong frames: %v, %v", frames, err)
+	}
+	for _, s := range []string{"0", "3-1", "a"} {
+		if _, err := parseFrames(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+	w, err := parseWindow("", "lung")
+	if err != nil || *w != dcmdump.WindowPresets["lung"] {
+		t.Errorf("Wrong preset: %v, %v", w, err)
+	}
+	if _, err = parseWindow("40,400", "lung"); err == nil {
+		t.Errorf("Expected error combining -window and -preset")
+	}
+
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00080016", "UI", "1.2.840.10008.5.1.4.1.1.7")
+	df.SetElement("00080018", "UI", "1.2.3")
+	df.SetElement("00100020", "LO", "P1")
+	df.SetElement("00280002", "US", 1)
+	df.SetElement("00280004", "CS", "MONOCHROME2")
+	df.SetElement("00280008", "IS", 2)
+	df.SetElement("00280010", "US", 1)
+	df.SetElement("00280011", "US", 2)
+	df.SetElement("00280100", "US", 8)
+	df.SetElement("7FE00010", "OB", []byte{0, 1, 2, 3})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in", "file.dcm")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = df.Write(f, ts.ExplicitVRLittleEndian)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := options{format: dcmdump.FormatJPEG, outDir: filepath.Join(dir, "out"), name: "{PatientID}/{frame}.{ext}"}
+	err = export(path, o)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, name := range []string{"1.jpg", "2.jpg"} {
+		if _, err := os.Stat(filepath.Join(dir, "out", "P1", name)); err != nil {
+			t.Errorf("Missing image: %s", err)
+		}
+	}
+	o.frames = []int{3}
+	if err = export(path, o); err == nil {
+		t.Errorf("Expected frame error")
+	}
+}
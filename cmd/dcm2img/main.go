@@ -0,0 +1,226 @@
+// Package main is a dcm2img command exporting the frames of DICOM files to
+// PNG, JPEG or TIFF images.
+//
+//	dcm2img [options] <dicom-file-or-dir>...
+//
+// Directories are walked recursively, files that aren't DICOM are skipped.
+// Output names are templates of tag keywords, see
+// dcmdump.DicomFile.ExpandTemplate, with {frame} the frame number starting at
+// 1 and {ext} the extension of the format:
+//
+//	dcm2img -preset lung -od out -name "{PatientID}/{SeriesNumber}/{InstanceNumber}-{frame}.{ext}" study/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `usage: dcm2img [options] <dicom-file-or-dir>...
+
+Export DICOM frames to PNG, JPEG or TIFF images.
+
+options:
+`
+
+type options struct {
+	format  string
+	frames  []int
+	window  *dcmdump.Window
+	quality int
+	stored  bool
+	outDir  string
+	name    string
+}
+
+var extensions = map[string]string{
+	dcmdump.FormatPNG:  "png",
+	dcmdump.FormatJPEG: "jpg",
+	dcmdump.FormatTIFF: "tif",
+}
+
+func main() {
+	o := options{}
+	var frames, window, preset string
+	fs := flag.NewFlagSet("dcm2img", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.format, "f", dcmdump.FormatPNG, "image format: png, jpeg or tiff")
+	fs.StringVar(&frames, "frames", "", "frames to export starting at 1, as in 1,3-5, all when not set")
+	fs.StringVar(&window, "window", "", "window center and width, as in 40,400")
+	presets := []string{}
+	for name := range dcmdump.WindowPresets {
+		presets = append(presets, name)
+	}
+	sort.Strings(presets)
+	fs.StringVar(&preset, "preset", "", "window preset: "+strings.Join(presets, ", "))
+	fs.IntVar(&o.quality, "quality", 0, "JPEG quality from 1 to 100")
+	fs.BoolVar(&o.stored, "stored", false, "write the stored values unchanged as 16 bit TIFF")
+	fs.StringVar(&o.outDir, "od", ".", "output directory")
+	fs.StringVar(&o.name, "name", "{SOPInstanceUID}-{frame}.{ext}", "output file name template")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var err error
+	o.frames, err = parseFrames(frames)
+	if err == nil {
+		o.window, err = parseWindow(window, preset)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dcm2img: %s\n", err)
+		os.Exit(2)
+	}
+	status := 0
+	for _, arg := range fs.Args() {
+		err := filepath.Walk(arg, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			err = export(path, o)
+			if err == dcmdump.ErrNotDICM && path != arg {
+				return nil
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "E: %s: %s\n", path, err)
+				status = 1
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "E: %s\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+// parseFrames reads a list of frames and ranges starting at 1.
+func parseFrames(s string) ([]int, error) {
+	frames := []int{}
+	if s == "" {
+		return frames, nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		from, to := r, r
+		if i := strings.Index(r, "-"); i >= 0 {
+			from, to = r[:i], r[i+1:]
+		}
+		a, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame '%s'", r)
+		}
+		b, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil || a < 1 || b < a {
+			return nil, fmt.Errorf("invalid frame '%s'", r)
+		}
+		for f := a; f <= b; f++ {
+			frames = append(frames, f)
+		}
+	}
+	return frames, nil
+}
+
+// parseWindow reads a center,width window or a preset name, nil when neither
+// is given.
+func parseWindow(window, preset string) (*dcmdump.Window, error) {
+	switch {
+	case window != "" && preset != "":
+		return nil, fmt.Errorf("-window and -preset can't be combined")
+	case preset != "":
+		w, ok := dcmdump.WindowPresets[preset]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset '%s'", preset)
+		}
+		return &w, nil
+	case window != "":
+		values := strings.Split(window, ",")
+		if len(values) != 2 {
+			return nil, fmt.Errorf("invalid window '%s', use center,width", window)
+		}
+		c, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window '%s', use center,width", window)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(values[1]), 64)
+		if err != nil || w < 1 {
+			return nil, fmt.Errorf("invalid window '%s', use center,width", window)
+		}
+		return &dcmdump.Window{Center: c, Width: w}, nil
+	}
+	return nil, nil
+}
+
+// export writes the selected frames of the file at path.
+func export(path string, o options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	df, err := dcmdump.ParseFile(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return err
+	}
+	frames := o.frames
+	if len(frames) == 0 {
+		for i := 1; i <= pi.NumberOfFrames; i++ {
+			frames = append(frames, i)
+		}
+	}
+	eo := dcmdump.ExportOptions{
+		Format:  o.format,
+		Render:  dcmdump.RenderOptions{Window: o.window},
+		Quality: o.quality,
+		Stored:  o.stored,
+	}
+	for _, frame := range frames {
+		if frame > pi.NumberOfFrames {
+			return fmt.Errorf("frame %d: %w", frame, dcmdump.ErrFrameIndex)
+		}
+		name, err := df.ExpandTemplate(o.name, map[string]string{
+			"frame": strconv.Itoa(frame),
+			"ext":   extensions[o.format],
+		})
+		if err != nil {
+			return err
+		}
+		out, err := dcmdump.JoinTemplate(o.outDir, name)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(out), 0755)
+		if err != nil {
+			return err
+		}
+		w, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		err = df.Export(w, frame-1, eo)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func TestConvert(t *testing.T) {
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("7FE00010", "OW", []byte{1, 2, 3, 4})
+
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	err := convert(&buf, df, options{blkDir: dir, blkPrefix: "blk", blkSize: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var ds map[string]map[string]interface{}
+	err = json.Unmarshal(buf.Bytes(), &ds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	u, err := url.Parse(ds["7FE00010"]["BulkDataURI"].(string))
+	if err != nil || u.Scheme != "file" {
+		t.Fatalf("Wrong BulkDataURI: %v", ds["7FE00010"])
+	}
+	b, err := ioutil.ReadFile(u.Path)
+	if err != nil || !bytes.Equal(b, []byte{1, 2, 3, 4}) {
+		t.Errorf("Wrong bulk data file: %v, %v", b, err)
+	}
+
+	buf.Reset()
+	err = convert(&buf, df, options{noBulkData: true, blkSize: 4})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if buf.String() != `{"00100010":{"vr":"PN","Value":[{"Alphabetic":"DOE^JOHN"}]}}`+"\n" {
+		t.Errorf("Bulk data not omitted: %s", buf.String())
+	}
+}
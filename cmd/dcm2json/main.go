@@ -0,0 +1,165 @@
+// Package main is a dcm2json command converting DICOM files to the DICOM JSON
+// model, with the options of the dcm4che dcm2json tool.
+//
+//	dcm2json [options] <dicom-file>
+//
+// Binary values are inlined unless extracted to sidecar files with
+// --blk-file-dir, their BulkDataURI is then a file URI read back by json2dcm.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `usage: dcm2json [options] <dicom-file>
+
+Convert a DICOM file to the DICOM JSON model.
+
+options:
+`
+
+type options struct {
+	indent     bool
+	noBulkData bool
+	blkDir     string
+	blkPrefix  string
+	blkSuffix  string
+	blkSize    int
+	out        string
+	convertUN  bool
+}
+
+// binaryVRs hold the values that can be extracted as bulk data.
+var binaryVRs = map[string]bool{"OB": true, "OD": true, "OF": true, "OL": true, "OV": true, "OW": true, "UN": true}
+
+func main() {
+	o := options{}
+	fs := flag.NewFlagSet("dcm2json", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	for _, name := range []string{"I", "indent"} {
+		fs.BoolVar(&o.indent, name, false, "use additional whitespace in JSON output")
+	}
+	for _, name := range []string{"B", "no-bulkdata"} {
+		fs.BoolVar(&o.noBulkData, name, false, "do not include bulk data in JSON output")
+	}
+	for _, name := range []string{"d", "blk-file-dir"} {
+		fs.StringVar(&o.blkDir, name, "", "extract bulk data to separate files in the given directory")
+	}
+	fs.StringVar(&o.blkPrefix, "blk-file-prefix", "blk", "prefix of the extracted bulk data file names")
+	fs.StringVar(&o.blkSuffix, "blk-file-suffix", "", "suffix of the extracted bulk data file names")
+	fs.IntVar(&o.blkSize, "blk-size", 1024, "minimum length in bytes of the values extracted or omitted as bulk data")
+	fs.BoolVar(&o.convertUN, "convert-un", false, "read UN elements with their dictionary VR")
+	for _, name := range []string{"o", "out"} {
+		fs.StringVar(&o.out, name, "", "output file, standard output when not set")
+	}
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	err := run(fs.Arg(0), o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dcm2json: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, o options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	df, err := dcmdump.ParseFileWithOptions(f, dcmdump.ParseOptions{ResolveUN: o.convertUN})
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if o.out != "" {
+		out, err := os.Create(o.out)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w = out
+	}
+	return convert(w, df, o)
+}
+
+// convert writes the JSON model of df to w.
+func convert(w io.Writer, df *dcmdump.DicomFile, o options) error {
+	if o.noBulkData {
+		df = &dcmdump.DicomFile{Elements: omitBulkData(df.Elements, o.blkSize)}
+	}
+	var bulkData dcmdump.BulkDataFunc
+	var blkErr error
+	if o.blkDir != "" {
+		n := 0
+		bulkData = func(de *dcmdump.DataElement) string {
+			if len(de.Data) < o.blkSize || blkErr != nil {
+				return ""
+			}
+			n++
+			path, err := filepath.Abs(filepath.Join(o.blkDir, fmt.Sprintf("%s%d%s", o.blkPrefix, n, o.blkSuffix)))
+			if err == nil {
+				err = ioutil.WriteFile(path, de.Data, 0644)
+			}
+			if err != nil {
+				blkErr = err
+				return ""
+			}
+			return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+		}
+	}
+	b, err := df.JSON(bulkData)
+	if err != nil {
+		return err
+	}
+	if blkErr != nil {
+		return blkErr
+	}
+	if o.indent {
+		var buf bytes.Buffer
+		err = json.Indent(&buf, b, "", "  ")
+		if err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// omitBulkData returns the elements without the binary values of at least
+// size bytes, sequence items included.
+func omitBulkData(elements []dcmdump.DataElement, size int) []dcmdump.DataElement {
+	kept := []dcmdump.DataElement{}
+	for _, de := range elements {
+		if binaryVRs[de.VRStr] && int(de.Len) >= size {
+			continue
+		}
+		if len(de.Items) > 0 {
+			items := make([]dcmdump.Item, len(de.Items))
+			for i, item := range de.Items {
+				item.Elements = omitBulkData(item.Elements, size)
+				items[i] = item
+			}
+			de.Items = items
+		}
+		kept = append(kept, de)
+	}
+	return kept
+}
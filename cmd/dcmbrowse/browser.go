@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// Modes of the browser
+const (
+	modeList = iota
+	modeSearch
+	modeHex
+	modeFrame
+)
+
+// ANSI escape sequences
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	reset       = "\x1b[0m"
+	cyan        = "\x1b[36m"
+	yellow      = "\x1b[33m"
+	green       = "\x1b[32m"
+	faint       = "\x1b[2m"
+)
+
+// row - Line of the element list, an element or, when de is nil, item number
+// item of the sequence seq.
+type row struct {
+	de    *dcmdump.DataElement
+	seq   *dcmdump.DataElement
+	item  int
+	depth int
+	// path holds the indexes of the element and item, separated with dots,
+	// down from the top level.
+	path string
+}
+
+// browser - State of the viewer, it is drawn by render and updated by
+// handle with each key.
+type browser struct {
+	df    *dcmdump.DicomFile
+	title string
+	// color, when set, draws with ANSI colors.
+	color bool
+	// sixel, when set, draws the frames as sixel images instead of text.
+	sixel bool
+
+	width, height int
+	mode          int
+	expanded      map[string]bool
+	rows          []row
+	cursor, top   int
+	query, status string
+	// hex holds the dump lines of the hex view, scrolled from hexTop.
+	hex    []string
+	hexTop int
+	frame  int
+}
+
+func newBrowser(df *dcmdump.DicomFile, title string) *browser {
+	b := &browser{df: df, title: title, width: 80, height: 24, expanded: map[string]bool{}}
+	b.update()
+	return b
+}
+
+// walk calls visit with the rows of elements depth first, descending into the
+// sequences and items for which it returns true.
+func walk(elements []dcmdump.DataElement, depth int, prefix string, visit func(r row) bool) {
+	for i := range elements {
+		de := &elements[i]
+		path := prefix + strconv.Itoa(i)
+		if !visit(row{de: de, depth: depth, path: path}) {
+			continue
+		}
+		for j := range de.Items {
+			item := path + "." + strconv.Itoa(j)
+			if visit(row{seq: de, item: j, depth: depth + 1, path: item}) {
+				walk(de.Items[j].Elements, depth+2, item+".", visit)
+			}
+		}
+	}
+}
+
+// update lists the visible rows, those in expanded sequences and items.
+func (b *browser) update() {
+	b.rows = b.rows[:0]
+	walk(b.df.Elements, 0, "", func(r row) bool {
+		b.rows = append(b.rows, r)
+		return b.expanded[r.path]
+	})
+	if b.cursor >= len(b.rows) {
+		b.cursor = len(b.rows) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+// expandable reports whether r has nested rows.
+func (r row) expandable() bool {
+	if r.de == nil {
+		return len(r.seq.Items[r.item].Elements) > 0
+	}
+	return len(r.de.Items) > 0
+}
+
+// body returns the number of lines between the header and the status line.
+func (b *browser) body() int {
+	if b.height < 3 {
+		return 1
+	}
+	return b.height - 2
+}
+
+// handle updates the browser with key and reports whether to quit.
+func (b *browser) handle(key string) bool {
+	if key == "ctrl-c" {
+		return true
+	}
+	b.status = ""
+	switch b.mode {
+	case modeSearch:
+		b.handleSearch(key)
+	case modeHex:
+		b.handleHex(key)
+	case modeFrame:
+		b.handleFrame(key)
+	default:
+		return b.handleList(key)
+	}
+	return false
+}
+
+func (b *browser) handleList(key string) bool {
+	switch key {
+	case "q":
+		return true
+	case "up", "k":
+		b.move(-1)
+	case "down", "j":
+		b.move(1)
+	case "pgup":
+		b.move(-b.body())
+	case "pgdn", " ":
+		b.move(b.body())
+	case "home", "g":
+		b.move(-len(b.rows))
+	case "end", "G":
+		b.move(len(b.rows))
+	case "enter", "right", "l":
+		if len(b.rows) == 0 {
+			break
+		}
+		r := b.rows[b.cursor]
+		if !r.expandable() {
+			break
+		}
+		if key == "enter" && b.expanded[r.path] {
+			delete(b.expanded, r.path)
+		} else {
+			b.expanded[r.path] = true
+		}
+		b.update()
+	case "left", "h":
+		b.collapse()
+	case "/":
+		b.mode, b.query = modeSearch, ""
+	case "n":
+		b.search()
+	case "x":
+		b.openHex()
+	case "f":
+		b.openFrame(0)
+	case "?":
+		b.status = "j/k move  enter expand  h collapse  / search  n next  x hex  f frames  q quit"
+	}
+	return false
+}
+
+func (b *browser) move(n int) {
+	b.cursor += n
+	if b.cursor >= len(b.rows) {
+		b.cursor = len(b.rows) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+}
+
+// collapse closes the row under the cursor, or moves to its parent when it
+// isn't expanded.
+func (b *browser) collapse() {
+	if len(b.rows) == 0 {
+		return
+	}
+	r := b.rows[b.cursor]
+	if b.expanded[r.path] {
+		delete(b.expanded, r.path)
+		b.update()
+		return
+	}
+	i := strings.LastIndex(r.path, ".")
+	if i < 0 {
+		return
+	}
+	for j := b.cursor - 1; j >= 0; j-- {
+		if b.rows[j].path == r.path[:i] {
+			b.cursor = j
+			return
+		}
+	}
+}
+
+func (b *browser) handleSearch(key string) {
+	switch key {
+	case "esc":
+		b.mode = modeList
+	case "enter":
+		b.mode = modeList
+		b.search()
+	case "backspace":
+		if r := []rune(b.query); len(r) > 0 {
+			b.query = string(r[:len(r)-1])
+		}
+	default:
+		if len([]rune(key)) == 1 {
+			b.query += key
+		}
+	}
+}
+
+// matches reports whether the keyword or the tag of de contains the query.
+func matches(de *dcmdump.DataElement, query string) bool {
+	q := strings.ToUpper(strings.NewReplacer("(", "", ")", "", ",", "").Replace(query))
+	return q != "" && (strings.Contains(strings.ToUpper(de.Name), q) || strings.Contains(de.TagStr, q))
+}
+
+// search moves the cursor to the next element matching the query after it,
+// wrapping around, and expands the sequences and items holding it.
+func (b *browser) search() {
+	if b.query == "" {
+		return
+	}
+	current := ""
+	if len(b.rows) > 0 {
+		current = b.rows[b.cursor].path
+	}
+	paths := []string{}
+	after := -1
+	walk(b.df.Elements, 0, "", func(r row) bool {
+		if r.path == current {
+			after = len(paths)
+		}
+		if r.de != nil && matches(r.de, b.query) {
+			paths = append(paths, r.path)
+		}
+		return true
+	})
+	if len(paths) == 0 {
+		b.status = "Not found: " + b.query
+		return
+	}
+	next := paths[0]
+	for i, p := range paths {
+		if i >= after && p != current {
+			next = p
+			break
+		}
+	}
+	parts := strings.Split(next, ".")
+	for i := 1; i < len(parts); i++ {
+		b.expanded[strings.Join(parts[:i], ".")] = true
+	}
+	b.update()
+	for i, r := range b.rows {
+		if r.path == next {
+			b.cursor = i
+		}
+	}
+}
+
+// openHex shows the hex dump of the value under the cursor.
+func (b *browser) openHex() {
+	if len(b.rows) == 0 || b.rows[b.cursor].de == nil {
+		return
+	}
+	de := b.rows[b.cursor].de
+	var buf bytes.Buffer
+	if err := de.Dump(&buf, dcmdump.HexDumpOptions{}); err != nil {
+		b.status = err.Error()
+		return
+	}
+	if len(de.Data) == 0 {
+		b.status = "No value"
+		return
+	}
+	b.hex = strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	b.hexTop = 0
+	b.mode = modeHex
+}
+
+func (b *browser) handleHex(key string) {
+	switch key {
+	case "q", "esc", "x":
+		b.mode = modeList
+	case "up", "k":
+		b.hexTop--
+	case "down", "j":
+		b.hexTop++
+	case "pgup":
+		b.hexTop -= b.body()
+	case "pgdn", " ":
+		b.hexTop += b.body()
+	case "home", "g":
+		b.hexTop = 0
+	case "end", "G":
+		b.hexTop = len(b.hex)
+	}
+	if b.hexTop > len(b.hex)-b.body() {
+		b.hexTop = len(b.hex) - b.body()
+	}
+	if b.hexTop < 0 {
+		b.hexTop = 0
+	}
+}
+
+// openFrame previews frame i, starting at 0.
+func (b *browser) openFrame(i int) {
+	pi, err := b.df.PixelDataInfo()
+	if err != nil {
+		b.status = err.Error()
+		return
+	}
+	if i < 0 || i >= pi.NumberOfFrames {
+		return
+	}
+	b.frame = i
+	b.mode = modeFrame
+}
+
+func (b *browser) handleFrame(key string) {
+	switch key {
+	case "q", "esc", "f":
+		b.mode = modeList
+	case "right", "l", "n", "pgdn", " ":
+		b.openFrame(b.frame + 1)
+	case "left", "h", "p", "pgup":
+		b.openFrame(b.frame - 1)
+	}
+}
+
+// render draws the screen.
+func (b *browser) render(w io.Writer) {
+	fmt.Fprint(w, clearScreen)
+	switch b.mode {
+	case modeHex:
+		de := b.rows[b.cursor].de
+		b.header(w, fmt.Sprintf("%s %s %d bytes", tagText(de.TagStr), name(de), len(de.Data)))
+		for i := b.hexTop; i < len(b.hex) && i < b.hexTop+b.body(); i++ {
+			b.line(w, []segment{{b.hex[i], ""}}, false)
+		}
+	case modeFrame:
+		b.renderFrame(w)
+		return
+	default:
+		b.header(w, fmt.Sprintf("%s, %d elements", b.title, len(b.df.Elements)))
+		if b.cursor < b.top {
+			b.top = b.cursor
+		}
+		if b.cursor >= b.top+b.body() {
+			b.top = b.cursor - b.body() + 1
+		}
+		for i := b.top; i < len(b.rows) && i < b.top+b.body(); i++ {
+			b.line(w, b.segments(b.rows[i]), i == b.cursor)
+		}
+	}
+	fmt.Fprintf(w, "\x1b[%d;1H", b.height)
+	switch {
+	case b.mode == modeSearch:
+		fmt.Fprint(w, "/"+b.query)
+	case b.status != "":
+		fmt.Fprint(w, b.truncate(b.status, b.width))
+	default:
+		fmt.Fprint(w, b.truncate("? help", b.width))
+	}
+}
+
+func (b *browser) renderFrame(w io.Writer) {
+	pi, _ := b.df.PixelDataInfo()
+	b.header(w, fmt.Sprintf("Frame %d/%d, n next, p previous, q back", b.frame+1, pi.NumberOfFrames))
+	img, err := b.df.Render(b.frame, nil)
+	if err != nil {
+		b.line(w, []segment{{err.Error(), ""}}, false)
+		return
+	}
+	if b.sixel {
+		sixel(w, img, b.width*8, b.body()*16)
+		return
+	}
+	for _, l := range asciiFrame(img, b.width, b.body()) {
+		b.line(w, []segment{{l, ""}}, false)
+	}
+}
+
+// segment - Part of a line drawn with a color.
+type segment struct {
+	text, color string
+}
+
+// segments returns the parts of the line of r.
+func (b *browser) segments(r row) []segment {
+	indent := strings.Repeat("  ", r.depth)
+	marker := "  "
+	if r.expandable() {
+		marker = "+ "
+		if b.expanded[r.path] {
+			marker = "- "
+		}
+	}
+	if r.de == nil {
+		item := r.seq.Items[r.item]
+		return []segment{{indent + marker, ""}, {fmt.Sprintf("Item %d", r.item+1), yellow}, {"  " + plural(len(item.Elements), "element"), faint}}
+	}
+	de := r.de
+	vr := de.VRStr
+	if vr == "" || vr == "00" {
+		vr = "UN"
+	}
+	return []segment{
+		{indent + marker, ""},
+		{tagText(de.TagStr) + " ", cyan},
+		{vr + " ", yellow},
+		{name(de) + "  ", ""},
+		{value(de), green},
+	}
+}
+
+// header draws the title line.
+func (b *browser) header(w io.Writer, title string) {
+	b.line(w, []segment{{title, ""}}, true)
+}
+
+// line draws segments cut to the width, selected lines are highlighted and
+// marked with a > in the first column.
+func (b *browser) line(w io.Writer, segments []segment, selected bool) {
+	n := b.width - 1
+	mark := " "
+	if selected {
+		mark = ">"
+	}
+	if b.color && selected {
+		fmt.Fprint(w, reverse)
+	}
+	fmt.Fprint(w, mark)
+	for _, s := range segments {
+		text := b.truncate(s.text, n)
+		n -= len([]rune(text))
+		if b.color && s.color != "" && !selected {
+			fmt.Fprint(w, s.color+text+reset)
+		} else {
+			fmt.Fprint(w, text)
+		}
+	}
+	if b.color && selected {
+		fmt.Fprint(w, strings.Repeat(" ", n)+reset)
+	}
+	fmt.Fprint(w, "\r\n")
+}
+
+// truncate cuts s to n runes.
+func (b *browser) truncate(s string, n int) string {
+	r := []rune(s)
+	if n < 0 {
+		n = 0
+	}
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// tagText formats a GGGGEEEE tag as (gggg,eeee).
+func tagText(tagStr string) string {
+	if len(tagStr) != 8 {
+		return tagStr
+	}
+	return "(" + strings.ToLower(tagStr[:4]) + "," + strings.ToLower(tagStr[4:]) + ")"
+}
+
+func name(de *dcmdump.DataElement) string {
+	if de.Name != "" {
+		return de.Name
+	}
+	return "Unknown"
+}
+
+// value returns the value shown for de, binary values are summarized.
+func value(de *dcmdump.DataElement) string {
+	switch {
+	case de.VRStr == "SQ":
+		return plural(len(de.Items), "item")
+	case de.UndefinedLen:
+		return fmt.Sprintf("(encapsulated, %d bytes)", de.Len)
+	}
+	if err := de.Load(); err != nil {
+		return "(" + err.Error() + ")"
+	}
+	switch de.VRStr {
+	case "OB", "OW", "OF", "OD", "OL", "OV", "UN", "", "00":
+		return fmt.Sprintf("(%d bytes)", len(de.Data))
+	}
+	s := strings.Join(de.Strings(), `\`)
+	return strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`, "\x1b", `\e`).Replace(s)
+}
+
+// plural returns n with word, pluralized with an s.
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func sampleFile(t *testing.T) *dcmdump.DicomFile {
+	uid, err := dcmdump.NewDataElement("0020000E", "UI", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid.PartOfSQ = true
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	df.SetElement("00080060", "CS", "CT")
+	df.SetElement("00081115", "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{uid}}})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00280002", "US", 1)
+	df.SetElement("00280004", "CS", "MONOCHROME2")
+	df.SetElement("00280008", "IS", 2)
+	df.SetElement("00280010", "US", 1)
+	df.SetElement("00280011", "US", 2)
+	df.SetElement("00280100", "US", 8)
+	df.SetElement("7FE00010", "OB", []byte{0, 255, 255, 0})
+	return df
+}
+
+// screen returns the lines drawn by b, without the escape sequences of the
+// top and bottom lines.
+func screen(b *browser) []string {
+	var buf bytes.Buffer
+	b.render(&buf)
+	s := strings.TrimPrefix(buf.String(), clearScreen)
+	s = strings.Replace(s, "\x1b[24;1H", "", 1)
+	return strings.Split(s, "\r\n")
+}
+
+func TestBrowser(t *testing.T) {
+	b := newBrowser(sampleFile(t), "file.dcm")
+	lines := screen(b)
+	if lines[0] != ">file.dcm, 10 elements" {
+		t.Errorf("Wrong header: %q", lines[0])
+	}
+	if lines[1] != ">  (0008,0060) CS Modality  CT" || lines[2] != " + (0008,1115) SQ ReferencedSeriesSequence  1 item" {
+		t.Errorf("Wrong rows:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// Expand the sequence and its item
+	for _, key := range []string{"down", "enter", "down", "l"} {
+		b.handle(key)
+	}
+	lines = screen(b)
+	expected := []string{
+		" - (0008,1115) SQ ReferencedSeriesSequence  1 item",
+		">  - Item 1  1 element",
+		"       (0020,000e) UI SeriesInstanceUID  1.2.3",
+		"   (0010,0010) PN PatientName  DOE^JOHN",
+	}
+	if strings.Join(lines[2:6], "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Wrong expanded rows:\n%s", strings.Join(lines, "\n"))
+	}
+	b.handle("h")
+	b.handle("h")
+	if b.cursor != 1 || len(b.rows) != 11 {
+		t.Errorf("Wrong collapse: %d of %d rows", b.cursor, len(b.rows))
+	}
+
+	// Collapsed matches are expanded
+	b.handle("enter")
+	b.handle("home")
+	for _, key := range []string{"/", "s", "e", "r", "i", "x", "backspace", "e", "s", "i", "enter"} {
+		b.handle(key)
+	}
+	if b.query != "seriesi" || b.rows[b.cursor].de == nil || b.rows[b.cursor].de.TagStr != "0020000E" {
+		t.Errorf("Wrong search %q: %+v", b.query, b.rows[b.cursor])
+	}
+	b.handle("/")
+	for _, key := range []string{"(", "0", "0", "1", "0", ",", "enter"} {
+		b.handle(key)
+	}
+	if b.rows[b.cursor].de.TagStr != "00100010" {
+		t.Errorf("Wrong tag search: %+v", b.rows[b.cursor])
+	}
+	b.handle("/")
+	b.handle("z")
+	b.handle("enter")
+	if lines = screen(b); lines[len(lines)-1] != "Not found: z" {
+		t.Errorf("Wrong status: %q", lines[len(lines)-1])
+	}
+
+	// Hex dump of the pixel data
+	b.handle("end")
+	b.handle("x")
+	lines = screen(b)
+	if b.mode != modeHex || lines[1] != " 00000000  00 ff ff 00                                       |....|" {
+		t.Errorf("Wrong hex view:\n%s", strings.Join(lines, "\n"))
+	}
+	b.handle("q")
+	if b.mode != modeList || b.handle("q") != true {
+		t.Errorf("Expected list mode to quit")
+	}
+}
+
+func TestFrames(t *testing.T) {
+	b := newBrowser(sampleFile(t), "file.dcm")
+	b.width, b.height = 5, 4
+	b.handle("f")
+	if b.mode != modeFrame {
+		t.Fatalf("Wrong mode: %s", b.status)
+	}
+	b.handle("n")
+	lines := screen(b)
+	if b.frame != 1 || lines[1] != " @@  " {
+		t.Errorf("Wrong frame %d:\n%q", b.frame, lines)
+	}
+	b.handle("n")
+	b.handle("p")
+	b.handle("p")
+	if b.frame != 0 {
+		t.Errorf("Wrong frame: %d", b.frame)
+	}
+	b.handle("q")
+	if b.mode != modeList {
+		t.Errorf("Wrong mode: %d", b.mode)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	img.SetGray(1, 0, color.Gray{255})
+	img.SetGray(3, 1, color.Gray{128})
+	lines := asciiFrame(img, 9, 4)
+	if strings.Join(lines, "|") != "  @@    |      ++" {
+		t.Errorf("Wrong preview: %q", lines)
+	}
+
+	var buf bytes.Buffer
+	if err := sixel(&buf, img, 8, 4); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	if !strings.HasPrefix(s, "\x1bPq\"1;1;8;4#0;2;0;0;0") || !strings.HasSuffix(s, "-\x1b\\") {
+		t.Errorf("Wrong sixel: %q", s)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[6~\r\x7fé\x03"))
+	for _, expected := range []string{"j", "up", "pgdn", "enter", "backspace", "é", "ctrl-c"} {
+		key, err := readKey(r)
+		if err != nil || key != expected {
+			t.Errorf("expected %q, got %q, %v", expected, key, err)
+		}
+	}
+	if _, err := readKey(r); err == nil {
+		t.Errorf("Expected error at end of input")
+	}
+}
@@ -0,0 +1,143 @@
+// Package main is a dcmbrowse command browsing the elements of a DICOM file
+// in the terminal.
+//
+//	dcmbrowse [options] <dicom-file>
+//
+// The elements are listed with their tag, VR, keyword and value, sequences
+// and items expand in place. Keys:
+//
+//	j/k, arrows, PgUp/PgDn, g/G  move
+//	enter, l / h                 expand / collapse, go to the parent
+//	/ and n                      search by keyword or tag, next match
+//	x                            hex dump of the value
+//	f, then n/p                  frame preview, next and previous frames
+//	q                            back, quit
+//
+// Frames are previewed as text, or as sixel images with -sixel:
+//
+//	dcmbrowse -sixel image.dcm
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `usage: dcmbrowse [options] <dicom-file>
+
+Browse the elements of a DICOM file, press ? for the keys.
+
+options:
+`
+
+// Terminal setup and restore sequences, alternate screen and hidden cursor.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l"
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+func main() {
+	fs := flag.NewFlagSet("dcmbrowse", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	noColor := fs.Bool("no-color", false, "draw without colors")
+	useSixel := fs.Bool("sixel", false, "preview frames as sixel images")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	df, err := dcmdump.ProcessFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dcmbrowse: %s\n", err)
+		os.Exit(1)
+	}
+	b := newBrowser(df, fs.Arg(0))
+	b.color, b.sixel = !*noColor, *useSixel
+	if err := run(b, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dcmbrowse: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run draws b on out and handles the keys read from in until quit.
+func run(b *browser, in, out *os.File) error {
+	restore, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", in.Name(), err)
+	}
+	defer restore()
+	fmt.Fprint(out, enterScreen)
+	defer fmt.Fprint(out, leaveScreen)
+	r := bufio.NewReader(in)
+	for {
+		if w, h, err := termSize(int(out.Fd())); err == nil && w > 0 && h > 0 {
+			b.width, b.height = w, h
+		}
+		var buf bytes.Buffer
+		b.render(&buf)
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		key, err := readKey(r)
+		if err != nil {
+			return err
+		}
+		if b.handle(key) {
+			return nil
+		}
+	}
+}
+
+// escapes maps the escape sequences of the terminal keys to their names.
+var escapes = map[string]string{
+	"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+	"OA": "up", "OB": "down", "OC": "right", "OD": "left",
+	"[5~": "pgup", "[6~": "pgdn",
+	"[H": "home", "[F": "end", "[1~": "home", "[4~": "end", "OH": "home", "OF": "end",
+}
+
+// readKey reads a key, returned as its character or, for special keys, its
+// name: up, down, left, right, pgup, pgdn, home, end, enter, esc, backspace
+// and ctrl-c.
+func readKey(r *bufio.Reader) (string, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch c {
+	case '\r', '\n':
+		return "enter", nil
+	case 0x7f, 0x08:
+		return "backspace", nil
+	case 0x03:
+		return "ctrl-c", nil
+	case 0x1b:
+	default:
+		return string(c), nil
+	}
+	// A lone escape is the key, otherwise a sequence follows at once
+	if r.Buffered() == 0 {
+		return "esc", nil
+	}
+	seq := []byte{}
+	for r.Buffered() > 0 {
+		b, _ := r.ReadByte()
+		seq = append(seq, b)
+		// Sequences end with a letter or ~ after their introducer
+		if len(seq) > 1 && (b == '~' || b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z') {
+			break
+		}
+	}
+	if key, ok := escapes[string(seq)]; ok {
+		return key, nil
+	}
+	return "esc", nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// ramp holds the characters of the text previews, darkest first.
+const ramp = " .:-=+*#%@"
+
+// sixelLevels is the number of gray levels of the sixel palette.
+const sixelLevels = 16
+
+// fit returns the size of an image of w x h scaled to fit in maxW x maxH,
+// with cells aspect times taller than wide.
+func fit(w, h, maxW, maxH int, aspect float64) (int, int) {
+	if w <= 0 || h <= 0 || maxW <= 0 || maxH <= 0 {
+		return 0, 0
+	}
+	scale := float64(maxW) / float64(w)
+	if s := float64(maxH) * aspect / float64(h); s < scale {
+		scale = s
+	}
+	cw, ch := int(float64(w)*scale), int(float64(h)*scale/aspect)
+	if cw < 1 {
+		cw = 1
+	}
+	if ch < 1 {
+		ch = 1
+	}
+	return cw, ch
+}
+
+// gray returns the gray level of the pixel of img under cell x, y of a
+// w x h grid.
+func gray(img image.Image, x, y, w, h int) uint8 {
+	b := img.Bounds()
+	px := b.Min.X + (2*x+1)*b.Dx()/(2*w)
+	py := b.Min.Y + (2*y+1)*b.Dy()/(2*h)
+	return color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y
+}
+
+// asciiFrame draws img with the characters of ramp in at most cols x rows
+// characters, twice as tall as wide.
+func asciiFrame(img image.Image, cols, rows int) []string {
+	w, h := fit(img.Bounds().Dx(), img.Bounds().Dy(), cols-1, rows, 2)
+	lines := make([]string, h)
+	for y := 0; y < h; y++ {
+		var sb strings.Builder
+		for x := 0; x < w; x++ {
+			sb.WriteByte(ramp[int(gray(img, x, y, w, h))*len(ramp)/256])
+		}
+		lines[y] = sb.String()
+	}
+	return lines
+}
+
+// sixel writes img as a DEC sixel image of gray levels, scaled to fit in
+// width x height pixels.
+func sixel(w io.Writer, img image.Image, width, height int) error {
+	pw, ph := fit(img.Bounds().Dx(), img.Bounds().Dy(), width, height, 1)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1bPq\"1;1;%d;%d", pw, ph)
+	for c := 0; c < sixelLevels; c++ {
+		p := c * 100 / (sixelLevels - 1)
+		fmt.Fprintf(bw, "#%d;2;%d;%d;%d", c, p, p, p)
+	}
+	levels := make([]int, pw*ph)
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			levels[y*pw+x] = int(gray(img, x, y, pw, ph)) * sixelLevels / 256
+		}
+	}
+	band := make([]byte, pw)
+	for y := 0; y < ph; y += 6 {
+		for c := 0; c < sixelLevels; c++ {
+			used := false
+			for x := 0; x < pw; x++ {
+				bits := byte(0)
+				for k := 0; k < 6 && y+k < ph; k++ {
+					if levels[(y+k)*pw+x] == c {
+						bits |= 1 << k
+					}
+				}
+				used = used || bits != 0
+				band[x] = '?' + bits
+			}
+			if !used {
+				continue
+			}
+			fmt.Fprintf(bw, "#%d", c)
+			sixelRuns(bw, band)
+			bw.WriteByte('$')
+		}
+		bw.WriteByte('-')
+	}
+	bw.WriteString("\x1b\\")
+	return bw.Flush()
+}
+
+// sixelRuns writes the sixels of band, repeated ones as !n runs.
+func sixelRuns(w *bufio.Writer, band []byte) {
+	for i := 0; i < len(band); {
+		j := i
+		for j < len(band) && band[j] == band[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			fmt.Fprintf(w, "!%d%c", n, band[i])
+		} else {
+			w.Write(band[i:j])
+		}
+		i = j
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// errNoTerminal is returned on platforms without terminal support.
+var errNoTerminal = errors.New("Terminal not supported on this platform")
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errNoTerminal
+}
+
+func termSize(fd int) (int, int, error) {
+	return 0, 0, errNoTerminal
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, patientName string) string {
+		df := &dcmdump.DicomFile{}
+		df.SetElement("00080016", "UI", "1.2.840.10008.5.1.4.1.1.7")
+		df.SetElement("00080018", "UI", "1.2.3")
+		df.SetElement("00100010", "PN", patientName)
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a, b := write("a.dcm", "DOE^JOHN"), write("b.dcm", "DOE^J")
+	var out bytes.Buffer
+	n, err := run(&out, a, b, dcmdump.CompareOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if n != 1 || out.String() != "changed PatientName: PN [DOE^JOHN] -> PN [DOE^J]\n" {
+		t.Errorf("Wrong output: %d, %q", n, out.String())
+	}
+	n, err = run(&out, a, b, dcmdump.CompareOptions{IgnoreTags: []string{"00100010"}})
+	if err != nil || n != 0 {
+		t.Errorf("Expected no difference, got %d, %v", n, err)
+	}
+	if _, err = run(&out, a, filepath.Join(dir, "missing.dcm"), dcmdump.CompareOptions{}); err == nil {
+		t.Errorf("Expected error for a missing file")
+	}
+}
@@ -0,0 +1,101 @@
+// Package main is a dcmdiff command printing the differences between the
+// elements of two DICOM files.
+//
+//	dcmdiff [options] <dicom-file-a> <dicom-file-b>
+//
+// The exit status is 0 when the files match, 1 when they differ and 2 on
+// errors, as with diff:
+//
+//	dcmdiff -ignore-uids -ignore PatientName original.dcm anonymized.dcm
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `usage: dcmdiff [options] <dicom-file-a> <dicom-file-b>
+
+Print the elements added, removed or changed from a to b.
+
+options:
+`
+
+// tags collects repeated -ignore flags.
+type tags []string
+
+func (t *tags) String() string { return strings.Join(*t, ",") }
+
+func (t *tags) Set(s string) error {
+	*t = append(*t, s)
+	return nil
+}
+
+func main() {
+	o := dcmdump.CompareOptions{}
+	var ignore tags
+	fs := flag.NewFlagSet("dcmdiff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.BoolVar(&o.IgnoreUIDs, "ignore-uids", false, "ignore UI elements")
+	fs.BoolVar(&o.IgnoreDates, "ignore-dates", false, "ignore DA, DT and TM elements")
+	fs.BoolVar(&o.IgnoreMeta, "ignore-meta", false, "ignore the file meta elements, group 0002")
+	fs.Var(&ignore, "ignore", "ignore tag, as gggg,eeee or keyword, can be repeated")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	for _, t := range ignore {
+		o.IgnoreTags = append(o.IgnoreTags, strings.NewReplacer("(", "", ")", "", ",", "").Replace(t))
+	}
+	n, err := run(os.Stdout, fs.Arg(0), fs.Arg(1), o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dcmdiff: %s\n", err)
+		os.Exit(2)
+	}
+	if n > 0 {
+		os.Exit(1)
+	}
+}
+
+// run prints the differences between the files at pathA and pathB and
+// returns their number.
+func run(w io.Writer, pathA, pathB string, o dcmdump.CompareOptions) (int, error) {
+	a, err := read(pathA)
+	if err != nil {
+		return 0, err
+	}
+	b, err := read(pathB)
+	if err != nil {
+		return 0, err
+	}
+	diffs := dcmdump.Compare(a, b, o)
+	for _, d := range diffs {
+		_, err = fmt.Fprintln(w, d)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(diffs), nil
+}
+
+func read(path string) (*dcmdump.DicomFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	df, err := dcmdump.ParseFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return df, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func testFile(t *testing.T) *dcmdump.DicomFile {
+	item, err := dcmdump.NewDataElement("00081150", "UI", "1.2.840.10008.5.1.4.1.1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.PartOfSQ = true
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+	df.SetElement("00081115", "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{item}}})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00280010", "US", 512)
+	df.SetElement("7FE00010", "OW", []int{0x0201, 0x0403})
+	var buf bytes.Buffer
+	err = df.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	df, err = dcmdump.ParseFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return df
+}
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+	err := write(&buf, testFile(t), options{format: "text"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := `
+# Dicom-File-Format
+
+# Dicom-Meta-Information-Header
+# Used TransferSyntax: Little Endian Explicit
+(0002,0000) UL 28                                       #   4, 1 FileMetaInfoGroupLength
+(0002,0010) UI =LittleEndianExplicit                    #  20, 1 TransferSyntaxUID
+
+# Dicom-Data-Set
+# Used TransferSyntax: Little Endian Explicit
+(0008,1115) SQ (Sequence with explicit length #=1)      #  42, 1 ReferencedSeriesSequence
+  (fffe,e000) na (Item with explicit length #=1)          #  34, 1 Item
+    (0008,1150) UI =CTImageStorage                          #  26, 1 ReferencedSOPClassUID
+(0010,0010) PN [DOE^JOHN]                               #   8, 1 PatientName
+(0028,0010) US 512                                      #   2, 1 Rows
+(7fe0,0010) OW 0201\0403                                #   4, 1 PixelData
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	o, err := parseArgs([]string{"+P", "0008,1150", "+P", "Rows", "file.dcm"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	write(&buf, testFile(t), o)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "(0008,1150) UI") || !strings.HasPrefix(lines[1], "(0028,0010) US 512") {
+		t.Errorf("Wrong search output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	o.format = "json"
+	write(&buf, testFile(t), o)
+	if buf.String() != `{"00280010":{"vr":"US","Value":[512]}}`+"\n" {
+		t.Errorf("Wrong JSON output: %s", buf.String())
+	}
+
+	buf.Reset()
+	o.format = "csv"
+	write(&buf, testFile(t), o)
+	if buf.String() != "Path,00081150,Rows\n,,512\n" {
+		t.Errorf("Wrong CSV output: %s", buf.String())
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	o, err := parseArgs([]string{"+sd", "+r", "+L", "--xml", "dir"})
+	if err != nil || !o.scanDirs || !o.recurse || !o.printLong || o.format != "xml" || o.files[0] != "dir" {
+		t.Errorf("Wrong options: %+v, %v", o, err)
+	}
+	o, err = parseArgs([]string{"--format", "tsv", "+uc", "file"})
+	if err != nil || o.format != "tsv" || !o.convertUN {
+		t.Errorf("Wrong format: %+v, %v", o, err)
+	}
+	o, err = parseArgs([]string{"--tree", "--width", "32", "file"})
+	if err != nil || o.format != "tree" || o.width != 32 {
+		t.Errorf("Wrong tree options: %+v, %v", o, err)
+	}
+	for _, args := range [][]string{{}, {"+X", "file"}, {"file", "+P"}, {"file", "--format"}, {"file", "--width", "x"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
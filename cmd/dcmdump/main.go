@@ -0,0 +1,236 @@
+// Package main is a dcmdump command with the options and output of the dcmtk
+// dcmdump tool.
+//
+//	dcmdump [options] dcmfile-in...
+//
+// The +P search option and +sd directory scanning mirror dcmtk, --json and
+// --xml print the DICOM JSON and Native DICOM models instead of the text dump,
+// --tree the elements as a tree and --format selects any format registered
+// with dcmdump.RegisterEncoder.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+const usage = `dcmdump: Dump DICOM file and data set
+
+usage: dcmdump [options] dcmfile-in...
+
+options:
+  -h    --help                 print this help text and exit
+  +sd   --scan-directories     scan directories for input files
+  +r    --recurse              recurse within specified directories
+  +F    --print-filename       print header with file name for each input file
+  +L    --print-all            print long tag values completely
+  -L    --print-short          print long tag values shortened (default)
+  +P    --search  [t]ag: "gggg,eeee" or dictionary name
+                               print the value of tag t, can be repeated
+  +uc   --convert-un           read UN elements with their dictionary VR
+        --json                 print the DICOM JSON model
+        --xml                  print the Native DICOM model
+        --tree                 print the elements as a tree, nested
+                               sequences indented by depth
+        --width   [n]umber     width of the tree values, 64 by default
+        --format  [n]ame: text, tree, json, xml, csv, tsv, parquet or other
+                               registered encoder, +P tags are the columns
+                               of csv, tsv and parquet
+`
+
+// errHelp is returned by parseArgs when the help is requested.
+var errHelp = errors.New("help")
+
+type options struct {
+	scanDirs      bool
+	recurse       bool
+	printFilename bool
+	printLong     bool
+	convertUN     bool
+	format        string
+	width         int
+	searches      []string
+	files         []string
+}
+
+func parseArgs(args []string) (options, error) {
+	o := options{format: "text"}
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; a {
+		case "-h", "--help":
+			return o, errHelp
+		case "+sd", "--scan-directories":
+			o.scanDirs = true
+		case "+r", "--recurse":
+			o.recurse = true
+		case "+F", "--print-filename":
+			o.printFilename = true
+		case "+L", "--print-all":
+			o.printLong = true
+		case "-L", "--print-short":
+			o.printLong = false
+		case "+uc", "--convert-un":
+			o.convertUN = true
+		case "--json":
+			o.format = "json"
+		case "--xml":
+			o.format = "xml"
+		case "--tree":
+			o.format = "tree"
+		case "--width":
+			if i+1 >= len(args) {
+				return o, fmt.Errorf("missing parameter for %s", a)
+			}
+			i++
+			n, err := strconv.Atoi(args[i])
+			if err != nil {
+				return o, fmt.Errorf("invalid width %s", args[i])
+			}
+			o.width = n
+		case "--format":
+			if i+1 >= len(args) {
+				return o, fmt.Errorf("missing parameter for %s", a)
+			}
+			i++
+			o.format = args[i]
+		case "+P", "--search":
+			if i+1 >= len(args) {
+				return o, fmt.Errorf("missing parameter for %s", a)
+			}
+			i++
+			o.searches = append(o.searches, searchKey(args[i]))
+		default:
+			if len(a) > 1 && (a[0] == '-' || a[0] == '+') {
+				return o, fmt.Errorf("unknown option %s", a)
+			}
+			o.files = append(o.files, a)
+		}
+	}
+	if len(o.files) == 0 {
+		return o, fmt.Errorf("missing parameter dcmfile-in")
+	}
+	return o, nil
+}
+
+// searchKey normalizes "gggg,eeee" and "(gggg,eeee)" tags to GGGGEEEE,
+// dictionary names are kept.
+func searchKey(s string) string {
+	t := strings.Trim(s, "()")
+	if len(t) == 9 && t[4] == ',' {
+		return strings.ToUpper(t[:4] + t[5:])
+	}
+	return s
+}
+
+// inputFiles expands the directories given with +sd.
+func inputFiles(o options) ([]string, error) {
+	files := []string{}
+	for _, f := range o.files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, f)
+			continue
+		}
+		if !o.scanDirs {
+			return nil, fmt.Errorf("%s is a directory, use +sd to scan it", f)
+		}
+		err = filepath.Walk(f, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != f && !o.recurse {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func main() {
+	o, err := parseArgs(os.Args[1:])
+	if err == errHelp {
+		fmt.Print(usage)
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E: %s\n\n%s", err, usage)
+		os.Exit(1)
+	}
+	files, err := inputFiles(o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E: %s\n", err)
+		os.Exit(1)
+	}
+	enc, err := newEncoder(os.Stdout, o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "E: %s: %s\n", o.format, err)
+		os.Exit(1)
+	}
+	status := 0
+	for i, path := range files {
+		if o.printFilename {
+			fmt.Printf("# dcmdump (%d/%d): %s\n", i+1, len(files), path)
+		}
+		err := dump(enc, path, o)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "E: %s: %s\n", path, err)
+			status = 1
+		}
+	}
+	if err := enc.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "E: %s\n", err)
+		status = 1
+	}
+	os.Exit(status)
+}
+
+func dump(enc dcmdump.Encoder, path string, o options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	df, err := dcmdump.ParseFileWithOptions(f, dcmdump.ParseOptions{ResolveUN: o.convertUN})
+	if err != nil {
+		return err
+	}
+	df.Path = path
+	return enc.Encode(df)
+}
+
+// newEncoder returns the encoder of the selected format, printing only the
+// elements matching the searches when given.
+func newEncoder(w io.Writer, o options) (dcmdump.Encoder, error) {
+	return dcmdump.NewEncoder(o.format, w, dcmdump.EncoderOptions{Tags: o.searches, Long: o.printLong, Width: o.width})
+}
+
+// write prints df in the selected format.
+func write(w io.Writer, df *dcmdump.DicomFile, o options) error {
+	enc, err := newEncoder(w, o)
+	if err != nil {
+		return err
+	}
+	err = enc.Encode(df)
+	if closeErr := enc.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/dcmwrite"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestModifyFile(t *testing.T) {
+	item, _ := dcmdump.NewDataElement("00081150", "UI", "1.2.3")
+	item.PartOfSQ = true
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00081115", "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{item}}})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00100030", "DA", "19700101")
+	path := filepath.Join(t.TempDir(), "file.dcm")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = df.Write(f, ts.ExplicitVRBigEndian)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []operation{}
+	for _, o := range []struct {
+		kind, expr string
+	}{
+		{modify, "(0010,0010)=SMITH^JANE"},
+		{insert, "Rows=512"},
+		{insert, "ReferencedSeriesSequence[0].(0008,1155)=1.2.3.4"},
+		{erase, "PatientBirthDate"},
+		{eraseAll, "(0008,1150)"},
+	} {
+		err := operations{o.kind, &ops}.Set(o.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", o.expr, err)
+		}
+	}
+	err = modifyFile(path, ops, true, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := os.Stat(path + ".bak"); err != nil {
+		t.Errorf("Backup not kept: %s", err)
+	}
+	f, _ = os.Open(path)
+	df, err = dcmdump.ParseFile(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if df.TransferSyntax != ts.ExplicitVRBigEndian {
+		t.Errorf("Transfer syntax not kept: %s", df.TransferSyntax)
+	}
+	for tagStr, expected := range map[string]string{"00100010": "SMITH^JANE", "00280010": "512"} {
+		de, ok := df.Lookup(tagStr)
+		if !ok || de.Strings()[0] != expected {
+			t.Errorf("%s: expected %s, got %v", tagStr, expected, de.Strings())
+		}
+	}
+	if _, ok := df.Lookup("00100030"); ok {
+		t.Errorf("PatientBirthDate not erased")
+	}
+	seq, _ := df.Lookup("00081115")
+	if len(seq.Items) != 1 || len(seq.Items[0].Elements) != 1 || seq.Items[0].Elements[0].TagStr != "00081155" {
+		t.Errorf("Wrong sequence: %v", seq.Items)
+	}
+
+	// Failed writes leave the original in place
+	original, _ := os.ReadFile(path)
+	ops = []operation{}
+	err = operations{modify, &ops}.Set("PatientName=" + strings.Repeat("A", 0x10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := modifyFile(path, ops, false, false); !errors.Is(err, dcmwrite.ErrVRLength) {
+		t.Errorf("Expected ErrVRLength, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); !bytes.Equal(b, original) {
+		t.Errorf("Original changed by a failed write")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 2 {
+		t.Errorf("Temporary file left: %v", entries)
+	}
+
+	err = apply(df, operation{kind: modify, path: []step{{tag: "00100020", item: -1}}, value: "ID"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	for _, expr := range []string{"NoSuchKeyword=1", "ReferencedSeriesSequence.(0008,1155)=1", "(0010,0010)"} {
+		if err := (operations{insert, &ops}).Set(expr); err == nil {
+			t.Errorf("%s: expected error", expr)
+		}
+	}
+}
+
+func TestNewElement(t *testing.T) {
+	for _, c := range []struct {
+		vr, value, expected string
+	}{
+		{"SV", `9007199254740993\-2`, `9007199254740993\-2`},
+		{"UV", "18446744073709551615", "18446744073709551615"},
+		{"US", "512", "512"},
+	} {
+		de, err := newElement("00091010", c.vr, c.value)
+		if err != nil || strings.Join(de.Strings(), `\`) != c.expected {
+			t.Errorf("%s: expected %s, got %v, %v", c.vr, c.expected, de.Strings(), err)
+		}
+	}
+	if _, err := newElement("00091010", "UV", "-1"); err == nil {
+		t.Errorf("Expected an error for a negative UV")
+	}
+}
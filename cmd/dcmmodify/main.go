@@ -0,0 +1,361 @@
+// Package main is a dcmmodify command editing the elements of DICOM files in
+// place, with the options of the dcmtk dcmodify tool.
+//
+//	dcmmodify [options] dcmfile-in...
+//
+// Tag paths are tags as (gggg,eeee) or dictionary names, items of sequences
+// are selected with [n], starting at 0:
+//
+//	dcmmodify -i "(0010,0010)=DOE^JOHN" -m "ReferencedSeriesSequence[0].(0008,1150)=1.2.3" -e PatientBirthDate file.dcm
+//
+// Files are written back with their transfer syntax, the original is kept
+// with a .bak extension unless -nb is given.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+const usage = `usage: dcmmodify [options] dcmfile-in...
+
+Modify DICOM files.
+
+options:
+`
+
+// Operation kinds
+const (
+	insert   = "insert"
+	modify   = "modify"
+	erase    = "erase"
+	eraseAll = "erase-all"
+)
+
+// ErrNotFound is returned when modifying or erasing an element that is not in
+// the file.
+var ErrNotFound = errors.New("tag not found")
+
+type operation struct {
+	kind  string
+	path  []step
+	value string
+}
+
+// step - Element of a tag path and, for sequences, the selected item.
+type step struct {
+	tag  string
+	item int
+}
+
+// operations collects -i, -m, -e and -ea in command line order.
+type operations struct {
+	kind string
+	list *[]operation
+}
+
+func (o operations) String() string { return "" }
+
+func (o operations) Set(s string) error {
+	expr, value := s, ""
+	if o.kind == insert || o.kind == modify {
+		i := strings.Index(s, "=")
+		if i < 0 {
+			return fmt.Errorf("missing value in '%s', use tag=value", s)
+		}
+		expr, value = s[:i], s[i+1:]
+	}
+	path, err := parsePath(expr)
+	if err != nil {
+		return err
+	}
+	*o.list = append(*o.list, operation{kind: o.kind, path: path, value: value})
+	return nil
+}
+
+// parsePath parses "(gggg,eeee)[n].Keyword" style tag paths.
+func parsePath(expr string) ([]step, error) {
+	path := []step{}
+	for _, s := range strings.Split(expr, ".") {
+		st := step{item: -1}
+		if i := strings.Index(s, "["); i >= 0 && strings.HasSuffix(s, "]") {
+			n, err := strconv.Atoi(s[i+1 : len(s)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid item index in '%s'", s)
+			}
+			st.item = n
+			s = s[:i]
+		}
+		t := strings.Trim(s, "()")
+		switch {
+		case len(t) == 9 && t[4] == ',':
+			st.tag = strings.ToUpper(t[:4] + t[5:])
+		default:
+			info, ok := tag.ByKeyword(s)
+			if !ok {
+				return nil, fmt.Errorf("unknown tag '%s'", s)
+			}
+			st.tag = info.TagStr()
+		}
+		path = append(path, st)
+	}
+	for _, st := range path[:len(path)-1] {
+		if st.item < 0 {
+			return nil, fmt.Errorf("missing item index for sequence %s in '%s'", st.tag, expr)
+		}
+	}
+	if path[len(path)-1].item >= 0 {
+		return nil, fmt.Errorf("the last tag of '%s' can't select an item", expr)
+	}
+	return path, nil
+}
+
+func main() {
+	ops := []operation{}
+	var noBackup, ignoreErrors bool
+	fs := flag.NewFlagSet("dcmmodify", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.Var(operations{insert, &ops}, "i", "insert or replace tag path with value: \"(gggg,eeee)=value\"")
+	fs.Var(operations{modify, &ops}, "m", "modify existing tag path to value: \"(gggg,eeee)=value\"")
+	fs.Var(operations{erase, &ops}, "e", "erase tag path")
+	fs.Var(operations{eraseAll, &ops}, "ea", "erase tag at any nesting level")
+	fs.BoolVar(&noBackup, "nb", false, "do not keep a .bak backup of the files")
+	fs.BoolVar(&ignoreErrors, "ie", false, "continue with the next operation on errors")
+	fs.Parse(os.Args[1:])
+	if fs.NArg() == 0 || len(ops) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	status := 0
+	for _, path := range fs.Args() {
+		err := modifyFile(path, ops, !noBackup, ignoreErrors)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "E: %s: %s\n", path, err)
+			status = 1
+		}
+	}
+	os.Exit(status)
+}
+
+func modifyFile(path string, ops []operation, backup, ignoreErrors bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	df, err := dcmdump.ParseFile(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	for _, op := range ops {
+		err = apply(df, op)
+		if err != nil && !ignoreErrors {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "W: %s: %s\n", path, err)
+		}
+	}
+	tmp, err := writeTemp(path, df)
+	if err != nil {
+		return err
+	}
+	if backup {
+		err = os.Rename(path, path+".bak")
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, path)
+}
+
+// writeTemp writes df to a temporary file next to path, with its mode, so the
+// original is only replaced once the new file is complete.
+func writeTemp(path string, df *dcmdump.DicomFile) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	err = df.Write(out, df.TransferSyntax)
+	if err == nil {
+		err = out.Chmod(info.Mode().Perm())
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// apply runs op on df. Inserts create the missing items of the path.
+func apply(df *dcmdump.DicomFile, op operation) error {
+	if op.kind == eraseAll {
+		target := op.path[len(op.path)-1].tag
+		if eraseTag(&df.Elements, target) == 0 {
+			return fmt.Errorf("%s: %w", target, ErrNotFound)
+		}
+		return nil
+	}
+	elements := &df.Elements
+	for _, st := range op.path[:len(op.path)-1] {
+		i := index(*elements, st.tag)
+		if i < 0 {
+			if op.kind != insert {
+				return fmt.Errorf("%s: %w", st.tag, ErrNotFound)
+			}
+			seq, err := dcmdump.NewDataElement(st.tag, "SQ", []dcmdump.Item{})
+			if err != nil {
+				return err
+			}
+			i = set(elements, seq)
+		}
+		seq := &(*elements)[i]
+		for len(seq.Items) <= st.item {
+			if op.kind != insert {
+				return fmt.Errorf("%s[%d]: %w", st.tag, st.item, ErrNotFound)
+			}
+			seq.Items = append(seq.Items, dcmdump.Item{})
+		}
+		elements = &seq.Items[st.item].Elements
+	}
+	last := op.path[len(op.path)-1].tag
+	i := index(*elements, last)
+	switch op.kind {
+	case erase:
+		if i < 0 {
+			return fmt.Errorf("%s: %w", last, ErrNotFound)
+		}
+		*elements = append((*elements)[:i], (*elements)[i+1:]...)
+		return nil
+	case modify:
+		if i < 0 {
+			return fmt.Errorf("%s: %w", last, ErrNotFound)
+		}
+	}
+	vr := ""
+	if i >= 0 {
+		vr = (*elements)[i].VRStr
+	}
+	de, err := newElement(last, vr, op.value)
+	if err != nil {
+		return err
+	}
+	de.PartOfSQ = len(op.path) > 1
+	set(elements, de)
+	return nil
+}
+
+// newElement encodes a command line value, vr defaults to the dictionary VR.
+func newElement(tagStr, vr, value string) (dcmdump.DataElement, error) {
+	if _, ok := vri.VR[vr]; !ok {
+		var group, element uint16
+		fmt.Sscanf(tagStr, "%04X%04X", &group, &element)
+		info, _ := tag.Find(group, element)
+		vr = info.VR
+	}
+	var v interface{} = value
+	switch vr {
+	case "":
+		return dcmdump.DataElement{}, fmt.Errorf("%s: unknown VR, only dictionary tags can be inserted", tagStr)
+	case "SQ", "OB", "OD", "OF", "OL", "OV", "OW", "UN":
+		return dcmdump.DataElement{}, fmt.Errorf("%s: %s values can't be set from the command line", tagStr, vr)
+	case "SV":
+		numbers := []int64{}
+		for _, s := range strings.Split(value, "\\") {
+			i, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return dcmdump.DataElement{}, fmt.Errorf("%s: invalid %s value '%s'", tagStr, vr, s)
+			}
+			numbers = append(numbers, i)
+		}
+		v = numbers
+	case "UV":
+		numbers := []uint64{}
+		for _, s := range strings.Split(value, "\\") {
+			u, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				return dcmdump.DataElement{}, fmt.Errorf("%s: invalid %s value '%s'", tagStr, vr, s)
+			}
+			numbers = append(numbers, u)
+		}
+		v = numbers
+	case "US", "SS", "UL", "SL", "FL", "FD":
+		numbers := []float64{}
+		for _, s := range strings.Split(value, "\\") {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return dcmdump.DataElement{}, fmt.Errorf("%s: invalid %s value '%s'", tagStr, vr, s)
+			}
+			numbers = append(numbers, f)
+		}
+		v = numbers
+	}
+	return dcmdump.NewDataElement(tagStr, vr, v)
+}
+
+func index(elements []dcmdump.DataElement, tagStr string) int {
+	for i := range elements {
+		if elements[i].TagStr == tagStr {
+			return i
+		}
+	}
+	return -1
+}
+
+// set replaces or inserts de keeping the elements ordered by tag, it returns
+// the index of de.
+func set(elements *[]dcmdump.DataElement, de dcmdump.DataElement) int {
+	for i := range *elements {
+		switch {
+		case (*elements)[i].TagStr == de.TagStr:
+			(*elements)[i] = de
+			return i
+		case (*elements)[i].TagStr > de.TagStr:
+			*elements = append(*elements, dcmdump.DataElement{})
+			copy((*elements)[i+1:], (*elements)[i:])
+			(*elements)[i] = de
+			return i
+		}
+	}
+	*elements = append(*elements, de)
+	return len(*elements) - 1
+}
+
+// eraseTag removes tagStr at every nesting level and returns how many
+// elements were removed.
+func eraseTag(elements *[]dcmdump.DataElement, tagStr string) int {
+	n := 0
+	kept := (*elements)[:0]
+	for _, de := range *elements {
+		if de.TagStr == tagStr {
+			n++
+			continue
+		}
+		for i := range de.Items {
+			n += eraseTag(&de.Items[i].Elements, tagStr)
+		}
+		kept = append(kept, de)
+	}
+	*elements = kept
+	return n
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestConvert(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "blk1"), []byte{1, 2, 3, 4}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"00080016":{"vr":"UI","Value":["1.2.840.10008.5.1.4.1.1.7"]},` +
+		`"00080018":{"vr":"UI","Value":["1.2.3"]},` +
+		`"7FE00010":{"vr":"OW","BulkDataURI":"blk1"}}`
+	var buf bytes.Buffer
+	err = convert(&buf, []byte(input), dir, options{transferSyntax: ts.ExplicitVRBigEndian})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	df, err := dcmdump.ParseFile(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if df.TransferSyntax != ts.ExplicitVRBigEndian || value(df, "00020003") != "1.2.3" {
+		t.Errorf("Wrong file meta: %s %s", df.TransferSyntax, value(df, "00020003"))
+	}
+	de, ok := df.Lookup("7FE00010")
+	if !ok || de.Len != 4 {
+		t.Errorf("Bulk data not read: %v", de)
+	}
+
+	_, err = readBulkData("http://localhost/blk1", dir)
+	if err == nil {
+		t.Errorf("Expected error for http BulkDataURI")
+	}
+}
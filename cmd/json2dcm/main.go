@@ -0,0 +1,132 @@
+// Package main is a json2dcm command converting the DICOM JSON model to DICOM
+// files, with the options of the dcm4che json2dcm tool.
+//
+//	json2dcm [options] -j <json-file> -o <dicom-file>
+//
+// BulkDataURI values are read from file URIs or paths relative to the JSON
+// file, as written by dcm2json --blk-file-dir.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+const usage = `usage: json2dcm [options] -j <json-file> -o <dicom-file>
+
+Convert a DICOM JSON model document to a DICOM file.
+
+options:
+`
+
+type options struct {
+	json           string
+	out            string
+	transferSyntax string
+	noFileMeta     bool
+}
+
+func main() {
+	o := options{}
+	fs := flag.NewFlagSet("json2dcm", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&o.json, "j", "", "JSON file to convert, standard input when -")
+	for _, name := range []string{"o", "out"} {
+		fs.StringVar(&o.out, name, "", "DICOM file to write, standard output when not set")
+	}
+	for _, name := range []string{"t", "transfer-syntax"} {
+		fs.StringVar(&o.transferSyntax, name, ts.ExplicitVRLittleEndian, "transfer syntax UID of the output")
+	}
+	fs.BoolVar(&o.noFileMeta, "no-fmi", false, "write the data set without preamble and file meta information")
+	fs.Parse(os.Args[1:])
+	if o.json == "" && fs.NArg() == 1 {
+		o.json = fs.Arg(0)
+	}
+	if o.json == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	err := run(o)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "json2dcm: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(o options) error {
+	var b []byte
+	var err error
+	dir := "."
+	if o.json == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(o.json)
+		dir = filepath.Dir(o.json)
+	}
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if o.out != "" {
+		out, err := os.Create(o.out)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w = out
+	}
+	return convert(w, b, dir, o)
+}
+
+// convert writes the DICOM file of the JSON document b, bulk data paths are
+// relative to dir.
+func convert(w io.Writer, b []byte, dir string, o options) error {
+	df, err := dcmdump.ParseJSON(b, func(uri string) ([]byte, error) {
+		return readBulkData(uri, dir)
+	})
+	if err != nil {
+		return err
+	}
+	if o.noFileMeta {
+		return df.WriteDataset(w, o.transferSyntax)
+	}
+	err = df.SetFileMeta(o.transferSyntax)
+	if err != nil {
+		return err
+	}
+	return df.Write(w, o.transferSyntax)
+}
+
+// readBulkData reads a file URI or a path relative to dir.
+func readBulkData(uri, dir string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "file":
+		return ioutil.ReadFile(filepath.FromSlash(u.Path))
+	case "":
+		return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(u.Path)))
+	}
+	return nil, fmt.Errorf("unsupported BulkDataURI %s", uri)
+}
+
+func value(df *dcmdump.DicomFile, tagStr string) string {
+	de, ok := df.Lookup(tagStr)
+	if !ok || len(de.Strings()) == 0 {
+		return ""
+	}
+	return de.Strings()[0]
+}
@@ -0,0 +1,306 @@
+// Package anonymize de-identifies DICOM files following the Basic
+// Application Level Confidentiality Profile of PS3.15.
+package anonymize
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// Anonymizer de-identifies files, UIDs are remapped consistently across all
+// the files it processes so references between them are kept.
+// It is safe for concurrent use.
+//
+// Batches run in several processes or at different times keep their
+// references by sharing UIDs:
+//
+//	uids, err := anonymize.OpenFileUIDs("uids.tsv")
+//	a := anonymize.New()
+//	a.UIDs = uids
+type Anonymizer struct {
+	Profile map[string]Action
+	// DateShift shifts dates by a number of days instead of removing them,
+	// Retain Longitudinal Temporal Information with Modified Dates Option.
+	DateShift int
+	// KeepPrivate keeps private tags, they are removed by default.
+	// Anonymizers made from Rules remove them with a PrivateTags rule instead.
+	KeepPrivate bool
+	// Default is the action of the elements without a rule, Keep when empty.
+	Default Action
+	// HashSalt is hashed with the values replaced by Hash, so they can't be
+	// found by hashing candidate values.
+	HashSalt string
+	// Method is recorded as the DeidentificationMethod instead of the Basic
+	// Profile when not empty.
+	Method string
+	// UIDs stores the replacements of RemapUID, MemoryUIDs when nil.
+	UIDs UIDMapper
+
+	mu sync.Mutex
+	// patterns are the wildcard rules, most specific first.
+	patterns []pattern
+	// custom is set for Anonymizers made from Rules, basic when they start
+	// from the Basic Profile.
+	custom, basic bool
+}
+
+// New returns an Anonymizer with the Basic Profile.
+func New() *Anonymizer {
+	return &Anonymizer{Profile: BasicProfile, UIDs: NewMemoryUIDs()}
+}
+
+// Anonymize de-identifies df in place and records the method used in the
+// DeidentificationMethodCodeSequence.
+func (a *Anonymizer) Anonymize(df *dcmdump.DicomFile) error {
+	elements, err := a.process(df.Elements)
+	if err != nil {
+		return err
+	}
+	df.Elements = elements
+	err = df.SetElement("00120062", "CS", "YES")
+	if err != nil {
+		return err
+	}
+	method := "Basic Application Confidentiality Profile"
+	codes := []dcmdump.Item{}
+	if !a.custom || a.basic {
+		item, err := codeItem("113100", method)
+		if err != nil {
+			return err
+		}
+		codes = append(codes, item)
+	}
+	if a.Method != "" {
+		method = a.Method
+	}
+	if a.DateShift != 0 {
+		method += ", Modified Dates"
+		item, err := codeItem("113107", "Retain Longitudinal Temporal Information Modified Dates Option")
+		if err != nil {
+			return err
+		}
+		codes = append(codes, item)
+	}
+	err = df.SetElement("00120063", "LO", method)
+	if err != nil {
+		return err
+	}
+	return df.SetElement("00120064", "SQ", codes)
+}
+
+// UID returns the replacement of uid from UIDs, the same one is returned for
+// every call with the same uid.
+func (a *Anonymizer) UID(uid string) (string, error) {
+	a.mu.Lock()
+	if a.UIDs == nil {
+		a.UIDs = NewMemoryUIDs()
+	}
+	uids := a.UIDs
+	a.mu.Unlock()
+	return uids.MapUID(uid)
+}
+
+func (a *Anonymizer) process(elements []dcmdump.DataElement) ([]dcmdump.DataElement, error) {
+	out := make([]dcmdump.DataElement, 0, len(elements))
+	for _, de := range elements {
+		action := a.action(de.TagStr)
+		if a.DateShift != 0 && (action == Remove || action == Empty || action == ShiftDate) && (de.VRStr == "DA" || de.VRStr == "DT") {
+			shifted, err := a.shift(de)
+			if err != nil {
+				return out, err
+			}
+			out = append(out, shifted)
+			continue
+		}
+		switch action {
+		case Remove:
+			continue
+		case Empty:
+			de.Data = []byte{}
+			de.Len = 0
+			de.Items = nil
+		case Dummy:
+			d, err := dcmdump.NewDataElement(de.TagStr, de.VRStr, dummy(de.VRStr))
+			if err != nil {
+				return out, err
+			}
+			d.N = de.N
+			de = d
+		case RemapUID:
+			values := de.Strings()
+			for i, v := range values {
+				if v == "" {
+					continue
+				}
+				n, err := a.UID(v)
+				if err != nil {
+					return out, err
+				}
+				values[i] = n
+			}
+			d, err := dcmdump.NewDataElement(de.TagStr, "UI", values)
+			if err != nil {
+				return out, err
+			}
+			d.N = de.N
+			de = d
+		case Hash:
+			d, err := a.hashElement(de)
+			if err != nil {
+				return out, err
+			}
+			de = d
+		}
+		if len(de.Items) > 0 {
+			items := make([]dcmdump.Item, len(de.Items))
+			for i, item := range de.Items {
+				e, err := a.process(item.Elements)
+				if err != nil {
+					return out, err
+				}
+				item.Elements = e
+				items[i] = item
+			}
+			de.Items = items
+		}
+		out = append(out, de)
+	}
+	return out, nil
+}
+
+// action returns the action of the first rule matching tagStr: its
+// Profile entry, then the patterns. The elements without a rule get the
+// Default action.
+func (a *Anonymizer) action(tagStr string) Action {
+	if action, ok := a.Profile[tagStr]; ok && (!a.removeGroup(tagStr) || a.custom) {
+		return action
+	}
+	for _, p := range a.patterns {
+		if p.match(tagStr) {
+			return p.action
+		}
+	}
+	if !a.custom && a.removeGroup(tagStr) {
+		return Remove
+	}
+	if a.Default == "" {
+		return Keep
+	}
+	return a.Default
+}
+
+// hashElement replaces the values of de with their hash, elements that can't
+// hold one get a dummy value.
+func (a *Anonymizer) hashElement(de dcmdump.DataElement) (dcmdump.DataElement, error) {
+	var value interface{}
+	switch de.VRStr {
+	case "AE", "CS", "LO", "LT", "PN", "SH", "ST", "UC", "UT", "UI":
+		values := de.Strings()
+		for i, v := range values {
+			if v != "" {
+				values[i] = a.hash(de.VRStr, v)
+			}
+		}
+		value = values
+	default:
+		value = dummy(de.VRStr)
+	}
+	d, err := dcmdump.NewDataElement(de.TagStr, de.VRStr, value)
+	d.N = de.N
+	return d, err
+}
+
+func isPrivate(tagStr string) bool {
+	return strings.IndexByte("13579BDF", tagStr[3]) >= 0
+}
+
+// removeGroup reports whether the tag is private, a curve or overlay data or
+// comments.
+func (a *Anonymizer) removeGroup(tagStr string) bool {
+	group := tagStr[:4]
+	if isPrivate(tagStr) && !a.KeepPrivate {
+		return true
+	}
+	if group[:2] == "50" {
+		return true
+	}
+	return group[:2] == "60" && (tagStr[4:] == "3000" || tagStr[4:] == "4000")
+}
+
+// shift moves the dates of a DA or DT element by DateShift days.
+func (a *Anonymizer) shift(de dcmdump.DataElement) (dcmdump.DataElement, error) {
+	values := de.Strings()
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+		e, err := dcmdump.NewDataElement(de.TagStr, de.VRStr, v)
+		if err != nil {
+			return de, err
+		}
+		t, err := e.Time()
+		if err != nil {
+			// Unparseable dates can't be kept
+			values[i] = ""
+			continue
+		}
+		t = t.AddDate(0, 0, a.DateShift)
+		if de.VRStr == "DA" {
+			values[i] = t.Format("20060102")
+		} else {
+			// Partial values keep their precision, the fraction and UTC
+			// offset are kept as is
+			date, suffix := v, ""
+			if j := strings.IndexAny(v, ".+-"); j >= 0 {
+				date, suffix = v[:j], v[j:]
+			}
+			layout := "20060102150405"
+			if len(date) < len(layout) {
+				layout = layout[:len(date)]
+			}
+			values[i] = t.Format(layout) + suffix
+		}
+	}
+	d, err := dcmdump.NewDataElement(de.TagStr, de.VRStr, values)
+	d.N = de.N
+	return d, err
+}
+
+func dummy(vr string) interface{} {
+	switch vr {
+	case "DA":
+		return "19000101"
+	case "TM":
+		return "000000"
+	case "DT":
+		return "19000101000000"
+	case "US", "SS", "UL", "SL", "SV", "UV", "FL", "FD", "IS", "DS":
+		return 0
+	case "UI":
+		return dcmdump.NewUID()
+	case "OB", "OW", "UN":
+		return []byte{}
+	case "SQ":
+		return []dcmdump.Item{}
+	}
+	return "ANONYMIZED"
+}
+
+func codeItem(value, meaning string) (dcmdump.Item, error) {
+	item := dcmdump.Item{}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00080100", "SH", value},
+		{"00080102", "SH", "DCM"},
+		{"00080104", "LO", meaning},
+	} {
+		de, err := dcmdump.NewDataElement(e.tag, e.vr, e.value)
+		if err != nil {
+			return item, err
+		}
+		de.PartOfSQ = true
+		item.Elements = append(item.Elements, de)
+	}
+	return item, nil
+}
@@ -0,0 +1,313 @@
+package anonymize
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func testFile(t *testing.T, sop string) *dcmdump.DicomFile {
+	df := &dcmdump.DicomFile{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00080018", "UI", sop},
+		{"00080020", "DA", "20200131"},
+		{"00100010", "PN", "DOE^JOHN"},
+		{"00100020", "LO", "12345"},
+		{"0020000D", "UI", "1.2.3"},
+		{"00091001", "LO", "private"},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return df
+}
+
+func lookup(df *dcmdump.DicomFile, tagStr string) *dcmdump.DataElement {
+	for i := range df.Elements {
+		if df.Elements[i].TagStr == tagStr {
+			return &df.Elements[i]
+		}
+	}
+	return nil
+}
+
+func TestAnonymize(t *testing.T) {
+	a := New()
+	a.DateShift = 10
+	files := []*dcmdump.DicomFile{testFile(t, "1.2.3.1"), testFile(t, "1.2.3.2")}
+	for _, df := range files {
+		err := a.Anonymize(df)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if de := lookup(df, "00100010"); de == nil || len(de.Data) != 0 {
+			t.Errorf("PatientName not emptied: %v", de)
+		}
+		if de := lookup(df, "00091001"); de != nil {
+			t.Errorf("Private tag not removed")
+		}
+		if de := lookup(df, "00080020"); de == nil || de.Strings()[0] != "20200210" {
+			t.Errorf("StudyDate not shifted: %v", de)
+		}
+		if de := lookup(df, "00120064"); de == nil || len(de.Items) != 2 {
+			t.Errorf("DeidentificationMethodCodeSequence missing: %v", de)
+		}
+	}
+	study1 := lookup(files[0], "0020000D").Strings()[0]
+	study2 := lookup(files[1], "0020000D").Strings()[0]
+	if study1 == "1.2.3" || study1 != study2 {
+		t.Errorf("StudyInstanceUID not remapped consistently: %s, %s", study1, study2)
+	}
+	sop1 := lookup(files[0], "00080018").Strings()[0]
+	sop2 := lookup(files[1], "00080018").Strings()[0]
+	if sop1 == sop2 {
+		t.Errorf("SOPInstanceUIDs collide: %s", sop1)
+	}
+}
+
+func TestShiftDateTime(t *testing.T) {
+	a := New()
+	a.DateShift = 10
+	for _, c := range []struct {
+		value, expected string
+	}{
+		{"2016", "2016"},
+		{"201612", "201612"},
+		{"20160102", "20160112"},
+		{"2020013112", "2020021012"},
+		{"202001021200+0100", "202001121200+0100"},
+		{"20200131235959.123456", "20200210235959.123456"},
+		{"20200131235959.5-0500", "20200210235959.5-0500"},
+	} {
+		de, err := dcmdump.NewDataElement("0008002A", "DT", c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		shifted, err := a.shift(de)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.value, err)
+		}
+		if v := shifted.Strings()[0]; v != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.value, c.expected, v)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	yaml := `# Research profile
+name: Research
+basic: true
+dateShift: 10
+hashSalt: "s3cret"
+actions:
+  PatientID: hash      # linkable across studies
+  "0009xxxx": keep
+  (0009,1001): remove
+  00091002: H
+  StudyDate: shift
+`
+	json := `{"name": "Research", "basic": true, "dateShift": 10, "hashSalt": "s3cret",
+		"actions": {"PatientID": "hash", "0009xxxx": "keep", "(0009,1001)": "remove", "00091002": "H", "StudyDate": "shift"}}`
+	for _, profile := range []string{yaml, json} {
+		r, err := ReadRules(strings.NewReader(profile))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		a, err := NewWithRules(r)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		df := testFile(t, "1.2.3.1")
+		for _, e := range []struct {
+			tag, vr string
+			value   interface{}
+		}{
+			{"00091002", "LO", "hashed"},
+			{"00091003", "LO", "kept"},
+			{"00111001", "LO", "removed"},
+			{"60003000", "OW", []byte{1, 2}},
+		} {
+			err = df.SetElement(e.tag, e.vr, e.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = a.Anonymize(df)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if de := lookup(df, "00100020"); de == nil || de.Strings()[0] != a.hash("LO", "12345") || len(de.Strings()[0]) != 16 {
+			t.Errorf("PatientID not hashed: %v", de)
+		}
+		if de := lookup(df, "00100010"); de == nil || len(de.Data) != 0 {
+			t.Errorf("PatientName not emptied by the Basic Profile: %v", de)
+		}
+		if de := lookup(df, "00080020"); de == nil || de.Strings()[0] != "20200210" {
+			t.Errorf("StudyDate not shifted: %v", de)
+		}
+		for tagStr, expected := range map[string]bool{"00091001": false, "00091002": true, "00091003": true, "00111001": false, "60003000": false} {
+			if de := lookup(df, tagStr); (de != nil) != expected {
+				t.Errorf("%s: expected kept %v, got %v", tagStr, expected, de)
+			}
+		}
+		if de := lookup(df, "00091003"); de == nil || de.Strings()[0] != "kept" {
+			t.Errorf("Private tag changed: %v", de)
+		}
+		if de := lookup(df, "00120063"); de == nil || de.Strings()[0] != "Research, Modified Dates" {
+			t.Errorf("Unexpected DeidentificationMethod: %v", de)
+		}
+	}
+
+	for _, profile := range []string{"actions:\n  PatientID: scramble\n", `{"actions": {"NotAKeyword": "keep"}}`, "unknown: 1\n"} {
+		r, err := ReadRules(strings.NewReader(profile))
+		if err == nil {
+			_, err = NewWithRules(r)
+		}
+		if err == nil {
+			t.Errorf("Expected an error for %q", profile)
+		}
+	}
+
+	a, err := NewWithRules(&Rules{Default: Remove, Actions: map[string]Action{"SOPInstanceUID": Hash, "PatientName": Keep}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	df := testFile(t, "1.2.3.1")
+	err = a.Anonymize(df)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if de := lookup(df, "00080018"); de == nil || !strings.HasPrefix(de.Strings()[0], "2.25.") {
+		t.Errorf("SOPInstanceUID not hashed to a UID: %v", de)
+	}
+	if de := lookup(df, "00100020"); de != nil {
+		t.Errorf("PatientID not removed by default: %v", de)
+	}
+	if de := lookup(df, "00120064"); de == nil || len(de.Items) != 0 {
+		t.Errorf("Unexpected method codes without the Basic Profile: %v", de)
+	}
+}
+
+func TestFileUIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uids.tsv")
+	studies := []string{}
+	for _, sop := range []string{"1.2.3.1", "1.2.3.2"} {
+		// A batch per file, the mapping is only shared through the file
+		uids, err := OpenFileUIDs(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		a := New()
+		a.UIDs = uids
+		df := testFile(t, sop)
+		err = a.Anonymize(df)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uids.Close()
+		studies = append(studies, lookup(df, "0020000D").Strings()[0])
+	}
+	if studies[0] == "1.2.3" || studies[0] != studies[1] {
+		t.Errorf("StudyInstanceUID not remapped consistently across batches: %v", studies)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The study and both instances
+	if lines := strings.Count(string(b), "\n"); lines != 3 {
+		t.Errorf("Expected 3 mappings, got %d:\n%s", lines, b)
+	}
+
+	err = ioutil.WriteFile(path, []byte("1.2.3\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenFileUIDs(path)
+	if err != ErrUIDFile {
+		t.Errorf("Expected ErrUIDFile, got %v", err)
+	}
+}
+
+func TestMaskPixels(t *testing.T) {
+	image := func() *dcmdump.DicomFile {
+		df := testFile(t, "1.2.3.1")
+		pixels := make([]byte, 2*4*4)
+		for i := range pixels {
+			pixels[i] = 0xFF
+		}
+		for _, e := range []struct {
+			tag, vr string
+			value   interface{}
+		}{
+			{"00080060", "CS", "US"},
+			{"00080070", "LO", "Acme Medical"},
+			{"00280002", "US", 1},
+			{"00280008", "IS", "2"},
+			{"00280010", "US", 4},
+			{"00280011", "US", 4},
+			{"00280100", "US", 8},
+			{"00280301", "CS", "YES"},
+			{"7FE00010", "OB", pixels},
+		} {
+			err := df.SetElement(e.tag, e.vr, e.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return df
+	}
+
+	df := image()
+	// Clipped to the 4x4 frames
+	err := MaskPixels(df, []Region{{X: 2, Y: -1, Width: 10, Height: 2}}, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	pixels := lookup(df, "7FE00010").Data
+	for i, v := range pixels {
+		frame, y, x := i/16, i%16/4, i%4
+		expected := byte(0xFF)
+		if frame == 1 && y == 0 && x >= 2 {
+			expected = 0
+		}
+		if v != expected {
+			t.Errorf("Frame %d pixel %d,%d: expected %d, got %d", frame, x, y, expected, v)
+		}
+	}
+	if de := lookup(df, "00280301"); de == nil || de.Strings()[0] != "NO" {
+		t.Errorf("BurnedInAnnotation not updated: %v", de)
+	}
+	if de := lookup(df, "00120064"); de == nil || len(de.Items) != 1 {
+		t.Errorf("Clean Pixel Data Option not recorded: %v", de)
+	}
+
+	df = image()
+	templates := []MaskTemplate{
+		{Name: "Other", Modality: "US", Manufacturer: "Other"},
+		{Name: "Acme", Modality: "US", Manufacturer: "ACME", Rows: 4, Columns: 4, Regions: []Region{{0, 0, 4, 1}}},
+	}
+	used, err := MaskBurnedIn(df, templates)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if used.Name != "Acme" {
+		t.Errorf("Unexpected template %s", used.Name)
+	}
+	pixels = lookup(df, "7FE00010").Data
+	if pixels[0] != 0 || pixels[3] != 0 || pixels[4] != 0xFF || pixels[16] != 0 {
+		t.Errorf("Banner not masked in every frame: %v", pixels)
+	}
+	_, err = MaskBurnedIn(df, DefaultMaskTemplates)
+	if err != ErrNoTemplate {
+		t.Errorf("Expected ErrNoTemplate, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package anonymize
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// ErrNoTemplate is returned by MaskBurnedIn when no template matches the file.
+var ErrNoTemplate = errors.New("No mask template for the file")
+
+// ErrMaskFormat is returned for pixel data whose samples can't be masked.
+var ErrMaskFormat = errors.New("Unsupported pixel format for masking")
+
+// Region - Rectangle of a frame in pixels, from its top left corner.
+type Region struct {
+	X, Y, Width, Height int
+}
+
+// MaskTemplate - Regions holding burned-in annotations on the images of a
+// type of equipment. Empty criteria match all files.
+type MaskTemplate struct {
+	Name     string
+	Modality string
+	// Manufacturer and ManufacturerModelName match the start of the values,
+	// ignoring case.
+	Manufacturer          string
+	ManufacturerModelName string
+	// Rows and Columns of the images, any size when 0.
+	Rows, Columns int
+	Regions       []Region
+}
+
+// DefaultMaskTemplates cover the top banner holding the patient and study
+// details of ultrasound screen captures at the common sizes. They are a
+// starting point, the templates of each model need to be verified on its
+// images.
+var DefaultMaskTemplates = []MaskTemplate{
+	{Name: "US 640x480 banner", Modality: "US", Rows: 480, Columns: 640, Regions: []Region{{0, 0, 640, 48}}},
+	{Name: "US 800x600 banner", Modality: "US", Rows: 600, Columns: 800, Regions: []Region{{0, 0, 800, 60}}},
+	{Name: "US 1024x768 banner", Modality: "US", Rows: 768, Columns: 1024, Regions: []Region{{0, 0, 1024, 77}}},
+}
+
+// Match reports whether the template applies to df.
+func (t *MaskTemplate) Match(df *dcmdump.DicomFile) bool {
+	for _, c := range []struct{ tag, value string }{
+		{"00080060", t.Modality},
+		{"00080070", t.Manufacturer},
+		{"00081090", t.ManufacturerModelName},
+	} {
+		if c.value == "" {
+			continue
+		}
+		v := ""
+		if de, ok := df.Lookup(c.tag); ok && len(de.Strings()) > 0 {
+			v = de.Strings()[0]
+		}
+		if c.tag == "00080060" && v != c.value {
+			return false
+		} else if !strings.HasPrefix(strings.ToUpper(v), strings.ToUpper(c.value)) {
+			return false
+		}
+	}
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return false
+	}
+	return (t.Rows == 0 || t.Rows == pi.Rows) && (t.Columns == 0 || t.Columns == pi.Columns)
+}
+
+// MaskBurnedIn masks the regions of the first of templates matching df, see
+// MaskPixels. It returns the template used.
+func MaskBurnedIn(df *dcmdump.DicomFile, templates []MaskTemplate) (*MaskTemplate, error) {
+	for i := range templates {
+		if templates[i].Match(df) {
+			return &templates[i], MaskPixels(df, templates[i].Regions)
+		}
+	}
+	return nil, ErrNoTemplate
+}
+
+// MaskPixels sets the samples of the regions to 0 in the given frames,
+// starting at 0, or in all of them when none are given. Regions are clipped
+// to the image.
+//
+// Native pixel data is masked as stored. RLE Lossless frames are decoded and
+// the pixel data stored as Explicit VR Little Endian, other encapsulated
+// transfer syntaxes are not supported.
+// BurnedInAnnotation is then set to NO and the Clean Pixel Data Option added
+// to the DeidentificationMethodCodeSequence, so files are masked after
+// Anonymize, which replaces the sequence.
+func MaskPixels(df *dcmdump.DicomFile, regions []Region, frames ...int) error {
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return err
+	}
+	subsampled := pi.PhotometricInterpretation == dcmdump.PhotometricYBRFull422 && !pi.Encapsulated
+	if pi.BitsAllocated%8 != 0 || pi.BitsAllocated == 0 || subsampled {
+		return ErrMaskFormat
+	}
+	if pi.Encapsulated && pi.TransferSyntax != ts.RLELossless {
+		return dcmdump.ErrUnsupportedTS
+	}
+	if len(frames) == 0 {
+		for i := 0; i < pi.NumberOfFrames; i++ {
+			frames = append(frames, i)
+		}
+	}
+	masked := map[int]bool{}
+	for _, i := range frames {
+		if i < 0 || i >= pi.NumberOfFrames {
+			return dcmdump.ErrFrameIndex
+		}
+		masked[i] = true
+	}
+	var data []byte
+	for i := 0; i < pi.NumberOfFrames; i++ {
+		frame, err := pi.DecodeFrame(i)
+		if err != nil {
+			return err
+		}
+		frame = append([]byte{}, frame...)
+		if masked[i] {
+			maskFrame(frame, pi, regions)
+		}
+		data = append(data, frame...)
+	}
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	vr := "OB"
+	if pi.BitsAllocated > 8 {
+		vr = "OW"
+	}
+	err = df.SetElement("7FE00010", vr, data)
+	if err != nil {
+		return err
+	}
+	if pi.Encapsulated {
+		df.TransferSyntax = ts.ExplicitVRLittleEndian
+		if _, ok := df.Lookup("00020010"); ok {
+			err = df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = df.SetElement("00280301", "CS", "NO")
+	if err != nil {
+		return err
+	}
+	return addMethodCode(df, "113101", "Clean Pixel Data Option")
+}
+
+// maskFrame zeroes the samples of the regions in a native frame.
+func maskFrame(frame []byte, pi *dcmdump.PixelDataInfo, regions []Region) {
+	size := pi.BitsAllocated / 8
+	plane := pi.Rows * pi.Columns * size
+	for _, r := range regions {
+		x0, y0, x1, y1 := r.X, r.Y, r.X+r.Width, r.Y+r.Height
+		if x0 < 0 {
+			x0 = 0
+		}
+		if y0 < 0 {
+			y0 = 0
+		}
+		if x1 > pi.Columns {
+			x1 = pi.Columns
+		}
+		if y1 > pi.Rows {
+			y1 = pi.Rows
+		}
+		if x0 >= x1 || y0 >= y1 {
+			continue
+		}
+		for y := y0; y < y1; y++ {
+			if pi.PlanarConfiguration == 1 {
+				for s := 0; s < pi.SamplesPerPixel; s++ {
+					start := s*plane + (y*pi.Columns+x0)*size
+					zero(frame[start : start+(x1-x0)*size])
+				}
+				continue
+			}
+			start := (y*pi.Columns + x0) * size * pi.SamplesPerPixel
+			zero(frame[start : start+(x1-x0)*size*pi.SamplesPerPixel])
+		}
+	}
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// addMethodCode appends a code to the DeidentificationMethodCodeSequence
+// unless it is already there.
+func addMethodCode(df *dcmdump.DicomFile, value, meaning string) error {
+	items := []dcmdump.Item{}
+	if de, ok := df.Lookup("00120064"); ok {
+		for _, item := range de.Items {
+			for _, e := range item.Elements {
+				if e.TagStr == "00080100" && len(e.Strings()) > 0 && e.Strings()[0] == value {
+					return nil
+				}
+			}
+			items = append(items, item)
+		}
+	}
+	item, err := codeItem(value, meaning)
+	if err != nil {
+		return err
+	}
+	return df.SetElement("00120064", "SQ", append(items, item))
+}
@@ -0,0 +1,87 @@
+package anonymize
+
+// Action - De-identification action codes from PS3.15 Table E.1-1.
+type Action string
+
+// Actions
+const (
+	Remove   Action = "X" // Remove the element
+	Empty    Action = "Z" // Replace with a zero length value
+	Dummy    Action = "D" // Replace with a dummy value of the same VR
+	RemapUID Action = "U" // Replace with a consistent new UID
+	Keep     Action = "K" // Keep the element
+
+	Hash      Action = "H" // Replace with a salted hash of the value
+	ShiftDate Action = "S" // Shift the dates by DateShift days
+)
+
+// BasicProfile - Basic Application Level Confidentiality Profile
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part15/chapter_E.html
+// Private tags, curves (50xx) and overlay data and comments (60xx) are
+// removed separately.
+var BasicProfile = map[string]Action{
+	"00020003": RemapUID, // MediaStorageSOPInstanceUID
+	"00080014": RemapUID, // InstanceCreatorUID
+	"00080018": RemapUID, // SOPInstanceUID
+	"00080020": Empty,    // StudyDate
+	"00080021": Remove,   // SeriesDate
+	"00080022": Remove,   // AcquisitionDate
+	"00080023": Empty,    // ContentDate
+	"0008002A": Remove,   // AcquisitionDateTime
+	"00080030": Empty,    // StudyTime
+	"00080031": Remove,   // SeriesTime
+	"00080032": Remove,   // AcquisitionTime
+	"00080033": Empty,    // ContentTime
+	"00080050": Empty,    // AccessionNumber
+	"00080080": Remove,   // InstitutionName
+	"00080081": Remove,   // InstitutionAddress
+	"00080090": Empty,    // ReferringPhysicianName
+	"00080092": Remove,   // ReferringPhysicianAddress
+	"00080094": Remove,   // ReferringPhysicianTelephoneNumbers
+	"00080096": Remove,   // ReferringPhysicianIdentificationSequence
+	"00081010": Remove,   // StationName
+	"00081030": Remove,   // StudyDescription
+	"0008103E": Remove,   // SeriesDescription
+	"00081040": Remove,   // InstitutionalDepartmentName
+	"00081048": Remove,   // PhysiciansOfRecord
+	"00081050": Remove,   // PerformingPhysicianName
+	"00081060": Remove,   // NameOfPhysiciansReadingStudy
+	"00081070": Remove,   // OperatorsName
+	"00081080": Remove,   // AdmittingDiagnosesDescription
+	"00081155": RemapUID, // ReferencedSOPInstanceUID
+	"00082111": Remove,   // DerivationDescription
+	"00100010": Empty,    // PatientName
+	"00100020": Empty,    // PatientID
+	"00100030": Empty,    // PatientBirthDate
+	"00100032": Remove,   // PatientBirthTime
+	"00100040": Empty,    // PatientSex
+	"00101000": Remove,   // OtherPatientIDs
+	"00101001": Remove,   // OtherPatientNames
+	"00101002": Remove,   // OtherPatientIDsSequence
+	"00101010": Remove,   // PatientAge
+	"00101020": Remove,   // PatientSize
+	"00101030": Remove,   // PatientWeight
+	"00101040": Remove,   // PatientAddress
+	"00101060": Remove,   // PatientMotherBirthName
+	"00102154": Remove,   // PatientTelephoneNumbers
+	"00102160": Remove,   // EthnicGroup
+	"00102180": Remove,   // Occupation
+	"001021B0": Remove,   // AdditionalPatientHistory
+	"00104000": Remove,   // PatientComments
+	"00181000": Remove,   // DeviceSerialNumber
+	"00181030": Remove,   // ProtocolName
+	"0020000D": RemapUID, // StudyInstanceUID
+	"0020000E": RemapUID, // SeriesInstanceUID
+	"00200010": Empty,    // StudyID
+	"00200052": RemapUID, // FrameOfReferenceUID
+	"00200200": RemapUID, // SynchronizationFrameOfReferenceUID
+	"00204000": Remove,   // ImageComments
+	"00321032": Remove,   // RequestingPhysician
+	"00321060": Remove,   // RequestedProcedureDescription
+	"00380010": Remove,   // AdmissionID
+	"00400254": Remove,   // PerformedProcedureStepDescription
+	"00400275": Remove,   // RequestAttributesSequence
+	"0040A124": RemapUID, // UID
+	"00880140": RemapUID, // StorageMediaFileSetUID
+	"30060024": RemapUID, // ReferencedFrameOfReferenceUID
+}
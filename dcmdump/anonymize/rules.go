@@ -0,0 +1,327 @@
+package anonymize
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// ErrRules is returned for profiles that can't be read.
+var ErrRules = errors.New("Invalid de-identification profile")
+
+// ErrAction is returned for rules with an unknown action.
+var ErrAction = errors.New("Unknown de-identification action")
+
+// ErrRuleTag is returned for rules whose tag is neither a keyword, a tag nor
+// a pattern.
+var ErrRuleTag = errors.New("Unknown de-identification rule tag")
+
+// PrivateTags is the rule tag matching all the private tags.
+const PrivateTags = "private"
+
+// Rules - De-identification profile declaring an action per tag.
+// Actions are given by tag (0010,0010 or 00100010), keyword (PatientName),
+// pattern or PrivateTags. Patterns use x for any hex digit of a tag, as in
+// 0009xxxx for a private group, 50xxxxxx for curves or 60xx3000 for overlay
+// data.
+// Exact tags take precedence over patterns, patterns with fewer wildcards
+// over the others and patterns over PrivateTags.
+//
+// Rules are read from JSON:
+//
+//	{"name": "Research", "basic": true, "dateShift": -30, "actions": {"PatientAge": "keep", "0009xxxx": "hash"}}
+//
+// or the equivalent YAML:
+//
+//	name: Research
+//	basic: true
+//	dateShift: -30
+//	actions:
+//	  PatientAge: keep
+//	  0009xxxx: hash
+type Rules struct {
+	// Name is recorded as the DeidentificationMethod.
+	Name string `json:"name,omitempty"`
+	// Basic starts from the Basic Profile and its removal of private tags,
+	// curves and overlay data, Actions override it.
+	Basic bool `json:"basic,omitempty"`
+	// DateShift in days for ShiftDate, see Anonymizer.DateShift.
+	DateShift int `json:"dateShift,omitempty"`
+	// HashSalt, see Anonymizer.HashSalt.
+	HashSalt string `json:"hashSalt,omitempty"`
+	// Default action of the elements matching no rule, see
+	// Anonymizer.Default.
+	Default Action            `json:"default,omitempty"`
+	Actions map[string]Action `json:"actions,omitempty"`
+}
+
+// basicPatterns are the group removals of the Basic Profile.
+var basicPatterns = map[string]Action{
+	PrivateTags: Remove,
+	"50xxxxxx":  Remove, // Curves
+	"60xx3000":  Remove, // OverlayData
+	"60xx4000":  Remove, // OverlayComments
+}
+
+var actionNames = map[string]Action{
+	"remove": Remove,
+	"empty":  Empty,
+	"dummy":  Dummy,
+	"uid":    RemapUID,
+	"keep":   Keep,
+	"hash":   Hash,
+	"shift":  ShiftDate,
+}
+
+// UnmarshalText reads an action by code (X, Z, D, U, K, H, S) or name
+// (remove, empty, dummy, uid, keep, hash, shift).
+func (a *Action) UnmarshalText(b []byte) error {
+	s := string(b)
+	if action, ok := actionNames[strings.ToLower(s)]; ok {
+		*a = action
+		return nil
+	}
+	for _, action := range actionNames {
+		if string(action) == strings.ToUpper(s) {
+			*a = action
+			return nil
+		}
+	}
+	return ErrAction
+}
+
+// pattern - Rule matching tags with wildcards.
+type pattern struct {
+	tag       string
+	action    Action
+	wildcards int
+}
+
+func (p pattern) match(tagStr string) bool {
+	if p.tag == PrivateTags {
+		return isPrivate(tagStr)
+	}
+	for i := 0; i < len(p.tag); i++ {
+		if p.tag[i] != 'X' && p.tag[i] != tagStr[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewWithRules returns an Anonymizer applying r.
+func NewWithRules(r *Rules) (*Anonymizer, error) {
+	a := &Anonymizer{
+		Profile:   map[string]Action{},
+		DateShift: r.DateShift,
+		HashSalt:  r.HashSalt,
+		Default:   r.Default,
+		Method:    r.Name,
+		UIDs:      NewMemoryUIDs(),
+		custom:    true,
+		basic:     r.Basic,
+	}
+	if a.Default != "" && !validAction(a.Default) {
+		return nil, ErrAction
+	}
+	actions := map[string]Action{}
+	if r.Basic {
+		for k, v := range BasicProfile {
+			a.Profile[k] = v
+		}
+		for k, v := range basicPatterns {
+			t, _ := ruleTag(k)
+			actions[t] = v
+		}
+	}
+	for k, v := range r.Actions {
+		if !validAction(v) {
+			return nil, ErrAction
+		}
+		t, err := ruleTag(k)
+		if err != nil {
+			return nil, err
+		}
+		actions[t] = v
+	}
+	for t, v := range actions {
+		wildcards := strings.Count(t, "X")
+		if t == PrivateTags {
+			wildcards = len("ggggeeee")
+		} else if wildcards == 0 {
+			a.Profile[t] = v
+			continue
+		}
+		a.patterns = append(a.patterns, pattern{tag: t, action: v, wildcards: wildcards})
+	}
+	sort.Slice(a.patterns, func(i, j int) bool {
+		if a.patterns[i].wildcards != a.patterns[j].wildcards {
+			return a.patterns[i].wildcards < a.patterns[j].wildcards
+		}
+		return a.patterns[i].tag < a.patterns[j].tag
+	})
+	return a, nil
+}
+
+// ReadRules reads a profile in JSON or YAML. Only the YAML mappings of the
+// Rules fields are supported, with comments and quoted scalars.
+func ReadRules(r io.Reader) (*Rules, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(trimmed, rules)
+		if err == ErrAction {
+			return nil, err
+		} else if err != nil {
+			return nil, ErrRules
+		}
+		return rules, nil
+	}
+	return rules, readYAML(b, rules)
+}
+
+// readYAML reads the top level scalars and the actions mapping of a YAML
+// profile.
+func readYAML(b []byte, rules *Rules) error {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	inActions := false
+	for s.Scan() {
+		line := stripComment(s.Text())
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+		indented := line[0] == ' ' || line[0] == '\t'
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return ErrRules
+		}
+		key, value := unquote(strings.TrimSpace(line[:i])), unquote(strings.TrimSpace(line[i+1:]))
+		if indented {
+			if !inActions {
+				return ErrRules
+			}
+			var action Action
+			err := action.UnmarshalText([]byte(value))
+			if err != nil {
+				return err
+			}
+			if rules.Actions == nil {
+				rules.Actions = map[string]Action{}
+			}
+			rules.Actions[key] = action
+			continue
+		}
+		inActions = false
+		var err error
+		switch key {
+		case "name":
+			rules.Name = value
+		case "basic":
+			rules.Basic, err = strconv.ParseBool(value)
+		case "dateShift":
+			rules.DateShift, err = strconv.Atoi(value)
+		case "hashSalt":
+			rules.HashSalt = value
+		case "default":
+			err = rules.Default.UnmarshalText([]byte(value))
+		case "actions":
+			inActions = value == ""
+			if !inActions {
+				return ErrRules
+			}
+		default:
+			return ErrRules
+		}
+		if err == ErrAction {
+			return err
+		} else if err != nil {
+			return ErrRules
+		}
+	}
+	return s.Err()
+}
+
+// stripComment removes a # comment outside quotes.
+func stripComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.Replace(s[1:len(s)-1], "''", "'", -1)
+	}
+	return s
+}
+
+// ruleTag returns the tag or pattern of a rule key in upper case.
+func ruleTag(k string) (string, error) {
+	if strings.ToLower(k) == PrivateTags {
+		return PrivateTags, nil
+	}
+	if info, ok := tag.ByKeyword(k); ok {
+		return info.TagStr(), nil
+	}
+	t := strings.ToUpper(strings.NewReplacer("(", "", ")", "", ",", "").Replace(k))
+	if len(t) != 8 {
+		return "", ErrRuleTag
+	}
+	for _, c := range t {
+		if !strings.ContainsRune("0123456789ABCDEFX", c) {
+			return "", ErrRuleTag
+		}
+	}
+	return t, nil
+}
+
+func validAction(a Action) bool {
+	for _, action := range actionNames {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// hash returns the replacement of a value by Hash: a UID derived from the
+// hash for UI elements, its first 16 hex digits otherwise so it fits short
+// string VRs.
+func (a *Anonymizer) hash(vr, v string) string {
+	sum := sha256.Sum256([]byte(a.HashSalt + v))
+	if vr == "UI" {
+		// UUID derived UID, PS3.5 B.2
+		return "2.25." + new(big.Int).SetBytes(sum[:16]).String()
+	}
+	return strings.ToUpper(hex.EncodeToString(sum[:8]))
+}
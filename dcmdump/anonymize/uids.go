@@ -0,0 +1,147 @@
+package anonymize
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// ErrUIDFile is returned for UID files with invalid lines.
+var ErrUIDFile = errors.New("Invalid UID mapping file")
+
+// UIDMapper - Store of the UID replacements of an Anonymizer. MapUID returns
+// the same replacement every time it is called with a UID so the references
+// between files are kept, sharing a store across runs keeps them between
+// batches.
+// Implementations are safe for concurrent use.
+type UIDMapper interface {
+	MapUID(uid string) (string, error)
+}
+
+// MemoryUIDs - UID replacements held in memory for the life of the process.
+type MemoryUIDs struct {
+	mu   sync.Mutex
+	uids map[string]string
+}
+
+// NewMemoryUIDs returns an empty MemoryUIDs.
+func NewMemoryUIDs() *MemoryUIDs {
+	return &MemoryUIDs{uids: map[string]string{}}
+}
+
+// MapUID returns the replacement of uid, a new UID the first time.
+func (m *MemoryUIDs) MapUID(uid string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n, ok := m.uids[uid]; ok {
+		return n, nil
+	}
+	n := dcmdump.NewUID()
+	m.uids[uid] = n
+	return n, nil
+}
+
+// FileUIDs - UID replacements kept in a file, one original and replacement
+// pair separated by a tab per line. New replacements are appended as they
+// are made.
+type FileUIDs struct {
+	mu   sync.Mutex
+	f    *os.File
+	uids map[string]string
+}
+
+// OpenFileUIDs reads the replacements of the file at path, it is created
+// when missing.
+func OpenFileUIDs(path string) (*FileUIDs, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	m := &FileUIDs{f: f, uids: map[string]string{}}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if s.Text() == "" {
+			continue
+		}
+		fields := strings.Split(s.Text(), "\t")
+		if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+			f.Close()
+			return nil, ErrUIDFile
+		}
+		m.uids[fields[0]] = fields[1]
+	}
+	if err = s.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// MapUID returns the replacement of uid, a new UID written to the file the
+// first time.
+func (m *FileUIDs) MapUID(uid string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n, ok := m.uids[uid]; ok {
+		return n, nil
+	}
+	n := dcmdump.NewUID()
+	_, err := m.f.WriteString(uid + "\t" + n + "\n")
+	if err != nil {
+		return "", err
+	}
+	m.uids[uid] = n
+	return n, nil
+}
+
+// Close closes the file.
+func (m *FileUIDs) Close() error {
+	return m.f.Close()
+}
+
+// SQLUIDs - UID replacements kept in the uid_map table of a database, which
+// can be shared by concurrent processes.
+type SQLUIDs struct {
+	db *sql.DB
+}
+
+// NewSQLUIDs returns a SQLUIDs for db, the uid_map table is created when
+// missing.
+func NewSQLUIDs(db *sql.DB) (*SQLUIDs, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS uid_map (original TEXT PRIMARY KEY, replacement TEXT NOT NULL)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLUIDs{db: db}, nil
+}
+
+// MapUID returns the replacement of uid, a new UID inserted in the table the
+// first time. The replacement inserted first wins when processes race for
+// the same uid.
+func (m *SQLUIDs) MapUID(uid string) (string, error) {
+	n, err := m.lookup(uid)
+	if err != sql.ErrNoRows {
+		return n, err
+	}
+	n = dcmdump.NewUID()
+	_, err = m.db.Exec(`INSERT INTO uid_map (original, replacement) VALUES (?, ?)`, uid, n)
+	if err != nil {
+		// Inserted by another process in the meantime
+		if existing, lookupErr := m.lookup(uid); lookupErr == nil {
+			return existing, nil
+		}
+		return "", err
+	}
+	return n, nil
+}
+
+func (m *SQLUIDs) lookup(uid string) (string, error) {
+	var n string
+	err := m.db.QueryRow(`SELECT replacement FROM uid_map WHERE original = ?`, uid).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,48 @@
+package dcmdump
+
+import "fmt"
+
+// BulkDataRef - Byte range of the value of an element in the input it was
+// parsed from, to serve it with HTTP range requests without reading it.
+// Encapsulated pixel data ranges hold the items of the fragments, up to the
+// sequence delimitation item.
+type BulkDataRef struct {
+	Offset int64
+	Length int64
+}
+
+// Range returns the HTTP Range header value selecting r.
+func (r BulkDataRef) Range() string {
+	if r.Length == 0 {
+		return ""
+	}
+	return fmt.Sprintf("bytes=%d-%d", r.Offset, r.Offset+r.Length-1)
+}
+
+// BulkData returns the byte range of the value of de in the input it was
+// parsed from, offsets include the preamble of files.
+// It is false for elements that weren't parsed, like those of NewDataElement,
+// and for the elements of deflated data sets, which aren't stored as is.
+func (de *DataElement) BulkData() (BulkDataRef, bool) {
+	if !de.located {
+		return BulkDataRef{}, false
+	}
+	return BulkDataRef{Offset: de.ValueOffset, Length: int64(de.Len)}, true
+}
+
+// BulkDataRanges returns a BulkDataFunc referencing the binary values longer
+// than threshold bytes by their range in the file at uri, with the offset and
+// length query parameters of dcm4che:
+//
+//	file.dcm?offset=1234&length=524288
+//
+// The other values are inlined.
+func BulkDataRanges(uri string, threshold int) BulkDataFunc {
+	return func(de *DataElement) string {
+		ref, ok := de.BulkData()
+		if !ok || ref.Length <= int64(threshold) {
+			return ""
+		}
+		return fmt.Sprintf("%s?offset=%d&length=%d", uri, ref.Offset, ref.Length)
+	}
+}
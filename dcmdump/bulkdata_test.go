@@ -0,0 +1,70 @@
+package dcmdump
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestBulkData(t *testing.T) {
+	data := sampleFile()
+	df, err := ParseFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, tagStr := range []string{"00100010", "7FE00010", "00081115"} {
+		de, _ := df.LookupElement(tagStr)
+		ref, ok := de.BulkData()
+		if !ok || ref.Length != int64(de.Len) {
+			t.Fatalf("%s: wrong reference %+v, %v", tagStr, ref, ok)
+		}
+		if tagStr != "00081115" && !bytes.Equal(data[ref.Offset:ref.Offset+ref.Length], de.Data) {
+			t.Errorf("%s: wrong range %+v", tagStr, ref)
+		}
+	}
+	item := df.Elements[2].Items[0].Elements[0]
+	ref, ok := item.BulkData()
+	if !ok || string(data[ref.Offset:ref.Offset+ref.Length]) != "1.2.3\x00" {
+		t.Errorf("Wrong nested reference %+v, %v", ref, ok)
+	}
+
+	pixels, _ := df.LookupElement("7FE00010")
+	ref, _ = pixels.BulkData()
+	if ref.Range() != fmt.Sprintf("bytes=%d-%d", ref.Offset, len(data)-1) {
+		t.Errorf("Wrong range header %q", ref.Range())
+	}
+	bulk := BulkDataRanges("file.dcm", 2)
+	if uri := bulk(pixels); uri != fmt.Sprintf("file.dcm?offset=%d&length=4", ref.Offset) {
+		t.Errorf("Wrong uri %q", uri)
+	}
+	if uri := bulk(&df.Elements[len(df.Elements)-2]); uri != "" {
+		t.Errorf("Expected short value inlined, got %q", uri)
+	}
+
+	// Elements not parsed and deflated data sets have no range
+	de, err := NewDataElement("00100010", "PN", "DOE^JOHN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := de.BulkData(); ok {
+		t.Errorf("Expected no reference for a new element")
+	}
+	var buf bytes.Buffer
+	if err := df.Write(&buf, ts.DeflatedExplicitVRLittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	deflated, err := ParseFile(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if de, _ := deflated.LookupElement("00100010"); de == nil {
+		t.Fatalf("PatientName not found")
+	} else if _, ok := de.BulkData(); ok {
+		t.Errorf("Expected no reference in a deflated data set")
+	}
+	if _, ok := deflated.Elements[2].Items[0].Elements[0].BulkData(); ok {
+		t.Errorf("Expected no reference in a deflated sequence")
+	}
+}
@@ -0,0 +1,270 @@
+// Package charset decodes DICOM text values to UTF-8 following the
+// SpecificCharacterSet (0008,0005) of the data set.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.12.html#sect_C.12.1.1.2
+//
+// ASCII, Latin-1 (ISO_IR 100), Cyrillic (ISO_IR 144), JIS X 0201 (ISO_IR 13)
+// and UTF-8 (ISO_IR 192) are built in, with or without ISO 2022 code
+// extensions, as are the multi byte sets JIS X 0208 and 0212 (ISO 2022 IR 87
+// and 159), KS X 1001 (ISO 2022 IR 149), GB2312 (ISO 2022 IR 58), GB18030
+// and GBK. Other sets can be added with Register.
+package charset
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrCharset is returned for character sets without a registered decoder, the
+// text is then decoded as Latin-1.
+var ErrCharset = errors.New("Unsupported character set")
+
+// Decoder converts bytes of a character set to UTF-8.
+// Multi byte sets used with ISO 2022 code extensions (ISO 2022 IR 87, 159,
+// 149 and 58) get their bytes with the high bit set, as in EUC-JP, EUC-KR
+// and GB2312 (EUC-CN), JIS X 0212 pairs prefixed with 0x8F.
+type Decoder func(data []byte) (string, error)
+
+var decodersMu sync.RWMutex
+var decoders = map[string]Decoder{
+	"":           ascii,
+	"ISO_IR 6":   ascii,
+	"ISO_IR 100": latin1,
+	"ISO_IR 144": cyrillic,
+	"ISO_IR 13":  jisX0201,
+	"ISO_IR 192": utf8Text,
+
+	"ISO 2022 IR 87":  eucJP,
+	"ISO 2022 IR 159": eucJP,
+	"ISO 2022 IR 149": euc94(ksX1001Table),
+	"ISO 2022 IR 58":  euc94(gb2312Table),
+	"GB18030":         gb18030,
+	"GBK":             gbk,
+}
+
+// Register sets the decoder for the defined term of a character set, for
+// example "GB18030" or "ISO 2022 IR 87", replacing any previous one.
+func Register(term string, d Decoder) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[term] = d
+}
+
+func decoder(term string) (Decoder, bool) {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	if d, ok := decoders[term]; ok {
+		return d, true
+	}
+	// ISO 2022 terms share the single byte decoders
+	d, ok := decoders[strings.Replace(term, "ISO 2022 IR ", "ISO_IR ", 1)]
+	return d, ok
+}
+
+// multiByte reports whether the G0 set with the given term uses two bytes per
+// character, which can hold the delimiter bytes.
+func multiByte(term string) bool {
+	return term == "ISO 2022 IR 87" || term == "ISO 2022 IR 159"
+}
+
+// escapes maps ISO 2022 escape sequences to the defined term they designate
+// and whether they designate G1 instead of G0.
+var escapes = map[string]struct {
+	term string
+	g1   bool
+}{
+	"(B":  {"ISO 2022 IR 6", false},
+	"(J":  {"ISO 2022 IR 13", false},
+	")I":  {"ISO 2022 IR 13", true},
+	"-A":  {"ISO 2022 IR 100", true},
+	"-L":  {"ISO 2022 IR 144", true},
+	"$B":  {"ISO 2022 IR 87", false},
+	"$(D": {"ISO 2022 IR 159", false},
+	"$)C": {"ISO 2022 IR 149", true},
+	"$)A": {"ISO 2022 IR 58", true},
+}
+
+// Decode converts data encoded with the SpecificCharacterSet terms to UTF-8.
+// With ISO 2022 code extensions the character sets are reset to the first
+// term after each of the delimiters bytes, for example "^=" for person names.
+// Delimiters are only recognized while G0 is a single byte set, the bytes of
+// JIS X 0208 and 0212 characters span the same range.
+// Undecodable text is returned as Latin-1 along an error.
+func Decode(data []byte, terms []string, delimiters string) (string, error) {
+	if len(terms) == 0 {
+		terms = []string{""}
+	}
+	first := strings.TrimSpace(terms[0])
+	if len(terms) == 1 && !strings.HasPrefix(first, "ISO 2022") {
+		d, ok := decoder(first)
+		if !ok {
+			s, _ := latin1(data)
+			return s, ErrCharset
+		}
+		return d(data)
+	}
+
+	var g0, g1 string
+	reset := func() {
+		g0, g1 = "ISO 2022 IR 6", ""
+		switch first {
+		case "ISO 2022 IR 13":
+			g0, g1 = first, first
+		case "ISO 2022 IR 100", "ISO 2022 IR 144":
+			g1 = first
+		}
+	}
+	reset()
+	var b strings.Builder
+	var errs error
+	run := []byte{}
+	runTerm := ""
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		s, err := decodeRun(run, runTerm)
+		if err != nil {
+			errs = err
+		}
+		b.WriteString(s)
+		run = run[:0]
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c == 0x1b {
+			flush()
+			seq := escapeAt(data[i+1:])
+			if e, ok := escapes[seq]; ok {
+				if e.g1 {
+					g1 = e.term
+				} else {
+					g0 = e.term
+				}
+			} else {
+				errs = ErrCharset
+			}
+			i += len(seq)
+			continue
+		}
+		if !multiByte(g0) && strings.IndexByte(delimiters, c) >= 0 {
+			flush()
+			b.WriteByte(c)
+			reset()
+			continue
+		}
+		term := g0
+		if c >= 0x80 {
+			term = g1
+		}
+		if term != runTerm {
+			flush()
+			runTerm = term
+		}
+		run = append(run, c)
+	}
+	flush()
+	return b.String(), errs
+}
+
+// escapeAt returns the escape sequence at the start of b, without ESC.
+func escapeAt(b []byte) string {
+	for n := 0; n < len(b) && n < 3; n++ {
+		// Final bytes are in 0x40-0x7E
+		if b[n] >= 0x40 {
+			return string(b[:n+1])
+		}
+	}
+	return string(b)
+}
+
+func decodeRun(run []byte, term string) (string, error) {
+	switch term {
+	case "ISO 2022 IR 87", "ISO 2022 IR 159":
+		// 7 bit G0 bytes to EUC-JP
+		euc := make([]byte, len(run))
+		for i, c := range run {
+			euc[i] = c | 0x80
+		}
+		if term == "ISO 2022 IR 159" {
+			euc = supplementary(euc)
+		}
+		run = euc
+	case "ISO 2022 IR 13":
+		return jisX0201(run)
+	case "ISO 2022 IR 6":
+		return ascii(run)
+	}
+	d, ok := decoder(term)
+	if !ok {
+		s, _ := latin1(run)
+		return s, ErrCharset
+	}
+	return d(run)
+}
+
+// supplementary prefixes JIS X 0212 pairs with the EUC-JP SS3 byte.
+func supplementary(b []byte) []byte {
+	out := []byte{}
+	for i := 0; i+1 < len(b); i += 2 {
+		out = append(out, 0x8f, b[i], b[i+1])
+	}
+	return out
+}
+
+func ascii(data []byte) (string, error) {
+	return string(data), nil
+}
+
+func utf8Text(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return strings.ToValidUTF8(string(data), "�"), ErrCharset
+	}
+	return string(data), nil
+}
+
+// latin1 - ISO 8859-1, every byte is its own code point.
+func latin1(data []byte) (string, error) {
+	r := make([]rune, len(data))
+	for i, c := range data {
+		r[i] = rune(c)
+	}
+	return string(r), nil
+}
+
+// cyrillic - ISO 8859-5.
+func cyrillic(data []byte) (string, error) {
+	r := make([]rune, len(data))
+	for i, c := range data {
+		switch {
+		case c < 0xa1 || c == 0xad:
+			r[i] = rune(c)
+		case c == 0xf0:
+			r[i] = '№'
+		case c == 0xfd:
+			r[i] = '§'
+		default:
+			r[i] = rune(c) - 0xa0 + 0x400
+		}
+	}
+	return string(r), nil
+}
+
+// jisX0201 - JIS X 0201 romaji and half width katakana.
+func jisX0201(data []byte) (string, error) {
+	r := make([]rune, len(data))
+	for i, c := range data {
+		switch {
+		case c == 0x5c:
+			r[i] = '¥'
+		case c == 0x7e:
+			r[i] = '‾'
+		case c >= 0xa1 && c <= 0xdf:
+			r[i] = rune(c) - 0xa1 + 0xff61
+		default:
+			r[i] = rune(c)
+		}
+	}
+	return string(r), nil
+}
@@ -0,0 +1,102 @@
+package charset
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecode(t *testing.T) {
+	s, err := Decode([]byte("Buc\xe9phale"), []string{"ISO_IR 100"}, "")
+	if err != nil || s != "Bucéphale" {
+		t.Errorf("Wrong Latin-1 text: %s, %v", s, err)
+	}
+	s, err = Decode([]byte("\xbb\xee\xe1\xd5\xd9"), []string{"ISO_IR 144"}, "")
+	if err != nil || s != "Люсей" {
+		t.Errorf("Wrong Cyrillic text: %s, %v", s, err)
+	}
+	_, err = Decode([]byte("abc"), []string{"ISO_IR 999"}, "")
+	if err != ErrCharset {
+		t.Errorf("Expected ErrCharset, got %v", err)
+	}
+
+	// PS3.5 H.3.2
+	data := []byte("Yamada^Tarou=\x1b$B;3ED\x1b(B^\x1b$BB@O:\x1b(B=\x1b$B$d$^$@\x1b(B^\x1b$B$?$m$&\x1b(B")
+	s, err = Decode(data, []string{"", "ISO 2022 IR 87"}, "^=\\")
+	if err != nil || s != "Yamada^Tarou=山田^太郎=やまだ^たろう" {
+		t.Errorf("Wrong ISO 2022 text: %q, %v", s, err)
+	}
+
+	// Kanji bytes matching the delimiters aren't split
+	s, err = Decode([]byte("\x1b$B;=\x1b(B^A"), []string{"", "ISO 2022 IR 87"}, "^=\\")
+	if err != nil || s != "蚕^A" {
+		t.Errorf("Wrong kanji with delimiter bytes: %q, %v", s, err)
+	}
+
+	// Katakana on G1 with ISO 2022 IR 13 as default
+	s, err = Decode([]byte("\xd4\xcf\xc0\xde"), []string{"ISO 2022 IR 13", "ISO 2022 IR 87"}, "^=")
+	if err != nil || s != "ﾔﾏﾀﾞ" {
+		t.Errorf("Wrong katakana text: %s, %v", s, err)
+	}
+
+	for _, c := range []struct {
+		data     string
+		terms    []string
+		expected string
+	}{
+		// PS3.5 I.2
+		{"Hong^Gildong=\x1b$)C\xfb\xf3^\x1b$)C\xd1\xce\xd4\xd7=\x1b$)C\xc8\xab^\x1b$)C\xb1\xe6\xb5\xbf", []string{"", "ISO 2022 IR 149"}, "Hong^Gildong=洪^吉洞=홍^길동"},
+		// PS3.5 K.2
+		{"Zhang^XiaoDong=\x1b$)A\xd5\xc5^\x1b$)A\xd0\xa1\xb6\xab=", []string{"", "ISO 2022 IR 58"}, "Zhang^XiaoDong=张^小东="},
+		// PS3.5 J.3
+		{"Wang^XiaoDong=\xcd\xf5^\xd0\xa1\xb6\xab=", []string{"GB18030"}, "Wang^XiaoDong=王^小东="},
+		{"\xcd\xf5^\xd0\xa1\xb6\xab", []string{"GBK"}, "王^小东"},
+		{"\x81\x30\x8a\x32\x94\x39\xfc\x36", []string{"GB18030"}, "å\U0001f600"},
+		{"\x1b$(D0!\x1b(B", []string{"", "ISO 2022 IR 159"}, "丂"},
+	} {
+		s, err := Decode([]byte(c.data), c.terms, "^=\\")
+		if err != nil || s != c.expected {
+			t.Errorf("%v: expected %q, got %q, %v", c.terms, c.expected, s, err)
+		}
+	}
+	s, err = Decode([]byte("\xcd"), []string{"GB18030"}, "")
+	if err != ErrCharset || s != "\ufffd" {
+		t.Errorf("Expected ErrCharset for a truncated character, got %q, %v", s, err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	previous, ok := decoder("ISO_IR 101")
+	t.Cleanup(func() {
+		decodersMu.Lock()
+		defer decodersMu.Unlock()
+		if ok {
+			decoders["ISO_IR 101"] = previous
+		} else {
+			delete(decoders, "ISO_IR 101")
+		}
+	})
+	Register("ISO_IR 101", func(b []byte) (string, error) { return strings.ToUpper(string(b)), nil })
+	s, err := Decode([]byte("abc"), []string{"ISO_IR 101"}, "")
+	if err != nil || s != "ABC" {
+		t.Errorf("Registered decoder not used: %q, %v", s, err)
+	}
+}
+
+func TestTables(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		table  string
+		length int
+	}{
+		{"jisX0208", jisX0208, 94 * 94},
+		{"jisX0212", jisX0212, 94 * 94},
+		{"ksX1001", ksX1001, 94 * 94},
+		{"gb2312", gb2312, 94 * 94},
+		{"gb18030Two", gb18030Two, 126 * 190},
+	} {
+		if n := utf8.RuneCountInString(c.table); n != c.length {
+			t.Errorf("%s: expected %d characters, got %d", c.name, c.length, n)
+		}
+	}
+}
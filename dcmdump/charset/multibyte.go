@@ -0,0 +1,155 @@
+package charset
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// runeTable - Characters of a table string, expanded on first use.
+type runeTable struct {
+	s     string
+	once  sync.Once
+	runes []rune
+}
+
+func (t *runeTable) at(i int) rune {
+	t.once.Do(func() { t.runes = []rune(t.s) })
+	if i < 0 || i >= len(t.runes) {
+		return utf8.RuneError
+	}
+	return t.runes[i]
+}
+
+var (
+	jisX0208Table   = &runeTable{s: jisX0208}
+	jisX0212Table   = &runeTable{s: jisX0212}
+	ksX1001Table    = &runeTable{s: ksX1001}
+	gb2312Table     = &runeTable{s: gb2312}
+	gb18030TwoTable = &runeTable{s: gb18030Two}
+)
+
+// inRange reports whether the byte at i of data is within lo and hi.
+func inRange(data []byte, i int, lo, hi byte) bool {
+	return i < len(data) && data[i] >= lo && data[i] <= hi
+}
+
+// euc94 returns the decoder of a 94x94 character set with its bytes in
+// 0xA1-0xFE, as used by EUC-KR and EUC-CN. Bytes below 0x80 are ASCII.
+func euc94(t *runeTable) Decoder {
+	return func(data []byte) (string, error) {
+		var b strings.Builder
+		var err error
+		for i := 0; i < len(data); i++ {
+			c := data[i]
+			if c < 0x80 {
+				b.WriteByte(c)
+				continue
+			}
+			r := utf8.RuneError
+			if inRange(data, i, 0xa1, 0xfe) && inRange(data, i+1, 0xa1, 0xfe) {
+				r = t.at(int(c-0xa1)*94 + int(data[i+1]-0xa1))
+				i++
+			}
+			if r == utf8.RuneError {
+				err = ErrCharset
+			}
+			b.WriteRune(r)
+		}
+		return b.String(), err
+	}
+}
+
+// eucJP decodes EUC-JP: JIS X 0208 pairs, JIS X 0212 pairs after the SS3
+// byte 0x8F and half width katakana after the SS2 byte 0x8E.
+func eucJP(data []byte) (string, error) {
+	var b strings.Builder
+	var err error
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < 0x80 {
+			b.WriteByte(c)
+			continue
+		}
+		r := utf8.RuneError
+		switch {
+		case c == 0x8e && inRange(data, i+1, 0xa1, 0xdf):
+			r = rune(data[i+1]) - 0xa1 + 0xff61
+			i++
+		case c == 0x8f && inRange(data, i+1, 0xa1, 0xfe) && inRange(data, i+2, 0xa1, 0xfe):
+			r = jisX0212Table.at(int(data[i+1]-0xa1)*94 + int(data[i+2]-0xa1))
+			i += 2
+		case inRange(data, i, 0xa1, 0xfe) && inRange(data, i+1, 0xa1, 0xfe):
+			r = jisX0208Table.at(int(c-0xa1)*94 + int(data[i+1]-0xa1))
+			i++
+		}
+		if r == utf8.RuneError {
+			err = ErrCharset
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), err
+}
+
+func gb18030(data []byte) (string, error) {
+	return decodeGB(data, true)
+}
+
+// gbk decodes GBK as the two byte subset of GB18030.
+func gbk(data []byte) (string, error) {
+	return decodeGB(data, false)
+}
+
+// decodeGB decodes the one and two byte characters of GB18030, and its four
+// byte characters when fourByte is set.
+func decodeGB(data []byte, fourByte bool) (string, error) {
+	var b strings.Builder
+	var err error
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c < 0x80 {
+			b.WriteByte(c)
+			continue
+		}
+		r := utf8.RuneError
+		switch {
+		case !inRange(data, i, 0x81, 0xfe):
+		case fourByte && inRange(data, i+1, 0x30, 0x39):
+			if inRange(data, i+2, 0x81, 0xfe) && inRange(data, i+3, 0x30, 0x39) {
+				p := ((int(c-0x81)*10+int(data[i+1]-0x30))*126+int(data[i+2]-0x81))*10 + int(data[i+3]-0x30)
+				r = gb18030Four(p)
+				i += 3
+			}
+		case inRange(data, i+1, 0x40, 0x7e):
+			r = gb18030TwoTable.at(int(c-0x81)*190 + int(data[i+1]-0x40))
+			i++
+		case inRange(data, i+1, 0x80, 0xfe):
+			r = gb18030TwoTable.at(int(c-0x81)*190 + int(data[i+1]-0x41))
+			i++
+		}
+		if r == utf8.RuneError {
+			err = ErrCharset
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), err
+}
+
+// gb18030Four returns the character of the pointer of a four byte GB18030
+// sequence, the BMP characters missing from the two byte table map by ranges
+// and the others linearly from 0x90308130.
+func gb18030Four(p int) rune {
+	const supplementary = 189000
+	switch {
+	case p >= supplementary && p < supplementary+0x100000:
+		return rune(0x10000 + p - supplementary)
+	case p > 39419:
+		return utf8.RuneError
+	}
+	i := sort.Search(len(gb18030Ranges), func(i int) bool { return gb18030Ranges[i][0] > uint32(p) }) - 1
+	if i < 0 {
+		return utf8.RuneError
+	}
+	return rune(gb18030Ranges[i][1] + uint32(p) - gb18030Ranges[i][0])
+}
@@ -0,0 +1,135 @@
+package dcmdump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Coding scheme designators of the well-known coding schemes.
+const (
+	SchemeDCM    = "DCM"
+	SchemeSNOMED = "SCT"
+	// SchemeSNOMEDRT is the retired SNOMED RT designator, its codes differ
+	// from the SNOMED CT ones.
+	SchemeSNOMEDRT = "SRT"
+	SchemeLOINC    = "LN"
+	SchemeUCUM     = "UCUM"
+)
+
+// Well-known codes used by structured reports and modality workflows.
+var (
+	CodeFinding             = Code{Value: "121071", Scheme: SchemeDCM, Meaning: "Finding"}
+	CodeImagingMeasurements = Code{Value: "126010", Scheme: SchemeDCM, Meaning: "Imaging Measurements"}
+	CodeImageLibrary        = Code{Value: "111028", Scheme: SchemeDCM, Meaning: "Image Library"}
+	CodeTrackingIdentifier  = Code{Value: "112039", Scheme: SchemeDCM, Meaning: "Tracking Identifier"}
+	CodeFindingSite         = Code{Value: "363698007", Scheme: SchemeSNOMED, Meaning: "Finding Site"}
+	CodeDiameter            = Code{Value: "81827009", Scheme: SchemeSNOMED, Meaning: "Diameter"}
+	CodeImagingReport       = Code{Value: "18748-4", Scheme: SchemeLOINC, Meaning: "Diagnostic imaging report"}
+	CodeMillimeter          = Code{Value: "mm", Scheme: SchemeUCUM, Meaning: "millimeter"}
+)
+
+// Code - Coded concept of a code sequence item.
+type Code struct {
+	// Value is the CodeValue (0008,0100), or the LongCodeValue (0008,0119) or
+	// URNCodeValue (0008,0120) when it doesn't fit.
+	Value         string
+	Scheme        string
+	SchemeVersion string
+	Meaning       string
+}
+
+// String returns the code as (Value, Scheme, "Meaning").
+func (c Code) String() string {
+	return fmt.Sprintf("(%s, %s, %q)", c.Value, c.Scheme, c.Meaning)
+}
+
+// Equal reports whether both codes have the same value and scheme, the
+// meaning is only descriptive.
+func (c Code) Equal(o Code) bool {
+	return c.Value == o.Value && c.Scheme == o.Scheme
+}
+
+// Codes returns the code of each item of a code sequence.
+func (de *DataElement) Codes() ([]Code, error) {
+	if de.VRStr != "SQ" {
+		return nil, ErrValueType
+	}
+	codes := []Code{}
+	for _, item := range de.Items {
+		c := Code{}
+		for i := range item.Elements {
+			e := &item.Elements[i]
+			switch e.TagStr {
+			case "00080100":
+				c.Value = e.trimmedValue()
+			case "00080119", "00080120":
+				if c.Value == "" {
+					c.Value = e.trimmedValue()
+				}
+			case "00080102":
+				c.Scheme = e.trimmedValue()
+			case "00080103":
+				c.SchemeVersion = e.trimmedValue()
+			case "00080104":
+				c.Meaning = e.trimmedValue()
+			}
+		}
+		codes = append(codes, c)
+	}
+	return codes, nil
+}
+
+// Codes returns the codes of the top level code sequence with the given tag.
+func (df *DicomFile) Codes(tagStr string) ([]Code, error) {
+	de, err := df.LookupElement(tagStr)
+	if err != nil {
+		return nil, ErrNoElement
+	}
+	return de.Codes()
+}
+
+// SetCodes inserts or replaces the top level code sequence with the given
+// tag, with an item for each code.
+// Values longer than 16 characters are set as LongCodeValue, or as
+// URNCodeValue for URNs.
+func (df *DicomFile) SetCodes(tagStr string, codes ...Code) error {
+	items := []Item{}
+	for _, c := range codes {
+		item, err := codeItem(c)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	return df.SetElement(tagStr, "SQ", items)
+}
+
+// codeItem returns the code sequence item of c.
+func codeItem(c Code) (Item, error) {
+	short, long, urn := c.Value, "", ""
+	if len(c.Value) > 16 && strings.HasPrefix(c.Value, "urn:") {
+		short, urn = "", c.Value
+	} else if len(c.Value) > 16 {
+		short, long = "", c.Value
+	}
+	item := Item{}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00080100", "SH", short},
+		{"00080102", "SH", c.Scheme},
+		{"00080103", "SH", c.SchemeVersion},
+		{"00080104", "LO", c.Meaning},
+		{"00080119", "UC", long},
+		{"00080120", "UR", urn},
+	} {
+		if e.value == "" && e.tag != "00080102" && e.tag != "00080104" {
+			continue
+		}
+		de, err := NewDataElement(e.tag, e.vr, e.value)
+		if err != nil {
+			return item, err
+		}
+		de.PartOfSQ = true
+		item.Elements = append(item.Elements, de)
+	}
+	return item, nil
+}
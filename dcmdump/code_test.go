@@ -0,0 +1,35 @@
+package dcmdump
+
+import "testing"
+
+func TestCodes(t *testing.T) {
+	long := Code{Value: "1.2.840.10008.6.1.1234567", Scheme: "99TEST", Meaning: "Long"}
+	urn := Code{Value: "urn:oid:1.2.840.10008.6.1", Scheme: "99TEST", Meaning: "URN"}
+	df := &DicomFile{}
+	err := df.SetCodes("0040A043", CodeFindingSite, long, urn)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	de, _ := df.Lookup("0040A043")
+	if len(de.Items) != 3 || de.Items[1].Elements[2].TagStr != "00080119" || de.Items[2].Elements[2].TagStr != "00080120" {
+		t.Fatalf("Wrong items: %v", de.Items)
+	}
+	codes, err := df.Codes("0040A043")
+	if err != nil || len(codes) != 3 {
+		t.Fatalf("Wrong codes: %v, %v", codes, err)
+	}
+	for i, expected := range []Code{CodeFindingSite, long, urn} {
+		if codes[i] != expected {
+			t.Errorf("Expected %s, got %s", expected, codes[i])
+		}
+	}
+	if !codes[0].Equal(Code{Value: "363698007", Scheme: "SCT", Meaning: "Site"}) || codes[0].Equal(CodeDiameter) {
+		t.Errorf("Wrong code comparison")
+	}
+	if s := CodeMillimeter.String(); s != `(mm, UCUM, "millimeter")` {
+		t.Errorf("Wrong code string: %s", s)
+	}
+	if _, err = df.Codes("00100010"); err != ErrNoElement {
+		t.Errorf("Expected ErrNoElement, got %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package dcmdump
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump/rle"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// ErrPixelFormat is returned when decoding native frames with a sample layout
+// that has no matching image type.
+var ErrPixelFormat = errors.New("Unsupported pixel format")
+
+// Decoder decodes the data of a frame into an image.
+// pi describes the image the frame belongs to.
+type Decoder func(data []byte, pi *PixelDataInfo) (image.Image, error)
+
+var codecsMu sync.RWMutex
+var codecs = map[string]Decoder{
+	ts.ImplicitVRLittleEndian:         decodeNative,
+	ts.ExplicitVRLittleEndian:         decodeNative,
+	ts.DeflatedExplicitVRLittleEndian: decodeNative,
+	ts.ExplicitVRBigEndian:            decodeNative,
+	ts.RLELossless:                    decodeRLE,
+	ts.JPEGBaseline:                   decodeJPEG,
+}
+
+// RegisterCodec sets the decoder used for frames with the given transfer
+// syntax, replacing any previous one.
+// It allows plugging in external libraries for JPEG Lossless, JPEG-LS or
+// JPEG 2000.
+func RegisterCodec(transferSyntax string, d Decoder) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[transferSyntax] = d
+}
+
+// Decode returns the frame as an image using the decoder registered for its
+// transfer syntax.
+// Native frames are returned with their stored values, image.Gray or
+// image.Gray16 for monochrome and image.RGBA for color.
+func (f *Frame) Decode() (image.Image, error) {
+	codecsMu.RLock()
+	d, ok := codecs[f.info.TransferSyntax]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, ErrUnsupportedTS
+	}
+	return d(f.Data, f.info)
+}
+
+func decodeJPEG(data []byte, pi *PixelDataInfo) (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(data))
+}
+
+func decodeRLE(data []byte, pi *PixelDataInfo) (image.Image, error) {
+	native, err := rle.Decode(data, pi.Rows, pi.Columns, pi.SamplesPerPixel, pi.BitsAllocated)
+	if err != nil {
+		return nil, err
+	}
+	// RLE frames are decoded little endian and by pixel
+	n := *pi
+	n.TransferSyntax = ts.ExplicitVRLittleEndian
+	n.PlanarConfiguration = 0
+	return decodeNative(native, &n)
+}
+
+// decodeNative converts native pixel data to an image.
+func decodeNative(data []byte, pi *PixelDataInfo) (image.Image, error) {
+	if size := pi.FrameSize(); size == 0 || len(data) < size {
+		return nil, ErrFrameIndex
+	}
+	rect := image.Rect(0, 0, pi.Columns, pi.Rows)
+	pixels := pi.Rows * pi.Columns
+	switch {
+	case pi.SamplesPerPixel == 1 && pi.BitsAllocated == 8:
+		img := image.NewGray(rect)
+		copy(img.Pix, data)
+		return img, nil
+	case pi.SamplesPerPixel == 1 && pi.BitsAllocated == 16:
+		img := image.NewGray16(rect)
+		order := ts.ByteOrder(pi.TransferSyntax)
+		for p := 0; p < pixels; p++ {
+			v := order.Uint16(data[2*p:])
+			img.Pix[2*p] = byte(v >> 8)
+			img.Pix[2*p+1] = byte(v)
+		}
+		return img, nil
+	case pi.SamplesPerPixel == 3 && pi.BitsAllocated == 8 && pi.subsampled():
+		// Y1 Y2 Cb Cr per pair of pixels, upsampled to YBR_FULL
+		img := image.NewRGBA(rect)
+		for p := 0; p < pixels; p++ {
+			pair := 4 * (p / 2)
+			img.Pix[4*p] = data[pair+p%2]
+			img.Pix[4*p+1] = data[pair+2]
+			img.Pix[4*p+2] = data[pair+3]
+			img.Pix[4*p+3] = 0xff
+		}
+		return img, nil
+	case pi.SamplesPerPixel == 3 && pi.BitsAllocated == 8:
+		img := image.NewRGBA(rect)
+		for p := 0; p < pixels; p++ {
+			for c := 0; c < 3; c++ {
+				if pi.PlanarConfiguration == 1 {
+					img.Pix[4*p+c] = data[c*pixels+p]
+				} else {
+					img.Pix[4*p+c] = data[3*p+c]
+				}
+			}
+			img.Pix[4*p+3] = 0xff
+		}
+		return img, nil
+	}
+	return nil, ErrPixelFormat
+}
@@ -0,0 +1,207 @@
+package dcmdump
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+)
+
+// ErrNoPalette is returned for files without Palette Color lookup tables.
+var ErrNoPalette = errors.New("No Palette Color LUT in file")
+
+// Photometric interpretations
+const (
+	PhotometricMonochrome1  = "MONOCHROME1"
+	PhotometricMonochrome2  = "MONOCHROME2"
+	PhotometricPaletteColor = "PALETTE COLOR"
+	PhotometricRGB          = "RGB"
+	PhotometricYBRFull      = "YBR_FULL"
+	PhotometricYBRFull422   = "YBR_FULL_422"
+)
+
+// Palette - Red, Green and Blue Palette Color lookup tables.
+type Palette struct {
+	Red, Green, Blue *LUT
+}
+
+// Palette returns the Palette Color lookup tables of the file, in their
+// normal or segmented form.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.7.9.html
+func (df *DicomFile) Palette() (*Palette, error) {
+	signed := df.stringValue("00280103") == "1"
+	luts := make([]*LUT, 3)
+	for i, tags := range [][3]string{
+		{"00281101", "00281201", "00281221"},
+		{"00281102", "00281202", "00281222"},
+		{"00281103", "00281203", "00281223"},
+	} {
+		descriptor, err := df.LookupElement(tags[0])
+		if err != nil {
+			return nil, ErrNoPalette
+		}
+		if data, err := df.LookupElement(tags[1]); err == nil {
+			luts[i], err = newLUT(descriptor, data, signed)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		segmented, err := df.LookupElement(tags[2])
+		if err != nil {
+			return nil, ErrNoPalette
+		}
+		luts[i], err = newSegmentedLUT(descriptor, segmented, signed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Palette{Red: luts[0], Green: luts[1], Blue: luts[2]}, nil
+}
+
+// newSegmentedLUT expands the discrete and linear segments of a Segmented
+// Palette Color LUT Data element, indirect segments are not supported.
+func newSegmentedLUT(descriptor, segmented *DataElement, signed bool) (*LUT, error) {
+	words := segmented.Ints()
+	data := []int{}
+	for n := 0; n+2 <= len(words); {
+		op, length := words[n], words[n+1]
+		n += 2
+		switch op {
+		case 0:
+			if n+length > len(words) {
+				return nil, ErrLUT
+			}
+			data = append(data, words[n:n+length]...)
+			n += length
+		case 1:
+			if len(data) == 0 || n >= len(words) {
+				return nil, ErrLUT
+			}
+			y0, y1 := data[len(data)-1], words[n]
+			for i := 1; i <= length; i++ {
+				data = append(data, y0+(y1-y0)*i/length)
+			}
+			n++
+		default:
+			return nil, ErrLUT
+		}
+	}
+	expanded := &DataElement{VRStr: "US", ByteOrder: segmented.ByteOrder}
+	for _, v := range data {
+		expanded.Data = append(expanded.Data, 0, 0)
+		expanded.order().PutUint16(expanded.Data[len(expanded.Data)-2:], uint16(v))
+	}
+	return newLUT(descriptor, expanded, signed)
+}
+
+// Apply returns the colors the stored values of a decoded PALETTE COLOR frame
+// map to.
+func (p *Palette) Apply(img image.Image, pi *PixelDataInfo) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := int(storedValue(img.At(x, y), pi))
+			out.Pix[i] = paletteByte(p.Red, v)
+			out.Pix[i+1] = paletteByte(p.Green, v)
+			out.Pix[i+2] = paletteByte(p.Blue, v)
+			out.Pix[i+3] = 0xff
+			i += 4
+		}
+	}
+	return out
+}
+
+// paletteByte returns the 8 bit intensity of the entry v maps to.
+func paletteByte(l *LUT, v int) byte {
+	e := l.Value(v)
+	if l.Bits <= 8 {
+		return byte(e)
+	}
+	return byte(e >> 8)
+}
+
+// YBRToRGB converts in place YBR_FULL samples decoded in the R, G and B
+// channels of img to RGB.
+func YBRToRGB(img *image.RGBA) *image.RGBA {
+	for p := 0; p+3 < len(img.Pix); p += 4 {
+		r, g, b := color.YCbCrToRGB(img.Pix[p], img.Pix[p+1], img.Pix[p+2])
+		img.Pix[p], img.Pix[p+1], img.Pix[p+2] = r, g, b
+	}
+	return img
+}
+
+// RGBToYBR converts in place RGB colors to YBR_FULL samples stored in the R,
+// G and B channels of img, as encoded in native YBR_FULL pixel data.
+func RGBToYBR(img *image.RGBA) *image.RGBA {
+	for p := 0; p+3 < len(img.Pix); p += 4 {
+		y, cb, cr := color.RGBToYCbCr(img.Pix[p], img.Pix[p+1], img.Pix[p+2])
+		img.Pix[p], img.Pix[p+1], img.Pix[p+2] = y, cb, cr
+	}
+	return img
+}
+
+// ColorFrame returns frame i, starting at 0, as RGB whatever its
+// photometric interpretation: RGB, YBR_FULL and YBR_FULL_422 samples or
+// PALETTE COLOR values.
+// The ICC profile of the file is not applied, see ICCProfile.
+func (df *DicomFile) ColorFrame(i int) (*image.RGBA, error) {
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return nil, err
+	}
+	frame, err := pi.Frame(i)
+	if err != nil {
+		return nil, err
+	}
+	img, err := frame.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return df.colorImage(img, pi)
+}
+
+// colorImage converts a decoded frame to RGB.
+func (df *DicomFile) colorImage(img image.Image, pi *PixelDataInfo) (*image.RGBA, error) {
+	if pi.PhotometricInterpretation == PhotometricPaletteColor {
+		p, err := df.Palette()
+		if err != nil {
+			return nil, err
+		}
+		return p.Apply(img, pi), nil
+	}
+	if pi.SamplesPerPixel != 3 {
+		return nil, ErrPixelFormat
+	}
+	// JPEG decoders already convert from YCbCr
+	if rgba, ok := img.(*image.RGBA); ok && strings.HasPrefix(pi.PhotometricInterpretation, PhotometricYBRFull) {
+		img = YBRToRGB(rgba)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba, nil
+}
+
+// ICCProfile returns the ICC Profile (0028,2000) of the file, or of its first
+// optical path for whole slide images, nil when it has none.
+// Colors can be matched with a color management library using it.
+func (df *DicomFile) ICCProfile() []byte {
+	if de, err := df.LookupElement("00282000"); err == nil && de.Load() == nil {
+		return de.Data
+	}
+	de, err := df.LookupElement("00480105")
+	if err != nil {
+		return nil
+	}
+	for _, item := range de.Items {
+		for _, e := range item.Elements {
+			if e.TagStr == "00282000" {
+				return e.Data
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package dcmdump
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// Kinds of Difference
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// CompareOptions - Elements ignored by Compare.
+type CompareOptions struct {
+	// IgnoreUIDs skips the UI elements.
+	IgnoreUIDs bool
+	// IgnoreDates skips the DA, DT and TM elements.
+	IgnoreDates bool
+	// IgnoreMeta skips the file meta elements, group 0002.
+	IgnoreMeta bool
+	// IgnoreTags are skipped at any level, given as GGGGEEEE tags or
+	// keywords.
+	IgnoreTags []string
+}
+
+// Difference - Element added, removed or changed from a to b.
+type Difference struct {
+	Kind string
+	// Path of the element, as in ReferencedSeriesSequence[0].SeriesInstanceUID,
+	// with (gggg,eeee) tags for elements without a keyword. Items added or
+	// removed from a sequence end with their index.
+	Path string
+	// A and B are nil for added and removed elements, and for items.
+	A, B *DataElement
+}
+
+// String returns the difference with the values of the elements shortened.
+func (d Difference) String() string {
+	switch {
+	case d.Kind == DiffChanged:
+		return fmt.Sprintf("%s %s: %s -> %s", d.Kind, d.Path, diffValue(d.A), diffValue(d.B))
+	case d.A != nil:
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Path, diffValue(d.A))
+	case d.B != nil:
+		return fmt.Sprintf("%s %s: %s", d.Kind, d.Path, diffValue(d.B))
+	}
+	return fmt.Sprintf("%s %s", d.Kind, d.Path)
+}
+
+// diffValue returns the VR and values of de, shortened to 64 characters.
+func diffValue(de *DataElement) string {
+	if de.VRStr == "SQ" {
+		return fmt.Sprintf("SQ %d items", len(de.Items))
+	}
+	v := strings.Join(de.Strings(), `\`)
+	if len(v) > 64 {
+		v = v[:61] + "..."
+	}
+	return fmt.Sprintf("%s [%s]", de.VRStr, v)
+}
+
+// Compare returns the differences between the elements of a and b in tag
+// order, sequences are compared item by item.
+// Values are compared as decoded, so byte order and padding differences
+// aren't reported, VR changes are.
+func Compare(a, b *DicomFile, o CompareOptions) []Difference {
+	ignored := map[string]bool{}
+	for _, t := range o.IgnoreTags {
+		if info, ok := tag.ByKeyword(t); ok {
+			t = info.TagStr()
+		}
+		ignored[strings.ToUpper(t)] = true
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if b != a {
+		b.mu.RLock()
+		defer b.mu.RUnlock()
+	}
+	c := &comparison{o: o, ignored: ignored}
+	c.elements("", a.Elements, b.Elements)
+	return c.diffs
+}
+
+type comparison struct {
+	o       CompareOptions
+	ignored map[string]bool
+	diffs   []Difference
+}
+
+func (c *comparison) skip(de *DataElement) bool {
+	switch {
+	case c.ignored[de.TagStr]:
+	case c.o.IgnoreMeta && strings.HasPrefix(de.TagStr, "0002"):
+	case c.o.IgnoreUIDs && de.VRStr == "UI":
+	case c.o.IgnoreDates && (de.VRStr == "DA" || de.VRStr == "DT" || de.VRStr == "TM"):
+	default:
+		return false
+	}
+	return true
+}
+
+// elements merges the elements of a and b sorted by tag.
+func (c *comparison) elements(prefix string, a, b []DataElement) {
+	sorted := func(elements []DataElement) []*DataElement {
+		s := []*DataElement{}
+		for i := range elements {
+			if !c.skip(&elements[i]) {
+				s = append(s, &elements[i])
+			}
+		}
+		sort.SliceStable(s, func(i, j int) bool { return s[i].TagStr < s[j].TagStr })
+		return s
+	}
+	sa, sb := sorted(a), sorted(b)
+	for i, j := 0, 0; i < len(sa) || j < len(sb); {
+		switch {
+		case j == len(sb) || i < len(sa) && sa[i].TagStr < sb[j].TagStr:
+			c.diffs = append(c.diffs, Difference{Kind: DiffRemoved, Path: prefix + elementName(sa[i]), A: sa[i]})
+			i++
+		case i == len(sa) || sb[j].TagStr < sa[i].TagStr:
+			c.diffs = append(c.diffs, Difference{Kind: DiffAdded, Path: prefix + elementName(sb[j]), B: sb[j]})
+			j++
+		default:
+			c.element(prefix, sa[i], sb[j])
+			i++
+			j++
+		}
+	}
+}
+
+func (c *comparison) element(prefix string, a, b *DataElement) {
+	path := prefix + elementName(a)
+	if a.VRStr == "SQ" && b.VRStr == "SQ" {
+		for n := 0; n < len(a.Items) || n < len(b.Items); n++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, n)
+			switch {
+			case n >= len(b.Items):
+				c.diffs = append(c.diffs, Difference{Kind: DiffRemoved, Path: itemPath})
+			case n >= len(a.Items):
+				c.diffs = append(c.diffs, Difference{Kind: DiffAdded, Path: itemPath})
+			default:
+				c.elements(itemPath+".", a.Items[n].Elements, b.Items[n].Elements)
+			}
+		}
+		return
+	}
+	if !sameValue(a, b) {
+		c.diffs = append(c.diffs, Difference{Kind: DiffChanged, Path: path, A: a, B: b})
+	}
+}
+
+// sameValue reports whether a and b hold the same VR and values.
+func sameValue(a, b *DataElement) bool {
+	if a.VRStr != b.VRStr {
+		return false
+	}
+	// Deferred values are loaded in copies, the files are only read locked
+	ca, cb := *a, *b
+	a, b = &ca, &cb
+	a.Load()
+	b.Load()
+	switch a.VRStr {
+	case "OB", "UN":
+		return bytes.Equal(a.Data, b.Data)
+	}
+	if a.order() == b.order() && bytes.Equal(a.Data, b.Data) {
+		return true
+	}
+	va, vb := a.Strings(), b.Strings()
+	if len(va) != len(vb) {
+		return false
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// elementName returns the keyword of de, or its tag as (gggg,eeee) for
+// private and unknown tags.
+func elementName(de *DataElement) string {
+	if name := tag.Tag[de.TagStr]["name"]; name != "" {
+		return name
+	}
+	return fmt.Sprintf("(%s,%s)", de.TagStr[:4], de.TagStr[4:])
+}
@@ -0,0 +1,58 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	item := func(uid string) Item {
+		de, err := NewDataElement("0020000E", "UI", uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		return Item{Elements: []DataElement{de}}
+	}
+	a := &DicomFile{}
+	a.SetElement("00080020", "DA", "20200101")
+	a.SetElement("00081115", "SQ", []Item{item("1.2.1"), item("1.2.2")})
+	a.SetElement("00100010", "PN", "DOE^JOHN")
+	a.SetElement("00100020", "LO", "ID1")
+	a.SetElement("00280010", "US", 512)
+	b := &DicomFile{}
+	b.SetElement("00080020", "DA", "20200102")
+	b.SetElement("00081115", "SQ", []Item{item("1.2.9")})
+	b.SetElement("00100010", "PN", "DOE^J")
+	b.SetElement("00100030", "DA", "19700101")
+	b.SetElement("00280010", "US", 512)
+	// Same value in big endian
+	for i := range b.Elements {
+		if b.Elements[i].TagStr == "00280010" {
+			b.Elements[i].ByteOrder = binary.BigEndian
+			binary.BigEndian.PutUint16(b.Elements[i].Data, 512)
+		}
+	}
+
+	expected := []string{
+		"changed StudyDate: DA [20200101] -> DA [20200102]",
+		"changed ReferencedSeriesSequence[0].SeriesInstanceUID: UI [1.2.1] -> UI [1.2.9]",
+		"removed ReferencedSeriesSequence[1]",
+		"changed PatientName: PN [DOE^JOHN] -> PN [DOE^J]",
+		"removed PatientID: LO [ID1]",
+		"added PatientBirthDate: DA [19700101]",
+	}
+	diffs := Compare(a, b, CompareOptions{})
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d differences, got %v", len(expected), diffs)
+	}
+	for i, d := range diffs {
+		if d.String() != expected[i] {
+			t.Errorf("Expected %s, got %s", expected[i], d)
+		}
+	}
+	diffs = Compare(a, b, CompareOptions{IgnoreUIDs: true, IgnoreDates: true, IgnoreTags: []string{"PatientName", "00100020"}})
+	if len(diffs) != 1 || diffs[0].Path != "ReferencedSeriesSequence[1]" {
+		t.Errorf("Wrong differences ignoring UIDs, dates and tags: %v", diffs)
+	}
+}
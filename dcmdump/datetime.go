@@ -0,0 +1,112 @@
+package dcmdump
+
+import (
+	"strconv"
+	"time"
+)
+
+// Location returns the time zone of the TimezoneOffsetFromUTC (0008,0201) of
+// the file, UTC when it is not set.
+func (df *DicomFile) Location() (*time.Location, error) {
+	z := df.stringValue("00080201")
+	if z == "" {
+		return time.UTC, nil
+	}
+	return parseUTCOffset(z)
+}
+
+// Time returns the first value of the DA, TM or DT element with the given
+// tag. Values without a UTC offset are in the TimezoneOffsetFromUTC of the
+// file.
+func (df *DicomFile) Time(tagStr string) (time.Time, error) {
+	de, err := df.LookupElement(tagStr)
+	if err != nil {
+		return time.Time{}, ErrNoElement
+	}
+	t, err := de.Time()
+	if err != nil {
+		return t, err
+	}
+	return df.inLocation(t)
+}
+
+// DateTime combines the DA element dateTag with the TM element timeTag, like
+// AcquisitionDate (0008,0022) and AcquisitionTime (0008,0032), in the
+// TimezoneOffsetFromUTC of the file.
+// The time is midnight when timeTag is not in the file.
+func (df *DicomFile) DateTime(dateTag, timeTag string) (time.Time, error) {
+	d, err := df.Time(dateTag)
+	if err != nil {
+		return d, err
+	}
+	tm, err := df.Time(timeTag)
+	if err == ErrNoElement {
+		return d, nil
+	} else if err != nil {
+		return tm, err
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), tm.Hour(), tm.Minute(), tm.Second(), tm.Nanosecond(), d.Location()), nil
+}
+
+// inLocation sets the time zone of the file on values read without UTC
+// offset.
+func (df *DicomFile) inLocation(t time.Time) (time.Time, error) {
+	if t.Location() != time.UTC {
+		return t, nil
+	}
+	loc, err := df.Location()
+	if err != nil {
+		return t, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc), nil
+}
+
+// PatientAge returns the age in years of the patient on the StudyDate
+// (0008,0020), from its PatientBirthDate (0010,0030).
+// The PatientAge (0010,1010) is used when either date is missing.
+func (df *DicomFile) PatientAge() (int, error) {
+	birth, err1 := df.Time("00100030")
+	study, err2 := df.Time("00080020")
+	if err1 == nil && err2 == nil {
+		return Age(birth, study), nil
+	}
+	de, err := df.LookupElement("00101010")
+	if err != nil {
+		if err1 != nil {
+			return 0, err1
+		}
+		return 0, err2
+	}
+	return parseAge(de.trimmedValue())
+}
+
+// Age returns the number of full years between birth and t.
+func Age(birth, t time.Time) int {
+	years := t.Year() - birth.Year()
+	if t.Month() < birth.Month() || t.Month() == birth.Month() && t.Day() < birth.Day() {
+		years--
+	}
+	return years
+}
+
+// parseAge returns the years of an AS value, nnnD, nnnW, nnnM or nnnY.
+func parseAge(s string) (int, error) {
+	if len(s) != 4 {
+		return 0, ErrValueFormat
+	}
+	n, err := strconv.Atoi(s[:3])
+	if err != nil {
+		return 0, ErrValueFormat
+	}
+	switch s[3] {
+	case 'Y':
+		return n, nil
+	case 'M':
+		return n / 12, nil
+	case 'W':
+		return n * 7 / 365, nil
+	case 'D':
+		return n / 365, nil
+	}
+	return 0, ErrValueFormat
+}
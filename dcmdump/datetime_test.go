@@ -0,0 +1,56 @@
+package dcmdump
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateTime(t *testing.T) {
+	df := &DicomFile{}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00080020", "DA", "20200131"},
+		{"00080022", "DA", "20200131"},
+		{"00080032", "TM", "101010.5"},
+		{"0008002A", "DT", "20200131101010+0000"},
+		{"00080201", "SH", "-0500"},
+		{"00100030", "DA", "19800201"},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	loc := time.FixedZone("-0500", -5*3600)
+	got, err := df.DateTime("00080022", "00080032")
+	if expected := time.Date(2020, 1, 31, 10, 10, 10, 5e8, loc); err != nil || !got.Equal(expected) {
+		t.Errorf("Expected %s, got %s, %v", expected, got, err)
+	}
+	got, err = df.DateTime("00080020", "00080030")
+	if expected := time.Date(2020, 1, 31, 0, 0, 0, 0, loc); err != nil || !got.Equal(expected) {
+		t.Errorf("Expected %s, got %s, %v", expected, got, err)
+	}
+	// DT values keep their own offset
+	got, err = df.Time("0008002A")
+	if expected := time.Date(2020, 1, 31, 10, 10, 10, 0, time.UTC); err != nil || !got.Equal(expected) {
+		t.Errorf("Expected %s, got %s, %v", expected, got, err)
+	}
+	if _, err = df.Time("00080030"); err != ErrNoElement {
+		t.Errorf("Expected ErrNoElement, got %v", err)
+	}
+
+	age, err := df.PatientAge()
+	if err != nil || age != 39 {
+		t.Errorf("Expected age 39, got %d, %v", age, err)
+	}
+	df.DeleteElement("00100030")
+	df.SetElement("00101010", "AS", "018M")
+	age, err = df.PatientAge()
+	if err != nil || age != 1 {
+		t.Errorf("Expected age 1 from PatientAge, got %d, %v", age, err)
+	}
+
+	df.SetElement("00080201", "SH", "0500")
+	if _, err = df.Location(); err == nil {
+		t.Errorf("Expected invalid offset error")
+	}
+}
@@ -3,33 +3,24 @@
 package dcmdump
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strconv"
 	"strings"
-	"errors"
+	"sync"
 
 	"github.com/davidgamba/go-dicom/dcmdump/tag"
 	"github.com/davidgamba/go-dicom/dcmdump/ts"
 	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
 )
 
-var debug bool
 var ErrNotDICM = errors.New("Not a Dicom File")
 
-func debugf(format string, a ...interface{}) (n int, err error) {
-	if debug {
-		return fmt.Printf(format, a...)
-	}
-	return 0, nil
-}
-func debugln(a ...interface{}) (n int, err error) {
-	if debug {
-		return // fmt.Println(a...)
-	}
-	return 0, nil
-}
-
 type stringSlice []string
 
 func (s stringSlice) contains(a string) bool {
@@ -53,36 +44,80 @@ type DataElement struct {
 	TagGroup []byte // [2]byte
 	TagElem  []byte // [2]byte
 	TagStr   string
-	Name 	 string
+	Name     string
 	VR       []byte // [2]byte
 	VRStr    string
 	VRLen    int
 	Len      uint32
 	Data     []byte
 	PartOfSQ bool
+	// ByteOrder of Data, little endian when nil.
+	ByteOrder binary.ByteOrder
+	// UndefinedLen is set when the element was encoded with undefined length
+	// and a delimitation item.
+	UndefinedLen bool
+	// Items of a sequence (SQ) element.
+	Items []Item
+	// CharacterSet is the SpecificCharacterSet (0008,0005) in effect for the
+	// element, text values are decoded with it.
+	CharacterSet []string
+	// Deferred is set for values left unread by a lazy parse, Data is nil
+	// and the value is Len bytes at ValueOffset in the file until Load.
+	Deferred bool
+	// ValueOffset is the position of the value in the input it was parsed
+	// from, see BulkData.
+	ValueOffset int64
+	source      io.ReaderAt
+	// located is set when ValueOffset is a position in the parsed input.
+	located bool
+}
+
+// Load reads the value of a deferred element into Data.
+func (de *DataElement) Load() error {
+	if !de.Deferred {
+		return nil
+	}
+	data := make([]byte, de.Len)
+	_, err := de.source.ReadAt(data, de.ValueOffset)
+	if err != nil {
+		return err
+	}
+	de.Data = data
+	de.Deferred = false
+	return nil
+}
+
+// Item - Sequence item (FFFE,E000) and its nested data elements.
+type Item struct {
+	N            int
+	Len          uint32
+	UndefinedLen bool
+	Elements     []DataElement
 }
 
 // DicomFile -
 type DicomFile struct {
-	Elements []DataElement
-	Path string
+	Preamble       [128]byte
+	Elements       []DataElement
+	Path           string
+	TransferSyntax string
+	// Warnings holds the errors skipped by a BestEffort parse and the
+	// recoverable issues of the file, like odd value lengths.
+	Warnings []error
+
+	// mu guards Elements for the lookup and mutation methods.
+	mu sync.RWMutex
 }
 
-// Look up element by tag string or Name
+// LookupElement returns a copy of the first top level element with the given
+// tag string or Name, see Lookup. Changes to it are not reflected in the
+// file, use FindFirst or SetElement for that.
 func (file *DicomFile) LookupElement(name string) (*DataElement, error) {
-
-	for _, elem := range file.Elements {
-		if elem.TagStr == name {
-			return &elem, nil
-		}
+	de, ok := file.Lookup(name)
+	if !ok {
+		return nil, errors.New("Could not find tag in dicom dictionary")
 	}
-	for _, elem := range file.Elements {
-		if elem.Name == name {
-			return &elem, nil
-		}
-	}
-
-	return nil, errors.New("Could not find tag in dicom dictionary")
+	return &de, nil
 }
 
 // String -
@@ -90,6 +125,10 @@ func (de *DataElement) String() string {
 	tn := tag.Tag[de.TagStr]["name"]
 	if _, ok := tag.Tag[de.TagStr]; !ok {
 		tn = "MISSING"
+		if de.Name != "" {
+			// Private tag resolved with its private creator
+			tn = de.Name
+		}
 	}
 	padding := ""
 	if de.PartOfSQ {
@@ -119,68 +158,54 @@ type fh os.File
 // 	return data, nil
 // }
 
-// http://rosettacode.org/wiki/Strip_control_codes_and_extended_characters_from_a_string#Go
-// two UTF-8 functions identical except for operator comparing c to 127
-func stripCtlFromUTF8(str string) string {
-	return strings.Map(func(r rune) rune {
-		if r >= 32 && r != 127 {
-			return r
-		}
-		return '.'
-	}, str)
+func tagString(b []byte, order binary.ByteOrder) string {
+	if len(b) < 4 {
+		return ""
+	}
+	return formatTag(order.Uint16(b[0:2]), order.Uint16(b[2:4]))
 }
 
-func tagString(b []byte) string {
-	tag := strings.ToUpper(fmt.Sprintf("%02x%02x%02x%02x", b[1], b[0], b[3], b[2]))
-	return tag
+// formatTag returns the GGGGEEEE tag of group and elem.
+func formatTag(group, elem uint16) string {
+	const digits = "0123456789ABCDEF"
+	var s [8]byte
+	for i, v := range [2]uint16{group, elem} {
+		for j := 3; j >= 0; j-- {
+			s[i*4+j] = digits[v&0xF]
+			v >>= 4
+		}
+	}
+	return string(s[:])
 }
 
-func printBytes(b []byte) {
-	if !debug {
-		return
-	}
-	l := len(b)
-	var s string
-	for i := 0; i < l; i++ {
-		s += stripCtlFromUTF8(string(b[i]))
-		if i != 0 && i%8 == 0 {
-			if i%16 == 0 {
-				fmt.Printf(" - %s\n", s)
-				s = ""
-			} else {
-				fmt.Printf(" - ")
-			}
-		}
-		fmt.Printf("%2x ", b[i])
-		if i == l-1 {
-			if 15-i%16 > 7 {
-				fmt.Printf(" - ")
-			}
-			for j := 0; j < 15-i%16; j++ {
-				// fmt.Printf("   ")
-				fmt.Printf("   ")
-			}
-			fmt.Printf(" - %s\n", s)
-			s = ""
-		}
+func (de *DataElement) order() binary.ByteOrder {
+	if de.ByteOrder == nil {
+		return binary.LittleEndian
 	}
-	fmt.Printf("\n")
+	return de.ByteOrder
 }
+
 func (de *DataElement) StringData() string {
 	return de.stringData()
 }
 
 func (de *DataElement) stringData() string {
+	if de.VRStr == "SQ" || de.TagStr == "FFFEE000" {
+		return ""
+	}
 	if de.TagStr == "00020010" {
-		dataStr := string(de.Data)
-		l := len(de.Data)
-		if de.Data[l-1] == 0x0 {
-			dataStr = string(de.Data[:l-1])
-		}
+		dataStr := strings.TrimRight(string(de.Data), "\x00")
 		if tsStr, ok := ts.TS[dataStr]; ok {
 			return dataStr + " " + tsStr["name"].(string)
 		}
 	}
+	if de.VRStr == "AT" {
+		s := ""
+		for n := 0; n+4 <= len(de.Data); n += 4 {
+			s += tag.Info{Group: de.order().Uint16(de.Data[n:]), Element: de.order().Uint16(de.Data[n+2:])}.String() + " "
+		}
+		return s
+	}
 	if _, ok := vri.VR[de.VRStr]["fixed"]; ok && vri.VR[de.VRStr]["fixed"].(bool) {
 		s := ""
 		l := len(de.Data)
@@ -195,222 +220,339 @@ func (de *DataElement) stringData() string {
 			return s
 		case 2:
 			for n+2 <= l {
-				e := binary.LittleEndian.Uint16(de.Data[n : n+2])
+				e := de.order().Uint16(de.Data[n : n+2])
 				s += fmt.Sprintf("%d ", e)
 				n += 2
 			}
 			return s
 		case 4:
 			for n+4 <= l {
-				e := binary.LittleEndian.Uint32(de.Data[n : n+4])
-				s += fmt.Sprintf("%d ", e)
+				e := de.order().Uint32(de.Data[n : n+4])
+				if de.VRStr == "FL" || de.VRStr == "OF" {
+					s += strconv.FormatFloat(float64(math.Float32frombits(e)), 'g', -1, 32) + " "
+				} else {
+					s += fmt.Sprintf("%d ", e)
+				}
 				n += 4
 			}
 			return s
+		case 8:
+			for n+8 <= l {
+				e := de.order().Uint64(de.Data[n : n+8])
+				switch de.VRStr {
+				case "FD", "OD":
+					s += strconv.FormatFloat(math.Float64frombits(e), 'g', -1, 64) + " "
+				case "SV":
+					s += fmt.Sprintf("%d ", int64(e))
+				default:
+					s += fmt.Sprintf("%d ", e)
+				}
+				n += 8
+			}
+			return s
 		default:
 			return string(de.Data)
 		}
 	} else {
 		if _, ok := vri.VR[de.VRStr]["padded"]; ok && vri.VR[de.VRStr]["padded"].(bool) {
 			l := len(de.Data)
-			if de.Data[l-1] == 0x0 {
+			if l > 0 && de.Data[l-1] == 0x0 {
 				return string(de.Data[:l-1])
 			}
 			return string(de.Data)
 		}
-		return string(de.Data)
+		return de.text()
 	}
 }
 
-func readNbytes (f *os.File, size int, off int) ([]byte, error) {
-	buff := make([]byte, size)
-	n, err := f.ReadAt(buff, int64(off))
-	if err != nil {
-		return buff, err
-	} else if n != size {
-		return buff, errors.New("Number of read byte does not equal given size")
-	}
-	return buff, nil
-}
-
-func parseDataElement(path string, n int, explicit bool, limit int, tags []string) ([]DataElement, error) {
-	l := limit
-	// Data element
-	m := n
-	elements := make([]DataElement,0)
-	dfile, err := os.Open(path)
-	if err != nil {
-		return elements, err
-	}
-
-	for n <= l && m+4 <= l && n <= limit && m+4 <= limit {
+func parseDataElement(p *Parser, explicit bool) ([]DataElement, error) {
+	elements := make([]DataElement, 0)
+	var gl groupLength
+	for {
 		undefinedLen := false
-		de := DataElement{N: n}
-		m += 4
-		t, err := readNbytes(dfile, 4, n)
-		if err != nil {
+		if p.endOfMeta() {
+			p.verifyGroupLength(&gl, "", p.Offset())
+			return elements, nil
+		}
+		if err := p.canceled(); err != nil {
 			return elements, err
 		}
-		de.TagGroup = t[:2]
-		de.TagElem = t[2:]
-		de.TagStr = tagString(t)
-		// TODO: Clean up tagString
-		tagStr := tagString(t)
-		n = m
-		if tagStr == "" {
-		} else if _, ok := tag.Tag[tagStr]; !ok {
-			// fmt.Fprintf(os.Stderr, "INFO: %d Missing tag '%s'\n", n, tagStr)
-		} else {
-			de.Name = tag.Tag[tagStr]["name"]
+		p.inflate()
+		de := DataElement{N: p.Offset()}
+		b, err := p.r.Peek(12)
+		if len(b) == 0 && err == io.EOF {
+			p.verifyGroupLength(&gl, "", p.Offset())
+			return elements, nil
 		}
-		var len uint32
-		var vr string
-		if explicit {
-			m += 2
-			vr_byte, err := readNbytes(dfile, 2, n)
-			if err != nil {
-				return elements, err
+		order := p.ByteOrder
+		elemExplicit := explicit
+		if len(b) >= 2 && b[0] == 0x02 && b[1] == 0x00 {
+			// File Meta Elements are always Explicit VR Little Endian
+			order = binary.LittleEndian
+			elemExplicit = true
+		}
+		h, size, herr := DecodeElementHeader(b, elemExplicit, order)
+		if herr == ErrShortBuffer && (err == nil || err == io.EOF) {
+			herr = io.ErrUnexpectedEOF
+		} else if herr == ErrShortBuffer {
+			herr = err
+		}
+		if size < 4 {
+			return elements, newParseError(&de, herr)
+		}
+		// Tag and VR share an allocation, the elements keep slices of it
+		t := make([]byte, 6)
+		copy(t, b[:size])
+		de.ByteOrder = order
+		de.TagGroup = t[:2:2]
+		de.TagElem = t[2:4:4]
+		de.TagStr = h.Tag()
+		if p.MaxElements > 0 {
+			if p.total == nil {
+				p.total = new(int)
 			}
-			de.VR = vr_byte
-			de.VRStr = string(vr_byte)
-			vr = string(vr_byte)
-			if _, ok := vri.VR[vr]; !ok {
-				if vr_byte[0] == 0x0 && vr_byte[1] == 0x0 {
-					// fmt.Fprintf(os.Stderr, "INFO: Blank VR\n")
-					vr = "00"
-					de.VRStr = "00"
-				} else {
-					// fmt.Fprintf(os.Stderr, "ERROR: %d Missing VR '%s'\n", n, vr)
-					return elements, err
-				}
+			if *p.total++; *p.total > p.MaxElements {
+				return elements, newParseError(&de, ErrElementCount)
 			}
-			n = m
-			if vr == "OB" ||
-				vr == "OD" ||
-				vr == "OF" ||
-				vr == "OL" ||
-				vr == "OW" ||
-				vr == "SQ" ||
-				vr == "UC" ||
-				vr == "UR" ||
-				vr == "UT" ||
-				vr == "UN" {
-				m += 2
-				n = m
-				m += 4
-				bytes, err := readNbytes(dfile, m-n, n)
-				if err != nil {
-					return elements, err
-				}
-				len = binary.LittleEndian.Uint32(bytes)
-				n = m
-			} else {
-				m += 2
-				bytes, err := readNbytes(dfile, m-n, n)
-				if err != nil {
-					return elements, err
-				}
-				len16 := binary.LittleEndian.Uint16(bytes)
-				len = uint32(len16)
-				n = m
+		}
+		p.verifyGroupLength(&gl, de.TagStr[:4], de.N)
+		if p.stopPast && p.StopAt != "" && de.TagStr > p.StopAt && de.TagStr[:4] != "FFFE" {
+			// The StopAt tag isn't in the file
+			return elements, nil
+		}
+		tagStr := de.TagStr
+		group, elem := h.Group, h.Element
+		if info, ok := tag.Tag[tagStr]; ok {
+			de.Name = info["name"]
+		} else if block, ok := tag.PrivateBlock(group, elem); ok {
+			if info, ok := tag.FindPrivate(p.creators[[2]uint16{group, block}], group, elem); ok {
+				de.Name = info.Keyword
 			}
+		} else if info, ok := tag.Find(group, elem); ok {
+			// Repeating groups like overlays (60xx)
+			de.Name = info.Keyword
 		} else {
-			m += 4
-			bytes, err := readNbytes(dfile, m-n, n)
-			if err != nil {
-				return elements, err
+			p.logf(LevelInfo, "%d Missing tag '%s'", de.N, tagStr)
+		}
+		vr := h.VR
+		if size >= 6 && h.VR != "" {
+			de.VR = t[4:6]
+			if vr == "\x00\x00" {
+				p.logf(LevelWarn, "%d Blank VR for tag '%s'", de.N, tagStr)
+				vr = "00"
+			}
+			de.VRStr = vr
+		}
+		if herr == ErrUnknownVR {
+			p.logf(LevelError, "%d Missing VR '%s' for tag '%s'", de.N, vr, tagStr)
+			return elements, newParseError(&de, ErrUnknownVR)
+		} else if herr != nil {
+			return elements, newParseError(&de, herr)
+		}
+		if h.VR == "" {
+			vr = p.implicitVR(group, elem, tagStr)
+			de.VRStr = vr
+		}
+		// UN values are encoded as implicit VR little endian
+		un := h.VR == "UN"
+		if un && p.ResolveUN {
+			if dict := p.implicitVR(group, elem, tagStr); dict != "" && dict != "UN" {
+				p.logf(LevelDebug, "%d Resolved VR UN to '%s' for tag '%s'", de.N, dict, tagStr)
+				vr = dict
+				de.VRStr = vr
+				copy(t[4:6], vr)
 			}
-			len = binary.LittleEndian.Uint32(bytes)
-			n = m
 		}
+		err = p.skip(size)
+		if err != nil {
+			return elements, newParseError(&de, err)
+		}
+		len := h.Length
+		if p.MaxElementSize > 0 && len != 0xFFFFFFFF && int64(len) > int64(p.MaxElementSize) {
+			return elements, newParseError(&de, ErrElementSize)
+		}
+		n := p.Offset()
+		de.ValueOffset = int64(n)
+		// Offsets in inflated data sets aren't positions in the input
+		de.located = !p.inflated
+		var value []byte
 		if len == 0xFFFFFFFF {
 			undefinedLen = true
-			for {
-				endTag, err := readNbytes(dfile, 4, m)
-				if err != nil {
-					return elements, err
-				}
-				endTagStr := tagString(endTag)
-				if de.TagStr == "FFFEE000" && endTagStr == "FFFEE00D" {
-					// FFFEE000 item
-					// find FFFEE00D: ItemDelimitationItem
-					len = uint32(m - n)
-					m = n
-					break
-				} else if endTagStr == "FFFEE0DD" {
-					// Find FFFEE0DD: SequenceDelimitationItem
-					len = uint32(m - n)
-					m = n
-					break
-				} else {
-					m++
-					if m >= l {
-						// fmt.Fprintf(os.Stderr, "ERROR: Couldn't find SequenceDelimitationItem\n")
-						return elements, err
-					}
+			if de.TagStr == "FFFEE000" {
+				// FFFEE000 item up to its FFFEE00D: ItemDelimitationItem
+				value, err = p.readUndefined(true, p.Explicit)
+			} else if vr == "OB" || vr == "OW" || (vr == "" && de.TagStr == "7FE00010") {
+				// Encapsulated pixel data fragments
+				limit := 0
+				if p.lazy() {
+					limit = p.LazyThreshold
 				}
+				value, err = p.readFragments(limit)
+			} else {
+				// Items up to the FFFEE0DD: SequenceDelimitationItem
+				value, err = p.readUndefined(false, p.Explicit && !un)
+			}
+			if err == io.ErrUnexpectedEOF {
+				p.logf(LevelError, "%d Couldn't find delimitation item for tag '%s'", de.N, tagStr)
+				return elements, newParseError(&de, ErrMissingDelimiter)
+			} else if err != nil {
+				return elements, newParseError(&de, err)
 			}
+			len = uint32(p.Offset() - 4 - n)
 		}
 		de.Len = len
-		debugf("Lenght: %d\n", len)
-		m += int(len)
-		if de.TagStr == "7FE00010" {
-			de.Data = []byte{}
-		} else if de.TagStr == "FFFEE000" {
-			de.Data = []byte{}
-			// fmt.Println(de.String())
-			parseDataElement(path, n, true, m, tags)
-		} else if vr == "SQ" {
-			de.Data = []byte{}
-			// fmt.Println(de.String())
-			parseDataElement(path, n, false, m, tags)
-		} else if stringInSlice(de.TagStr, tags) {
-			if m < limit && m < l {
-				de.Data, err = readNbytes(dfile, m-n, n)
-				if err != nil {
-					return elements, err
+		de.UndefinedLen = undefinedLen
+		if len%2 != 0 && !undefinedLen {
+			p.logf(LevelWarn, "%d Odd length %d for tag '%s'", de.N, len, tagStr)
+			p.Warnings = append(p.Warnings, newParseError(&de, ErrOddLength))
+		}
+		if p.Logger != nil {
+			p.logf(LevelDebug, "%d Tag '%s' VR '%s' length %d", de.N, tagStr, vr, len)
+		}
+		keep, stop := p.decide(&de)
+		if stop {
+			return elements, nil
+		}
+		if (de.TagStr == "FFFEE000" || vr == "SQ") && keep {
+			if !undefinedLen {
+				value, err = p.readN(int(len))
+			}
+			s := p.sub(value, n)
+			if un {
+				s.Explicit, s.ByteOrder = false, binary.LittleEndian
+			}
+			if vr == "SQ" {
+				s.depth++
+				if p.MaxDepth > 0 && s.depth > p.MaxDepth {
+					err = ErrSequenceDepth
 				}
 			}
-			if de.TagStr == "0020000E" {
-				m = l
+			if err == nil {
+				de.Data = []byte{}
+				// Items are always encoded without VR, their content with the
+				// data set encoding.
+				var children []DataElement
+				children, err = parseDataElement(s, de.TagStr == "FFFEE000" && p.Explicit)
+				p.Warnings = append(p.Warnings, s.Warnings...)
+				if de.TagStr == "FFFEE000" {
+					for i := range children {
+						children[i].PartOfSQ = true
+					}
+					de.Items = []Item{{N: de.N, Len: len, UndefinedLen: undefinedLen, Elements: children}}
+				} else {
+					for _, c := range children {
+						de.Items = append(de.Items, c.Items...)
+					}
+				}
 			}
-			// fmt.Println(de.String())
+		} else if keep || de.TagStr == "00020010" || de.TagStr == "00080005" || tag.IsPrivateCreator(group, elem) || elem == 0 && p.VerifyGroupLengths {
+			if undefinedLen && value == nil {
+				// Fragments over the lazy threshold
+				de.Deferred = true
+				de.source = p.Source
+			} else if undefinedLen {
+				de.Data = value
+			} else if p.deferValue(&de) && de.TagStr[:4] != "0002" && de.TagStr != "00080005" && !tag.IsPrivateCreator(group, elem) {
+				de.Deferred = true
+				de.source = p.Source
+				err = p.skip(int(len))
+			} else {
+				de.Data, err = p.readN(int(len))
+			}
+		} else if !undefinedLen {
+			err = p.skip(int(len))
+		}
+		if err != nil {
+			return elements, newParseError(&de, err)
 		}
 		if undefinedLen {
-			m += 8
+			// Delimitation item length
+			err = p.skip(4)
+			if err != nil {
+				return elements, newParseError(&de, err)
+			}
+		}
+		if de.TagStr == "00020010" {
+			p.setTransferSyntax(string(de.Data))
+			explicit = p.Explicit
+		}
+		if de.TagStr == "00080005" {
+			p.CharacterSet = de.stringValues()
+		}
+		if elem == 0 && group != 0xFFFE && de.Len == 4 && de.Data != nil {
+			gl = groupLength{group: de.TagStr[:4], declared: order.Uint32(de.Data), start: p.Offset()}
+		}
+		if de.TagStr == "00280103" && de.Len == 2 && de.Data != nil {
+			p.signed = de.order().Uint16(de.Data) == 1
+		}
+		de.CharacterSet = p.CharacterSet
+		if tag.IsPrivateCreator(group, elem) {
+			if p.creators == nil {
+				p.creators = map[[2]uint16]string{}
+			}
+			p.creators[[2]uint16{group, elem}] = de.trimmedValue()
 		}
-		n = m
 		// if de.Name != "PixelData"{
 		// 	elements = append(elements, de)
 		// }
-		if stringInSlice(de.TagStr, tags) {
+		p.progress()
+		if keep {
 			elements = append(elements, de)
+			if de.TagStr == p.StopAt {
+				return elements, nil
+			}
 		}
 	}
-	dfile.Close()
-	return elements, err
 }
 
 func stringInSlice(a string, tags []string) bool {
-    for _, b := range tags {
-        if b == a {
-            return true
-        }
-    }
-    if len(tags) == 0 {
-    	return true
-    }
-    return false
+	for _, b := range tags {
+		if b == a {
+			return true
+		}
+	}
+	if len(tags) == 0 {
+		return true
+	}
+	return false
 }
 
+// ProcessFile parses the file at path starting at offset m, up to the
+// SeriesInstanceUID.
+// The transfer syntax of the data set is detected from the file meta group,
+// explicit is only used for files without a TransferSyntaxUID.
+//
+// Deprecated: Use the ProcessFile function with options, which reads the
+// whole file.
 func (di *DicomFile) ProcessFile(path string, m int, explicit bool, tags []string) error {
-	fi, err := os.Stat(path);
-	if err != nil {
+	return di.ProcessFileContext(context.Background(), path, m, explicit, tags, nil)
+}
 
-	    return err
+// ProcessFileContext parses the file like ProcessFile until ctx is done,
+// calling progress after each top level element when not nil.
+//
+// Deprecated: Use the ProcessFile function with WithContext and
+// WithProgress.
+func (di *DicomFile) ProcessFileContext(ctx context.Context, path string, m int, explicit bool, tags []string, progress func(ParseProgress)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	pre := make([]byte, 132)
+	_, err = io.ReadFull(f, pre)
+	if err == nil && string(pre[128:]) == "DICM" {
+		copy(di.Preamble[:], pre)
+	}
+	_, err = f.Seek(int64(m), io.SeekStart)
+	if err != nil {
+		return err
 	}
-	// get the size
-	size := fi.Size()
-	di.Elements, err = parseDataElement(path, m, explicit, int(size), tags)
+	di.Path = path
+	p := NewParser(f, m, explicit, tags)
+	p.Context = ctx
+	p.Progress = progress
+	di.Elements, err = p.Parse()
+	di.TransferSyntax = p.TransferSyntax
 	return err
 }
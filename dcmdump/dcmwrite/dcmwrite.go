@@ -0,0 +1,107 @@
+// Package dcmwrite encodes DICOM data elements into their binary form.
+package dcmwrite
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// UndefinedLength marks sequences, items and encapsulated pixel data whose
+// end is given by a delimitation item.
+const UndefinedLength = 0xFFFFFFFF
+
+// ErrVRLength is returned when a value is too long for the 2 byte length of
+// its VR.
+var ErrVRLength = errors.New("Value length exceeds VR length field")
+
+// Encoder writes data elements with the given VR encoding and byte order.
+type Encoder struct {
+	w        io.Writer
+	Explicit bool
+	Order    binary.ByteOrder
+}
+
+// NewEncoder -
+func NewEncoder(w io.Writer, explicit bool, order binary.ByteOrder) *Encoder {
+	return &Encoder{w: w, Explicit: explicit, Order: order}
+}
+
+// WriteHeader writes the tag, VR and length of an element.
+// Items and delimitation items (group FFFE) never carry a VR.
+func (e *Encoder) WriteHeader(group, elem uint16, vr string, length uint32) error {
+	b := make([]byte, 4, 12)
+	e.Order.PutUint16(b[0:], group)
+	e.Order.PutUint16(b[2:], elem)
+	if !e.Explicit || group == 0xFFFE {
+		b = b[:8]
+		e.Order.PutUint32(b[4:], length)
+	} else if vri.LongLength(vr) {
+		b = append(b, vr[0], vr[1], 0, 0, 0, 0, 0, 0)
+		e.Order.PutUint32(b[8:], length)
+	} else {
+		if length > 0xFFFF {
+			return ErrVRLength
+		}
+		b = append(b, vr[0], vr[1], 0, 0)
+		e.Order.PutUint16(b[6:], uint16(length))
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+// WriteElement writes a full element with a defined length.
+func (e *Encoder) WriteElement(group, elem uint16, vr string, value []byte) error {
+	err := e.WriteHeader(group, elem, vr, uint32(len(value)))
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(value)
+	return err
+}
+
+// Write writes raw bytes.
+func (e *Encoder) Write(b []byte) (int, error) {
+	return e.w.Write(b)
+}
+
+// WritePreamble writes the 128 byte preamble followed by the DICM prefix.
+func WritePreamble(w io.Writer, preamble [128]byte) error {
+	_, err := w.Write(append(preamble[:], 'D', 'I', 'C', 'M'))
+	return err
+}
+
+// UnitSize returns the size in bytes of each value of a binary VR.
+// It is the size that needs swapping when changing byte order, 1 for values
+// that are byte streams or strings.
+func UnitSize(vr string) int {
+	if vr == "AT" {
+		// Pair of 16 bit unsigned integers
+		return 2
+	}
+	if _, ok := vri.VR[vr]["fixed"]; ok && vri.VR[vr]["fixed"].(bool) {
+		if vr == "AS" {
+			return 1
+		}
+		return vri.VR[vr]["len"].(int)
+	}
+	return 1
+}
+
+// Swap returns a copy of data with the byte order of each size long unit
+// reversed.
+func Swap(data []byte, size int) []byte {
+	b := make([]byte, len(data))
+	copy(b, data)
+	if size <= 1 {
+		return b
+	}
+	for n := 0; n+size <= len(b); n += size {
+		for i, j := n, n+size-1; i < j; i, j = i+1, j-1 {
+			b[i], b[j] = b[j], b[i]
+		}
+	}
+	return b
+}
@@ -0,0 +1,358 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/dcmwrite"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// MediaStorageDirectoryStorage - SOP Class UID of DICOMDIR files.
+const MediaStorageDirectoryStorage = "1.2.840.10008.1.3.10"
+
+// ErrDirectoryRecord is returned when the directory record offsets of a
+// DICOMDIR don't point to its records.
+var ErrDirectoryRecord = errors.New("Invalid directory record offset")
+
+// DirectoryRecord - Record of the DirectoryRecordSequence (0004,1220) of a
+// DICOMDIR, linked to its lower level records.
+type DirectoryRecord struct {
+	// Type is the DirectoryRecordType, PATIENT, STUDY, SERIES, IMAGE...
+	Type     string
+	Elements []DataElement
+	Children []*DirectoryRecord
+}
+
+// Lookup returns the record element with the given tag.
+func (r *DirectoryRecord) Lookup(tagStr string) (*DataElement, error) {
+	for i := range r.Elements {
+		if r.Elements[i].TagStr == tagStr {
+			return &r.Elements[i], nil
+		}
+	}
+	return nil, ErrNoElement
+}
+
+// ReferencedFile returns the path of the file referenced by the record
+// relative to the DICOMDIR, or "" if it doesn't reference one.
+func (r *DirectoryRecord) ReferencedFile() string {
+	de, err := r.Lookup("00041500")
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(de.Strings()...)
+}
+
+// DirectoryRecords returns the root records of a DICOMDIR, PATIENT records
+// with their studies, series and images as children.
+// Records are linked by their offsets in the file so df needs to be parsed
+// from the start of the file, as ProcessFile does.
+func (df *DicomFile) DirectoryRecords() ([]*DirectoryRecord, error) {
+	seq, err := df.LookupElement("00041220")
+	if err != nil {
+		return nil, err
+	}
+	items := map[int]*Item{}
+	for i := range seq.Items {
+		items[seq.Items[i].N] = &seq.Items[i]
+	}
+	first, err := df.LookupElement("00041200")
+	if err != nil {
+		return nil, err
+	}
+	visited := map[int]bool{}
+	var link func(offset int) ([]*DirectoryRecord, error)
+	link = func(offset int) ([]*DirectoryRecord, error) {
+		records := []*DirectoryRecord{}
+		for offset != 0 {
+			item, ok := items[offset]
+			if !ok || visited[offset] {
+				return records, ErrDirectoryRecord
+			}
+			visited[offset] = true
+			r := &DirectoryRecord{Elements: item.Elements}
+			if de, err := r.Lookup("00041430"); err == nil {
+				r.Type = de.trimmedValue()
+			}
+			if lower := recordOffset(r, "00041420"); lower != 0 {
+				var err error
+				r.Children, err = link(lower)
+				if err != nil {
+					return records, err
+				}
+			}
+			records = append(records, r)
+			offset = recordOffset(r, "00041400")
+		}
+		return records, nil
+	}
+	return link(firstInt(first))
+}
+
+func recordOffset(r *DirectoryRecord, tagStr string) int {
+	de, err := r.Lookup(tagStr)
+	if err != nil {
+		return 0
+	}
+	return firstInt(de)
+}
+
+func firstInt(de *DataElement) int {
+	values := de.Ints()
+	if len(values) == 0 {
+		return 0
+	}
+	return values[0]
+}
+
+// directoryKeys are the elements copied into each record type.
+var directoryKeys = map[string][]string{
+	// PatientName, PatientID
+	"PATIENT": {"00080005", "00100010", "00100020"},
+	// StudyDate, StudyTime, AccessionNumber, StudyDescription, StudyInstanceUID, StudyID
+	"STUDY": {"00080005", "00080020", "00080030", "00080050", "00081030", "0020000D", "00200010"},
+	// Modality, SeriesInstanceUID, SeriesNumber
+	"SERIES": {"00080005", "00080060", "0020000E", "00200011"},
+	// InstanceNumber
+	"IMAGE": {"00080005", "00200013"},
+}
+
+// NewDICOMDIR returns a DICOMDIR describing the DICOM files under root, to be
+// written as root/DICOMDIR with Write and Explicit VR Little Endian.
+// Referenced File IDs are the file paths relative to root, media profiles
+// restricting them to 8 uppercase characters per component are not enforced.
+func NewDICOMDIR(root string) (*DicomFile, error) {
+	tags := []string{"00020002", "00020003", "00020010", "00080016", "00080018"}
+	for _, keys := range directoryKeys {
+		tags = append(tags, keys...)
+	}
+	patients := []*DirectoryRecord{}
+	index := map[string]*DirectoryRecord{}
+	child := func(parent *[]*DirectoryRecord, key, recordType string, elements []DataElement) *DirectoryRecord {
+		if r, ok := index[key]; ok {
+			return r
+		}
+		r := &DirectoryRecord{Type: recordType}
+		for _, de := range elements {
+			if stringSlice(directoryKeys[recordType]).contains(de.TagStr) {
+				r.Elements = append(r.Elements, de)
+			}
+		}
+		index[key] = r
+		*parent = append(*parent, r)
+		return r
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || info.Name() == "DICOMDIR" {
+			return nil
+		}
+		dicm, err := IsDICM(path)
+		if err != nil || !dicm {
+			return err
+		}
+		df, err := readElements(path, tags)
+		if err != nil {
+			return err
+		}
+		patientID := df.stringValue("00100020")
+		studyUID := df.stringValue("0020000D")
+		seriesUID := df.stringValue("0020000E")
+		patient := child(&patients, "P"+patientID, "PATIENT", df.Elements)
+		study := child(&patient.Children, "S"+studyUID, "STUDY", df.Elements)
+		series := child(&study.Children, "R"+studyUID+seriesUID, "SERIES", df.Elements)
+		image := child(&series.Children, "I"+path, "IMAGE", df.Elements)
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		refs := []struct{ tag, vr, value string }{
+			{"00041500", "CS", ""},
+			{"00041510", "UI", df.firstValue("00020002", "00080016")},
+			{"00041511", "UI", df.firstValue("00020003", "00080018")},
+			{"00041512", "UI", df.stringValue("00020010")},
+		}
+		for _, e := range refs {
+			var de DataElement
+			if e.tag == "00041500" {
+				de, err = NewDataElement(e.tag, e.vr, strings.Split(filepath.ToSlash(rel), "/"))
+			} else {
+				de, err = NewDataElement(e.tag, e.vr, e.value)
+			}
+			if err != nil {
+				return err
+			}
+			image.Elements = append(image.Elements, de)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildDICOMDIR(patients)
+}
+
+// readElements parses the given tags of the file at path, the whole data set
+// is read.
+func readElements(path string, tags []string) (*DicomFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	_, err = f.Seek(132, 0)
+	if err != nil {
+		return nil, err
+	}
+	p := NewParser(f, 132, true, tags)
+	p.StopAt = ""
+	df := &DicomFile{Path: path}
+	df.Elements, err = p.Parse()
+	df.TransferSyntax = p.TransferSyntax
+	return df, err
+}
+
+// firstValue returns the value of the first of the tags present in the file.
+func (df *DicomFile) firstValue(tags ...string) string {
+	for _, t := range tags {
+		if v := df.stringValue(t); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// buildDICOMDIR encodes the record tree into the DirectoryRecordSequence,
+// linking records by their offset from the start of the file.
+func buildDICOMDIR(roots []*DirectoryRecord) (*DicomFile, error) {
+	df := &DicomFile{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00020001", "OB", []byte{0, 1}},
+		{"00020002", "UI", MediaStorageDirectoryStorage},
+		{"00020003", "UI", NewUID()},
+		{"00020012", "UI", ImplementationClassUID},
+		{"00020013", "SH", ImplementationVersionName},
+		{"00041130", "CS", ""},
+		{"00041200", "UL", 0},
+		{"00041202", "UL", 0},
+		{"00041212", "US", 0},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Records in sequence order with their encoded item size, offsets are
+	// fixed size so they can be set after measuring.
+	records := []*DirectoryRecord{}
+	var flatten func(rs []*DirectoryRecord)
+	flatten = func(rs []*DirectoryRecord) {
+		for _, r := range rs {
+			records = append(records, r)
+			flatten(r.Children)
+		}
+	}
+	flatten(roots)
+	items := make([]Item, len(records))
+	sizes := make([]int, len(records))
+	total := 0
+	for i, r := range records {
+		items[i] = Item{Elements: recordElements(r, 0, 0)}
+		var buf bytes.Buffer
+		enc := dcmwrite.NewEncoder(&buf, true, binary.LittleEndian)
+		for _, de := range items[i].Elements {
+			err := writeElement(enc, &de)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sizes[i] = 8 + buf.Len()
+		total += sizes[i]
+	}
+	err := df.SetElement("00041220", "SQ", []Item{})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = df.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		return nil, err
+	}
+	offsets := map[*DirectoryRecord]int{}
+	offset := buf.Len()
+	for i, r := range records {
+		offsets[r] = offset
+		offset += sizes[i]
+	}
+	next := func(rs []*DirectoryRecord, i int) int {
+		if i+1 < len(rs) {
+			return offsets[rs[i+1]]
+		}
+		return 0
+	}
+	var link func(rs []*DirectoryRecord)
+	link = func(rs []*DirectoryRecord) {
+		for i, r := range rs {
+			lower := 0
+			if len(r.Children) > 0 {
+				lower = offsets[r.Children[0]]
+			}
+			r.Elements = recordElements(r, next(rs, i), lower)
+			link(r.Children)
+		}
+	}
+	link(roots)
+	for i, r := range records {
+		items[i] = Item{Elements: r.Elements}
+	}
+	if len(roots) > 0 {
+		err = df.SetElement("00041200", "UL", offsets[roots[0]])
+		if err != nil {
+			return nil, err
+		}
+		err = df.SetElement("00041202", "UL", offsets[roots[len(roots)-1]])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = df.SetElement("00041220", "SQ", items)
+	return df, err
+}
+
+// recordElements returns the elements of r with its record header.
+func recordElements(r *DirectoryRecord, next, lower int) []DataElement {
+	elements := []DataElement{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00041400", "UL", next},
+		{"00041410", "US", 0xFFFF},
+		{"00041420", "UL", lower},
+		{"00041430", "CS", r.Type},
+	} {
+		de, _ := NewDataElement(e.tag, e.vr, e.value)
+		de.PartOfSQ = true
+		elements = append(elements, de)
+	}
+	for _, de := range r.Elements {
+		switch de.TagStr {
+		case "00041400", "00041410", "00041420", "00041430":
+			continue
+		}
+		de.PartOfSQ = true
+		elements = append(elements, de)
+	}
+	return elements
+}
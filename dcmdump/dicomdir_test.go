@@ -0,0 +1,76 @@
+package dcmdump
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestDICOMDIR(t *testing.T) {
+	dir := t.TempDir()
+	for i, e := range []struct{ name, patient, study, series, sop string }{
+		{"A/IM1", "P1", "1.1", "1.1.1", "1.1.1.1"},
+		{"A/IM2", "P1", "1.1", "1.1.1", "1.1.1.2"},
+		{"B/IM1", "P2", "2.1", "2.1.1", "2.1.1.1"},
+	} {
+		df := &DicomFile{}
+		for _, v := range []struct{ tag, vr, value string }{
+			{"00020002", "UI", "1.2.840.10008.5.1.4.1.1.2"},
+			{"00020003", "UI", e.sop},
+			{"00080016", "UI", "1.2.840.10008.5.1.4.1.1.2"},
+			{"00080018", "UI", e.sop},
+			{"00100020", "LO", e.patient},
+			{"0020000D", "UI", e.study},
+			{"0020000E", "UI", e.series},
+		} {
+			if err := df.SetElement(v.tag, v.vr, v.value); err != nil {
+				t.Fatal(err)
+			}
+		}
+		df.SetElement("00200013", "IS", i+1)
+		var buf bytes.Buffer
+		if err := df.Write(&buf, ts.ExplicitVRLittleEndian); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, e.name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dicomdir, err := NewDICOMDIR(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	err = dicomdir.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	records, err := parseSample(t, buf.Bytes(), true).DirectoryRecords()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(records) != 2 || records[0].Type != "PATIENT" {
+		t.Fatalf("Wrong patient records: %v", records)
+	}
+	studies := records[0].Children
+	if len(studies) != 1 || len(studies[0].Children) != 1 {
+		t.Fatalf("Wrong study records: %v", studies)
+	}
+	images := studies[0].Children[0].Children
+	if len(images) != 2 || images[1].Type != "IMAGE" {
+		t.Fatalf("Wrong image records: %v", images)
+	}
+	if images[1].ReferencedFile() != filepath.Join("A", "IM2") {
+		t.Errorf("Wrong referenced file: %s", images[1].ReferencedFile())
+	}
+	de, err := images[1].Lookup("00041511")
+	if err != nil || de.Strings()[0] != "1.1.1.2" {
+		t.Errorf("Wrong referenced SOP instance: %v", de)
+	}
+}
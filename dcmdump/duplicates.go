@@ -0,0 +1,129 @@
+package dcmdump
+
+import (
+	"sort"
+	"sync"
+)
+
+// Relations of a duplicate to the file kept in its group
+const (
+	// DuplicateExact files are identical.
+	DuplicateExact = "exact"
+	// DuplicateResent files have the same dataset with a different file meta
+	// group or encoding, as when an instance is sent again by another
+	// application entity.
+	DuplicateResent = "resent"
+	// DuplicateTranscoded files have the same decoded pixel data with a
+	// different dataset, as when transcoded to another transfer syntax. Their
+	// other elements may differ too.
+	DuplicateTranscoded = "transcoded"
+	// DuplicateConflict files share the SOPInstanceUID only.
+	DuplicateConflict = "conflict"
+)
+
+// Duplicate - File of a DuplicateGroup.
+type Duplicate struct {
+	Path        string
+	Fingerprint *Fingerprint
+	// Relation to the first file of the group, blank for the first file.
+	Relation string
+}
+
+// DuplicateGroup - Files sharing a SOPInstanceUID, the first of Files is the
+// one kept.
+type DuplicateGroup struct {
+	SOPInstanceUID string
+	Files          []Duplicate
+}
+
+// Prunable returns the paths of the exact and resent duplicates, which can be
+// removed keeping the first file.
+func (g *DuplicateGroup) Prunable() []string {
+	paths := []string{}
+	for _, f := range g.Files {
+		if f.Relation == DuplicateExact || f.Relation == DuplicateResent {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// DuplicateFinder - Collects the fingerprints of files by SOPInstanceUID to
+// find the duplicates of an archive. It is safe for concurrent use.
+type DuplicateFinder struct {
+	mu    sync.Mutex
+	byUID map[string][]Duplicate
+}
+
+// NewDuplicateFinder returns an empty DuplicateFinder.
+func NewDuplicateFinder() *DuplicateFinder {
+	return &DuplicateFinder{byUID: map[string][]Duplicate{}}
+}
+
+// Add fingerprints df, read from path. The pixels of files with an
+// unsupported transfer syntax are not hashed, they can't be found as
+// transcoded.
+func (d *DuplicateFinder) Add(path string, df *DicomFile) error {
+	uid := df.stringValue("00080018")
+	if uid == "" {
+		return ErrMissingUID
+	}
+	f, err := df.Fingerprint()
+	if err == ErrUnsupportedTS {
+		f, err = df.hashes()
+	}
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.byUID[uid] = append(d.byUID[uid], Duplicate{Path: path, Fingerprint: f})
+	return nil
+}
+
+// AddDir adds the DICOM files of the tree at root, calling failed with the
+// files that couldn't be read or added.
+func (d *DuplicateFinder) AddDir(root string, failed func(path string, err error)) {
+	w := NewWalker(root, nil)
+	for result := range w.Walk() {
+		err := result.Err
+		if err == nil {
+			err = d.Add(result.Path, result.File)
+		}
+		if err != nil && failed != nil {
+			failed(result.Path, err)
+		}
+	}
+}
+
+// Groups returns the SOPInstanceUIDs added more than once, sorted by UID.
+// Files are sorted by path, each is related to the first one.
+func (d *DuplicateFinder) Groups() []DuplicateGroup {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	groups := []DuplicateGroup{}
+	for uid, files := range d.byUID {
+		if len(files) < 2 {
+			continue
+		}
+		g := DuplicateGroup{SOPInstanceUID: uid, Files: append([]Duplicate{}, files...)}
+		sort.Slice(g.Files, func(i, j int) bool { return g.Files[i].Path < g.Files[j].Path })
+		kept := g.Files[0].Fingerprint
+		for i := 1; i < len(g.Files); i++ {
+			f := g.Files[i].Fingerprint
+			switch {
+			case f.File == kept.File:
+				g.Files[i].Relation = DuplicateExact
+			case f.Dataset == kept.Dataset:
+				g.Files[i].Relation = DuplicateResent
+			case f.Pixels != "" && f.Pixels == kept.Pixels:
+				g.Files[i].Relation = DuplicateTranscoded
+			default:
+				g.Files[i].Relation = DuplicateConflict
+			}
+		}
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].SOPInstanceUID < groups[j].SOPInstanceUID })
+	return groups
+}
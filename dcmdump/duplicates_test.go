@@ -0,0 +1,66 @@
+package dcmdump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestDuplicateFinder(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, uid string, edit func(df *DicomFile)) {
+		df := &DicomFile{}
+		df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+		df.SetElement("00080018", "UI", uid)
+		df.SetElement("00100010", "PN", "DOE^JOHN")
+		df.SetElement("00280002", "US", 1)
+		df.SetElement("00280004", "CS", "MONOCHROME2")
+		df.SetElement("00280010", "US", 1)
+		df.SetElement("00280011", "US", 2)
+		df.SetElement("00280100", "US", 8)
+		df.SetElement("7FE00010", "OB", []byte{1, 2})
+		if edit != nil {
+			edit(df)
+		}
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.dcm", "1.2.3", nil)
+	write("b.dcm", "1.2.3", nil)
+	write("c.dcm", "1.2.3", func(df *DicomFile) { df.SetElement("00020016", "AE", "SENDER") })
+	write("d.dcm", "1.2.3", func(df *DicomFile) { df.SetElement("00100010", "PN", "DOE^J") })
+	write("e.dcm", "1.2.3", func(df *DicomFile) { df.SetElement("7FE00010", "OB", []byte{1, 3}) })
+	write("f.dcm", "1.2.4", nil)
+
+	d := NewDuplicateFinder()
+	d.AddDir(dir, func(path string, err error) { t.Errorf("%s: %s", path, err) })
+	groups := d.Groups()
+	if len(groups) != 1 || groups[0].SOPInstanceUID != "1.2.3" || len(groups[0].Files) != 5 {
+		t.Fatalf("Wrong groups: %+v", groups)
+	}
+	relations := []string{}
+	for _, f := range groups[0].Files {
+		relations = append(relations, f.Relation)
+	}
+	expected := []string{"", DuplicateExact, DuplicateResent, DuplicateTranscoded, DuplicateConflict}
+	if !reflect.DeepEqual(relations, expected) {
+		t.Errorf("Expected %v, got %v", expected, relations)
+	}
+	prunable := groups[0].Prunable()
+	if len(prunable) != 2 || filepath.Base(prunable[0]) != "b.dcm" || filepath.Base(prunable[1]) != "c.dcm" {
+		t.Errorf("Wrong prunable files: %v", prunable)
+	}
+	if err := d.Add("none", &DicomFile{}); err != ErrMissingUID {
+		t.Errorf("Expected ErrMissingUID, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package dcmdump
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"sync"
+)
+
+// ErrEncoder is returned for output formats without a registered encoder.
+var ErrEncoder = errors.New("Unknown output format")
+
+// Encoder writes DicomFiles in an output format.
+type Encoder interface {
+	// Encode writes df. Tabular formats use df.Path as the first column.
+	Encode(df *DicomFile) error
+	// Close ends the output, as with the footer of a Parquet file, and
+	// flushes it. It doesn't close the underlying writer.
+	Close() error
+}
+
+// EncoderOptions - Options of the registered encoders, each one uses the
+// fields that apply to its format.
+type EncoderOptions struct {
+	// Tags restricts the output to the elements with the given GGGGEEEE tags
+	// or keywords, at any level for text and at the top level for JSON and
+	// XML. They are the columns of the tabular formats, as tag paths, see
+	// ParseSelector.
+	Tags []string
+	// Long prints long text values completely.
+	Long bool
+	// Width of the values of the tree format, see TreePrinter.
+	Width int
+	// Join separates multiple values in CSV and TSV columns.
+	Join string
+	// BulkData of the JSON and XML formats, values are inlined when nil.
+	BulkData BulkDataFunc
+}
+
+// NewEncoderFunc returns an Encoder writing to w.
+type NewEncoderFunc func(w io.Writer, o EncoderOptions) (Encoder, error)
+
+var encodersMu sync.RWMutex
+var encoders = map[string]NewEncoderFunc{
+	"text":    newTextEncoder,
+	"tree":    newTreeEncoder,
+	"json":    newJSONEncoder,
+	"xml":     newXMLEncoder,
+	"csv":     newCSVEncoder,
+	"tsv":     newTSVEncoder,
+	"parquet": newParquetEncoder,
+}
+
+// RegisterEncoder sets the encoder of an output format name, replacing any
+// previous one.
+func RegisterEncoder(name string, f NewEncoderFunc) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	encoders[name] = f
+}
+
+// NewEncoder returns an Encoder of the format registered as name.
+func NewEncoder(name string, w io.Writer, o EncoderOptions) (Encoder, error) {
+	encodersMu.RLock()
+	f, ok := encoders[name]
+	encodersMu.RUnlock()
+	if !ok {
+		return nil, ErrEncoder
+	}
+	return f(w, o)
+}
+
+// Encoders returns the registered format names, sorted.
+func Encoders() []string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	names := []string{}
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// markupEncoder - JSON and XML Encoder, a document per line.
+type markupEncoder struct {
+	w       io.Writer
+	o       EncoderOptions
+	marshal func(df *DicomFile, bulkData BulkDataFunc) ([]byte, error)
+}
+
+func newJSONEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	return &markupEncoder{w: w, o: o, marshal: (*DicomFile).JSON}, nil
+}
+
+func newXMLEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	return &markupEncoder{w: w, o: o, marshal: (*DicomFile).XML}, nil
+}
+
+func (e *markupEncoder) Encode(df *DicomFile) error {
+	if len(e.o.Tags) > 0 {
+		filtered := &DicomFile{TransferSyntax: df.TransferSyntax}
+		for _, de := range df.Elements {
+			if de.matches(e.o.Tags) {
+				filtered.Elements = append(filtered.Elements, de)
+			}
+		}
+		df = filtered
+	}
+	b, err := e.marshal(df, e.o.BulkData)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+func (e *markupEncoder) Close() error {
+	return nil
+}
+
+// matches reports whether the tag or keyword of de is in tags.
+func (de *DataElement) matches(tags []string) bool {
+	for _, t := range tags {
+		if de.TagStr == t || de.Name == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package dcmdump
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type countEncoder struct {
+	w io.Writer
+	n int
+}
+
+func (e *countEncoder) Encode(df *DicomFile) error {
+	e.n++
+	return nil
+}
+
+func (e *countEncoder) Close() error {
+	_, err := io.WriteString(e.w, strings.Repeat("x", e.n))
+	return err
+}
+
+func TestEncoders(t *testing.T) {
+	RegisterEncoder("count", func(w io.Writer, o EncoderOptions) (Encoder, error) {
+		return &countEncoder{w: w}, nil
+	})
+	if names := strings.Join(Encoders(), " "); names != "count csv json parquet text tree tsv xml" {
+		t.Errorf("Wrong encoders %s", names)
+	}
+	df := &DicomFile{Path: "a.dcm"}
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00100020", "LO", "ID1")
+
+	expected := map[string]string{
+		"count": "xx",
+		"csv":   "Path,PatientName\na.dcm,DOE^JOHN\na.dcm,DOE^JOHN\n",
+		"tsv":   "Path\tPatientName\na.dcm\tDOE^JOHN\na.dcm\tDOE^JOHN\n",
+		"json":  `{"00100010":{"vr":"PN","Value":[{"Alphabetic":"DOE^JOHN"}]}}` + "\n" + `{"00100010":{"vr":"PN","Value":[{"Alphabetic":"DOE^JOHN"}]}}` + "\n",
+		"text":  strings.Repeat("(0010,0010) PN [DOE^JOHN]                               #   8, 1 PatientName\n", 2),
+	}
+	for name, out := range expected {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(name, &buf, EncoderOptions{Tags: []string{"PatientName"}})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		for i := 0; i < 2; i++ {
+			if err = enc.Encode(df); err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+		if err = enc.Close(); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if buf.String() != out {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, out, buf.String())
+		}
+	}
+	if _, err := NewEncoder("yaml", io.Discard, EncoderOptions{}); err != ErrEncoder {
+		t.Errorf("Expected ErrEncoder, got %v", err)
+	}
+}
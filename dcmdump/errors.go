@@ -0,0 +1,60 @@
+package dcmdump
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownVR is returned when an explicit VR element has a VR that is not
+// part of the standard.
+var ErrUnknownVR = errors.New("Unknown VR")
+
+// ErrMissingDelimiter is returned when the delimitation item of an undefined
+// length element can't be found.
+var ErrMissingDelimiter = errors.New("Could not find delimitation item")
+
+// ErrOddLength is recorded in the Warnings of the parse for elements with an
+// odd value length, the standard requires even lengths.
+var ErrOddLength = errors.New("Odd value length")
+
+// ErrElementSize is returned when an element is longer than the
+// MaxElementSize of the parser.
+var ErrElementSize = errors.New("Element exceeds the maximum size")
+
+// ErrSequenceDepth is returned when sequences are nested deeper than the
+// MaxDepth of the parser.
+var ErrSequenceDepth = errors.New("Sequences exceed the maximum depth")
+
+// ErrElementCount is returned when a file has more elements than the
+// MaxElements of the parser.
+var ErrElementCount = errors.New("Elements exceed the maximum count")
+
+// ParseError records where in the file an element failed to parse.
+// Use errors.Is on the error to branch on the cause, for example
+// io.ErrUnexpectedEOF for truncated files or ErrUnknownVR.
+type ParseError struct {
+	Offset int    // Offset of the element in the file
+	Tag    string // Tag of the element, blank if the tag couldn't be read
+	VR     string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d (%s) %s: %s", e.Offset, e.Tag, e.VR, e.Err)
+}
+
+// Unwrap returns the underlying cause.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// newParseError wraps err with the context of de.
+// Errors from nested elements already carry their own context and are
+// returned as is.
+func newParseError(de *DataElement, err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &ParseError{Offset: de.N, Tag: de.TagStr, VR: de.VRStr, Err: err}
+}
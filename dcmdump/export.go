@@ -0,0 +1,117 @@
+package dcmdump
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Image formats of Export
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatTIFF = "tiff"
+)
+
+// ErrFormat is returned when exporting to an unknown image format.
+var ErrFormat = errors.New("Unsupported image format")
+
+// WindowPresets - Common CT windows by name, in HU.
+var WindowPresets = map[string]Window{
+	"abdomen":     {Center: 40, Width: 400},
+	"bone":        {Center: 400, Width: 1800},
+	"brain":       {Center: 40, Width: 80},
+	"liver":       {Center: 30, Width: 150},
+	"lung":        {Center: -600, Width: 1500},
+	"mediastinum": {Center: 50, Width: 350},
+	"stroke":      {Center: 40, Width: 40},
+}
+
+// ExportOptions - Format and rendering of Export.
+type ExportOptions struct {
+	// Format is FormatPNG, FormatJPEG or FormatTIFF, PNG when empty.
+	Format string
+	// Render selects the VOI transformation, see RenderWithOptions.
+	Render RenderOptions
+	// Quality of JPEG images, jpeg.DefaultQuality when 0.
+	Quality int
+	// Stored writes the stored values of monochrome frames unchanged as 16
+	// bit TIFF, signed for signed pixel data, for quantitative work.
+	Stored bool
+}
+
+// Export writes frame i, starting at 0, as an image. Monochrome frames are
+// written as 16 bit PNG and TIFF and 8 bit JPEG, color frames as 8 bit RGB.
+func (df *DicomFile) Export(w io.Writer, i int, o ExportOptions) error {
+	switch o.Format {
+	case "":
+		o.Format = FormatPNG
+	case FormatPNG, FormatJPEG, FormatTIFF:
+	default:
+		return ErrFormat
+	}
+	if o.Stored {
+		if o.Format != FormatTIFF {
+			return ErrFormat
+		}
+		img, signed, err := df.storedImage(i)
+		if err != nil {
+			return err
+		}
+		return writeTIFF(w, img, signed)
+	}
+	img, err := df.RenderWithOptions(i, o.Render)
+	if err != nil {
+		return err
+	}
+	switch o.Format {
+	case FormatJPEG:
+		quality := o.Quality
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		if _, ok := img.(*image.Gray16); ok {
+			gray := image.NewGray(img.Bounds())
+			draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+			img = gray
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatTIFF:
+		return writeTIFF(w, img, false)
+	}
+	return png.Encode(w, img)
+}
+
+// storedImage returns the stored values of monochrome frame i, as two's
+// complement when signed is returned.
+func (df *DicomFile) storedImage(i int) (*image.Gray16, bool, error) {
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return nil, false, err
+	}
+	if pi.SamplesPerPixel != 1 || pi.PhotometricInterpretation == PhotometricPaletteColor {
+		return nil, false, ErrPixelFormat
+	}
+	frame, err := pi.Frame(i)
+	if err != nil {
+		return nil, false, err
+	}
+	img, err := frame.Decode()
+	if err != nil {
+		return nil, false, err
+	}
+	b := img.Bounds()
+	out := image.NewGray16(image.Rect(0, 0, b.Dx(), b.Dy()))
+	p := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := uint16(int(storedValue(img.At(x, y), pi)))
+			out.Pix[p], out.Pix[p+1] = byte(v>>8), byte(v)
+			p += 2
+		}
+	}
+	return out, pi.PixelRepresentation == 1, nil
+}
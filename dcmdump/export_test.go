@@ -0,0 +1,70 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestExport(t *testing.T) {
+	df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	df.SetElement("00280002", "US", 1)
+	df.SetElement("00280004", "CS", "MONOCHROME2")
+	df.SetElement("00280010", "US", 1)
+	df.SetElement("00280011", "US", 2)
+	df.SetElement("00280100", "US", 16)
+	df.SetElement("00280103", "US", 1)
+	df.SetElement("00281052", "DS", -1024)
+	df.SetElement("7FE00010", "OW", []byte{0x18, 0xfc, 0xe8, 0x03}) // -1000, 1000
+
+	var buf bytes.Buffer
+	err := df.Export(&buf, 0, ExportOptions{Render: RenderOptions{Window: &Window{Center: 0, Width: 4000}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if gray, ok := img.(*image.Gray16); !ok || gray.Gray16At(0, 0).Y >= gray.Gray16At(1, 0).Y {
+		t.Errorf("Wrong PNG: %v", img)
+	}
+
+	buf.Reset()
+	err = df.Export(&buf, 0, ExportOptions{Format: FormatTIFF, Stored: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	b := buf.Bytes()
+	le := binary.LittleEndian
+	if string(b[:4]) != "II*\x00" || le.Uint32(b[4:]) != 8 {
+		t.Fatalf("Wrong TIFF header: %x", b[:8])
+	}
+	entries := map[uint16]uint32{}
+	for i, n := 0, int(le.Uint16(b[8:])); i < n; i++ {
+		e := b[10+12*i:]
+		if le.Uint16(e[2:]) == 3 {
+			entries[le.Uint16(e)] = uint32(le.Uint16(e[8:]))
+		} else {
+			entries[le.Uint16(e)] = le.Uint32(e[8:])
+		}
+	}
+	if entries[256] != 2 || entries[257] != 1 || entries[258] != 16 || entries[339] != 2 || entries[279] != 4 {
+		t.Errorf("Wrong TIFF entries: %v", entries)
+	}
+	strip := b[entries[273]:]
+	if int16(le.Uint16(strip)) != -1000 || int16(le.Uint16(strip[2:])) != 1000 {
+		t.Errorf("Wrong stored values: %x", strip)
+	}
+
+	if err = df.Export(&buf, 0, ExportOptions{Format: "bmp"}); err != ErrFormat {
+		t.Errorf("Expected ErrFormat, got %v", err)
+	}
+	if err = df.Export(&buf, 0, ExportOptions{Format: FormatPNG, Stored: true}); err != ErrFormat {
+		t.Errorf("Expected ErrFormat, got %v", err)
+	}
+}
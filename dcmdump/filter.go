@@ -0,0 +1,298 @@
+package dcmdump
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrFilter is returned for invalid filter expressions.
+var ErrFilter = errors.New("Invalid filter expression")
+
+// Filter - Boolean expression on the elements of a file, as in
+//
+//	Modality=="CT" && StudyDate>="20230101"
+//	!(SeriesDescription=="SCOUT" || ImageType=="LOCALIZER") && Rows>256
+//
+// Comparisons take a tag path on the left, see ParseSelector, and a quoted
+// string or a number on the right. They hold when any value of any element
+// matched does: strings are compared as text, so DA and TM values order by
+// date and time, and numbers numerically with the values of IS, DS and
+// binary elements. A tag path on its own tests for the presence of the
+// element. Expressions are combined with !, && and || and grouped with
+// parentheses.
+type Filter struct {
+	expr string
+	root filterNode
+	// tags of the top level elements used, nil when a path starts with *.
+	tags []string
+}
+
+type filterNode interface {
+	match(df *DicomFile) bool
+}
+
+// ParseFilter parses a Filter expression.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := filterTokens(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens, tags: []string{}}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, ErrFilter
+	}
+	return &Filter{expr: expr, root: root, tags: p.tags}, nil
+}
+
+// Match reports whether df matches the filter.
+func (f *Filter) Match(df *DicomFile) bool {
+	return f.root.match(df)
+}
+
+// String returns the expression of the filter.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// keep returns tags extended with the elements needed by the filter, nil
+// when it needs all of them.
+func (f *Filter) keep(tags []string) []string {
+	if f.tags == nil {
+		return nil
+	}
+	keep := append([]string{}, tags...)
+	for _, t := range f.tags {
+		if !stringInSlice(t, keep) {
+			keep = append(keep, t)
+		}
+	}
+	return keep
+}
+
+var (
+	filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+	filterTagRE     = regexp.MustCompile(`^\([0-9A-Fa-f]{4},[0-9A-Fa-f]{4}\)`)
+)
+
+// filterTokens splits expr into operators, quoted strings, kept quoted, and
+// words, tag paths or numbers.
+func filterTokens(expr string) ([]string, error) {
+	tokens := []string{}
+	word := func(c byte) bool {
+		return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("_.[]*+-", c) >= 0
+	}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+			continue
+		case c == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, ErrFilter
+			}
+			tokens = append(tokens, expr[i:j+1])
+			i = j + 1
+			continue
+		case word(c) || filterTagRE.MatchString(expr[i:]):
+			j := i
+			for j < len(expr) {
+				if t := filterTagRE.FindString(expr[j:]); t != "" {
+					j += len(t)
+				} else if word(expr[j]) {
+					j++
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+			continue
+		}
+		op := ""
+		for _, o := range filterOperators {
+			if strings.HasPrefix(expr[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, ErrFilter
+		}
+		tokens = append(tokens, op)
+		i += len(op)
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+	tags   []string
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// or := and { "||" and }
+func (p *filterParser) or() (filterNode, error) {
+	n, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var right filterNode
+		right, err = p.and()
+		n = filterOr{n, right}
+	}
+	return n, err
+}
+
+// and := unary { "&&" unary }
+func (p *filterParser) and() (filterNode, error) {
+	n, err := p.unary()
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var right filterNode
+		right, err = p.unary()
+		n = filterAnd{n, right}
+	}
+	return n, err
+}
+
+// unary := "!" unary | "(" or ")" | comparison
+func (p *filterParser) unary() (filterNode, error) {
+	switch p.peek() {
+	case "!":
+		p.next()
+		n, err := p.unary()
+		return filterNot{n}, err
+	case "(":
+		p.next()
+		n, err := p.or()
+		if err == nil && p.next() != ")" {
+			err = ErrFilter
+		}
+		return n, err
+	}
+	return p.comparison()
+}
+
+// comparison := path [ op ( string | number ) ]
+func (p *filterParser) comparison() (filterNode, error) {
+	s, err := ParseSelector(p.next())
+	if err != nil {
+		return nil, ErrFilter
+	}
+	if s[0].tag == "" {
+		p.tags = nil
+	} else if p.tags != nil {
+		p.tags = append(p.tags, s[0].tag)
+	}
+	c := filterComparison{selector: s}
+	switch p.peek() {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return c, nil
+	}
+	c.op = p.next()
+	value := p.next()
+	if strings.HasPrefix(value, `"`) {
+		c.value, err = strconv.Unquote(value)
+		if err != nil {
+			return nil, ErrFilter
+		}
+		return c, nil
+	}
+	c.number, err = strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, ErrFilter
+	}
+	c.numeric = true
+	return c, nil
+}
+
+type filterOr [2]filterNode
+
+func (n filterOr) match(df *DicomFile) bool { return n[0].match(df) || n[1].match(df) }
+
+type filterAnd [2]filterNode
+
+func (n filterAnd) match(df *DicomFile) bool { return n[0].match(df) && n[1].match(df) }
+
+type filterNot [1]filterNode
+
+func (n filterNot) match(df *DicomFile) bool { return !n[0].match(df) }
+
+type filterComparison struct {
+	selector Selector
+	// op is blank for presence tests.
+	op      string
+	value   string
+	number  float64
+	numeric bool
+}
+
+func (c filterComparison) match(df *DicomFile) bool {
+	matches := c.selector.Select(df)
+	if c.op == "" {
+		return len(matches) > 0
+	}
+	for _, de := range matches {
+		e := *de
+		e.Load()
+		if c.numeric {
+			for _, v := range e.Floats() {
+				if holds(c.op, v-c.number) {
+					return true
+				}
+			}
+			continue
+		}
+		for _, v := range e.Strings() {
+			if holds(c.op, float64(strings.Compare(strings.TrimSpace(v), c.value))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// holds reports whether op holds for a difference d between two values.
+func holds(op string, d float64) bool {
+	switch op {
+	case "==":
+		return d == 0
+	case "!=":
+		return d != 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	case ">":
+		return d > 0
+	}
+	return d >= 0
+}
@@ -0,0 +1,88 @@
+package dcmdump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestFilter(t *testing.T) {
+	df := &DicomFile{}
+	df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY", "AXIAL"})
+	df.SetElement("00080020", "DA", "20230315")
+	df.SetElement("00080060", "CS", "CT")
+	df.SetElement("0008103E", "LO", `Head "routine"`)
+	df.SetElement("00280010", "US", 512)
+	df.SetElement("00281050", "DS", []string{"40", "400"})
+
+	cases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`Modality=="CT" && StudyDate>="20230101"`, true},
+		{`Modality=="MR" || StudyDate<"20230101"`, false},
+		{`(0008,0060) != "CT"`, false},
+		{`ImageType=="AXIAL"`, true},
+		{`!(ImageType=="LOCALIZER") && Rows>256`, true},
+		{`Rows<=256 || WindowCenter>=400`, true},
+		{`WindowCenter==-40`, false},
+		{`SeriesDescription=="Head \"routine\""`, true},
+		{`PatientName`, false},
+		{`!PatientName && 00280010==512`, true},
+		{`Modality=="MR" || Rows==512 && StudyDate=="20230315"`, true},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", c.expr, err)
+		}
+		if f.Match(df) != c.expected {
+			t.Errorf("%s: expected %v", c.expr, c.expected)
+		}
+	}
+	for _, expr := range []string{``, `Modality==`, `Modality=="CT`, `(Modality=="CT"`, `Modality==CT`, `NotAKeyword`, `Rows>1 Rows`, `Rows % 2`} {
+		if _, err := ParseFilter(expr); err != ErrFilter {
+			t.Errorf("%s: expected ErrFilter, got %v", expr, err)
+		}
+	}
+}
+
+func TestWalkerFilter(t *testing.T) {
+	dir := t.TempDir()
+	for i, modality := range []string{"CT", "MR", "CT"} {
+		df := &DicomFile{}
+		df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+		df.SetElement("00080060", "CS", modality)
+		df.SetElement("00100020", "LO", "P1")
+		f, err := os.Create(filepath.Join(dir, string(rune('a'+i))+".dcm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := NewWalker(dir, []string{"00100020"})
+	var err error
+	w.Filter, err = ParseFilter(`Modality=="CT"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for r := range w.Walk() {
+		if r.Err != nil {
+			t.Fatalf("%s: %s", r.Path, r.Err)
+		}
+		if filepath.Base(r.Path) == "b.dcm" {
+			t.Errorf("MR file not filtered")
+		}
+		n++
+	}
+	if s := w.Stats(); n != 2 || s.Files != 2 || s.Filtered != 1 {
+		t.Errorf("Wrong results %d, stats %+v", n, s)
+	}
+}
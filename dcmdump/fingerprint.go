@@ -0,0 +1,129 @@
+package dcmdump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"io"
+	"os"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// Fingerprint - SHA-256 hashes of a file, hex encoded.
+type Fingerprint struct {
+	// File hashes the bytes of the file at Path, or of the file as written
+	// with its transfer syntax when it has no Path.
+	File string
+	// Dataset hashes the elements outside of group 0002 written as explicit
+	// VR little endian, so it doesn't change with byte order nor VR encoding.
+	Dataset string
+	// Pixels hashes the decoded values of all frames, it doesn't change when
+	// transcoding with a lossless transfer syntax.
+	// It is blank for files without PixelData.
+	Pixels string
+}
+
+// Fingerprint returns the hashes of the file, its dataset and its decoded
+// pixel data.
+// Frames are read one at a time, ErrUnsupportedTS is returned when no
+// decoder is registered for the transfer syntax of the pixel data.
+func (df *DicomFile) Fingerprint() (*Fingerprint, error) {
+	f, err := df.hashes()
+	if err != nil {
+		return nil, err
+	}
+	fr, err := df.FrameReader()
+	if err == ErrNoPixelData {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	for {
+		frame, err := fr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		img, err := frame.Decode()
+		if err != nil {
+			return nil, err
+		}
+		writePixels(h, img)
+	}
+	f.Pixels = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
+
+// hashes returns the File and Dataset hashes of the file.
+func (df *DicomFile) hashes() (*Fingerprint, error) {
+	f := &Fingerprint{}
+	h := sha256.New()
+	err := df.writeFile(h)
+	if err != nil {
+		return nil, err
+	}
+	f.File = hex.EncodeToString(h.Sum(nil))
+
+	h = sha256.New()
+	err = df.WriteDataset(h, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		return nil, err
+	}
+	f.Dataset = hex.EncodeToString(h.Sum(nil))
+	return f, nil
+}
+
+// writeFile copies the file at df.Path to w, or writes df with its transfer
+// syntax when it wasn't read from a path.
+func (df *DicomFile) writeFile(w io.Writer) error {
+	if df.Path == "" {
+		transferSyntax := df.TransferSyntax
+		if v := df.stringValue("00020010"); v != "" {
+			transferSyntax = v
+		}
+		return df.Write(w, transferSyntax)
+	}
+	fh, err := os.Open(df.Path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	_, err = io.Copy(w, fh)
+	return err
+}
+
+// writePixels writes the pixel values of img in row order.
+// Gray images are written with their stored values, big endian for 16 bits,
+// other images as 8 bit RGB when opaque and 16 bit RGBA otherwise.
+func writePixels(w io.Writer, img image.Image) {
+	b := img.Bounds()
+	switch img := img.(type) {
+	case *image.Gray:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			w.Write(img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)])
+		}
+		return
+	case *image.Gray16:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			w.Write(img.Pix[img.PixOffset(b.Min.X, y):img.PixOffset(b.Max.X, y)])
+		}
+		return
+	}
+	row := []byte{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row = row[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBA64Model.Convert(img.At(x, y)).(color.RGBA64)
+			if c.A == 0xffff {
+				row = append(row, byte(c.R>>8), byte(c.G>>8), byte(c.B>>8))
+			} else {
+				row = append(row, byte(c.R>>8), byte(c.R), byte(c.G>>8), byte(c.G), byte(c.B>>8), byte(c.B), byte(c.A>>8), byte(c.A))
+			}
+		}
+		w.Write(row)
+	}
+}
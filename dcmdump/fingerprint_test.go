@@ -0,0 +1,92 @@
+package dcmdump
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestFingerprint(t *testing.T) {
+	df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00020010", "UI", ts.ExplicitVRLittleEndian},
+		{"00280002", "US", 1},
+		{"00280010", "US", 1},
+		{"00280011", "US", 2},
+		{"00280100", "US", 16},
+		{"7FE00010", "OW", []int{500, 600}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := df.Fingerprint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(f.File) != 64 || len(f.Dataset) != 64 || len(f.Pixels) != 64 {
+		t.Fatalf("Wrong hashes: %v", f)
+	}
+
+	// Transcoding keeps the dataset and pixel hashes
+	var buf bytes.Buffer
+	err = df.Write(&buf, ts.ExplicitVRBigEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	p := NewParser(bytes.NewReader(buf.Bytes()[132:]), 132, true, []string{})
+	p.StopAt = ""
+	elements, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	be, err := (&DicomFile{Elements: elements, TransferSyntax: p.TransferSyntax}).Fingerprint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if be.File == f.File || be.Dataset != f.Dataset || be.Pixels != f.Pixels {
+		t.Errorf("Wrong transcoded hashes:\n%v\n%v", f, be)
+	}
+
+	// Files read from a path hash their bytes, not a re-serialization
+	path := filepath.Join(t.TempDir(), "file.dcm")
+	data := append(buf.Bytes(), 0, 0)
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromPath, err := (&DicomFile{Elements: elements, TransferSyntax: p.TransferSyntax, Path: path}).Fingerprint()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if sum := sha256.Sum256(data); fromPath.File != hex.EncodeToString(sum[:]) || fromPath.Dataset != f.Dataset {
+		t.Errorf("Wrong hashes of %s: %v", path, fromPath)
+	}
+
+	err = df.SetElement("7FE00010", "OW", []int{500, 601})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := df.Fingerprint()
+	if err != nil || changed.Pixels == f.Pixels {
+		t.Errorf("Pixel hash not changed: %v, %v", changed, err)
+	}
+
+	err = df.DeleteElement("7FE00010")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err = df.Fingerprint()
+	if err != nil || changed.Pixels != "" {
+		t.Errorf("Expected blank pixel hash: %v, %v", changed, err)
+	}
+}
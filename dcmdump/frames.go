@@ -0,0 +1,115 @@
+package dcmdump
+
+// Functional group sequences of enhanced multi-frame IODs, PS3.3 C.7.6.16
+const (
+	SharedFunctionalGroupsSequence   = "52009229"
+	PerFrameFunctionalGroupsSequence = "52009230"
+)
+
+// FrameAttributes - Attributes of a single frame, from the functional groups
+// of enhanced multi-frame files or the top level elements of other files.
+// Attributes missing from the file are nil.
+type FrameAttributes struct {
+	ImagePositionPatient    []float64
+	ImageOrientationPatient []float64
+	PixelSpacing            []float64
+	SliceThickness          *float64
+	// RescaleSlope and RescaleIntercept default to 1 and 0.
+	RescaleSlope     float64
+	RescaleIntercept float64
+	WindowCenter     []float64
+	WindowWidth      []float64
+	// DimensionIndexValues of the frame in the FrameContentSequence.
+	DimensionIndexValues []int
+}
+
+// FrameElement returns the element with the given tag that applies to frame
+// i, starting at 0.
+// The functional group macros of the PerFrameFunctionalGroupsSequence item of
+// the frame are searched first, then the ones of the
+// SharedFunctionalGroupsSequence and last the top level elements.
+func (df *DicomFile) FrameElement(i int, tagStr string) (*DataElement, error) {
+	if i < 0 {
+		return nil, ErrFrameIndex
+	}
+	if perFrame, err := df.LookupElement(PerFrameFunctionalGroupsSequence); err == nil {
+		if i >= len(perFrame.Items) {
+			return nil, ErrFrameIndex
+		}
+		if de := functionalGroupElement(perFrame.Items[i], tagStr); de != nil {
+			return de, nil
+		}
+	}
+	if shared, err := df.LookupElement(SharedFunctionalGroupsSequence); err == nil && len(shared.Items) > 0 {
+		if de := functionalGroupElement(shared.Items[0], tagStr); de != nil {
+			return de, nil
+		}
+	}
+	return df.LookupElement(tagStr)
+}
+
+// functionalGroupElement looks up tagStr in each functional group macro
+// sequence of item.
+func functionalGroupElement(item Item, tagStr string) *DataElement {
+	for _, macro := range item.Elements {
+		if macro.VRStr != "SQ" && len(macro.Items) == 0 {
+			continue
+		}
+		for _, macroItem := range macro.Items {
+			for j := range macroItem.Elements {
+				if macroItem.Elements[j].TagStr == tagStr {
+					return &macroItem.Elements[j]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// FrameAttributes returns the position, orientation, spacing, rescale and
+// window of frame i, starting at 0.
+func (df *DicomFile) FrameAttributes(i int) (*FrameAttributes, error) {
+	fa := &FrameAttributes{RescaleSlope: 1}
+	for _, a := range []struct {
+		tag string
+		v   *[]float64
+	}{
+		{"00200032", &fa.ImagePositionPatient},
+		{"00200037", &fa.ImageOrientationPatient},
+		{"00280030", &fa.PixelSpacing},
+		{"00281050", &fa.WindowCenter},
+		{"00281051", &fa.WindowWidth},
+	} {
+		de, err := df.FrameElement(i, a.tag)
+		if err == ErrFrameIndex {
+			return nil, err
+		}
+		if err == nil && len(de.Data) > 0 {
+			*a.v = de.Floats()
+		}
+	}
+	fa.SliceThickness = df.frameFloat(i, "00180050")
+	if v := df.frameFloat(i, "00281053"); v != nil {
+		fa.RescaleSlope = *v
+	}
+	if v := df.frameFloat(i, "00281052"); v != nil {
+		fa.RescaleIntercept = *v
+	}
+	if de, err := df.FrameElement(i, "00209157"); err == nil {
+		fa.DimensionIndexValues = de.Ints()
+	}
+	return fa, nil
+}
+
+// frameFloat returns the first value of a numeric element of frame i or nil.
+func (df *DicomFile) frameFloat(i int, tagStr string) *float64 {
+	de, err := df.FrameElement(i, tagStr)
+	if err != nil {
+		return nil
+	}
+	values := de.Floats()
+	if len(values) == 0 || de.Strings()[0] == "" {
+		return nil
+	}
+	return &values[0]
+}
@@ -0,0 +1,63 @@
+package dcmdump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrameAttributes(t *testing.T) {
+	macro := func(sequence, tagStr, vr string, value interface{}) DataElement {
+		de, err := NewDataElement(tagStr, vr, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		sq, err := NewDataElement(sequence, "SQ", []Item{{Elements: []DataElement{de}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sq.PartOfSQ = true
+		return sq
+	}
+	df := &DicomFile{}
+	df.SetElement("00280008", "IS", 2)
+	df.SetElement(SharedFunctionalGroupsSequence, "SQ", []Item{{Elements: []DataElement{
+		macro("00289110", "00280030", "DS", []float64{0.5, 0.5}),
+		macro("00289145", "00281053", "DS", 2),
+	}}})
+	perFrame := []Item{}
+	for _, z := range []float64{10, 12.5} {
+		perFrame = append(perFrame, Item{Elements: []DataElement{
+			macro("00209113", "00200032", "DS", []float64{0, 0, z}),
+			macro("00289145", "00281052", "DS", -z),
+		}})
+	}
+	df.SetElement(PerFrameFunctionalGroupsSequence, "SQ", perFrame)
+	df.SetElement("00281052", "DS", -1024)
+
+	fa, err := df.FrameAttributes(1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := &FrameAttributes{
+		ImagePositionPatient: []float64{0, 0, 12.5},
+		PixelSpacing:         []float64{0.5, 0.5},
+		RescaleSlope:         2,
+		RescaleIntercept:     -12.5,
+	}
+	if !reflect.DeepEqual(fa, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, fa)
+	}
+	_, err = df.FrameAttributes(2)
+	if err != ErrFrameIndex {
+		t.Errorf("Expected ErrFrameIndex, got %v", err)
+	}
+
+	// Classic files use the top level elements for every frame
+	classic := &DicomFile{}
+	classic.SetElement("00281052", "DS", -1024)
+	de, err := classic.FrameElement(0, "00281052")
+	if err != nil || de.Floats()[0] != -1024 {
+		t.Errorf("Unexpected top level element %v, %v", de, err)
+	}
+}
@@ -0,0 +1,119 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// fuzzSeeds returns valid files for the fuzz corpus.
+func fuzzSeeds(t testing.TB) [][]byte {
+	item, err := NewDataElement("00081150", "UI", "1.2.840.10008.5.1.4.1.1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item.PartOfSQ = true
+	df := &DicomFile{}
+	df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+	df.SetElement("00080005", "CS", "ISO_IR 100")
+	df.SetElement("00081115", "SQ", []Item{{Elements: []DataElement{item}}})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00280010", "US", 2)
+	df.SetElement("00280011", "US", 2)
+	df.SetElement("00280100", "US", 8)
+	df.SetElement("7FE00010", "OB", []byte{1, 2, 3, 4})
+	// Empty TransferSyntaxUID and padded values, which used to panic
+	empty := append(make([]byte, 128), "DICM"...)
+	empty = append(empty, explicitElement(0x0002, 0x0010, "UI", nil)...)
+	empty = append(empty, explicitElement(0x0008, 0x0016, "UI", nil)...)
+	seeds := [][]byte{sampleFile(), empty}
+	for _, uid := range []string{ts.ExplicitVRLittleEndian, ts.ImplicitVRLittleEndian, ts.ExplicitVRBigEndian} {
+		var buf bytes.Buffer
+		if err := df.Write(&buf, uid); err != nil {
+			t.Fatal(err)
+		}
+		seeds = append(seeds, buf.Bytes())
+	}
+	return seeds
+}
+
+func FuzzParseFile(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, bestEffort bool) {
+		o := ParseOptions{MaxElementSize: 1 << 20, MaxDepth: 16, MaxElements: 10000, BestEffort: bestEffort}
+		df, err := ParseFileWithOptions(bytes.NewReader(data), o)
+		if err != nil {
+			return
+		}
+		// Decoding the values of any element read must not panic
+		var visit func(elements []DataElement)
+		visit = func(elements []DataElement) {
+			for i := range elements {
+				de := &elements[i]
+				_ = de.String()
+				de.Strings()
+				de.Floats()
+				de.Ints()
+				de.Time()
+				for _, item := range de.Items {
+					visit(item.Elements)
+				}
+			}
+		}
+		visit(df.Elements)
+		if pi, err := df.PixelDataInfo(); err == nil {
+			pi.OffsetTable()
+			pi.Fragments()
+			for i := 0; i < pi.NumberOfFrames && i < 2; i++ {
+				if frame, err := pi.Frame(i); err == nil {
+					frame.Decode()
+				}
+			}
+		}
+		df.JSON(nil)
+		df.XML(nil)
+		var buf bytes.Buffer
+		df.Write(&buf, ts.ExplicitVRLittleEndian)
+	})
+}
+
+func FuzzParseDataset(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed[132:], true)
+		f.Add(seed[132:], false)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, explicit bool) {
+		p := NewParser(bytes.NewReader(data), 0, explicit, []string{})
+		p.StopAt = ""
+		p.MaxElementSize, p.MaxDepth, p.MaxElements = 1<<20, 16, 10000
+		elements, err := p.Parse()
+		if err != nil {
+			return
+		}
+		for i := range elements {
+			_ = elements[i].String()
+			elements[i].Strings()
+		}
+	})
+}
+
+func FuzzDecodeElementHeader(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed[132:], true)
+		f.Add(seed[132:], false)
+	}
+	f.Fuzz(func(t *testing.T, data []byte, explicit bool) {
+		h, n, err := DecodeElementHeader(data, explicit, binary.LittleEndian)
+		if n > len(data) {
+			t.Fatalf("header size %d past the buffer %d", n, len(data))
+		}
+		if err == nil && n != 8 && n != 12 {
+			t.Fatalf("header size %d for %+v", n, h)
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package dcmdump
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrGeometry is returned for frames without a valid ImagePositionPatient,
+// ImageOrientationPatient or PixelSpacing.
+var ErrGeometry = errors.New("Missing or invalid image plane attributes")
+
+// Plane - Position of a frame in the patient based coordinate system, PS3.3
+// C.7.6.2. Coordinates are in mm, pixel indices start at 0 with integer
+// indices at pixel centers.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.7.6.2.html
+type Plane struct {
+	// Position of the center of the top left pixel.
+	Position [3]float64
+	// Row and Column direction cosines, Row pointing to increasing columns.
+	Row, Column [3]float64
+	// PixelSpacing between rows then between columns, as in the attribute.
+	PixelSpacing  [2]float64
+	Rows, Columns int
+	// Thickness is the SliceThickness, 0 when unknown.
+	Thickness float64
+}
+
+// FramePlane returns the plane of frame i, starting at 0.
+func (df *DicomFile) FramePlane(i int) (*Plane, error) {
+	fa, err := df.FrameAttributes(i)
+	if err != nil {
+		return nil, err
+	}
+	position, orientation, spacing := fa.ImagePositionPatient, fa.ImageOrientationPatient, fa.PixelSpacing
+	if len(position) != 3 || len(orientation) != 6 || len(spacing) != 2 || spacing[0] <= 0 || spacing[1] <= 0 {
+		return nil, ErrGeometry
+	}
+	p := &Plane{PixelSpacing: [2]float64{spacing[0], spacing[1]}}
+	copy(p.Position[:], position)
+	copy(p.Row[:], orientation[:3])
+	copy(p.Column[:], orientation[3:])
+	if norm(p.Row) == 0 || norm(p.Column) == 0 {
+		return nil, ErrGeometry
+	}
+	if fa.SliceThickness != nil {
+		p.Thickness = *fa.SliceThickness
+	}
+	if de, err := df.FrameElement(i, "00280010"); err == nil && len(de.Ints()) > 0 {
+		p.Rows = de.Ints()[0]
+	}
+	if de, err := df.FrameElement(i, "00280011"); err == nil && len(de.Ints()) > 0 {
+		p.Columns = de.Ints()[0]
+	}
+	return p, nil
+}
+
+// FramePlanes returns the planes of all the frames of the file in frame
+// order.
+func (df *DicomFile) FramePlanes() ([]*Plane, error) {
+	frames := 1
+	if de, err := df.LookupElement("00280008"); err == nil && len(de.Ints()) > 0 && de.Ints()[0] > 1 {
+		frames = de.Ints()[0]
+	}
+	planes := make([]*Plane, frames)
+	for i := range planes {
+		p, err := df.FramePlane(i)
+		if err != nil {
+			return nil, err
+		}
+		planes[i] = p
+	}
+	return planes, nil
+}
+
+// Normal returns the unit normal of the plane, Row × Column.
+func (p *Plane) Normal() [3]float64 {
+	r, c := p.Row, p.Column
+	n := [3]float64{
+		r[1]*c[2] - r[2]*c[1],
+		r[2]*c[0] - r[0]*c[2],
+		r[0]*c[1] - r[1]*c[0],
+	}
+	l := norm(n)
+	if l == 0 {
+		return n
+	}
+	return [3]float64{n[0] / l, n[1] / l, n[2] / l}
+}
+
+// Location returns the distance of the plane from the origin along its
+// normal, used to sort parallel slices.
+func (p *Plane) Location() float64 {
+	return dot(p.Position, p.Normal())
+}
+
+// Patient returns the patient coordinates of the pixel at col and row.
+func (p *Plane) Patient(col, row float64) [3]float64 {
+	x, y := col*p.PixelSpacing[1], row*p.PixelSpacing[0]
+	out := p.Position
+	for k := range out {
+		out[k] += p.Row[k]*x + p.Column[k]*y
+	}
+	return out
+}
+
+// Pixel returns the column and row of the projection of point on the plane,
+// and its signed distance from the plane along the normal.
+func (p *Plane) Pixel(point [3]float64) (col, row, distance float64) {
+	d := [3]float64{point[0] - p.Position[0], point[1] - p.Position[1], point[2] - p.Position[2]}
+	return dot(d, p.Row) / p.PixelSpacing[1], dot(d, p.Column) / p.PixelSpacing[0], dot(d, p.Normal())
+}
+
+// Contains reports whether the pixel at col and row is inside the frame.
+func (p *Plane) Contains(col, row float64) bool {
+	return col > -0.5 && row > -0.5 && col < float64(p.Columns)-0.5 && row < float64(p.Rows)-0.5
+}
+
+// SortPlanes sorts planes in place by their location along the normal of the
+// first one.
+func SortPlanes(planes []*Plane) {
+	if len(planes) == 0 {
+		return
+	}
+	n := planes[0].Normal()
+	sort.SliceStable(planes, func(i, j int) bool {
+		return dot(planes[i].Position, n) < dot(planes[j].Position, n)
+	})
+}
+
+// SliceSpacing - Distances between consecutive sorted slices.
+type SliceSpacing struct {
+	// Spacing is the median distance between slices.
+	Spacing   float64
+	Distances []float64
+	// Gaps and Overlaps are the indices i of the distances between slices i
+	// and i+1 longer or shorter than Spacing by more than the tolerance.
+	// Slices at the same location are overlaps.
+	Gaps     []int
+	Overlaps []int
+	// Parallel is false when the slices don't share an orientation.
+	Parallel bool
+}
+
+// Regular reports whether the slices are parallel, without gaps nor
+// overlaps.
+func (s *SliceSpacing) Regular() bool {
+	return s.Parallel && len(s.Gaps) == 0 && len(s.Overlaps) == 0
+}
+
+// CheckSpacing measures the distances between planes sorted with SortPlanes,
+// tolerance is in mm.
+func CheckSpacing(planes []*Plane, tolerance float64) *SliceSpacing {
+	s := &SliceSpacing{Parallel: true}
+	if len(planes) == 0 {
+		return s
+	}
+	n := planes[0].Normal()
+	for i, p := range planes {
+		if pn := p.Normal(); math.Abs(dot(pn, n)-1) > 1e-3 {
+			s.Parallel = false
+		}
+		if i > 0 {
+			s.Distances = append(s.Distances, dot(p.Position, n)-dot(planes[i-1].Position, n))
+		}
+	}
+	if len(s.Distances) == 0 {
+		return s
+	}
+	sorted := append([]float64{}, s.Distances...)
+	sort.Float64s(sorted)
+	s.Spacing = sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		s.Spacing = (sorted[len(sorted)/2-1] + s.Spacing) / 2
+	}
+	for i, d := range s.Distances {
+		switch {
+		case d > s.Spacing+tolerance:
+			s.Gaps = append(s.Gaps, i)
+		case d < s.Spacing-tolerance || d <= tolerance:
+			s.Overlaps = append(s.Overlaps, i)
+		}
+	}
+	return s
+}
+
+// Planes returns the planes of the instances of the series in the order of
+// SortedInstances, ErrGeometry when one of them has none.
+func (s *Series) Planes() ([]*Plane, error) {
+	planes := []*Plane{}
+	for _, in := range s.SortedInstances() {
+		if in.File == nil {
+			return nil, ErrGeometry
+		}
+		p, err := in.File.FramePlane(0)
+		if err != nil {
+			return nil, err
+		}
+		planes = append(planes, p)
+	}
+	return planes, nil
+}
+
+func dot(a, b [3]float64) float64 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func norm(a [3]float64) float64 {
+	return math.Sqrt(dot(a, a))
+}
@@ -0,0 +1,55 @@
+package dcmdump
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGeometry(t *testing.T) {
+	slice := func(z float64) *DicomFile {
+		df := &DicomFile{}
+		df.SetElement("00200032", "DS", []float64{-100, -50, z})
+		// Sagittal rows, coronal columns
+		df.SetElement("00200037", "DS", []float64{0, 1, 0, 0, 0, -1})
+		df.SetElement("00280010", "US", 256)
+		df.SetElement("00280011", "US", 128)
+		df.SetElement("00280030", "DS", []float64{0.5, 0.25})
+		return df
+	}
+	p, err := slice(10).FramePlane(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if n := p.Normal(); n != [3]float64{-1, 0, 0} {
+		t.Errorf("Wrong normal: %v", n)
+	}
+	point := p.Patient(4, 2)
+	if point != [3]float64{-100, -49, 9} {
+		t.Errorf("Wrong patient coordinates: %v", point)
+	}
+	col, row, d := p.Pixel([3]float64{-98, -49, 9})
+	if math.Abs(col-4) > 1e-9 || math.Abs(row-2) > 1e-9 || math.Abs(d+2) > 1e-9 {
+		t.Errorf("Wrong pixel: %v, %v, %v", col, row, d)
+	}
+	if !p.Contains(127, 255) || p.Contains(128, 0) {
+		t.Errorf("Wrong bounds")
+	}
+	if _, err = (&DicomFile{}).FramePlane(0); err != ErrGeometry {
+		t.Errorf("Expected ErrGeometry, got %v", err)
+	}
+
+	planes := []*Plane{}
+	for _, x := range []float64{-90, -100, -80, -70, -40, -40} {
+		p := *p
+		p.Position[0] = x
+		planes = append(planes, &p)
+	}
+	SortPlanes(planes)
+	if planes[0].Position[0] != -40 || planes[5].Position[0] != -100 {
+		t.Errorf("Wrong order: %v, %v", planes[0].Position, planes[5].Position)
+	}
+	s := CheckSpacing(planes, 0.1)
+	if s.Spacing != 10 || len(s.Gaps) != 1 || s.Gaps[0] != 1 || len(s.Overlaps) != 1 || s.Overlaps[0] != 0 || s.Regular() {
+		t.Errorf("Wrong spacing: %+v", s)
+	}
+}
@@ -0,0 +1,72 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"errors"
+
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// ErrShortBuffer is returned by DecodeElementHeader when the buffer ends
+// before the header.
+var ErrShortBuffer = errors.New("Buffer too short for element header")
+
+// Header - Tag, VR and length of a data element, see DecodeElementHeader.
+type Header struct {
+	Group   uint16
+	Element uint16
+	// VR is blank for implicit VR elements and items, "\x00\x00" for blank
+	// explicit VRs.
+	VR     string
+	Length uint32
+}
+
+// Tag returns the GGGGEEEE tag of h.
+func (h Header) Tag() string {
+	return formatTag(h.Group, h.Element)
+}
+
+// Undefined reports whether the value of h ends with a delimitation item.
+func (h Header) Undefined() bool {
+	return h.Length == 0xFFFFFFFF
+}
+
+// DecodeElementHeader decodes the element header at the start of buf,
+// returning it with its size n, 8 or 12 bytes.
+// Items and delimitation items (FFFE group) are always decoded without VR.
+// It returns ErrShortBuffer, with the fields read so far, when buf ends within
+// the header and ErrUnknownVR, with n past the VR, for VRs that aren't part of
+// the standard.
+func DecodeElementHeader(buf []byte, explicit bool, order binary.ByteOrder) (h Header, n int, err error) {
+	if len(buf) < 4 {
+		return h, 0, ErrShortBuffer
+	}
+	h.Group, h.Element = order.Uint16(buf[0:2]), order.Uint16(buf[2:4])
+	if !explicit || h.Group == 0xFFFE {
+		if len(buf) < 8 {
+			return h, 4, ErrShortBuffer
+		}
+		h.Length = order.Uint32(buf[4:8])
+		return h, 8, nil
+	}
+	if len(buf) < 6 {
+		return h, 4, ErrShortBuffer
+	}
+	h.VR = vrString(buf[4:6])
+	if _, ok := vri.VR[h.VR]; !ok && (buf[4] != 0 || buf[5] != 0) {
+		return h, 6, ErrUnknownVR
+	}
+	if !vri.LongLength(h.VR) {
+		if len(buf) < 8 {
+			return h, 6, ErrShortBuffer
+		}
+		h.Length = uint32(order.Uint16(buf[6:8]))
+		return h, 8, nil
+	}
+	// Reserved
+	if len(buf) < 12 {
+		return h, 6, ErrShortBuffer
+	}
+	h.Length = order.Uint32(buf[8:12])
+	return h, 12, nil
+}
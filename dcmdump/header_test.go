@@ -0,0 +1,46 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestDecodeElementHeader(t *testing.T) {
+	cases := []struct {
+		name     string
+		buf      []byte
+		explicit bool
+		order    binary.ByteOrder
+		expected Header
+		n        int
+		err      error
+	}{
+		{"explicit short", []byte{0x10, 0x00, 0x10, 0x00, 'P', 'N', 0x08, 0x00, 'D', 'O', 'E'}, true, binary.LittleEndian,
+			Header{Group: 0x0010, Element: 0x0010, VR: "PN", Length: 8}, 8, nil},
+		{"explicit long", []byte{0x08, 0x00, 0x15, 0x11, 'S', 'Q', 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}, true, binary.LittleEndian,
+			Header{Group: 0x0008, Element: 0x1115, VR: "SQ", Length: 0xFFFFFFFF}, 12, nil},
+		{"big endian", []byte{0x00, 0x28, 0x00, 0x10, 'U', 'S', 0x00, 0x02}, true, binary.BigEndian,
+			Header{Group: 0x0028, Element: 0x0010, VR: "US", Length: 2}, 8, nil},
+		{"implicit", []byte{0x10, 0x00, 0x20, 0x00, 0x04, 0x01, 0x00, 0x00}, false, binary.LittleEndian,
+			Header{Group: 0x0010, Element: 0x0020, Length: 260}, 8, nil},
+		{"item", []byte{0xFE, 0xFF, 0x00, 0xE0, 0x0A, 0x00, 0x00, 0x00}, true, binary.LittleEndian,
+			Header{Group: 0xFFFE, Element: 0xE000, Length: 10}, 8, nil},
+		{"blank VR", []byte{0x10, 0x00, 0x10, 0x00, 0, 0, 0x02, 0x00}, true, binary.LittleEndian,
+			Header{Group: 0x0010, Element: 0x0010, VR: "\x00\x00", Length: 2}, 8, nil},
+		{"unknown VR", []byte{0x10, 0x00, 0x10, 0x00, 'Z', 'Z', 0x02, 0x00}, true, binary.LittleEndian,
+			Header{Group: 0x0010, Element: 0x0010, VR: "ZZ"}, 6, ErrUnknownVR},
+		{"short tag", []byte{0x10, 0x00}, true, binary.LittleEndian, Header{}, 0, ErrShortBuffer},
+		{"short length", []byte{0x08, 0x00, 0x15, 0x11, 'S', 'Q', 0, 0}, true, binary.LittleEndian,
+			Header{Group: 0x0008, Element: 0x1115, VR: "SQ"}, 6, ErrShortBuffer},
+	}
+	for _, c := range cases {
+		h, n, err := DecodeElementHeader(c.buf, c.explicit, c.order)
+		if err != c.err || n != c.n || h != c.expected {
+			t.Errorf("%s: got %+v %d %v, expected %+v %d %v", c.name, h, n, err, c.expected, c.n, c.err)
+		}
+	}
+	h := Header{Group: 0x7FE0, Element: 0x0010, Length: 0xFFFFFFFF}
+	if h.Tag() != "7FE00010" || !h.Undefined() {
+		t.Errorf("got %s %v", h.Tag(), h.Undefined())
+	}
+}
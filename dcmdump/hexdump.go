@@ -0,0 +1,85 @@
+package dcmdump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultHexWidth is the number of bytes per line of a hex dump without
+// Width.
+const DefaultHexWidth = 16
+
+// HexDumpOptions - Layout of the lines of HexDump.
+type HexDumpOptions struct {
+	// Width is the number of bytes per line, DefaultHexWidth when 0.
+	Width int
+	// Offset is added to the offsets of the lines, the position of the data
+	// in the file for example.
+	Offset int64
+	// NoOffset leaves out the offset column.
+	NoOffset bool
+	// NoText leaves out the column of the printable characters.
+	NoText bool
+}
+
+// HexDump writes data as lines of hexadecimal bytes, grouped by 8, with the
+// offset of their first byte and their printable ASCII characters.
+// With the default options the output is the one of encoding/hex.Dump:
+//
+//	00000000  44 4f 45 5e 4a 4f 48 4e                           |DOE^JOHN|
+func HexDump(w io.Writer, data []byte, o HexDumpOptions) error {
+	width := o.Width
+	if width <= 0 {
+		width = DefaultHexWidth
+	}
+	bw := bufio.NewWriter(w)
+	for start := 0; start < len(data); start += width {
+		end := start + width
+		if end > len(data) {
+			end = len(data)
+		}
+		line := data[start:end]
+		if !o.NoOffset {
+			fmt.Fprintf(bw, "%08x  ", o.Offset+int64(start))
+		}
+		for i := 0; i < width; i++ {
+			if i >= len(line) && o.NoText {
+				break
+			}
+			switch {
+			case i > 0 && i%8 == 0:
+				bw.WriteString("  ")
+			case i > 0:
+				bw.WriteByte(' ')
+			}
+			// Short lines are padded for the text column to line up
+			if i < len(line) {
+				fmt.Fprintf(bw, "%02x", line[i])
+			} else {
+				bw.WriteString("  ")
+			}
+		}
+		if !o.NoText {
+			bw.WriteString("  |")
+			for _, b := range line {
+				if b < 0x20 || b > 0x7e {
+					b = '.'
+				}
+				bw.WriteByte(b)
+			}
+			bw.WriteByte('|')
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// Dump writes the hex dump of the value of de, loaded when deferred, see
+// HexDump.
+func (de *DataElement) Dump(w io.Writer, o HexDumpOptions) error {
+	if err := de.Load(); err != nil {
+		return err
+	}
+	return HexDump(w, de.Data, o)
+}
@@ -0,0 +1,48 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHexDump(t *testing.T) {
+	data := []byte("DOE^JOHN\x00\x01\x02\x7f0123456789")
+	var buf bytes.Buffer
+	if err := HexDump(&buf, data, HexDumpOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != hex.Dump(data) {
+		t.Errorf("expected:\n%s\ngot:\n%s", hex.Dump(data), buf.String())
+	}
+
+	for _, c := range []struct {
+		data     []byte
+		o        HexDumpOptions
+		expected string
+	}{
+		{data[:6], HexDumpOptions{Width: 4, Offset: 0x100}, "00000100  44 4f 45 5e  |DOE^|\n00000104  4a 4f        |JO|\n"},
+		{data[:10], HexDumpOptions{Width: 10, NoOffset: true}, "44 4f 45 5e 4a 4f 48 4e  00 01  |DOE^JOHN..|\n"},
+		{data[:6], HexDumpOptions{Width: 4, NoText: true}, "00000000  44 4f 45 5e\n00000004  4a 4f\n"},
+	} {
+		buf.Reset()
+		if err := HexDump(&buf, c.data, c.o); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != c.expected {
+			t.Errorf("%+v: expected %q, got %q", c.o, c.expected, buf.String())
+		}
+	}
+
+	de, err := NewDataElement("00100010", "PN", "DOE^JOHN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := de.Dump(&buf, HexDumpOptions{Width: 8, Offset: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "00000008  44 4f 45 5e 4a 4f 48 4e  |DOE^JOHN|\n" {
+		t.Errorf("wrong element dump: %q", buf.String())
+	}
+}
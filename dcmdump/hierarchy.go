@@ -0,0 +1,235 @@
+package dcmdump
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrMissingUID is returned when adding a file without StudyInstanceUID,
+// SeriesInstanceUID or SOPInstanceUID to an Index.
+var ErrMissingUID = errors.New("File is missing a Study, Series or SOP Instance UID")
+
+// Patient - Patient level of an Index.
+type Patient struct {
+	ID      string
+	Name    string
+	Studies []*Study
+}
+
+// Study - Study level of an Index.
+type Study struct {
+	UID             string
+	ID              string
+	Date            string
+	Description     string
+	AccessionNumber string
+	Patient         *Patient
+	Series          []*Series
+}
+
+// Series - Series level of an Index.
+type Series struct {
+	UID         string
+	Modality    string
+	Number      int
+	Description string
+	Study       *Study
+	Instances   []*Instance
+}
+
+// Instance - SOP Instance level of an Index.
+type Instance struct {
+	SOPInstanceUID string
+	SOPClassUID    string
+	InstanceNumber int
+	// ImagePositionPatient and ImageOrientationPatient are nil for
+	// instances without them.
+	ImagePositionPatient    []float64
+	ImageOrientationPatient []float64
+	Series                  *Series
+	File                    *DicomFile
+}
+
+// Index - Organizes files into a Patient, Study, Series and SOP Instance
+// hierarchy keyed by UID. It is safe for concurrent use.
+// Patients are keyed by PatientID, then PatientName for files without one.
+type Index struct {
+	mu        sync.RWMutex
+	patients  []*Patient
+	byPatient map[string]*Patient
+	studies   map[string]*Study
+	series    map[string]*Series
+	instances map[string]*Instance
+}
+
+// NewIndex returns an empty Index.
+func NewIndex() *Index {
+	return &Index{
+		byPatient: map[string]*Patient{},
+		studies:   map[string]*Study{},
+		series:    map[string]*Series{},
+		instances: map[string]*Instance{},
+	}
+}
+
+// Add inserts df in the hierarchy. A file with the SOPInstanceUID of an
+// indexed instance replaces it.
+func (ix *Index) Add(df *DicomFile) (*Instance, error) {
+	get := func(tagStr string) string {
+		de, err := df.LookupElement(tagStr)
+		if err != nil {
+			return ""
+		}
+		return strings.Join(de.Strings(), "\\")
+	}
+	floats := func(tagStr string) []float64 {
+		de, err := df.LookupElement(tagStr)
+		if err != nil || len(de.Data) == 0 {
+			return nil
+		}
+		return de.Floats()
+	}
+	studyUID, seriesUID, sopUID := get("0020000D"), get("0020000E"), get("00080018")
+	if studyUID == "" || seriesUID == "" || sopUID == "" {
+		return nil, ErrMissingUID
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if old, ok := ix.instances[sopUID]; ok {
+		old.Series.remove(old)
+	}
+	study, ok := ix.studies[studyUID]
+	if !ok {
+		key := get("00100020")
+		if key == "" {
+			key = "\\" + get("00100010")
+		}
+		patient, ok := ix.byPatient[key]
+		if !ok {
+			patient = &Patient{ID: get("00100020"), Name: get("00100010")}
+			ix.byPatient[key] = patient
+			ix.patients = append(ix.patients, patient)
+		}
+		study = &Study{
+			UID:             studyUID,
+			ID:              get("00200010"),
+			Date:            get("00080020"),
+			Description:     get("00081030"),
+			AccessionNumber: get("00080050"),
+			Patient:         patient,
+		}
+		ix.studies[studyUID] = study
+		patient.Studies = append(patient.Studies, study)
+	}
+	series, ok := ix.series[seriesUID]
+	if !ok {
+		series = &Series{
+			UID:         seriesUID,
+			Modality:    get("00080060"),
+			Number:      atoi(get("00200011")),
+			Description: get("0008103E"),
+			Study:       study,
+		}
+		ix.series[seriesUID] = series
+		study.Series = append(study.Series, series)
+	}
+	instance := &Instance{
+		SOPInstanceUID:          sopUID,
+		SOPClassUID:             get("00080016"),
+		InstanceNumber:          atoi(get("00200013")),
+		ImagePositionPatient:    floats("00200032"),
+		ImageOrientationPatient: floats("00200037"),
+		Series:                  series,
+		File:                    df,
+	}
+	ix.instances[sopUID] = instance
+	series.Instances = append(series.Instances, instance)
+	return instance, nil
+}
+
+func (s *Series) remove(instance *Instance) {
+	for i, in := range s.Instances {
+		if in == instance {
+			s.Instances = append(s.Instances[:i], s.Instances[i+1:]...)
+			return
+		}
+	}
+}
+
+// atoi returns the value of an IS string, 0 when empty or invalid.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+// Patients returns the patients in the order they were added.
+func (ix *Index) Patients() []*Patient {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return append([]*Patient{}, ix.patients...)
+}
+
+// Study returns the study with the given StudyInstanceUID, nil if not found.
+func (ix *Index) Study(uid string) *Study {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.studies[uid]
+}
+
+// Series returns the series with the given SeriesInstanceUID, nil if not
+// found.
+func (ix *Index) Series(uid string) *Series {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.series[uid]
+}
+
+// Instance returns the instance with the given SOPInstanceUID, nil if not
+// found.
+func (ix *Index) Instance(uid string) *Instance {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.instances[uid]
+}
+
+// SortedInstances returns the instances of the series in spatial order.
+// When every instance has an ImagePositionPatient they are sorted by their
+// distance along the normal of the first ImageOrientationPatient, otherwise by
+// InstanceNumber. Ties are broken by SOPInstanceUID.
+func (s *Series) SortedInstances() []*Instance {
+	instances := append([]*Instance{}, s.Instances...)
+	var normal []float64
+	for _, in := range instances {
+		if len(in.ImagePositionPatient) != 3 {
+			normal = nil
+			break
+		}
+		if normal == nil && len(in.ImageOrientationPatient) == 6 {
+			o := in.ImageOrientationPatient
+			normal = []float64{
+				o[1]*o[5] - o[2]*o[4],
+				o[2]*o[3] - o[0]*o[5],
+				o[0]*o[4] - o[1]*o[3],
+			}
+		}
+	}
+	distance := func(in *Instance) float64 {
+		p := in.ImagePositionPatient
+		return p[0]*normal[0] + p[1]*normal[1] + p[2]*normal[2]
+	}
+	sort.SliceStable(instances, func(i, j int) bool {
+		a, b := instances[i], instances[j]
+		if normal != nil && distance(a) != distance(b) {
+			return distance(a) < distance(b)
+		}
+		if a.InstanceNumber != b.InstanceNumber {
+			return a.InstanceNumber < b.InstanceNumber
+		}
+		return a.SOPInstanceUID < b.SOPInstanceUID
+	})
+	return instances
+}
@@ -0,0 +1,57 @@
+package dcmdump
+
+import "testing"
+
+func TestIndex(t *testing.T) {
+	file := func(patient, study, series, sop string, number int, z float64) *DicomFile {
+		df := &DicomFile{}
+		df.SetElement("00080018", "UI", sop)
+		df.SetElement("00100020", "LO", patient)
+		df.SetElement("0020000D", "UI", study)
+		df.SetElement("0020000E", "UI", series)
+		df.SetElement("00200013", "IS", number)
+		df.SetElement("00200032", "DS", []float64{0, 0, z})
+		df.SetElement("00200037", "DS", []float64{1, 0, 0, 0, 1, 0})
+		return df
+	}
+	ix := NewIndex()
+	for _, df := range []*DicomFile{
+		file("P1", "1.1", "1.1.1", "1.1.1.1", 1, 20),
+		file("P1", "1.1", "1.1.1", "1.1.1.2", 2, 10),
+		file("P1", "1.1", "1.1.2", "1.1.2.1", 1, 0),
+		file("P1", "1.2", "1.2.1", "1.2.1.1", 1, 0),
+		file("P2", "2.1", "2.1.1", "2.1.1.1", 1, 0),
+		// Replaces the first instance
+		file("P1", "1.1", "1.1.1", "1.1.1.1", 3, 5),
+	} {
+		_, err := ix.Add(df)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	patients := ix.Patients()
+	if len(patients) != 2 || patients[0].ID != "P1" || len(patients[0].Studies) != 2 {
+		t.Fatalf("Wrong patients: %+v", patients)
+	}
+	series := ix.Series("1.1.1")
+	if series == nil || series.Study != ix.Study("1.1") || len(series.Instances) != 2 {
+		t.Fatalf("Wrong series: %+v", series)
+	}
+	sorted := series.SortedInstances()
+	if sorted[0].SOPInstanceUID != "1.1.1.1" || sorted[0].InstanceNumber != 3 {
+		t.Errorf("Instances not sorted by position: %v, %v", sorted[0], sorted[1])
+	}
+	// Without positions InstanceNumber is used
+	sorted[0].ImagePositionPatient = nil
+	if sorted = series.SortedInstances(); sorted[0].SOPInstanceUID != "1.1.1.2" {
+		t.Errorf("Instances not sorted by number: %v, %v", sorted[0], sorted[1])
+	}
+	if in := ix.Instance("2.1.1.1"); in == nil || in.Series.Study.Patient.ID != "P2" {
+		t.Errorf("Wrong instance: %+v", in)
+	}
+
+	_, err := ix.Add(&DicomFile{})
+	if err != ErrMissingUID {
+		t.Errorf("Expected ErrMissingUID, got %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+// Package index keeps the metadata of the DICOM files under a directory in a
+// SQLite database, so large archives are searched without reading their
+// files again.
+//
+// The database is opened by the caller with a SQLite driver:
+//
+//	db, err := sql.Open("sqlite3", "archive.db")
+//	ix, err := index.New(db)
+//	stats, err := ix.Crawl("/archive")
+//	records, err := ix.Find(index.Query{Modality: "MR", StudyDate: index.Range{From: "20200101"}})
+package index
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// ErrUnknownTag is returned for index tags that are not in the dictionary.
+var ErrUnknownTag = errors.New("Unknown index tag")
+
+// DefaultTags are the keywords of the tags indexed when none are given.
+var DefaultTags = []string{
+	"SOPClassUID",
+	"SOPInstanceUID",
+	"StudyDate",
+	"AccessionNumber",
+	"Modality",
+	"StudyDescription",
+	"SeriesDescription",
+	"PatientName",
+	"PatientID",
+	"StudyInstanceUID",
+	"SeriesInstanceUID",
+}
+
+// indexedColumns get a database index when their tag is indexed.
+var indexedColumns = []string{"PatientID", "StudyDate", "Modality", "StudyInstanceUID", "SeriesInstanceUID"}
+
+// Index - Metadata of DICOM files stored in the instances table of a SQLite
+// database, one row per file with a column per indexed tag.
+type Index struct {
+	db   *sql.DB
+	tags []tag.Info
+}
+
+// Stats - Files seen by a crawl.
+type Stats struct {
+	// Indexed files are new or changed since the last crawl.
+	Indexed int
+	// Unchanged files have the size and modification time of their row.
+	Unchanged int
+	// Removed rows are for files that are no longer under the directory.
+	Removed int
+	// Skipped files are not DICOM or could not be read or parsed.
+	Skipped int
+}
+
+// Record - Indexed file.
+type Record struct {
+	Path string
+	// Values of the indexed tags by keyword, multiple values are joined with
+	// backslashes.
+	Values map[string]string
+}
+
+// New returns an Index of tags, given by keyword or tag, in db.
+// The instances table is created when missing and the columns of new tags
+// added to it, DefaultTags are indexed when none are given.
+func New(db *sql.DB, tags ...string) (*Index, error) {
+	if len(tags) == 0 {
+		tags = DefaultTags
+	}
+	ix := &Index{db: db}
+	var err error
+	ix.tags, err = resolveTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS instances (path TEXT PRIMARY KEY, size INTEGER, modified INTEGER)`)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := ix.columns()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range ix.tags {
+		if columns[t.Keyword] {
+			continue
+		}
+		_, err = db.Exec(`ALTER TABLE instances ADD COLUMN "` + t.Keyword + `" TEXT`)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range ix.tags {
+		for _, c := range indexedColumns {
+			if t.Keyword != c {
+				continue
+			}
+			_, err = db.Exec(`CREATE INDEX IF NOT EXISTS "instances_` + c + `" ON instances ("` + c + `")`)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ix, nil
+}
+
+// resolveTags returns the dictionary entries of tags, their keywords name
+// the columns.
+func resolveTags(tags []string) ([]tag.Info, error) {
+	infos := []tag.Info{}
+	seen := map[string]bool{}
+	for _, k := range tags {
+		info, ok := tag.ByKeyword(k)
+		if t, err := strconv.ParseUint(k, 16, 32); !ok && err == nil && len(k) == 8 {
+			info, ok = tag.Find(uint16(t>>16), uint16(t))
+		}
+		if !ok || info.Keyword == "" {
+			return nil, ErrUnknownTag
+		}
+		if seen[info.Keyword] {
+			continue
+		}
+		seen[info.Keyword] = true
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// columns returns the columns of the instances table.
+func (ix *Index) columns() (map[string]bool, error) {
+	rows, err := ix.db.Query(`PRAGMA table_info(instances)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue interface{}
+		err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk)
+		if err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// Crawl walks dir and indexes its new and changed files, the rows of files
+// removed from dir are deleted.
+// Files are only parsed up to the last indexed tag, so repeated crawls only
+// read the files that changed.
+func (ix *Index) Crawl(dir string) (*Stats, error) {
+	return ix.CrawlContext(context.Background(), dir, nil)
+}
+
+// CrawlContext crawls dir like Crawl until ctx is done, in which case nothing
+// is committed. progress, when not nil, is called after each file.
+func (ix *Index) CrawlContext(ctx context.Context, dir string, progress func(Stats)) (*Stats, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	known, err := ix.known(dir)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ix.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{}
+	seen := map[string]bool{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if progress != nil {
+			defer func() { progress(*stats) }()
+		}
+		if k, ok := known[path]; ok && k.size == info.Size() && k.modified == info.ModTime().UnixNano() {
+			seen[path] = true
+			stats.Unchanged++
+			return nil
+		}
+		values, err := ix.read(ctx, path)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err != nil {
+			stats.Skipped++
+			return nil
+		}
+		err = ix.insert(tx, path, info, values)
+		if err != nil {
+			return err
+		}
+		seen[path] = true
+		stats.Indexed++
+		return nil
+	})
+	for path := range known {
+		if err != nil || seen[path] {
+			continue
+		}
+		_, err = tx.Exec(`DELETE FROM instances WHERE path = ?`, path)
+		if err == nil {
+			stats.Removed++
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return stats, tx.Commit()
+}
+
+type fileState struct {
+	size, modified int64
+}
+
+// known returns the size and modification time of the rows under dir.
+func (ix *Index) known(dir string) (map[string]fileState, error) {
+	prefix := dir + string(filepath.Separator)
+	rows, err := ix.db.Query(`SELECT path, size, modified FROM instances WHERE substr(path, 1, ?) = ?`, len(prefix), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	known := map[string]fileState{}
+	for rows.Next() {
+		var path string
+		var s fileState
+		err = rows.Scan(&path, &s.size, &s.modified)
+		if err != nil {
+			return nil, err
+		}
+		known[path] = s
+	}
+	return known, rows.Err()
+}
+
+// read returns the values of the indexed tags of the file at path.
+func (ix *Index) read(ctx context.Context, path string) ([]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	df, err := dcmdump.ParseFileWithOptions(f, dcmdump.ParseOptions{StopAtTag: ix.lastTag(), SkipPixelData: true, Context: ctx})
+	if err != nil {
+		return nil, err
+	}
+	return ix.values(df), nil
+}
+
+// lastTag returns the highest indexed tag, where the parse can stop.
+func (ix *Index) lastTag() string {
+	last := ""
+	for _, t := range ix.tags {
+		if s := t.TagStr(); s > last {
+			last = s
+		}
+	}
+	return last
+}
+
+// values returns the values of the indexed tags of df, nil for the missing
+// ones.
+func (ix *Index) values(df *dcmdump.DicomFile) []interface{} {
+	values := make([]interface{}, len(ix.tags))
+	for i, t := range ix.tags {
+		de, err := df.LookupElement(t.TagStr())
+		if err != nil {
+			continue
+		}
+		values[i] = strings.Join(de.Strings(), `\`)
+	}
+	return values
+}
+
+func (ix *Index) insert(tx *sql.Tx, path string, info os.FileInfo, values []interface{}) error {
+	columns := []string{"path", "size", "modified"}
+	for _, t := range ix.tags {
+		columns = append(columns, `"`+t.Keyword+`"`)
+	}
+	args := append([]interface{}{path, info.Size(), info.ModTime().UnixNano()}, values...)
+	placeholders := strings.Repeat(", ?", len(args))[2:]
+	_, err := tx.Exec(`INSERT OR REPLACE INTO instances (`+strings.Join(columns, ", ")+`) VALUES (`+placeholders+`)`, args...)
+	return err
+}
+
+// Range - Range of dates, either end can be empty.
+type Range struct {
+	From, To string
+}
+
+// Query - Keys of a search, empty keys match all files.
+// PatientID and Keys can use the * and ? wildcards.
+type Query struct {
+	PatientID string
+	StudyDate Range
+	Modality  string
+	// Keys adds matches by keyword of indexed tags.
+	Keys map[string]string
+}
+
+// where returns the condition and arguments of q.
+func (ix *Index) where(q Query) (string, []interface{}, error) {
+	keys := map[string]string{}
+	for k, v := range q.Keys {
+		keys[k] = v
+	}
+	if q.PatientID != "" {
+		keys["PatientID"] = q.PatientID
+	}
+	if q.Modality != "" {
+		keys["Modality"] = q.Modality
+	}
+	names := []string{}
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	conditions := []string{}
+	args := []interface{}{}
+	for _, k := range names {
+		if !ix.indexes(k) {
+			return "", nil, ErrUnknownTag
+		}
+		if strings.ContainsAny(keys[k], "*?") {
+			conditions = append(conditions, `"`+k+`" GLOB ?`)
+		} else {
+			conditions = append(conditions, `"`+k+`" = ?`)
+		}
+		args = append(args, keys[k])
+	}
+	if q.StudyDate.From != "" || q.StudyDate.To != "" {
+		if !ix.indexes("StudyDate") {
+			return "", nil, ErrUnknownTag
+		}
+		if q.StudyDate.From != "" {
+			conditions = append(conditions, `"StudyDate" >= ?`)
+			args = append(args, q.StudyDate.From)
+		}
+		if q.StudyDate.To != "" {
+			conditions = append(conditions, `"StudyDate" <= ?`)
+			args = append(args, q.StudyDate.To)
+		}
+	}
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+func (ix *Index) indexes(keyword string) bool {
+	for _, t := range ix.tags {
+		if t.Keyword == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the records matching q ordered by path.
+func (ix *Index) Find(q Query) ([]Record, error) {
+	where, args, err := ix.where(q)
+	if err != nil {
+		return nil, err
+	}
+	columns := []string{"path"}
+	for _, t := range ix.tags {
+		columns = append(columns, `"`+t.Keyword+`"`)
+	}
+	rows, err := ix.db.Query(`SELECT `+strings.Join(columns, ", ")+` FROM instances`+where+` ORDER BY path`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	records := []Record{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(ix.tags)+1)
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		r := Record{Path: values[0].String, Values: map[string]string{}}
+		for i, t := range ix.tags {
+			if values[i+1].Valid {
+				r.Values[t.Keyword] = values[i+1].String
+			}
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// ByPatientID returns the records of a patient.
+func (ix *Index) ByPatientID(patientID string) ([]Record, error) {
+	return ix.Find(Query{PatientID: patientID})
+}
+
+// ByStudyDate returns the records of the studies between from and to,
+// either can be empty.
+func (ix *Index) ByStudyDate(from, to string) ([]Record, error) {
+	return ix.Find(Query{StudyDate: Range{From: from, To: to}})
+}
+
+// ByModality returns the records of a modality.
+func (ix *Index) ByModality(modality string) ([]Record, error) {
+	return ix.Find(Query{Modality: modality})
+}
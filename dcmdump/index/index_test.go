@@ -0,0 +1,411 @@
+package index
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestResolveTags(t *testing.T) {
+	tags, err := resolveTags([]string{"PatientID", "00080060", "PatientID"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(tags) != 2 || tags[0].Keyword != "PatientID" || tags[1].Keyword != "Modality" {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+	_, err = resolveTags([]string{"PatientID; DROP TABLE instances"})
+	if err != ErrUnknownTag {
+		t.Errorf("Expected ErrUnknownTag, got %v", err)
+	}
+}
+
+func TestValues(t *testing.T) {
+	tags, _ := resolveTags([]string{"Modality", "PatientID", "ImageType"})
+	ix := &Index{tags: tags}
+	if last := ix.lastTag(); last != "00100020" {
+		t.Errorf("Unexpected last tag %s", last)
+	}
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY"})
+	df.SetElement("00080060", "CS", "MR")
+	expected := []interface{}{"MR", nil, `ORIGINAL\PRIMARY`}
+	if values := ix.values(df); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+}
+
+func TestWhere(t *testing.T) {
+	tags, _ := resolveTags(DefaultTags)
+	ix := &Index{tags: tags}
+	for _, c := range []struct {
+		q     Query
+		where string
+		args  []interface{}
+	}{
+		{Query{}, "", []interface{}{}},
+		{
+			Query{PatientID: "P*", Modality: "CT", StudyDate: Range{From: "20200101"}},
+			` WHERE "Modality" = ? AND "PatientID" GLOB ? AND "StudyDate" >= ?`,
+			[]interface{}{"CT", "P*", "20200101"},
+		},
+		{
+			Query{StudyDate: Range{From: "20200101", To: "20201231"}, Keys: map[string]string{"AccessionNumber": "A1"}},
+			` WHERE "AccessionNumber" = ? AND "StudyDate" >= ? AND "StudyDate" <= ?`,
+			[]interface{}{"A1", "20200101", "20201231"},
+		},
+	} {
+		where, args, err := ix.where(c.q)
+		if err != nil || where != c.where || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("Unexpected condition for %+v: %q %v %v", c.q, where, args, err)
+		}
+	}
+	_, _, err := ix.where(Query{Keys: map[string]string{"BodyPartExamined": "HEAD"}})
+	if err != ErrUnknownTag {
+		t.Errorf("Expected ErrUnknownTag, got %v", err)
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	db, fake := openFake(t)
+	_, err := New(db, "PatientID", "Modality")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ix, err := New(db, "PatientID", "Modality", "StudyDate")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []string{"path", "size", "modified", "PatientID", "Modality", "StudyDate"}
+	if !reflect.DeepEqual(fake.columns, expected) {
+		t.Errorf("Expected columns %v, got %v", expected, fake.columns)
+	}
+	if !fake.indexes["instances_PatientID"] || !fake.indexes["instances_StudyDate"] {
+		t.Errorf("Missing indexes %v", fake.indexes)
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.dcm"), "P1", "CT")
+	writeFile(t, filepath.Join(dir, "sub", "b.dcm"), "P2", "MR")
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not DICOM"), 0644)
+	// Unreadable files are skipped
+	os.Symlink(filepath.Join(dir, "missing.dcm"), filepath.Join(dir, "link.dcm"))
+	stats, err := ix.Crawl(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *stats != (Stats{Indexed: 2, Skipped: 2}) {
+		t.Errorf("Wrong first crawl %+v", stats)
+	}
+	stats, err = ix.Crawl(dir)
+	if err != nil || *stats != (Stats{Unchanged: 2, Skipped: 2}) {
+		t.Errorf("Wrong second crawl %+v, %v", stats, err)
+	}
+
+	writeFile(t, filepath.Join(dir, "sub", "b.dcm"), "P2", "CT")
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "sub", "b.dcm"), later, later)
+	os.Remove(filepath.Join(dir, "a.dcm"))
+	stats, err = ix.Crawl(dir)
+	if err != nil || *stats != (Stats{Indexed: 1, Removed: 1, Skipped: 2}) {
+		t.Errorf("Wrong crawl of changes %+v, %v", stats, err)
+	}
+	records, err := ix.Find(Query{PatientID: "P*", Modality: "CT"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(records) != 1 || records[0].Path != filepath.Join(dir, "sub", "b.dcm") || records[0].Values["StudyDate"] != "20200102" {
+		t.Errorf("Wrong records %+v", records)
+	}
+	if records, _ := ix.ByModality("MR"); len(records) != 0 {
+		t.Errorf("Expected no MR records, got %+v", records)
+	}
+
+	// Failed removals roll back the crawl
+	fake.failDelete = true
+	os.Remove(filepath.Join(dir, "sub", "b.dcm"))
+	stats, err = ix.Crawl(dir)
+	if err == nil || stats != nil {
+		t.Errorf("Expected an error, got %+v", stats)
+	}
+	if records, _ := ix.ByPatientID("P2"); len(records) != 1 {
+		t.Errorf("Expected the row kept, got %+v", records)
+	}
+}
+
+func writeFile(t *testing.T, path, patientID, modality string) {
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+	df.SetElement("00080020", "DA", "20200102")
+	df.SetElement("00080060", "CS", modality)
+	df.SetElement("00100020", "LO", patientID)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = df.Write(f, ts.ExplicitVRLittleEndian)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeDriver - database/sql driver running the statements of Index against
+// in memory tables, in place of SQLite.
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+var registerFake sync.Once
+var fakeSQL = &fakeDriver{dbs: map[string]*fakeDB{}}
+
+// openFake returns a new database of the fake driver.
+func openFake(t *testing.T) (*sql.DB, *fakeDB) {
+	registerFake.Do(func() { sql.Register("fake", fakeSQL) })
+	fakeSQL.mu.Lock()
+	f := &fakeDB{rows: map[string]map[string]driver.Value{}, indexes: map[string]bool{}}
+	fakeSQL.dbs[t.Name()] = f
+	fakeSQL.mu.Unlock()
+	db, err := sql.Open("fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, f
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		return nil, errors.New("unknown database")
+	}
+	return &fakeConn{db}, nil
+}
+
+type fakeDB struct {
+	mu         sync.Mutex
+	columns    []string
+	rows       map[string]map[string]driver.Value
+	saved      map[string]map[string]driver.Value
+	indexes    map[string]bool
+	failDelete bool
+}
+
+var (
+	createRE    = regexp.MustCompile(`^CREATE TABLE IF NOT EXISTS instances \((.*)\)$`)
+	alterRE     = regexp.MustCompile(`^ALTER TABLE instances ADD COLUMN "(\w+)" TEXT$`)
+	indexRE     = regexp.MustCompile(`^CREATE INDEX IF NOT EXISTS "(\w+)" ON instances \("\w+"\)$`)
+	insertRE    = regexp.MustCompile(`^INSERT OR REPLACE INTO instances \((.*)\) VALUES \([?, ]*\)$`)
+	selectRE    = regexp.MustCompile(`^SELECT (.*?) FROM instances(?: WHERE (.*?))?(?: ORDER BY path)?$`)
+	conditionRE = regexp.MustCompile(`^(?:"(\w+)" (=|GLOB|>=|<=) \?|substr\(path, 1, \?\) = \?)$`)
+)
+
+func (db *fakeDB) run(query string, args []driver.Value) (*fakeRows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	rows := &fakeRows{}
+	switch {
+	case createRE.MatchString(query):
+		if db.columns == nil {
+			for _, c := range strings.Split(createRE.FindStringSubmatch(query)[1], ", ") {
+				db.columns = append(db.columns, strings.Fields(c)[0])
+			}
+		}
+	case query == `PRAGMA table_info(instances)`:
+		rows.columns = []string{"cid", "name", "type", "notnull", "dflt_value", "pk"}
+		for i, c := range db.columns {
+			rows.values = append(rows.values, []driver.Value{int64(i), c, "TEXT", int64(0), nil, int64(0)})
+		}
+	case alterRE.MatchString(query):
+		c := alterRE.FindStringSubmatch(query)[1]
+		if db.column(c) {
+			return nil, errors.New("duplicate column name: " + c)
+		}
+		db.columns = append(db.columns, c)
+	case indexRE.MatchString(query):
+		db.indexes[indexRE.FindStringSubmatch(query)[1]] = true
+	case insertRE.MatchString(query):
+		row := map[string]driver.Value{}
+		for i, c := range strings.Split(insertRE.FindStringSubmatch(query)[1], ", ") {
+			c = strings.Trim(c, `"`)
+			if !db.column(c) {
+				return nil, errors.New("no such column: " + c)
+			}
+			row[c] = args[i]
+		}
+		db.rows[row["path"].(string)] = row
+	case query == `DELETE FROM instances WHERE path = ?`:
+		if db.failDelete {
+			return nil, errors.New("disk I/O error")
+		}
+		delete(db.rows, args[0].(string))
+	case selectRE.MatchString(query):
+		m := selectRE.FindStringSubmatch(query)
+		for _, c := range strings.Split(m[1], ", ") {
+			rows.columns = append(rows.columns, strings.Trim(c, `"`))
+		}
+		paths := []string{}
+		for path, row := range db.rows {
+			ok, err := matchRow(row, m[2], args)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			values := []driver.Value{}
+			for _, c := range rows.columns {
+				values = append(values, db.rows[path][c])
+			}
+			rows.values = append(rows.values, values)
+		}
+	default:
+		return nil, errors.New("unexpected statement: " + query)
+	}
+	return rows, nil
+}
+
+func (db *fakeDB) column(name string) bool {
+	for _, c := range db.columns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchRow applies the conditions of a WHERE clause to row.
+func matchRow(row map[string]driver.Value, where string, args []driver.Value) (bool, error) {
+	if where == "" {
+		return true, nil
+	}
+	for _, condition := range strings.Split(where, " AND ") {
+		m := conditionRE.FindStringSubmatch(condition)
+		if m == nil {
+			return false, errors.New("unexpected condition: " + condition)
+		}
+		if m[1] == "" {
+			n, prefix := int(args[0].(int64)), args[1].(string)
+			path := row["path"].(string)
+			if len(path) < n || path[:n] != prefix {
+				return false, nil
+			}
+			args = args[2:]
+			continue
+		}
+		v, ok := row[m[1]].(string)
+		arg := args[0].(string)
+		args = args[1:]
+		switch {
+		case !ok:
+			return false, nil
+		case m[2] == "=" && v != arg,
+			m[2] == ">=" && v < arg,
+			m[2] == "<=" && v > arg:
+			return false, nil
+		case m[2] == "GLOB":
+			expr := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(arg))
+			if !regexp.MustCompile("^" + expr + "$").MatchString(v) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c.db, query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.saved = map[string]map[string]driver.Value{}
+	for path, row := range c.db.rows {
+		c.db.saved[path] = row
+	}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rows = c.db.saved
+	return nil
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, err := s.db.run(s.query, args)
+	return driver.RowsAffected(0), err
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.db.run(s.query, args)
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
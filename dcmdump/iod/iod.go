@@ -0,0 +1,169 @@
+// Package iod validates data sets against the Information Object
+// Definitions of PS3.3 for their SOP class.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/PS3.3.html
+package iod
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// ErrUnknownIOD is returned for SOP classes without a registered IOD.
+var ErrUnknownIOD = errors.New("No IOD for SOP class")
+
+// Kind - Kind of violation.
+type Kind string
+
+// Kinds of violations
+const (
+	Missing     Kind = "missing"
+	Empty       Kind = "empty"
+	InvalidEnum Kind = "enumerated value"
+	InvalidVR   Kind = "VR"
+	InvalidVM   Kind = "VM"
+)
+
+// Violation - Attribute that doesn't conform to the IOD.
+type Violation struct {
+	// Module is empty for VR and VM violations of elements outside the
+	// modules.
+	Module  string
+	Tag     string
+	Kind    Kind
+	Message string
+}
+
+func (v Violation) String() string {
+	name := tag.Tag[v.Tag]["name"]
+	s := fmt.Sprintf("(%s,%s) %s %s: %s", v.Tag[:4], v.Tag[4:], name, v.Kind, v.Message)
+	if v.Module != "" {
+		s += " [" + v.Module + "]"
+	}
+	return s
+}
+
+var (
+	mu        sync.RWMutex
+	iodsExtra = map[string][]Module{}
+)
+
+// RegisterIOD sets the mandatory modules checked for a SOP class, replacing
+// the built-in definition if any.
+func RegisterIOD(sopClass string, modules ...Module) {
+	mu.Lock()
+	defer mu.Unlock()
+	iodsExtra[sopClass] = modules
+}
+
+func lookup(sopClass string) ([]Module, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if m, ok := iodsExtra[sopClass]; ok {
+		return m, true
+	}
+	m, ok := iods[sopClass]
+	return m, ok
+}
+
+// Validate checks the top level elements of df against the mandatory modules
+// of the IOD for its SOP Class UID: type 1 attributes are present and not
+// empty, type 2 attributes present and enumerated values valid. The VR and
+// VM of every element in the dictionary are checked as well.
+// Conditional types are only checked when present, their conditions are not
+// evaluated.
+func Validate(df *dcmdump.DicomFile) ([]Violation, error) {
+	sopClass := ""
+	for _, t := range []string{"00080016", "00020002"} {
+		if de, err := df.LookupElement(t); err == nil && len(de.Strings()) > 0 {
+			sopClass = de.Strings()[0]
+			break
+		}
+	}
+	modules, ok := lookup(sopClass)
+	if !ok {
+		return nil, ErrUnknownIOD
+	}
+	elements := map[string]*dcmdump.DataElement{}
+	for i := range df.Elements {
+		elements[df.Elements[i].TagStr] = &df.Elements[i]
+	}
+	violations := []Violation{}
+	for _, m := range modules {
+		for _, a := range m.Attributes {
+			de, ok := elements[a.Tag]
+			if !ok {
+				if a.Type == Type1 || a.Type == Type2 {
+					violations = append(violations, Violation{Module: m.Name, Tag: a.Tag, Kind: Missing, Message: "type " + a.Type + " attribute not present"})
+				}
+				continue
+			}
+			values := de.Strings()
+			if len(values) == 0 || len(values) == 1 && values[0] == "" {
+				if a.Type == Type1 || a.Type == Type1C {
+					violations = append(violations, Violation{Module: m.Name, Tag: a.Tag, Kind: Empty, Message: "type " + a.Type + " attribute is empty"})
+				}
+				continue
+			}
+			if len(a.Enum) == 0 {
+				continue
+			}
+			for _, v := range values {
+				if !contains(a.Enum, v) {
+					violations = append(violations, Violation{Module: m.Name, Tag: a.Tag, Kind: InvalidEnum, Message: fmt.Sprintf("%q not one of %s", v, strings.Join(a.Enum, ", "))})
+				}
+			}
+		}
+	}
+	for i := range df.Elements {
+		violations = append(violations, conformance(&df.Elements[i])...)
+	}
+	return violations, nil
+}
+
+// otherVRs are the VRs allowed besides the one in the dictionary, which is the
+// one used for implicit VR.
+var otherVRs = map[string]string{
+	"00280106": "SS", // SmallestImagePixelValue
+	"00280107": "SS", // LargestImagePixelValue
+	"00281101": "SS", // RedPaletteColorLookupTableDescriptor
+	"00281102": "SS", // GreenPaletteColorLookupTableDescriptor
+	"00281103": "SS", // BluePaletteColorLookupTableDescriptor
+	"00283002": "SS", // LUTDescriptor
+	"00283006": "OW", // LUTData
+	"7FE00010": "OB", // PixelData
+}
+
+// conformance checks the VR and VM of de against the dictionary.
+func conformance(de *dcmdump.DataElement) []Violation {
+	entry, ok := tag.Tag[de.TagStr]
+	if !ok {
+		return nil
+	}
+	violations := []Violation{}
+	if vr := entry["vr"]; vr != "" && de.VRStr != "" && de.VRStr != "UN" && de.VRStr != vr && de.VRStr != otherVRs[de.TagStr] {
+		violations = append(violations, Violation{Tag: de.TagStr, Kind: InvalidVR, Message: fmt.Sprintf("%s instead of %s", de.VRStr, vr)})
+	}
+	switch de.VRStr {
+	case "", "OB", "OD", "OF", "OL", "OV", "OW", "UN", "SQ", "LT", "ST", "UT", "UR":
+		return violations
+	}
+	n := len(de.Strings())
+	if vm := entry["vm"]; n > 0 && vm != "" && !tag.MatchVM(vm, n) {
+		violations = append(violations, Violation{Tag: de.TagStr, Kind: InvalidVM, Message: fmt.Sprintf("%d values instead of %s", n, vm)})
+	}
+	return violations
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package iod
+
+import (
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func TestValidate(t *testing.T) {
+	df := &dcmdump.DicomFile{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00080016", "UI", "1.2.840.10008.5.1.4.1.1.7"},
+		{"00080018", "UI", "1.2.3.4"},
+		{"00080060", "CS", "OT"},
+		{"00080064", "CS", "WSD"},
+		{"00080090", "PN", ""},
+		{"00100010", "PN", "Doe^John"},
+		{"00100020", "LO", "123"},
+		{"00100030", "DA", ""},
+		{"00100040", "CS", "X"},
+		{"0020000D", "UI", ""},
+		{"0020000E", "UI", "1.2.3.1"},
+		{"00200011", "IS", ""},
+		{"00200013", "IS", "1"},
+		{"00280002", "US", 1},
+		{"00280004", "CS", "MONOCHROME2"},
+		{"00280010", "US", []int{2, 2}},
+		{"00280011", "US", 2},
+		{"00280100", "US", 8},
+		{"00280101", "US", 8},
+		{"00280102", "US", 7},
+		{"00280103", "US", 0},
+		{"7FE00010", "OB", []byte{1, 2, 3, 4}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	violations, err := Validate(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := map[string]Kind{
+		"00080020": Missing,     // StudyDate
+		"00080030": Missing,     // StudyTime
+		"00080050": Missing,     // AccessionNumber
+		"00200010": Missing,     // StudyID
+		"0020000D": Empty,       // StudyInstanceUID
+		"00100040": InvalidEnum, // PatientSex
+		"00280010": InvalidVM,   // Rows
+	}
+	found := map[string]Kind{}
+	for _, v := range violations {
+		found[v.Tag] = v.Kind
+		if expected[v.Tag] != v.Kind {
+			t.Errorf("Unexpected violation %s", v)
+		}
+	}
+	for tagStr, kind := range expected {
+		if found[tagStr] != kind {
+			t.Errorf("Missing %s violation for %s", kind, tagStr)
+		}
+	}
+
+	df.SetElement("00080016", "UI", "1.2.3")
+	_, err = Validate(df)
+	if err != ErrUnknownIOD {
+		t.Errorf("Expected ErrUnknownIOD, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package iod
+
+// Attribute types of PS3.5 7.4
+const (
+	Type1  = "1"  // Required, not empty
+	Type1C = "1C" // Conditional type 1
+	Type2  = "2"  // Required, may be empty
+	Type2C = "2C" // Conditional type 2
+	Type3  = "3"  // Optional
+)
+
+// Attribute - Module attribute with its type and enumerated values.
+type Attribute struct {
+	Tag  string
+	Type string
+	// Enum lists the enumerated values allowed, any value when empty.
+	Enum []string
+}
+
+// Module - Information Object Module of PS3.3 Annex C.
+type Module struct {
+	Name       string
+	Attributes []Attribute
+}
+
+// Modules
+var (
+	PatientModule = Module{"Patient", []Attribute{
+		{"00100010", Type2, nil}, // PatientName
+		{"00100020", Type2, nil}, // PatientID
+		{"00100030", Type2, nil}, // PatientBirthDate
+		{"00100040", Type2, []string{"M", "F", "O"}},
+	}}
+	GeneralStudyModule = Module{"General Study", []Attribute{
+		{"0020000D", Type1, nil}, // StudyInstanceUID
+		{"00080020", Type2, nil}, // StudyDate
+		{"00080030", Type2, nil}, // StudyTime
+		{"00080090", Type2, nil}, // ReferringPhysicianName
+		{"00200010", Type2, nil}, // StudyID
+		{"00080050", Type2, nil}, // AccessionNumber
+	}}
+	GeneralSeriesModule = Module{"General Series", []Attribute{
+		{"00080060", Type1, nil}, // Modality
+		{"0020000E", Type1, nil}, // SeriesInstanceUID
+		{"00200011", Type2, nil}, // SeriesNumber
+		{"00200060", Type2C, []string{"L", "R"}},
+		{"00185100", Type2C, []string{"HFP", "HFS", "HFDR", "HFDL", "FFDR", "FFDL", "FFP", "FFS", "LFP", "LFS", "RFP", "RFS", "AFDR", "AFDL", "PFDR", "PFDL"}},
+	}}
+	FrameOfReferenceModule = Module{"Frame of Reference", []Attribute{
+		{"00200052", Type1, nil}, // FrameOfReferenceUID
+		{"00201040", Type2, nil}, // PositionReferenceIndicator
+	}}
+	GeneralEquipmentModule = Module{"General Equipment", []Attribute{
+		{"00080070", Type2, nil}, // Manufacturer
+	}}
+	SCEquipmentModule = Module{"SC Equipment", []Attribute{
+		{"00080064", Type1, []string{"DV", "DI", "DF", "WSD", "SD", "SI", "DRW", "SYN"}},
+	}}
+	GeneralImageModule = Module{"General Image", []Attribute{
+		{"00200013", Type2, nil},  // InstanceNumber
+		{"00200020", Type2C, nil}, // PatientOrientation
+		{"00280300", Type3, []string{"YES", "NO"}},
+	}}
+	ImagePlaneModule = Module{"Image Plane", []Attribute{
+		{"00280030", Type1, nil}, // PixelSpacing
+		{"00200037", Type1, nil}, // ImageOrientationPatient
+		{"00200032", Type1, nil}, // ImagePositionPatient
+		{"00180050", Type2, nil}, // SliceThickness
+	}}
+	ImagePixelModule = Module{"Image Pixel", []Attribute{
+		{"00280002", Type1, nil}, // SamplesPerPixel
+		{"00280004", Type1, []string{"MONOCHROME1", "MONOCHROME2", "PALETTE COLOR", "RGB", "YBR_FULL", "YBR_FULL_422", "YBR_PARTIAL_420", "YBR_ICT", "YBR_RCT"}},
+		{"00280010", Type1, nil}, // Rows
+		{"00280011", Type1, nil}, // Columns
+		{"00280100", Type1, nil}, // BitsAllocated
+		{"00280101", Type1, nil}, // BitsStored
+		{"00280102", Type1, nil}, // HighBit
+		{"00280103", Type1, []string{"0", "1"}},
+		{"00280006", Type1C, []string{"0", "1"}},
+		{"7FE00010", Type1C, nil}, // PixelData
+	}}
+	CTImageModule = Module{"CT Image", []Attribute{
+		{"00080008", Type1, nil}, // ImageType
+		{"00280002", Type1, []string{"1"}},
+		{"00280004", Type1, []string{"MONOCHROME1", "MONOCHROME2"}},
+		{"00280100", Type1, []string{"16"}},
+		{"00280101", Type1, []string{"12", "13", "14", "15", "16"}},
+		{"00280102", Type1, nil}, // HighBit
+		{"00281052", Type1, nil}, // RescaleIntercept
+		{"00281053", Type1, nil}, // RescaleSlope
+		{"00180060", Type2, nil}, // KVP
+		{"00200012", Type2, nil}, // AcquisitionNumber
+	}}
+	MRImageModule = Module{"MR Image", []Attribute{
+		{"00080008", Type1, nil}, // ImageType
+		{"00280002", Type1, []string{"1"}},
+		{"00280004", Type1, []string{"MONOCHROME1", "MONOCHROME2"}},
+		{"00280100", Type1, []string{"16"}},
+		{"00180020", Type1, []string{"SE", "IR", "GR", "EP", "RM"}},
+		{"00180021", Type1, []string{"SK", "MTC", "SS", "TRSS", "SP", "MP", "OSP", "NONE"}},
+		{"00180022", Type1, nil}, // ScanOptions
+		{"00180023", Type1, []string{"2D", "3D"}},
+		{"00180081", Type2, nil}, // EchoTime
+		{"00180091", Type2, nil}, // EchoTrainLength
+	}}
+	SOPCommonModule = Module{"SOP Common", []Attribute{
+		{"00080016", Type1, nil}, // SOPClassUID
+		{"00080018", Type1, nil}, // SOPInstanceUID
+	}}
+)
+
+// iods maps SOP classes to their mandatory modules.
+var iods = map[string][]Module{
+	// CR Image Storage
+	"1.2.840.10008.5.1.4.1.1.1": {PatientModule, GeneralStudyModule, GeneralSeriesModule, GeneralEquipmentModule, GeneralImageModule, ImagePixelModule, SOPCommonModule},
+	// CT Image Storage
+	"1.2.840.10008.5.1.4.1.1.2": {PatientModule, GeneralStudyModule, GeneralSeriesModule, FrameOfReferenceModule, GeneralEquipmentModule, GeneralImageModule, ImagePlaneModule, ImagePixelModule, CTImageModule, SOPCommonModule},
+	// MR Image Storage
+	"1.2.840.10008.5.1.4.1.1.4": {PatientModule, GeneralStudyModule, GeneralSeriesModule, FrameOfReferenceModule, GeneralEquipmentModule, GeneralImageModule, ImagePlaneModule, ImagePixelModule, MRImageModule, SOPCommonModule},
+	// Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.7": {PatientModule, GeneralStudyModule, GeneralSeriesModule, SCEquipmentModule, GeneralImageModule, ImagePixelModule, SOPCommonModule},
+}
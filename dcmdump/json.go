@@ -0,0 +1,289 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// jsonAttribute - DICOM JSON Model attribute
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part18/chapter_F.html
+type jsonAttribute struct {
+	VR           string        `json:"vr"`
+	Value        []interface{} `json:"Value,omitempty"`
+	InlineBinary string        `json:"InlineBinary,omitempty"`
+	BulkDataURI  string        `json:"BulkDataURI,omitempty"`
+}
+
+// jsonPersonName - DICOM JSON Model PN value
+type jsonPersonName struct {
+	Alphabetic  string `json:",omitempty"`
+	Ideographic string `json:",omitempty"`
+	Phonetic    string `json:",omitempty"`
+}
+
+// BulkDataFunc returns the BulkDataURI for a binary element.
+// Returning an empty string inlines the value as InlineBinary instead.
+type BulkDataFunc func(de *DataElement) string
+
+// MarshalJSON encodes the data set in the DICOM JSON Model (PS3.18 Annex F).
+// Binary values are inlined, the file meta group and group lengths are not
+// part of the model and are left out.
+func (df *DicomFile) MarshalJSON() ([]byte, error) {
+	return df.JSON(nil)
+}
+
+// JSON encodes the data set in the DICOM JSON Model, bulkData is called for
+// each binary element to get its BulkDataURI.
+func (df *DicomFile) JSON(bulkData BulkDataFunc) ([]byte, error) {
+	return json.Marshal(jsonDataset(df.Elements, bulkData))
+}
+
+func jsonDataset(elements []DataElement, bulkData BulkDataFunc) map[string]jsonAttribute {
+	ds := map[string]jsonAttribute{}
+	for i := range elements {
+		de := &elements[i]
+		if de.TagStr[:4] == "0002" || de.TagStr[4:] == "0000" {
+			continue
+		}
+		ds[de.TagStr] = jsonElement(de, bulkData)
+	}
+	return ds
+}
+
+func jsonElement(de *DataElement, bulkData BulkDataFunc) jsonAttribute {
+	vr := de.VRStr
+	if _, ok := vri.VR[vr]; !ok {
+		vr = "UN"
+	}
+	a := jsonAttribute{VR: vr}
+	switch vr {
+	case "SQ":
+		for _, item := range de.Items {
+			a.Value = append(a.Value, jsonDataset(item.Elements, bulkData))
+		}
+	case "OB", "OD", "OF", "OL", "OV", "OW", "UN":
+		if len(de.Data) == 0 {
+			break
+		}
+		if bulkData != nil {
+			a.BulkDataURI = bulkData(de)
+		}
+		if a.BulkDataURI == "" {
+			a.InlineBinary = base64.StdEncoding.EncodeToString(de.Data)
+		}
+	case "PN":
+		for _, v := range de.stringValues() {
+			a.Value = append(a.Value, jsonPN(v))
+		}
+	case "IS", "DS":
+		for _, v := range de.stringValues() {
+			f, err := strconv.ParseFloat(v, 64)
+			switch {
+			case v == "":
+				a.Value = append(a.Value, nil)
+			case err != nil:
+				// Keep invalid numbers as written
+				a.Value = append(a.Value, v)
+			case vr == "IS" && f == float64(int64(f)):
+				a.Value = append(a.Value, int64(f))
+			default:
+				a.Value = append(a.Value, f)
+			}
+		}
+	case "US", "SS", "UL", "SL", "SV", "UV", "FL", "FD", "AT":
+		a.Value = de.binaryValues()
+	default:
+		for _, v := range de.stringValues() {
+			if v == "" {
+				a.Value = append(a.Value, nil)
+				continue
+			}
+			a.Value = append(a.Value, v)
+		}
+	}
+	return a
+}
+
+// jsonInteger returns the digits of an SV or UV value, written as a number or
+// as a string for those beyond the precision of JavaScript numbers.
+func jsonInteger(raw json.RawMessage) (string, error) {
+	if len(raw) > 0 && raw[0] == '"' {
+		var v string
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+	return string(raw), nil
+}
+
+func jsonPN(v string) interface{} {
+	if v == "" {
+		return nil
+	}
+	groups := strings.SplitN(v, "=", 3)
+	pn := jsonPersonName{Alphabetic: groups[0]}
+	if len(groups) > 1 {
+		pn.Ideographic = groups[1]
+	}
+	if len(groups) > 2 {
+		pn.Phonetic = groups[2]
+	}
+	return pn
+}
+
+// jsonValue - DICOM JSON Model attribute as decoded, values are kept raw
+// until the VR is known.
+type jsonValue struct {
+	VR           string            `json:"vr"`
+	Value        []json.RawMessage `json:"Value"`
+	InlineBinary string            `json:"InlineBinary"`
+	BulkDataURI  string            `json:"BulkDataURI"`
+}
+
+// BulkDataReader returns the value referenced by a BulkDataURI.
+type BulkDataReader func(uri string) ([]byte, error)
+
+// UnmarshalJSON decodes a data set in the DICOM JSON Model, values are
+// encoded as explicit VR little endian.
+// Elements with a BulkDataURI are kept with an empty value.
+func (df *DicomFile) UnmarshalJSON(b []byte) error {
+	elements, err := jsonElements(b, false, nil)
+	if err != nil {
+		return err
+	}
+	df.Elements = elements
+	if df.TransferSyntax == "" {
+		df.TransferSyntax = ts.ExplicitVRLittleEndian
+	}
+	return nil
+}
+
+// ParseJSON decodes a data set in the DICOM JSON Model like UnmarshalJSON,
+// bulkData is called to read the value of each element with a BulkDataURI.
+func ParseJSON(b []byte, bulkData BulkDataReader) (*DicomFile, error) {
+	elements, err := jsonElements(b, false, bulkData)
+	if err != nil {
+		return nil, err
+	}
+	return &DicomFile{Elements: elements, TransferSyntax: ts.ExplicitVRLittleEndian}, nil
+}
+
+func jsonElements(b []byte, partOfSQ bool, bulkData BulkDataReader) ([]DataElement, error) {
+	ds := map[string]jsonValue{}
+	err := json.Unmarshal(b, &ds)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(ds))
+	for t := range ds {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	elements := make([]DataElement, 0, len(tags))
+	for i, t := range tags {
+		value, err := jsonDecodeValue(ds[t], bulkData)
+		if err != nil {
+			return nil, err
+		}
+		de, err := NewDataElement(t, ds[t].VR, value)
+		if err != nil {
+			return nil, err
+		}
+		de.N = i
+		de.PartOfSQ = partOfSQ
+		elements = append(elements, de)
+	}
+	return elements, nil
+}
+
+// jsonDecodeValue returns the value of a as accepted by NewDataElement.
+func jsonDecodeValue(a jsonValue, bulkData BulkDataReader) (interface{}, error) {
+	switch a.VR {
+	case "SQ":
+		items := []Item{}
+		for _, raw := range a.Value {
+			elements, err := jsonElements(raw, true, bulkData)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, Item{N: len(items), Elements: elements})
+		}
+		return items, nil
+	case "OB", "OD", "OF", "OL", "OV", "OW", "UN":
+		if a.BulkDataURI != "" && bulkData != nil {
+			return bulkData(a.BulkDataURI)
+		}
+		return base64.StdEncoding.DecodeString(a.InlineBinary)
+	case "SV":
+		values := []int64{}
+		for _, raw := range a.Value {
+			v, err := jsonInteger(raw)
+			if err != nil {
+				return nil, err
+			}
+			i, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, i)
+		}
+		return values, nil
+	case "UV":
+		values := []uint64{}
+		for _, raw := range a.Value {
+			v, err := jsonInteger(raw)
+			if err != nil {
+				return nil, err
+			}
+			u, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, u)
+		}
+		return values, nil
+	case "US", "SS", "UL", "SL", "FL", "FD":
+		values := []float64{}
+		for _, raw := range a.Value {
+			var f float64
+			err := json.Unmarshal(raw, &f)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, f)
+		}
+		return values, nil
+	}
+	values := []string{}
+	for _, raw := range a.Value {
+		switch {
+		case bytes.Equal(raw, []byte("null")):
+			values = append(values, "")
+		case a.VR == "PN":
+			var pn jsonPersonName
+			err := json.Unmarshal(raw, &pn)
+			if err != nil {
+				return nil, err
+			}
+			v := strings.Join([]string{pn.Alphabetic, pn.Ideographic, pn.Phonetic}, "=")
+			values = append(values, strings.TrimRight(v, "="))
+		case raw[0] != '"':
+			// IS and DS values are JSON numbers, kept as written
+			values = append(values, string(raw))
+		default:
+			var v string
+			err := json.Unmarshal(raw, &v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,121 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	df := parseSample(t, sampleFile(), true)
+	b, err := json.Marshal(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := `{"00081115":{"vr":"SQ","Value":[{"00081150":{"vr":"UI","Value":["1.2.3"]}}]},` +
+		`"00100010":{"vr":"PN","Value":[{"Alphabetic":"DOE^JOHN"}]},` +
+		`"00280010":{"vr":"US","Value":[512]},` +
+		`"7FE00010":{"vr":"OW","InlineBinary":"AQIDBA=="}}`
+	if string(b) != expected {
+		t.Errorf("Wrong JSON:\n%s\n%s", b, expected)
+	}
+
+	b, err = df.JSON(func(de *DataElement) string { return "http://localhost/bulk/" + de.TagStr })
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var ds map[string]map[string]interface{}
+	err = json.Unmarshal(b, &ds)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if ds["7FE00010"]["BulkDataURI"] != "http://localhost/bulk/7FE00010" {
+		t.Errorf("Wrong BulkDataURI: %v", ds["7FE00010"])
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	input := `{"00100010":{"vr":"PN","Value":[{"Alphabetic":"DOE^JOHN","Ideographic":"ドウ"}]},` +
+		`"00081115":{"vr":"SQ","Value":[{"00081150":{"vr":"UI","Value":["1.2.3"]}}]},` +
+		`"00200013":{"vr":"IS","Value":[7]},` +
+		`"00280010":{"vr":"US","Value":[512]},` +
+		`"00080061":{"vr":"CS","Value":["CT",null]},` +
+		`"7FE00010":{"vr":"OW","InlineBinary":"AQIDBA=="}}`
+	df := &DicomFile{}
+	err := json.Unmarshal([]byte(input), df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tags := []string{}
+	for _, de := range df.Elements {
+		tags = append(tags, de.TagStr)
+	}
+	if strings.Join(tags, " ") != "00080061 00081115 00100010 00200013 00280010 7FE00010" {
+		t.Errorf("Wrong elements %v", tags)
+	}
+	for tagStr, expected := range map[string]string{
+		"00080061": "CT\\",
+		"00100010": "DOE^JOHN=ドウ",
+		"00200013": "7",
+		"00280010": "512",
+	} {
+		de, _ := df.LookupElement(tagStr)
+		if v := strings.Join(de.Strings(), "\\"); v != expected {
+			t.Errorf("Expected %s %q, got %q", tagStr, expected, v)
+		}
+	}
+	de, _ := df.LookupElement("7FE00010")
+	if !bytes.Equal(de.Data, []byte{1, 2, 3, 4}) {
+		t.Errorf("Wrong pixel data %v", de.Data)
+	}
+	de, _ = df.LookupElement("00081115")
+	if len(de.Items) != 1 || de.Items[0].Elements[0].Strings()[0] != "1.2.3" {
+		t.Errorf("Wrong sequence %v", de.Items)
+	}
+
+	b, err := json.Marshal(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	again := &DicomFile{}
+	err = json.Unmarshal(b, again)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(df.Elements, again.Elements) {
+		t.Errorf("Round trip mismatch:\n%v\n%v", df.Elements, again.Elements)
+	}
+
+	// 64 bit integers keep their precision, as numbers or strings
+	input = `{"00091010":{"vr":"SV","Value":[9007199254740993,"-9223372036854775808"]},` +
+		`"00091011":{"vr":"UV","Value":["18446744073709551615"]}}`
+	df = &DicomFile{}
+	err = json.Unmarshal([]byte(input), df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for i, expected := range []string{"9007199254740993\\-9223372036854775808", "18446744073709551615"} {
+		if v := strings.Join(df.Elements[i].Strings(), "\\"); v != expected {
+			t.Errorf("Expected %s, got %s", expected, v)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	input := `{"7FE00010":{"vr":"OW","BulkDataURI":"blk/1"}}`
+	df, err := ParseJSON([]byte(input), func(uri string) ([]byte, error) {
+		if uri != "blk/1" {
+			t.Errorf("Wrong uri %s", uri)
+		}
+		return []byte{1, 2, 3, 4}, nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	de, _ := df.LookupElement("7FE00010")
+	if !bytes.Equal(de.Data, []byte{1, 2, 3, 4}) || de.Len != 4 {
+		t.Errorf("Bulk data not read: %v", de)
+	}
+}
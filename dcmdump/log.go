@@ -0,0 +1,67 @@
+package dcmdump
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Level - Severity of a log message.
+type Level int
+
+// Log levels
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Logger - Receives the diagnostics of a parse, like tags missing from the
+// dictionary or blank VRs.
+type Logger interface {
+	Logf(level Level, format string, args ...interface{})
+}
+
+// LoggerFunc adapts a function to the Logger interface.
+type LoggerFunc func(level Level, format string, args ...interface{})
+
+// Logf calls f.
+func (f LoggerFunc) Logf(level Level, format string, args ...interface{}) {
+	f(level, format, args...)
+}
+
+// NewLogger returns a Logger writing messages of at least the given level to
+// w, one per line prefixed with the level.
+func NewLogger(w io.Writer, min Level) Logger {
+	var mu sync.Mutex
+	return LoggerFunc(func(level Level, format string, args ...interface{}) {
+		if level < min {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "%s: "+format+"\n", append([]interface{}{level}, args...)...)
+	})
+}
+
+// logf sends a message to the parser Logger, if any.
+func (p *Parser) logf(level Level, format string, args ...interface{}) {
+	if p.Logger != nil {
+		p.Logger.Logf(level, format, args...)
+	}
+}
@@ -0,0 +1,100 @@
+package dcmdump
+
+import "strings"
+
+// FindAll returns the elements matching name, a tag as GGGGEEEE or a keyword,
+// at any nesting depth.
+// Matches are ordered by depth, top level elements first, then by their order
+// in the file. The pointers refer to the elements of df and are valid until
+// it is modified.
+func (df *DicomFile) FindAll(name string) []*DataElement {
+	matches := []*DataElement{}
+	df.find(name, -1, func(de *DataElement) bool {
+		matches = append(matches, de)
+		return true
+	})
+	return matches
+}
+
+// FindFirst returns the first element of FindAll, top level elements are
+// matched before the ones in sequence items.
+func (df *DicomFile) FindFirst(name string) (*DataElement, bool) {
+	var match *DataElement
+	df.find(name, -1, func(de *DataElement) bool {
+		match = de
+		return false
+	})
+	return match, match != nil
+}
+
+// Lookup returns a copy of the first top level element matching name, a tag
+// as GGGGEEEE or a keyword.
+func (df *DicomFile) Lookup(name string) (DataElement, bool) {
+	var match DataElement
+	found := false
+	df.find(name, 0, func(de *DataElement) bool {
+		match, found = *de, true
+		return false
+	})
+	return match, found
+}
+
+// find calls fn with the elements matching name, breadth first down to
+// maxDepth or all levels when negative, until fn returns false.
+func (df *DicomFile) find(name string, maxDepth int, fn func(de *DataElement) bool) {
+	df.mu.RLock()
+	defer df.mu.RUnlock()
+	tagStr := strings.ToUpper(name)
+	level := make([]*DataElement, len(df.Elements))
+	for i := range df.Elements {
+		level[i] = &df.Elements[i]
+	}
+	for depth := 0; len(level) > 0 && (maxDepth < 0 || depth <= maxDepth); depth++ {
+		next := []*DataElement{}
+		for _, de := range level {
+			if (de.TagStr == tagStr || de.Name == name) && !fn(de) {
+				return
+			}
+			for i := range de.Items {
+				for j := range de.Items[i].Elements {
+					next = append(next, &de.Items[i].Elements[j])
+				}
+			}
+		}
+		level = next
+	}
+}
+
+// FindElement returns the element with the given GGGGEEEE tag among elements,
+// the ones of a sequence item for example, nil when missing.
+func FindElement(elements []DataElement, tagStr string) *DataElement {
+	for i := range elements {
+		if elements[i].TagStr == tagStr {
+			return &elements[i]
+		}
+	}
+	return nil
+}
+
+// ElementString returns the values of the element with the given tag among
+// elements joined with backslashes, empty when missing.
+func ElementString(elements []DataElement, tagStr string) string {
+	de := FindElement(elements, tagStr)
+	if de == nil {
+		return ""
+	}
+	return strings.Join(de.Strings(), `\`)
+}
+
+// ElementInt returns the first value of the element with the given tag among
+// elements as an int, 0 when missing.
+func ElementInt(elements []DataElement, tagStr string) int {
+	de := FindElement(elements, tagStr)
+	if de == nil {
+		return 0
+	}
+	if v := de.Ints(); len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
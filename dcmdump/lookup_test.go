@@ -0,0 +1,70 @@
+package dcmdump
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	nested, _ := NewDataElement("00100010", "PN", "NESTED")
+	nested.PartOfSQ = true
+	df := &DicomFile{}
+	df.SetElement("00081115", "SQ", []Item{{Elements: []DataElement{nested}}})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+
+	all := df.FindAll("PatientName")
+	if len(all) != 2 || all[0].StringData() != "DOE^JOHN" || all[1].StringData() != "NESTED" {
+		t.Fatalf("Wrong matches: %v", all)
+	}
+	de, ok := df.FindFirst("00100010")
+	if !ok || de != &df.Elements[1] {
+		t.Fatalf("Expected the top level element, got %v", de)
+	}
+	de.Data = []byte("SMITH^JANE")
+	if v, ok := df.Lookup("00100010"); !ok || v.StringData() != "SMITH^JANE" {
+		t.Errorf("FindFirst didn't return the file element: %v", v)
+	}
+	v, _ := df.Lookup("00100010")
+	v.Data = nil
+	if df.Elements[1].Data == nil {
+		t.Errorf("Lookup didn't return a copy")
+	}
+	if _, ok := df.Lookup("00081150"); ok {
+		t.Errorf("Lookup matched an element out of the top level")
+	}
+	if len(df.FindAll("00081150")) != 0 {
+		t.Errorf("Unexpected matches")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			df.FindAll("PatientName")
+		}()
+		go func(i int) {
+			defer wg.Done()
+			df.SetElement("00200013", "IS", i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestFindElement(t *testing.T) {
+	df := &DicomFile{}
+	df.SetElement("00200013", "IS", []int{7, 8})
+	df.SetElement("00080060", "CS", []string{"CT", "PT"})
+	if de := FindElement(df.Elements, "00080060"); de != &df.Elements[0] {
+		t.Errorf("Wrong element: %v", de)
+	}
+	if FindElement(df.Elements, "00100010") != nil || ElementString(df.Elements, "00100010") != "" || ElementInt(df.Elements, "00100010") != 0 {
+		t.Errorf("Expected no value for a missing element")
+	}
+	if v := ElementString(df.Elements, "00080060"); v != `CT\PT` {
+		t.Errorf("Wrong string value: %s", v)
+	}
+	if v := ElementInt(df.Elements, "00200013"); v != 7 {
+		t.Errorf("Wrong int value: %d", v)
+	}
+}
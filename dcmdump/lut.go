@@ -0,0 +1,70 @@
+package dcmdump
+
+import "errors"
+
+// ErrLUT is returned for lookup tables whose descriptor doesn't match their
+// data.
+var ErrLUT = errors.New("Invalid lookup table")
+
+// LUT - Lookup table of a Palette Color, Modality or VOI LUT.
+type LUT struct {
+	// FirstMapped is the value mapped to the first entry, values below it map
+	// to the first entry and values past the table to the last one.
+	FirstMapped int
+	// Bits of each entry.
+	Bits int
+	Data []uint16
+}
+
+// newLUT reads a table from its descriptor, number of entries, first mapped
+// value and bits per entry, and data elements. The first mapped value is
+// signed when signed is set, whatever the VR of the descriptor.
+func newLUT(descriptor, data *DataElement, signed bool) (*LUT, error) {
+	d := descriptor.Ints()
+	if len(d) != 3 {
+		return nil, ErrLUT
+	}
+	entries := d[0]
+	if entries == 0 {
+		entries = 1 << 16
+	}
+	l := &LUT{FirstMapped: d[1], Bits: d[2]}
+	if signed && l.FirstMapped >= 1<<15 {
+		l.FirstMapped -= 1 << 16
+	}
+	values := data.Ints()
+	if l.Bits <= 8 && len(values) == (entries+1)/2 && len(data.Data) == entries+entries%2 {
+		// 8 bit entries packed in OW
+		values = values[:0]
+		for _, b := range data.Data[:entries] {
+			values = append(values, int(b))
+		}
+	}
+	if len(values) < entries {
+		return nil, ErrLUT
+	}
+	l.Data = make([]uint16, entries)
+	for i := range l.Data {
+		l.Data[i] = uint16(values[i])
+	}
+	return l, nil
+}
+
+// Value returns the entry v maps to.
+func (l *LUT) Value(v int) uint16 {
+	i := v - l.FirstMapped
+	if i < 0 {
+		i = 0
+	} else if i >= len(l.Data) {
+		i = len(l.Data) - 1
+	}
+	return l.Data[i]
+}
+
+// Max returns the largest value an entry can hold.
+func (l *LUT) Max() uint16 {
+	if l.Bits <= 0 || l.Bits >= 16 {
+		return 0xffff
+	}
+	return 1<<uint(l.Bits) - 1
+}
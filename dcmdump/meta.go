@@ -0,0 +1,48 @@
+package dcmdump
+
+import (
+	"errors"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// ErrMissingSOPUID is returned when building the file meta group of a data
+// set without SOPClassUID or SOPInstanceUID.
+var ErrMissingSOPUID = errors.New("Data set is missing its SOP Class or SOP Instance UID")
+
+// SetFileMeta sets the elements of the file meta group (0002) required for
+// the data set to be written as a file with the given transfer syntax.
+// The MediaStorage SOP Class and Instance UIDs are copied from the data set
+// and the implementation is identified as this library. Other file meta
+// elements are kept.
+// An empty transferSyntax uses the one of the file, or Explicit VR Little
+// Endian when it has none.
+func (df *DicomFile) SetFileMeta(transferSyntax string) error {
+	sopClass, sopInstance := df.stringValue("00080016"), df.stringValue("00080018")
+	if sopClass == "" || sopInstance == "" {
+		return ErrMissingSOPUID
+	}
+	if transferSyntax == "" {
+		transferSyntax = df.TransferSyntax
+	}
+	if transferSyntax == "" {
+		transferSyntax = ts.ExplicitVRLittleEndian
+	}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00020001", "OB", []byte{0, 1}},
+		{"00020002", "UI", sopClass},
+		{"00020003", "UI", sopInstance},
+		{"00020010", "UI", transferSyntax},
+		{"00020012", "UI", ImplementationClassUID},
+		{"00020013", "SH", ImplementationVersionName},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
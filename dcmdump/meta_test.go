@@ -0,0 +1,41 @@
+package dcmdump
+
+import (
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestSetFileMeta(t *testing.T) {
+	df := &DicomFile{}
+	df.SetElement("00080018", "UI", "1.2.3")
+	if err := df.SetFileMeta(""); err != ErrMissingSOPUID {
+		t.Errorf("Expected ErrMissingSOPUID, got %v", err)
+	}
+	df.SetElement("00080016", "UI", "1.2.840.10008.5.1.4.1.1.7")
+	df.SetElement("00020016", "AE", "SCU")
+	err := df.SetFileMeta("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for tagStr, expected := range map[string]string{
+		"00020002": "1.2.840.10008.5.1.4.1.1.7",
+		"00020003": "1.2.3",
+		"00020010": ts.ExplicitVRLittleEndian,
+		"00020012": ImplementationClassUID,
+		"00020013": ImplementationVersionName,
+		"00020016": "SCU",
+	} {
+		if v := df.stringValue(tagStr); v != expected {
+			t.Errorf("%s: expected %s, got %s", tagStr, expected, v)
+		}
+	}
+	if de, _ := df.Lookup("00020001"); len(de.Data) != 2 || de.Data[1] != 1 {
+		t.Errorf("Wrong FileMetaInformationVersion: %v", de.Data)
+	}
+
+	err = df.SetFileMeta(ts.ExplicitVRBigEndian)
+	if err != nil || df.stringValue("00020010") != ts.ExplicitVRBigEndian {
+		t.Errorf("Transfer syntax not updated: %s, %v", df.stringValue("00020010"), err)
+	}
+}
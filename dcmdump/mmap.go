@@ -0,0 +1,21 @@
+package dcmdump
+
+import "errors"
+
+// ErrClosed is returned when reading from a closed MappedFile.
+var ErrClosed = errors.New("File is closed")
+
+// OpenMapped opens the file at path for parsing from memory.
+// On platforms that support it the file is memory mapped, so headers are
+// parsed straight from the page cache instead of with a read call for each
+// buffer, otherwise reads go to the file.
+// Use it with ParseFileLazy to scan large archives, the MappedFile has to be
+// kept open until the deferred values are loaded:
+//
+//	m, err := dcmdump.OpenMapped(path)
+//	...
+//	defer m.Close()
+//	df, err := dcmdump.ParseFileLazy(m, 4096)
+func OpenMapped(path string) (*MappedFile, error) {
+	return openMapped(path)
+}
@@ -0,0 +1,50 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package dcmdump
+
+import "os"
+
+// MappedFile - File read with ReadAt calls on platforms without memory
+// mapping.
+type MappedFile struct {
+	f      *os.File
+	size   int64
+	closed bool
+}
+
+func openMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &MappedFile{f: f, size: info.Size()}, nil
+}
+
+// ReadAt reads len(b) bytes of the file at offset off.
+func (m *MappedFile) ReadAt(b []byte, off int64) (int, error) {
+	if m.closed {
+		return 0, ErrClosed
+	}
+	return m.f.ReadAt(b, off)
+}
+
+// Len returns the size of the file.
+func (m *MappedFile) Len() int64 {
+	return m.size
+}
+
+// Close closes the file, the data of elements that were not loaded can't be
+// read afterwards.
+func (m *MappedFile) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	return m.f.Close()
+}
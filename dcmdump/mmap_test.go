@@ -0,0 +1,49 @@
+package dcmdump
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMapped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sample.dcm")
+	data := sampleFile()
+	err := os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := OpenMapped(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if m.Len() != int64(len(data)) {
+		t.Errorf("Wrong length %d", m.Len())
+	}
+	df, err := ParseFileLazy(m, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	de, _ := df.LookupElement("7FE00010")
+	if !de.Deferred {
+		t.Fatalf("PixelData not deferred")
+	}
+	err = de.Load()
+	if err != nil || !bytes.Equal(de.Data, []byte{1, 2, 3, 4}) {
+		t.Errorf("Wrong loaded value %v, %v", de.Data, err)
+	}
+	b := make([]byte, 8)
+	if n, err := m.ReadAt(b, int64(len(data)-4)); n != 4 || err != io.EOF {
+		t.Errorf("Expected short read with io.EOF, got %d, %v", n, err)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err = m.ReadAt(b, 0); err != ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package dcmdump
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// MappedFile - File mapped read only in memory.
+type MappedFile struct {
+	data   []byte
+	size   int64
+	closed bool
+}
+
+func openMapped(path string) (*MappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// The mapping stays valid once the file is closed
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	m := &MappedFile{size: info.Size()}
+	if m.size == 0 {
+		m.data = []byte{}
+		return m, nil
+	}
+	m.data, err = syscall.Mmap(int(f.Fd()), 0, int(m.size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadAt copies len(b) bytes of the file at offset off.
+func (m *MappedFile) ReadAt(b []byte, off int64) (int, error) {
+	if m.closed {
+		return 0, ErrClosed
+	}
+	if off < 0 {
+		return 0, os.ErrInvalid
+	}
+	if off >= m.size {
+		return 0, io.EOF
+	}
+	n := copy(b, m.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Len returns the size of the file.
+func (m *MappedFile) Len() int64 {
+	return m.size
+}
+
+// Close unmaps the file, the data of elements that were not loaded can't be
+// read afterwards.
+func (m *MappedFile) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if len(m.data) == 0 {
+		return nil
+	}
+	return syscall.Munmap(m.data)
+}
@@ -0,0 +1,339 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// ErrInvalidTag is returned for tags that are not 8 hex digits.
+var ErrInvalidTag = errors.New("Tag must be 8 hex digits")
+
+// ErrNoElement is returned when the element is not in the file.
+var ErrNoElement = errors.New("Element not in file")
+
+// SetElement inserts or replaces the top level element with the given tag.
+// value is encoded according to vr:
+//   - string or []string for string VRs, multiple values are joined with a
+//     backslash and padded to an even length,
+//   - int, []int, int64, []int64, float64 or []float64 for binary numbers,
+//     IS and DS, uint64 or []uint64 also for SV, UV and OV,
+//   - string or []string of GGGGEEEE or (gggg,eeee) tags, tag.Info or
+//     []tag.Info for AT,
+//   - []byte for OB, OW, UN and other raw values,
+//   - []Item for SQ.
+//
+// Elements are kept ordered by tag and their length recalculated.
+func (df *DicomFile) SetElement(tagStr, vr string, value interface{}) error {
+	de, err := NewDataElement(tagStr, vr, value)
+	if err != nil {
+		return err
+	}
+	df.mu.Lock()
+	defer df.mu.Unlock()
+	i := len(df.Elements)
+	for j := range df.Elements {
+		if df.Elements[j].TagStr == de.TagStr {
+			de.N = df.Elements[j].N
+			df.Elements[j] = de
+			return nil
+		}
+		if df.Elements[j].TagStr > de.TagStr && j < i {
+			i = j
+		}
+	}
+	df.Elements = append(df.Elements, DataElement{})
+	copy(df.Elements[i+1:], df.Elements[i:])
+	df.Elements[i] = de
+	return nil
+}
+
+// DeleteElement removes the top level element with the given tag.
+func (df *DicomFile) DeleteElement(tagStr string) error {
+	tagStr = strings.ToUpper(tagStr)
+	df.mu.Lock()
+	defer df.mu.Unlock()
+	for i := range df.Elements {
+		if df.Elements[i].TagStr == tagStr {
+			df.Elements = append(df.Elements[:i], df.Elements[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNoElement
+}
+
+// NewDataElement returns a little endian element with value encoded as
+// described in SetElement.
+func NewDataElement(tagStr, vr string, value interface{}) (DataElement, error) {
+	tagStr = strings.ToUpper(tagStr)
+	t, err := hex.DecodeString(tagStr)
+	if err != nil || len(t) != 4 {
+		return DataElement{}, ErrInvalidTag
+	}
+	if _, ok := vri.VR[vr]; !ok {
+		return DataElement{}, ErrUnknownVR
+	}
+	de := DataElement{
+		TagGroup:  []byte{t[1], t[0]},
+		TagElem:   []byte{t[3], t[2]},
+		TagStr:    tagStr,
+		Name:      tag.Tag[tagStr]["name"],
+		VR:        []byte(vr),
+		VRStr:     vr,
+		ByteOrder: binary.LittleEndian,
+	}
+	if vr == "SQ" {
+		items, ok := value.([]Item)
+		if !ok {
+			return de, ErrValueType
+		}
+		de.Data = []byte{}
+		de.Items = items
+		return de, nil
+	}
+	de.Data, err = encodeValue(vr, value)
+	if err != nil {
+		return de, err
+	}
+	de.Len = uint32(len(de.Data))
+	return de, nil
+}
+
+// encodeValue encodes value as little endian data for vr.
+func encodeValue(vr string, value interface{}) ([]byte, error) {
+	if b, ok := value.([]byte); ok {
+		return padValue(append([]byte{}, b...), padding(vr)), nil
+	}
+	switch vr {
+	case "SV", "UV", "OV":
+		return encodeVeryLongs(vr, value)
+	case "US", "SS", "UL", "SL", "FL", "FD", "OW", "OL", "OF", "OD":
+		floats, ok := toFloats(value)
+		if !ok {
+			return nil, ErrValueType
+		}
+		return encodeNumbers(vr, floats), nil
+	case "AT":
+		if t, ok := value.(tag.Info); ok {
+			value = []tag.Info{t}
+		}
+		if infos, ok := value.([]tag.Info); ok {
+			b := []byte{}
+			for _, t := range infos {
+				b = append(b, byte(t.Group), byte(t.Group>>8), byte(t.Element), byte(t.Element>>8))
+			}
+			return b, nil
+		}
+		tags, ok := toStrings(value)
+		if !ok {
+			return nil, ErrValueType
+		}
+		b := []byte{}
+		for _, s := range tags {
+			t, err := hex.DecodeString(strings.NewReplacer("(", "", ")", "", ",", "").Replace(s))
+			if err != nil || len(t) != 4 {
+				return nil, ErrInvalidTag
+			}
+			b = append(b, t[1], t[0], t[3], t[2])
+		}
+		return b, nil
+	case "OB", "UN":
+		return nil, ErrValueType
+	case "IS", "DS":
+		values, err := numericStrings(vr, value)
+		if err != nil {
+			return nil, err
+		}
+		return padValue([]byte(strings.Join(values, "\\")), ' '), nil
+	}
+	values, ok := toStrings(value)
+	if !ok {
+		return nil, ErrValueType
+	}
+	return padValue([]byte(strings.Join(values, "\\")), padding(vr)), nil
+}
+
+func encodeNumbers(vr string, values []float64) []byte {
+	b := []byte{}
+	for _, v := range values {
+		switch vr {
+		case "US", "SS", "OW":
+			b = append(b, 0, 0)
+			binary.LittleEndian.PutUint16(b[len(b)-2:], uint16(int64(v)))
+		case "UL", "SL", "OL":
+			b = append(b, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(int64(v)))
+		case "FL", "OF":
+			b = append(b, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint32(b[len(b)-4:], math.Float32bits(float32(v)))
+		case "FD", "OD":
+			b = append(b, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.LittleEndian.PutUint64(b[len(b)-8:], math.Float64bits(v))
+		}
+	}
+	return b
+}
+
+// encodeVeryLongs encodes the 64 bit integers of value for SV, UV and OV.
+// Integers are encoded as is, a float64 only holds 53 bits of them.
+func encodeVeryLongs(vr string, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case int:
+		value = []int64{int64(v)}
+	case int64:
+		value = []int64{v}
+	case uint64:
+		value = []uint64{v}
+	case []int:
+		ints := []int64{}
+		for _, i := range v {
+			ints = append(ints, int64(i))
+		}
+		value = ints
+	}
+	values := []uint64{}
+	switch v := value.(type) {
+	case []int64:
+		for _, i := range v {
+			if i < 0 && vr != "SV" {
+				return nil, ErrValueType
+			}
+			values = append(values, uint64(i))
+		}
+	case []uint64:
+		for _, u := range v {
+			if u > math.MaxInt64 && vr == "SV" {
+				return nil, ErrValueType
+			}
+			values = append(values, u)
+		}
+	default:
+		floats, ok := toFloats(value)
+		if !ok {
+			return nil, ErrValueType
+		}
+		for _, f := range floats {
+			if vr == "SV" {
+				values = append(values, uint64(int64(f)))
+			} else {
+				values = append(values, uint64(f))
+			}
+		}
+	}
+	b := make([]byte, 8*len(values))
+	for i, u := range values {
+		binary.LittleEndian.PutUint64(b[8*i:], u)
+	}
+	return b, nil
+}
+
+func toFloats(value interface{}) ([]float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return []float64{float64(v)}, true
+	case int64:
+		return []float64{float64(v)}, true
+	case uint16:
+		return []float64{float64(v)}, true
+	case uint32:
+		return []float64{float64(v)}, true
+	case float64:
+		return []float64{v}, true
+	case []int:
+		f := []float64{}
+		for _, i := range v {
+			f = append(f, float64(i))
+		}
+		return f, true
+	case []int64:
+		f := []float64{}
+		for _, i := range v {
+			f = append(f, float64(i))
+		}
+		return f, true
+	case []float64:
+		return v, true
+	}
+	return nil, false
+}
+
+// numericStrings returns the IS or DS values of value, numbers are formatted
+// to fit the VR, see WriteOptions.ValidateNumbers for strings.
+func numericStrings(vr string, value interface{}) ([]string, error) {
+	switch value.(type) {
+	case string, []string:
+		values, _ := toStrings(value)
+		return values, nil
+	}
+	floats, ok := toFloats(value)
+	if !ok {
+		return nil, ErrValueType
+	}
+	format := formatDS
+	if vr == "IS" {
+		format = formatIS
+	}
+	values := []string{}
+	for _, f := range floats {
+		s, err := format(f)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+func toStrings(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	}
+	if ints, ok := toInts(value); ok {
+		s := []string{}
+		for _, i := range ints {
+			s = append(s, strconv.FormatInt(i, 10))
+		}
+		return s, true
+	}
+	floats, ok := toFloats(value)
+	if !ok {
+		return nil, false
+	}
+	s := []string{}
+	for _, f := range floats {
+		s = append(s, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return s, true
+}
+
+// toInts returns the values of the integer types of toFloats.
+func toInts(value interface{}) ([]int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return []int64{int64(v)}, true
+	case int64:
+		return []int64{v}, true
+	case uint16:
+		return []int64{int64(v)}, true
+	case uint32:
+		return []int64{int64(v)}, true
+	case []int:
+		ints := []int64{}
+		for _, i := range v {
+			ints = append(ints, int64(i))
+		}
+		return ints, true
+	case []int64:
+		return v, true
+	}
+	return nil, false
+}
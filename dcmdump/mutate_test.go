@@ -0,0 +1,102 @@
+package dcmdump
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetElement(t *testing.T) {
+	df := &DicomFile{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00100020", "LO", "ID1"},
+		{"00080060", "CS", "CT"},
+		{"00200013", "IS", 7},
+		{"00100010", "PN", "DOE^JOHN"},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", e.tag, err)
+		}
+	}
+	df.Elements[1].N = 3
+	err := df.SetElement("00100010", "PN", "SMITH^JANE")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	tags := []string{}
+	for _, de := range df.Elements {
+		tags = append(tags, de.TagStr)
+	}
+	if len(tags) != 4 || tags[0] != "00080060" || tags[1] != "00100010" || tags[2] != "00100020" || tags[3] != "00200013" {
+		t.Errorf("Elements not ordered by tag: %v", tags)
+	}
+	if de := df.Elements[1]; de.StringData() != "SMITH^JANE" || de.Len != 10 || de.N != 3 {
+		t.Errorf("Element not replaced: %q %d %d", de.StringData(), de.Len, de.N)
+	}
+
+	for _, c := range []struct {
+		tag, vr string
+		value   interface{}
+		err     error
+	}{
+		{"0010001", "LO", "ID", ErrInvalidTag},
+		{"0010002G", "LO", "ID", ErrInvalidTag},
+		{"00100020", "XX", "ID", ErrUnknownVR},
+		{"00100020", "LO", []Item{}, ErrValueType},
+		{"00081115", "SQ", "ID", ErrValueType},
+		{"00280010", "US", "512", ErrValueType},
+	} {
+		if err := df.SetElement(c.tag, c.vr, c.value); err != c.err {
+			t.Errorf("%s %s: expected %v, got %v", c.tag, c.vr, c.err, err)
+		}
+	}
+
+	if err := df.DeleteElement("00080060"); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if err := df.DeleteElement("00080060"); err != ErrNoElement {
+		t.Errorf("Expected ErrNoElement, got %v", err)
+	}
+	if len(df.Elements) != 3 {
+		t.Errorf("Wrong elements after delete: %v", df.Elements)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	for _, c := range []struct {
+		vr       string
+		value    interface{}
+		expected string
+	}{
+		{"LO", 12345678, "12345678"},
+		{"LO", []int{1, -2}, "1\\-2"},
+		{"LO", 0.5, "0.5 "},
+		{"IS", 12345678, "12345678"},
+		{"LO", []byte("ABC"), "ABC "},
+		{"UI", []byte("1.2.3"), "1.2.3\x00"},
+		{"OB", []byte{1, 2, 3}, "\x01\x02\x03\x00"},
+	} {
+		b, err := encodeValue(c.vr, c.value)
+		if err != nil || string(b) != c.expected {
+			t.Errorf("%s %v: expected %q, got %q, %v", c.vr, c.value, c.expected, b, err)
+		}
+	}
+
+	// Values are copied before padding
+	raw := make([]byte, 3, 4)
+	copy(raw, "ABC")
+	de, err := NewDataElement("00100020", "LO", raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw[:4][3] != 0 {
+		t.Errorf("Padding written to the array of the value")
+	}
+	raw[0] = 'X'
+	if !bytes.Equal(de.Data, []byte("ABC ")) {
+		t.Errorf("Element shares the array of the value: %q", de.Data)
+	}
+}
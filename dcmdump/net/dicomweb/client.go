@@ -0,0 +1,309 @@
+// Package dicomweb implements DICOMweb QIDO-RS, WADO-RS and STOW-RS services
+// over HTTP.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part18/PS3.18.html
+package dicomweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// Media types
+const (
+	MediaTypeDICOM       = "application/dicom"
+	MediaTypeDICOMJSON   = "application/dicom+json"
+	MediaTypeOctetStream = "application/octet-stream"
+)
+
+// ErrContentType is returned for responses that are not of the media type
+// requested.
+var ErrContentType = errors.New("Unexpected response content type")
+
+// StatusError is returned for responses with an HTTP error status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "DICOMweb request failed: " + e.Status
+}
+
+// Client - DICOMweb user agent.
+type Client struct {
+	// URL of the service, as in http://localhost:8080/dicom-web
+	URL string
+	// HTTPClient used for requests, http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Header is added to every request, as in an Authorization header.
+	Header http.Header
+
+	ctx context.Context
+}
+
+// NewClient returns a client for the service at url.
+func NewClient(url string) *Client {
+	return &Client{URL: strings.TrimRight(url, "/"), Header: http.Header{}}
+}
+
+// WithContext returns a copy of the client sending its requests with ctx,
+// they are canceled once it is done.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	client := *c
+	client.ctx = ctx
+	return &client
+}
+
+// SearchStudies queries the studies with QIDO-RS, query holds the matching
+// attributes by keyword or tag and options such as includefield and limit.
+func (c *Client) SearchStudies(query url.Values) ([]*dcmdump.DicomFile, error) {
+	return c.search("/studies", query)
+}
+
+// SearchSeries queries the series of a study, or of all studies when
+// studyUID is empty.
+func (c *Client) SearchSeries(studyUID string, query url.Values) ([]*dcmdump.DicomFile, error) {
+	return c.search(resourcePath(studyUID, "")+"/series", query)
+}
+
+// SearchInstances queries the instances of a series, of a study when
+// seriesUID is empty or of all studies when both are empty.
+func (c *Client) SearchInstances(studyUID, seriesUID string, query url.Values) ([]*dcmdump.DicomFile, error) {
+	return c.search(resourcePath(studyUID, seriesUID)+"/instances", query)
+}
+
+// RetrieveStudy retrieves all the instances of a study with WADO-RS.
+func (c *Client) RetrieveStudy(studyUID string) ([]*dcmdump.DicomFile, error) {
+	return c.retrieve(resourcePath(studyUID, ""))
+}
+
+// RetrieveSeries retrieves all the instances of a series.
+func (c *Client) RetrieveSeries(studyUID, seriesUID string) ([]*dcmdump.DicomFile, error) {
+	return c.retrieve(resourcePath(studyUID, seriesUID))
+}
+
+// RetrieveInstance retrieves a single instance.
+func (c *Client) RetrieveInstance(studyUID, seriesUID, instanceUID string) (*dcmdump.DicomFile, error) {
+	files, err := c.retrieve(resourcePath(studyUID, seriesUID) + "/instances/" + url.PathEscape(instanceUID))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, &StatusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	}
+	return files[0], nil
+}
+
+// RetrieveMetadata retrieves the metadata of the instances of a study,
+// series or instance, bulk data is left out.
+func (c *Client) RetrieveMetadata(studyUID, seriesUID, instanceUID string) ([]*dcmdump.DicomFile, error) {
+	path := resourcePath(studyUID, seriesUID)
+	if instanceUID != "" {
+		path += "/instances/" + url.PathEscape(instanceUID)
+	}
+	return c.search(path+"/metadata", nil)
+}
+
+// RetrieveFrames retrieves frames of an instance by their number, starting at
+// 1, as uncompressed pixel data.
+func (c *Client) RetrieveFrames(studyUID, seriesUID, instanceUID string, frames ...int) ([][]byte, error) {
+	list := make([]string, len(frames))
+	for i, f := range frames {
+		list[i] = strconv.Itoa(f)
+	}
+	path := resourcePath(studyUID, seriesUID) + "/instances/" + url.PathEscape(instanceUID) + "/frames/" + strings.Join(list, ",")
+	resp, err := c.do("GET", path, nil, `multipart/related; type="`+MediaTypeOctetStream+`"`, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data := [][]byte{}
+	err = readMultipart(resp, func(p *multipart.Part) error {
+		b, err := ioutil.ReadAll(p)
+		data = append(data, b)
+		return err
+	})
+	return data, err
+}
+
+// Store uploads files with STOW-RS, to studyUID when not empty, and returns
+// the response with the ReferencedSOPSequence and FailedSOPSequence.
+// The response is also returned with an error when the service failed to
+// store some of the instances.
+func (c *Client) Store(studyUID string, files ...*dcmdump.DicomFile) (*dcmdump.DicomFile, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, df := range files {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {MediaTypeDICOM}})
+		if err != nil {
+			return nil, err
+		}
+		transferSyntax := df.TransferSyntax
+		if transferSyntax == "" {
+			transferSyntax = ts.ExplicitVRLittleEndian
+		}
+		err = df.Write(part, transferSyntax)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := mw.Close()
+	if err != nil {
+		return nil, err
+	}
+	contentType := `multipart/related; type="` + MediaTypeDICOM + `"; boundary=` + mw.Boundary()
+	path := "/studies"
+	if studyUID != "" {
+		path = resourcePath(studyUID, "")
+	}
+	req, err := c.request("POST", path, nil, MediaTypeDICOMJSON, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := &dcmdump.DicomFile{}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), MediaTypeDICOMJSON) {
+		err = json.NewDecoder(resp.Body).Decode(result)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return result, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return result, nil
+}
+
+// resourcePath returns the path of a study or series, the root when
+// studyUID is empty.
+func resourcePath(studyUID, seriesUID string) string {
+	if studyUID == "" {
+		return ""
+	}
+	path := "/studies/" + url.PathEscape(studyUID)
+	if seriesUID != "" {
+		path += "/series/" + url.PathEscape(seriesUID)
+	}
+	return path
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+func (c *Client) request(method, path string, query url.Values, accept string, body io.Reader) (*http.Request, error) {
+	u := c.URL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", accept)
+	return req, nil
+}
+
+// do sends a request and returns the response when successful.
+func (c *Client) do(method, path string, query url.Values, accept string, body io.Reader) (*http.Response, error) {
+	req, err := c.request(method, path, query, accept, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, nil
+}
+
+// search decodes a DICOM JSON response, No Content is an empty result.
+func (c *Client) search(path string, query url.Values) ([]*dcmdump.DicomFile, error) {
+	resp, err := c.do("GET", path, query, MediaTypeDICOMJSON, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	files := []*dcmdump.DicomFile{}
+	if resp.StatusCode == http.StatusNoContent {
+		return files, nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(&files)
+	return files, err
+}
+
+// retrieve parses the instances of a multipart response.
+func (c *Client) retrieve(path string) ([]*dcmdump.DicomFile, error) {
+	resp, err := c.do("GET", path, nil, `multipart/related; type="`+MediaTypeDICOM+`"`, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	files := []*dcmdump.DicomFile{}
+	err = readMultipart(resp, func(p *multipart.Part) error {
+		df, err := dcmdump.ParseFile(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, df)
+		return nil
+	})
+	return files, err
+}
+
+// readMultipart calls f with each part of a multipart/related response.
+func readMultipart(resp *http.Response, f func(*multipart.Part) error) error {
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return ErrContentType
+	}
+	r := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = f(p)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dicomweb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func testFile(t *testing.T, instanceUID string) *dcmdump.DicomFile {
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00020002", "UI", "1.2.840.10008.5.1.4.1.1.7"},
+		{"00020003", "UI", instanceUID},
+		{"00080016", "UI", "1.2.840.10008.5.1.4.1.1.7"},
+		{"00080018", "UI", instanceUID},
+		{"00100010", "PN", "Doe^John"},
+		{"0020000D", "UI", "1.2.3"},
+		{"0020000E", "UI", "1.2.3.1"},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return df
+}
+
+func TestClient(t *testing.T) {
+	var stored []*dcmdump.DicomFile
+	mux := http.NewServeMux()
+	mux.HandleFunc("/studies", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			for {
+				p, err := mr.NextPart()
+				if err != nil {
+					break
+				}
+				df, err := dcmdump.ParseFile(p)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				stored = append(stored, df)
+			}
+			w.Header().Set("Content-Type", MediaTypeDICOMJSON)
+			w.Write([]byte(`{"00081190":{"vr":"UR","Value":["http://localhost/studies/1.2.3"]}}`))
+			return
+		}
+		if r.URL.Query().Get("PatientName") != "DOE*" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", MediaTypeDICOMJSON)
+		w.Write([]byte(`[{"0020000D":{"vr":"UI","Value":["1.2.3"]},"00100010":{"vr":"PN","Value":[{"Alphabetic":"Doe^John"}]}}]`))
+	})
+	mux.HandleFunc("/studies/1.2.3/series/1.2.3.1", func(w http.ResponseWriter, r *http.Request) {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", `multipart/related; type="application/dicom"; boundary=`+mw.Boundary())
+		for _, uid := range []string{"1.2.3.4", "1.2.3.5"} {
+			p, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {MediaTypeDICOM}})
+			testFile(t, uid).Write(p, ts.ExplicitVRLittleEndian)
+		}
+		mw.Close()
+	})
+	mux.HandleFunc("/studies/1.2.3/series/1.2.3.1/instances/1.2.3.4/frames/1,2", func(w http.ResponseWriter, r *http.Request) {
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", `multipart/related; type="application/octet-stream"; boundary=`+mw.Boundary())
+		for _, frame := range []string{"\x01\x02", "\x03\x04"} {
+			p, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {MediaTypeOctetStream}})
+			p.Write([]byte(frame))
+		}
+		mw.Close()
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	c := NewClient(server.URL)
+
+	studies, err := c.SearchStudies(url.Values{"PatientName": {"DOE*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(studies) != 1 {
+		t.Fatalf("Expected 1 study, got %d", len(studies))
+	}
+	de, err := studies[0].LookupElement("00100010")
+	if err != nil || de.Strings()[0] != "Doe^John" {
+		t.Errorf("Unexpected PatientName %v, %v", de, err)
+	}
+	studies, err = c.SearchStudies(url.Values{"PatientName": {"SMITH"}})
+	if err != nil || len(studies) != 0 {
+		t.Errorf("Expected no studies, got %v, %v", studies, err)
+	}
+
+	files, err := c.RetrieveSeries("1.2.3", "1.2.3.1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(files))
+	}
+	de, err = files[1].LookupElement("00080018")
+	if err != nil || de.Strings()[0] != "1.2.3.5" {
+		t.Errorf("Unexpected SOPInstanceUID %v, %v", de, err)
+	}
+
+	frames, err := c.RetrieveFrames("1.2.3", "1.2.3.1", "1.2.3.4", 1, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(frames) != 2 || !bytes.Equal(frames[1], []byte{3, 4}) {
+		t.Errorf("Unexpected frames %v", frames)
+	}
+
+	_, err = c.RetrieveInstance("1.2.3", "1.2.3.1", "9.9.9")
+	if e, ok := err.(*StatusError); !ok || e.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	result, err := c.Store("", testFile(t, "1.2.3.6"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(stored) != 1 {
+		t.Fatalf("Expected 1 stored instance, got %d", len(stored))
+	}
+	de, err = result.LookupElement("00081190")
+	if err != nil || de.Strings()[0] != "http://localhost/studies/1.2.3" {
+		t.Errorf("Unexpected RetrieveURL %v, %v", de, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.WithContext(ctx).SearchStudies(url.Values{"PatientName": {"DOE*"}})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
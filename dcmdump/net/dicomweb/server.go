@@ -0,0 +1,395 @@
+package dicomweb
+
+import (
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/net/match"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// Server - QIDO-RS and WADO-RS service over a FileStore, mount it with
+// http.StripPrefix to serve it under a path.
+type Server struct {
+	Store *FileStore
+	// URL the service is reached at, used for the RetrieveURL of search
+	// results. RetrieveURL is left out when empty.
+	URL string
+}
+
+// NewServer returns a Server for the files indexed by store.
+func NewServer(store *FileStore) *Server {
+	return &Server{Store: store}
+}
+
+// Default attributes returned for each search level
+var (
+	studyAttributes = []string{
+		"00080020", "00080030", "00080050", "00080061", "00080090", "00081030",
+		"00100010", "00100020", "00100030", "00100040",
+		"0020000D", "00200010", "00201206", "00201208",
+	}
+	seriesAttributes = []string{
+		"00080060", "0008103E", "0020000D", "0020000E", "00200011", "00201209",
+	}
+	instanceAttributes = []string{
+		"00080016", "00080018", "0020000D", "0020000E", "00200013",
+		"00280008", "00280010", "00280011",
+	}
+)
+
+// route - Resource of a request path.
+type route struct {
+	study, series, sopID string
+	// search is the level searched, empty for retrieve requests.
+	search string
+	action string
+	frames string
+}
+
+func parseRoute(path string) (route, bool) {
+	r := route{}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i := 0; i < len(parts); i++ {
+		switch p := parts[i]; p {
+		case "studies", "series", "instances":
+			if r.search != "" || r.action != "" {
+				return r, false
+			}
+			if i+1 == len(parts) || parts[i+1] == "series" || parts[i+1] == "instances" || parts[i+1] == "metadata" || parts[i+1] == "frames" {
+				r.search = p
+				continue
+			}
+			i++
+			switch p {
+			case "studies":
+				r.study = parts[i]
+			case "series":
+				r.series = parts[i]
+			default:
+				r.sopID = parts[i]
+			}
+		case "metadata":
+			if r.search != "" || i+1 != len(parts) {
+				return r, false
+			}
+			r.action = p
+		case "frames":
+			if r.search != "" || r.sopID == "" || i+2 != len(parts) {
+				return r, false
+			}
+			r.action = p
+			r.frames = parts[i+1]
+			i++
+		default:
+			return r, false
+		}
+	}
+	return r, r.search != "" || r.study != ""
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rt, ok := parseRoute(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if rt.search != "" {
+		s.search(w, r, rt)
+		return
+	}
+	instances := s.Store.find(rt.study, rt.series, rt.sopID)
+	if len(instances) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	switch rt.action {
+	case "metadata":
+		s.metadata(w, instances)
+	case "frames":
+		s.frames(w, instances[0], rt.frames)
+	default:
+		s.retrieve(w, instances)
+	}
+}
+
+// retrieve sends the files of the instances as stored.
+func (s *Server) retrieve(w http.ResponseWriter, instances []*instance) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", `multipart/related; type="`+MediaTypeDICOM+`"; boundary=`+mw.Boundary())
+	for _, in := range instances {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {MediaTypeDICOM}})
+		if err != nil {
+			return
+		}
+		f, err := os.Open(in.path)
+		if err != nil {
+			return
+		}
+		_, err = io.Copy(part, f)
+		f.Close()
+		if err != nil {
+			return
+		}
+	}
+	mw.Close()
+}
+
+func (s *Server) metadata(w http.ResponseWriter, instances []*instance) {
+	files := make([]*dcmdump.DicomFile, len(instances))
+	for i, in := range instances {
+		files[i] = &dcmdump.DicomFile{Elements: in.elements}
+	}
+	writeJSON(w, files)
+}
+
+// frames sends frames of an instance as native pixel data.
+func (s *Server) frames(w http.ResponseWriter, in *instance, list string) {
+	df, err := in.file()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	frames := [][]byte{}
+	for _, f := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			http.Error(w, "Invalid frame number "+f, http.StatusBadRequest)
+			return
+		}
+		data, err := pi.DecodeFrame(n - 1)
+		switch {
+		case err == dcmdump.ErrUnsupportedTS:
+			http.Error(w, err.Error(), http.StatusNotAcceptable)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		frames = append(frames, data)
+	}
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", `multipart/related; type="`+MediaTypeOctetStream+`"; boundary=`+mw.Boundary())
+	for _, data := range frames {
+		part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {MediaTypeOctetStream}})
+		if err != nil {
+			return
+		}
+		_, err = part.Write(data)
+		if err != nil {
+			return
+		}
+	}
+	mw.Close()
+}
+
+// search answers QIDO-RS requests, the first instance of each study or
+// series gives the attributes of the result.
+func (s *Server) search(w http.ResponseWriter, r *http.Request, rt route) {
+	query := r.URL.Query()
+	attributes := append([]string{}, instanceAttributes...)
+	switch rt.search {
+	case "studies":
+		attributes = append([]string{}, studyAttributes...)
+	case "series":
+		attributes = append([]string{}, seriesAttributes...)
+	}
+	all := false
+	for _, v := range query["includefield"] {
+		for _, k := range strings.Split(v, ",") {
+			if k == "all" {
+				all = true
+				continue
+			}
+			t, ok := queryTag(k)
+			if !ok {
+				http.Error(w, "Unknown attribute "+k, http.StatusBadRequest)
+				return
+			}
+			attributes = append(attributes, t)
+		}
+	}
+	matching := map[string]string{}
+	for k, v := range query {
+		switch k {
+		case "includefield", "limit", "offset", "fuzzymatching":
+			continue
+		}
+		t, ok := queryTag(k)
+		if !ok {
+			http.Error(w, "Unknown attribute "+k, http.StatusBadRequest)
+			return
+		}
+		matching[t] = v[0]
+		attributes = append(attributes, t)
+	}
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	results := []*dcmdump.DicomFile{}
+	for _, group := range groupInstances(s.Store.find(rt.study, rt.series, ""), rt.search) {
+		df := s.result(group, rt.search, attributes, all)
+		if !matches(df, matching) {
+			continue
+		}
+		results = append(results, df)
+	}
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	if len(results) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, results)
+}
+
+// groupInstances groups instances by study or series, in index order.
+func groupInstances(instances []*instance, level string) [][]*instance {
+	groups := [][]*instance{}
+	index := map[string]int{}
+	for _, in := range instances {
+		key := in.sopID
+		switch level {
+		case "studies":
+			key = in.study
+		case "series":
+			key = in.series
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], in)
+	}
+	return groups
+}
+
+// result returns the attributes of a search result.
+func (s *Server) result(group []*instance, level string, attributes []string, all bool) *dcmdump.DicomFile {
+	first := group[0]
+	df := &dcmdump.DicomFile{}
+	wanted := map[string]bool{}
+	for _, t := range attributes {
+		wanted[t] = true
+	}
+	for _, de := range first.elements {
+		if all || wanted[de.TagStr] {
+			df.Elements = append(df.Elements, de)
+		}
+	}
+	series := map[string]bool{}
+	modalities := []string{}
+	for _, in := range group {
+		if !series[in.series] {
+			series[in.series] = true
+			if m := value(in.elements, "00080060"); m != "" && !contains(modalities, m) {
+				modalities = append(modalities, m)
+			}
+		}
+	}
+	switch level {
+	case "studies":
+		if wanted["00080061"] {
+			df.SetElement("00080061", "CS", modalities)
+		}
+		df.SetElement("00201206", "IS", len(series))
+		df.SetElement("00201208", "IS", len(group))
+	case "series":
+		df.SetElement("00201209", "IS", len(group))
+	}
+	if s.URL != "" {
+		path := "/studies/" + first.study
+		switch level {
+		case "series":
+			path += "/series/" + first.series
+		case "instances":
+			path += "/series/" + first.series + "/instances/" + first.sopID
+		}
+		df.SetElement("00081190", "UR", strings.TrimRight(s.URL, "/")+path)
+	}
+	sort.SliceStable(df.Elements, func(i, j int) bool { return df.Elements[i].TagStr < df.Elements[j].TagStr })
+	return df
+}
+
+// matches reports whether the attributes of df match all the keys.
+// UID lists can also be separated by commas in QIDO-RS queries.
+func matches(df *dcmdump.DicomFile, matching map[string]string) bool {
+	for t, m := range matching {
+		if m == "" {
+			continue
+		}
+		de, err := df.LookupElement(t)
+		if err != nil {
+			return false
+		}
+		if de.VRStr == "UI" {
+			m = strings.Replace(m, ",", `\`, -1)
+		}
+		ok := false
+		for _, v := range de.Strings() {
+			if match.Value(de.VRStr, v, m) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// queryTag returns the tag of a query key given by keyword or tag.
+func queryTag(key string) (string, bool) {
+	if info, ok := tag.ByKeyword(key); ok {
+		return info.TagStr(), true
+	}
+	t, err := strconv.ParseUint(key, 16, 32)
+	if err != nil || len(key) != 8 {
+		return "", false
+	}
+	return strings.ToUpper(key), t > 0
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, files []*dcmdump.DicomFile) {
+	b, err := json.Marshal(files)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", MediaTypeDICOMJSON)
+	w.Write(b)
+}
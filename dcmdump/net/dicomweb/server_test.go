@@ -0,0 +1,106 @@
+package dicomweb
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	for i, uid := range []string{"1.2.3.4", "1.2.3.5"} {
+		df := testFile(t, uid)
+		df.SetElement("00080060", "CS", "OT")
+		df.SetElement("00080020", "DA", "2020010"+string(rune('1'+i)))
+		df.SetElement("00280010", "US", 2)
+		df.SetElement("00280011", "US", 2)
+		df.SetElement("00280100", "US", 8)
+		df.SetElement("7FE00010", "OB", []byte{1, 2, 3, 4})
+		f, err := os.Create(filepath.Join(dir, uid+".dcm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a DICOM file"), 0644)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	s := NewServer(store)
+	server := httptest.NewServer(http.StripPrefix("/dicom-web", s))
+	defer server.Close()
+	s.URL = server.URL + "/dicom-web"
+	c := NewClient(s.URL)
+
+	studies, err := c.SearchStudies(url.Values{"PatientName": {"doe*"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(studies) != 1 {
+		t.Fatalf("Expected 1 study, got %d", len(studies))
+	}
+	for tagStr, expected := range map[string]string{
+		"00080061": "OT",
+		"00201206": "1",
+		"00201208": "2",
+		"00081190": s.URL + "/studies/1.2.3",
+	} {
+		de, err := studies[0].LookupElement(tagStr)
+		if err != nil || de.Strings()[0] != expected {
+			t.Errorf("Expected %s %s, got %v, %v", tagStr, expected, de, err)
+		}
+	}
+	studies, err = c.SearchStudies(url.Values{"PatientName": {"SMITH*"}})
+	if err != nil || len(studies) != 0 {
+		t.Errorf("Expected no studies, got %v, %v", studies, err)
+	}
+
+	instances, err := c.SearchInstances("1.2.3", "", url.Values{"StudyDate": {"20200102-"}, "includefield": {"PatientName"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("Expected 1 instance, got %d", len(instances))
+	}
+	de, err := instances[0].LookupElement("00080018")
+	if err != nil || de.Strings()[0] != "1.2.3.5" {
+		t.Errorf("Unexpected SOPInstanceUID %v, %v", de, err)
+	}
+	if _, err = instances[0].LookupElement("00100010"); err != nil {
+		t.Errorf("Missing included PatientName")
+	}
+
+	files, err := c.RetrieveStudy("1.2.3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 instances, got %d", len(files))
+	}
+	metadata, err := c.RetrieveMetadata("1.2.3", "1.2.3.1", "1.2.3.4")
+	if err != nil || len(metadata) != 1 {
+		t.Fatalf("Unexpected metadata %v, %v", metadata, err)
+	}
+	frames, err := c.RetrieveFrames("1.2.3", "1.2.3.1", "1.2.3.4", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(frames) != 1 || !bytes.Equal(frames[0], []byte{1, 2, 3, 4}) {
+		t.Errorf("Unexpected frames %v", frames)
+	}
+	_, err = c.RetrieveFrames("1.2.3", "1.2.3.1", "1.2.3.4", 2)
+	if e, ok := err.(*StatusError); !ok || e.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
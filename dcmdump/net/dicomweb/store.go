@@ -0,0 +1,129 @@
+package dicomweb
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// maxMetadataValue is the length over which binary values are left out of
+// the index.
+const maxMetadataValue = 1024
+
+// instance - Indexed file with its metadata.
+type instance struct {
+	path                 string
+	study, series, sopID string
+	elements             []dcmdump.DataElement
+}
+
+// FileStore - Index of the DICOM files under a directory.
+// It is safe for concurrent use.
+type FileStore struct {
+	Dir string
+
+	mu        sync.RWMutex
+	instances []*instance
+}
+
+// NewFileStore indexes the files under dir.
+func NewFileStore(dir string) (*FileStore, error) {
+	s := &FileStore{Dir: dir}
+	return s, s.Index()
+}
+
+// Index walks Dir again and replaces the index, files that are not DICOM are
+// skipped.
+func (s *FileStore) Index() error {
+	instances := []*instance{}
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		in, err := readInstance(path)
+		if err == dcmdump.ErrNotDICM {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		instances = append(instances, in)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].path < instances[j].path })
+	s.mu.Lock()
+	s.instances = instances
+	s.mu.Unlock()
+	return nil
+}
+
+func readInstance(path string) (*instance, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	df, err := dcmdump.ParseFile(f)
+	if err != nil {
+		return nil, err
+	}
+	in := &instance{
+		path:   path,
+		study:  value(df.Elements, "0020000D"),
+		series: value(df.Elements, "0020000E"),
+		sopID:  value(df.Elements, "00080018"),
+	}
+	for _, de := range df.Elements {
+		if de.TagStr[:4] == "0002" || len(de.Data) > maxMetadataValue {
+			continue
+		}
+		in.elements = append(in.elements, de)
+	}
+	return in, nil
+}
+
+// find returns the instances of a study, series or instance, all instances
+// for empty UIDs.
+func (s *FileStore) find(study, series, sopID string) []*instance {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := []*instance{}
+	for _, in := range s.instances {
+		if study != "" && in.study != study || series != "" && in.series != series || sopID != "" && in.sopID != sopID {
+			continue
+		}
+		found = append(found, in)
+	}
+	return found
+}
+
+// file parses the whole file of an instance.
+func (in *instance) file() (*dcmdump.DicomFile, error) {
+	f, err := os.Open(in.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return dcmdump.ParseFile(f)
+}
+
+// value returns the first value of a top level element.
+func value(elements []dcmdump.DataElement, tagStr string) string {
+	for i := range elements {
+		if elements[i].TagStr == tagStr {
+			if v := elements[i].Strings(); len(v) > 0 {
+				return v[0]
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,382 @@
+package dimse
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// ErrRejected is returned by Dial when the peer rejects the association.
+var ErrRejected = errors.New("Association rejected")
+
+// ErrAborted is returned when the peer aborts the association.
+var ErrAborted = errors.New("Association aborted")
+
+// ErrNoContext is returned when no accepted presentation context matches the
+// SOP class of a request.
+var ErrNoContext = errors.New("No accepted presentation context")
+
+// DefaultTransferSyntaxes are proposed and accepted when none are given.
+var DefaultTransferSyntaxes = []string{ts.ExplicitVRLittleEndian, ts.ImplicitVRLittleEndian}
+
+// Message - DIMSE message, a command and its optional data set.
+type Message struct {
+	ContextID byte
+	Command   *Command
+	// Data is the data set encoded with the TransferSyntax of the context.
+	Data []byte
+}
+
+// Association - Established association between two application entities.
+// Send can be called concurrently, Receive is meant to be called from a
+// single goroutine.
+type Association struct {
+	CalledAE  string
+	CallingAE string
+	// Contexts accepted for the association, with their single transfer
+	// syntax.
+	Contexts map[byte]*PresentationContext
+	// MaxOperationsInvoked and MaxOperationsPerformed negotiated by the
+	// asynchronous operations window, 0 meaning unlimited. Both are 1 for
+	// synchronous operations.
+	MaxOperationsInvoked   uint16
+	MaxOperationsPerformed uint16
+	// UserIdentity proposed by the requester, nil when none.
+	UserIdentity *UserIdentity
+	// IdentityResponse of the acceptor to a UserIdentity requesting a
+	// positive response, nil when none was sent.
+	IdentityResponse []byte
+
+	conn      net.Conn
+	maxPDU    uint32
+	mu        sync.Mutex
+	messageID uint16
+}
+
+// Dial opens an association with the application entity calledAE at addr
+// proposing the given presentation contexts.
+// Contexts without transfer syntaxes propose DefaultTransferSyntaxes and
+// context IDs are assigned when left at 0.
+func Dial(addr, callingAE, calledAE string, contexts []PresentationContext) (*Association, error) {
+	return DialConfig(addr, AssociationConfig{CallingAE: callingAE, CalledAE: calledAE}, contexts)
+}
+
+// DialConfig opens an association at addr negotiating the parameters of
+// config, as described in Dial.
+func DialConfig(addr string, config AssociationConfig, contexts []PresentationContext) (*Association, error) {
+	return DialContext(context.Background(), addr, config, contexts)
+}
+
+// DialContext opens an association like DialConfig, ctx bounds the
+// connection and the negotiation. Once established, the association is
+// aborted when ctx is done and the operations in progress fail.
+func DialContext(ctx context.Context, addr string, config AssociationConfig, contexts []PresentationContext) (*Association, error) {
+	var conn net.Conn
+	var err error
+	if config.TLSConfig != nil {
+		d := &tls.Dialer{Config: config.TLSConfig}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn = tracePDUs(conn, config.Trace)
+	if ctx.Done() == nil {
+		a, err := request(conn, config, contexts)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return a, nil
+	}
+	c := &contextConn{Conn: conn, closed: make(chan struct{})}
+	established := make(chan *Association, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.closed:
+			return
+		}
+		// Unblocks the negotiation or the operations in progress
+		c.SetDeadline(time.Now())
+		a := <-established
+		if a == nil {
+			return
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		c.SetWriteDeadline(time.Now().Add(abortTimeout))
+		writePDU(c, pduAbort, make([]byte, 4))
+		c.Close()
+	}()
+	a, err := request(c, config, contexts)
+	established <- a
+	if err != nil {
+		c.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// abortTimeout bounds the A-ABORT sent when the context of an association is
+// done.
+const abortTimeout = time.Second
+
+// contextConn signals the goroutine watching the context of an association
+// once the connection is closed.
+type contextConn struct {
+	net.Conn
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (c *contextConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// request negotiates the association over conn as the requester.
+func request(conn net.Conn, config AssociationConfig, contexts []PresentationContext) (*Association, error) {
+	rq := associate{
+		CalledAE:                  config.CalledAE,
+		CallingAE:                 config.CallingAE,
+		MaxPDULength:              config.MaxPDULength,
+		ImplementationClassUID:    dcmdump.ImplementationClassUID,
+		ImplementationVersionName: dcmdump.ImplementationVersionName,
+		asyncOps:                  config.asyncOps(),
+		MaxOperationsInvoked:      config.MaxOperationsInvoked,
+		MaxOperationsPerformed:    config.MaxOperationsPerformed,
+		identity:                  config.UserIdentity,
+	}
+	if rq.MaxPDULength == 0 {
+		rq.MaxPDULength = DefaultMaxPDULength
+	}
+	proposed := map[byte]PresentationContext{}
+	for i, pc := range contexts {
+		if pc.ID == 0 {
+			pc.ID = byte(2*i + 1)
+		}
+		if len(pc.TransferSyntaxes) == 0 {
+			pc.TransferSyntaxes = DefaultTransferSyntaxes
+		}
+		proposed[pc.ID] = pc
+		rq.Contexts = append(rq.Contexts, pc)
+	}
+	err := writePDU(conn, pduAssociateRQ, rq.encode(false))
+	if err != nil {
+		return nil, err
+	}
+	pduType, data, err := readPDU(conn)
+	if err != nil {
+		return nil, err
+	}
+	switch pduType {
+	case pduAssociateAC:
+	case pduAssociateRJ:
+		return nil, ErrRejected
+	case pduAbort:
+		return nil, ErrAborted
+	default:
+		return nil, ErrPDU
+	}
+	ac, err := decodeAssociate(data)
+	if err != nil {
+		return nil, err
+	}
+	a := &Association{
+		CalledAE:               config.CalledAE,
+		CallingAE:              config.CallingAE,
+		Contexts:               map[byte]*PresentationContext{},
+		MaxOperationsInvoked:   1,
+		MaxOperationsPerformed: 1,
+		UserIdentity:           config.UserIdentity,
+		IdentityResponse:       ac.identityResponse,
+		conn:                   conn,
+		maxPDU:                 ac.MaxPDULength,
+	}
+	if ac.asyncOps {
+		a.MaxOperationsInvoked, a.MaxOperationsPerformed = ac.MaxOperationsInvoked, ac.MaxOperationsPerformed
+	}
+	for _, pc := range ac.Contexts {
+		if pc.Result != ResultAcceptance || len(pc.TransferSyntaxes) == 0 {
+			continue
+		}
+		accepted := pc
+		accepted.AbstractSyntax = proposed[pc.ID].AbstractSyntax
+		accepted.SCPRole = ac.scpRoles[accepted.AbstractSyntax]
+		a.Contexts[pc.ID] = &accepted
+	}
+	return a, nil
+}
+
+// Context returns the accepted presentation context for an abstract syntax,
+// preferring transferSyntax when given.
+func (a *Association) Context(abstractSyntax, transferSyntax string) (*PresentationContext, error) {
+	var found *PresentationContext
+	for _, pc := range a.Contexts {
+		if pc.AbstractSyntax != abstractSyntax {
+			continue
+		}
+		if transferSyntax == "" || pc.TransferSyntaxes[0] == transferSyntax {
+			return pc, nil
+		}
+		found = pc
+	}
+	if found == nil {
+		return nil, ErrNoContext
+	}
+	return found, nil
+}
+
+// TransferSyntax returns the transfer syntax accepted for a context.
+func (a *Association) TransferSyntax(contextID byte) string {
+	pc, ok := a.Contexts[contextID]
+	if !ok || len(pc.TransferSyntaxes) == 0 {
+		return ts.ImplicitVRLittleEndian
+	}
+	return pc.TransferSyntaxes[0]
+}
+
+// NextMessageID returns a new message ID for a request.
+func (a *Association) NextMessageID() uint16 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messageID++
+	return a.messageID
+}
+
+// Send writes a command and its data set, if not nil, fragmented to the peer
+// maximum PDU length.
+func (a *Association) Send(contextID byte, cmd *Command, data []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cmd.HasDataSet = data != nil
+	err := a.sendPDVs(contextID, true, cmd.encode())
+	if err != nil || data == nil {
+		return err
+	}
+	return a.sendPDVs(contextID, false, data)
+}
+
+func (a *Association) sendPDVs(contextID byte, command bool, data []byte) error {
+	max := int(a.maxPDU)
+	if max == 0 || max > DefaultMaxPDULength*16 {
+		max = DefaultMaxPDULength
+	}
+	max -= 6
+	for {
+		n := len(data)
+		if n > max {
+			n = max
+		}
+		p := pdv{ContextID: contextID, Command: command, Last: n == len(data), Data: data[:n]}
+		err := writePDU(a.conn, pduDataTF, p.encode())
+		if err != nil || p.Last {
+			return err
+		}
+		data = data[n:]
+	}
+}
+
+// Receive reads the next message.
+// It returns io.EOF once the peer releases the association, which is then
+// confirmed and closed.
+func (a *Association) Receive() (*Message, error) {
+	var command, data []byte
+	var m *Message
+	for {
+		pduType, b, err := readPDU(a.conn)
+		if err != nil {
+			return nil, err
+		}
+		switch pduType {
+		case pduDataTF:
+		case pduReleaseRQ:
+			writePDU(a.conn, pduReleaseRP, make([]byte, 4))
+			a.conn.Close()
+			return nil, io.EOF
+		case pduAbort:
+			a.conn.Close()
+			return nil, ErrAborted
+		default:
+			a.Abort()
+			return nil, ErrPDU
+		}
+		pdvs, err := decodePDVs(b)
+		if err != nil {
+			a.Abort()
+			return nil, err
+		}
+		for _, p := range pdvs {
+			if p.Command {
+				command = append(command, p.Data...)
+				if !p.Last {
+					continue
+				}
+				cmd, err := decodeCommand(command)
+				if err != nil {
+					a.Abort()
+					return nil, err
+				}
+				m = &Message{ContextID: p.ContextID, Command: cmd}
+				if !cmd.HasDataSet {
+					return m, nil
+				}
+				continue
+			}
+			if m == nil {
+				a.Abort()
+				return nil, ErrPDU
+			}
+			data = append(data, p.Data...)
+			if p.Last {
+				m.Data = data
+				return m, nil
+			}
+		}
+	}
+}
+
+// Release ends the association and closes the connection.
+func (a *Association) Release() error {
+	defer a.conn.Close()
+	a.mu.Lock()
+	err := writePDU(a.conn, pduReleaseRQ, make([]byte, 4))
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	for {
+		pduType, _, err := readPDU(a.conn)
+		if err != nil {
+			return err
+		}
+		switch pduType {
+		case pduReleaseRP:
+			return nil
+		case pduAbort:
+			return ErrAborted
+		}
+	}
+}
+
+// Abort aborts the association and closes the connection.
+func (a *Association) Abort() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	writePDU(a.conn, pduAbort, make([]byte, 4))
+	return a.conn.Close()
+}
@@ -0,0 +1,205 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// Command fields
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part07/chapter_E.html
+const (
+	CStoreRQ        = 0x0001
+	CStoreRSP       = 0x8001
+	CGetRQ          = 0x0010
+	CGetRSP         = 0x8010
+	CFindRQ         = 0x0020
+	CFindRSP        = 0x8020
+	CMoveRQ         = 0x0021
+	CMoveRSP        = 0x8021
+	CEchoRQ         = 0x0030
+	CEchoRSP        = 0x8030
+	NEventReportRQ  = 0x0100
+	NEventReportRSP = 0x8100
+	NGetRQ          = 0x0110
+	NGetRSP         = 0x8110
+	NSetRQ          = 0x0120
+	NSetRSP         = 0x8120
+	NActionRQ       = 0x0130
+	NActionRSP      = 0x8130
+	NCreateRQ       = 0x0140
+	NCreateRSP      = 0x8140
+	NDeleteRQ       = 0x0150
+	NDeleteRSP      = 0x8150
+	CCancelRQ       = 0x0FFF
+)
+
+// Status codes
+const (
+	StatusSuccess        = 0x0000
+	StatusCancel         = 0xFE00
+	StatusPending        = 0xFF00
+	StatusPendingWarning = 0xFF01
+	// StatusUnrecognizedOperation is returned for commands not handled.
+	StatusUnrecognizedOperation = 0x0211
+	// StatusProcessingFailure - Failed, unable to process.
+	StatusProcessingFailure = 0x0110
+)
+
+// noDataSet is the CommandDataSetType of commands without a data set.
+const noDataSet = 0x0101
+
+// Command - DIMSE command set, group 0000 encoded as implicit VR little
+// endian.
+// Only the fields used by the message are encoded, zero values are left out
+// except for the counts of C-GET and C-MOVE responses.
+type Command struct {
+	CommandField              uint16
+	MessageID                 uint16
+	MessageIDBeingRespondedTo uint16
+	AffectedSOPClassUID       string
+	RequestedSOPClassUID      string
+	AffectedSOPInstanceUID    string
+	RequestedSOPInstanceUID   string
+	MoveDestination           string
+	Priority                  uint16
+	// HasDataSet is set when the command is followed by a data set.
+	HasDataSet   bool
+	Status       uint16
+	ErrorComment string
+	EventTypeID  uint16
+	ActionTypeID uint16
+	// Sub-operation counts of C-GET and C-MOVE responses.
+	Remaining uint16
+	Completed uint16
+	Failed    uint16
+	Warning   uint16
+	// MoveOriginator of C-STORE sub-operations.
+	MoveOriginatorAE        string
+	MoveOriginatorMessageID uint16
+}
+
+// IsResponse reports whether the command is a response.
+func (c *Command) IsResponse() bool {
+	return c.CommandField&0x8000 != 0
+}
+
+func (c *Command) encode() []byte {
+	b := []byte{}
+	add := func(elem uint16, value []byte) {
+		h := make([]byte, 8)
+		binary.LittleEndian.PutUint16(h[2:], elem)
+		binary.LittleEndian.PutUint32(h[4:], uint32(len(value)))
+		b = append(append(b, h...), value...)
+	}
+	str := func(elem uint16, s string, pad byte) {
+		if s == "" {
+			return
+		}
+		v := []byte(s)
+		if len(v)%2 != 0 {
+			v = append(v, pad)
+		}
+		add(elem, v)
+	}
+	us := func(elem uint16, v uint16, always bool) {
+		if v == 0 && !always {
+			return
+		}
+		add(elem, []byte{byte(v), byte(v >> 8)})
+	}
+	rsp := c.IsResponse()
+	counts := c.CommandField == CGetRSP || c.CommandField == CMoveRSP
+	str(0x0002, c.AffectedSOPClassUID, 0)
+	str(0x0003, c.RequestedSOPClassUID, 0)
+	us(0x0100, c.CommandField, true)
+	us(0x0110, c.MessageID, !rsp)
+	us(0x0120, c.MessageIDBeingRespondedTo, rsp)
+	str(0x0600, c.MoveDestination, ' ')
+	us(0x0700, c.Priority, c.CommandField == CStoreRQ || c.CommandField == CFindRQ || c.CommandField == CGetRQ || c.CommandField == CMoveRQ)
+	dataSetType := uint16(noDataSet)
+	if c.HasDataSet {
+		dataSetType = 0
+	}
+	us(0x0800, dataSetType, true)
+	us(0x0900, c.Status, rsp)
+	str(0x0902, c.ErrorComment, ' ')
+	str(0x1000, c.AffectedSOPInstanceUID, 0)
+	str(0x1001, c.RequestedSOPInstanceUID, 0)
+	us(0x1002, c.EventTypeID, false)
+	us(0x1008, c.ActionTypeID, false)
+	us(0x1020, c.Remaining, counts)
+	us(0x1021, c.Completed, counts)
+	us(0x1022, c.Failed, counts)
+	us(0x1023, c.Warning, counts)
+	str(0x1030, c.MoveOriginatorAE, ' ')
+	us(0x1031, c.MoveOriginatorMessageID, false)
+
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(len(b)))
+	h := []byte{0, 0, 0, 0, 4, 0, 0, 0}
+	return append(append(h, length...), b...)
+}
+
+func decodeCommand(data []byte) (*Command, error) {
+	c := &Command{}
+	for n := 0; n < len(data); {
+		// Command sets are always implicit VR little endian
+		h, size, err := dcmdump.DecodeElementHeader(data[n:], false, binary.LittleEndian)
+		l := int(h.Length)
+		if err != nil || h.Group != 0x0000 || l < 0 || int64(n+size)+int64(l) > int64(len(data)) {
+			return c, ErrPDU
+		}
+		v := data[n+size : n+size+l]
+		n += size + l
+		s := strings.TrimRight(string(v), "\x00 ")
+		var u uint16
+		if l >= 2 {
+			u = binary.LittleEndian.Uint16(v)
+		}
+		switch h.Element {
+		case 0x0002:
+			c.AffectedSOPClassUID = s
+		case 0x0003:
+			c.RequestedSOPClassUID = s
+		case 0x0100:
+			c.CommandField = u
+		case 0x0110:
+			c.MessageID = u
+		case 0x0120:
+			c.MessageIDBeingRespondedTo = u
+		case 0x0600:
+			c.MoveDestination = strings.TrimSpace(s)
+		case 0x0700:
+			c.Priority = u
+		case 0x0800:
+			c.HasDataSet = u != noDataSet
+		case 0x0900:
+			c.Status = u
+		case 0x0902:
+			c.ErrorComment = s
+		case 0x1000:
+			c.AffectedSOPInstanceUID = s
+		case 0x1001:
+			c.RequestedSOPInstanceUID = s
+		case 0x1002:
+			c.EventTypeID = u
+		case 0x1008:
+			c.ActionTypeID = u
+		case 0x1020:
+			c.Remaining = u
+		case 0x1021:
+			c.Completed = u
+		case 0x1022:
+			c.Failed = u
+		case 0x1023:
+			c.Warning = u
+		case 0x1030:
+			c.MoveOriginatorAE = strings.TrimSpace(s)
+		case 0x1031:
+			c.MoveOriginatorMessageID = u
+		}
+	}
+	return c, nil
+}
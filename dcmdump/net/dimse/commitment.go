@@ -0,0 +1,252 @@
+package dimse
+
+import (
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// StorageCommitmentInstance is the well-known SOP instance of the Storage
+// Commitment Push Model.
+const StorageCommitmentInstance = "1.2.840.10008.1.20.1.1"
+
+// Storage commitment action and event types
+const (
+	commitmentRequest       = 1
+	commitmentSuccess       = 1
+	commitmentFailuresExist = 2
+)
+
+// Storage commitment FailureReason (0008,1197) values
+const (
+	FailureProcessing              = 0x0110
+	FailureNoSuchInstance          = 0x0112
+	FailureClassInstanceConflict   = 0x0119
+	FailureSOPClassNotSupported    = 0x0122
+	FailureDuplicateTransactionUID = 0x0131
+	FailureResourceLimitation      = 0x0213
+)
+
+// SOPReference - SOP instance referenced by a storage commitment.
+type SOPReference struct {
+	SOPClassUID    string
+	SOPInstanceUID string
+	// FailureReason of a failed reference, 0 when committed.
+	FailureReason uint16
+}
+
+// Commitment - Result of a storage commitment transaction.
+type Commitment struct {
+	TransactionUID string
+	Committed      []SOPReference
+	Failed         []SOPReference
+}
+
+// References returns the SOP references of the files.
+func References(files ...*dcmdump.DicomFile) []SOPReference {
+	refs := []SOPReference{}
+	for _, df := range files {
+		refs = append(refs, SOPReference{
+			SOPClassUID:    firstString(df, "00080016", "00020002"),
+			SOPInstanceUID: firstString(df, "00080018", "00020003"),
+		})
+	}
+	return refs
+}
+
+// Commit requests the storage commitment of refs from the application entity
+// calledAE at addr and waits for the result on the same association.
+// Use RequestCommitment instead when the result is reported on a new
+// association.
+func Commit(addr, callingAE, calledAE string, refs []SOPReference) (*Commitment, error) {
+	a, err := Dial(addr, callingAE, calledAE, []PresentationContext{{AbstractSyntax: sopclass.StorageCommitmentPushModel}})
+	if err != nil {
+		return nil, err
+	}
+	transactionUID, err := a.RequestCommitment(refs)
+	if err != nil {
+		a.Abort()
+		return nil, err
+	}
+	m, err := a.Receive()
+	if err != nil {
+		return nil, err
+	}
+	c, err := CommitmentReport(a, m)
+	if err == nil && c.TransactionUID != transactionUID {
+		err = ErrPDU
+	}
+	if err != nil {
+		a.Abort()
+		return nil, err
+	}
+	return c, a.Release()
+}
+
+// RequestCommitment sends the N-ACTION storage commitment request of refs
+// and returns its transaction UID once accepted.
+func (a *Association) RequestCommitment(refs []SOPReference) (string, error) {
+	transactionUID := dcmdump.NewUID()
+	info := &dcmdump.DicomFile{}
+	err := info.SetElement("00081195", "UI", transactionUID)
+	if err != nil {
+		return "", err
+	}
+	err = info.SetElement("00081199", "SQ", referenceItems(refs))
+	if err != nil {
+		return "", err
+	}
+	err = a.NAction(sopclass.StorageCommitmentPushModel, StorageCommitmentInstance, commitmentRequest, info)
+	if err != nil {
+		return "", err
+	}
+	return transactionUID, nil
+}
+
+// CommitmentReport parses the storage commitment result of an N-EVENT-REPORT
+// request and answers it.
+// It can be called from Server.Handle to receive results reported on a new
+// association.
+func CommitmentReport(a *Association, m *Message) (*Commitment, error) {
+	if m.Command.CommandField != NEventReportRQ || m.Command.AffectedSOPClassUID != sopclass.StorageCommitmentPushModel {
+		return nil, ErrPDU
+	}
+	rsp := &Command{
+		CommandField:              NEventReportRSP,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+		AffectedSOPInstanceUID:    m.Command.AffectedSOPInstanceUID,
+		EventTypeID:               m.Command.EventTypeID,
+	}
+	df, err := responseFile(a, m)
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusCannotUnderstand, err.Error()
+		a.Send(m.ContextID, rsp, nil)
+		return nil, err
+	}
+	c := &Commitment{
+		TransactionUID: firstString(df, "00081195"),
+		Committed:      references(df, "00081199"),
+		Failed:         references(df, "00081198"),
+	}
+	return c, a.Send(m.ContextID, rsp, nil)
+}
+
+// referenceItems returns the items of a ReferencedSOPSequence (0008,1199)
+// or, for references with a FailureReason, a FailedSOPSequence (0008,1198).
+func referenceItems(refs []SOPReference) []dcmdump.Item {
+	items := []dcmdump.Item{}
+	for _, ref := range refs {
+		class, _ := dcmdump.NewDataElement("00081150", "UI", ref.SOPClassUID)
+		instance, _ := dcmdump.NewDataElement("00081155", "UI", ref.SOPInstanceUID)
+		item := dcmdump.Item{Elements: []dcmdump.DataElement{class, instance}}
+		if ref.FailureReason != 0 {
+			reason, _ := dcmdump.NewDataElement("00081197", "US", int(ref.FailureReason))
+			item.Elements = append(item.Elements, reason)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// references returns the SOP references of the sequence tagStr.
+func references(df *dcmdump.DicomFile, tagStr string) []SOPReference {
+	de, err := df.LookupElement(tagStr)
+	if err != nil {
+		return nil
+	}
+	refs := []SOPReference{}
+	for _, item := range de.Items {
+		ref := SOPReference{}
+		if e := dcmdump.FindElement(item.Elements, "00081150"); e != nil && len(e.Strings()) > 0 {
+			ref.SOPClassUID = e.Strings()[0]
+		}
+		if e := dcmdump.FindElement(item.Elements, "00081155"); e != nil && len(e.Strings()) > 0 {
+			ref.SOPInstanceUID = e.Strings()[0]
+		}
+		if e := dcmdump.FindElement(item.Elements, "00081197"); e != nil && len(e.Ints()) > 0 {
+			ref.FailureReason = uint16(e.Ints()[0])
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// CommitmentServer - Storage Commitment Push Model service class provider.
+// Each request is answered and its result reported on the same association.
+type CommitmentServer struct {
+	Server
+	// Commit returns the FailureReason of the referenced instance, 0 once it
+	// is safely stored.
+	Commit func(a *Association, ref SOPReference) uint16
+}
+
+// NewCommitmentServer returns a CommitmentServer committing the instances
+// accepted by commit.
+func NewCommitmentServer(aeTitle string, commit func(a *Association, ref SOPReference) uint16) *CommitmentServer {
+	s := &CommitmentServer{
+		Server: Server{AETitle: aeTitle, SOPClasses: []string{sopclass.StorageCommitmentPushModel}},
+		Commit: commit,
+	}
+	s.Handle = s.handleCommitment
+	return s
+}
+
+func (s *CommitmentServer) handleCommitment(a *Association, m *Message) error {
+	rsp := &Command{
+		CommandField:              m.Command.CommandField | 0x8000,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.RequestedSOPClassUID,
+		AffectedSOPInstanceUID:    m.Command.RequestedSOPInstanceUID,
+		ActionTypeID:              m.Command.ActionTypeID,
+	}
+	switch {
+	case m.Command.CommandField != NActionRQ:
+		rsp.AffectedSOPClassUID = m.Command.AffectedSOPClassUID
+		rsp.Status = StatusUnrecognizedOperation
+		return a.Send(m.ContextID, rsp, nil)
+	case m.Command.RequestedSOPInstanceUID != StorageCommitmentInstance:
+		rsp.Status = StatusNoSuchSOPInstance
+		return a.Send(m.ContextID, rsp, nil)
+	case m.Command.ActionTypeID != commitmentRequest:
+		rsp.Status = StatusNoSuchAction
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	df, err := responseFile(a, m)
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusCannotUnderstand, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	transactionUID := firstString(df, "00081195")
+	if transactionUID == "" {
+		rsp.Status, rsp.ErrorComment = StatusInvalidAttributeValue, "Missing TransactionUID"
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	rsp.Status = StatusSuccess
+	err = a.Send(m.ContextID, rsp, nil)
+	if err != nil {
+		return err
+	}
+
+	committed, failed := []SOPReference{}, []SOPReference{}
+	for _, ref := range references(df, "00081199") {
+		if s.Commit != nil {
+			ref.FailureReason = s.Commit(a, ref)
+		}
+		if ref.FailureReason != 0 {
+			failed = append(failed, ref)
+		} else {
+			committed = append(committed, ref)
+		}
+	}
+	info := &dcmdump.DicomFile{}
+	info.SetElement("00081195", "UI", transactionUID)
+	if len(committed) > 0 {
+		info.SetElement("00081199", "SQ", referenceItems(committed))
+	}
+	eventType := uint16(commitmentSuccess)
+	if len(failed) > 0 {
+		eventType = commitmentFailuresExist
+		info.SetElement("00081198", "SQ", referenceItems(failed))
+	}
+	return a.NEventReport(sopclass.StorageCommitmentPushModel, StorageCommitmentInstance, eventType, info)
+}
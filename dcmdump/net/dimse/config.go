@@ -0,0 +1,120 @@
+package dimse
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+)
+
+// User identity types
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part07/sect_D.3.3.7.html
+const (
+	IdentityUsername         = 1
+	IdentityUsernamePasscode = 2
+	IdentityKerberos         = 3
+	IdentitySAML             = 4
+	IdentityJWT              = 5
+)
+
+// AssociationConfig - Parameters proposed when requesting an association,
+// zero values use the defaults.
+type AssociationConfig struct {
+	CallingAE string
+	CalledAE  string
+	// MaxPDULength announced to the peer, DefaultMaxPDULength when 0.
+	MaxPDULength uint32
+	// MaxOperationsInvoked and MaxOperationsPerformed propose an asynchronous
+	// operations window when either is greater than 1, operations are
+	// synchronous otherwise.
+	MaxOperationsInvoked   uint16
+	MaxOperationsPerformed uint16
+	// UserIdentity proposed to the peer, none when nil.
+	UserIdentity *UserIdentity
+	// TLSConfig secures the connection with TLS when not nil, see
+	// BCP195Config.
+	TLSConfig *tls.Config
+	// Trace is called with each PDU sent and received, see LogPDUs and
+	// CapturePDUs.
+	Trace func(p *PDU)
+}
+
+// UserIdentity - User Identity negotiation sub-item.
+type UserIdentity struct {
+	Type byte
+	// PrimaryField holds the username, Kerberos ticket, SAML assertion or
+	// JSON web token.
+	PrimaryField []byte
+	// SecondaryField holds the passcode of IdentityUsernamePasscode.
+	SecondaryField []byte
+	// ResponseRequested asks the acceptor for a positive response, see
+	// Association.IdentityResponse.
+	ResponseRequested bool
+}
+
+// UsernameIdentity returns a username identity, with its passcode when not
+// empty.
+func UsernameIdentity(username, passcode string) *UserIdentity {
+	if passcode == "" {
+		return &UserIdentity{Type: IdentityUsername, PrimaryField: []byte(username)}
+	}
+	return &UserIdentity{Type: IdentityUsernamePasscode, PrimaryField: []byte(username), SecondaryField: []byte(passcode)}
+}
+
+// KerberosIdentity returns a Kerberos service ticket identity, requesting
+// the server ticket as response.
+func KerberosIdentity(ticket []byte) *UserIdentity {
+	return &UserIdentity{Type: IdentityKerberos, PrimaryField: ticket, ResponseRequested: true}
+}
+
+// SAMLIdentity returns a SAML assertion identity.
+func SAMLIdentity(assertion []byte) *UserIdentity {
+	return &UserIdentity{Type: IdentitySAML, PrimaryField: assertion}
+}
+
+// asyncOps reports whether the asynchronous operations window is proposed.
+func (c *AssociationConfig) asyncOps() bool {
+	return c.MaxOperationsInvoked > 1 || c.MaxOperationsPerformed > 1
+}
+
+func (u *UserIdentity) encode() []byte {
+	b := []byte{u.Type, 0}
+	if u.ResponseRequested {
+		b[1] = 1
+	}
+	b = appendField(b, u.PrimaryField)
+	return appendField(b, u.SecondaryField)
+}
+
+func decodeUserIdentity(v []byte) (*UserIdentity, error) {
+	if len(v) < 4 {
+		return nil, ErrPDU
+	}
+	u := &UserIdentity{Type: v[0], ResponseRequested: v[1] == 1}
+	var err error
+	v, u.PrimaryField, err = readField(v[2:])
+	if err != nil {
+		return nil, err
+	}
+	if len(v) > 0 {
+		_, u.SecondaryField, err = readField(v)
+	}
+	return u, err
+}
+
+// appendField appends a field prefixed by its 2 byte length.
+func appendField(b, field []byte) []byte {
+	l := make([]byte, 2)
+	binary.BigEndian.PutUint16(l, uint16(len(field)))
+	return append(append(b, l...), field...)
+}
+
+// readField returns the rest of v after the length prefixed field.
+func readField(v []byte) ([]byte, []byte, error) {
+	if len(v) < 2 {
+		return nil, nil, ErrPDU
+	}
+	l := int(binary.BigEndian.Uint16(v))
+	if len(v) < 2+l {
+		return nil, nil, ErrPDU
+	}
+	return v[2+l:], v[2 : 2+l], nil
+}
@@ -0,0 +1,781 @@
+package dimse
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// listen starts s on a local port and returns its address.
+func listen(t *testing.T, s *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { l.Close() })
+	return l.Addr().String()
+}
+
+func TestEcho(t *testing.T) {
+	addr := listen(t, &Server{AETitle: "SCP"})
+	err := Echo(addr, "SCU", "SCP")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = Echo(addr, "SCU", "OTHER")
+	if err != ErrRejected {
+		t.Errorf("Expected ErrRejected, got %v", err)
+	}
+}
+
+func TestContextWithoutTransferSyntax(t *testing.T) {
+	addr := listen(t, &Server{AETitle: "SCP"})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rq := associate{
+		CalledAE:               "SCP",
+		CallingAE:              "SCU",
+		MaxPDULength:           DefaultMaxPDULength,
+		ImplementationClassUID: dcmdump.ImplementationClassUID,
+		Contexts: []PresentationContext{
+			{ID: 1, AbstractSyntax: sopclass.VerificationSOPClass},
+			{ID: 3, AbstractSyntax: sopclass.VerificationSOPClass, TransferSyntaxes: []string{ts.ImplicitVRLittleEndian}},
+		},
+	}
+	err = writePDU(conn, pduAssociateRQ, rq.encode(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pduType, data, err := readPDU(conn)
+	if err != nil || pduType != pduAssociateAC {
+		t.Fatalf("Expected an A-ASSOCIATE-AC, got %d, %v", pduType, err)
+	}
+	ac, err := decodeAssociate(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(ac.Contexts) != 2 || ac.Contexts[0].Result != ResultProviderRejection || ac.Contexts[1].Result != ResultAcceptance {
+		t.Errorf("Wrong presentation contexts: %+v", ac.Contexts)
+	}
+}
+
+func TestCommandEncoding(t *testing.T) {
+	c := &Command{
+		CommandField:              CMoveRSP,
+		MessageIDBeingRespondedTo: 7,
+		AffectedSOPClassUID:       "1.2.3",
+		Status:                    StatusPending,
+		Remaining:                 2,
+		Completed:                 1,
+		HasDataSet:                false,
+	}
+	d, err := decodeCommand(c.encode())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(c, d) {
+		t.Errorf("Expected %+v, got %+v", c, d)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStoreServer("SCP", dir)
+	addr := listen(t, &s.Server)
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00080016", "UI", "1.2.840.10008.5.1.4.1.1.7"},
+		{"00080018", "UI", "1.2.3.4"},
+		{"00100010", "PN", "Doe^John"},
+		{"0020000D", "UI", "1.2.3"},
+		{"0020000E", "UI", "1.2.3.1"},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := Store(addr, "SCU", "SCP", df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	path := filepath.Join(dir, "1.2.3", "1.2.3.1", "1.2.3.4.dcm")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	p := dcmdump.NewParser(bytes.NewReader(b[132:]), 132, true, []string{})
+	p.StopAt = ""
+	stored := &dcmdump.DicomFile{}
+	stored.Elements, err = p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	de, err := stored.LookupElement("00100010")
+	if err != nil || de.Strings()[0] != "Doe^John" {
+		t.Errorf("Unexpected PatientName %v, %v", de, err)
+	}
+	de, err = stored.LookupElement("00020003")
+	if err != nil || de.Strings()[0] != "1.2.3.4" {
+		t.Errorf("Unexpected MediaStorageSOPInstanceUID %v, %v", de, err)
+	}
+
+	// UIDs naming other directories are replaced
+	df.SetElement("00080018", "UI", "1.2.3.5")
+	df.SetElement("0020000D", "UI", "..")
+	df.SetElement("0020000E", "UI", "..")
+	err = Store(addr, "SCU", "SCP", df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unknown", "unknown", "1.2.3.5.dcm")); err != nil {
+		t.Errorf("File with .. UIDs not stored under unknown: %s", err)
+	}
+
+	df.SetElement("00080016", "UI", "1.2.3.999")
+	err = Store(addr, "SCU", "SCP", df)
+	if err != ErrRejected && err != ErrNoContext {
+		t.Errorf("Expected unsupported SOP class error, got %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	var received *dcmdump.DicomFile
+	s := &Server{
+		AETitle:    "SCP",
+		SOPClasses: []string{sopclass.StudyRootQRIMFind},
+		Handle: func(a *Association, m *Message) error {
+			var err error
+			received, err = responseFile(a, m)
+			if err != nil {
+				return err
+			}
+			for _, name := range []string{"Doe^John", "Doe^Jane"} {
+				df := &dcmdump.DicomFile{}
+				df.SetElement("00080052", "CS", StudyLevel)
+				df.SetElement("00100010", "PN", name)
+				var buf bytes.Buffer
+				df.WriteDataset(&buf, a.TransferSyntax(m.ContextID))
+				err = a.Send(m.ContextID, &Command{
+					CommandField:              CFindRSP,
+					MessageIDBeingRespondedTo: m.Command.MessageID,
+					AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+					Status:                    StatusPending,
+				}, buf.Bytes())
+				if err != nil {
+					return err
+				}
+			}
+			return a.Send(m.ContextID, &Command{
+				CommandField:              CFindRSP,
+				MessageIDBeingRespondedTo: m.Command.MessageID,
+				AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+				Status:                    StatusSuccess,
+			}, nil)
+		},
+	}
+	addr := listen(t, s)
+	names := []string{}
+	q := Query{
+		PatientName: "DOE^*",
+		StudyDate:   Range{From: "20200101"},
+		Keys:        map[string]string{"ReferringPhysicianName": ""},
+	}
+	err := FindStudies(addr, "SCU", "SCP", q, func(df *dcmdump.DicomFile) error {
+		de, err := df.LookupElement("00100010")
+		if err != nil {
+			return err
+		}
+		names = append(names, de.Strings()[0])
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(names, []string{"Doe^John", "Doe^Jane"}) {
+		t.Errorf("Unexpected matches %v", names)
+	}
+	for tagStr, expected := range map[string]string{
+		"00080052": "STUDY",
+		"00100010": "DOE^*",
+		"00080020": "20200101-",
+		"0020000D": "",
+		"00080090": "",
+	} {
+		de, err := received.LookupElement(tagStr)
+		if err != nil {
+			t.Errorf("Missing key %s", tagStr)
+			continue
+		}
+		if v := de.Strings(); len(v) > 0 && v[0] != expected || len(v) == 0 && expected != "" {
+			t.Errorf("Expected %s %q, got %q", tagStr, expected, v)
+		}
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	df.SetElement("00080016", "UI", "1.2.840.10008.5.1.4.1.1.7")
+	df.SetElement("00080018", "UI", "1.2.3.4")
+	df.SetElement("0020000D", "UI", "1.2.3")
+	destinations := map[string]string{}
+	s := &Server{
+		AETitle:    "SCP",
+		SOPClasses: append([]string{sopclass.StudyRootQRIMGet, sopclass.StudyRootQRIMMove}, StorageSOPClasses...),
+		Handle: func(a *Association, m *Message) error {
+			identifier, err := responseFile(a, m)
+			if err != nil {
+				return err
+			}
+			de, err := identifier.LookupElement("0020000D")
+			if err != nil || de.Strings()[0] != "1.2.3" {
+				return ErrPDU
+			}
+			rsp := &Command{
+				CommandField:              m.Command.CommandField | 0x8000,
+				MessageIDBeingRespondedTo: m.Command.MessageID,
+				AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+				Status:                    StatusPending,
+				Remaining:                 1,
+			}
+			err = a.Send(m.ContextID, rsp, nil)
+			if err != nil {
+				return err
+			}
+			if m.Command.CommandField == CGetRQ {
+				err = a.Store(df)
+			} else {
+				err = Store(destinations[m.Command.MoveDestination], "SCP", m.Command.MoveDestination, df)
+			}
+			if err != nil {
+				return err
+			}
+			rsp.Status, rsp.Remaining, rsp.Completed = StatusSuccess, 0, 1
+			return a.Send(m.ContextID, rsp, nil)
+		},
+	}
+	addr := listen(t, s)
+	q := Query{StudyInstanceUID: "1.2.3"}
+
+	var stored []string
+	var progress []Progress
+	store := func(df *dcmdump.DicomFile) error {
+		de, err := df.LookupElement("00080018")
+		if err != nil {
+			return err
+		}
+		stored = append(stored, de.Strings()[0])
+		return nil
+	}
+	err := Get(addr, "SCU", "SCP", StudyLevel, q, store, func(p Progress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []Progress{{Remaining: 1}, {Completed: 1}}
+	if !reflect.DeepEqual(stored, []string{"1.2.3.4"}) || !reflect.DeepEqual(progress, expected) {
+		t.Errorf("Unexpected C-GET result %v %v", stored, progress)
+	}
+
+	// Reserve a port for the embedded store SCP
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destinations["SCU"] = l.Addr().String()
+	l.Close()
+	stored, progress = nil, nil
+	err = MoveStore(addr, destinations["SCU"], "SCU", "SCP", StudyLevel, q, store, func(p Progress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(stored, []string{"1.2.3.4"}) || !reflect.DeepEqual(progress, expected) {
+		t.Errorf("Unexpected C-MOVE result %v %v", stored, progress)
+	}
+}
+
+func TestWorklist(t *testing.T) {
+	items := []*dcmdump.DicomFile{}
+	for _, w := range []struct{ name, modality, date string }{
+		{"Doe^John", "CT", "20200102"},
+		{"Doe^Jane", "MR", "20200103"},
+		{"Roe^Richard", "MR", "20191231"},
+	} {
+		step := []dcmdump.DataElement{}
+		for _, e := range []struct{ tag, vr, value string }{
+			{"00080060", "CS", w.modality},
+			{"00400001", "AE", "SCU"},
+			{"00400002", "DA", w.date},
+			{"00400009", "SH", "SPS" + w.date},
+		} {
+			de, err := dcmdump.NewDataElement(e.tag, e.vr, e.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step = append(step, de)
+		}
+		df := &dcmdump.DicomFile{}
+		df.SetElement("00100010", "PN", w.name)
+		df.SetElement("00101010", "AS", "040Y")
+		df.SetElement("00400100", "SQ", []dcmdump.Item{{Elements: step}})
+		items = append(items, df)
+	}
+	s := NewWorklistServer("SCP", items)
+	addr := listen(t, &s.Server)
+	var matches []*dcmdump.DicomFile
+	q := WorklistQuery{
+		Modality:                        "MR",
+		ScheduledStationAETitle:         "SCU",
+		ScheduledProcedureStepStartDate: Range{From: "20200101"},
+	}
+	err := FindWorklist(addr, "SCU", "SCP", q, func(df *dcmdump.DicomFile) error {
+		matches = append(matches, df)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+	de, err := matches[0].LookupElement("00100010")
+	if err != nil || de.Strings()[0] != "Doe^Jane" {
+		t.Errorf("Unexpected PatientName %v, %v", de, err)
+	}
+	if _, err = matches[0].LookupElement("00100020"); err != nil {
+		t.Errorf("Missing return key PatientID")
+	}
+	if _, err = matches[0].LookupElement("00101010"); err == nil {
+		t.Errorf("Unexpected PatientAge, not requested")
+	}
+	de, err = matches[0].LookupElement("00400100")
+	if err != nil || len(de.Items) != 1 {
+		t.Fatalf("Unexpected ScheduledProcedureStepSequence %v, %v", de, err)
+	}
+	step := &dcmdump.DicomFile{Elements: de.Items[0].Elements}
+	de, err = step.LookupElement("00400009")
+	if err != nil || de.Strings()[0] != "SPS20200103" {
+		t.Errorf("Unexpected ScheduledProcedureStepID %v, %v", de, err)
+	}
+
+	s.OnFind = func(a *Association, identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+		return nil, ErrPDU
+	}
+	err = FindWorklist(addr, "SCU", "SCP", WorklistQuery{}, func(df *dcmdump.DicomFile) error { return nil })
+	if se, ok := err.(*StatusError); !ok || se.Status != StatusProcessingFailure {
+		t.Errorf("Expected processing failure, got %v", err)
+	}
+}
+
+func TestPerformedProcedureStep(t *testing.T) {
+	s := NewMPPSServer("SCP")
+	created := ""
+	s.OnCreate = func(a *Association, sopInstance string, df *dcmdump.DicomFile) error {
+		created = sopInstance
+		return nil
+	}
+	addr := listen(t, &s.Server)
+	df := &dcmdump.DicomFile{}
+	df.SetElement("00100010", "PN", "Doe^John")
+	df.SetElement("00400252", "CS", StepInProgress)
+	df.SetElement("00400253", "SH", "PPS1")
+	uid, err := CreatePerformedProcedureStep(addr, "SCU", "SCP", df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if uid == "" || created != uid {
+		t.Errorf("Unexpected step UID %q, created %q", uid, created)
+	}
+
+	modifications := &dcmdump.DicomFile{}
+	modifications.SetElement("00400251", "TM", "101500")
+	modifications.SetElement("00400252", "CS", StepCompleted)
+	err = SetPerformedProcedureStep(addr, "SCU", "SCP", uid, modifications)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	step, ok := s.Step(uid)
+	if !ok {
+		t.Fatalf("Missing step %s", uid)
+	}
+	for tagStr, expected := range map[string]string{
+		"00100010": "Doe^John",
+		"00400251": "101500",
+		"00400252": StepCompleted,
+		"00400253": "PPS1",
+	} {
+		if v := firstString(step, tagStr); v != expected {
+			t.Errorf("Expected %s %q, got %q", tagStr, expected, v)
+		}
+	}
+
+	for _, c := range []struct {
+		uid    string
+		status uint16
+	}{
+		{uid, StatusNotInProgress},
+		{"1.2.3", StatusNoSuchSOPInstance},
+	} {
+		err = SetPerformedProcedureStep(addr, "SCU", "SCP", c.uid, modifications)
+		if se, ok := err.(*StatusError); !ok || se.Status != c.status {
+			t.Errorf("Expected status 0x%04X, got %v", c.status, err)
+		}
+	}
+	_, err = CreatePerformedProcedureStep(addr, "SCU", "SCP", modifications)
+	if se, ok := err.(*StatusError); !ok || se.Status != StatusInvalidAttributeValue {
+		t.Errorf("Expected invalid attribute value, got %v", err)
+	}
+}
+
+func TestCommit(t *testing.T) {
+	s := NewCommitmentServer("SCP", func(a *Association, ref SOPReference) uint16 {
+		if ref.SOPInstanceUID == "1.2.3.2" {
+			return FailureNoSuchInstance
+		}
+		return 0
+	})
+	addr := listen(t, &s.Server)
+	refs := []SOPReference{
+		{SOPClassUID: "1.2.840.10008.5.1.4.1.1.7", SOPInstanceUID: "1.2.3.1"},
+		{SOPClassUID: "1.2.840.10008.5.1.4.1.1.7", SOPInstanceUID: "1.2.3.2"},
+	}
+	c, err := Commit(addr, "SCU", "SCP", refs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	failed := refs[1]
+	failed.FailureReason = FailureNoSuchInstance
+	if c.TransactionUID == "" || !reflect.DeepEqual(c.Committed, refs[:1]) || !reflect.DeepEqual(c.Failed, []SOPReference{failed}) {
+		t.Errorf("Unexpected commitment %+v", c)
+	}
+}
+
+func TestAssociationConfig(t *testing.T) {
+	var identity *UserIdentity
+	s := &Server{
+		AETitle:      "SCP",
+		MaxPDULength: 32768,
+		Authenticate: func(id *UserIdentity) ([]byte, error) {
+			identity = id
+			if id == nil || string(id.SecondaryField) != "secret" {
+				return nil, ErrRejected
+			}
+			return []byte("token"), nil
+		},
+	}
+	addr := listen(t, s)
+	contexts := []PresentationContext{{AbstractSyntax: sopclass.VerificationSOPClass}}
+	config := AssociationConfig{
+		CallingAE:            "SCU",
+		CalledAE:             "SCP",
+		MaxPDULength:         65536,
+		MaxOperationsInvoked: 4,
+		UserIdentity:         UsernameIdentity("user", "secret"),
+	}
+	config.UserIdentity.ResponseRequested = true
+	a, err := DialConfig(addr, config, contexts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if a.maxPDU != 32768 || a.MaxOperationsInvoked != 1 || a.MaxOperationsPerformed != 1 {
+		t.Errorf("Unexpected negotiation %d %d %d", a.maxPDU, a.MaxOperationsInvoked, a.MaxOperationsPerformed)
+	}
+	if string(a.IdentityResponse) != "token" {
+		t.Errorf("Unexpected identity response %q", a.IdentityResponse)
+	}
+	if identity == nil || identity.Type != IdentityUsernamePasscode || string(identity.PrimaryField) != "user" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	err = a.Echo()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	a.Release()
+
+	config.UserIdentity = UsernameIdentity("user", "")
+	_, err = DialConfig(addr, config, contexts)
+	if err != ErrRejected {
+		t.Errorf("Expected ErrRejected, got %v", err)
+	}
+}
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 usable by
+// clients and servers.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+func TestTLS(t *testing.T) {
+	cert, pool := testCertificate(t)
+	addr := listen(t, &Server{AETitle: "SCP", TLSConfig: BCP195Config([]tls.Certificate{cert}, pool)})
+	contexts := []PresentationContext{{AbstractSyntax: sopclass.VerificationSOPClass}}
+	config := AssociationConfig{
+		CallingAE: "SCU",
+		CalledAE:  "SCP",
+		TLSConfig: BCP195Config([]tls.Certificate{cert}, pool),
+	}
+	a, err := DialConfig(addr, config, contexts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = a.Echo()
+	if err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	a.Release()
+
+	// Clients without a certificate are refused
+	config.TLSConfig = BCP195Config(nil, pool)
+	_, err = DialConfig(addr, config, contexts)
+	if err == nil {
+		t.Errorf("Expected error without client certificate")
+	}
+	_, err = Dial(addr, "SCU", "SCP", contexts)
+	if err == nil {
+		t.Errorf("Expected error without TLS")
+	}
+}
+
+// lockedBuffer is written by the server goroutines while the test reads it.
+type lockedBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestTrace(t *testing.T) {
+	var log lockedBuffer
+	var capture bytes.Buffer
+	addr := listen(t, &Server{AETitle: "SCP", Trace: LogPDUs(&log)})
+	config := AssociationConfig{CallingAE: "SCU", CalledAE: "SCP", Trace: CapturePDUs(&capture)}
+	a, err := DialConfig(addr, config, []PresentationContext{{AbstractSyntax: sopclass.VerificationSOPClass}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = a.Echo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	a.Release()
+
+	names := []string{}
+	var commands []uint16
+	err = ReadCapture(&capture, func(p *PDU) error {
+		name := p.Name()
+		if p.Sent {
+			name = ">" + name
+		}
+		names = append(names, name)
+		for _, c := range p.Commands() {
+			commands = append(commands, c.CommandField)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []string{">A-ASSOCIATE-RQ", "A-ASSOCIATE-AC", ">P-DATA-TF", "P-DATA-TF", ">A-RELEASE-RQ", "A-RELEASE-RP"}
+	if !reflect.DeepEqual(names, expected) || !reflect.DeepEqual(commands, []uint16{CEchoRQ, CEchoRSP}) {
+		t.Errorf("Unexpected capture %v %v", names, commands)
+	}
+	logged := log.String()
+	for _, s := range []string{"< A-ASSOCIATE-RQ", "> A-ASSOCIATE-AC", "C-ECHO-RQ", "C-ECHO-RSP", "00000000  "} {
+		if !strings.Contains(logged, s) {
+			t.Errorf("Missing %q in log:\n%s", s, logged)
+		}
+	}
+}
+
+func TestQueryServer(t *testing.T) {
+	db := Files{}
+	for _, series := range []string{"1.2.3.1", "1.2.3.2"} {
+		df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+		for _, e := range []struct{ tag, vr, value string }{
+			{"00080016", "UI", "1.2.840.10008.5.1.4.1.1.7"},
+			{"00080018", "UI", series + ".1"},
+			{"00080020", "DA", "20200102"},
+			{"00080060", "CS", "OT"},
+			{"00100010", "PN", "Doe^John"},
+			{"00100020", "LO", "P1"},
+			{"0020000D", "UI", "1.2.3"},
+			{"0020000E", "UI", series},
+		} {
+			err := df.SetElement(e.tag, e.vr, e.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		db = append(db, df)
+	}
+	s := NewQueryServer("SCP", db)
+	addr := listen(t, &s.Server)
+
+	count := func(level string, q Query) int {
+		n := 0
+		err := find(addr, "SCU", "SCP", sopclass.StudyRootQRIMFind, level, q, func(df *dcmdump.DicomFile) error {
+			if v := firstString(df, "00080052"); v != level {
+				t.Errorf("Unexpected level %q", v)
+			}
+			if _, err := df.LookupElement("00080016"); err == nil && level != ImageLevel {
+				t.Errorf("Unexpected SOPClassUID at level %s", level)
+			}
+			n++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return n
+	}
+	for _, c := range []struct {
+		level    string
+		q        Query
+		expected int
+	}{
+		{StudyLevel, Query{PatientName: "doe*"}, 1},
+		{StudyLevel, Query{StudyDate: Range{From: "20200103"}}, 0},
+		{SeriesLevel, Query{StudyInstanceUID: "1.2.3"}, 2},
+		{ImageLevel, Query{StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.2"}, 1},
+	} {
+		if n := count(c.level, c.q); n != c.expected {
+			t.Errorf("Expected %d %s matches for %+v, got %d", c.expected, c.level, c.q, n)
+		}
+	}
+
+	var stored []string
+	store := func(df *dcmdump.DicomFile) error {
+		stored = append(stored, firstString(df, "00080018"))
+		return nil
+	}
+	err := Get(addr, "SCU", "SCP", StudyLevel, Query{StudyInstanceUID: "1.2.3"}, store, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(stored, []string{"1.2.3.1.1", "1.2.3.2.1"}) {
+		t.Errorf("Unexpected C-GET instances %v", stored)
+	}
+
+	dest := NewStoreServer("DEST", "")
+	var mu sync.Mutex
+	moved := []string{}
+	dest.OnStore = func(a *Association, df *dcmdump.DicomFile) error {
+		mu.Lock()
+		defer mu.Unlock()
+		moved = append(moved, firstString(df, "00080018"))
+		return nil
+	}
+	s.Destinations["DEST"] = listen(t, &dest.Server)
+	var progress []Progress
+	err = Move(addr, "SCU", "SCP", "DEST", SeriesLevel, Query{StudyInstanceUID: "1.2.3", SeriesInstanceUID: "1.2.3.1"}, func(p Progress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(moved, []string{"1.2.3.1.1"}) || !reflect.DeepEqual(progress, []Progress{{Completed: 1}}) {
+		t.Errorf("Unexpected C-MOVE result %v %v", moved, progress)
+	}
+	err = Move(addr, "SCU", "SCP", "OTHER", StudyLevel, Query{StudyInstanceUID: "1.2.3"}, nil)
+	if se, ok := err.(*StatusError); !ok || se.Status != StatusMoveDestinationUnknown {
+		t.Errorf("Expected unknown destination, got %v", err)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	addr := listen(t, &Server{
+		AETitle:    "SCP",
+		SOPClasses: []string{sopclass.ModalityWorklistIMFind},
+		Handle: func(a *Association, m *Message) error {
+			// Never answers, the requester gives up
+			<-release
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := DialContext(ctx, addr, AssociationConfig{CallingAE: "SCU", CalledAE: "SCP"}, nil)
+	if err == nil {
+		t.Fatal("Expected an error for a canceled context")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	a, err := DialContext(ctx, addr, AssociationConfig{CallingAE: "SCU", CalledAE: "SCP"}, []PresentationContext{{AbstractSyntax: sopclass.ModalityWorklistIMFind}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	err = a.Send(1, &Command{CommandField: CFindRQ, MessageID: a.NextMessageID(), AffectedSOPClassUID: sopclass.ModalityWorklistIMFind}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan error)
+	go func() {
+		_, err := a.Receive()
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("Expected Receive to fail once the context is done")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Receive still blocked after the context is done")
+	}
+}
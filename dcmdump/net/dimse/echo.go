@@ -0,0 +1,67 @@
+// Package dimse implements the DICOM upper layer protocol and DIMSE services
+// to talk to remote application entities.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part07/PS3.7.html
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part08/PS3.8.html
+package dimse
+
+import (
+	"fmt"
+
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// StatusError is returned for responses with a failure status.
+type StatusError struct {
+	Status  uint16
+	Comment string
+}
+
+func (e *StatusError) Error() string {
+	if e.Comment != "" {
+		return fmt.Sprintf("DIMSE status 0x%04X: %s", e.Status, e.Comment)
+	}
+	return fmt.Sprintf("DIMSE status 0x%04X", e.Status)
+}
+
+// Echo verifies the connectivity with the application entity calledAE at
+// addr with a C-ECHO.
+func Echo(addr, callingAE, calledAE string) error {
+	a, err := Dial(addr, callingAE, calledAE, []PresentationContext{{AbstractSyntax: sopclass.VerificationSOPClass}})
+	if err != nil {
+		return err
+	}
+	err = a.Echo()
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// Echo sends a C-ECHO request on the association and waits for its response.
+func (a *Association) Echo() error {
+	pc, err := a.Context(sopclass.VerificationSOPClass, "")
+	if err != nil {
+		return err
+	}
+	id := a.NextMessageID()
+	err = a.Send(pc.ID, &Command{
+		CommandField:        CEchoRQ,
+		MessageID:           id,
+		AffectedSOPClassUID: sopclass.VerificationSOPClass,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	m, err := a.Receive()
+	if err != nil {
+		return err
+	}
+	if m.Command.CommandField != CEchoRSP || m.Command.MessageIDBeingRespondedTo != id {
+		return ErrPDU
+	}
+	if m.Command.Status != StatusSuccess {
+		return &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+	}
+	return nil
+}
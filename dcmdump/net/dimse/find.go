@@ -0,0 +1,307 @@
+package dimse
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// ErrUnknownKey is returned for query keys that are not in the dictionary.
+var ErrUnknownKey = errors.New("Unknown query key")
+
+// Query/Retrieve levels
+const (
+	PatientLevel = "PATIENT"
+	StudyLevel   = "STUDY"
+	SeriesLevel  = "SERIES"
+	ImageLevel   = "IMAGE"
+)
+
+// Range - Range matching of dates and times, either end can be empty.
+type Range struct {
+	From, To string
+}
+
+func (r Range) String() string {
+	if r.From == "" && r.To == "" {
+		return ""
+	}
+	if r.From == r.To {
+		return r.From
+	}
+	return r.From + "-" + r.To
+}
+
+// Query - Matching keys of a C-FIND, empty keys match all values.
+// Wildcards * and ? can be used on string keys, UID keys can list several
+// values separated with backslashes.
+type Query struct {
+	PatientName      string
+	PatientID        string
+	PatientBirthDate Range
+
+	StudyInstanceUID  string
+	StudyID           string
+	StudyDate         Range
+	StudyTime         Range
+	AccessionNumber   string
+	StudyDescription  string
+	ModalitiesInStudy string
+
+	SeriesInstanceUID string
+	SeriesNumber      string
+	Modality          string
+
+	SOPInstanceUID string
+	InstanceNumber string
+
+	// Keys adds matching or return keys by tag or keyword, their VR is
+	// taken from the dictionary.
+	Keys map[string]string
+}
+
+type queryKey struct {
+	tag, vr string
+}
+
+// levelKeys are the keys returned at each level.
+var levelKeys = map[string][]queryKey{
+	PatientLevel: {
+		{"00100010", "PN"},
+		{"00100020", "LO"},
+		{"00100030", "DA"},
+		{"00100040", "CS"},
+		{"00201200", "IS"}, // NumberOfPatientRelatedStudies
+	},
+	StudyLevel: {
+		{"00080020", "DA"},
+		{"00080030", "TM"},
+		{"00080050", "SH"},
+		{"00080061", "CS"},
+		{"00081030", "LO"},
+		{"00100010", "PN"},
+		{"00100020", "LO"},
+		{"00100030", "DA"},
+		{"00100040", "CS"},
+		{"0020000D", "UI"},
+		{"00200010", "SH"},
+		{"00201206", "IS"}, // NumberOfStudyRelatedSeries
+		{"00201208", "IS"}, // NumberOfStudyRelatedInstances
+	},
+	SeriesLevel: {
+		{"00080060", "CS"},
+		{"0008103E", "LO"},
+		{"0020000D", "UI"},
+		{"0020000E", "UI"},
+		{"00200011", "IS"},
+		{"00201209", "IS"}, // NumberOfSeriesRelatedInstances
+	},
+	ImageLevel: {
+		{"00080016", "UI"},
+		{"00080018", "UI"},
+		{"0020000D", "UI"},
+		{"0020000E", "UI"},
+		{"00200013", "IS"},
+	},
+}
+
+func (q *Query) values() map[string]string {
+	return map[string]string{
+		"00080018": q.SOPInstanceUID,
+		"00080020": q.StudyDate.String(),
+		"00080030": q.StudyTime.String(),
+		"00080050": q.AccessionNumber,
+		"00080060": q.Modality,
+		"00080061": q.ModalitiesInStudy,
+		"00081030": q.StudyDescription,
+		"00100010": q.PatientName,
+		"00100020": q.PatientID,
+		"00100030": q.PatientBirthDate.String(),
+		"0020000D": q.StudyInstanceUID,
+		"0020000E": q.SeriesInstanceUID,
+		"00200010": q.StudyID,
+		"00200011": q.SeriesNumber,
+		"00200013": q.InstanceNumber,
+	}
+}
+
+// Identifier returns the C-FIND identifier of the query at level with the
+// return keys of the level.
+// Keys of upper levels set in the query are included, as required for
+// hierarchical queries on series and instances.
+func (q *Query) Identifier(level string) (*dcmdump.DicomFile, error) {
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	err := df.SetElement("00080052", "CS", level)
+	if err != nil {
+		return nil, err
+	}
+	values := q.values()
+	for _, k := range levelKeys[level] {
+		err = df.SetElement(k.tag, k.vr, values[k.tag])
+		if err != nil {
+			return nil, err
+		}
+		delete(values, k.tag)
+	}
+	for _, k := range levelKeys[StudyLevel] {
+		if v := values[k.tag]; v != "" {
+			err = df.SetElement(k.tag, k.vr, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, k := range levelKeys[SeriesLevel] {
+		if v := values[k.tag]; v != "" && level == ImageLevel {
+			err = df.SetElement(k.tag, k.vr, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	for k, v := range q.Keys {
+		info, ok := tag.ByKeyword(k)
+		if !ok {
+			info, ok = tagInfo(k)
+		}
+		if !ok || info.VR == "" {
+			return nil, ErrUnknownKey
+		}
+		err = df.SetElement(info.TagStr(), info.VR, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return df, nil
+}
+
+// FindPatients queries the application entity calledAE at addr with the
+// Patient Root information model, fn is called with each match.
+func FindPatients(addr, callingAE, calledAE string, q Query, fn func(*dcmdump.DicomFile) error) error {
+	return find(addr, callingAE, calledAE, sopclass.PatientRootQRIMFind, PatientLevel, q, fn)
+}
+
+// FindStudies queries the application entity calledAE at addr for studies
+// with the Study Root information model, fn is called with each match.
+func FindStudies(addr, callingAE, calledAE string, q Query, fn func(*dcmdump.DicomFile) error) error {
+	return find(addr, callingAE, calledAE, sopclass.StudyRootQRIMFind, StudyLevel, q, fn)
+}
+
+// FindSeries queries the application entity calledAE at addr for series
+// with the Study Root information model, fn is called with each match.
+func FindSeries(addr, callingAE, calledAE string, q Query, fn func(*dcmdump.DicomFile) error) error {
+	return find(addr, callingAE, calledAE, sopclass.StudyRootQRIMFind, SeriesLevel, q, fn)
+}
+
+// FindInstances queries the application entity calledAE at addr for
+// instances with the Study Root information model, fn is called with each
+// match.
+func FindInstances(addr, callingAE, calledAE string, q Query, fn func(*dcmdump.DicomFile) error) error {
+	return find(addr, callingAE, calledAE, sopclass.StudyRootQRIMFind, ImageLevel, q, fn)
+}
+
+func find(addr, callingAE, calledAE, sopClass, level string, q Query, fn func(*dcmdump.DicomFile) error) error {
+	identifier, err := q.Identifier(level)
+	if err != nil {
+		return err
+	}
+	return findIdentifier(addr, callingAE, calledAE, sopClass, identifier, fn)
+}
+
+// findIdentifier sends identifier on a new association with calledAE.
+func findIdentifier(addr, callingAE, calledAE, sopClass string, identifier *dcmdump.DicomFile, fn func(*dcmdump.DicomFile) error) error {
+	a, err := Dial(addr, callingAE, calledAE, []PresentationContext{{AbstractSyntax: sopClass}})
+	if err != nil {
+		return err
+	}
+	err = a.Find(sopClass, identifier, fn)
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// Find sends a C-FIND request with identifier on the association and calls
+// fn with each match as it is received.
+// When fn returns an error the query is cancelled and the error returned.
+func (a *Association) Find(sopClass string, identifier *dcmdump.DicomFile, fn func(*dcmdump.DicomFile) error) error {
+	pc, err := a.Context(sopClass, "")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = identifier.WriteDataset(&buf, pc.TransferSyntaxes[0])
+	if err != nil {
+		return err
+	}
+	id := a.NextMessageID()
+	err = a.Send(pc.ID, &Command{
+		CommandField:        CFindRQ,
+		MessageID:           id,
+		AffectedSOPClassUID: sopClass,
+	}, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	for {
+		m, err := a.Receive()
+		if err != nil {
+			return err
+		}
+		if m.Command.CommandField != CFindRSP || m.Command.MessageIDBeingRespondedTo != id {
+			return ErrPDU
+		}
+		switch m.Command.Status {
+		case StatusPending, StatusPendingWarning:
+			if fnErr != nil || m.Data == nil {
+				continue
+			}
+			df, err := responseFile(a, m)
+			if err != nil {
+				return err
+			}
+			fnErr = fn(df)
+			if fnErr != nil {
+				err = a.Send(pc.ID, &Command{CommandField: CCancelRQ, MessageIDBeingRespondedTo: id}, nil)
+				if err != nil {
+					return err
+				}
+			}
+		case StatusSuccess:
+			return fnErr
+		case StatusCancel:
+			if fnErr != nil {
+				return fnErr
+			}
+			return &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+		default:
+			return &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+		}
+	}
+}
+
+// responseFile parses the data set of a response.
+func responseFile(a *Association, m *Message) (*dcmdump.DicomFile, error) {
+	transferSyntax := a.TransferSyntax(m.ContextID)
+	elements, err := dcmdump.ParseDataset(bytes.NewReader(m.Data), transferSyntax)
+	if err != nil {
+		return nil, err
+	}
+	return &dcmdump.DicomFile{Elements: elements, TransferSyntax: transferSyntax}, nil
+}
+
+// tagInfo looks up a tag string in the dictionary.
+func tagInfo(tagStr string) (tag.Info, bool) {
+	t, err := strconv.ParseUint(tagStr, 16, 32)
+	if err != nil || len(tagStr) != 8 {
+		return tag.Info{}, false
+	}
+	return tag.Find(uint16(t>>16), uint16(t))
+}
@@ -0,0 +1,194 @@
+package dimse
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// PerformedProcedureStepStatus (0040,0252) values
+const (
+	StepInProgress   = "IN PROGRESS"
+	StepCompleted    = "COMPLETED"
+	StepDiscontinued = "DISCONTINUED"
+)
+
+// StatusNotInProgress - Failed, the performed procedure step may no longer
+// be updated.
+const StatusNotInProgress = 0xC310
+
+// CreatePerformedProcedureStep creates a Modality Performed Procedure Step
+// with attributes at the application entity calledAE at addr, as done by a
+// modality when the acquisition starts.
+// The attributes have to include the PerformedProcedureStepStatus set to
+// StepInProgress, the UID of the created step is returned.
+func CreatePerformedProcedureStep(addr, callingAE, calledAE string, attributes *dcmdump.DicomFile) (string, error) {
+	var sopInstance string
+	err := withMPPS(addr, callingAE, calledAE, func(a *Association) error {
+		var err error
+		sopInstance, err = a.NCreate(sopclass.ModalityPerformedProcedureStep, dcmdump.NewUID(), attributes)
+		return err
+	})
+	return sopInstance, err
+}
+
+// SetPerformedProcedureStep updates the step sopInstance with the
+// modifications, the step is completed or discontinued by setting its
+// PerformedProcedureStepStatus.
+func SetPerformedProcedureStep(addr, callingAE, calledAE, sopInstance string, modifications *dcmdump.DicomFile) error {
+	return withMPPS(addr, callingAE, calledAE, func(a *Association) error {
+		return a.NSet(sopclass.ModalityPerformedProcedureStep, sopInstance, modifications)
+	})
+}
+
+func withMPPS(addr, callingAE, calledAE string, fn func(a *Association) error) error {
+	a, err := Dial(addr, callingAE, calledAE, []PresentationContext{{AbstractSyntax: sopclass.ModalityPerformedProcedureStep}})
+	if err != nil {
+		return err
+	}
+	err = fn(a)
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// MPPSServer - Modality Performed Procedure Step service class provider.
+// Steps are kept in memory, OnCreate and OnSet can forward them to a
+// backend.
+type MPPSServer struct {
+	Server
+	// OnCreate is called with a new step and OnSet with a step after applying
+	// the modifications of an N-SET, before they are kept. An error fails
+	// the request with a processing failure status.
+	OnCreate func(a *Association, sopInstance string, df *dcmdump.DicomFile) error
+	OnSet    func(a *Association, sopInstance string, df *dcmdump.DicomFile) error
+
+	mu    sync.Mutex
+	steps map[string]*dcmdump.DicomFile
+}
+
+// NewMPPSServer returns an MPPSServer.
+// OnCreate and OnSet can be set before serving.
+func NewMPPSServer(aeTitle string) *MPPSServer {
+	s := &MPPSServer{
+		Server: Server{AETitle: aeTitle, SOPClasses: []string{sopclass.ModalityPerformedProcedureStep}},
+		steps:  map[string]*dcmdump.DicomFile{},
+	}
+	s.Handle = s.handleMPPS
+	return s
+}
+
+// Step returns the attributes of the step sopInstance.
+func (s *MPPSServer) Step(sopInstance string) (*dcmdump.DicomFile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	df, ok := s.steps[sopInstance]
+	return df, ok
+}
+
+func (s *MPPSServer) handleMPPS(a *Association, m *Message) error {
+	rsp := &Command{
+		CommandField:              m.Command.CommandField | 0x8000,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+		AffectedSOPInstanceUID:    m.Command.AffectedSOPInstanceUID,
+	}
+	switch m.Command.CommandField {
+	case NCreateRQ:
+		if rsp.AffectedSOPInstanceUID == "" {
+			rsp.AffectedSOPInstanceUID = dcmdump.NewUID()
+		}
+		rsp.Status, rsp.ErrorComment = s.create(a, m, rsp.AffectedSOPInstanceUID)
+	case NSetRQ:
+		rsp.AffectedSOPClassUID = m.Command.RequestedSOPClassUID
+		rsp.AffectedSOPInstanceUID = m.Command.RequestedSOPInstanceUID
+		rsp.Status, rsp.ErrorComment = s.set(a, m, rsp.AffectedSOPInstanceUID)
+	default:
+		rsp.Status = StatusUnrecognizedOperation
+	}
+	return a.Send(m.ContextID, rsp, nil)
+}
+
+func (s *MPPSServer) create(a *Association, m *Message, sopInstance string) (uint16, string) {
+	if m.Command.AffectedSOPClassUID != sopclass.ModalityPerformedProcedureStep {
+		return StatusNoSuchSOPClass, ""
+	}
+	df, err := responseFile(a, m)
+	if err != nil {
+		return StatusCannotUnderstand, err.Error()
+	}
+	if status := stepStatus(df); status != StepInProgress {
+		return StatusInvalidAttributeValue, "PerformedProcedureStepStatus must be " + StepInProgress
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.steps[sopInstance]; ok {
+		return StatusDuplicateSOPInstance, ""
+	}
+	if s.OnCreate != nil {
+		err = s.OnCreate(a, sopInstance, df)
+		if err != nil {
+			return StatusProcessingFailure, err.Error()
+		}
+	}
+	s.steps[sopInstance] = df
+	return StatusSuccess, ""
+}
+
+func (s *MPPSServer) set(a *Association, m *Message, sopInstance string) (uint16, string) {
+	if m.Command.RequestedSOPClassUID != sopclass.ModalityPerformedProcedureStep {
+		return StatusNoSuchSOPClass, ""
+	}
+	modifications, err := responseFile(a, m)
+	if err != nil {
+		return StatusCannotUnderstand, err.Error()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	step, ok := s.steps[sopInstance]
+	if !ok {
+		return StatusNoSuchSOPInstance, ""
+	}
+	if stepStatus(step) != StepInProgress {
+		return StatusNotInProgress, ""
+	}
+	df := &dcmdump.DicomFile{
+		Elements:       mergeElements(step.Elements, modifications.Elements),
+		TransferSyntax: step.TransferSyntax,
+	}
+	switch stepStatus(df) {
+	case StepInProgress, StepCompleted, StepDiscontinued:
+	default:
+		return StatusInvalidAttributeValue, "Unknown PerformedProcedureStepStatus"
+	}
+	if s.OnSet != nil {
+		err = s.OnSet(a, sopInstance, df)
+		if err != nil {
+			return StatusProcessingFailure, err.Error()
+		}
+	}
+	s.steps[sopInstance] = df
+	return StatusSuccess, ""
+}
+
+func stepStatus(df *dcmdump.DicomFile) string {
+	return strings.TrimSpace(firstString(df, "00400252"))
+}
+
+// mergeElements returns the elements with the top level modifications
+// applied, ordered by tag.
+func mergeElements(elements, modifications []dcmdump.DataElement) []dcmdump.DataElement {
+	merged := append([]dcmdump.DataElement{}, modifications...)
+	for _, de := range elements {
+		if dcmdump.FindElement(modifications, de.TagStr) == nil {
+			merged = append(merged, de)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].TagStr < merged[j].TagStr })
+	return merged
+}
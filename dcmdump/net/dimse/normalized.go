@@ -0,0 +1,116 @@
+package dimse
+
+import (
+	"bytes"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// DIMSE-N status codes
+const (
+	StatusInvalidAttributeValue = 0x0106
+	StatusDuplicateSOPInstance  = 0x0111
+	StatusNoSuchSOPInstance     = 0x0112
+	StatusNoSuchSOPClass        = 0x0118
+	StatusNoSuchAction          = 0x0123
+)
+
+// NCreate sends an N-CREATE request for a sopClass instance with attributes
+// and waits for its response.
+// When sopInstance is empty the instance UID is assigned by the peer, the
+// UID of the created instance is returned.
+func (a *Association) NCreate(sopClass, sopInstance string, attributes *dcmdump.DicomFile) (string, error) {
+	m, err := a.normalized(&Command{
+		CommandField:           NCreateRQ,
+		AffectedSOPClassUID:    sopClass,
+		AffectedSOPInstanceUID: sopInstance,
+	}, sopClass, attributes)
+	if err != nil {
+		return "", err
+	}
+	if m.Command.AffectedSOPInstanceUID != "" {
+		sopInstance = m.Command.AffectedSOPInstanceUID
+	}
+	return sopInstance, nil
+}
+
+// NSet sends an N-SET request with the modifications of the sopInstance
+// attributes and waits for its response.
+func (a *Association) NSet(sopClass, sopInstance string, modifications *dcmdump.DicomFile) error {
+	_, err := a.normalized(&Command{
+		CommandField:            NSetRQ,
+		RequestedSOPClassUID:    sopClass,
+		RequestedSOPInstanceUID: sopInstance,
+	}, sopClass, modifications)
+	return err
+}
+
+// NAction sends an N-ACTION request of actionType on sopInstance with the
+// action information and waits for its response.
+func (a *Association) NAction(sopClass, sopInstance string, actionType uint16, info *dcmdump.DicomFile) error {
+	_, err := a.normalized(&Command{
+		CommandField:            NActionRQ,
+		RequestedSOPClassUID:    sopClass,
+		RequestedSOPInstanceUID: sopInstance,
+		ActionTypeID:            actionType,
+	}, sopClass, info)
+	return err
+}
+
+// NEventReport sends an N-EVENT-REPORT request of eventType on sopInstance
+// with the event information and waits for its response.
+func (a *Association) NEventReport(sopClass, sopInstance string, eventType uint16, info *dcmdump.DicomFile) error {
+	_, err := a.normalized(&Command{
+		CommandField:           NEventReportRQ,
+		AffectedSOPClassUID:    sopClass,
+		AffectedSOPInstanceUID: sopInstance,
+		EventTypeID:            eventType,
+	}, sopClass, info)
+	return err
+}
+
+// normalized sends a DIMSE-N request with the data set of df, if not nil,
+// and returns its response.
+func (a *Association) normalized(cmd *Command, sopClass string, df *dcmdump.DicomFile) (*Message, error) {
+	pc, err := a.Context(sopClass, "")
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if df != nil {
+		var buf bytes.Buffer
+		err = df.WriteDataset(&buf, pc.TransferSyntaxes[0])
+		if err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+	cmd.MessageID = a.NextMessageID()
+	err = a.Send(pc.ID, cmd, data)
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if m.Command.CommandField != cmd.CommandField|0x8000 || m.Command.MessageIDBeingRespondedTo != cmd.MessageID {
+		return nil, ErrPDU
+	}
+	if m.Command.Status != StatusSuccess && !normalizedWarning(m.Command.Status) {
+		return nil, &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+	}
+	return m, nil
+}
+
+// normalizedWarning reports whether status is one of the DIMSE-N warnings,
+// the request was still applied.
+func normalizedWarning(status uint16) bool {
+	switch status {
+	case 0x0001, // Requested optional attributes are not supported
+		0x0107, // Attribute list error
+		0x0116: // Attribute value out of range
+		return true
+	}
+	return status&0xF000 == StatusCoercionOfElement
+}
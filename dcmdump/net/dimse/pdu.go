@@ -0,0 +1,328 @@
+package dimse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+)
+
+// PDU types
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part08/sect_9.3.html
+const (
+	pduAssociateRQ = 0x01
+	pduAssociateAC = 0x02
+	pduAssociateRJ = 0x03
+	pduDataTF      = 0x04
+	pduReleaseRQ   = 0x05
+	pduReleaseRP   = 0x06
+	pduAbort       = 0x07
+)
+
+// Item types of the A-ASSOCIATE PDUs
+const (
+	itemApplicationContext = 0x10
+	itemPresentationRQ     = 0x20
+	itemPresentationAC     = 0x21
+	itemAbstractSyntax     = 0x30
+	itemTransferSyntax     = 0x40
+	itemUserInformation    = 0x50
+	itemMaxLength          = 0x51
+	itemImplementationUID  = 0x52
+	itemAsyncOps           = 0x53
+	itemRoleSelection      = 0x54
+	itemImplementationName = 0x55
+	itemUserIdentityRQ     = 0x58
+	itemUserIdentityAC     = 0x59
+)
+
+// ApplicationContextName - DICOM Application Context Name.
+const ApplicationContextName = "1.2.840.10008.3.1.1.1"
+
+// DefaultMaxPDULength is the maximum PDU length announced to peers.
+const DefaultMaxPDULength = 16384
+
+// maxPDUSize bounds the PDUs accepted from peers.
+const maxPDUSize = 64 << 20
+
+// ErrPDU is returned for malformed or unexpected PDUs.
+var ErrPDU = errors.New("Invalid PDU")
+
+// Presentation context results
+const (
+	ResultAcceptance                 = 0
+	ResultUserRejection              = 1
+	ResultProviderRejection          = 2
+	ResultAbstractSyntaxNotSupported = 3
+	ResultTransferSyntaxNotSupported = 4
+)
+
+// PresentationContext - Abstract syntax and transfer syntaxes negotiated for
+// an association.
+// Requests list the proposed TransferSyntaxes, acceptances the one chosen.
+type PresentationContext struct {
+	ID               byte
+	AbstractSyntax   string
+	TransferSyntaxes []string
+	Result           byte
+	// SCPRole proposes the requester as SCP of the abstract syntax, as
+	// needed to receive the C-STORE sub-operations of a C-GET.
+	SCPRole bool
+}
+
+// associate - A-ASSOCIATE-RQ and A-ASSOCIATE-AC PDU content.
+type associate struct {
+	CalledAE                  string
+	CallingAE                 string
+	Contexts                  []PresentationContext
+	MaxPDULength              uint32
+	ImplementationClassUID    string
+	ImplementationVersionName string
+	// scpRoles are the abstract syntaxes of role selection sub-items.
+	scpRoles map[string]bool
+	// asyncOps is set when the asynchronous operations window sub-item is
+	// present.
+	asyncOps               bool
+	MaxOperationsInvoked   uint16
+	MaxOperationsPerformed uint16
+	// identity of requests and identityResponse of acceptances, their
+	// sub-item is left out when nil.
+	identity         *UserIdentity
+	identityResponse []byte
+}
+
+func readPDU(r io.Reader) (byte, []byte, error) {
+	h := make([]byte, 6)
+	_, err := io.ReadFull(r, h)
+	if err != nil {
+		return 0, nil, err
+	}
+	l := binary.BigEndian.Uint32(h[2:])
+	if l > maxPDUSize {
+		return 0, nil, ErrPDU
+	}
+	data := make([]byte, l)
+	_, err = io.ReadFull(r, data)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return h[0], data, err
+}
+
+func writePDU(w io.Writer, pduType byte, data []byte) error {
+	b := make([]byte, 6, 6+len(data))
+	b[0] = pduType
+	binary.BigEndian.PutUint32(b[2:], uint32(len(data)))
+	_, err := w.Write(append(b, data...))
+	return err
+}
+
+func item(itemType byte, data []byte) []byte {
+	b := []byte{itemType, 0, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], uint16(len(data)))
+	return append(b, data...)
+}
+
+func aeTitle(ae string) []byte {
+	b := []byte(ae + strings.Repeat(" ", 16))
+	return b[:16]
+}
+
+func (a *associate) encode(ac bool) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0, 1, 0, 0})
+	b.Write(aeTitle(a.CalledAE))
+	b.Write(aeTitle(a.CallingAE))
+	b.Write(make([]byte, 32))
+	b.Write(item(itemApplicationContext, []byte(ApplicationContextName)))
+	for _, pc := range a.Contexts {
+		itemType := byte(itemPresentationRQ)
+		content := []byte{pc.ID, 0, 0, 0}
+		if ac {
+			itemType = itemPresentationAC
+			content[2] = pc.Result
+		} else {
+			content = append(content, item(itemAbstractSyntax, []byte(pc.AbstractSyntax))...)
+		}
+		for _, t := range pc.TransferSyntaxes {
+			content = append(content, item(itemTransferSyntax, []byte(t))...)
+		}
+		b.Write(item(itemType, content))
+	}
+	user := make([]byte, 4)
+	binary.BigEndian.PutUint32(user, a.MaxPDULength)
+	userInfo := item(itemMaxLength, user)
+	userInfo = append(userInfo, item(itemImplementationUID, []byte(a.ImplementationClassUID))...)
+	if a.asyncOps {
+		ops := make([]byte, 4)
+		binary.BigEndian.PutUint16(ops, a.MaxOperationsInvoked)
+		binary.BigEndian.PutUint16(ops[2:], a.MaxOperationsPerformed)
+		userInfo = append(userInfo, item(itemAsyncOps, ops)...)
+	}
+	roles := map[string]bool{}
+	for _, pc := range a.Contexts {
+		if !pc.SCPRole || roles[pc.AbstractSyntax] {
+			continue
+		}
+		roles[pc.AbstractSyntax] = true
+		role := make([]byte, 2, 4+len(pc.AbstractSyntax))
+		binary.BigEndian.PutUint16(role, uint16(len(pc.AbstractSyntax)))
+		// SCU role not supported, SCP role supported
+		role = append(append(role, pc.AbstractSyntax...), 0, 1)
+		userInfo = append(userInfo, item(itemRoleSelection, role)...)
+	}
+	if a.ImplementationVersionName != "" {
+		userInfo = append(userInfo, item(itemImplementationName, []byte(a.ImplementationVersionName))...)
+	}
+	if a.identity != nil && !ac {
+		userInfo = append(userInfo, item(itemUserIdentityRQ, a.identity.encode())...)
+	}
+	if a.identityResponse != nil && ac {
+		userInfo = append(userInfo, item(itemUserIdentityAC, appendField(nil, a.identityResponse))...)
+	}
+	b.Write(item(itemUserInformation, userInfo))
+	return b.Bytes()
+}
+
+// items returns the type and value of the items in data.
+func items(data []byte, f func(itemType byte, value []byte) error) error {
+	for n := 0; n < len(data); {
+		if n+4 > len(data) {
+			return ErrPDU
+		}
+		l := int(binary.BigEndian.Uint16(data[n+2:]))
+		if n+4+l > len(data) {
+			return ErrPDU
+		}
+		err := f(data[n], data[n+4:n+4+l])
+		if err != nil {
+			return err
+		}
+		n += 4 + l
+	}
+	return nil
+}
+
+func decodeAssociate(data []byte) (*associate, error) {
+	if len(data) < 68 {
+		return nil, ErrPDU
+	}
+	a := &associate{
+		CalledAE:  strings.TrimSpace(string(data[4:20])),
+		CallingAE: strings.TrimSpace(string(data[20:36])),
+		scpRoles:  map[string]bool{},
+	}
+	err := items(data[68:], func(itemType byte, value []byte) error {
+		switch itemType {
+		case itemPresentationRQ, itemPresentationAC:
+			if len(value) < 4 {
+				return ErrPDU
+			}
+			pc := PresentationContext{ID: value[0], Result: value[2]}
+			err := items(value[4:], func(subType byte, v []byte) error {
+				switch subType {
+				case itemAbstractSyntax:
+					pc.AbstractSyntax = trimUID(v)
+				case itemTransferSyntax:
+					pc.TransferSyntaxes = append(pc.TransferSyntaxes, trimUID(v))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			a.Contexts = append(a.Contexts, pc)
+		case itemUserInformation:
+			return items(value, func(subType byte, v []byte) error {
+				switch subType {
+				case itemMaxLength:
+					if len(v) == 4 {
+						a.MaxPDULength = binary.BigEndian.Uint32(v)
+					}
+				case itemImplementationUID:
+					a.ImplementationClassUID = trimUID(v)
+				case itemAsyncOps:
+					if len(v) != 4 {
+						return ErrPDU
+					}
+					a.asyncOps = true
+					a.MaxOperationsInvoked = binary.BigEndian.Uint16(v)
+					a.MaxOperationsPerformed = binary.BigEndian.Uint16(v[2:])
+				case itemRoleSelection:
+					if len(v) < 2 {
+						return ErrPDU
+					}
+					l := int(binary.BigEndian.Uint16(v))
+					if len(v) != l+4 {
+						return ErrPDU
+					}
+					a.scpRoles[trimUID(v[2:2+l])] = v[l+3] == 1
+				case itemImplementationName:
+					a.ImplementationVersionName = strings.TrimSpace(string(v))
+				case itemUserIdentityRQ:
+					var err error
+					a.identity, err = decodeUserIdentity(v)
+					return err
+				case itemUserIdentityAC:
+					_, response, err := readField(v)
+					a.identityResponse = response
+					return err
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	for i := range a.Contexts {
+		a.Contexts[i].SCPRole = a.scpRoles[a.Contexts[i].AbstractSyntax]
+	}
+	return a, err
+}
+
+func trimUID(b []byte) string {
+	return strings.TrimRight(string(b), "\x00 ")
+}
+
+// pdv - Presentation Data Value item of a P-DATA-TF PDU.
+type pdv struct {
+	ContextID byte
+	Command   bool
+	Last      bool
+	Data      []byte
+}
+
+func decodePDVs(data []byte) ([]pdv, error) {
+	pdvs := []pdv{}
+	for n := 0; n < len(data); {
+		if n+6 > len(data) {
+			return pdvs, ErrPDU
+		}
+		l := int(binary.BigEndian.Uint32(data[n:]))
+		if l < 2 || n+4+l > len(data) {
+			return pdvs, ErrPDU
+		}
+		h := data[n+5]
+		pdvs = append(pdvs, pdv{
+			ContextID: data[n+4],
+			Command:   h&0x01 != 0,
+			Last:      h&0x02 != 0,
+			Data:      data[n+6 : n+4+l],
+		})
+		n += 4 + l
+	}
+	return pdvs, nil
+}
+
+func (p pdv) encode() []byte {
+	b := make([]byte, 6, 6+len(p.Data))
+	binary.BigEndian.PutUint32(b, uint32(2+len(p.Data)))
+	b[4] = p.ContextID
+	if p.Command {
+		b[5] |= 0x01
+	}
+	if p.Last {
+		b[5] |= 0x02
+	}
+	return append(b, p.Data...)
+}
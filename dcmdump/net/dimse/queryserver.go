@@ -0,0 +1,229 @@
+package dimse
+
+import (
+	"bytes"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// Query/Retrieve status codes
+const (
+	StatusMoveDestinationUnknown = 0xA801
+	// StatusSubOperationsFailed - Warning, sub-operations complete with one
+	// or more failures.
+	StatusSubOperationsFailed = 0xB000
+)
+
+// QueryRetrieveSOPClasses are the Patient Root and Study Root information
+// models served by NewQueryServer.
+var QueryRetrieveSOPClasses = []string{
+	sopclass.PatientRootQRIMFind,
+	sopclass.PatientRootQRIMMove,
+	sopclass.PatientRootQRIMGet,
+	sopclass.StudyRootQRIMFind,
+	sopclass.StudyRootQRIMMove,
+	sopclass.StudyRootQRIMGet,
+}
+
+// Database - Backend of a QueryServer.
+// The Find methods are called with the C-FIND identifier of their level and
+// return one data set per patient, study, series or instance matching it;
+// the server only returns the keys requested by the identifier.
+// LocateInstances returns the instances matching a C-GET or C-MOVE
+// identifier, they are sent as returned.
+type Database interface {
+	FindPatients(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+	FindStudies(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+	FindSeries(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+	FindInstances(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+	LocateInstances(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+}
+
+// QueryServer - Query/Retrieve service class provider answering C-FIND,
+// C-GET and C-MOVE requests from a Database.
+type QueryServer struct {
+	Server
+	Database Database
+	// Destinations are the addresses of the application entities C-MOVE
+	// requests can send instances to, by AE title.
+	Destinations map[string]string
+}
+
+// NewQueryServer returns a QueryServer for the Query/Retrieve SOP classes,
+// accepting the storage SOP classes for the C-STORE sub-operations of C-GET.
+// Destinations can be set before serving.
+func NewQueryServer(aeTitle string, db Database) *QueryServer {
+	s := &QueryServer{
+		Server: Server{
+			AETitle:    aeTitle,
+			SOPClasses: append(append([]string{}, QueryRetrieveSOPClasses...), StorageSOPClasses...),
+		},
+		Database:     db,
+		Destinations: map[string]string{},
+	}
+	s.Handle = s.handleQuery
+	return s
+}
+
+func (s *QueryServer) handleQuery(a *Association, m *Message) error {
+	rsp := &Command{
+		CommandField:              m.Command.CommandField | 0x8000,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+	}
+	switch m.Command.CommandField {
+	case CCancelRQ:
+		// Requests are answered before the next one is read
+		return nil
+	case CFindRQ, CGetRQ, CMoveRQ:
+	default:
+		rsp.Status = StatusUnrecognizedOperation
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	identifier, err := responseFile(a, m)
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusCannotUnderstand, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	if m.Command.CommandField == CFindRQ {
+		return s.find(a, m, identifier, rsp)
+	}
+	return s.retrieve(a, m, identifier, rsp)
+}
+
+func (s *QueryServer) find(a *Association, m *Message, identifier *dcmdump.DicomFile, rsp *Command) error {
+	level := firstString(identifier, "00080052")
+	var matches []*dcmdump.DicomFile
+	var err error
+	switch level {
+	case PatientLevel:
+		matches, err = s.Database.FindPatients(identifier)
+	case StudyLevel:
+		matches, err = s.Database.FindStudies(identifier)
+	case SeriesLevel:
+		matches, err = s.Database.FindSeries(identifier)
+	case ImageLevel:
+		matches, err = s.Database.FindInstances(identifier)
+	default:
+		rsp.Status, rsp.ErrorComment = StatusDataSetMismatch, "Unknown QueryRetrieveLevel"
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusProcessingFailure, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	rsp.Status = StatusPending
+	for _, df := range matches {
+		match := &dcmdump.DicomFile{Elements: returnKeys(identifier.Elements, df.Elements)}
+		match.SetElement("00080052", "CS", level)
+		var buf bytes.Buffer
+		err = match.WriteDataset(&buf, a.TransferSyntax(m.ContextID))
+		if err != nil {
+			return err
+		}
+		err = a.Send(m.ContextID, rsp, buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	rsp.Status = StatusSuccess
+	return a.Send(m.ContextID, rsp, nil)
+}
+
+// retrieve sends the located instances with C-STORE sub-operations, on the
+// association for C-GET and on a new one with the destination for C-MOVE.
+func (s *QueryServer) retrieve(a *Association, m *Message, identifier *dcmdump.DicomFile, rsp *Command) error {
+	instances, err := s.Database.LocateInstances(identifier)
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusProcessingFailure, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	store := a.Store
+	if m.Command.CommandField == CMoveRQ {
+		addr, ok := s.Destinations[m.Command.MoveDestination]
+		if !ok {
+			rsp.Status = StatusMoveDestinationUnknown
+			return a.Send(m.ContextID, rsp, nil)
+		}
+		if len(instances) > 0 {
+			dest, err := Dial(addr, a.CalledAE, m.Command.MoveDestination, storeContexts(instances))
+			if err != nil {
+				rsp.Status, rsp.ErrorComment = StatusProcessingFailure, err.Error()
+				return a.Send(m.ContextID, rsp, nil)
+			}
+			defer dest.Release()
+			store = func(df *dcmdump.DicomFile) error {
+				return dest.store(df, a.CallingAE, m.Command.MessageID)
+			}
+		}
+	}
+	rsp.Status = StatusPending
+	rsp.Remaining = uint16(len(instances))
+	for _, df := range instances {
+		if store(df) != nil {
+			rsp.Failed++
+		} else {
+			rsp.Completed++
+		}
+		rsp.Remaining--
+		if rsp.Remaining == 0 {
+			continue
+		}
+		err = a.Send(m.ContextID, rsp, nil)
+		if err != nil {
+			return err
+		}
+	}
+	rsp.Status = StatusSuccess
+	if rsp.Failed > 0 {
+		rsp.Status = StatusSubOperationsFailed
+	}
+	return a.Send(m.ContextID, rsp, nil)
+}
+
+// Files - Database of instances held in memory.
+// The Find methods return the first matching instance of each patient,
+// study, series or instance.
+type Files []*dcmdump.DicomFile
+
+// FindPatients returns the patients matching identifier.
+func (f Files) FindPatients(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+	return f.find(identifier, "00100020"), nil
+}
+
+// FindStudies returns the studies matching identifier.
+func (f Files) FindStudies(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+	return f.find(identifier, "0020000D"), nil
+}
+
+// FindSeries returns the series matching identifier.
+func (f Files) FindSeries(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+	return f.find(identifier, "0020000E"), nil
+}
+
+// FindInstances returns the instances matching identifier.
+func (f Files) FindInstances(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+	return f.find(identifier, "00080018"), nil
+}
+
+// LocateInstances returns the instances matching identifier.
+func (f Files) LocateInstances(identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error) {
+	return f.find(identifier, "00080018"), nil
+}
+
+// find returns the first instance matching identifier for each value of the
+// unique key tagStr.
+func (f Files) find(identifier *dcmdump.DicomFile, tagStr string) []*dcmdump.DicomFile {
+	matches := []*dcmdump.DicomFile{}
+	seen := map[string]bool{}
+	for _, df := range f {
+		key := firstString(df, tagStr)
+		if seen[key] || !Matches(identifier, df) {
+			continue
+		}
+		seen[key] = true
+		matches = append(matches, df)
+	}
+	return matches
+}
@@ -0,0 +1,216 @@
+package dimse
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// Progress - Sub-operation counts of a C-GET or C-MOVE response.
+type Progress struct {
+	Remaining uint16
+	Completed uint16
+	Failed    uint16
+	Warning   uint16
+}
+
+// retrieveKeys are the unique keys of each level, the keys of the upper levels
+// are included in the identifier when set.
+var retrieveKeys = []struct {
+	level, tag string
+}{
+	{PatientLevel, "00100020"},
+	{StudyLevel, "0020000D"},
+	{SeriesLevel, "0020000E"},
+	{ImageLevel, "00080018"},
+}
+
+// RetrieveIdentifier returns the C-GET or C-MOVE identifier of the query at
+// level, with only the unique keys.
+func (q *Query) RetrieveIdentifier(level string) (*dcmdump.DicomFile, error) {
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	err := df.SetElement("00080052", "CS", level)
+	if err != nil {
+		return nil, err
+	}
+	values := q.values()
+	for _, k := range retrieveKeys {
+		vr := "UI"
+		if k.level == PatientLevel {
+			vr = "LO"
+		}
+		if v := values[k.tag]; v != "" || k.level == level {
+			err = df.SetElement(k.tag, vr, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if k.level == level {
+			break
+		}
+	}
+	return df, nil
+}
+
+// retrieveModel returns the information model used for a level.
+func retrieveModel(level, study, patient string) string {
+	if level == PatientLevel {
+		return patient
+	}
+	return study
+}
+
+// Get retrieves the instances matching the query at level from the
+// application entity calledAE at addr with C-GET, store is called with each
+// instance received and progress, when not nil, with each response.
+func Get(addr, callingAE, calledAE, level string, q Query, store func(*dcmdump.DicomFile) error, progress func(Progress)) error {
+	identifier, err := q.RetrieveIdentifier(level)
+	if err != nil {
+		return err
+	}
+	sopClass := retrieveModel(level, sopclass.StudyRootQRIMGet, sopclass.PatientRootQRIMGet)
+	contexts := []PresentationContext{{AbstractSyntax: sopClass}}
+	for _, c := range StorageSOPClasses {
+		contexts = append(contexts, PresentationContext{AbstractSyntax: c, SCPRole: true})
+	}
+	a, err := Dial(addr, callingAE, calledAE, contexts)
+	if err != nil {
+		return err
+	}
+	err = a.Get(sopClass, identifier, store, progress)
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// Get sends a C-GET request with identifier on the association and answers
+// the C-STORE sub-operations received on it, calling store with each
+// instance.
+// An error from store fails the sub-operation, the retrieve goes on.
+func (a *Association) Get(sopClass string, identifier *dcmdump.DicomFile, store func(*dcmdump.DicomFile) error, progress func(Progress)) error {
+	return a.retrieve(CGetRQ, sopClass, "", identifier, func(m *Message) error {
+		status := uint16(StatusSuccess)
+		comment := ""
+		df, err := receivedFile(a, m)
+		if err != nil {
+			status, comment = StatusCannotUnderstand, err.Error()
+		} else if err = store(df); err != nil {
+			status, comment = StatusOutOfResources, err.Error()
+		}
+		return a.Send(m.ContextID, &Command{
+			CommandField:              CStoreRSP,
+			MessageIDBeingRespondedTo: m.Command.MessageID,
+			AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+			AffectedSOPInstanceUID:    m.Command.AffectedSOPInstanceUID,
+			Status:                    status,
+			ErrorComment:              comment,
+		}, nil)
+	}, progress)
+}
+
+// Move asks the application entity calledAE at addr to send the instances
+// matching the query at level to the application entity destination with
+// C-MOVE, progress, when not nil, is called with each response.
+func Move(addr, callingAE, calledAE, destination, level string, q Query, progress func(Progress)) error {
+	identifier, err := q.RetrieveIdentifier(level)
+	if err != nil {
+		return err
+	}
+	sopClass := retrieveModel(level, sopclass.StudyRootQRIMMove, sopclass.PatientRootQRIMMove)
+	a, err := Dial(addr, callingAE, calledAE, []PresentationContext{{AbstractSyntax: sopClass}})
+	if err != nil {
+		return err
+	}
+	err = a.Move(sopClass, destination, identifier, progress)
+	if err != nil {
+		a.Abort()
+		return err
+	}
+	return a.Release()
+}
+
+// MoveStore retrieves the instances matching the query at level from the
+// application entity calledAE at addr with C-MOVE, receiving them with a
+// store SCP listening on listenAddr.
+// The remote application entity has to know callingAE at listenAddr.
+// store is called with each instance received, possibly from several
+// goroutines.
+func MoveStore(addr, listenAddr, callingAE, calledAE, level string, q Query, store func(*dcmdump.DicomFile) error, progress func(Progress)) error {
+	l, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return err
+	}
+	s := NewStoreServer(callingAE, "")
+	s.OnStore = func(a *Association, df *dcmdump.DicomFile) error {
+		return store(df)
+	}
+	go s.Serve(l)
+	defer l.Close()
+	return Move(addr, callingAE, calledAE, callingAE, level, q, progress)
+}
+
+// Move sends a C-MOVE request with identifier on the association, the
+// instances are sent by the peer to the application entity destination.
+func (a *Association) Move(sopClass, destination string, identifier *dcmdump.DicomFile, progress func(Progress)) error {
+	return a.retrieve(CMoveRQ, sopClass, destination, identifier, nil, progress)
+}
+
+// retrieve sends a C-GET or C-MOVE request and waits for its final response,
+// C-STORE requests received meanwhile are passed to store.
+func (a *Association) retrieve(commandField uint16, sopClass, destination string, identifier *dcmdump.DicomFile, store func(*Message) error, progress func(Progress)) error {
+	pc, err := a.Context(sopClass, "")
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = identifier.WriteDataset(&buf, pc.TransferSyntaxes[0])
+	if err != nil {
+		return err
+	}
+	id := a.NextMessageID()
+	err = a.Send(pc.ID, &Command{
+		CommandField:        commandField,
+		MessageID:           id,
+		AffectedSOPClassUID: sopClass,
+		MoveDestination:     destination,
+	}, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	for {
+		m, err := a.Receive()
+		if err != nil {
+			return err
+		}
+		if m.Command.CommandField == CStoreRQ && store != nil {
+			err = store(m)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if m.Command.CommandField != commandField|0x8000 || m.Command.MessageIDBeingRespondedTo != id {
+			return ErrPDU
+		}
+		if progress != nil {
+			progress(Progress{
+				Remaining: m.Command.Remaining,
+				Completed: m.Command.Completed,
+				Failed:    m.Command.Failed,
+				Warning:   m.Command.Warning,
+			})
+		}
+		switch m.Command.Status {
+		case StatusPending, StatusPendingWarning:
+		case StatusSuccess:
+			return nil
+		default:
+			return &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+		}
+	}
+}
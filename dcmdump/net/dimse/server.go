@@ -0,0 +1,231 @@
+package dimse
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// Server - Service class provider accepting associations.
+type Server struct {
+	// AETitle the server answers to, any called AE is accepted when empty.
+	AETitle string
+	// SOPClasses accepted besides Verification.
+	SOPClasses []string
+	// TransferSyntaxes accepted, DefaultTransferSyntaxes when empty.
+	TransferSyntaxes []string
+	// TLSConfig secures the connections with TLS when not nil, see
+	// BCP195Config.
+	TLSConfig *tls.Config
+	// Trace is called with each PDU sent and received, see LogPDUs and
+	// CapturePDUs.
+	Trace func(p *PDU)
+	// MaxPDULength announced to requesters, DefaultMaxPDULength when 0.
+	MaxPDULength uint32
+	// Authenticate is called with the user identity proposed by the
+	// requester, nil when none. An error rejects the association, the
+	// response is sent when the requester asked for it.
+	// Any requester is accepted when nil.
+	Authenticate func(id *UserIdentity) (response []byte, err error)
+	// Handle is called for each request other than C-ECHO and has to send
+	// the responses. Requests are answered with an unrecognized operation
+	// status when nil.
+	Handle func(a *Association, m *Message) error
+}
+
+// ListenAndServe listens on the TCP address addr and serves associations.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l, each association is served on its own
+// goroutine.
+// Connections are secured with TLSConfig when set.
+func (s *Server) Serve(l net.Listener) error {
+	if s.TLSConfig != nil {
+		l = tls.NewListener(l, s.TLSConfig)
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn negotiates an association on conn and serves its requests until
+// it is released.
+func (s *Server) ServeConn(conn net.Conn) error {
+	conn = tracePDUs(conn, s.Trace)
+	a, err := s.accept(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	for {
+		m, err := a.Receive()
+		if err != nil {
+			a.conn.Close()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		err = s.handle(a, m)
+		if err != nil {
+			a.Abort()
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(a *Association, m *Message) error {
+	switch {
+	case m.Command.CommandField == CEchoRQ:
+		return a.Send(m.ContextID, &Command{
+			CommandField:              CEchoRSP,
+			MessageIDBeingRespondedTo: m.Command.MessageID,
+			AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+			Status:                    StatusSuccess,
+		}, nil)
+	case m.Command.IsResponse():
+		return nil
+	case s.Handle != nil:
+		return s.Handle(a, m)
+	}
+	return a.Send(m.ContextID, &Command{
+		CommandField:              m.Command.CommandField | 0x8000,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+		Status:                    StatusUnrecognizedOperation,
+	}, nil)
+}
+
+// accept answers the A-ASSOCIATE-RQ read from conn.
+func (s *Server) accept(conn net.Conn) (*Association, error) {
+	pduType, data, err := readPDU(conn)
+	if err != nil {
+		return nil, err
+	}
+	if pduType != pduAssociateRQ {
+		writePDU(conn, pduAbort, make([]byte, 4))
+		return nil, ErrPDU
+	}
+	rq, err := decodeAssociate(data)
+	if err != nil {
+		writePDU(conn, pduAbort, make([]byte, 4))
+		return nil, err
+	}
+	if s.AETitle != "" && rq.CalledAE != s.AETitle {
+		// Rejected permanent, service user, called AE title not recognized
+		writePDU(conn, pduAssociateRJ, []byte{0, 1, 1, 7})
+		return nil, ErrRejected
+	}
+	var response []byte
+	if s.Authenticate != nil {
+		response, err = s.Authenticate(rq.identity)
+		if err != nil {
+			// Rejected permanent, service user, no reason given
+			writePDU(conn, pduAssociateRJ, []byte{0, 1, 1, 1})
+			return nil, ErrRejected
+		}
+	}
+	a := &Association{
+		CalledAE:  rq.CalledAE,
+		CallingAE: rq.CallingAE,
+		Contexts:  map[byte]*PresentationContext{},
+		// Requests are performed one at a time
+		MaxOperationsInvoked:   1,
+		MaxOperationsPerformed: 1,
+		UserIdentity:           rq.identity,
+		conn:                   conn,
+		maxPDU:                 rq.MaxPDULength,
+	}
+	ac := associate{
+		CalledAE:                  rq.CalledAE,
+		CallingAE:                 rq.CallingAE,
+		MaxPDULength:              s.MaxPDULength,
+		ImplementationClassUID:    dcmdump.ImplementationClassUID,
+		ImplementationVersionName: dcmdump.ImplementationVersionName,
+		asyncOps:                  rq.asyncOps,
+		MaxOperationsInvoked:      1,
+		MaxOperationsPerformed:    1,
+	}
+	if ac.MaxPDULength == 0 {
+		ac.MaxPDULength = DefaultMaxPDULength
+	}
+	if s.Authenticate != nil && rq.identity != nil && rq.identity.ResponseRequested {
+		if response == nil {
+			response = []byte{}
+		}
+		ac.identityResponse = response
+	}
+	for _, pc := range rq.Contexts {
+		result := PresentationContext{ID: pc.ID, Result: ResultAbstractSyntaxNotSupported}
+		if len(pc.TransferSyntaxes) == 0 {
+			// Contexts must propose at least one transfer syntax
+			result.Result = ResultProviderRejection
+			result.TransferSyntaxes = []string{ts.ImplicitVRLittleEndian}
+			ac.Contexts = append(ac.Contexts, result)
+			continue
+		}
+		if s.supports(pc.AbstractSyntax) {
+			result.Result = ResultTransferSyntaxNotSupported
+			if t := s.transferSyntax(pc.TransferSyntaxes); t != "" {
+				result.Result = ResultAcceptance
+				result.TransferSyntaxes = []string{t}
+				// The abstract syntax is only used for role selection
+				result.AbstractSyntax = pc.AbstractSyntax
+				result.SCPRole = pc.SCPRole
+				accepted := result
+				a.Contexts[pc.ID] = &accepted
+			}
+		}
+		if result.Result != ResultAcceptance {
+			// Rejected contexts still carry a transfer syntax sub-item
+			result.TransferSyntaxes = pc.TransferSyntaxes[:1]
+		}
+		ac.Contexts = append(ac.Contexts, result)
+	}
+	err = writePDU(conn, pduAssociateAC, ac.encode(true))
+	return a, err
+}
+
+func (s *Server) supports(abstractSyntax string) bool {
+	if abstractSyntax == sopclass.VerificationSOPClass {
+		return true
+	}
+	for _, c := range s.SOPClasses {
+		if c == abstractSyntax {
+			return true
+		}
+	}
+	return false
+}
+
+// transferSyntax returns the first proposed transfer syntax the server
+// accepts.
+func (s *Server) transferSyntax(proposed []string) string {
+	accepted := s.TransferSyntaxes
+	if len(accepted) == 0 {
+		accepted = DefaultTransferSyntaxes
+	}
+	for _, p := range proposed {
+		for _, t := range accepted {
+			if p == t {
+				return p
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,273 @@
+package dimse
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// Storage status codes
+const (
+	StatusOutOfResources    = 0xA700
+	StatusCannotUnderstand  = 0xC000
+	StatusDataSetMismatch   = 0xA900
+	StatusCoercionOfElement = 0xB000
+)
+
+// StorageSOPClasses are the storage SOP classes accepted by NewStoreServer.
+var StorageSOPClasses = []string{
+	"1.2.840.10008.5.1.4.1.1.1",      // CR Image Storage
+	"1.2.840.10008.5.1.4.1.1.1.1",    // Digital X-Ray Image Storage - For Presentation
+	"1.2.840.10008.5.1.4.1.1.1.1.1",  // Digital X-Ray Image Storage - For Processing
+	"1.2.840.10008.5.1.4.1.1.1.2",    // Digital Mammography X-Ray Image Storage - For Presentation
+	"1.2.840.10008.5.1.4.1.1.1.2.1",  // Digital Mammography X-Ray Image Storage - For Processing
+	"1.2.840.10008.5.1.4.1.1.2",      // CT Image Storage
+	"1.2.840.10008.5.1.4.1.1.2.1",    // Enhanced CT Image Storage
+	"1.2.840.10008.5.1.4.1.1.3.1",    // Ultrasound Multi-frame Image Storage
+	"1.2.840.10008.5.1.4.1.1.4",      // MR Image Storage
+	"1.2.840.10008.5.1.4.1.1.4.1",    // Enhanced MR Image Storage
+	"1.2.840.10008.5.1.4.1.1.6.1",    // Ultrasound Image Storage
+	"1.2.840.10008.5.1.4.1.1.7",      // Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.7.1",    // Multi-frame Single Bit Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.7.2",    // Multi-frame Grayscale Byte Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.7.3",    // Multi-frame Grayscale Word Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.7.4",    // Multi-frame True Color Secondary Capture Image Storage
+	"1.2.840.10008.5.1.4.1.1.11.1",   // Grayscale Softcopy Presentation State Storage
+	"1.2.840.10008.5.1.4.1.1.12.1",   // X-Ray Angiographic Image Storage
+	"1.2.840.10008.5.1.4.1.1.12.2",   // X-Ray Radiofluoroscopic Image Storage
+	"1.2.840.10008.5.1.4.1.1.20",     // Nuclear Medicine Image Storage
+	"1.2.840.10008.5.1.4.1.1.66",     // Raw Data Storage
+	"1.2.840.10008.5.1.4.1.1.66.1",   // Spatial Registration Storage
+	"1.2.840.10008.5.1.4.1.1.66.4",   // Segmentation Storage
+	"1.2.840.10008.5.1.4.1.1.77.1.4", // VL Photographic Image Storage
+	"1.2.840.10008.5.1.4.1.1.88.11",  // Basic Text SR Storage
+	"1.2.840.10008.5.1.4.1.1.88.22",  // Enhanced SR Storage
+	"1.2.840.10008.5.1.4.1.1.88.33",  // Comprehensive SR Storage
+	"1.2.840.10008.5.1.4.1.1.88.59",  // Key Object Selection Document Storage
+	"1.2.840.10008.5.1.4.1.1.104.1",  // Encapsulated PDF Storage
+	"1.2.840.10008.5.1.4.1.1.128",    // Positron Emission Tomography Image Storage
+	"1.2.840.10008.5.1.4.1.1.481.1",  // RT Image Storage
+	"1.2.840.10008.5.1.4.1.1.481.2",  // RT Dose Storage
+	"1.2.840.10008.5.1.4.1.1.481.3",  // RT Structure Set Storage
+	"1.2.840.10008.5.1.4.1.1.481.5",  // RT Plan Storage
+}
+
+// StoreServer - C-STORE service class provider.
+// Received instances are passed to OnStore or, when it is nil, written to
+// Dir as StudyInstanceUID/SeriesInstanceUID/SOPInstanceUID.dcm.
+type StoreServer struct {
+	Server
+	Dir string
+	// OnStore is called with each received instance, including its file
+	// meta group. An error fails the C-STORE with an out of resources
+	// status.
+	OnStore func(a *Association, df *dcmdump.DicomFile) error
+}
+
+// NewStoreServer returns a StoreServer for the storage SOP classes writing
+// received instances to dir.
+// SOPClasses, TransferSyntaxes and OnStore can be changed before serving.
+func NewStoreServer(aeTitle, dir string) *StoreServer {
+	s := &StoreServer{
+		Server: Server{AETitle: aeTitle, SOPClasses: StorageSOPClasses},
+		Dir:    dir,
+	}
+	s.Handle = s.handleStore
+	return s
+}
+
+func (s *StoreServer) handleStore(a *Association, m *Message) error {
+	if m.Command.CommandField != CStoreRQ {
+		return a.Send(m.ContextID, &Command{
+			CommandField:              m.Command.CommandField | 0x8000,
+			MessageIDBeingRespondedTo: m.Command.MessageID,
+			AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+			Status:                    StatusUnrecognizedOperation,
+		}, nil)
+	}
+	status := uint16(StatusSuccess)
+	comment := ""
+	df, err := receivedFile(a, m)
+	if err != nil {
+		status, comment = StatusCannotUnderstand, err.Error()
+	} else if s.OnStore != nil {
+		err = s.OnStore(a, df)
+	} else {
+		err = s.write(df)
+	}
+	if err != nil && status == StatusSuccess {
+		status, comment = StatusOutOfResources, err.Error()
+	}
+	return a.Send(m.ContextID, &Command{
+		CommandField:              CStoreRSP,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+		AffectedSOPInstanceUID:    m.Command.AffectedSOPInstanceUID,
+		Status:                    status,
+		ErrorComment:              comment,
+	}, nil)
+}
+
+// receivedFile parses the data set of a C-STORE request and adds its file meta
+// group.
+func receivedFile(a *Association, m *Message) (*dcmdump.DicomFile, error) {
+	transferSyntax := a.TransferSyntax(m.ContextID)
+	elements, err := dcmdump.ParseDataset(bytes.NewReader(m.Data), transferSyntax)
+	if err != nil {
+		return nil, err
+	}
+	df := &dcmdump.DicomFile{Elements: elements, TransferSyntax: transferSyntax}
+	err = df.SetFileMeta(transferSyntax)
+	if err != nil {
+		return nil, err
+	}
+	err = df.SetElement("00020016", "AE", a.CallingAE)
+	if err != nil {
+		return nil, err
+	}
+	return df, nil
+}
+
+func (s *StoreServer) write(df *dcmdump.DicomFile) error {
+	dir := filepath.Join(s.Dir, uidValue(df, "0020000D"), uidValue(df, "0020000E"))
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, uidValue(df, "00020003")+".dcm")
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = df.Write(f, df.TransferSyntax)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	df.Path = path
+	return f.Close()
+}
+
+// uidValue returns a UID of df usable as a file name, "unknown" when it is
+// missing or not a valid UI value, as the peer could send ".." to write
+// outside of Dir.
+func uidValue(df *dcmdump.DicomFile, tagStr string) string {
+	de, err := df.LookupElement(tagStr)
+	if err != nil || len(de.Strings()) == 0 {
+		return "unknown"
+	}
+	uid := de.Strings()[0]
+	if uid == "" || len(uid) > 64 || strings.Trim(uid, "0123456789.") != "" || strings.Trim(uid, ".") == "" {
+		return "unknown"
+	}
+	return uid
+}
+
+// Store sends df with a C-STORE request and waits for its response.
+// The file is sent with its transfer syntax when accepted for its SOP class
+// or converted to the transfer syntax accepted.
+func (a *Association) Store(df *dcmdump.DicomFile) error {
+	return a.store(df, "", 0)
+}
+
+// store sends a C-STORE, as a C-MOVE sub-operation when moveOriginator is
+// set.
+func (a *Association) store(df *dcmdump.DicomFile, moveOriginator string, moveMessageID uint16) error {
+	sopClass := firstString(df, "00080016", "00020002")
+	sopInstance := firstString(df, "00080018", "00020003")
+	pc, err := a.Context(sopClass, df.TransferSyntax)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = df.WriteDataset(&buf, pc.TransferSyntaxes[0])
+	if err != nil {
+		return err
+	}
+	id := a.NextMessageID()
+	err = a.Send(pc.ID, &Command{
+		CommandField:            CStoreRQ,
+		MessageID:               id,
+		AffectedSOPClassUID:     sopClass,
+		AffectedSOPInstanceUID:  sopInstance,
+		MoveOriginatorAE:        moveOriginator,
+		MoveOriginatorMessageID: moveMessageID,
+	}, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	m, err := a.Receive()
+	if err != nil {
+		return err
+	}
+	if m.Command.CommandField != CStoreRSP || m.Command.MessageIDBeingRespondedTo != id {
+		return ErrPDU
+	}
+	if m.Command.Status != StatusSuccess && m.Command.Status&0xF000 != StatusCoercionOfElement {
+		return &StatusError{Status: m.Command.Status, Comment: m.Command.ErrorComment}
+	}
+	return nil
+}
+
+// firstString returns the value of the first of the tags present in df.
+func firstString(df *dcmdump.DicomFile, tags ...string) string {
+	for _, t := range tags {
+		de, err := df.LookupElement(t)
+		if err != nil {
+			continue
+		}
+		if v := de.Strings(); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// Store sends the files to the application entity calledAE at addr with
+// C-STORE requests on a single association.
+func Store(addr, callingAE, calledAE string, files ...*dcmdump.DicomFile) error {
+	a, err := Dial(addr, callingAE, calledAE, storeContexts(files))
+	if err != nil {
+		return err
+	}
+	for _, df := range files {
+		err = a.Store(df)
+		if err != nil {
+			a.Abort()
+			return err
+		}
+	}
+	return a.Release()
+}
+
+// storeContexts proposes a context for the SOP class of each file.
+func storeContexts(files []*dcmdump.DicomFile) []PresentationContext {
+	contexts := []PresentationContext{}
+	seen := map[string]bool{}
+	for _, df := range files {
+		c := firstString(df, "00080016", "00020002")
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		contexts = append(contexts, PresentationContext{AbstractSyntax: c, TransferSyntaxes: transferSyntaxes(df)})
+	}
+	return contexts
+}
+
+// transferSyntaxes proposes the file transfer syntax first.
+func transferSyntaxes(df *dcmdump.DicomFile) []string {
+	proposed := []string{}
+	if df.TransferSyntax != "" {
+		proposed = append(proposed, df.TransferSyntax)
+	}
+	for _, t := range DefaultTransferSyntaxes {
+		if t != df.TransferSyntax {
+			proposed = append(proposed, t)
+		}
+	}
+	return proposed
+}
@@ -0,0 +1,38 @@
+package dimse
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+)
+
+// BCP195CipherSuites are the forward secret AEAD cipher suites of the BCP 195
+// secure transport connection profile used by BCP195Config, TLS 1.3 suites
+// are not configurable.
+var BCP195CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// BCP195Config returns a TLS configuration following the BCP 195 secure
+// transport connection profile of PS3.15 B.9, TLS 1.2 or later with
+// BCP195CipherSuites, presenting certificates to the peer.
+// When pool is not nil the peers are authenticated with it: servers require
+// client certificates and clients verify the server certificates.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part15/sect_B.9.html
+func BCP195Config(certificates []tls.Certificate, pool *x509.CertPool) *tls.Config {
+	config := &tls.Config{
+		Certificates: certificates,
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: append([]uint16{}, BCP195CipherSuites...),
+	}
+	if pool != nil {
+		config.RootCAs = pool
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config
+}
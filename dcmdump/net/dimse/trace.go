@@ -0,0 +1,231 @@
+package dimse
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrCapture is returned by ReadCapture for files that are not captures.
+var ErrCapture = errors.New("Not a PDU capture")
+
+// captureMagic starts the files written by CapturePDUs.
+const captureMagic = "DICMPDU1"
+
+var pduNames = map[byte]string{
+	pduAssociateRQ: "A-ASSOCIATE-RQ",
+	pduAssociateAC: "A-ASSOCIATE-AC",
+	pduAssociateRJ: "A-ASSOCIATE-RJ",
+	pduDataTF:      "P-DATA-TF",
+	pduReleaseRQ:   "A-RELEASE-RQ",
+	pduReleaseRP:   "A-RELEASE-RP",
+	pduAbort:       "A-ABORT",
+}
+
+var commandNames = map[uint16]string{
+	CStoreRQ:        "C-STORE-RQ",
+	CStoreRSP:       "C-STORE-RSP",
+	CGetRQ:          "C-GET-RQ",
+	CGetRSP:         "C-GET-RSP",
+	CFindRQ:         "C-FIND-RQ",
+	CFindRSP:        "C-FIND-RSP",
+	CMoveRQ:         "C-MOVE-RQ",
+	CMoveRSP:        "C-MOVE-RSP",
+	CEchoRQ:         "C-ECHO-RQ",
+	CEchoRSP:        "C-ECHO-RSP",
+	NEventReportRQ:  "N-EVENT-REPORT-RQ",
+	NEventReportRSP: "N-EVENT-REPORT-RSP",
+	NGetRQ:          "N-GET-RQ",
+	NGetRSP:         "N-GET-RSP",
+	NSetRQ:          "N-SET-RQ",
+	NSetRSP:         "N-SET-RSP",
+	NActionRQ:       "N-ACTION-RQ",
+	NActionRSP:      "N-ACTION-RSP",
+	NCreateRQ:       "N-CREATE-RQ",
+	NCreateRSP:      "N-CREATE-RSP",
+	NDeleteRQ:       "N-DELETE-RQ",
+	NDeleteRSP:      "N-DELETE-RSP",
+	CCancelRQ:       "C-CANCEL-RQ",
+}
+
+// PDU - Protocol data unit sent or received on an association, as given to
+// the Trace functions of AssociationConfig and Server.
+type PDU struct {
+	Time time.Time
+	// Sent is set for PDUs sent to the peer.
+	Sent bool
+	Type byte
+	// Data is the PDU following its 6 byte header.
+	Data []byte
+}
+
+// Name returns the name of the PDU type.
+func (p *PDU) Name() string {
+	if name, ok := pduNames[p.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("PDU 0x%02X", p.Type)
+}
+
+func (p *PDU) String() string {
+	direction := "<"
+	if p.Sent {
+		direction = ">"
+	}
+	return fmt.Sprintf("%s %s %s length %d", p.Time.Format("15:04:05.000000"), direction, p.Name(), len(p.Data))
+}
+
+// Commands returns the command sets of a P-DATA-TF completed by a single
+// PDV, fragmented command sets are left out.
+func (p *PDU) Commands() []*Command {
+	if p.Type != pduDataTF {
+		return nil
+	}
+	pdvs, err := decodePDVs(p.Data)
+	if err != nil {
+		return nil
+	}
+	commands := []*Command{}
+	for _, v := range pdvs {
+		if !v.Command || !v.Last {
+			continue
+		}
+		c, err := decodeCommand(v.Data)
+		if err == nil {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+// LogPDUs returns a Trace function writing each PDU to w with its hex dump
+// and decoded command sets.
+// It can be shared by concurrent associations.
+func LogPDUs(w io.Writer) func(p *PDU) {
+	var mu sync.Mutex
+	return func(p *PDU) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintln(w, p)
+		for _, c := range p.Commands() {
+			name, ok := commandNames[c.CommandField]
+			if !ok {
+				name = fmt.Sprintf("0x%04X", c.CommandField)
+			}
+			fmt.Fprintf(w, "  %s %+v\n", name, *c)
+		}
+		fmt.Fprint(w, hex.Dump(p.Data))
+	}
+}
+
+// CapturePDUs returns a Trace function recording each PDU to w, to be read
+// back with ReadCapture.
+// It can be shared by concurrent associations.
+func CapturePDUs(w io.Writer) func(p *PDU) {
+	var mu sync.Mutex
+	started := false
+	return func(p *PDU) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !started {
+			w.Write([]byte(captureMagic))
+			started = true
+		}
+		h := make([]byte, 15)
+		binary.BigEndian.PutUint64(h, uint64(p.Time.UnixNano()))
+		if p.Sent {
+			h[8] = 1
+		}
+		h[9] = p.Type
+		binary.BigEndian.PutUint32(h[11:], uint32(len(p.Data)))
+		w.Write(append(h, p.Data...))
+	}
+}
+
+// ReadCapture calls fn with each PDU recorded by CapturePDUs in r.
+func ReadCapture(r io.Reader, fn func(p *PDU) error) error {
+	magic := make([]byte, len(captureMagic))
+	_, err := io.ReadFull(r, magic)
+	if err == io.EOF {
+		return nil
+	} else if err != nil || string(magic) != captureMagic {
+		return ErrCapture
+	}
+	h := make([]byte, 9)
+	for {
+		_, err = io.ReadFull(r, h)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		pduType, data, err := readPDU(r)
+		if err != nil {
+			return err
+		}
+		p := &PDU{
+			Time: time.Unix(0, int64(binary.BigEndian.Uint64(h))),
+			Sent: h[8] == 1,
+			Type: pduType,
+			Data: data,
+		}
+		err = fn(p)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// traceConn calls trace with the PDUs read from and written to a connection.
+type traceConn struct {
+	net.Conn
+	trace func(p *PDU)
+
+	mu      sync.Mutex
+	in, out []byte
+}
+
+// tracePDUs wraps conn when trace is not nil.
+func tracePDUs(conn net.Conn, trace func(p *PDU)) net.Conn {
+	if trace == nil {
+		return conn
+	}
+	return &traceConn{Conn: conn, trace: trace}
+}
+
+func (c *traceConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.mu.Lock()
+	c.in = c.emit(append(c.in, b[:n]...), false)
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *traceConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.mu.Lock()
+	c.out = c.emit(append(c.out, b[:n]...), true)
+	c.mu.Unlock()
+	return n, err
+}
+
+// emit traces the complete PDUs of buf and returns the remaining bytes.
+func (c *traceConn) emit(buf []byte, sent bool) []byte {
+	for len(buf) >= 6 {
+		l := int(binary.BigEndian.Uint32(buf[2:]))
+		if len(buf) < 6+l {
+			return buf
+		}
+		c.trace(&PDU{Time: time.Now(), Sent: sent, Type: buf[0], Data: append([]byte{}, buf[6:6+l]...)})
+		buf = buf[6+l:]
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	return buf
+}
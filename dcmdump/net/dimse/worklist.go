@@ -0,0 +1,292 @@
+package dimse
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/net/match"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	"github.com/davidgamba/go-dicom/qr/sopclass"
+)
+
+// WorklistQuery - Matching keys of a Modality Worklist C-FIND, empty keys
+// match all values.
+// The scheduled procedure step keys are sent in a single item of the
+// ScheduledProcedureStepSequence (0040,0100).
+type WorklistQuery struct {
+	PatientName          string
+	PatientID            string
+	AccessionNumber      string
+	RequestedProcedureID string
+	StudyInstanceUID     string
+
+	ScheduledStationAETitle          string
+	ScheduledProcedureStepStartDate  Range
+	ScheduledProcedureStepStartTime  Range
+	Modality                         string
+	ScheduledPerformingPhysicianName string
+	ScheduledProcedureStepID         string
+	ScheduledProcedureStepStatus     string
+
+	// Keys adds top level matching or return keys by tag or keyword, their
+	// VR is taken from the dictionary.
+	Keys map[string]string
+}
+
+// worklistKeys are the top level return keys of a worklist query.
+var worklistKeys = []queryKey{
+	{"00080050", "SH"},
+	{"00080090", "PN"}, // ReferringPhysicianName
+	{"00100010", "PN"},
+	{"00100020", "LO"},
+	{"00100030", "DA"},
+	{"00100040", "CS"},
+	{"0020000D", "UI"},
+	{"00321060", "LO"}, // RequestedProcedureDescription
+	{"00401001", "SH"},
+}
+
+// stepKeys are the return keys of the ScheduledProcedureStepSequence item.
+var stepKeys = []queryKey{
+	{"00080060", "CS"},
+	{"00400001", "AE"},
+	{"00400002", "DA"},
+	{"00400003", "TM"},
+	{"00400006", "PN"},
+	{"00400007", "LO"}, // ScheduledProcedureStepDescription
+	{"00400009", "SH"},
+	{"00400010", "SH"}, // ScheduledStationName
+	{"00400020", "CS"},
+}
+
+func (q *WorklistQuery) values() map[string]string {
+	return map[string]string{
+		"00080050": q.AccessionNumber,
+		"00080060": q.Modality,
+		"00100010": q.PatientName,
+		"00100020": q.PatientID,
+		"0020000D": q.StudyInstanceUID,
+		"00400001": q.ScheduledStationAETitle,
+		"00400002": q.ScheduledProcedureStepStartDate.String(),
+		"00400003": q.ScheduledProcedureStepStartTime.String(),
+		"00400006": q.ScheduledPerformingPhysicianName,
+		"00400009": q.ScheduledProcedureStepID,
+		"00400020": q.ScheduledProcedureStepStatus,
+		"00401001": q.RequestedProcedureID,
+	}
+}
+
+// Identifier returns the C-FIND identifier of the query with the worklist
+// return keys.
+func (q *WorklistQuery) Identifier() (*dcmdump.DicomFile, error) {
+	df := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	values := q.values()
+	step := dcmdump.Item{}
+	for _, k := range stepKeys {
+		de, err := dcmdump.NewDataElement(k.tag, k.vr, values[k.tag])
+		if err != nil {
+			return nil, err
+		}
+		step.Elements = append(step.Elements, de)
+	}
+	err := df.SetElement("00400100", "SQ", []dcmdump.Item{step})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range worklistKeys {
+		err = df.SetElement(k.tag, k.vr, values[k.tag])
+		if err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range q.Keys {
+		info, ok := tag.ByKeyword(k)
+		if !ok {
+			info, ok = tagInfo(k)
+		}
+		if !ok || info.VR == "" {
+			return nil, ErrUnknownKey
+		}
+		err = df.SetElement(info.TagStr(), info.VR, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return df, nil
+}
+
+// FindWorklist queries the application entity calledAE at addr with the
+// Modality Worklist information model, fn is called with each scheduled
+// procedure step.
+func FindWorklist(addr, callingAE, calledAE string, q WorklistQuery, fn func(*dcmdump.DicomFile) error) error {
+	identifier, err := q.Identifier()
+	if err != nil {
+		return err
+	}
+	return findIdentifier(addr, callingAE, calledAE, sopclass.ModalityWorklistIMFind, identifier, fn)
+}
+
+// WorklistServer - Modality Worklist C-FIND service class provider.
+// Queries are answered from OnFind or, when it is nil, with the Items
+// matching the identifier.
+// Only the keys requested by the identifier are returned.
+type WorklistServer struct {
+	Server
+	Items []*dcmdump.DicomFile
+	// OnFind returns the worklist items matching identifier, Matches can be
+	// used to apply the C-FIND matching rules. An error fails the C-FIND with
+	// a processing failure status.
+	OnFind func(a *Association, identifier *dcmdump.DicomFile) ([]*dcmdump.DicomFile, error)
+}
+
+// NewWorklistServer returns a WorklistServer answering with items.
+// Items and OnFind can be changed before serving.
+func NewWorklistServer(aeTitle string, items []*dcmdump.DicomFile) *WorklistServer {
+	s := &WorklistServer{
+		Server: Server{AETitle: aeTitle, SOPClasses: []string{sopclass.ModalityWorklistIMFind}},
+		Items:  items,
+	}
+	s.Handle = s.handleFind
+	return s
+}
+
+func (s *WorklistServer) handleFind(a *Association, m *Message) error {
+	rsp := &Command{
+		CommandField:              m.Command.CommandField | 0x8000,
+		MessageIDBeingRespondedTo: m.Command.MessageID,
+		AffectedSOPClassUID:       m.Command.AffectedSOPClassUID,
+	}
+	switch {
+	case m.Command.CommandField == CCancelRQ:
+		// All the matches have already been sent
+		return nil
+	case m.Command.CommandField != CFindRQ:
+		rsp.Status = StatusUnrecognizedOperation
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	identifier, err := responseFile(a, m)
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusCannotUnderstand, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	var items []*dcmdump.DicomFile
+	if s.OnFind != nil {
+		items, err = s.OnFind(a, identifier)
+	} else {
+		for _, df := range s.Items {
+			if Matches(identifier, df) {
+				items = append(items, df)
+			}
+		}
+	}
+	if err != nil {
+		rsp.Status, rsp.ErrorComment = StatusProcessingFailure, err.Error()
+		return a.Send(m.ContextID, rsp, nil)
+	}
+	rsp.Status = StatusPending
+	for _, df := range items {
+		match := &dcmdump.DicomFile{Elements: returnKeys(identifier.Elements, df.Elements)}
+		var buf bytes.Buffer
+		err = match.WriteDataset(&buf, a.TransferSyntax(m.ContextID))
+		if err != nil {
+			return err
+		}
+		err = a.Send(m.ContextID, rsp, buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	rsp.Status = StatusSuccess
+	return a.Send(m.ContextID, rsp, nil)
+}
+
+// Matches reports whether df matches the keys of the C-FIND identifier.
+// Empty keys match all values, sequence keys match when any item matches
+// the keys of their first item.
+func Matches(identifier, df *dcmdump.DicomFile) bool {
+	return matchElements(identifier.Elements, df.Elements)
+}
+
+func matchElements(keys, elements []dcmdump.DataElement) bool {
+	for i := range keys {
+		k := &keys[i]
+		if k.TagStr == "00080005" || k.TagStr == "00080052" {
+			continue
+		}
+		if k.VRStr == "SQ" {
+			// Sequences without matching keys are universal
+			if len(k.Items) == 0 || matchElements(k.Items[0].Elements, nil) {
+				continue
+			}
+			de := dcmdump.FindElement(elements, k.TagStr)
+			if de == nil {
+				return false
+			}
+			ok := false
+			for _, item := range de.Items {
+				if matchElements(k.Items[0].Elements, item.Elements) {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return false
+			}
+			continue
+		}
+		m := strings.Join(k.Strings(), `\`)
+		if m == "" {
+			continue
+		}
+		de := dcmdump.FindElement(elements, k.TagStr)
+		if de == nil {
+			return false
+		}
+		ok := false
+		for _, v := range de.Strings() {
+			if match.Value(k.VRStr, v, m) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// returnKeys returns the elements of the match requested by keys, keys
+// missing from the match are returned empty.
+// Only the sequence items matching the keys are returned.
+func returnKeys(keys, elements []dcmdump.DataElement) []dcmdump.DataElement {
+	out := []dcmdump.DataElement{}
+	if de := dcmdump.FindElement(elements, "00080005"); de != nil {
+		out = append(out, *de)
+	}
+	for _, k := range keys {
+		if k.TagStr == "00080005" {
+			continue
+		}
+		de := dcmdump.FindElement(elements, k.TagStr)
+		switch {
+		case de == nil:
+			k.Data, k.Len, k.Items = []byte{}, 0, nil
+			out = append(out, k)
+		case k.VRStr == "SQ" && len(k.Items) > 0:
+			sq := *de
+			sq.Items = nil
+			for _, item := range de.Items {
+				if matchElements(k.Items[0].Elements, item.Elements) {
+					sq.Items = append(sq.Items, dcmdump.Item{Elements: returnKeys(k.Items[0].Elements, item.Elements)})
+				}
+			}
+			out = append(out, sq)
+		default:
+			out = append(out, *de)
+		}
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+// Package match implements the attribute matching of C-FIND and QIDO-RS
+// queries.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part04/sect_C.2.2.2.html
+package match
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Value applies the matching rules of PS3.4 C.2.2.2 to the value v of an
+// attribute with the given VR and the key m: UID lists separated by
+// backslashes, date and time ranges, wildcards and single values.
+// Person names are matched ignoring case.
+func Value(vr, v, m string) bool {
+	switch {
+	case vr == "UI":
+		for _, uid := range strings.Split(m, `\`) {
+			if uid == v {
+				return true
+			}
+		}
+		return false
+	case (vr == "DA" || vr == "TM" || vr == "DT") && strings.Contains(m, "-"):
+		r := strings.SplitN(m, "-", 2)
+		return (r[0] == "" || v >= r[0]) && (r[1] == "" || v <= r[1])
+	case strings.ContainsAny(m, "*?"):
+		expr := regexp.QuoteMeta(m)
+		expr = strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(expr)
+		if vr == "PN" {
+			expr = "(?i)" + expr
+		}
+		return regexp.MustCompile("^" + expr + "$").MatchString(v)
+	case vr == "PN":
+		return strings.EqualFold(v, m)
+	}
+	return v == m
+}
@@ -0,0 +1,30 @@
+package match
+
+import "testing"
+
+func TestValue(t *testing.T) {
+	for _, c := range []struct {
+		vr, v, m string
+		expected bool
+	}{
+		{"UI", "1.2.3", `1.2.4\1.2.3`, true},
+		{"UI", "1.2.3", "1.2.3,1.2.4", false},
+		{"UI", "1.2.3", "1.2", false},
+		{"DA", "20160102", "20160101-20160131", true},
+		{"DA", "20160102", "20160103-", false},
+		{"DA", "20160102", "-20160102", true},
+		{"TM", "1200", "1100-1300", true},
+		{"PN", "DOE^JOHN", "doe*", true},
+		{"PN", "DOE^JOHN", "doe^john", true},
+		{"LO", "DOE^JOHN", "doe*", false},
+		{"LO", "ID12", "ID?2", true},
+		{"LO", "ID1.2", "ID1?2", true},
+		{"LO", "ID1x2", "ID1.2", false},
+		{"CS", "CT", "CT", true},
+		{"CS", "CT", "MR", false},
+	} {
+		if Value(c.vr, c.v, c.m) != c.expected {
+			t.Errorf("%s %q %q: expected %v", c.vr, c.v, c.m, c.expected)
+		}
+	}
+}
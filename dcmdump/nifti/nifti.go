@@ -0,0 +1,166 @@
+// Package nifti writes volumes assembled from DICOM series as NIfTI-1 files.
+// https://nifti.nimh.nih.gov/nifti-1
+package nifti
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// headerSize is sizeof_hdr, the voxels follow the header and the 4 bytes of
+// the empty extension.
+const (
+	headerSize = 348
+	voxOffset  = headerSize + 4
+)
+
+// NIfTI-1 codes
+const (
+	dtFloat32    = 16
+	unitsMM      = 2
+	xformScanner = 1
+)
+
+// Write writes v as a single file NIfTI-1 image of float32 voxels.
+// The affine maps voxel indices to RAS+ coordinates: the DICOM patient
+// coordinates, LPS+, with x and y negated. It is written as both the qform and
+// the sform, with the scanner anatomical code.
+func Write(w io.Writer, v *dcmdump.Volume, description string) error {
+	h := make([]byte, voxOffset)
+	le := binary.LittleEndian
+	le.PutUint32(h[0:], headerSize)
+	h[38] = 'r'
+	for i, d := range []int{3, v.Columns, v.Rows, v.Slices, 1, 1, 1, 1} {
+		le.PutUint16(h[40+2*i:], uint16(d))
+	}
+	le.PutUint16(h[70:], dtFloat32)
+	le.PutUint16(h[72:], 32)
+
+	srow, quatern, qfac := affine(v)
+	for i, d := range []float64{qfac, v.Spacing[0], v.Spacing[1], v.Spacing[2]} {
+		putFloat(h[76+4*i:], d)
+	}
+	putFloat(h[108:], voxOffset)
+	putFloat(h[112:], 1)
+	h[123] = unitsMM
+	copy(h[148:228], description)
+	le.PutUint16(h[252:], xformScanner)
+	le.PutUint16(h[254:], xformScanner)
+	for i, q := range quatern {
+		putFloat(h[256+4*i:], q)
+	}
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 4; c++ {
+			putFloat(h[280+16*r+4*c:], srow[r][c])
+		}
+	}
+	copy(h[344:], "n+1\x00")
+	_, err := w.Write(h)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, 4*len(v.Data))
+	for i, f := range v.Data {
+		le.PutUint32(data[4*i:], math.Float32bits(f))
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// WriteFile writes v to path, gzip compressed when it ends with .gz as in
+// .nii.gz.
+func WriteFile(path string, v *dcmdump.Volume, description string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	err = Write(w, v, description)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// affine returns the rows of the voxel to RAS+ matrix, the quaternion b, c, d
+// and offsets of its rotation and the qfac of its handedness.
+func affine(v *dcmdump.Volume) (srow [3][4]float64, quatern [6]float64, qfac float64) {
+	// LPS to RAS
+	sign := [3]float64{-1, -1, 1}
+	axes := [3][3]float64{v.Row, v.Column, v.Normal}
+	var r [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i][j] = sign[i] * axes[j][i]
+			srow[i][j] = r[i][j] * v.Spacing[j]
+		}
+		srow[i][3] = sign[i] * v.Origin[i]
+	}
+	qfac = 1
+	det := r[0][0]*(r[1][1]*r[2][2]-r[1][2]*r[2][1]) -
+		r[0][1]*(r[1][0]*r[2][2]-r[1][2]*r[2][0]) +
+		r[0][2]*(r[1][0]*r[2][1]-r[1][1]*r[2][0])
+	if det < 0 {
+		qfac = -1
+		for i := 0; i < 3; i++ {
+			r[i][2] = -r[i][2]
+		}
+	}
+	b, c, d := rotationQuaternion(r)
+	quatern = [6]float64{b, c, d, srow[0][3], srow[1][3], srow[2][3]}
+	return srow, quatern, qfac
+}
+
+// rotationQuaternion returns b, c and d of the unit quaternion of a proper
+// rotation, with a non negative a as NIfTI requires.
+func rotationQuaternion(r [3][3]float64) (b, c, d float64) {
+	var a float64
+	switch trace := r[0][0] + r[1][1] + r[2][2]; {
+	case trace > 0:
+		s := 0.5 / math.Sqrt(trace+1)
+		a = 0.25 / s
+		b = (r[2][1] - r[1][2]) * s
+		c = (r[0][2] - r[2][0]) * s
+		d = (r[1][0] - r[0][1]) * s
+	case r[0][0] > r[1][1] && r[0][0] > r[2][2]:
+		s := 2 * math.Sqrt(1+r[0][0]-r[1][1]-r[2][2])
+		a = (r[2][1] - r[1][2]) / s
+		b = 0.25 * s
+		c = (r[0][1] + r[1][0]) / s
+		d = (r[0][2] + r[2][0]) / s
+	case r[1][1] > r[2][2]:
+		s := 2 * math.Sqrt(1+r[1][1]-r[0][0]-r[2][2])
+		a = (r[0][2] - r[2][0]) / s
+		b = (r[0][1] + r[1][0]) / s
+		c = 0.25 * s
+		d = (r[1][2] + r[2][1]) / s
+	default:
+		s := 2 * math.Sqrt(1+r[2][2]-r[0][0]-r[1][1])
+		a = (r[1][0] - r[0][1]) / s
+		b = (r[0][2] + r[2][0]) / s
+		c = (r[1][2] + r[2][1]) / s
+		d = 0.25 * s
+	}
+	if a < 0 {
+		b, c, d = -b, -c, -d
+	}
+	return b, c, d
+}
+
+func putFloat(b []byte, f float64) {
+	binary.LittleEndian.PutUint32(b, math.Float32bits(float32(f)))
+}
@@ -0,0 +1,86 @@
+package nifti
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func TestWriteFile(t *testing.T) {
+	v := &dcmdump.Volume{
+		Columns: 2, Rows: 1, Slices: 2,
+		Spacing: [3]float64{0.5, 0.5, 2},
+		Origin:  [3]float64{10, 20, 30},
+		// Sagittal slices
+		Row:    [3]float64{0, 1, 0},
+		Column: [3]float64{0, 0, -1},
+		Normal: [3]float64{-1, 0, 0},
+		Data:   []float32{0, 1, 2, -1024},
+	}
+	path := filepath.Join(t.TempDir(), "volume.nii.gz")
+	err := WriteFile(path, v, "T1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	le := binary.LittleEndian
+	float := func(offset int) float64 {
+		return float64(math.Float32frombits(le.Uint32(b[offset:])))
+	}
+	if len(b) != voxOffset+16 || le.Uint32(b) != headerSize || string(b[344:348]) != "n+1\x00" {
+		t.Fatalf("Wrong header: %d bytes, %x", len(b), b[344:348])
+	}
+	if le.Uint16(b[40:]) != 3 || le.Uint16(b[42:]) != 2 || le.Uint16(b[44:]) != 1 || le.Uint16(b[46:]) != 2 || le.Uint16(b[70:]) != dtFloat32 {
+		t.Errorf("Wrong dimensions: %x", b[40:72])
+	}
+	if float(108) != voxOffset || float(88) != 2 || string(b[148:150]) != "T1" {
+		t.Errorf("Wrong offset, spacing or description")
+	}
+	// Voxel 1, 0, 1 is at LPS 10, 20.5, 30 - 2 along the normal: 8, 20.5, 30
+	for r, expected := range []float64{-8, -20.5, 30} {
+		p := float(280+16*r) + float(280+16*r+8) + float(280+16*r+12)
+		if math.Abs(p-expected) > 1e-4 {
+			t.Errorf("Wrong sform row %d: %v", r, p)
+		}
+	}
+	// Rotation from the quaternion, scaled by qfac for the third column
+	qb, qc, qd := float(256), float(260), float(264)
+	qa := math.Sqrt(math.Max(0, 1-qb*qb-qc*qc-qd*qd))
+	r := [3][3]float64{
+		{qa*qa + qb*qb - qc*qc - qd*qd, 2 * (qb*qc - qa*qd), 2 * (qb*qd + qa*qc)},
+		{2 * (qb*qc + qa*qd), qa*qa + qc*qc - qb*qb - qd*qd, 2 * (qc*qd - qa*qb)},
+		{2 * (qb*qd - qa*qc), 2 * (qc*qd + qa*qb), qa*qa + qd*qd - qc*qc - qb*qb},
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			s := float(280+16*i+4*j) / v.Spacing[j]
+			if j == 2 {
+				s *= float(76)
+			}
+			if math.Abs(r[i][j]-s) > 1e-4 {
+				t.Errorf("Wrong qform %d,%d: %v, sform %v", i, j, r[i][j], s)
+			}
+		}
+	}
+	if float(voxOffset+12) != -1024 {
+		t.Errorf("Wrong voxels: %x", b[voxOffset:])
+	}
+}
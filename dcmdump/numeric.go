@@ -0,0 +1,127 @@
+package dcmdump
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ParseIS parses the backslash separated values of an Integer String.
+// Leading and trailing spaces and + signs are allowed, as are integral values
+// in exponent notation, like 1e3, written by some modalities. It fails with
+// ErrValueFormat for values that aren't integers and ErrValueRange for those
+// outside of the IS range, -2^31 to 2^31-1.
+func ParseIS(s string) ([]int64, error) {
+	values := []int64{}
+	for _, v := range splitNumbers(s) {
+		if valueFormat["IS"].MatchString(v) {
+			i, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return values, ErrValueRange
+			}
+			values = append(values, i)
+			continue
+		}
+		if !valueFormat["DS"].MatchString(v) {
+			return values, ErrValueFormat
+		}
+		f, _ := strconv.ParseFloat(v, 64)
+		if f != math.Trunc(f) {
+			return values, ErrValueFormat
+		}
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			return values, ErrValueRange
+		}
+		values = append(values, int64(f))
+	}
+	return values, nil
+}
+
+// ParseDS parses the backslash separated values of a Decimal String, fixed
+// or in exponent notation. Leading and trailing spaces and + signs are
+// allowed. It fails with ErrValueFormat for values that aren't decimal
+// numbers, NaN and infinities included, and ErrValueRange for those that
+// overflow a float64.
+func ParseDS(s string) ([]float64, error) {
+	values := []float64{}
+	for _, v := range splitNumbers(s) {
+		if !valueFormat["DS"].MatchString(v) {
+			return values, ErrValueFormat
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return values, ErrValueRange
+		}
+		values = append(values, f)
+	}
+	return values, nil
+}
+
+// splitNumbers splits s on backslashes and trims the spaces and padding of
+// each value, no values are returned for blank strings.
+func splitNumbers(s string) []string {
+	s = strings.Trim(s, " \x00")
+	if s == "" {
+		return nil
+	}
+	values := strings.Split(s, `\`)
+	for i := range values {
+		values[i] = strings.Trim(values[i], " \x00")
+	}
+	return values
+}
+
+// ISValues returns the values of an IS element, see ParseIS.
+func (de *DataElement) ISValues() ([]int64, error) {
+	return ParseIS(string(de.Data))
+}
+
+// DSValues returns the values of a DS element, see ParseDS.
+func (de *DataElement) DSValues() ([]float64, error) {
+	return ParseDS(string(de.Data))
+}
+
+// formatIS formats f as an IS value, failing with ErrValueRange outside of
+// the IS range.
+func formatIS(f float64) (string, error) {
+	if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+		return "", ErrValueRange
+	}
+	return strconv.FormatInt(int64(f), 10), nil
+}
+
+// formatDS formats f as a DS value, rounded to fit in its 16 bytes.
+func formatDS(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", ErrValueFormat
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	for prec := 16; len(s) > maxLength["DS"] && prec > 1; prec-- {
+		s = strconv.FormatFloat(f, 'g', prec-1, 64)
+	}
+	return s, nil
+}
+
+// validateNumbers checks that the IS and DS values of elements, and of the
+// items they hold, parse, see ParseIS and ParseDS.
+func validateNumbers(elements []DataElement) error {
+	for i := range elements {
+		de := &elements[i]
+		for _, item := range de.Items {
+			if err := validateNumbers(item.Elements); err != nil {
+				return err
+			}
+		}
+		var err error
+		switch de.VRStr {
+		case "IS":
+			_, err = de.ISValues()
+		case "DS":
+			_, err = de.DSValues()
+		}
+		if err != nil {
+			return &ValidationError{Tag: de.TagStr, VR: de.VRStr, Value: strings.Trim(string(de.Data), " \x00"), Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package dcmdump
+
+import (
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestParseIS(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected []int64
+		err      error
+	}{
+		{"", []int64{}, nil},
+		{" 12\\-3 ", []int64{12, -3}, nil},
+		{"+7\\1e3\\2.0E2", []int64{7, 1000, 200}, nil},
+		{"2147483647\\-2147483648", []int64{2147483647, -2147483648}, nil},
+		{"2147483648", []int64{}, ErrValueRange},
+		{"3e10", []int64{}, ErrValueRange},
+		{"1.5", []int64{}, ErrValueFormat},
+		{"1\\\\2", []int64{1}, ErrValueFormat},
+		{"0x10", []int64{}, ErrValueFormat},
+	} {
+		values, err := ParseIS(c.value)
+		if err != c.err || !reflect.DeepEqual(values, c.expected) {
+			t.Errorf("%q: expected %v %v, got %v %v", c.value, c.expected, c.err, values, err)
+		}
+	}
+
+	// Parsed values can be set back
+	values, _ := ParseIS("12\\-3")
+	for _, c := range []struct {
+		value    interface{}
+		expected string
+	}{
+		{values, "12\\-3 "},
+		{values[0], "12"},
+	} {
+		de, err := NewDataElement("00200013", "IS", c.value)
+		if err != nil || de.StringData() != c.expected {
+			t.Errorf("%v: expected %q, got %q, %v", c.value, c.expected, de.StringData(), err)
+		}
+	}
+	if _, err := NewDataElement("00200013", "IS", []int64{1 << 40}); err != ErrValueRange {
+		t.Errorf("Expected ErrValueRange, got %v", err)
+	}
+}
+
+func TestParseDS(t *testing.T) {
+	for _, c := range []struct {
+		value    string
+		expected []float64
+		err      error
+	}{
+		{"  ", []float64{}, nil},
+		{" 0.5\\-.25\\+3. ", []float64{0.5, -0.25, 3}, nil},
+		{"1.5e3\\-2E-2\\1e+2", []float64{1500, -0.02, 100}, nil},
+		{"1e400", []float64{}, ErrValueRange},
+		{"NaN", []float64{}, ErrValueFormat},
+		{"1,5", []float64{}, ErrValueFormat},
+	} {
+		values, err := ParseDS(c.value)
+		if err != c.err || !reflect.DeepEqual(values, c.expected) {
+			t.Errorf("%q: expected %v %v, got %v %v", c.value, c.expected, c.err, values, err)
+		}
+	}
+}
+
+func TestNumericEncoding(t *testing.T) {
+	de, err := NewDataElement("00280030", "DS", []float64{0.1 + 0.2, -1.2345678901234567e-300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := de.Validate(); err != nil {
+		t.Errorf("generated DS doesn't conform: %q %v", de.Data, err)
+	}
+	values, err := de.DSValues()
+	if err != nil || len(values) != 2 || values[0] < 0.3 || values[0] > 0.30000001 {
+		t.Errorf("wrong DS values %q: %v %v", de.Data, values, err)
+	}
+	de, err = NewDataElement("00200013", "IS", 1e3)
+	if err != nil || string(de.Data) != "1000" {
+		t.Errorf("wrong IS: %q %v", de.Data, err)
+	}
+	if _, err := NewDataElement("00200013", "IS", 1e10); err != ErrValueRange {
+		t.Errorf("expected ErrValueRange, got %v", err)
+	}
+	if _, err := NewDataElement("00200013", "IS", 1.5); err != ErrValueRange {
+		t.Errorf("expected ErrValueRange, got %v", err)
+	}
+
+	df := &DicomFile{}
+	df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+	df.SetElement("00281050", "DS", "1,5")
+	if err := df.Write(ioutil.Discard, ts.ExplicitVRLittleEndian); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err = df.WriteWithOptions(ioutil.Discard, ts.ExplicitVRLittleEndian, WriteOptions{ValidateNumbers: true})
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Tag != "00281050" || !errors.Is(err, ErrValueFormat) {
+		t.Errorf("expected a DS validation error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package dcmdump
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+)
+
+// Option - Option of ProcessFile, setting a field of its ParseOptions.
+type Option func(o *ParseOptions)
+
+// ProcessFile reads the whole file at path, with its preamble, as set by the
+// options:
+//
+//	df, err := dcmdump.ProcessFile(path, dcmdump.WithTags("00100010", "00100020"), dcmdump.WithoutPixelData())
+func ProcessFile(path string, opts ...Option) (*DicomFile, error) {
+	o := ParseOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	df, err := ParseFileWithOptions(f, o)
+	if df != nil {
+		df.Path = path
+	}
+	return df, err
+}
+
+// WithTags keeps the top level elements with the given GGGGEEEE tags only,
+// all of them when none is given.
+func WithTags(tags ...string) Option {
+	return func(o *ParseOptions) { o.Tags = tags }
+}
+
+// WithExplicitVR sets the encoding of data sets without TransferSyntaxUID,
+// explicit VR by default.
+func WithExplicitVR(explicit bool) Option {
+	return func(o *ParseOptions) { o.ImplicitVR = !explicit }
+}
+
+// WithByteOrder sets the byte order of data sets without TransferSyntaxUID,
+// little endian by default.
+func WithByteOrder(order binary.ByteOrder) Option {
+	return func(o *ParseOptions) { o.ByteOrder = order }
+}
+
+// WithMaxElementSize fails the elements longer than size bytes with
+// ErrElementSize.
+func WithMaxElementSize(size int) Option {
+	return func(o *ParseOptions) { o.MaxElementSize = size }
+}
+
+// WithMaxDepth fails sequences nested deeper than depth, top level
+// sequences being at depth 1, with ErrSequenceDepth.
+func WithMaxDepth(depth int) Option {
+	return func(o *ParseOptions) { o.MaxDepth = depth }
+}
+
+// WithMaxElements fails files with more than n elements, nested ones
+// included, with ErrElementCount.
+func WithMaxElements(n int) Option {
+	return func(o *ParseOptions) { o.MaxElements = n }
+}
+
+// WithResolveUN reads the UN elements of known tags with their dictionary VR.
+func WithResolveUN(resolve bool) Option {
+	return func(o *ParseOptions) { o.ResolveUN = resolve }
+}
+
+// WithoutPixelData leaves out the pixel data elements without reading them.
+func WithoutPixelData() Option {
+	return func(o *ParseOptions) { o.SkipPixelData = true }
+}
+
+// WithStopAt ends the parse once the element with the given GGGGEEEE tag is
+// read, or at the first element past it.
+func WithStopAt(tag string) Option {
+	return func(o *ParseOptions) { o.StopAtTag = tag }
+}
+
+// WithLogger sets the Logger receiving the diagnostics of the parse.
+func WithLogger(l Logger) Option {
+	return func(o *ParseOptions) { o.Logger = l }
+}
+
+// WithContext ends the parse once ctx is done, the elements read so far are
+// returned with its error.
+func WithContext(ctx context.Context) Option {
+	return func(o *ParseOptions) { o.Context = ctx }
+}
+
+// WithProgress calls progress after each top level element.
+func WithProgress(progress func(ParseProgress)) Option {
+	return func(o *ParseOptions) { o.Progress = progress }
+}
@@ -0,0 +1,57 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestProcessFileOptions(t *testing.T) {
+	dir := t.TempDir()
+	df := &DicomFile{}
+	df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+	df.SetElement("0020000E", "UI", "1.2.3")
+	df.SetElement("00200013", "IS", 4)
+	df.SetElement("7FE00010", "OB", make([]byte, 1024))
+	path := filepath.Join(dir, "a.dcm")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = df.Write(f, ts.ExplicitVRLittleEndian)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	df, err = ProcessFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if df.Path != path || df.stringValue("00200013") != "4" {
+		t.Errorf("Whole file not read: %s %v", df.Path, df.Elements)
+	}
+	df, err = ProcessFile(path, WithTags("00200013"), WithoutPixelData())
+	if err != nil || len(df.Elements) != 1 || df.Elements[0].TagStr != "00200013" {
+		t.Errorf("Wrong elements %v, %v", df.Elements, err)
+	}
+	_, err = ProcessFile(path, WithMaxElementSize(512))
+	if !errors.Is(err, ErrElementSize) {
+		t.Errorf("Expected ErrElementSize, got %v", err)
+	}
+
+	// Implicit big endian data set without file meta group
+	data := append(make([]byte, 128), "DICM"...)
+	data = append(data, 0x00, 0x10, 0x00, 0x10, 0, 0, 0, 8)
+	data = append(data, "DOE^JOHN"...)
+	path = filepath.Join(dir, "b.dcm")
+	os.WriteFile(path, data, 0644)
+	df, err = ProcessFile(path, WithExplicitVR(false), WithByteOrder(binary.BigEndian))
+	if err != nil || df.stringValue("00100010") != "DOE^JOHN" {
+		t.Errorf("Wrong implicit big endian parse %v, %v", df.Elements, err)
+	}
+}
@@ -0,0 +1,171 @@
+package dcmdump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Collision policies of a Sorter
+const (
+	CollisionRename    = "rename"
+	CollisionSkip      = "skip"
+	CollisionOverwrite = "overwrite"
+	CollisionError     = "error"
+)
+
+// ErrCollision is returned by sorters with the CollisionError policy when the
+// destination of a file is taken.
+var ErrCollision = errors.New("Destination file already exists")
+
+// SortResult - Destination of a file organized by a Sorter.
+type SortResult struct {
+	Source      string
+	Destination string
+	// Skipped is set for files already at their destination and collisions
+	// skipped with CollisionSkip.
+	Skipped bool
+	Err     error
+}
+
+// Sorter - Copies or moves files into the directory layout of a template of
+// tag keywords under Dest, see ExpandTemplate:
+//
+//	{PatientID}/{StudyDate}-{StudyDescription}/{SeriesNumber}-{SeriesDescription}/{InstanceNumber}.dcm
+//
+// It is safe for concurrent use.
+type Sorter struct {
+	Dest     string
+	Template string
+	// Move removes the files from their source, they are copied otherwise.
+	Move bool
+	// DryRun reports the destinations without touching any file.
+	DryRun bool
+	// Collision is the policy for destinations already taken, by an existing
+	// file or one sorted earlier, CollisionRename when empty. Renamed files
+	// get a _1, _2... suffix before their extension.
+	Collision string
+	// Filter, when set, restricts SortDir to the files matching it.
+	Filter *Filter
+
+	mu      sync.Mutex
+	planned map[string]bool
+}
+
+// NewSorter returns a Sorter copying files into dest.
+func NewSorter(dest, template string) *Sorter {
+	return &Sorter{Dest: dest, Template: template}
+}
+
+// Sort copies or moves the file at path, parsed as df, to its destination.
+func (s *Sorter) Sort(path string, df *DicomFile) SortResult {
+	r := SortResult{Source: path}
+	name, err := df.ExpandTemplate(s.Template, nil)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	r.Destination, err = JoinTemplate(s.Dest, name)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	if same, _ := samePath(path, r.Destination); same {
+		r.Skipped = true
+		return r
+	}
+
+	s.mu.Lock()
+	if s.planned == nil {
+		s.planned = map[string]bool{}
+	}
+	taken := func(p string) bool {
+		_, err := os.Lstat(p)
+		return s.planned[p] || err == nil
+	}
+	if taken(r.Destination) {
+		switch s.Collision {
+		case CollisionSkip:
+			r.Skipped = true
+		case CollisionOverwrite:
+		case CollisionError:
+			r.Err = ErrCollision
+		default:
+			ext := filepath.Ext(r.Destination)
+			base := strings.TrimSuffix(r.Destination, ext)
+			for n := 1; taken(r.Destination); n++ {
+				r.Destination = fmt.Sprintf("%s_%d%s", base, n, ext)
+			}
+		}
+	}
+	if r.Err == nil && !r.Skipped {
+		s.planned[r.Destination] = true
+	}
+	s.mu.Unlock()
+	if r.Err != nil || r.Skipped || s.DryRun {
+		return r
+	}
+
+	r.Err = os.MkdirAll(filepath.Dir(r.Destination), 0755)
+	if r.Err != nil {
+		return r
+	}
+	if s.Move && os.Rename(path, r.Destination) == nil {
+		return r
+	}
+	// Copy, also for moves across file systems
+	r.Err = copyFile(path, r.Destination)
+	if r.Err == nil && s.Move {
+		r.Err = os.Remove(path)
+	}
+	return r
+}
+
+// SortDir sorts the DICOM files of the tree at root, calling report with the
+// result of each of them and of the files that couldn't be read.
+// Dest should not be inside root when moving files.
+func (s *Sorter) SortDir(root string, report func(SortResult)) {
+	w := NewWalker(root, nil)
+	w.Filter = s.Filter
+	for result := range w.Walk() {
+		if result.Err != nil {
+			report(SortResult{Source: result.Path, Err: result.Err})
+			continue
+		}
+		report(s.Sort(result.Path, result.File))
+	}
+}
+
+// samePath reports whether a and b are the same file.
+func samePath(a, b string) (bool, error) {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(fa, fb), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package dcmdump
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestSorter(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in")
+	os.MkdirAll(src, 0755)
+	for i, series := range []int{1, 1, 2} {
+		df := &DicomFile{}
+		df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+		df.SetElement("00080018", "UI", NewUID())
+		df.SetElement("00100020", "LO", "P1")
+		df.SetElement("00200011", "IS", series)
+		df.SetElement("00200013", "IS", 1)
+		f, err := os.Create(filepath.Join(src, string(rune('a'+i))+".dcm"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(src, "notes.txt"), []byte("not DICOM"), 0644)
+
+	dest := filepath.Join(dir, "out")
+	s := NewSorter(dest, "{PatientID}/{SeriesNumber}-{SeriesDescription:NoDescription}/{InstanceNumber}.dcm")
+	s.DryRun = true
+	results := []SortResult{}
+	s.SortDir(src, func(r SortResult) { results = append(results, r) })
+	if len(results) != 3 {
+		t.Fatalf("Wrong results: %+v", results)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Dry run created %s", dest)
+	}
+
+	s = NewSorter(dest, s.Template)
+	s.Move = true
+	destinations := []string{}
+	s.SortDir(src, func(r SortResult) {
+		if r.Err != nil {
+			t.Errorf("%s: %s", r.Source, r.Err)
+		}
+		rel, _ := filepath.Rel(dest, r.Destination)
+		destinations = append(destinations, filepath.ToSlash(rel))
+	})
+	sort.Strings(destinations)
+	expected := []string{"P1/1-NoDescription/1.dcm", "P1/1-NoDescription/1_1.dcm", "P1/2-NoDescription/1.dcm"}
+	for i, d := range expected {
+		if i >= len(destinations) || destinations[i] != d {
+			t.Fatalf("Expected %v, got %v", expected, destinations)
+		}
+		if _, err := os.Stat(filepath.Join(dest, d)); err != nil {
+			t.Errorf("Missing file: %s", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(src, "a.dcm")); !os.IsNotExist(err) {
+		t.Errorf("Source not moved")
+	}
+
+	df := &DicomFile{}
+	df.SetElement("00100020", "LO", "P1")
+	df.SetElement("00200011", "IS", 2)
+	df.SetElement("00200013", "IS", 1)
+	s = NewSorter(dest, s.Template)
+	s.Collision = CollisionError
+	if r := s.Sort(filepath.Join(src, "notes.txt"), df); r.Err != ErrCollision {
+		t.Errorf("Expected ErrCollision, got %v", r.Err)
+	}
+	s.Collision = CollisionSkip
+	if r := s.Sort(filepath.Join(src, "notes.txt"), df); r.Err != nil || !r.Skipped {
+		t.Errorf("Expected a skipped file, got %+v", r)
+	}
+	if r := s.Sort(filepath.Join(dest, "P1", "2-NoDescription", "1.dcm"), df); !r.Skipped {
+		t.Errorf("Expected a file in place to be skipped, got %+v", r)
+	}
+
+	// Values and templates can't lead out of Dest
+	df.SetElement("00100020", "LO", "..")
+	s = NewSorter(dest, "{PatientID}/x.dcm")
+	s.DryRun = true
+	if r := s.Sort(filepath.Join(src, "notes.txt"), df); r.Err != nil || r.Destination != filepath.Join(dest, "_", "x.dcm") {
+		t.Errorf("Wrong destination %+v", r)
+	}
+	s.Template = ".{SeriesDescription:.}/x.dcm"
+	if r := s.Sort(filepath.Join(src, "notes.txt"), df); r.Err != ErrOutsideDir {
+		t.Errorf("Expected ErrOutsideDir, got %+v", r)
+	}
+}
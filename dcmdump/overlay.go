@@ -0,0 +1,174 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/dcmwrite"
+)
+
+// ErrNoOverlayData is returned for overlays without OverlayData (60xx,3000),
+// like the retired overlays embedded in the unused bits of the pixel data.
+var ErrNoOverlayData = errors.New("No OverlayData in overlay group")
+
+// Overlay - Overlay plane of a repeating group 60xx, PS3.3 C.9.2.
+type Overlay struct {
+	// Group is the repeating group, between 0x6000 and 0x601E.
+	Group   uint16
+	Rows    int
+	Columns int
+	// Type is G for graphics or R for ROI.
+	Type        string
+	Subtype     string
+	Label       string
+	Description string
+	// Origin is the row and column of the first overlay point relative to
+	// the image, starting at 1.
+	Origin         [2]int
+	BitsAllocated  int
+	BitPosition    int
+	NumberOfFrames int
+	// ImageFrameOrigin is the first image frame the overlay applies to,
+	// starting at 1.
+	ImageFrameOrigin int
+
+	data []byte
+}
+
+// isOverlayGroup reports whether a tag is in the 60xx repeating groups, only
+// even groups up to 601E are overlays.
+func isOverlayGroup(tagStr string) bool {
+	return len(tagStr) == 8 && tagStr[:2] == "60" && tagStr[2:4] <= "1E" && strings.IndexByte("02468ACE", tagStr[3]) >= 0
+}
+
+// Overlays returns the overlay planes of the file ordered by group.
+func (df *DicomFile) Overlays() ([]*Overlay, error) {
+	groups := map[uint16][]*DataElement{}
+	for i := range df.Elements {
+		de := &df.Elements[i]
+		if de.PartOfSQ || !isOverlayGroup(de.TagStr) || de.TagStr[4:] == "0000" {
+			continue
+		}
+		group := de.order().Uint16(de.TagGroup)
+		groups[group] = append(groups[group], de)
+	}
+	overlays := []*Overlay{}
+	for group, elements := range groups {
+		o, err := newOverlay(group, elements)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, o)
+	}
+	sort.Slice(overlays, func(i, j int) bool { return overlays[i].Group < overlays[j].Group })
+	return overlays, nil
+}
+
+// Overlay returns the overlay plane of the given repeating group.
+func (df *DicomFile) Overlay(group uint16) (*Overlay, error) {
+	overlays, err := df.Overlays()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range overlays {
+		if o.Group == group {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("No overlay group %04X", group)
+}
+
+func newOverlay(group uint16, elements []*DataElement) (*Overlay, error) {
+	o := &Overlay{Group: group, NumberOfFrames: 1, ImageFrameOrigin: 1, BitsAllocated: 1}
+	for _, de := range elements {
+		err := de.Load()
+		if err != nil {
+			return nil, err
+		}
+		if v, ok := map[string]*int{
+			"0010": &o.Rows,
+			"0011": &o.Columns,
+			"0015": &o.NumberOfFrames,
+			"0051": &o.ImageFrameOrigin,
+			"0100": &o.BitsAllocated,
+			"0102": &o.BitPosition,
+		}[de.TagStr[4:]]; ok {
+			*v, err = de.intValue()
+			if err != nil {
+				return nil, newParseError(de, err)
+			}
+			continue
+		}
+		switch de.TagStr[4:] {
+		case "0040":
+			o.Type = strings.Join(de.Strings(), "\\")
+		case "0045":
+			o.Subtype = strings.Join(de.Strings(), "\\")
+		case "1500":
+			o.Label = strings.Join(de.Strings(), "\\")
+		case "0022":
+			o.Description = strings.Join(de.Strings(), "\\")
+		case "0050":
+			v := de.Ints()
+			if len(v) == 2 {
+				o.Origin = [2]int{v[0], v[1]}
+			}
+		case "3000":
+			o.data = de.Data
+			if de.VRStr == "OW" && de.order() != binary.LittleEndian {
+				// Bits are packed from the least significant bit of little
+				// endian words
+				o.data = dcmwrite.Swap(de.Data, 2)
+			}
+		}
+	}
+	return o, nil
+}
+
+// Bits returns the overlay points of frame i, starting at 0, indexed by row
+// and column.
+func (o *Overlay) Bits(i int) ([][]bool, error) {
+	if o.data == nil {
+		return nil, ErrNoOverlayData
+	}
+	if i < 0 || i >= o.NumberOfFrames {
+		return nil, ErrFrameIndex
+	}
+	size := o.Rows * o.Columns
+	start := i * size
+	if (start+size+7)/8 > len(o.data) {
+		return nil, fmt.Errorf("OverlayData of group %04X too short: %d bytes", o.Group, len(o.data))
+	}
+	bits := make([][]bool, o.Rows)
+	for r := range bits {
+		bits[r] = make([]bool, o.Columns)
+		for c := range bits[r] {
+			n := start + r*o.Columns + c
+			bits[r][c] = o.data[n/8]&(1<<uint(n%8)) != 0
+		}
+	}
+	return bits, nil
+}
+
+// Image returns frame i, starting at 0, as a gray image with overlay points
+// set to white.
+func (o *Overlay) Image(i int) (*image.Gray, error) {
+	bits, err := o.Bits(i)
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, o.Columns, o.Rows))
+	for r, row := range bits {
+		for c, set := range row {
+			if set {
+				img.SetGray(c, r, color.Gray{Y: 0xff})
+			}
+		}
+	}
+	return img, nil
+}
@@ -0,0 +1,57 @@
+package dcmdump
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestOverlays(t *testing.T) {
+	data := explicitElement(0x0028, 0x0010, "US", []byte{3, 0})
+	data = append(data, explicitElement(0x6002, 0x0010, "US", []byte{3, 0})...)
+	data = append(data, explicitElement(0x6002, 0x0011, "US", []byte{3, 0})...)
+	data = append(data, explicitElement(0x6002, 0x0040, "CS", []byte("G "))...)
+	data = append(data, explicitElement(0x6002, 0x0050, "SS", []byte{1, 0, 2, 0})...)
+	data = append(data, explicitElement(0x6002, 0x0100, "US", []byte{1, 0})...)
+	// Diagonal, bits 0, 4 and 8
+	data = append(data, explicitElement(0x6002, 0x3000, "OW", []byte{0x11, 0x01})...)
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if elements[6].Name != "OverlayData" {
+		t.Errorf("Repeating group tag not resolved: %s", elements[6].String())
+	}
+	df := &DicomFile{Elements: elements}
+	overlays, err := df.Overlays()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(overlays) != 1 {
+		t.Fatalf("Expected 1 overlay, got %d", len(overlays))
+	}
+	o := overlays[0]
+	if o.Group != 0x6002 || o.Rows != 3 || o.Columns != 3 || o.Type != "G" || o.Origin != [2]int{1, 2} {
+		t.Errorf("Wrong overlay: %+v", o)
+	}
+	bits, err := o.Bits(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := [][]bool{{true, false, false}, {false, true, false}, {false, false, true}}
+	if !reflect.DeepEqual(bits, expected) {
+		t.Errorf("Expected %v, got %v", expected, bits)
+	}
+	img, err := o.Image(0)
+	if err != nil || img.GrayAt(1, 1).Y != 0xff || img.GrayAt(1, 0).Y != 0 {
+		t.Errorf("Wrong image: %v, %v", img, err)
+	}
+	_, err = o.Bits(1)
+	if err != ErrFrameIndex {
+		t.Errorf("Expected ErrFrameIndex, got %v", err)
+	}
+	_, err = df.Overlay(0x6000)
+	if err == nil {
+		t.Errorf("Expected error for missing overlay group")
+	}
+}
@@ -0,0 +1,561 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// DefaultRowGroupSize is the number of rows of the row groups of a
+// ParquetWriter.
+const DefaultRowGroupSize = 65536
+
+// Parquet physical, converted and repetition types
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetDate            = 6
+	parquetTimestampMicros = 10
+
+	parquetRequired = 0
+	parquetOptional = 1
+	parquetRepeated = 2
+)
+
+// ParquetWriter - Table of elements with a row per file and a column per tag
+// path, see ParseSelector, written as an Apache Parquet file. The first
+// column is the path of the file.
+//
+// Columns are typed by the VR of the dictionary for their last tag: DOUBLE
+// for FL, FD and DS, INT64 for the integer VRs, IS included, DATE for DA,
+// TIMESTAMP_MICROS in UTC for DT, raw BYTE_ARRAY for OB, OW, UN and the other
+// binary VRs, taking the first element matched, and UTF8 strings otherwise.
+// Columns of multi valued elements, as given by the VM, or of paths that can
+// match several elements are repeated, the others optional. Values that
+// can't be converted are skipped.
+//
+// Rows are buffered for a row group, written uncompressed with PLAIN
+// encoding. It is safe for concurrent use.
+type ParquetWriter struct {
+	// RowGroupSize is the number of rows per row group, DefaultRowGroupSize
+	// when not set.
+	RowGroupSize int
+
+	mu        sync.Mutex
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	selectors []Selector
+	rows      int
+	total     int64
+	groups    []parquetRowGroup
+}
+
+type parquetColumn struct {
+	name       string
+	selector   Selector
+	kind       int32
+	converted  int32 // -1 for none
+	repetition int32
+	binary     bool
+
+	// Row group buffers
+	values []byte
+	defs   []byte
+	reps   []byte
+}
+
+type parquetRowGroup struct {
+	rows   int64
+	size   int64
+	chunks []parquetChunk
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	count  int64
+}
+
+// NewParquetWriter returns a ParquetWriter writing the columns given as tag
+// paths to w. Close writes the file footer.
+func NewParquetWriter(w io.Writer, columns []string) (*ParquetWriter, error) {
+	p := &ParquetWriter{w: w}
+	p.columns = append(p.columns, &parquetColumn{name: "Path", kind: parquetByteArray, converted: parquetUTF8, repetition: parquetRequired})
+	for _, c := range columns {
+		s, err := ParseSelector(c)
+		if err != nil {
+			return nil, err
+		}
+		p.selectors = append(p.selectors, s)
+		p.columns = append(p.columns, newParquetColumn(c, s))
+	}
+	return p, nil
+}
+
+func newParquetColumn(name string, s Selector) *parquetColumn {
+	c := &parquetColumn{name: parquetName(name), selector: s, kind: parquetByteArray, converted: parquetUTF8, repetition: parquetOptional}
+	last := s[len(s)-1]
+	vr, vm := "", ""
+	if last.tag != "" {
+		vr, vm = tag.Tag[last.tag]["vr"], tag.Tag[last.tag]["vm"]
+	}
+	switch vr {
+	case "FL", "FD", "DS":
+		c.kind, c.converted = parquetDouble, -1
+	case "SS", "US", "SL", "UL", "SV", "UV", "IS":
+		c.kind, c.converted = parquetInt64, -1
+	case "DA":
+		c.kind, c.converted = parquetInt32, parquetDate
+	case "DT":
+		c.kind, c.converted = parquetInt64, parquetTimestampMicros
+	case "OB", "OW", "OF", "OD", "OL", "OV", "UN":
+		c.converted, c.binary = -1, true
+		return c
+	}
+	if vm != "1" {
+		c.repetition = parquetRepeated
+	}
+	for _, st := range s[:len(s)-1] {
+		if st.tag == "" || st.item == allItems {
+			c.repetition = parquetRepeated
+		}
+	}
+	return c
+}
+
+// parquetName returns a tag path as a column name of letters, digits and
+// underscores.
+func parquetName(path string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, path)
+	for strings.Contains(name, "__") {
+		name = strings.Replace(name, "__", "_", -1)
+	}
+	return strings.Trim(name, "_")
+}
+
+// Write adds the row of df, read from path.
+func (p *ParquetWriter) Write(path string, df *DicomFile) error {
+	rows := make([][][]byte, len(p.columns))
+	rows[0] = [][]byte{[]byte(path)}
+	for i, c := range p.columns[1:] {
+		rows[i+1] = c.row(df)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(); err != nil {
+		return err
+	}
+	for i, c := range p.columns {
+		c.add(rows[i])
+	}
+	p.rows++
+	size := p.RowGroupSize
+	if size <= 0 {
+		size = DefaultRowGroupSize
+	}
+	if p.rows >= size {
+		return p.flush()
+	}
+	return nil
+}
+
+func newParquetEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	return NewParquetWriter(w, o.Tags)
+}
+
+// Encode adds the row of df, read from df.Path, as the parquet Encoder.
+func (p *ParquetWriter) Encode(df *DicomFile) error {
+	return p.Write(df.Path, df)
+}
+
+// WriteDir adds the DICOM files of the tree at root matching filter, all of
+// them when nil, calling failed with the files that couldn't be read. Only
+// the elements of the columns and the filter are parsed. It doesn't Close p.
+func (p *ParquetWriter) WriteDir(root string, filter *Filter, failed func(path string, err error)) error {
+	w := NewWalker(root, selectorTags(p.selectors))
+	w.Filter = filter
+	results := w.Walk()
+	for result := range results {
+		if result.Err != nil {
+			if failed != nil {
+				failed(result.Path, result.Err)
+			}
+			continue
+		}
+		err := p.Write(result.Path, result.File)
+		if err != nil {
+			// Drain the walk
+			for range results {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the footer of the file, it doesn't
+// close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(); err != nil {
+		return err
+	}
+	if p.rows > 0 {
+		if err := p.flush(); err != nil {
+			return err
+		}
+	}
+	footer := p.footer()
+	footer = append(footer, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(footer[len(footer)-4:], uint32(len(footer)-4))
+	return p.write(append(footer, "PAR1"...))
+}
+
+func (p *ParquetWriter) start() error {
+	if p.offset > 0 {
+		return nil
+	}
+	return p.write([]byte("PAR1"))
+}
+
+func (p *ParquetWriter) write(b []byte) error {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return err
+}
+
+// flush writes the buffered rows as a row group with a data page per column.
+func (p *ParquetWriter) flush() error {
+	g := parquetRowGroup{rows: int64(p.rows)}
+	for _, c := range p.columns {
+		page := []byte{}
+		if c.repetition == parquetRepeated {
+			page = append(page, rleLevels(c.reps)...)
+		}
+		if c.repetition != parquetRequired {
+			page = append(page, rleLevels(c.defs)...)
+		}
+		page = append(page, c.values...)
+		count := int64(p.rows)
+		if c.repetition != parquetRequired {
+			count = int64(len(c.defs))
+		}
+
+		t := newThriftWriter()
+		t.i32(1, 0) // DATA_PAGE
+		t.i32(2, int32(len(page)))
+		t.i32(3, int32(len(page)))
+		t.begin(5)
+		t.i32(1, int32(count))
+		t.i32(2, 0) // PLAIN
+		t.i32(3, 3) // RLE
+		t.i32(4, 3)
+		t.end()
+		header := t.bytes()
+
+		chunk := parquetChunk{offset: p.offset, size: int64(len(header) + len(page)), count: count}
+		if err := p.write(header); err != nil {
+			return err
+		}
+		if err := p.write(page); err != nil {
+			return err
+		}
+		g.chunks = append(g.chunks, chunk)
+		g.size += chunk.size
+		c.values, c.defs, c.reps = c.values[:0], c.defs[:0], c.reps[:0]
+	}
+	p.groups = append(p.groups, g)
+	p.total += int64(p.rows)
+	p.rows = 0
+	return nil
+}
+
+// footer returns the FileMetaData of the file.
+func (p *ParquetWriter) footer() []byte {
+	t := newThriftWriter()
+	t.i32(1, 1)
+	t.list(2, thriftStruct, len(p.columns)+1)
+	t.item()
+	t.str(4, "schema")
+	t.i32(5, int32(len(p.columns)))
+	t.end()
+	for _, c := range p.columns {
+		t.item()
+		t.i32(1, c.kind)
+		t.i32(3, c.repetition)
+		t.str(4, c.name)
+		if c.converted >= 0 {
+			t.i32(6, c.converted)
+		}
+		t.end()
+	}
+	t.i64(3, p.total)
+	t.list(4, thriftStruct, len(p.groups))
+	for _, g := range p.groups {
+		t.item()
+		t.list(1, thriftStruct, len(g.chunks))
+		for i, chunk := range g.chunks {
+			c := p.columns[i]
+			t.item()
+			t.i64(2, chunk.offset)
+			t.begin(3)
+			t.i32(1, c.kind)
+			t.list(2, thriftI32, 2)
+			t.varint(zigzag(0)) // PLAIN
+			t.varint(zigzag(3)) // RLE
+			t.list(3, thriftBinary, 1)
+			t.binary(c.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, chunk.count)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.end()
+			t.end()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.end()
+	}
+	t.str(6, "go-dicom")
+	return t.bytes()
+}
+
+// row returns the PLAIN encoded values of the column for df.
+func (c *parquetColumn) row(df *DicomFile) [][]byte {
+	values := [][]byte{}
+	for _, de := range c.selector.Select(df) {
+		e := *de
+		e.Load()
+		values = append(values, c.encode(&e)...)
+		if c.binary {
+			break
+		}
+	}
+	if c.repetition == parquetOptional && len(values) > 1 {
+		values = values[:1]
+	}
+	return values
+}
+
+func (c *parquetColumn) encode(de *DataElement) [][]byte {
+	values := [][]byte{}
+	u64 := func(v uint64) {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, v)
+		values = append(values, b)
+	}
+	switch {
+	case c.binary:
+		values = append(values, byteArray(de.Data))
+	case c.kind == parquetDouble:
+		for _, v := range de.Floats() {
+			u64(math.Float64bits(v))
+		}
+	case c.converted == parquetDate:
+		for _, s := range de.Strings() {
+			t, err := time.Parse("20060102", strings.Replace(s, ".", "", -1))
+			if err == nil {
+				b := make([]byte, 4)
+				binary.LittleEndian.PutUint32(b, uint32(int32(t.Unix()/86400)))
+				values = append(values, b)
+			}
+		}
+	case c.converted == parquetTimestampMicros:
+		for _, s := range de.Strings() {
+			t, err := parseDateTime(s, "20060102150405", "0101000000")
+			if err == nil {
+				u64(uint64(t.UnixNano() / 1000))
+			}
+		}
+	case c.kind == parquetInt64:
+		for _, v := range de.Floats() {
+			u64(uint64(int64(v)))
+		}
+	default:
+		for _, s := range de.Strings() {
+			values = append(values, byteArray([]byte(s)))
+		}
+	}
+	return values
+}
+
+// add appends a row of encoded values, without levels for required columns.
+func (c *parquetColumn) add(values [][]byte) {
+	if c.repetition == parquetRequired {
+		c.values = append(c.values, byteArray(values[0])...)
+		return
+	}
+	if len(values) == 0 {
+		c.defs = append(c.defs, 0)
+		c.reps = append(c.reps, 0)
+		return
+	}
+	for i, v := range values {
+		c.defs = append(c.defs, 1)
+		if i == 0 {
+			c.reps = append(c.reps, 0)
+		} else {
+			c.reps = append(c.reps, 1)
+		}
+		c.values = append(c.values, v...)
+	}
+}
+
+// byteArray returns b PLAIN encoded, prefixed with its length.
+func byteArray(b []byte) []byte {
+	v := make([]byte, 4, 4+len(b))
+	binary.LittleEndian.PutUint32(v, uint32(len(b)))
+	return append(v, b...)
+}
+
+// rleLevels returns levels of bit width 1 as RLE runs, prefixed with their
+// length.
+func rleLevels(levels []byte) []byte {
+	b := make([]byte, 4)
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		b = appendUvarint(b, uint64(j-i)<<1)
+		b = append(b, levels[i])
+		i = j
+	}
+	binary.LittleEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// selectorTags returns the top level tags of selectors, nil when one of them
+// starts with a wildcard.
+func selectorTags(selectors []Selector) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, s := range selectors {
+		if s[0].tag == "" {
+			return nil
+		}
+		if !seen[s[0].tag] {
+			seen[s[0].tag] = true
+			tags = append(tags, s[0].tag)
+		}
+	}
+	return tags
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter - Encoder of the Thrift compact protocol structures of the
+// Parquet metadata.
+type thriftWriter struct {
+	b []byte
+	// last field id of each nested struct
+	last []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		t.b = append(t.b, byte(d)<<4|typ)
+	} else {
+		t.b = append(t.b, typ)
+		t.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.b = appendUvarint(t.b, v)
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.varint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(zigzag(v))
+}
+
+func (t *thriftWriter) str(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.binary(s)
+}
+
+// binary writes a string without field header, as in lists.
+func (t *thriftWriter) binary(s string) {
+	t.varint(uint64(len(s)))
+	t.b = append(t.b, s...)
+}
+
+// list writes the header of a list of n elements of type elem.
+func (t *thriftWriter) list(id int16, elem byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.b = append(t.b, byte(n)<<4|elem)
+		return
+	}
+	t.b = append(t.b, 0xF0|elem)
+	t.varint(uint64(n))
+}
+
+// begin starts a struct field, item a struct element of a list, both ended
+// with end.
+func (t *thriftWriter) begin(id int16) {
+	t.field(id, thriftStruct)
+	t.item()
+}
+
+func (t *thriftWriter) item() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.b = append(t.b, 0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// bytes ends the top level struct and returns the encoding.
+func (t *thriftWriter) bytes() []byte {
+	t.end()
+	return t.b
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return append(b, buf[:binary.PutUvarint(buf, v)]...)
+}
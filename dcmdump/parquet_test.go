@@ -0,0 +1,73 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestParquetWriter(t *testing.T) {
+	item := func(code string) Item {
+		de, err := NewDataElement("00080100", "SH", code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		return Item{Elements: []DataElement{de}}
+	}
+	df := &DicomFile{}
+	df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY"})
+	df.SetElement("00080020", "DA", "20230315")
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00200013", "IS", 7)
+	df.SetElement("00281050", "DS", []string{"40", "400"})
+	df.SetElement("00321064", "SQ", []Item{item("CT1"), item("CT2")})
+
+	columns := []string{"PatientName", "ImageType", "StudyDate", "InstanceNumber", "WindowCenter", "RequestedProcedureCodeSequence[1].CodeValue", "PatientID"}
+	var buf bytes.Buffer
+	p, err := NewParquetWriter(&buf, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.RowGroupSize = 2
+	for _, path := range []string{"a.dcm", "b.dcm", "c.dcm"} {
+		if err = p.Write(path, df); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatalf("Missing magic numbers")
+	}
+	if n := binary.LittleEndian.Uint32(b[len(b)-8:]); int(n) > len(b)-12 {
+		t.Fatalf("Wrong footer length %d", n)
+	}
+	if len(p.groups) != 2 || p.total != 3 {
+		t.Errorf("Wrong row groups %+v", p.groups)
+	}
+	expected := []struct {
+		name       string
+		kind       int32
+		repetition int32
+	}{
+		{"Path", parquetByteArray, parquetRequired},
+		{"PatientName", parquetByteArray, parquetOptional},
+		{"ImageType", parquetByteArray, parquetRepeated},
+		{"StudyDate", parquetInt32, parquetOptional},
+		{"InstanceNumber", parquetInt64, parquetOptional},
+		{"WindowCenter", parquetDouble, parquetRepeated},
+		{"RequestedProcedureCodeSequence_1_CodeValue", parquetByteArray, parquetOptional},
+		{"PatientID", parquetByteArray, parquetOptional},
+	}
+	for i, c := range p.columns {
+		if c.name != expected[i].name || c.kind != expected[i].kind || c.repetition != expected[i].repetition {
+			t.Errorf("Wrong column %s %d %d", c.name, c.kind, c.repetition)
+		}
+	}
+	if !bytes.Contains(b, []byte("CT2")) || bytes.Contains(b, []byte("CT1")) {
+		t.Errorf("Wrong CodeValue column")
+	}
+}
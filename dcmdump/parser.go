@@ -0,0 +1,804 @@
+package dcmdump
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// Parser reads data elements sequentially from an io.Reader.
+// It keeps an internal buffered cursor so it can be fed from files, network
+// streams, gzip readers or in-memory buffers alike.
+type Parser struct {
+	r *bufio.Reader
+	n int
+
+	// Explicit sets whether the data set is encoded with explicit VR.
+	Explicit bool
+	// ByteOrder of the data set, File Meta Elements (group 0002) are always
+	// read as Explicit VR Little Endian.
+	ByteOrder binary.ByteOrder
+	// Tags is the list of tags to keep, an empty list keeps all elements.
+	Tags []string
+	// StopAt ends the parse once the element with the given tag is read.
+	StopAt string
+	// SkipPixelData leaves out the pixel data elements without reading their
+	// value.
+	SkipPixelData bool
+	// MetadataOnly ends the parse after the file meta group.
+	MetadataOnly bool
+	// Handler, when set, decides for each top level element whether it is
+	// kept, instead of Tags. It is called with the tag, VR and length set,
+	// before values of defined length are read.
+	Handler func(de *DataElement) WalkDecision
+	// Logger, when set, receives the diagnostics of the parse, nested data
+	// sets use the same Logger.
+	Logger Logger
+	// VerifyGroupLengths compares the declared group lengths (gggg,0000) with
+	// the size of their group, mismatches are logged as warnings.
+	VerifyGroupLengths bool
+	// BestEffort recovers from corrupt elements, like invalid VRs or lengths
+	// past the end of the data, by skipping to the next plausible tag.
+	// It reads the rest of the input in memory, deflated data sets can't be
+	// recovered.
+	BestEffort bool
+	// Warnings holds the errors recovered from in BestEffort mode and those
+	// that don't stop the parse, like ErrOddLength.
+	Warnings []error
+	// MaxElementSize, when above zero, ends the parse with ErrElementSize at
+	// the first element longer than it, undefined length values included.
+	MaxElementSize int
+	// MaxDepth, when above zero, ends the parse with ErrSequenceDepth at the
+	// first sequence nested deeper than it, top level sequences are at depth
+	// 1.
+	MaxDepth int
+	// MaxElements, when above zero, ends the parse with ErrElementCount once
+	// more elements are read, nested ones included.
+	MaxElements int
+	// ResolveUN reads the explicit UN elements with a known tag with their
+	// dictionary VR, values in the byte order of the data set and sequences
+	// as implicit VR little endian.
+	ResolveUN bool
+
+	// TransferSyntax is set once the TransferSyntaxUID (0002,0010) is read,
+	// Explicit and ByteOrder are switched to match it for the data set.
+	TransferSyntax string
+	// CharacterSet is set once the SpecificCharacterSet (0008,0005) is read,
+	// nested data sets inherit it.
+	CharacterSet []string
+
+	// LazyThreshold, when above zero with a Source, leaves the values longer
+	// than it unread. They are marked Deferred and read from Source on Load.
+	LazyThreshold int
+	// Source reads the file the parser is fed from, at the same offsets.
+	Source io.ReaderAt
+
+	// Context, when set, ends the parse with its error once it is done. It is
+	// checked before each top level element.
+	Context context.Context
+	// Progress, when set, is called after each top level element.
+	Progress func(ParseProgress)
+
+	inflated bool
+	// allowMissingPreamble lets parseFile read files starting with the first
+	// element.
+	allowMissingPreamble bool
+	// stopPast also ends the parse at the first element past StopAt.
+	stopPast bool
+	// signed is set once a PixelRepresentation of 1 is read, the US or SS
+	// attributes of implicit VR data sets are then read as SS.
+	signed bool
+	// creators maps private blocks to their private creator.
+	creators map[[2]uint16]string
+	// scratch holds element lengths while they are decoded.
+	scratch [8]byte
+	// elements counts the top level elements read.
+	elements int
+	// depth is the sequence nesting of the data set, nesting the one of the
+	// undefined length sequences being read.
+	depth, nesting int
+	// total counts the elements read, shared with the nested parsers.
+	total *int
+}
+
+// ParseProgress - Progress of a parse, given to Parser.Progress.
+type ParseProgress struct {
+	// Bytes is the offset reached in the input.
+	Bytes int
+	// Elements read at the top level, kept or not.
+	Elements int
+}
+
+// NewParser returns a Parser reading from r.
+// offset is the position of the first byte of r within the file so element
+// offsets match the ones on disk.
+func NewParser(r io.Reader, offset int, explicit bool, tags []string) *Parser {
+	return &Parser{
+		r:         bufio.NewReader(r),
+		n:         offset,
+		Explicit:  explicit,
+		ByteOrder: binary.LittleEndian,
+		Tags:      tags,
+		StopAt:    "0020000E",
+	}
+}
+
+// WalkDecision - Decision of a Parser Handler about an element.
+type WalkDecision int
+
+// Walk decisions
+const (
+	KeepElement WalkDecision = iota // Read the value and return the element
+	SkipElement                     // Skip the value, the element is not returned
+	StopParse                       // End the parse before the element
+)
+
+// ParseOptions - Options of ParseFileWithOptions.
+type ParseOptions struct {
+	// StopAtTag ends the parse once the element is read, or at the first
+	// element past it. The whole file is read when empty.
+	StopAtTag string
+	// SkipPixelData leaves out the pixel data elements.
+	SkipPixelData bool
+	// MetadataOnly only reads the file meta group.
+	MetadataOnly bool
+	// Tags to keep, all elements are kept when empty.
+	Tags []string
+	// Logger receives the diagnostics of the parse.
+	Logger Logger
+	// VerifyGroupLengths logs a warning for each group length that doesn't
+	// match the size of its group.
+	VerifyGroupLengths bool
+	// AllowMissingPreamble reads files without preamble nor DICM marker when
+	// they start with a plausible file meta or data set element.
+	AllowMissingPreamble bool
+	// BestEffort returns the elements that could be read from corrupt files,
+	// the skipped errors are set as the Warnings of the file.
+	BestEffort bool
+	// Context cancels the parse, the elements read so far are returned with
+	// its error.
+	Context context.Context
+	// Progress is called after each top level element.
+	Progress func(ParseProgress)
+	// ImplicitVR reads data sets without TransferSyntaxUID as implicit VR,
+	// they are read as explicit VR otherwise.
+	ImplicitVR bool
+	// ByteOrder of data sets without TransferSyntaxUID, little endian when
+	// nil.
+	ByteOrder binary.ByteOrder
+	// MaxElementSize, MaxDepth and MaxElements limit the resources used by
+	// the parse of untrusted files, see Parser.
+	MaxElementSize int
+	MaxDepth       int
+	MaxElements    int
+	// ResolveUN reads UN elements with their dictionary VR, see Parser.
+	ResolveUN bool
+}
+
+// ParseFile reads a whole file with its preamble and file meta group.
+func ParseFile(r io.Reader) (*DicomFile, error) {
+	return parseFile(r, nil, 0)
+}
+
+// ParseFileWithOptions reads a file with its preamble, up to the part
+// selected by the options.
+func ParseFileWithOptions(r io.Reader, o ParseOptions) (*DicomFile, error) {
+	return parseFile(r, nil, 0, func(p *Parser) {
+		p.StopAt = strings.ToUpper(o.StopAtTag)
+		p.stopPast = true
+		p.SkipPixelData = o.SkipPixelData
+		p.MetadataOnly = o.MetadataOnly
+		p.Logger = o.Logger
+		p.VerifyGroupLengths = o.VerifyGroupLengths
+		p.allowMissingPreamble = o.AllowMissingPreamble
+		p.BestEffort = o.BestEffort
+		p.Context = o.Context
+		p.Progress = o.Progress
+		p.Explicit = !o.ImplicitVR
+		if o.ByteOrder != nil {
+			p.ByteOrder = o.ByteOrder
+		}
+		p.MaxElementSize = o.MaxElementSize
+		p.MaxDepth = o.MaxDepth
+		p.MaxElements = o.MaxElements
+		p.ResolveUN = o.ResolveUN
+		if o.Tags != nil {
+			p.Tags = o.Tags
+		}
+	})
+}
+
+// ParseFileLazy reads a whole file like ParseFile but values longer than
+// threshold bytes are deferred, r has to be kept open until they are loaded.
+func ParseFileLazy(r io.ReaderAt, threshold int) (*DicomFile, error) {
+	return parseFile(io.NewSectionReader(r, 0, 1<<62), r, threshold)
+}
+
+func parseFile(r io.Reader, source io.ReaderAt, threshold int, options ...func(*Parser)) (*DicomFile, error) {
+	df := &DicomFile{}
+	p := NewParser(r, 0, true, []string{})
+	p.StopAt = ""
+	p.Source = source
+	p.LazyThreshold = threshold
+	for _, o := range options {
+		o(p)
+	}
+	b, err := p.r.Peek(132)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(b) == 132 && string(b[128:]) == "DICM" {
+		copy(df.Preamble[:], b)
+		err = p.skip(132)
+		if err != nil {
+			return nil, err
+		}
+	} else if !p.allowMissingPreamble || !p.sniffDataset(b) {
+		return nil, ErrNotDICM
+	}
+	df.Elements, err = p.Parse()
+	df.TransferSyntax = p.TransferSyntax
+	df.Warnings = p.Warnings
+	return df, err
+}
+
+// sniffDataset reports whether b starts with a file meta (0002) or an
+// identifying (0008) group element, as found in files without preamble.
+// The encoding of data sets without file meta group is guessed from it.
+func (p *Parser) sniffDataset(b []byte) bool {
+	if len(b) < 8 {
+		return false
+	}
+	_, explicit := vri.VR[string(b[4:6])]
+	switch {
+	case binary.LittleEndian.Uint16(b) == 0x0002:
+		// The file meta group sets the transfer syntax
+		return explicit
+	case binary.LittleEndian.Uint16(b) == 0x0008 && explicit:
+		p.setTransferSyntax(ts.ExplicitVRLittleEndian)
+	case binary.LittleEndian.Uint16(b) == 0x0008:
+		p.setTransferSyntax(ts.ImplicitVRLittleEndian)
+	case binary.BigEndian.Uint16(b) == 0x0008 && explicit:
+		p.setTransferSyntax(ts.ExplicitVRBigEndian)
+	default:
+		return false
+	}
+	return true
+}
+
+// ParseWithHandler reads a file with its preamble, handler decides which
+// elements of the data set are kept and when to stop.
+func ParseWithHandler(r io.Reader, handler func(de *DataElement) WalkDecision) (*DicomFile, error) {
+	return parseFile(r, nil, 0, func(p *Parser) {
+		p.Handler = handler
+	})
+}
+
+// ParseDataset reads all the elements of a data set without file meta group,
+// as sent over the network, encoded with the given transfer syntax.
+func ParseDataset(r io.Reader, transferSyntax string) ([]DataElement, error) {
+	p := NewParser(r, 0, true, []string{})
+	p.StopAt = ""
+	p.setTransferSyntax(transferSyntax)
+	return p.Parse()
+}
+
+// canceled returns the error of the Context once it is done.
+func (p *Parser) canceled() error {
+	if p.Context == nil {
+		return nil
+	}
+	return p.Context.Err()
+}
+
+// progress reports an element read to Progress.
+func (p *Parser) progress() {
+	p.elements++
+	if p.Progress != nil {
+		p.Progress(ParseProgress{Bytes: p.n, Elements: p.elements})
+	}
+}
+
+// Offset returns the current position of the cursor.
+func (p *Parser) Offset() int {
+	return p.n
+}
+
+// Parse reads data elements until the end of the reader.
+func (p *Parser) Parse() ([]DataElement, error) {
+	if p.BestEffort {
+		return p.parseBestEffort()
+	}
+	return parseDataElement(p, p.Explicit)
+}
+
+// limitError reports whether err is a resource limit, which isn't recovered
+// from.
+func limitError(err error) bool {
+	return errors.Is(err, ErrElementSize) || errors.Is(err, ErrSequenceDepth) || errors.Is(err, ErrElementCount)
+}
+
+// parseBestEffort parses the rest of the input, restarting past each element
+// that fails at the next plausible tag.
+func (p *Parser) parseBestEffort() ([]DataElement, error) {
+	start := p.n
+	data, err := ioutil.ReadAll(p.r)
+	if err != nil {
+		return nil, err
+	}
+	elements := []DataElement{}
+	offset := start
+	for {
+		p.r = bufio.NewReader(bytes.NewReader(data[offset-start:]))
+		p.n = offset
+		found, err := parseDataElement(p, p.Explicit)
+		elements = append(elements, found...)
+		var pe *ParseError
+		if err == nil || p.inflated || !errors.As(err, &pe) || limitError(err) {
+			return elements, err
+		}
+		p.Warnings = append(p.Warnings, err)
+		p.logf(LevelWarn, "%d Skipping corrupt element: %s", pe.Offset, err)
+		last := ""
+		if len(elements) > 0 {
+			last = elements[len(elements)-1].TagStr
+		}
+		from := offset + 1
+		if pe.Offset >= offset {
+			from = pe.Offset + 1
+		}
+		offset = p.resync(data, start, from, last)
+		if offset < 0 {
+			return elements, nil
+		}
+	}
+}
+
+// resync returns the offset of the first plausible element header at or past
+// from, -1 when there is none.
+// data holds the input from offset start.
+func (p *Parser) resync(data []byte, start, from int, last string) int {
+	for i := from; i-start+8 <= len(data); i++ {
+		if p.plausibleTag(data[i-start:], last) {
+			return i
+		}
+	}
+	return -1
+}
+
+// plausibleTag reports whether b starts with an element header past the last
+// tag read whose value fits in b.
+// Implicit VR tags also have to be in the dictionary.
+func (p *Parser) plausibleTag(b []byte, last string) bool {
+	order, explicit := p.ByteOrder, p.Explicit
+	if b[0] == 0x02 && b[1] == 0x00 {
+		order, explicit = binary.LittleEndian, true
+	}
+	h, n, err := DecodeElementHeader(b, explicit, order)
+	t := h.Tag()
+	if err != nil || h.Group == 0xFFFE || t <= last {
+		return false
+	}
+	if !explicit {
+		if _, ok := tag.Tag[t]; !ok {
+			return false
+		}
+	} else if _, ok := vri.VR[h.VR]; !ok {
+		return false
+	} else if n == 12 && (b[6] != 0 || b[7] != 0) {
+		return false
+	}
+	return h.Undefined() || int64(n)+int64(h.Length) <= int64(len(b))
+}
+
+// sub returns a Parser over the already read value of a sequence or item.
+// Tags and StopAt only apply to the top level data set, nested elements are
+// all kept.
+func (p *Parser) sub(data []byte, offset int) *Parser {
+	s := NewParser(bytes.NewReader(data), offset, p.Explicit, []string{})
+	s.ByteOrder = p.ByteOrder
+	s.CharacterSet = p.CharacterSet
+	s.Logger = p.Logger
+	s.signed = p.signed
+	s.VerifyGroupLengths = p.VerifyGroupLengths
+	s.MaxElementSize = p.MaxElementSize
+	s.MaxDepth = p.MaxDepth
+	s.MaxElements = p.MaxElements
+	s.ResolveUN = p.ResolveUN
+	s.depth = p.depth
+	s.total = p.total
+	s.inflated = p.inflated
+	s.StopAt = ""
+	return s
+}
+
+// groupLength - Declared length of the group being read.
+type groupLength struct {
+	group    string
+	declared uint32
+	// start is the offset of the first element after the group length.
+	start int
+}
+
+// verifyGroupLength logs a warning when the group of gl ends at offset, that
+// is when group differs, with a size other than the declared one.
+func (p *Parser) verifyGroupLength(gl *groupLength, group string, offset int) {
+	if !p.VerifyGroupLengths || gl.group == "" || gl.group == group {
+		return
+	}
+	if actual := offset - gl.start; actual != int(gl.declared) {
+		p.logf(LevelWarn, "%d Group length mismatch for group '%s': declared %d, actual %d", gl.start, gl.group, gl.declared, actual)
+	}
+	gl.group = ""
+}
+
+// vrStrings interns the VRs so element headers don't allocate them.
+var vrStrings = func() map[[2]byte]string {
+	m := map[[2]byte]string{}
+	for vr := range vri.VR {
+		if len(vr) == 2 {
+			m[[2]byte{vr[0], vr[1]}] = vr
+		}
+	}
+	return m
+}()
+
+// vrString returns b as a string.
+func vrString(b []byte) string {
+	if vr, ok := vrStrings[[2]byte{b[0], b[1]}]; ok {
+		return vr
+	}
+	return string(b)
+}
+
+// pixelValueVRs are the attributes with a US or SS VR depending on the
+// PixelRepresentation.
+var pixelValueVRs = stringSlice{"00280106", "00280107", "00280108", "00280109", "00280120"}
+
+// implicitVR returns the dictionary VR of an element of an implicit VR data
+// set, empty for unknown tags, items and delimiters.
+func (p *Parser) implicitVR(group, elem uint16, tagStr string) string {
+	switch {
+	case group == 0xFFFE:
+		return ""
+	case elem == 0:
+		// Group length
+		return "UL"
+	case tag.IsPrivateCreator(group, elem):
+		return "LO"
+	case pixelValueVRs.contains(tagStr) && p.signed:
+		return "SS"
+	}
+	if block, ok := tag.PrivateBlock(group, elem); ok {
+		info, _ := tag.FindPrivate(p.creators[[2]uint16{group, block}], group, elem)
+		return info.VR
+	}
+	info, _ := tag.Find(group, elem)
+	return info.VR
+}
+
+func (p *Parser) setTransferSyntax(uid string) {
+	uid = strings.TrimRight(uid, "\x00 ")
+	p.TransferSyntax = uid
+	p.Explicit = ts.Explicit(uid)
+	p.ByteOrder = ts.ByteOrder(uid)
+}
+
+// inflate wraps the reader in a flate reader once the end of the file meta
+// group is reached on a deflated transfer syntax.
+// Offsets past that point are positions in the inflated data set.
+func (p *Parser) inflate() {
+	if p.inflated || p.TransferSyntax != ts.DeflatedExplicitVRLittleEndian {
+		return
+	}
+	b, err := p.r.Peek(2)
+	if err != nil || (b[0] == 0x02 && b[1] == 0x00) {
+		return
+	}
+	p.inflated = true
+	p.r = bufio.NewReader(flate.NewReader(p.r))
+}
+
+// decide returns whether the element is kept and whether the parse stops
+// before it.
+func (p *Parser) decide(de *DataElement) (keep, stop bool) {
+	if p.Handler == nil {
+		return p.keep(de.TagStr), false
+	}
+	return p.handle(*de)
+}
+
+// handle calls the Handler with a copy of the element so parsed elements
+// don't escape to the heap when there is no Handler.
+func (p *Parser) handle(de DataElement) (keep, stop bool) {
+	switch p.Handler(&de) {
+	case SkipElement:
+		return false, false
+	case StopParse:
+		return false, true
+	}
+	return true, false
+}
+
+// keep reports whether the element with the given tag is returned.
+func (p *Parser) keep(tagStr string) bool {
+	if p.SkipPixelData {
+		switch tagStr {
+		case "7FE00008", "7FE00009", "7FE00010":
+			return false
+		}
+	}
+	return stringInSlice(tagStr, p.Tags)
+}
+
+// endOfMeta reports whether a metadata only parse reached the data set.
+func (p *Parser) endOfMeta() bool {
+	if !p.MetadataOnly {
+		return false
+	}
+	b, err := p.r.Peek(2)
+	return err == nil && (b[0] != 0x02 || b[1] != 0x00)
+}
+
+// deferValue reports whether the value of de is left unread.
+// Deflated data sets are read from the inflated stream that Source can't
+// seek into.
+func (p *Parser) deferValue(de *DataElement) bool {
+	return p.lazy() && int(de.Len) > p.LazyThreshold
+}
+
+// lazy reports whether values over LazyThreshold can be read from Source.
+func (p *Parser) lazy() bool {
+	return p.LazyThreshold > 0 && p.Source != nil && !p.inflated
+}
+
+// readChunk is the size up to which values are allocated at once, longer
+// ones grow as they are read so truncated files declaring huge lengths don't
+// allocate them.
+const readChunk = 1 << 20
+
+func (p *Parser) readN(size int) ([]byte, error) {
+	if size <= readChunk {
+		buf := make([]byte, size)
+		n, err := io.ReadFull(p.r, buf)
+		p.n += n
+		return buf[:n], err
+	}
+	buf := make([]byte, 0, readChunk)
+	for len(buf) < size {
+		chunk := size - len(buf)
+		if chunk > readChunk {
+			chunk = readChunk
+		}
+		start := len(buf)
+		buf = append(buf, make([]byte, chunk)...)
+		n, err := io.ReadFull(p.r, buf[start:])
+		p.n += n
+		if err != nil {
+			return buf[:start+n], err
+		}
+	}
+	return buf, nil
+}
+
+// readFull fills b, returning io.EOF when nothing could be read.
+func (p *Parser) readFull(b []byte) error {
+	n, err := io.ReadFull(p.r, b)
+	p.n += n
+	return err
+}
+
+// readScratch reads size bytes, up to 8, into a buffer reused by the next
+// call.
+func (p *Parser) readScratch(size int) ([]byte, error) {
+	err := p.readFull(p.scratch[:size])
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return p.scratch[:size], err
+}
+
+func (p *Parser) skip(size int) error {
+	n, err := p.r.Discard(size)
+	p.n += n
+	return err
+}
+
+// readFragments reads the items of encapsulated pixel data up to the
+// SequenceDelimitationItem.
+// It returns the raw items, the delimitation tag is consumed.
+// The item lengths are followed so fragment data matching a delimitation
+// tag is not mistaken for the end of the value.
+// With a limit above zero the items are discarded once they reach limit bytes
+// and a nil value is returned.
+func (p *Parser) readFragments(limit int) ([]byte, error) {
+	buf := []byte{}
+	for {
+		t, err := p.readN(4)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return buf, err
+		}
+		switch tagString(t, binary.LittleEndian) {
+		case "FFFEE0DD":
+			return buf, nil
+		case "FFFEE000":
+		default:
+			return buf, ErrMissingDelimiter
+		}
+		l, err := p.readN(4)
+		if err != nil {
+			return buf, io.ErrUnexpectedEOF
+		}
+		size := int(binary.LittleEndian.Uint32(l))
+		if p.MaxElementSize > 0 && len(buf)+8+size > p.MaxElementSize {
+			return buf, ErrElementSize
+		}
+		if buf == nil || limit > 0 && len(buf)+8+size > limit {
+			buf = nil
+			if p.skip(size) != nil {
+				return buf, io.ErrUnexpectedEOF
+			}
+			continue
+		}
+		value, err := p.readN(size)
+		if err != nil {
+			return buf, io.ErrUnexpectedEOF
+		}
+		buf = append(buf, t...)
+		buf = append(buf, l...)
+		buf = append(buf, value...)
+	}
+}
+
+// readUndefined reads the value of an undefined length sequence, or item when
+// item is set, up to its delimitation item.
+// It returns the raw value, the delimitation tag is consumed.
+// The lengths of nested elements are followed so the delimiters of nested
+// undefined length sequences and items aren't mistaken for the outer one.
+// explicit is the encoding of the nested elements.
+func (p *Parser) readUndefined(item, explicit bool) ([]byte, error) {
+	buf := []byte{}
+	var err error
+	if item {
+		err = p.readItemContent(&buf, explicit, true)
+	} else {
+		err = p.readItems(&buf, explicit, true)
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+// readItems appends the items of a sequence to buf, up to and including the
+// SequenceDelimitationItem unless top is set.
+func (p *Parser) readItems(buf *[]byte, explicit, top bool) error {
+	p.nesting++
+	defer func() { p.nesting-- }()
+	if p.MaxDepth > 0 && p.depth+p.nesting > p.MaxDepth {
+		return ErrSequenceDepth
+	}
+	for {
+		t, err := p.readN(4)
+		if err != nil {
+			return err
+		}
+		switch tagString(t, p.ByteOrder) {
+		case "FFFEE0DD":
+			if top {
+				return nil
+			}
+			*buf = append(*buf, t...)
+			return p.readInto(buf, 4)
+		case "FFFEE000":
+		default:
+			return ErrMissingDelimiter
+		}
+		*buf = append(*buf, t...)
+		l, err := p.readN(4)
+		if err != nil {
+			return err
+		}
+		*buf = append(*buf, l...)
+		length := p.ByteOrder.Uint32(l)
+		if length == 0xFFFFFFFF {
+			err = p.readItemContent(buf, explicit, false)
+		} else {
+			err = p.readInto(buf, int(length))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readItemContent appends the elements of an undefined length item to buf,
+// up to and including the ItemDelimitationItem unless top is set.
+// A top level item also ends at a SequenceDelimitationItem.
+func (p *Parser) readItemContent(buf *[]byte, explicit, top bool) error {
+	for {
+		t, err := p.readN(4)
+		if err != nil {
+			return err
+		}
+		tagStr := tagString(t, p.ByteOrder)
+		switch {
+		case tagStr == "FFFEE00D" && top, tagStr == "FFFEE0DD" && top:
+			return nil
+		case tagStr == "FFFEE00D":
+			*buf = append(*buf, t...)
+			return p.readInto(buf, 4)
+		case tagStr == "FFFEE0DD":
+			return ErrMissingDelimiter
+		}
+		*buf = append(*buf, t...)
+		var length uint32
+		vr := ""
+		if explicit && tagStr[:4] != "FFFE" {
+			v, err := p.readN(2)
+			if err != nil {
+				return err
+			}
+			*buf = append(*buf, v...)
+			vr = string(v)
+			if vri.LongLength(vr) {
+				l, err := p.readN(6)
+				if err != nil {
+					return err
+				}
+				*buf = append(*buf, l...)
+				length = p.ByteOrder.Uint32(l[2:])
+			} else {
+				l, err := p.readN(2)
+				if err != nil {
+					return err
+				}
+				*buf = append(*buf, l...)
+				length = uint32(p.ByteOrder.Uint16(l))
+			}
+		} else {
+			l, err := p.readN(4)
+			if err != nil {
+				return err
+			}
+			*buf = append(*buf, l...)
+			length = p.ByteOrder.Uint32(l)
+		}
+		if length == 0xFFFFFFFF {
+			// Sequences and encapsulated pixel data, UN sequences are
+			// implicit VR little endian.
+			err = p.readItems(buf, explicit && vr != "UN", false)
+		} else {
+			err = p.readInto(buf, int(length))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readInto appends size bytes to buf.
+func (p *Parser) readInto(buf *[]byte, size int) error {
+	if p.MaxElementSize > 0 && len(*buf)+size > p.MaxElementSize {
+		return ErrElementSize
+	}
+	b, err := p.readN(size)
+	if err != nil {
+		return err
+	}
+	*buf = append(*buf, b...)
+	return nil
+}
@@ -0,0 +1,724 @@
+package dcmdump
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// explicitElement encodes a single explicit VR little endian element.
+func explicitElement(group, elem uint16, vr string, value []byte) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint16(b[0:], group)
+	binary.LittleEndian.PutUint16(b[2:], elem)
+	b = append(b, vr...)
+	if vri.LongLength(vr) {
+		l := make([]byte, 6)
+		binary.LittleEndian.PutUint32(l[2:], uint32(len(value)))
+		b = append(b, l...)
+	} else {
+		l := make([]byte, 2)
+		binary.LittleEndian.PutUint16(l, uint16(len(value)))
+		b = append(b, l...)
+	}
+	return append(b, value...)
+}
+
+func TestParserReader(t *testing.T) {
+	data := []byte{}
+	data = append(data, explicitElement(0x0008, 0x0060, "CS", []byte("CT"))...)
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))...)
+	data = append(data, explicitElement(0x0028, 0x0010, "US", []byte{0x00, 0x02})...)
+
+	elements, err := NewParser(bytes.NewReader(data), 132, true, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 3 {
+		t.Fatalf("Expected 3 elements, got %d", len(elements))
+	}
+	if elements[0].N != 132 || elements[1].N != 142 {
+		t.Errorf("Wrong offsets: %d, %d", elements[0].N, elements[1].N)
+	}
+	if elements[1].Name != "PatientName" || elements[1].StringData() != "DOE^JOHN" {
+		t.Errorf("Wrong element: %s", elements[1].String())
+	}
+	if elements[2].StringData() != "512 " {
+		t.Errorf("Wrong US value: '%s'", elements[2].StringData())
+	}
+
+	elements, err = NewParser(bytes.NewReader(data), 0, true, []string{"00100010"}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 1 || elements[0].TagStr != "00100010" {
+		t.Errorf("Tag filter failed: %v", elements)
+	}
+}
+
+func TestParserUndefinedLength(t *testing.T) {
+	item := explicitElement(0x0008, 0x1150, "UI", []byte("1.2.3\x00"))
+	data := []byte{}
+	// SQ with undefined length
+	data = append(data, 0x08, 0x00, 0x15, 0x11, 'S', 'Q', 0, 0, 0xff, 0xff, 0xff, 0xff)
+	// Item with undefined length
+	data = append(data, 0xfe, 0xff, 0x00, 0xe0, 0xff, 0xff, 0xff, 0xff)
+	data = append(data, item...)
+	// ItemDelimitationItem
+	data = append(data, 0xfe, 0xff, 0x0d, 0xe0, 0, 0, 0, 0)
+	// SequenceDelimitationItem
+	data = append(data, 0xfe, 0xff, 0xdd, 0xe0, 0, 0, 0, 0)
+	data = append(data, explicitElement(0x0010, 0x0020, "LO", []byte("ID01"))...)
+
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 2 {
+		t.Fatalf("Expected 2 elements, got %d", len(elements))
+	}
+	if elements[0].TagStr != "00081115" || elements[0].Len != uint32(8+len(item)+8) {
+		t.Errorf("Wrong sequence: %s", elements[0].String())
+	}
+	if len(elements[0].Items) != 1 || len(elements[0].Items[0].Elements) != 1 {
+		t.Fatalf("Wrong sequence items: %v", elements[0].Items)
+	}
+	child := elements[0].Items[0].Elements[0]
+	if child.TagStr != "00081150" || child.StringData() != "1.2.3" || !child.PartOfSQ {
+		t.Errorf("Wrong item element: %s", child.String())
+	}
+	if elements[1].TagStr != "00100020" || elements[1].StringData() != "ID01" {
+		t.Errorf("Wrong element after sequence: %s", elements[1].String())
+	}
+}
+
+func TestParseError(t *testing.T) {
+	data := explicitElement(0x0008, 0x0060, "CS", []byte("CT"))
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))...)
+
+	_, err := NewParser(bytes.NewReader(data[:len(data)-2]), 0, true, []string{}).Parse()
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Expected ParseError, got %v", err)
+	}
+	if pe.Offset != 10 || pe.Tag != "00100010" || pe.VR != "PN" || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Wrong error context: %s", err)
+	}
+
+	data[14], data[15] = 'X', 'X'
+	_, err = NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if !errors.Is(err, ErrUnknownVR) {
+		t.Errorf("Expected ErrUnknownVR, got %v", err)
+	}
+}
+
+func TestParserPrivateTags(t *testing.T) {
+	data := explicitElement(0x0029, 0x0011, "LO", []byte("SIEMENS CSA HEADER"))
+	data = append(data, explicitElement(0x0029, 0x1110, "OB", []byte{1, 2})...)
+	data = append(data, explicitElement(0x0029, 0x1210, "OB", []byte{1, 2})...)
+
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{"00291110", "00291210"}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 2 || elements[0].Name != "CSAImageHeaderInfo" || elements[1].Name != "" {
+		t.Errorf("Wrong private tag names: %v", elements)
+	}
+}
+
+func TestParseFileLazy(t *testing.T) {
+	data := sampleFile()
+	df, err := ParseFileLazy(bytes.NewReader(data), 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, de := range df.Elements {
+		deferred := de.TagStr == "00100010" || de.TagStr == "7FE00010"
+		if de.Deferred != deferred || deferred && de.Data != nil {
+			t.Errorf("%s: expected deferred %v, got %v with %v", de.TagStr, deferred, de.Deferred, de.Data)
+		}
+	}
+	de, _ := df.LookupElement("7FE00010")
+	err = de.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !bytes.Equal(de.Data, []byte{1, 2, 3, 4}) || de.Deferred {
+		t.Errorf("Wrong loaded value %v", de.Data)
+	}
+
+	var buf bytes.Buffer
+	err = df.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("Deferred values not written:\n%x\n%x", buf.Bytes(), data)
+	}
+}
+
+func TestParseFileWithOptions(t *testing.T) {
+	data := sampleFile()
+	for _, c := range []struct {
+		options  ParseOptions
+		expected []string
+	}{
+		{ParseOptions{}, []string{"00020000", "00020010", "00081115", "00100010", "00280010", "7FE00010"}},
+		{ParseOptions{MetadataOnly: true}, []string{"00020000", "00020010"}},
+		{ParseOptions{SkipPixelData: true}, []string{"00020000", "00020010", "00081115", "00100010", "00280010"}},
+		{ParseOptions{StopAtTag: "00100010"}, []string{"00020000", "00020010", "00081115", "00100010"}},
+		// Not in the file, the parse ends at the next element
+		{ParseOptions{StopAtTag: "0020000E"}, []string{"00020000", "00020010", "00081115", "00100010"}},
+		{ParseOptions{Tags: []string{"00100010"}}, []string{"00100010"}},
+	} {
+		df, err := ParseFileWithOptions(bytes.NewReader(data), c.options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		tags := []string{}
+		for _, de := range df.Elements {
+			tags = append(tags, de.TagStr)
+		}
+		if strings.Join(tags, " ") != strings.Join(c.expected, " ") {
+			t.Errorf("%+v: expected %v, got %v", c.options, c.expected, tags)
+		}
+	}
+}
+
+func TestParseWithHandler(t *testing.T) {
+	seen := []string{}
+	df, err := ParseWithHandler(bytes.NewReader(sampleFile()), func(de *DataElement) WalkDecision {
+		seen = append(seen, de.TagStr)
+		if de.Data != nil && de.TagStr[:4] != "0002" {
+			t.Errorf("%s: value read before the decision", de.TagStr)
+		}
+		switch {
+		case de.TagStr == "00081115":
+			return SkipElement
+		case de.TagStr == "00280010" && de.Len == 2:
+			return StopParse
+		}
+		return KeepElement
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if strings.Join(seen, " ") != "00020000 00020010 00081115 00100010 00280010" {
+		t.Errorf("Wrong elements seen %v", seen)
+	}
+	tags := []string{}
+	for _, de := range df.Elements {
+		tags = append(tags, de.TagStr)
+	}
+	if strings.Join(tags, " ") != "00020000 00020010 00100010" {
+		t.Errorf("Wrong elements kept %v", tags)
+	}
+	if df.TransferSyntax != ts.ExplicitVRLittleEndian {
+		t.Errorf("Wrong transfer syntax %s", df.TransferSyntax)
+	}
+}
+
+func TestParserLogger(t *testing.T) {
+	data := sampleFile()
+	data = append(data, explicitElement(0x0010, 0xFFF0, "LO", []byte("X "))...)
+	data = append(data, 0x10, 0x00, 0xf2, 0xff, 0, 0, 0, 0)
+	var buf bytes.Buffer
+	_, err := ParseFileWithOptions(bytes.NewReader(data), ParseOptions{Logger: NewLogger(&buf, LevelInfo)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "INFO: 248 Missing tag '0010FFF0'\nINFO: 258 Missing tag '0010FFF2'\nWARN: 258 Blank VR for tag '0010FFF2'\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	levels := map[Level]int{}
+	_, err = ParseFileWithOptions(bytes.NewReader(sampleFile()), ParseOptions{Logger: LoggerFunc(func(level Level, format string, args ...interface{}) {
+		levels[level]++
+	})})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// Every element, including the nested ones, logs its length
+	if levels[LevelDebug] != 8 || len(levels) != 1 {
+		t.Errorf("Wrong log levels: %v", levels)
+	}
+}
+
+func TestParserVerifyGroupLengths(t *testing.T) {
+	data := sampleFile()[:172]
+	data = append(data, explicitElement(0x0008, 0x0000, "UL", []byte{99, 0, 0, 0})...)
+	data = append(data, explicitElement(0x0008, 0x0060, "CS", []byte("CT"))...)
+	data = append(data, explicitElement(0x0010, 0x0000, "UL", []byte{4, 0, 0, 0})...)
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))...)
+	var buf bytes.Buffer
+	_, err := ParseFileWithOptions(bytes.NewReader(data), ParseOptions{Logger: NewLogger(&buf, LevelWarn), VerifyGroupLengths: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "WARN: 184 Group length mismatch for group '0008': declared 99, actual 10\n" +
+		"WARN: 206 Group length mismatch for group '0010': declared 4, actual 16\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	_, err = ParseFileWithOptions(bytes.NewReader(data), ParseOptions{Logger: NewLogger(&buf, LevelWarn)})
+	if err != nil || buf.Len() != 0 {
+		t.Errorf("Group lengths verified without the option: %s, %v", buf.String(), err)
+	}
+}
+
+func TestParseFileMissingPreamble(t *testing.T) {
+	implicit := []byte{0x08, 0x00, 0x60, 0x00, 2, 0, 0, 0, 'C', 'T'}
+	implicit = append(implicit, 0x10, 0x00, 0x10, 0x00, 8, 0, 0, 0)
+	implicit = append(implicit, "DOE^JOHN"...)
+	for _, c := range []struct {
+		name           string
+		data           []byte
+		transferSyntax string
+		elements       int
+	}{
+		{"meta", sampleFile()[132:], ts.ExplicitVRLittleEndian, 6},
+		{"explicit", explicitElement(0x0008, 0x0060, "CS", []byte("CT")), ts.ExplicitVRLittleEndian, 1},
+		{"implicit", implicit, ts.ImplicitVRLittleEndian, 2},
+	} {
+		_, err := ParseFile(bytes.NewReader(c.data))
+		if err != ErrNotDICM {
+			t.Errorf("%s: expected ErrNotDICM, got %v", c.name, err)
+		}
+		df, err := ParseFileWithOptions(bytes.NewReader(c.data), ParseOptions{AllowMissingPreamble: true})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+		if df.TransferSyntax != c.transferSyntax || len(df.Elements) != c.elements || df.Elements[0].N != 0 {
+			t.Errorf("%s: wrong parse %s: %v", c.name, df.TransferSyntax, df.Elements)
+		}
+	}
+	_, err := ParseFileWithOptions(bytes.NewReader([]byte("not a dicom file")), ParseOptions{AllowMissingPreamble: true})
+	if err != ErrNotDICM {
+		t.Errorf("Expected ErrNotDICM, got %v", err)
+	}
+}
+
+func TestParseBestEffort(t *testing.T) {
+	badVR := explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))
+	badVR[4], badVR[5] = 'X', 'X'
+	pastEOF := explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))
+	pastEOF[6] = 200
+	for _, c := range []struct {
+		name    string
+		corrupt []byte
+	}{
+		{"invalid VR", badVR},
+		{"length past EOF", pastEOF},
+	} {
+		data := sampleFile()[:172]
+		data = append(data, explicitElement(0x0008, 0x0060, "CS", []byte("CT"))...)
+		data = append(data, c.corrupt...)
+		data = append(data, explicitElement(0x0010, 0x0020, "LO", []byte("ID"))...)
+		data = append(data, explicitElement(0x0020, 0x0013, "IS", []byte("1 "))...)
+
+		_, err := ParseFile(bytes.NewReader(data))
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+		var buf bytes.Buffer
+		df, err := ParseFileWithOptions(bytes.NewReader(data), ParseOptions{BestEffort: true, Logger: NewLogger(&buf, LevelWarn)})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+		tags := []string{}
+		for _, de := range df.Elements {
+			tags = append(tags, de.TagStr)
+		}
+		expected := []string{"00020000", "00020010", "00080060", "00100020", "00200013"}
+		if !reflect.DeepEqual(tags, expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, expected, tags)
+		}
+		var pe *ParseError
+		if len(df.Warnings) != 1 || !errors.As(df.Warnings[0], &pe) || pe.Offset != 182 {
+			t.Errorf("%s: wrong warnings: %v", c.name, df.Warnings)
+		}
+		if !strings.Contains(buf.String(), "WARN: 182 Skipping corrupt element") {
+			t.Errorf("%s: warning not logged: %s", c.name, buf.String())
+		}
+	}
+}
+
+func TestParserNestedUndefinedLength(t *testing.T) {
+	undefined := []byte{0xff, 0xff, 0xff, 0xff}
+	itemStart := append([]byte{0xfe, 0xff, 0x00, 0xe0}, undefined...)
+	itemEnd := []byte{0xfe, 0xff, 0x0d, 0xe0, 0, 0, 0, 0}
+	seqEnd := []byte{0xfe, 0xff, 0xdd, 0xe0, 0, 0, 0, 0}
+	sequence := func(group, elem uint16, content ...byte) []byte {
+		b := explicitElement(group, elem, "SQ", nil)
+		b = append(b[:8], undefined...)
+		b = append(b, itemStart...)
+		b = append(b, content...)
+		b = append(b, itemEnd...)
+		return append(b, seqEnd...)
+	}
+	inner := sequence(0x0008, 0x1199, explicitElement(0x0008, 0x1150, "UI", []byte("1.2.3\x00"))...)
+	data := sequence(0x0040, 0xA730, inner...)
+	data = append(data, explicitElement(0x0040, 0xA731, "LO", []byte("X "))...)
+
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 2 || elements[1].TagStr != "0040A731" {
+		t.Fatalf("Wrong elements: %v", elements)
+	}
+	outer := elements[0].Items
+	if len(outer) != 1 || len(outer[0].Elements) != 1 || outer[0].Elements[0].TagStr != "00081199" {
+		t.Fatalf("Wrong outer items: %v", outer)
+	}
+	nested := outer[0].Elements[0].Items
+	if len(nested) != 1 || len(nested[0].Elements) != 1 || !bytes.Equal(nested[0].Elements[0].Data, []byte("1.2.3\x00")) {
+		t.Errorf("Wrong nested items: %v", nested)
+	}
+
+	_, err = NewParser(bytes.NewReader(data[:len(data)-18]), 0, true, []string{}).Parse()
+	if !errors.Is(err, ErrMissingDelimiter) {
+		t.Errorf("Expected ErrMissingDelimiter, got %v", err)
+	}
+}
+
+func TestParserImplicitVR(t *testing.T) {
+	implicit := func(group, elem uint16, value []byte) []byte {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint16(b[0:], group)
+		binary.LittleEndian.PutUint16(b[2:], elem)
+		binary.LittleEndian.PutUint32(b[4:], uint32(len(value)))
+		return append(b, value...)
+	}
+	item := implicit(0x0008, 0x1150, []byte("1.2.3\x00"))
+	data := implicit(0x0008, 0x1115, implicit(0xfffe, 0xe000, item))
+	data = append(data, implicit(0x0028, 0x0010, []byte{0x00, 0x02})...)
+	data = append(data, implicit(0x0028, 0x0103, []byte{0x01, 0x00})...)
+	data = append(data, implicit(0x0028, 0x0106, []byte{0xff, 0xff})...)
+	data = append(data, implicit(0x0029, 0x0010, []byte("SIEMENS CSA HEADER"))...)
+	data = append(data, implicit(0x0029, 0x1008, []byte("IMAGE NUM 4 "))...)
+	data = append(data, implicit(0x0099, 0x0001, []byte{1, 2})...)
+
+	elements, err := NewParser(bytes.NewReader(data), 0, false, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 7 {
+		t.Fatalf("Expected 7 elements, got %d", len(elements))
+	}
+	for i, vr := range []string{"SQ", "US", "US", "SS", "LO", "CS", ""} {
+		if elements[i].VRStr != vr {
+			t.Errorf("%s: expected VR '%s', got '%s'", elements[i].TagStr, vr, elements[i].VRStr)
+		}
+	}
+	if len(elements[0].Items) != 1 || elements[0].Items[0].Elements[0].VRStr != "UI" {
+		t.Errorf("Wrong sequence: %v", elements[0].Items)
+	}
+	if elements[1].Ints()[0] != 512 || elements[3].Ints()[0] != -1 {
+		t.Errorf("Wrong values: %v, %v", elements[1].Ints(), elements[3].Ints())
+	}
+}
+
+// benchmarkFile returns a CT like file with a typical header and a 512x512
+// 16 bit image.
+func benchmarkFile() []byte {
+	tags := []string{}
+	for t := range tag.Tag {
+		if t > "00080000" && t < "00290000" && t[4:] != "0000" && t != "00081115" {
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	data := sampleFile()[:172]
+	for _, t := range tags[:150] {
+		b, _ := hex.DecodeString(t)
+		data = append(data, explicitElement(binary.BigEndian.Uint16(b), binary.BigEndian.Uint16(b[2:]), "LO", []byte("VALUE "))...)
+	}
+	item := explicitElement(0x0008, 0x1150, "UI", []byte("1.2.840.10008.5.1.4.1.1.2\x00"))
+	item = append(item, explicitElement(0x0008, 0x1155, "UI", []byte("1.2.3.4.5.6.7.8.9\x00"))...)
+	seq := append([]byte{0xfe, 0xff, 0x00, 0xe0, byte(len(item)), 0, 0, 0}, item...)
+	data = append(data, explicitElement(0x0040, 0x0275, "SQ", seq)...)
+	return append(data, explicitElement(0x7FE0, 0x0010, "OW", make([]byte, 512*512*2))...)
+}
+
+func BenchmarkParseFile(b *testing.B) {
+	data := benchmarkFile()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseFile(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseHeaders(b *testing.B) {
+	data := benchmarkFile()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseFileWithOptions(bytes.NewReader(data), ParseOptions{SkipPixelData: true})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseContext(t *testing.T) {
+	data := sampleFile()
+	progress := []ParseProgress{}
+	df, err := ParseFileWithOptions(bytes.NewReader(data), ParseOptions{Progress: func(pp ParseProgress) {
+		progress = append(progress, pp)
+	}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(progress) != len(df.Elements) {
+		t.Fatalf("Expected %d progress calls, got %d", len(df.Elements), len(progress))
+	}
+	last := progress[len(progress)-1]
+	if last.Elements != len(df.Elements) || last.Bytes != len(data) {
+		t.Errorf("Unexpected final progress %+v for %d bytes", last, len(data))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	df, err = ParseFileWithOptions(bytes.NewReader(data), ParseOptions{Context: ctx, Progress: func(pp ParseProgress) {
+		if pp.Elements == 2 {
+			cancel()
+		}
+	}})
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if len(df.Elements) != 2 {
+		t.Errorf("Expected the 2 elements read before cancel, got %d", len(df.Elements))
+	}
+}
+
+func TestParserLimits(t *testing.T) {
+	item := func(content []byte) []byte {
+		b := []byte{0xFE, 0xFF, 0x00, 0xE0, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(b[4:], uint32(len(content)))
+		return append(b, content...)
+	}
+	// Three nested sequences of defined length
+	nested := explicitElement(0x0008, 0x0100, "SH", []byte("CODE"))
+	for i := 0; i < 3; i++ {
+		nested = explicitElement(0x0040, 0xA730, "SQ", item(nested))
+	}
+	// The same depth with undefined lengths
+	undefined := explicitElement(0x0008, 0x0100, "SH", []byte("CODE"))
+	for i := 0; i < 3; i++ {
+		sq := []byte{0x40, 0x00, 0x30, 0xA7, 'S', 'Q', 0, 0, 0xFF, 0xFF, 0xFF, 0xFF}
+		sq = append(sq, 0xFE, 0xFF, 0x00, 0xE0, 0xFF, 0xFF, 0xFF, 0xFF)
+		sq = append(sq, undefined...)
+		sq = append(sq, 0xFE, 0xFF, 0x0D, 0xE0, 0, 0, 0, 0)
+		undefined = append(sq, 0xFE, 0xFF, 0xDD, 0xE0, 0, 0, 0, 0)
+	}
+	parse := func(data []byte, limits func(p *Parser)) error {
+		p := NewParser(bytes.NewReader(data), 0, true, []string{})
+		p.StopAt = ""
+		limits(p)
+		_, err := p.Parse()
+		return err
+	}
+	cases := []struct {
+		name     string
+		data     []byte
+		limits   func(p *Parser)
+		expected error
+	}{
+		{"depth", nested, func(p *Parser) { p.MaxDepth = 3 }, nil},
+		{"too deep", nested, func(p *Parser) { p.MaxDepth = 2 }, ErrSequenceDepth},
+		{"undefined depth", undefined, func(p *Parser) { p.MaxDepth = 3 }, nil},
+		{"undefined too deep", undefined, func(p *Parser) { p.MaxDepth = 2 }, ErrSequenceDepth},
+		{"elements", nested, func(p *Parser) { p.MaxElements = 7 }, nil},
+		{"too many elements", nested, func(p *Parser) { p.MaxElements = 6 }, ErrElementCount},
+		{"size", undefined, func(p *Parser) { p.MaxElementSize = len(undefined) }, nil},
+		{"undefined too large", undefined, func(p *Parser) { p.MaxElementSize = 32 }, ErrElementSize},
+		{"too large", nested, func(p *Parser) { p.MaxElementSize = 32 }, ErrElementSize},
+	}
+	for _, c := range cases {
+		err := parse(c.data, c.limits)
+		if c.expected == nil && err != nil || !errors.Is(err, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, err)
+		}
+	}
+
+	// A truncated element declaring a 4 GB value is not allocated
+	data := explicitElement(0x7FE0, 0x0010, "OB", []byte{1, 2, 3, 4})
+	binary.LittleEndian.PutUint32(data[8:], 0xFFFFFFF0)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := parse(data, func(p *Parser) {})
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+	if after.TotalAlloc-before.TotalAlloc > 16<<20 {
+		t.Errorf("Allocated %d bytes for a truncated element", after.TotalAlloc-before.TotalAlloc)
+	}
+}
+
+func TestParserOddLength(t *testing.T) {
+	item := explicitElement(0x0008, 0x0100, "SH", []byte("T-D1"))
+	item = append(item, explicitElement(0x0008, 0x0104, "LO", []byte("ABC"))...)
+	seq := append([]byte{0xFE, 0xFF, 0x00, 0xE0, byte(len(item)), 0, 0, 0}, item...)
+	data := sampleFile()[:172]
+	data = append(data, explicitElement(0x0008, 0x0018, "UI", []byte("1.2.3"))...)
+	data = append(data, explicitElement(0x0008, 0x0060, "CS", []byte("MR "))...)
+	data = append(data, explicitElement(0x0008, 0x2218, "SQ", seq)...)
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE"))...)
+	data = append(data, explicitElement(0x0010, 0x0020, "LO", nil)...)
+	data = append(data, explicitElement(0x0028, 0x0010, "US", []byte{0x00, 0x02, 0x01})...)
+
+	df, err := ParseFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	odd := []string{}
+	for _, w := range df.Warnings {
+		var pe *ParseError
+		if !errors.As(w, &pe) || !errors.Is(w, ErrOddLength) {
+			t.Errorf("unexpected warning: %v", w)
+			continue
+		}
+		odd = append(odd, pe.Tag)
+	}
+	expected := []string{"00080018", "00080060", "00080104", "00082218", "00100010", "00280010", "FFFEE000"}
+	sort.Strings(odd)
+	if !reflect.DeepEqual(odd, expected) {
+		t.Errorf("expected odd length warnings for %v, got %v", expected, odd)
+	}
+	values := map[string][]string{}
+	for _, de := range df.Elements {
+		values[de.TagStr] = de.Strings()
+	}
+	for tag, v := range map[string][]string{
+		"00080018": {"1.2.3"},
+		"00080060": {"MR"},
+		"00100010": {"DOE"},
+		"00100020": {},
+		"00280010": {"512"},
+	} {
+		if !reflect.DeepEqual(values[tag], v) {
+			t.Errorf("%s: expected %q, got %q", tag, v, values[tag])
+		}
+	}
+
+	// Written values are padded to an even length
+	var buf bytes.Buffer
+	if err := df.Write(&buf, ts.ExplicitVRLittleEndian); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseFile(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", out.Warnings)
+	}
+	for _, de := range out.Elements {
+		switch de.TagStr {
+		case "00080018":
+			if string(de.Data) != "1.2.3\x00" {
+				t.Errorf("expected NUL padding, got %q", de.Data)
+			}
+		case "00100010":
+			if string(de.Data) != "DOE " {
+				t.Errorf("expected space padding, got %q", de.Data)
+			}
+		}
+	}
+}
+
+func TestEmptyValues(t *testing.T) {
+	for vr := range vri.VR {
+		for _, data := range [][]byte{{}, {'1'}, {0x0, 0x0, 0x0}} {
+			de := DataElement{TagStr: "00091010", VRStr: vr, Data: data, Len: uint32(len(data))}
+			_ = de.String()
+			_ = de.StringData()
+			de.Strings()
+			de.Ints()
+			de.Floats()
+			de.VM()
+			de.StringAt(0)
+			de.Time()
+			de.PersonName()
+			de.PersonNames()
+		}
+	}
+}
+
+func TestParserResolveUN(t *testing.T) {
+	item := []byte{0x08, 0x00, 0x50, 0x11, 0x06, 0x00, 0x00, 0x00}
+	item = append(item, "1.2.3\x00"...)
+	seq := append([]byte{0xFE, 0xFF, 0x00, 0xE0, byte(len(item)), 0, 0, 0}, item...)
+	seq = append(seq, 0xFE, 0xFF, 0xDD, 0xE0, 0, 0, 0, 0)
+	data := explicitElement(0x0008, 0x1115, "UN", nil)
+	binary.LittleEndian.PutUint32(data[8:], 0xFFFFFFFF)
+	data = append(data, seq...)
+	data = append(data, explicitElement(0x0010, 0x0010, "UN", []byte("DOE^JOHN"))...)
+	data = append(data, explicitElement(0x0028, 0x0010, "UN", []byte{0x00, 0x02})...)
+	data = append(data, explicitElement(0x0009, 0x1010, "UN", []byte{0x01, 0x02})...)
+
+	for _, resolve := range []bool{false, true} {
+		p := NewParser(bytes.NewReader(data), 0, true, []string{})
+		p.ResolveUN = resolve
+		elements, err := p.Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+		vrs := []string{}
+		for _, de := range elements {
+			vrs = append(vrs, de.VRStr)
+		}
+		expected := []string{"UN", "UN", "UN", "UN"}
+		if resolve {
+			expected = []string{"SQ", "PN", "US", "UN"}
+		}
+		if !reflect.DeepEqual(vrs, expected) {
+			t.Errorf("resolve %v: expected %v, got %v", resolve, expected, vrs)
+		}
+		if !resolve {
+			continue
+		}
+		if len(elements[0].Items) != 1 || len(elements[0].Items[0].Elements) != 1 ||
+			elements[0].Items[0].Elements[0].Strings()[0] != "1.2.3" {
+			t.Errorf("wrong sequence: %v", elements[0].Items)
+		}
+		if v := elements[1].Strings(); !reflect.DeepEqual(v, []string{"DOE^JOHN"}) {
+			t.Errorf("expected DOE^JOHN, got %v", v)
+		}
+		if v := elements[2].Ints(); !reflect.DeepEqual(v, []int{512}) {
+			t.Errorf("expected 512, got %v", v)
+		}
+	}
+
+	// Values keep the byte order of the data set
+	be := []byte{0x00, 0x28, 0x00, 0x10, 'U', 'N', 0, 0, 0, 0, 0, 2, 0x02, 0x00}
+	p := NewParser(bytes.NewReader(be), 0, true, []string{})
+	p.ByteOrder, p.ResolveUN = binary.BigEndian, true
+	elements, err := p.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 1 || elements[0].VRStr != "US" || !reflect.DeepEqual(elements[0].Ints(), []int{512}) {
+		t.Errorf("wrong big endian element: %v", elements)
+	}
+}
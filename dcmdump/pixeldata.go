@@ -0,0 +1,348 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/rle"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// ErrNoPixelData is returned when the file has no PixelData element.
+var ErrNoPixelData = errors.New("No PixelData in file")
+
+// ErrFrameIndex is returned when requesting a frame that is not in the file.
+var ErrFrameIndex = errors.New("Frame index out of range")
+
+// ErrFrameFragments is returned when the fragments of encapsulated pixel data
+// can't be mapped to frames.
+var ErrFrameFragments = errors.New("Can't map fragments to frames")
+
+// PixelDataInfo describes the PixelData (7FE0,0010) of a file.
+// The file needs to be parsed with PixelData kept, that is without a StopAt
+// tag before it.
+type PixelDataInfo struct {
+	NumberOfFrames  int
+	Rows            int
+	Columns         int
+	BitsAllocated   int
+	SamplesPerPixel int
+	// PlanarConfiguration is 1 when color samples are stored by plane.
+	PlanarConfiguration int
+	// PixelRepresentation is 1 for signed samples.
+	PixelRepresentation int
+	// PhotometricInterpretation of the samples, as in MONOCHROME2 or RGB.
+	PhotometricInterpretation string
+	// Encapsulated is set for compressed transfer syntaxes, where frames are
+	// stored in fragments.
+	Encapsulated bool
+	// TransferSyntax the pixel data is encoded with.
+	TransferSyntax string
+
+	data []byte
+}
+
+// PixelDataInfo returns the image description of the file and gives access
+// to its frames.
+func (df *DicomFile) PixelDataInfo() (*PixelDataInfo, error) {
+	de, err := df.LookupElement("7FE00010")
+	if err != nil {
+		return nil, ErrNoPixelData
+	}
+	err = de.Load()
+	if err != nil {
+		return nil, err
+	}
+	return df.pixelDataInfo(de)
+}
+
+// pixelDataInfo returns the image description of the file with the value of
+// de as data.
+func (df *DicomFile) pixelDataInfo(de *DataElement) (*PixelDataInfo, error) {
+	pi := PixelDataInfo{
+		NumberOfFrames:  1,
+		SamplesPerPixel: 1,
+		Encapsulated:    de.UndefinedLen,
+		TransferSyntax:  df.TransferSyntax,
+		data:            de.Data,
+	}
+	if e, err := df.LookupElement("00020010"); err == nil {
+		pi.TransferSyntax = strings.TrimRight(string(e.Data), "\x00 ")
+	}
+	pi.PhotometricInterpretation = df.stringValue("00280004")
+	for _, a := range []struct {
+		tag string
+		v   *int
+	}{
+		{"00280008", &pi.NumberOfFrames},
+		{"00280010", &pi.Rows},
+		{"00280011", &pi.Columns},
+		{"00280100", &pi.BitsAllocated},
+		{"00280002", &pi.SamplesPerPixel},
+		{"00280006", &pi.PlanarConfiguration},
+		{"00280103", &pi.PixelRepresentation},
+	} {
+		e, err := df.LookupElement(a.tag)
+		if err != nil {
+			continue
+		}
+		*a.v, err = e.intValue()
+		if err != nil {
+			return nil, newParseError(e, err)
+		}
+	}
+	return &pi, nil
+}
+
+// FrameSize returns the size in bytes of each native frame, rounded up for 1
+// bit samples, 0 when the image attributes are invalid or the size doesn't
+// fit in an int.
+// Native YBR_FULL_422 frames hold two samples per pixel, the chroma ones
+// being shared by pairs of pixels.
+func (pi *PixelDataInfo) FrameSize() int {
+	size := (pi.frameBits() + 7) / 8
+	if size > uint64(maxInt) {
+		return 0
+	}
+	return int(size)
+}
+
+const maxInt = int(^uint(0) >> 1)
+
+// frameBits returns the number of bits of each native frame, 0 when the
+// image attributes are invalid.
+func (pi *PixelDataInfo) frameBits() uint64 {
+	samples := pi.SamplesPerPixel
+	if pi.subsampled() {
+		samples = 2
+	}
+	bits := uint64(1)
+	for _, n := range []int{pi.Rows, pi.Columns, samples, pi.BitsAllocated} {
+		// The attributes are US values, so the product can't overflow
+		if n <= 0 || n > 0xFFFF {
+			return 0
+		}
+		bits *= uint64(n)
+	}
+	return bits
+}
+
+// frameRange returns the offset and size of native frame i within length
+// bytes of pixel data.
+// Frames of 1 bit samples that don't end on a byte boundary are packed across
+// frames and can't be cut, ErrPixelFormat is returned for them.
+func (pi *PixelDataInfo) frameRange(i, length int) (int, int, error) {
+	size := pi.FrameSize()
+	if size == 0 {
+		return 0, 0, ErrFrameIndex
+	}
+	// Compared by division so hostile sizes don't overflow
+	if i < 0 || length/size <= i {
+		return 0, 0, ErrFrameIndex
+	}
+	if pi.NumberOfFrames > 1 && pi.frameBits()%8 != 0 {
+		return 0, 0, ErrPixelFormat
+	}
+	return size * i, size, nil
+}
+
+// subsampled reports whether native frames are stored with horizontal chroma
+// subsampling.
+func (pi *PixelDataInfo) subsampled() bool {
+	return pi.PhotometricInterpretation == PhotometricYBRFull422 && !pi.Encapsulated && pi.SamplesPerPixel == 3
+}
+
+// Frame - Raw data of a single frame.
+type Frame struct {
+	// Data holds native frames as stored and encapsulated frames as the
+	// concatenation of their compressed fragments.
+	Data []byte
+
+	info *PixelDataInfo
+}
+
+// Frame returns frame i, starting at 0.
+func (pi *PixelDataInfo) Frame(i int) (*Frame, error) {
+	data, err := pi.frameData(i)
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{Data: data, info: pi}, nil
+}
+
+func (pi *PixelDataInfo) frameData(i int) ([]byte, error) {
+	if i < 0 || i >= pi.NumberOfFrames {
+		return nil, ErrFrameIndex
+	}
+	if pi.Encapsulated {
+		return pi.encapsulatedFrame(i)
+	}
+	start, size, err := pi.frameRange(i, len(pi.data))
+	if err != nil {
+		return nil, err
+	}
+	return pi.data[start : start+size], nil
+}
+
+// Fragment - Item of encapsulated pixel data.
+type Fragment struct {
+	// Offset of the item tag from the first fragment, as used by the Basic
+	// Offset Table.
+	Offset int
+	Data   []byte
+}
+
+// OffsetTable returns the Basic Offset Table of encapsulated pixel data, the
+// offset of the first fragment of each frame.
+// The table is optional, an empty list is returned when it isn't present.
+func (pi *PixelDataInfo) OffsetTable() ([]uint32, error) {
+	items, err := splitItems(pi.data)
+	if err != nil {
+		return nil, err
+	}
+	table := []uint32{}
+	for n := 0; n+4 <= len(items[0]); n += 4 {
+		table = append(table, binary.LittleEndian.Uint32(items[0][n:]))
+	}
+	return table, nil
+}
+
+// Fragments returns the fragments of encapsulated pixel data, the Basic
+// Offset Table is not included.
+func (pi *PixelDataInfo) Fragments() ([]Fragment, error) {
+	items, err := splitItems(pi.data)
+	if err != nil {
+		return nil, err
+	}
+	fragments := []Fragment{}
+	offset := 0
+	for _, item := range items[1:] {
+		fragments = append(fragments, Fragment{Offset: offset, Data: item})
+		offset += 8 + len(item)
+	}
+	return fragments, nil
+}
+
+// FrameFragments returns the fragments of frame i, starting at 0.
+// Fragments are mapped to frames with the Basic Offset Table when present,
+// otherwise a single frame takes all fragments or each frame takes one.
+func (pi *PixelDataInfo) FrameFragments(i int) ([]Fragment, error) {
+	if i < 0 || i >= pi.NumberOfFrames {
+		return nil, ErrFrameIndex
+	}
+	table, err := pi.OffsetTable()
+	if err != nil {
+		return nil, err
+	}
+	fragments, err := pi.Fragments()
+	if err != nil {
+		return nil, err
+	}
+	offsets := make([]int, len(fragments))
+	for j, f := range fragments {
+		offsets[j] = f.Offset
+	}
+	start, end, err := frameFragments(table, offsets, pi.NumberOfFrames, i)
+	if err != nil {
+		return nil, err
+	}
+	return fragments[start:end], nil
+}
+
+// frameFragments returns the range of the fragments of frame i given the
+// offsets of all fragments.
+func frameFragments(table []uint32, offsets []int, frames, i int) (int, int, error) {
+	switch {
+	case len(table) == frames:
+		start := -1
+		end := len(offsets)
+		for j, offset := range offsets {
+			if start < 0 && offset == int(table[i]) {
+				start = j
+			}
+			if i+1 < len(table) && offset >= int(table[i+1]) {
+				end = j
+				break
+			}
+		}
+		if start < 0 || start >= end {
+			return 0, 0, ErrFrameFragments
+		}
+		return start, end, nil
+	case len(table) != 0:
+		return 0, 0, ErrFrameFragments
+	case frames == 1:
+		return 0, len(offsets), nil
+	case len(offsets) == frames:
+		return i, i + 1, nil
+	}
+	return 0, 0, ErrFrameFragments
+}
+
+// DecodeFrame returns frame i as native pixel data, decompressing it when
+// needed.
+// Only RLE Lossless is supported for encapsulated transfer syntaxes.
+func (pi *PixelDataInfo) DecodeFrame(i int) ([]byte, error) {
+	frame, err := pi.frameData(i)
+	if err != nil || !pi.Encapsulated {
+		return frame, err
+	}
+	if pi.TransferSyntax != ts.RLELossless {
+		return nil, ErrUnsupportedTS
+	}
+	return rle.Decode(frame, pi.Rows, pi.Columns, pi.SamplesPerPixel, pi.BitsAllocated)
+}
+
+func (pi *PixelDataInfo) encapsulatedFrame(i int) ([]byte, error) {
+	fragments, err := pi.FrameFragments(i)
+	if err != nil {
+		return nil, err
+	}
+	frame := []byte{}
+	for _, f := range fragments {
+		frame = append(frame, f.Data...)
+	}
+	return frame, nil
+}
+
+// splitItems returns the values of the items in encapsulated pixel data,
+// the first one is the Basic Offset Table.
+// Encapsulated pixel data is always little endian.
+func splitItems(data []byte) ([][]byte, error) {
+	items := [][]byte{}
+	n := 0
+	for n+8 <= len(data) {
+		if tagString(data[n:n+4], binary.LittleEndian) != "FFFEE000" {
+			break
+		}
+		l := int(binary.LittleEndian.Uint32(data[n+4 : n+8]))
+		n += 8
+		if n+l > len(data) {
+			return items, &ParseError{Offset: n - 8, Tag: "FFFEE000", Err: io.ErrUnexpectedEOF}
+		}
+		items = append(items, data[n:n+l])
+		n += l
+	}
+	if len(items) == 0 {
+		return items, ErrFrameFragments
+	}
+	return items, nil
+}
+
+// intValue returns the value of a single valued binary or IS element.
+func (de *DataElement) intValue() (int, error) {
+	switch de.VRStr {
+	case "IS", "DS":
+		return strconv.Atoi(strings.Trim(string(de.Data), " \x00"))
+	}
+	switch len(de.Data) {
+	case 2:
+		return int(de.order().Uint16(de.Data)), nil
+	case 4:
+		return int(de.order().Uint32(de.Data)), nil
+	}
+	return strconv.Atoi(strings.Trim(string(de.Data), " \x00"))
+}
@@ -0,0 +1,358 @@
+package dcmdump
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestEncapsulatedFragments(t *testing.T) {
+	item := func(value ...byte) []byte {
+		return append([]byte{0xfe, 0xff, 0x00, 0xe0, byte(len(value)), 0, 0, 0}, value...)
+	}
+	data := explicitElement(0x0028, 0x0008, "IS", []byte("2 "))
+	// Encapsulated PixelData with undefined length
+	data = append(data, 0xe0, 0x7f, 0x10, 0x00, 'O', 'B', 0, 0, 0xff, 0xff, 0xff, 0xff)
+	// Basic Offset Table, frame 1 starts on the 3rd fragment
+	data = append(data, item(0, 0, 0, 0, 22, 0, 0, 0)...)
+	// Fragment data holding a SequenceDelimitationItem tag
+	data = append(data, item(0xfe, 0xff, 0xdd, 0xe0)...)
+	data = append(data, item(1, 2)...)
+	data = append(data, item(3, 4, 5, 6)...)
+	data = append(data, 0xfe, 0xff, 0xdd, 0xe0, 0, 0, 0, 0)
+	data = append(data, explicitElement(0xfffc, 0xfffc, "OB", []byte{0, 0})...)
+
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(elements) != 3 || !elements[1].UndefinedLen {
+		t.Fatalf("Wrong elements: %v", elements)
+	}
+	df := &DicomFile{Elements: elements}
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	table, err := pi.OffsetTable()
+	if err != nil || len(table) != 2 || table[1] != 22 {
+		t.Errorf("Wrong offset table: %v, %v", table, err)
+	}
+	fragments, err := pi.Fragments()
+	if err != nil || len(fragments) != 3 || fragments[2].Offset != 22 {
+		t.Errorf("Wrong fragments: %v, %v", fragments, err)
+	}
+	frame, err := pi.Frame(0)
+	if err != nil || !bytes.Equal(frame.Data, []byte{0xfe, 0xff, 0xdd, 0xe0, 1, 2}) {
+		t.Errorf("Wrong frame 0: %v, %v", frame, err)
+	}
+	frame, err = pi.Frame(1)
+	if err != nil || !bytes.Equal(frame.Data, []byte{3, 4, 5, 6}) {
+		t.Errorf("Wrong frame 1: %v, %v", frame, err)
+	}
+}
+
+func TestFrameDecode(t *testing.T) {
+	pi := &PixelDataInfo{
+		NumberOfFrames:  1,
+		Rows:            1,
+		Columns:         2,
+		BitsAllocated:   16,
+		SamplesPerPixel: 1,
+		TransferSyntax:  ts.ExplicitVRLittleEndian,
+		data:            []byte{0x02, 0x01, 0x04, 0x03},
+	}
+	frame, err := pi.Frame(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	img, err := frame.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if g, ok := img.(*image.Gray16); !ok || g.Gray16At(1, 0).Y != 0x0304 {
+		t.Errorf("Wrong image: %v", img)
+	}
+
+	pi.TransferSyntax = "1.2.3.4"
+	_, err = frame.Decode()
+	if err != ErrUnsupportedTS {
+		t.Errorf("Expected ErrUnsupportedTS, got %v", err)
+	}
+	RegisterCodec("1.2.3.4", func(data []byte, pi *PixelDataInfo) (image.Image, error) {
+		return image.NewGray(image.Rect(0, 0, pi.Columns, pi.Rows)), nil
+	})
+	img, err = frame.Decode()
+	if _, ok := img.(*image.Gray); err != nil || !ok {
+		t.Errorf("Registered codec not used: %v, %v", img, err)
+	}
+}
+
+func TestFrameSize(t *testing.T) {
+	for _, c := range []struct {
+		pi   PixelDataInfo
+		bits uint64
+		size int
+	}{
+		{PixelDataInfo{Rows: 512, Columns: 512, BitsAllocated: 16, SamplesPerPixel: 1}, 512 * 512 * 16, 512 * 512 * 2},
+		{PixelDataInfo{Rows: 2, Columns: 4, BitsAllocated: 1, SamplesPerPixel: 1}, 8, 1},
+		{PixelDataInfo{Rows: 3, Columns: 3, BitsAllocated: 1, SamplesPerPixel: 1}, 9, 2},
+		{PixelDataInfo{Rows: 2, Columns: 2, BitsAllocated: 8, SamplesPerPixel: 3, PhotometricInterpretation: PhotometricYBRFull422}, 64, 8},
+		{PixelDataInfo{Rows: 0, Columns: 2, BitsAllocated: 8, SamplesPerPixel: 1}, 0, 0},
+		{PixelDataInfo{Rows: -1, Columns: 2, BitsAllocated: 8, SamplesPerPixel: 1}, 0, 0},
+		{PixelDataInfo{Rows: 0x10000, Columns: 2, BitsAllocated: 8, SamplesPerPixel: 1}, 0, 0},
+		{PixelDataInfo{Rows: 1, Columns: 2, BitsAllocated: 0, SamplesPerPixel: 1}, 0, 0},
+		{PixelDataInfo{Rows: 2, Columns: 2, BitsAllocated: 8, SamplesPerPixel: 0}, 0, 0},
+	} {
+		if bits := c.pi.frameBits(); bits != c.bits {
+			t.Errorf("%+v: expected %d bits, got %d", c.pi, c.bits, bits)
+		}
+		if size := c.pi.FrameSize(); size != c.size {
+			t.Errorf("%+v: expected %d bytes, got %d", c.pi, c.size, size)
+		}
+	}
+	// The largest attributes don't overflow the bit count
+	pi := PixelDataInfo{Rows: 0xFFFF, Columns: 0xFFFF, BitsAllocated: 0xFFFF, SamplesPerPixel: 0xFFFF}
+	if bits := pi.frameBits(); bits != 0xFFFF*0xFFFF*0xFFFF*0xFFFF {
+		t.Errorf("Wrong bits of the largest frame %d", bits)
+	}
+}
+
+func TestFrameRange(t *testing.T) {
+	for _, c := range []struct {
+		pi          PixelDataInfo
+		i, length   int
+		start, size int
+		err         error
+	}{
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 2, BitsAllocated: 16, SamplesPerPixel: 1}, 1, 16, 8, 8, nil},
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 4, BitsAllocated: 1, SamplesPerPixel: 1}, 1, 2, 1, 1, nil},
+		{PixelDataInfo{NumberOfFrames: 1, Rows: 3, Columns: 3, BitsAllocated: 1, SamplesPerPixel: 1}, 0, 2, 0, 2, nil},
+		// 3x3 bit frames are packed across byte boundaries
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 3, Columns: 3, BitsAllocated: 1, SamplesPerPixel: 1}, 0, 3, 0, 0, ErrPixelFormat},
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 2, BitsAllocated: 16, SamplesPerPixel: 1}, 2, 16, 0, 0, ErrFrameIndex},
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 2, BitsAllocated: 16, SamplesPerPixel: 1}, -1, 16, 0, 0, ErrFrameIndex},
+		{PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 2, BitsAllocated: 16, SamplesPerPixel: 1}, 1, 15, 0, 0, ErrFrameIndex},
+		{PixelDataInfo{NumberOfFrames: 1 << 30, Rows: 0xFFFF, Columns: 0xFFFF, BitsAllocated: 0xFFFF, SamplesPerPixel: 0xFFFF}, 1<<30 - 1, 4, 0, 0, ErrFrameIndex},
+		{PixelDataInfo{NumberOfFrames: 1, Rows: 1, Columns: 2, BitsAllocated: 0, SamplesPerPixel: 1}, 0, 4, 0, 0, ErrFrameIndex},
+	} {
+		start, size, err := c.pi.frameRange(c.i, c.length)
+		if start != c.start || size != c.size || err != c.err {
+			t.Errorf("%+v frame %d of %d bytes: expected %d %d %v, got %d %d %v", c.pi, c.i, c.length, c.start, c.size, c.err, start, size, err)
+		}
+	}
+
+	pi := &PixelDataInfo{NumberOfFrames: 2, Rows: 2, Columns: 4, BitsAllocated: 1, SamplesPerPixel: 1, data: []byte{0x0f, 0xf0}}
+	frame, err := pi.Frame(1)
+	if err != nil || !bytes.Equal(frame.Data, []byte{0xf0}) {
+		t.Errorf("Wrong 1 bit frame: %v, %v", frame, err)
+	}
+}
+
+func TestFrameFragments(t *testing.T) {
+	offsets := []int{0, 12, 22}
+	for _, c := range []struct {
+		table      []uint32
+		offsets    []int
+		frames, i  int
+		start, end int
+		err        error
+	}{
+		// Basic Offset Table, frame 1 starts on the 3rd fragment
+		{[]uint32{0, 22}, offsets, 2, 0, 0, 2, nil},
+		{[]uint32{0, 22}, offsets, 2, 1, 2, 3, nil},
+		{[]uint32{0, 12, 22}, offsets, 3, 1, 1, 2, nil},
+		// Offsets not starting a fragment, out of order or not one per frame
+		{[]uint32{0, 10}, offsets, 2, 1, 0, 0, ErrFrameFragments},
+		{[]uint32{0, 0}, offsets, 2, 0, 0, 0, ErrFrameFragments},
+		{[]uint32{0}, offsets, 2, 0, 0, 0, ErrFrameFragments},
+		// Empty table, single frames use all fragments
+		{nil, offsets, 1, 0, 0, 3, nil},
+		{nil, offsets, 3, 2, 2, 3, nil},
+		{nil, offsets, 2, 0, 0, 0, ErrFrameFragments},
+	} {
+		start, end, err := frameFragments(c.table, c.offsets, c.frames, c.i)
+		if start != c.start || end != c.end || err != c.err {
+			t.Errorf("%v %d frames, frame %d: expected %d %d %v, got %d %d %v", c.table, c.frames, c.i, c.start, c.end, c.err, start, end, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00280002", "US", 1},
+		{"00280004", "CS", "MONOCHROME1"},
+		{"00280010", "US", 1},
+		{"00280011", "US", 3},
+		{"00280100", "US", 16},
+		{"00281050", "DS", 100},
+		{"00281051", "DS", 101},
+		{"00281052", "DS", -1000},
+		{"00281053", "DS", 2},
+		{"7FE00010", "OW", []int{500, 550, 600}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	img, err := df.Render(0, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	g, ok := img.(*image.Gray16)
+	if !ok {
+		t.Fatalf("Expected Gray16, got %T", img)
+	}
+	// MONOCHROME1 inverts the window output
+	for x, y := range []uint16{0xffff, 0x7eb8, 0} {
+		if g.Gray16At(x, 0).Y != y {
+			t.Errorf("Pixel %d: expected %04x, got %04x", x, y, g.Gray16At(x, 0).Y)
+		}
+	}
+	img, _ = df.Render(0, &Window{Center: 1000, Width: 1})
+	if g := img.(*image.Gray16); g.Gray16At(2, 0).Y != 0xffff {
+		t.Errorf("Window not applied: %v", g.Pix)
+	}
+}
+
+func TestColorFrame(t *testing.T) {
+	df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00280002", "US", 1},
+		{"00280004", "CS", "PALETTE COLOR"},
+		{"00280010", "US", 1},
+		{"00280011", "US", 3},
+		{"00280100", "US", 8},
+		{"00281101", "US", []int{3, 10, 16}},
+		{"00281102", "US", []int{3, 10, 16}},
+		{"00281103", "US", []int{3, 10, 8}},
+		{"00281201", "OW", []int{0x0000, 0x8000, 0xffff}},
+		// Discrete 0 then linear up to 0xff00 over 2 entries
+		{"00281222", "OW", []int{0, 1, 0, 1, 2, 0xff00}},
+		{"00281203", "OW", []int{0x10, 0x20, 0x30}},
+		{"7FE00010", "OB", []byte{9, 11, 40, 0}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	img, err := df.ColorFrame(0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// Values below and past the tables map to their first and last entries
+	for x, c := range []color.RGBA{{0, 0, 0x10, 0xff}, {0x80, 0x7f, 0x20, 0xff}, {0xff, 0xff, 0x30, 0xff}} {
+		if img.RGBAAt(x, 0) != c {
+			t.Errorf("Pixel %d: expected %v, got %v", x, c, img.RGBAAt(x, 0))
+		}
+	}
+
+	df = &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00280002", "US", 3},
+		{"00280004", "CS", "YBR_FULL_422"},
+		{"00280010", "US", 1},
+		{"00280011", "US", 2},
+		{"00280100", "US", 8},
+		// Y1 Y2 Cb Cr
+		{"7FE00010", "OB", []byte{0x00, 0xff, 0x80, 0x80}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rendered, err := df.Render(0, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	rgba := rendered.(*image.RGBA)
+	for x, c := range []color.RGBA{{0, 0, 0, 0xff}, {0xff, 0xff, 0xff, 0xff}} {
+		if rgba.RGBAAt(x, 0) != c {
+			t.Errorf("Pixel %d: expected %v, got %v", x, c, rgba.RGBAAt(x, 0))
+		}
+	}
+}
+
+func TestRenderLUTs(t *testing.T) {
+	lutItem := func(descriptor, data []int) Item {
+		item := Item{}
+		for _, e := range []struct {
+			tag, vr string
+			value   []int
+		}{
+			{"00283002", "US", descriptor},
+			{"00283006", "OW", data},
+		} {
+			de, err := NewDataElement(e.tag, e.vr, e.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			de.PartOfSQ = true
+			item.Elements = append(item.Elements, de)
+		}
+		return item
+	}
+	df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00280002", "US", 1},
+		{"00280004", "CS", "MONOCHROME2"},
+		{"00280010", "US", 1},
+		{"00280011", "US", 3},
+		{"00280100", "US", 8},
+		{"00283000", "SQ", []Item{lutItem([]int{3, 0, 16}, []int{10, 11, 12})}},
+		{"00283010", "SQ", []Item{
+			lutItem([]int{3, 10, 8}, []int{0, 128, 255}),
+			lutItem([]int{3, 10, 8}, []int{255, 128, 0}),
+		}},
+		{"7FE00010", "OB", []byte{0, 1, 2, 0}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []struct {
+		options  RenderOptions
+		expected []uint16
+	}{
+		// The file has no window, the first VOI LUT applies
+		{RenderOptions{}, []uint16{0, 0x8080, 0xffff}},
+		{RenderOptions{VOILUT: 2}, []uint16{0xffff, 0x8080, 0}},
+		{RenderOptions{Window: &Window{Center: 11, Width: 2, Function: VOILinearExact}}, []uint16{0, 0x8000, 0xffff}},
+		{RenderOptions{Window: &Window{Center: 11, Width: 2, Function: VOISigmoid}}, []uint16{0x1e84, 0x8000, 0xe17b}},
+	} {
+		img, err := df.RenderWithOptions(0, c.options)
+		if err != nil {
+			t.Fatalf("%+v: unexpected error: %s", c.options, err)
+		}
+		g := img.(*image.Gray16)
+		for x, y := range c.expected {
+			if g.Gray16At(x, 0).Y != y {
+				t.Errorf("%+v pixel %d: expected %04x, got %04x", c.options, x, y, g.Gray16At(x, 0).Y)
+			}
+		}
+	}
+	_, err := df.RenderWithOptions(0, RenderOptions{VOILUT: 3})
+	if err != ErrLUT {
+		t.Errorf("Expected ErrLUT, got %v", err)
+	}
+}
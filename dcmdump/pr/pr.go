@@ -0,0 +1,381 @@
+// Package pr reads Grayscale Softcopy Presentation State files and applies
+// them to the images they reference, PS3.3 A.33.1 and PS3.4 N.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_A.33.html
+package pr
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// ErrNotPresentationState is returned for files without a
+// ReferencedSeriesSequence.
+var ErrNotPresentationState = errors.New("Not a Presentation State")
+
+// ErrNotReferenced is returned when rendering an image the presentation
+// state doesn't reference.
+var ErrNotReferenced = errors.New("Image not referenced by the Presentation State")
+
+// Annotation units
+const (
+	UnitsPixel   = "PIXEL"
+	UnitsDisplay = "DISPLAY"
+)
+
+// Point - Column and row of a PIXEL annotation, the top left corner of the
+// top left pixel being 0,0, or fraction of the displayed area width and
+// height for DISPLAY annotations.
+type Point struct {
+	X, Y float64
+}
+
+// ImageReference - Item of a ReferencedImageSequence.
+type ImageReference struct {
+	SOPClassUID    string
+	SOPInstanceUID string
+	// Frames starting at 1, all of them when empty.
+	Frames []int
+}
+
+// DisplayedArea - Item of the DisplayedAreaSelectionSequence.
+type DisplayedArea struct {
+	// Images the area applies to, all of them when empty.
+	Images []ImageReference
+	// TopLeft and BottomRight pixels of the area, starting at 1,1. They may be
+	// outside of the image.
+	TopLeft, BottomRight [2]int
+	// SizeMode is SCALE TO FIT, TRUE SIZE or MAGNIFY.
+	SizeMode      string
+	PixelSpacing  []float64
+	Magnification float64
+}
+
+// Text - Item of the TextObjectSequence.
+type Text struct {
+	Value string
+	// BoundingBox top left and bottom right corners, when HasBoundingBox.
+	BoundingBox    [2]Point
+	BoundingUnits  string
+	HasBoundingBox bool
+	Anchor         Point
+	AnchorUnits    string
+	HasAnchor      bool
+	AnchorVisible  bool
+}
+
+// Graphic - Item of the GraphicObjectSequence.
+type Graphic struct {
+	Units string
+	// Type is POINT, POLYLINE, INTERPOLATED, CIRCLE or ELLIPSE.
+	Type   string
+	Points []Point
+	Filled bool
+}
+
+// Annotation - Item of the GraphicAnnotationSequence.
+type Annotation struct {
+	Images   []ImageReference
+	Layer    string
+	Texts    []Text
+	Graphics []Graphic
+}
+
+// GraphicLayer - Item of the GraphicLayerSequence.
+type GraphicLayer struct {
+	Name  string
+	Order int
+	// Gray is the recommended grayscale P-value, -1 when not given.
+	Gray int
+	// CIELab is the recommended scaled CIELab color, nil when not given.
+	CIELab      []int
+	Description string
+}
+
+// Shutter - Display Shutter module. Pixels outside any of the shapes are
+// displayed with Value.
+type Shutter struct {
+	// Shapes are RECTANGULAR, CIRCULAR or POLYGONAL.
+	Shapes []string
+	// Left, Right, Upper and Lower edges of the rectangle, in pixels starting
+	// at 1.
+	Left, Right, Upper, Lower int
+	// Center column and row and Radius of the circle.
+	Center [2]int
+	Radius int
+	// Vertices of the polygon as columns and rows.
+	Vertices [][2]int
+	// Value is the P-value of the shuttered pixels.
+	Value int
+}
+
+// VOI - Item of the SoftcopyVOILUTSequence, holding either a window or a
+// LUT.
+type VOI struct {
+	Images []ImageReference
+	Window *dcmdump.Window
+	// LUT first mapped value is read unsigned, see PresentationState.
+	LUT *dcmdump.LUT
+}
+
+// PresentationState - Presentation state of a set of images.
+type PresentationState struct {
+	Label       string
+	Description string
+	// References are the images of the ReferencedSeriesSequence.
+	References     []ImageReference
+	DisplayedAreas []DisplayedArea
+	Annotations    []Annotation
+	Layers         []GraphicLayer
+	// Shutter is nil when the presentation state has none.
+	Shutter *Shutter
+	// Rescale or ModalityLUT replace the modality transformation of the
+	// images when not nil. The first mapped value of the LUTs is read
+	// unsigned, Render makes it signed for the images with signed values.
+	Rescale     *dcmdump.Rescale
+	ModalityLUT *dcmdump.LUT
+	VOIs        []VOI
+	// PresentationLUTShape is IDENTITY or INVERSE.
+	PresentationLUTShape string
+	// Rotation clockwise in degrees, applied after Flip.
+	Rotation int
+	Flip     bool
+}
+
+// Parse reads the presentation state of df.
+func Parse(df *dcmdump.DicomFile) (*PresentationState, error) {
+	series := dcmdump.FindElement(df.Elements, "00081115")
+	if series == nil {
+		return nil, ErrNotPresentationState
+	}
+	s := &PresentationState{
+		Label:                dcmdump.ElementString(df.Elements, "00700080"),
+		Description:          dcmdump.ElementString(df.Elements, "00700081"),
+		PresentationLUTShape: dcmdump.ElementString(df.Elements, "20500020"),
+		Rotation:             dcmdump.ElementInt(df.Elements, "00700042"),
+		Flip:                 dcmdump.ElementString(df.Elements, "00700041") == "YES",
+	}
+	for _, item := range series.Items {
+		s.References = append(s.References, references(item.Elements)...)
+	}
+	if de := dcmdump.FindElement(df.Elements, "0070005A"); de != nil {
+		for _, item := range de.Items {
+			s.DisplayedAreas = append(s.DisplayedAreas, newDisplayedArea(item.Elements))
+		}
+	}
+	if de := dcmdump.FindElement(df.Elements, "00700001"); de != nil {
+		for _, item := range de.Items {
+			s.Annotations = append(s.Annotations, newAnnotation(item.Elements))
+		}
+	}
+	if de := dcmdump.FindElement(df.Elements, "00700060"); de != nil {
+		for _, item := range de.Items {
+			l := GraphicLayer{
+				Name:        dcmdump.ElementString(item.Elements, "00700002"),
+				Order:       dcmdump.ElementInt(item.Elements, "00700062"),
+				Gray:        -1,
+				CIELab:      ints(item.Elements, "00700401"),
+				Description: dcmdump.ElementString(item.Elements, "00700068"),
+			}
+			if dcmdump.FindElement(item.Elements, "00700066") != nil {
+				l.Gray = dcmdump.ElementInt(item.Elements, "00700066")
+			}
+			s.Layers = append(s.Layers, l)
+		}
+	}
+	if shapes := dcmdump.ElementString(df.Elements, "00181600"); shapes != "" {
+		sh := &Shutter{
+			Shapes: strings.Split(shapes, `\`),
+			Left:   dcmdump.ElementInt(df.Elements, "00181602"),
+			Right:  dcmdump.ElementInt(df.Elements, "00181604"),
+			Upper:  dcmdump.ElementInt(df.Elements, "00181606"),
+			Lower:  dcmdump.ElementInt(df.Elements, "00181608"),
+			Radius: dcmdump.ElementInt(df.Elements, "00181612"),
+			Value:  dcmdump.ElementInt(df.Elements, "00181622"),
+		}
+		// Shutter coordinates are given as row\column
+		if c := ints(df.Elements, "00181610"); len(c) == 2 {
+			sh.Center = [2]int{c[1], c[0]}
+		}
+		v := ints(df.Elements, "00181620")
+		for i := 0; i+1 < len(v); i += 2 {
+			sh.Vertices = append(sh.Vertices, [2]int{v[i+1], v[i]})
+		}
+		s.Shutter = sh
+	}
+	var err error
+	if de := dcmdump.FindElement(df.Elements, "00283000"); de != nil && len(de.Items) > 0 {
+		s.ModalityLUT, err = dcmdump.ItemLUT(de.Items[0], false)
+		if err != nil {
+			return nil, err
+		}
+	} else if dcmdump.FindElement(df.Elements, "00281053") != nil {
+		s.Rescale = &dcmdump.Rescale{
+			Slope:     floats(df.Elements, "00281053")[0],
+			Intercept: floatValue(df.Elements, "00281052"),
+		}
+	}
+	if de := dcmdump.FindElement(df.Elements, "00283110"); de != nil {
+		for _, item := range de.Items {
+			v := VOI{Images: references(item.Elements)}
+			if lut := dcmdump.FindElement(item.Elements, "00283010"); lut != nil && len(lut.Items) > 0 {
+				v.LUT, err = dcmdump.ItemLUT(lut.Items[0], false)
+				if err != nil {
+					return nil, err
+				}
+			} else if c, w := floats(item.Elements, "00281050"), floats(item.Elements, "00281051"); len(c) > 0 && len(w) > 0 {
+				v.Window = &dcmdump.Window{Center: c[0], Width: w[0], Function: dcmdump.ElementString(item.Elements, "00281056")}
+			} else {
+				continue
+			}
+			s.VOIs = append(s.VOIs, v)
+		}
+	}
+	return s, nil
+}
+
+func newDisplayedArea(elements []dcmdump.DataElement) DisplayedArea {
+	a := DisplayedArea{
+		Images:        references(elements),
+		SizeMode:      dcmdump.ElementString(elements, "00700100"),
+		PixelSpacing:  floats(elements, "00700101"),
+		Magnification: floatValue(elements, "00700103"),
+	}
+	if tl := ints(elements, "00700052"); len(tl) == 2 {
+		a.TopLeft = [2]int{tl[0], tl[1]}
+	}
+	if br := ints(elements, "00700053"); len(br) == 2 {
+		a.BottomRight = [2]int{br[0], br[1]}
+	}
+	return a
+}
+
+func newAnnotation(elements []dcmdump.DataElement) Annotation {
+	a := Annotation{
+		Images: references(elements),
+		Layer:  dcmdump.ElementString(elements, "00700002"),
+	}
+	if de := dcmdump.FindElement(elements, "00700008"); de != nil {
+		for _, item := range de.Items {
+			t := Text{
+				Value:         dcmdump.ElementString(item.Elements, "00700006"),
+				BoundingUnits: dcmdump.ElementString(item.Elements, "00700003"),
+				AnchorUnits:   dcmdump.ElementString(item.Elements, "00700004"),
+				AnchorVisible: dcmdump.ElementString(item.Elements, "00700015") == "Y",
+			}
+			tl, br := points(item.Elements, "00700010"), points(item.Elements, "00700011")
+			if len(tl) == 1 && len(br) == 1 {
+				t.BoundingBox = [2]Point{tl[0], br[0]}
+				t.HasBoundingBox = true
+			}
+			if anchor := points(item.Elements, "00700014"); len(anchor) == 1 {
+				t.Anchor = anchor[0]
+				t.HasAnchor = true
+			}
+			a.Texts = append(a.Texts, t)
+		}
+	}
+	if de := dcmdump.FindElement(elements, "00700009"); de != nil {
+		for _, item := range de.Items {
+			a.Graphics = append(a.Graphics, Graphic{
+				Units:  dcmdump.ElementString(item.Elements, "00700005"),
+				Type:   dcmdump.ElementString(item.Elements, "00700023"),
+				Points: points(item.Elements, "00700022"),
+				Filled: dcmdump.ElementString(item.Elements, "00700024") == "Y",
+			})
+		}
+	}
+	return a
+}
+
+// references reads the ReferencedImageSequence of an item.
+func references(elements []dcmdump.DataElement) []ImageReference {
+	de := dcmdump.FindElement(elements, "00081140")
+	if de == nil {
+		return nil
+	}
+	refs := []ImageReference{}
+	for _, item := range de.Items {
+		refs = append(refs, ImageReference{
+			SOPClassUID:    dcmdump.ElementString(item.Elements, "00081150"),
+			SOPInstanceUID: dcmdump.ElementString(item.Elements, "00081155"),
+			Frames:         ints(item.Elements, "00081160"),
+		})
+	}
+	return refs
+}
+
+// refers reports whether refs include frame, starting at 0, of the image
+// uid. Empty refs include all images.
+func refers(refs []ImageReference, uid string, frame int) bool {
+	if len(refs) == 0 {
+		return true
+	}
+	for _, r := range refs {
+		if r.SOPInstanceUID != uid {
+			continue
+		}
+		if len(r.Frames) == 0 {
+			return true
+		}
+		for _, f := range r.Frames {
+			if f == frame+1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Applies reports whether the presentation state references image.
+func (s *PresentationState) Applies(image *dcmdump.DicomFile) bool {
+	uid := dcmdump.ElementString(image.Elements, "00080018")
+	for _, r := range s.References {
+		if r.SOPInstanceUID == uid {
+			return true
+		}
+	}
+	return false
+}
+
+// Layer returns the graphic layer name, nil when it isn't declared.
+func (s *PresentationState) Layer(name string) *GraphicLayer {
+	for i := range s.Layers {
+		if s.Layers[i].Name == name {
+			return &s.Layers[i]
+		}
+	}
+	return nil
+}
+
+func ints(elements []dcmdump.DataElement, tagStr string) []int {
+	if de := dcmdump.FindElement(elements, tagStr); de != nil {
+		return de.Ints()
+	}
+	return nil
+}
+
+func floatValue(elements []dcmdump.DataElement, tagStr string) float64 {
+	if v := floats(elements, tagStr); len(v) > 0 {
+		return v[0]
+	}
+	return 0
+}
+
+func floats(elements []dcmdump.DataElement, tagStr string) []float64 {
+	if de := dcmdump.FindElement(elements, tagStr); de != nil {
+		return de.Floats()
+	}
+	return nil
+}
+
+// points reads column\row pairs.
+func points(elements []dcmdump.DataElement, tagStr string) []Point {
+	v := floats(elements, tagStr)
+	p := []Point{}
+	for i := 0; i+1 < len(v); i += 2 {
+		p = append(p, Point{X: v[i], Y: v[i+1]})
+	}
+	return p
+}
@@ -0,0 +1,106 @@
+package pr
+
+import (
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestRender(t *testing.T) {
+	element := func(tagStr, vr string, value interface{}) dcmdump.DataElement {
+		de, err := dcmdump.NewDataElement(tagStr, vr, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		return de
+	}
+	item := func(elements ...dcmdump.DataElement) dcmdump.Item {
+		return dcmdump.Item{Elements: elements}
+	}
+	img := &dcmdump.DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+	img.SetElement("00080018", "UI", "1.2.3.4")
+	img.SetElement("00280002", "US", 1)
+	img.SetElement("00280004", "CS", "MONOCHROME2")
+	img.SetElement("00280010", "US", 4)
+	img.SetElement("00280011", "US", 4)
+	img.SetElement("00280100", "US", 8)
+	img.SetElement("00280101", "US", 8)
+	pixels := []byte{}
+	for v := 0; v < 16; v++ {
+		pixels = append(pixels, byte(v))
+	}
+	img.SetElement("7FE00010", "OB", pixels)
+
+	ps := &dcmdump.DicomFile{}
+	ps.SetElement("00081115", "SQ", []dcmdump.Item{
+		item(element("00081140", "SQ", []dcmdump.Item{item(element("00081155", "UI", "1.2.3.4"))})),
+	})
+	ps.SetElement("00181600", "CS", "RECTANGULAR")
+	ps.SetElement("00181602", "IS", 1)
+	ps.SetElement("00181604", "IS", 4)
+	ps.SetElement("00181606", "IS", 1)
+	ps.SetElement("00181608", "IS", 3)
+	ps.SetElement("00181622", "US", 0xffff)
+	ps.SetElement("00283110", "SQ", []dcmdump.Item{
+		item(element("00281050", "DS", 8), element("00281051", "DS", 16), element("00281056", "CS", "LINEAR_EXACT")),
+	})
+	ps.SetElement("00700001", "SQ", []dcmdump.Item{
+		item(
+			element("00700002", "CS", "L1"),
+			element("00700008", "SQ", []dcmdump.Item{item(
+				element("00700004", "CS", "DISPLAY"),
+				element("00700006", "ST", "Lesion"),
+				element("00700014", "FL", []float64{0.5, 0.5}),
+			)}),
+			element("00700009", "SQ", []dcmdump.Item{item(
+				element("00700005", "CS", "PIXEL"),
+				element("00700022", "FL", []float64{3.5, 0.5}),
+				element("00700023", "CS", "POINT"),
+			)}),
+		),
+	})
+	ps.SetElement("00700041", "CS", "YES")
+	ps.SetElement("0070005A", "SQ", []dcmdump.Item{
+		item(element("00700052", "SL", []int{2, 1}), element("00700053", "SL", []int{4, 4}), element("00700100", "CS", "SCALE TO FIT")),
+	})
+	ps.SetElement("00700060", "SQ", []dcmdump.Item{
+		item(element("00700002", "CS", "L1"), element("00700062", "IS", 1), element("00700066", "US", 0x8000)),
+	})
+
+	s, err := Parse(ps)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(s.References) != 1 || s.Shutter == nil || len(s.VOIs) != 1 || len(s.Annotations) != 1 || s.Layers[0].Gray != 0x8000 {
+		t.Fatalf("Wrong presentation state: %+v", s)
+	}
+	view, err := s.Render(img, 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if b := view.Image.Bounds(); b.Dx() != 3 || b.Dy() != 4 {
+		t.Fatalf("Wrong displayed area: %v", b)
+	}
+	for _, c := range []struct{ x, y, value int }{
+		{0, 0, 0x80}, // Annotation
+		{1, 1, 96},   // Column 3 with flip
+		{2, 3, 0xff}, // Shutter
+	} {
+		if v := view.Image.RGBAAt(c.x, c.y); int(v.R) != c.value || v.R != v.B {
+			t.Errorf("Wrong pixel %d,%d: %v", c.x, c.y, v)
+		}
+	}
+	if len(view.Texts) != 1 || view.Texts[0].Anchor != (Point{1.5, 2}) {
+		t.Errorf("Wrong texts: %+v", view.Texts)
+	}
+
+	img.SetElement("00080018", "UI", "1.2.3.5")
+	if _, err = s.Render(img, 0); err != ErrNotReferenced {
+		t.Errorf("Expected ErrNotReferenced, got %v", err)
+	}
+	if _, err = Parse(img); err != ErrNotPresentationState {
+		t.Errorf("Expected ErrNotPresentationState, got %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+package pr
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// View - Image rendered with a presentation state.
+type View struct {
+	Image *image.RGBA
+	// Texts of the annotations, with their bounding boxes and anchors in
+	// pixels of Image. They are not drawn.
+	Texts []Text
+	// PixelSpacing of Image in mm for TRUE SIZE displayed areas, row then
+	// column spacing, nil otherwise.
+	PixelSpacing []float64
+}
+
+// Render returns frame i, starting at 0, of image with the presentation
+// state applied in the order of PS3.4 N.2: modality and VOI transformations,
+// presentation LUT shape, shutter, displayed area, flip, rotation and
+// annotations. MAGNIFY displayed areas are scaled, SCALE TO FIT and TRUE SIZE
+// ones are left to the display.
+// Graphics are drawn in the recommended color of their layer, white when it
+// has none.
+func (s *PresentationState) Render(img *dcmdump.DicomFile, i int) (*View, error) {
+	if !s.Applies(img) {
+		return nil, ErrNotReferenced
+	}
+	uid := dcmdump.ElementString(img.Elements, "00080018")
+	pi, err := img.PixelDataInfo()
+	if err != nil {
+		return nil, err
+	}
+	o := dcmdump.RenderOptions{PresentationLUTShape: s.PresentationLUTShape}
+	if o.PresentationLUTShape == "" {
+		o.PresentationLUTShape = "IDENTITY"
+	}
+	signed := pi.PixelRepresentation == 1
+	rescale := s.Rescale
+	if rescale == nil && s.ModalityLUT == nil {
+		rescale = &dcmdump.Rescale{Slope: 1}
+		if v := floats(img.Elements, "00281053"); len(v) > 0 {
+			rescale.Slope = v[0]
+		}
+		rescale.Intercept = floatValue(img.Elements, "00281052")
+	}
+	if s.ModalityLUT != nil {
+		o.ModalityLUT = signedLUT(s.ModalityLUT, signed)
+	} else {
+		o.Rescale = rescale
+	}
+	for _, v := range s.VOIs {
+		if !refers(v.Images, uid, i) {
+			continue
+		}
+		o.Window = v.Window
+		if v.LUT != nil {
+			o.VOITable = signedLUT(v.LUT, s.ModalityLUT == nil && (signed || rescale.Intercept < 0))
+		}
+		break
+	}
+	if o.Window == nil && o.VOITable == nil {
+		o.Window = identityWindow(img, pi, s.ModalityLUT, rescale)
+	}
+	rendered, err := img.RenderWithOptions(i, o)
+	if err != nil {
+		return nil, err
+	}
+	gray, ok := rendered.(*image.Gray16)
+	if !ok {
+		return nil, dcmdump.ErrPixelFormat
+	}
+	if s.Shutter != nil {
+		s.Shutter.apply(gray)
+	}
+
+	area := DisplayedArea{TopLeft: [2]int{1, 1}, BottomRight: [2]int{pi.Columns, pi.Rows}}
+	for _, a := range s.DisplayedAreas {
+		if refers(a.Images, uid, i) {
+			area = a
+			break
+		}
+	}
+	g := &geometry{
+		x0:       area.TopLeft[0] - 1,
+		y0:       area.TopLeft[1] - 1,
+		w:        area.BottomRight[0] - area.TopLeft[0] + 1,
+		h:        area.BottomRight[1] - area.TopLeft[1] + 1,
+		flip:     s.Flip,
+		rotation: ((s.Rotation % 360) + 360) % 360 / 90 * 90,
+		scale:    1,
+	}
+	if g.w <= 0 || g.h <= 0 {
+		return nil, dcmdump.ErrFrameIndex
+	}
+	if area.SizeMode == "MAGNIFY" && area.Magnification > 0 {
+		g.scale = area.Magnification
+	}
+	view := &View{Image: g.raster(gray)}
+	if area.SizeMode == "TRUE SIZE" {
+		view.PixelSpacing = area.PixelSpacing
+	}
+
+	annotations := []Annotation{}
+	for _, a := range s.Annotations {
+		if refers(a.Images, uid, i) {
+			annotations = append(annotations, a)
+		}
+	}
+	sort.SliceStable(annotations, func(i, j int) bool {
+		return s.layerOrder(annotations[i].Layer) < s.layerOrder(annotations[j].Layer)
+	})
+	size := view.Image.Bounds().Size()
+	for _, a := range annotations {
+		c := s.layerColor(a.Layer)
+		for _, graphic := range a.Graphics {
+			pts := []Point{}
+			for _, p := range graphic.Points {
+				pts = append(pts, g.point(p, graphic.Units, size))
+			}
+			shape := graphic
+			shape.Points = pts
+			shape.draw(view.Image, c)
+		}
+		for _, t := range a.Texts {
+			t.BoundingBox[0] = g.point(t.BoundingBox[0], t.BoundingUnits, size)
+			t.BoundingBox[1] = g.point(t.BoundingBox[1], t.BoundingUnits, size)
+			t.Anchor = g.point(t.Anchor, t.AnchorUnits, size)
+			t.BoundingUnits, t.AnchorUnits = UnitsPixel, UnitsPixel
+			view.Texts = append(view.Texts, t)
+		}
+	}
+	return view, nil
+}
+
+// signedLUT returns l with a signed first mapped value when signed is set.
+func signedLUT(l *dcmdump.LUT, signed bool) *dcmdump.LUT {
+	if !signed || l.FirstMapped < 1<<15 {
+		return l
+	}
+	c := *l
+	c.FirstMapped -= 1 << 16
+	return &c
+}
+
+// identityWindow returns the window mapping the whole range of the modality
+// transformation outputs, as presentation states without VOI transformation
+// do.
+func identityWindow(img *dcmdump.DicomFile, pi *dcmdump.PixelDataInfo, modality *dcmdump.LUT, rescale *dcmdump.Rescale) *dcmdump.Window {
+	var lo, hi float64
+	if modality != nil {
+		hi = float64(modality.Max())
+	} else {
+		bits := dcmdump.ElementInt(img.Elements, "00280101")
+		if bits == 0 {
+			bits = pi.BitsAllocated
+		}
+		hi = float64(int(1)<<uint(bits) - 1)
+		if pi.PixelRepresentation == 1 {
+			lo, hi = -float64(int(1)<<uint(bits-1)), float64(int(1)<<uint(bits-1)-1)
+		}
+		lo, hi = lo*rescale.Slope+rescale.Intercept, hi*rescale.Slope+rescale.Intercept
+		if hi < lo {
+			lo, hi = hi, lo
+		}
+	}
+	return &dcmdump.Window{Center: (lo + hi) / 2, Width: hi - lo, Function: dcmdump.VOILinearExact}
+}
+
+// apply sets the pixels outside of the shutter to its value.
+func (sh *Shutter) apply(img *image.Gray16) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !sh.open(x-b.Min.X+1, y-b.Min.Y+1) {
+				img.SetGray16(x, y, color.Gray16{Y: uint16(sh.Value)})
+			}
+		}
+	}
+}
+
+// open reports whether the pixel at col and row, starting at 1, is inside
+// all of the shapes.
+func (sh *Shutter) open(col, row int) bool {
+	for _, shape := range sh.Shapes {
+		switch shape {
+		case "RECTANGULAR":
+			if col < sh.Left || col > sh.Right || row < sh.Upper || row > sh.Lower {
+				return false
+			}
+		case "CIRCULAR":
+			dx, dy := col-sh.Center[0], row-sh.Center[1]
+			if dx*dx+dy*dy > sh.Radius*sh.Radius {
+				return false
+			}
+		case "POLYGONAL":
+			inside := false
+			for i, j := 0, len(sh.Vertices)-1; i < len(sh.Vertices); j, i = i, i+1 {
+				a, b := sh.Vertices[i], sh.Vertices[j]
+				if (a[1] > row) != (b[1] > row) &&
+					float64(col) < float64(b[0]-a[0])*float64(row-a[1])/float64(b[1]-a[1])+float64(a[0]) {
+					inside = !inside
+				}
+			}
+			if !inside {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// geometry - Spatial transformation from the image to the view.
+type geometry struct {
+	// x0, y0, w and h of the displayed area in pixels from the image origin.
+	x0, y0, w, h int
+	flip         bool
+	rotation     int
+	scale        float64
+}
+
+// transform maps a point of the displayed area before scaling, from its top
+// left corner.
+func (g *geometry) transform(x, y float64) (float64, float64) {
+	w, h := float64(g.w), float64(g.h)
+	if g.flip {
+		x = w - x
+	}
+	switch g.rotation {
+	case 90:
+		x, y = h-y, x
+	case 180:
+		x, y = w-x, h-y
+	case 270:
+		x, y = y, w-x
+	}
+	return x, y
+}
+
+// point maps an annotation point to pixels of the view of the given size.
+func (g *geometry) point(p Point, units string, size image.Point) Point {
+	if units == UnitsDisplay {
+		return Point{X: p.X * float64(size.X), Y: p.Y * float64(size.Y)}
+	}
+	x, y := g.transform(p.X-float64(g.x0), p.Y-float64(g.y0))
+	return Point{X: x * g.scale, Y: y * g.scale}
+}
+
+// raster returns the displayed area of img, black outside of the image,
+// flipped, rotated and scaled.
+func (g *geometry) raster(img *image.Gray16) *image.RGBA {
+	w, h := g.w, g.h
+	if g.rotation == 90 || g.rotation == 270 {
+		w, h = h, w
+	}
+	area := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(area, area.Bounds(), image.Black, image.Point{}, draw.Src)
+	b := img.Bounds()
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			sx, sy := b.Min.X+g.x0+x, b.Min.Y+g.y0+y
+			if !(image.Point{X: sx, Y: sy}).In(b) {
+				continue
+			}
+			v := uint8(img.Gray16At(sx, sy).Y >> 8)
+			tx, ty := g.transform(float64(x)+0.5, float64(y)+0.5)
+			area.SetRGBA(int(tx), int(ty), color.RGBA{R: v, G: v, B: v, A: 0xff})
+		}
+	}
+	if g.scale == 1 {
+		return area
+	}
+	out := image.NewRGBA(image.Rect(0, 0, int(math.Round(float64(w)*g.scale)), int(math.Round(float64(h)*g.scale))))
+	ob := out.Bounds()
+	for y := 0; y < ob.Dy(); y++ {
+		for x := 0; x < ob.Dx(); x++ {
+			out.SetRGBA(x, y, area.RGBAAt(int(float64(x)/g.scale), int(float64(y)/g.scale)))
+		}
+	}
+	return out
+}
+
+// layerOrder returns the order of the layer, annotations of undeclared
+// layers are drawn first.
+func (s *PresentationState) layerOrder(name string) int {
+	if l := s.Layer(name); l != nil {
+		return l.Order
+	}
+	return math.MinInt32
+}
+
+// layerColor returns the recommended color of the layer, from its CIELab
+// value or else its grayscale value, white when it has none.
+func (s *PresentationState) layerColor(name string) color.RGBA {
+	l := s.Layer(name)
+	switch {
+	case l == nil:
+	case len(l.CIELab) == 3:
+		return cieLabToRGB(l.CIELab)
+	case l.Gray >= 0:
+		v := uint8(l.Gray >> 8)
+		return color.RGBA{R: v, G: v, B: v, A: 0xff}
+	}
+	return color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+}
+
+// cieLabToRGB converts a DICOM scaled CIELab value, PS3.3 C.10.7.1.1, to
+// sRGB.
+func cieLabToRGB(lab []int) color.RGBA {
+	l := float64(lab[0]) * 100 / 0xffff
+	a := float64(lab[1])*255/0xffff - 128
+	b := float64(lab[2])*255/0xffff - 128
+	f := func(t float64) float64 {
+		if t > 6.0/29 {
+			return t * t * t
+		}
+		return 3 * (6.0 / 29) * (6.0 / 29) * (t - 4.0/29)
+	}
+	fy := (l + 16) / 116
+	// D50 reference white
+	x, y, z := 0.96422*f(fy+a/500), f(fy), 0.82521*f(fy-b/200)
+	// XYZ D50 to linear sRGB with Bradford adaptation to D65
+	rgb := [3]float64{
+		3.1338561*x - 1.6168667*y - 0.4906146*z,
+		-0.9787684*x + 1.9161415*y + 0.0334540*z,
+		0.0719453*x - 0.2289914*y + 1.4052427*z,
+	}
+	out := [3]uint8{}
+	for i, c := range rgb {
+		if c <= 0.0031308 {
+			c *= 12.92
+		} else {
+			c = 1.055*math.Pow(c, 1/2.4) - 0.055
+		}
+		out[i] = uint8(math.Round(math.Max(0, math.Min(1, c)) * 0xff))
+	}
+	return color.RGBA{R: out[0], G: out[1], B: out[2], A: 0xff}
+}
+
+// draw draws the graphic with points in pixels of img. Circles and ellipses
+// are drawn as polygons.
+func (g Graphic) draw(img *image.RGBA, c color.RGBA) {
+	pts := g.Points
+	closed := false
+	switch g.Type {
+	case "POINT":
+		for _, p := range pts {
+			img.SetRGBA(int(p.X), int(p.Y), c)
+		}
+		return
+	case "CIRCLE":
+		if len(pts) != 2 {
+			return
+		}
+		r := math.Hypot(pts[1].X-pts[0].X, pts[1].Y-pts[0].Y)
+		pts, closed = ellipse(pts[0], r, r, 0), true
+	case "ELLIPSE":
+		if len(pts) != 4 {
+			return
+		}
+		center := Point{X: (pts[0].X + pts[1].X) / 2, Y: (pts[0].Y + pts[1].Y) / 2}
+		a := math.Hypot(pts[1].X-pts[0].X, pts[1].Y-pts[0].Y) / 2
+		b := math.Hypot(pts[3].X-pts[2].X, pts[3].Y-pts[2].Y) / 2
+		angle := math.Atan2(pts[1].Y-pts[0].Y, pts[1].X-pts[0].X)
+		pts, closed = ellipse(center, a, b, angle), true
+	case "POLYLINE", "INTERPOLATED":
+		closed = len(pts) > 2 && pts[0] == pts[len(pts)-1]
+	default:
+		return
+	}
+	if g.Filled && closed {
+		fill(img, pts, c)
+	}
+	for i := 1; i < len(pts); i++ {
+		line(img, pts[i-1], pts[i], c)
+	}
+	if closed && len(pts) > 1 {
+		line(img, pts[len(pts)-1], pts[0], c)
+	}
+}
+
+// ellipse returns the vertices of a polygon approximating an ellipse.
+func ellipse(center Point, a, b, angle float64) []Point {
+	n := int(math.Max(16, math.Ceil(2*math.Pi*math.Max(a, b)/2)))
+	pts := make([]Point, n)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	for i := range pts {
+		t := 2 * math.Pi * float64(i) / float64(n)
+		x, y := a*math.Cos(t), b*math.Sin(t)
+		pts[i] = Point{X: center.X + x*cos - y*sin, Y: center.Y + x*sin + y*cos}
+	}
+	return pts
+}
+
+// line draws a one pixel wide segment.
+func line(img *image.RGBA, p0, p1 Point, c color.RGBA) {
+	steps := int(math.Ceil(math.Max(math.Abs(p1.X-p0.X), math.Abs(p1.Y-p0.Y))))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		img.SetRGBA(int(math.Floor(p0.X+(p1.X-p0.X)*t)), int(math.Floor(p0.Y+(p1.Y-p0.Y)*t)), c)
+	}
+}
+
+// fill fills the pixels whose center is inside the polygon.
+func fill(img *image.RGBA, pts []Point, c color.RGBA) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		cy := float64(y) + 0.5
+		xs := []float64{}
+		for i, j := 0, len(pts)-1; i < len(pts); j, i = i, i+1 {
+			a, b := pts[i], pts[j]
+			if (a.Y > cy) != (b.Y > cy) {
+				xs = append(xs, a.X+(cy-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(xs)
+		for k := 0; k+1 < len(xs); k += 2 {
+			for x := int(math.Ceil(xs[k] - 0.5)); float64(x)+0.5 <= xs[k+1]; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package dcmdump
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// VOI LUT functions
+const (
+	VOILinear      = "LINEAR"
+	VOILinearExact = "LINEAR_EXACT"
+	VOISigmoid     = "SIGMOID"
+)
+
+// Window - VOI LUT window applied when rendering monochrome frames.
+type Window struct {
+	Center float64
+	Width  float64
+	// Function is the VOI LUT Function of the window, LINEAR when empty.
+	Function string
+}
+
+// RenderOptions - Selection of the VOI transformation of RenderWithOptions.
+type RenderOptions struct {
+	// Window is applied instead of the ones of the file when not nil.
+	Window *Window
+	// WindowIndex selects the WindowCenter and WindowWidth values of the file
+	// applied, starting at 0.
+	WindowIndex int
+	// VOILUT selects the item of the VOI LUT Sequence applied instead of the
+	// windows, starting at 1. When 0 the first item is only applied to files
+	// without windows.
+	VOILUT int
+
+	// Rescale replaces the modality transformation of the file when not nil,
+	// see ModalityLUT.
+	Rescale *Rescale
+	// ModalityLUT replaces the modality transformation of the file when not
+	// nil, as presentation states do.
+	ModalityLUT *LUT
+	// VOITable is applied instead of the windows and VOI LUTs of the file
+	// when not nil and Window is.
+	VOITable *LUT
+	// PresentationLUTShape, IDENTITY or INVERSE, replaces the inversion of
+	// MONOCHROME1 frames when not empty.
+	PresentationLUTShape string
+}
+
+// Rescale - Rescale Slope and Intercept of a modality transformation.
+type Rescale struct {
+	Slope     float64
+	Intercept float64
+}
+
+// Render returns frame i, starting at 0, ready to be displayed or encoded as
+// PNG.
+// Monochrome frames have the Rescale Slope and Intercept and the window of
+// the frame applied and are returned as image.Gray16, MONOCHROME1 frames are inverted.
+// When window is nil the first WindowCenter and WindowWidth of the file are
+// used, or the range of the frame values if the file has none.
+// Color frames are returned as image.RGBA, see ColorFrame.
+func (df *DicomFile) Render(i int, window *Window) (image.Image, error) {
+	return df.RenderWithOptions(i, RenderOptions{Window: window})
+}
+
+// RenderWithOptions returns frame i like Render, with the VOI transformation
+// selected by o.
+// The Modality LUT Sequence of the file is applied instead of the Rescale
+// Slope and Intercept when present, the VOI LUT Sequence when selected by o
+// or when the file has no window.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.11.html
+func (df *DicomFile) RenderWithOptions(i int, o RenderOptions) (image.Image, error) {
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return nil, err
+	}
+	frame, err := pi.Frame(i)
+	if err != nil {
+		return nil, err
+	}
+	img, err := frame.Decode()
+	if err != nil {
+		return nil, err
+	}
+	photometric := pi.PhotometricInterpretation
+	if pi.SamplesPerPixel != 1 || photometric == PhotometricPaletteColor {
+		return df.colorImage(img, pi)
+	}
+
+	var modality *LUT
+	slope, intercept := 1.0, 0.0
+	switch {
+	case o.ModalityLUT != nil:
+		modality = o.ModalityLUT
+	case o.Rescale != nil:
+		slope, intercept = o.Rescale.Slope, o.Rescale.Intercept
+	default:
+		modality, err = df.frameLUT(i, "00283000", 1, pi.PixelRepresentation == 1)
+		if err != nil {
+			return nil, err
+		}
+		if v := df.frameFloat(i, "00281053"); v != nil {
+			slope = *v
+		}
+		if v := df.frameFloat(i, "00281052"); v != nil {
+			intercept = *v
+		}
+	}
+	b := img.Bounds()
+	values := make([]float64, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := storedValue(img.At(x, y), pi)
+			if modality != nil {
+				values = append(values, float64(modality.Value(int(v))))
+			} else {
+				values = append(values, v*slope+intercept)
+			}
+		}
+	}
+
+	window := o.Window
+	voi := o.VOITable
+	if window != nil {
+		voi = nil
+	}
+	if window == nil && voi == nil && o.VOILUT == 0 {
+		window = df.frameWindow(i, o.WindowIndex)
+	}
+	if window == nil && voi == nil {
+		index := o.VOILUT
+		if index == 0 {
+			index = 1
+		}
+		// Modality LUT outputs are unsigned, rescaled values may be signed
+		voi, err = df.frameLUT(i, "00283010", index, modality == nil && (pi.PixelRepresentation == 1 || intercept < 0))
+		if err != nil {
+			return nil, err
+		}
+		if voi == nil && o.VOILUT != 0 {
+			return nil, ErrLUT
+		}
+	}
+	if window == nil && voi == nil {
+		window = valueRange(values)
+	}
+
+	out := image.NewGray16(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for p, v := range values {
+		var y uint16
+		if voi != nil {
+			y = uint16(math.Round(float64(voi.Value(int(math.Round(v)))) * 0xffff / float64(voi.Max())))
+		} else {
+			y = window.apply(v)
+		}
+		if o.PresentationLUTShape == "INVERSE" || o.PresentationLUTShape == "" && photometric == PhotometricMonochrome1 {
+			y = 0xffff - y
+		}
+		out.Pix[2*p] = byte(y >> 8)
+		out.Pix[2*p+1] = byte(y)
+	}
+	return out, nil
+}
+
+// frameWindow returns the window of frame i at index, nil when there is
+// none.
+func (df *DicomFile) frameWindow(i, index int) *Window {
+	centers, err := df.FrameElement(i, "00281050")
+	if err != nil {
+		return nil
+	}
+	widths, err := df.FrameElement(i, "00281051")
+	if err != nil {
+		return nil
+	}
+	c, w := centers.Floats(), widths.Floats()
+	if index < 0 || index >= len(c) || index >= len(w) || w[index] < 1 || centers.Strings()[0] == "" {
+		return nil
+	}
+	window := &Window{Center: c[index], Width: w[index]}
+	if functions, err := df.FrameElement(i, "00281056"); err == nil {
+		if f := functions.Strings(); index < len(f) {
+			window.Function = f[index]
+		} else if len(f) > 0 {
+			window.Function = f[0]
+		}
+	}
+	return window
+}
+
+// frameLUT returns the LUT of item index, starting at 1, of the Modality or
+// VOI LUT Sequence of frame i, nil when the frame has none.
+func (df *DicomFile) frameLUT(i int, tagStr string, index int, signed bool) (*LUT, error) {
+	de, err := df.FrameElement(i, tagStr)
+	if err != nil || index > len(de.Items) {
+		return nil, nil
+	}
+	return ItemLUT(de.Items[index-1], signed)
+}
+
+// ItemLUT returns the table of an item of a Modality, VOI or Presentation
+// LUT Sequence from its LUTDescriptor and LUTData. The first mapped value is
+// read as signed when signed is set.
+func ItemLUT(item Item, signed bool) (*LUT, error) {
+	var descriptor, data *DataElement
+	for j, e := range item.Elements {
+		switch e.TagStr {
+		case "00283002":
+			descriptor = &item.Elements[j]
+		case "00283006":
+			data = &item.Elements[j]
+		}
+	}
+	if descriptor == nil || data == nil {
+		return nil, ErrLUT
+	}
+	return newLUT(descriptor, data, signed)
+}
+
+// apply maps v to the output range with the VOI LUT function of the window.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.11.2.html
+func (w *Window) apply(v float64) uint16 {
+	switch {
+	case w.Width <= 0:
+		// Thresholds with every function
+	case w.Function == VOILinearExact:
+		switch {
+		case v <= w.Center-w.Width/2:
+			return 0
+		case v > w.Center+w.Width/2:
+			return 0xffff
+		}
+		return uint16(math.Round(((v-w.Center)/w.Width + 0.5) * 0xffff))
+	case w.Function == VOISigmoid:
+		return uint16(math.Round(0xffff / (1 + math.Exp(-4*(v-w.Center)/w.Width))))
+	}
+	c := w.Center - 0.5
+	width := w.Width - 1
+	switch {
+	case width <= 0:
+		if v <= c {
+			return 0
+		}
+		return 0xffff
+	case v <= c-width/2:
+		return 0
+	case v > c+width/2:
+		return 0xffff
+	}
+	return uint16(((v-c)/width + 0.5) * 0xffff)
+}
+
+// valueRange returns a window covering all values.
+func valueRange(values []float64) *Window {
+	if len(values) == 0 {
+		return &Window{Center: 0, Width: 1}
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return &Window{Center: (lo+hi)/2 + 0.5, Width: hi - lo + 1}
+}
+
+// storedValue returns the stored pixel value of a decoded monochrome pixel.
+func storedValue(c color.Color, pi *PixelDataInfo) float64 {
+	switch c := c.(type) {
+	case color.Gray:
+		if pi.PixelRepresentation == 1 {
+			return float64(int8(c.Y))
+		}
+		return float64(c.Y)
+	case color.Gray16:
+		if pi.PixelRepresentation == 1 {
+			return float64(int16(c.Y))
+		}
+		return float64(c.Y)
+	}
+	y := color.Gray16Model.Convert(c).(color.Gray16).Y
+	return float64(y)
+}
+
+// stringValue returns the first value of a top level element or "".
+func (df *DicomFile) stringValue(tagStr string) string {
+	de, err := df.LookupElement(tagStr)
+	if err != nil {
+		return ""
+	}
+	values := de.Strings()
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
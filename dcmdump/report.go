@@ -0,0 +1,139 @@
+package dcmdump
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Report - Table of elements with a row per file and a column per tag path,
+// see ParseSelector, written as CSV or TSV as files are added. The first
+// column is the path of the file.
+// It is safe for concurrent use.
+type Report struct {
+	// Comma separates the columns, ',' when not set, '\t' for TSV.
+	Comma rune
+	// Join separates the values of multi valued elements and of paths
+	// matching several elements, a backslash when empty.
+	Join string
+	// NoHeader skips the header row naming the columns.
+	NoHeader bool
+
+	columns   []string
+	selectors []Selector
+	mu        sync.Mutex
+	w         io.Writer
+	csv       *csv.Writer
+}
+
+// NewReport returns a Report writing the columns given as tag paths to w.
+func NewReport(w io.Writer, columns []string) (*Report, error) {
+	r := &Report{columns: columns, w: w}
+	for _, c := range columns {
+		s, err := ParseSelector(c)
+		if err != nil {
+			return nil, err
+		}
+		r.selectors = append(r.selectors, s)
+	}
+	return r, nil
+}
+
+// Write adds the row of df, read from path. Rows are buffered, see Flush.
+func (r *Report) Write(path string, df *DicomFile) error {
+	row := []string{path}
+	join := r.Join
+	if join == "" {
+		join = `\`
+	}
+	for _, s := range r.selectors {
+		values := []string{}
+		for _, de := range s.Select(df) {
+			e := *de
+			e.Load()
+			values = append(values, e.Strings()...)
+		}
+		row = append(row, strings.Join(values, join))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.csv == nil {
+		r.csv = csv.NewWriter(r.w)
+		if r.Comma != 0 {
+			r.csv.Comma = r.Comma
+		}
+		if !r.NoHeader {
+			err := r.csv.Write(append([]string{"Path"}, r.columns...))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return r.csv.Write(row)
+}
+
+// WriteDir adds the DICOM files of the tree at root matching filter, all of
+// them when nil, calling failed with the files that couldn't be read. Only
+// the elements of the columns and the filter are parsed.
+func (r *Report) WriteDir(root string, filter *Filter, failed func(path string, err error)) error {
+	w := NewWalker(root, selectorTags(r.selectors))
+	w.Filter = filter
+	results := w.Walk()
+	for result := range results {
+		if result.Err != nil {
+			if failed != nil {
+				failed(result.Path, result.Err)
+			}
+			continue
+		}
+		err := r.Write(result.Path, result.File)
+		if err != nil {
+			// Drain the walk
+			for range results {
+			}
+			return err
+		}
+	}
+	return r.Flush()
+}
+
+func newCSVEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	r, err := NewReport(w, o.Tags)
+	if err != nil {
+		return nil, err
+	}
+	r.Join = o.Join
+	return r, nil
+}
+
+func newTSVEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	r, err := NewReport(w, o.Tags)
+	if err != nil {
+		return nil, err
+	}
+	r.Comma, r.Join = '\t', o.Join
+	return r, nil
+}
+
+// Encode adds the row of df, read from df.Path, as the csv and tsv Encoder.
+func (r *Report) Encode(df *DicomFile) error {
+	return r.Write(df.Path, df)
+}
+
+// Close flushes r as an Encoder.
+func (r *Report) Close() error {
+	return r.Flush()
+}
+
+// Flush writes the buffered rows.
+func (r *Report) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.csv == nil {
+		return nil
+	}
+	r.csv.Flush()
+	return r.csv.Error()
+}
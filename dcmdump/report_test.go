@@ -0,0 +1,35 @@
+package dcmdump
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	df := &DicomFile{}
+	df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY"})
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("0008103E", "LO", `Head, "routine"`)
+	var buf bytes.Buffer
+	r, err := NewReport(&buf, []string{"PatientName", "ImageType", "SeriesDescription", "PatientID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := selectorTags(r.selectors); len(tags) != 4 || tags[0] != "00100010" {
+		t.Errorf("Wrong tags %v", tags)
+	}
+	r.Write("a.dcm", df)
+	r.Write("b.dcm", &DicomFile{})
+	if err = r.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Path,PatientName,ImageType,SeriesDescription,PatientID\n" +
+		`a.dcm,DOE^JOHN,ORIGINAL\PRIMARY,"Head, ""routine""",` + "\n" +
+		"b.dcm,,,,\n"
+	if buf.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, buf.String())
+	}
+	if _, err = NewReport(&buf, []string{"NotAKeyword"}); err != ErrSelector {
+		t.Errorf("Expected ErrSelector, got %v", err)
+	}
+}
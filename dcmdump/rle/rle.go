@@ -0,0 +1,91 @@
+// Package rle decodes the DICOM RLE Lossless transfer syntax.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part05/chapter_G.html
+package rle
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrHeader is returned when the RLE header is missing or its segment count
+// doesn't match the image description.
+var ErrHeader = errors.New("Invalid RLE header")
+
+// ErrSegment is returned when a segment decodes to the wrong number of bytes.
+var ErrSegment = errors.New("Invalid RLE segment")
+
+// Decode decompresses an RLE encoded frame into native pixel data.
+// Samples are returned interleaved (PlanarConfiguration 0) and multi byte
+// samples as little endian.
+func Decode(frame []byte, rows, columns, samplesPerPixel, bitsAllocated int) ([]byte, error) {
+	if len(frame) < 64 || bitsAllocated%8 != 0 {
+		return nil, ErrHeader
+	}
+	bytesPerSample := bitsAllocated / 8
+	segments := int(binary.LittleEndian.Uint32(frame))
+	if segments != samplesPerPixel*bytesPerSample || segments < 1 || segments > 15 {
+		return nil, ErrHeader
+	}
+	offsets := make([]int, segments+1)
+	for i := 0; i < segments; i++ {
+		offsets[i] = int(binary.LittleEndian.Uint32(frame[4+4*i:]))
+	}
+	offsets[segments] = len(frame)
+	pixels := rows * columns
+	for i := 0; i < segments; i++ {
+		if offsets[i] < 64 || offsets[i] > offsets[i+1] {
+			return nil, ErrHeader
+		}
+		// A run of 2 bytes decodes to 128 at most, a frame too short for the
+		// image is rejected before allocating it.
+		if pixels > 64*(offsets[i+1]-offsets[i]) {
+			return nil, ErrSegment
+		}
+	}
+	stride := samplesPerPixel * bytesPerSample
+	out := make([]byte, pixels*stride)
+	for i := 0; i < segments; i++ {
+		data, err := decodeSegment(frame[offsets[i]:offsets[i+1]], pixels)
+		if err != nil {
+			return nil, err
+		}
+		// Segments hold the most significant byte of each sample first
+		sample := i / bytesPerSample
+		pos := sample*bytesPerSample + bytesPerSample - 1 - i%bytesPerSample
+		for p, b := range data {
+			out[p*stride+pos] = b
+		}
+	}
+	return out, nil
+}
+
+// decodeSegment unpacks a PackBits segment of size bytes.
+// Segments are padded to an even length so trailing data is ignored.
+func decodeSegment(data []byte, size int) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for n := 0; n < len(data) && len(out) < size; {
+		h := int8(data[n])
+		n++
+		switch {
+		case h >= 0:
+			l := int(h) + 1
+			if n+l > len(data) {
+				return nil, ErrSegment
+			}
+			out = append(out, data[n:n+l]...)
+			n += l
+		case h != -128:
+			if n >= len(data) {
+				return nil, ErrSegment
+			}
+			for i := 0; i < 1-int(h); i++ {
+				out = append(out, data[n])
+			}
+			n++
+		}
+	}
+	if len(out) < size {
+		return nil, ErrSegment
+	}
+	return out[:size], nil
+}
@@ -0,0 +1,53 @@
+package rle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	// 2x2 16 bit image: 0x0102 0x0102 0x0304 0x0506
+	high := []byte{0xff, 0x01, 0x01, 0x03, 0x05, 0x80}
+	low := []byte{0xff, 0x02, 0x01, 0x04, 0x06}
+	header := make([]byte, 64)
+	binary.LittleEndian.PutUint32(header[0:], 2)
+	binary.LittleEndian.PutUint32(header[4:], 64)
+	binary.LittleEndian.PutUint32(header[8:], uint32(64+len(high)))
+	frame := append(append(header, high...), low...)
+
+	out, err := Decode(frame, 2, 2, 1, 16)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []byte{0x02, 0x01, 0x02, 0x01, 0x04, 0x03, 0x06, 0x05}
+	if !bytes.Equal(out, expected) {
+		t.Errorf("Expected %x, got %x", expected, out)
+	}
+
+	_, err = Decode(frame, 2, 2, 3, 8)
+	if err != ErrHeader {
+		t.Errorf("Expected ErrHeader, got %v", err)
+	}
+	_, err = Decode(frame, 3, 2, 1, 16)
+	if err != ErrSegment {
+		t.Errorf("Expected ErrSegment, got %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	header := make([]byte, 64)
+	binary.LittleEndian.PutUint32(header[0:], 1)
+	binary.LittleEndian.PutUint32(header[4:], 64)
+	f.Add(append(header, 0xff, 0x01, 0x01, 0x03), 2, 2, 1, 8)
+	f.Fuzz(func(t *testing.T, frame []byte, rows, columns, samplesPerPixel, bitsAllocated int) {
+		if rows < 0 || rows > 0xFFFF || columns < 0 || columns > 0xFFFF || samplesPerPixel < 0 || samplesPerPixel > 4 {
+			// Values out of the US range of the image description
+			return
+		}
+		out, err := Decode(frame, rows, columns, samplesPerPixel, bitsAllocated)
+		if err == nil && len(out) != rows*columns*samplesPerPixel*bitsAllocated/8 {
+			t.Errorf("Wrong size %d", len(out))
+		}
+	})
+}
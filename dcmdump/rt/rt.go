@@ -0,0 +1,159 @@
+// Package rt reads the regions of interest and contours of RT Structure Set
+// files, PS3.3 C.8.8.5 and C.8.8.6.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.8.8.5.html
+package rt
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// ErrNotStructureSet is returned for files without a StructureSetROISequence.
+var ErrNotStructureSet = errors.New("Not an RT Structure Set")
+
+// Point - Patient based coordinates in mm.
+type Point [3]float64
+
+// Contour - Item of the ContourSequence of an ROI.
+type Contour struct {
+	Number int
+	// GeometricType is POINT, OPEN_PLANAR, OPEN_NONPLANAR or CLOSED_PLANAR.
+	GeometricType string
+	Points        []Point
+	// ImageUIDs are the SOPInstanceUIDs of the ContourImageSequence.
+	ImageUIDs []string
+}
+
+// ROI - Region of interest of the StructureSetROISequence with the contours
+// of its ROIContourSequence item.
+type ROI struct {
+	Number              int
+	Name                string
+	Description         string
+	FrameOfReferenceUID string
+	GenerationAlgorithm string
+	// Color is the ROIDisplayColor as RGB.
+	Color []int
+	// InterpretedType of the RTROIObservationsSequence, for example PTV or
+	// ORGAN.
+	InterpretedType string
+	Contours        []Contour
+}
+
+// StructureSet - RT Structure Set module.
+type StructureSet struct {
+	Label       string
+	Name        string
+	Description string
+	ROIs        []*ROI
+}
+
+// Parse reads the structure set and the contours of each of its ROIs.
+func Parse(df *dcmdump.DicomFile) (*StructureSet, error) {
+	rois := dcmdump.FindElement(df.Elements, "30060020")
+	if rois == nil {
+		return nil, ErrNotStructureSet
+	}
+	s := &StructureSet{
+		Label:       dcmdump.ElementString(df.Elements, "30060002"),
+		Name:        dcmdump.ElementString(df.Elements, "30060004"),
+		Description: dcmdump.ElementString(df.Elements, "30060006"),
+	}
+	byNumber := map[int]*ROI{}
+	for _, item := range rois.Items {
+		r := &ROI{
+			Number:              dcmdump.ElementInt(item.Elements, "30060022"),
+			Name:                dcmdump.ElementString(item.Elements, "30060026"),
+			Description:         dcmdump.ElementString(item.Elements, "30060028"),
+			FrameOfReferenceUID: dcmdump.ElementString(item.Elements, "30060024"),
+			GenerationAlgorithm: dcmdump.ElementString(item.Elements, "30060036"),
+		}
+		s.ROIs = append(s.ROIs, r)
+		byNumber[r.Number] = r
+	}
+	if contours := dcmdump.FindElement(df.Elements, "30060039"); contours != nil {
+		for _, item := range contours.Items {
+			n := dcmdump.ElementInt(item.Elements, "30060084")
+			r, ok := byNumber[n]
+			if !ok {
+				return nil, fmt.Errorf("ROIContourSequence references unknown ROI %d", n)
+			}
+			if de := dcmdump.FindElement(item.Elements, "3006002A"); de != nil {
+				r.Color = de.Ints()
+			}
+			seq := dcmdump.FindElement(item.Elements, "30060040")
+			if seq == nil {
+				continue
+			}
+			for _, c := range seq.Items {
+				contour, err := newContour(c.Elements)
+				if err != nil {
+					return nil, fmt.Errorf("ROI %d: %s", n, err)
+				}
+				r.Contours = append(r.Contours, contour)
+			}
+		}
+	}
+	if observations := dcmdump.FindElement(df.Elements, "30060080"); observations != nil {
+		for _, item := range observations.Items {
+			if r, ok := byNumber[dcmdump.ElementInt(item.Elements, "30060084")]; ok {
+				r.InterpretedType = dcmdump.ElementString(item.Elements, "300600A4")
+			}
+		}
+	}
+	return s, nil
+}
+
+func newContour(elements []dcmdump.DataElement) (Contour, error) {
+	c := Contour{
+		Number:        dcmdump.ElementInt(elements, "30060048"),
+		GeometricType: dcmdump.ElementString(elements, "30060042"),
+	}
+	if de := dcmdump.FindElement(elements, "30060050"); de != nil {
+		data := de.Floats()
+		if len(data)%3 != 0 {
+			return c, fmt.Errorf("ContourData with %d values is not a list of points", len(data))
+		}
+		c.Points = make([]Point, len(data)/3)
+		for i := range c.Points {
+			c.Points[i] = Point{data[3*i], data[3*i+1], data[3*i+2]}
+		}
+		if n := dcmdump.FindElement(elements, "30060046"); n != nil && len(n.Ints()) > 0 && n.Ints()[0] != len(c.Points) {
+			return c, fmt.Errorf("NumberOfContourPoints %d doesn't match ContourData with %d points", n.Ints()[0], len(c.Points))
+		}
+	}
+	if images := dcmdump.FindElement(elements, "30060016"); images != nil {
+		for _, item := range images.Items {
+			c.ImageUIDs = append(c.ImageUIDs, dcmdump.ElementString(item.Elements, "00081155"))
+		}
+	}
+	return c, nil
+}
+
+// ROI returns the ROI with the given name, nil if not found.
+func (s *StructureSet) ROI(name string) *ROI {
+	for _, r := range s.ROIs {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// ContoursByImage groups the contours of the ROI by the SOPInstanceUID of
+// their referenced images. Contours without image references are keyed by an
+// empty UID.
+func (r *ROI) ContoursByImage() map[string][]Contour {
+	m := map[string][]Contour{}
+	for _, c := range r.Contours {
+		if len(c.ImageUIDs) == 0 {
+			m[""] = append(m[""], c)
+		}
+		for _, uid := range c.ImageUIDs {
+			m[uid] = append(m[uid], c)
+		}
+	}
+	return m
+}
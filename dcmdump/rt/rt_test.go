@@ -0,0 +1,73 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func TestParse(t *testing.T) {
+	element := func(tagStr, vr string, value interface{}) dcmdump.DataElement {
+		de, err := dcmdump.NewDataElement(tagStr, vr, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		return de
+	}
+	item := func(elements ...dcmdump.DataElement) dcmdump.Item {
+		return dcmdump.Item{Elements: elements}
+	}
+	contour := func(number int, uid string, points ...float64) dcmdump.Item {
+		return item(
+			element("30060016", "SQ", []dcmdump.Item{item(element("00081155", "UI", uid))}),
+			element("30060042", "CS", "CLOSED_PLANAR"),
+			element("30060046", "IS", len(points)/3),
+			element("30060048", "IS", number),
+			element("30060050", "DS", points),
+		)
+	}
+	df := &dcmdump.DicomFile{}
+	df.SetElement("30060002", "SH", "RS1")
+	df.SetElement("30060020", "SQ", []dcmdump.Item{
+		item(element("30060022", "IS", 1), element("30060026", "LO", "BODY")),
+		item(element("30060022", "IS", 2), element("30060026", "LO", "PTV")),
+	})
+	df.SetElement("30060039", "SQ", []dcmdump.Item{
+		item(
+			element("3006002A", "IS", []int{0, 255, 0}),
+			element("30060040", "SQ", []dcmdump.Item{
+				contour(1, "1.2.3.1", 0, 0, 10, 1, 0, 10, 1, 1, 10),
+				contour(2, "1.2.3.2", 0, 0, 12.5, 1, 1, 12.5),
+			}),
+			element("30060084", "IS", 2),
+		),
+	})
+	df.SetElement("30060080", "SQ", []dcmdump.Item{
+		item(element("30060084", "IS", 2), element("300600A4", "CS", "PTV")),
+	})
+
+	s, err := Parse(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if s.Label != "RS1" || len(s.ROIs) != 2 || len(s.ROIs[0].Contours) != 0 {
+		t.Fatalf("Wrong structure set: %+v", s)
+	}
+	r := s.ROI("PTV")
+	if r == nil || r.InterpretedType != "PTV" || len(r.Color) != 3 || len(r.Contours) != 2 {
+		t.Fatalf("Wrong ROI: %+v", r)
+	}
+	if p := r.Contours[0].Points; len(p) != 3 || p[2] != (Point{1, 1, 10}) {
+		t.Errorf("Wrong contour points: %v", p)
+	}
+	byImage := r.ContoursByImage()
+	if len(byImage["1.2.3.2"]) != 1 || byImage["1.2.3.2"][0].Number != 2 {
+		t.Errorf("Wrong contours by image: %v", byImage)
+	}
+
+	_, err = Parse(&dcmdump.DicomFile{})
+	if err != ErrNotStructureSet {
+		t.Errorf("Expected ErrNotStructureSet, got %v", err)
+	}
+}
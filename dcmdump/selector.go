@@ -0,0 +1,117 @@
+package dcmdump
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+)
+
+// ErrSelector is returned for invalid tag paths.
+var ErrSelector = errors.New("Invalid tag path")
+
+// allItems selects every item of a sequence.
+const allItems = -1
+
+// selectorStep - Element of a Selector and, for sequences, its item.
+type selectorStep struct {
+	// tag is blank for the * wildcard.
+	tag  string
+	item int
+}
+
+// Selector - Parsed tag path of Get.
+type Selector []selectorStep
+
+// ParseSelector parses a tag path: elements given by keyword, GGGGEEEE or
+// (gggg,eeee) tag, or * for any element, separated by dots. Items of
+// sequences are selected with [n], starting at 0, or [*] for all of them, the
+// default.
+//
+//	RequestedProcedureCodeSequence[0].CodeValue
+//	ReferencedSeriesSequence[*].(0020,000E)
+//	*[*].CodeMeaning
+func ParseSelector(path string) (Selector, error) {
+	s := Selector{}
+	for _, part := range strings.Split(path, ".") {
+		st := selectorStep{item: allItems}
+		if i := strings.Index(part, "["); i >= 0 {
+			if !strings.HasSuffix(part, "]") {
+				return nil, ErrSelector
+			}
+			index := part[i+1 : len(part)-1]
+			if index != "*" {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, ErrSelector
+				}
+				st.item = n
+			}
+			part = part[:i]
+		}
+		t := strings.NewReplacer("(", "", ")", "", ",", "").Replace(part)
+		switch {
+		case part == "*":
+		case len(t) == 8 && strings.Trim(strings.ToUpper(t), "0123456789ABCDEF") == "":
+			st.tag = strings.ToUpper(t)
+		default:
+			info, ok := tag.ByKeyword(part)
+			if !ok {
+				return nil, ErrSelector
+			}
+			st.tag = info.TagStr()
+		}
+		s = append(s, st)
+	}
+	return s, nil
+}
+
+// Select returns the elements of df matching s, in file order.
+func (s Selector) Select(df *DicomFile) []*DataElement {
+	df.mu.RLock()
+	defer df.mu.RUnlock()
+	matches := []*DataElement{}
+	level := [][]DataElement{df.Elements}
+	for n, st := range s {
+		next := [][]DataElement{}
+		for _, elements := range level {
+			for i := range elements {
+				de := &elements[i]
+				if st.tag != "" && de.TagStr != st.tag {
+					continue
+				}
+				if n == len(s)-1 {
+					matches = append(matches, de)
+					continue
+				}
+				for j := range de.Items {
+					if st.item == allItems || st.item == j {
+						next = append(next, de.Items[j].Elements)
+					}
+				}
+			}
+		}
+		level = next
+	}
+	return matches
+}
+
+// Get returns the elements matching a tag path, see ParseSelector.
+func (df *DicomFile) Get(path string) ([]*DataElement, error) {
+	s, err := ParseSelector(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(df), nil
+}
+
+// GetString returns the values of the first element matching a tag path
+// joined with a backslash, false when none matches.
+func (df *DicomFile) GetString(path string) (string, bool, error) {
+	matches, err := df.Get(path)
+	if err != nil || len(matches) == 0 {
+		return "", false, err
+	}
+	return strings.Join(matches[0].Strings(), `\`), true, nil
+}
@@ -0,0 +1,62 @@
+package dcmdump
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	item := func(code, meaning string) Item {
+		v, err := NewDataElement("00080100", "SH", code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewDataElement("00080104", "LO", meaning)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.PartOfSQ, m.PartOfSQ = true, true
+		return Item{Elements: []DataElement{v, m}}
+	}
+	df := &DicomFile{}
+	df.SetElement("00100010", "PN", "DOE^JOHN")
+	df.SetElement("00321064", "SQ", []Item{item("CT1", "Head"), item("CT2", "Chest")})
+
+	cases := []struct {
+		path     string
+		expected []string
+	}{
+		{"PatientName", []string{"DOE^JOHN"}},
+		{"RequestedProcedureCodeSequence[0].CodeValue", []string{"CT1"}},
+		{"(0032,1064)[1].00080104", []string{"Chest"}},
+		{"RequestedProcedureCodeSequence[*].CodeValue", []string{"CT1", "CT2"}},
+		{"RequestedProcedureCodeSequence.CodeMeaning", []string{"Head", "Chest"}},
+		{"*[1].*", []string{"CT2", "Chest"}},
+		{"RequestedProcedureCodeSequence[2].CodeValue", []string{}},
+		{"PatientID", []string{}},
+	}
+	for _, c := range cases {
+		matches, err := df.Get(c.path)
+		if err != nil {
+			t.Fatalf("%s: %s", c.path, err)
+		}
+		values := []string{}
+		for _, de := range matches {
+			values = append(values, de.Strings()...)
+		}
+		if len(values) != len(c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.path, c.expected, values)
+			continue
+		}
+		for i := range values {
+			if values[i] != c.expected[i] {
+				t.Errorf("%s: expected %v, got %v", c.path, c.expected, values)
+			}
+		}
+	}
+	if v, ok, err := df.GetString("RequestedProcedureCodeSequence[1].CodeValue"); err != nil || !ok || v != "CT2" {
+		t.Errorf("Wrong GetString: %q %v %v", v, ok, err)
+	}
+	for _, path := range []string{"NotAKeyword", "PatientName[x]", "RequestedProcedureCodeSequence[0.CodeValue", ""} {
+		if _, err := df.Get(path); err != ErrSelector {
+			t.Errorf("%s: expected ErrSelector, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package sr
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// WriteHTML renders the content tree as nested HTML lists.
+func (c *ContentItem) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<ul class=\"sr\">\n")
+	c.html(&b, 1)
+	b.WriteString("</ul>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c *ContentItem) html(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(b, "%s<li class=\"%s\">", indent, strings.ToLower(c.ValueType))
+	if c.ConceptName != nil {
+		fmt.Fprintf(b, "<b>%s</b>", html.EscapeString(c.ConceptName.Meaning))
+	}
+	if v := c.String(); v != "" {
+		if c.ConceptName != nil {
+			b.WriteString(": ")
+		}
+		b.WriteString(html.EscapeString(v))
+	}
+	if len(c.Children) > 0 {
+		fmt.Fprintf(b, "\n%s<ul>\n", indent)
+		for _, child := range c.Children {
+			child.html(b, depth+1)
+		}
+		fmt.Fprintf(b, "%s</ul>\n%s", indent, indent)
+	}
+	b.WriteString("</li>\n")
+}
+
+// String returns the value of the item as text, empty for containers.
+func (c *ContentItem) String() string {
+	switch c.ValueType {
+	case Num:
+		if c.Value == nil {
+			return ""
+		}
+		s := fmt.Sprint(*c.Value)
+		if c.Units != nil && c.Units.Value != "1" {
+			s += " " + c.Units.Value
+		}
+		return s
+	case CodeType:
+		return c.Code.String()
+	case SCoord, SCoord3D:
+		return fmt.Sprintf("%s %v", c.GraphicType, c.GraphicData)
+	case Image, Composite, Waveform:
+		uids := []string{}
+		for _, r := range c.References {
+			uids = append(uids, r.SOPInstanceUID)
+		}
+		return strings.Join(uids, ", ")
+	}
+	return c.Text
+}
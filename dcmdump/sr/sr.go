@@ -0,0 +1,170 @@
+// Package sr reads DICOM Structured Report documents into a tree of content
+// items, PS3.3 C.17.3.
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part03/sect_C.17.3.html
+package sr
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+// ErrNotSR is returned for files without an SR document content.
+var ErrNotSR = errors.New("Not a Structured Report")
+
+// Value types
+const (
+	Container = "CONTAINER"
+	Text      = "TEXT"
+	Num       = "NUM"
+	CodeType  = "CODE"
+	SCoord    = "SCOORD"
+	SCoord3D  = "SCOORD3D"
+	Image     = "IMAGE"
+	Composite = "COMPOSITE"
+	Waveform  = "WAVEFORM"
+	Date      = "DATE"
+	Time      = "TIME"
+	DateTime  = "DATETIME"
+	UIDRef    = "UIDREF"
+	PName     = "PNAME"
+)
+
+// Code - Coded entry of a code sequence item.
+type Code struct {
+	Value   string `json:"value"`
+	Scheme  string `json:"scheme"`
+	Meaning string `json:"meaning"`
+}
+
+func (c *Code) String() string {
+	if c == nil {
+		return ""
+	}
+	return c.Meaning
+}
+
+// Reference - SOP instance referenced by IMAGE, COMPOSITE and WAVEFORM items.
+type Reference struct {
+	SOPClassUID    string `json:"sopClassUID"`
+	SOPInstanceUID string `json:"sopInstanceUID"`
+	Frames         []int  `json:"frames,omitempty"`
+}
+
+// ContentItem - Node of the content tree. Only the fields of its ValueType
+// are set.
+type ContentItem struct {
+	ValueType        string `json:"valueType"`
+	RelationshipType string `json:"relationshipType,omitempty"`
+	ConceptName      *Code  `json:"conceptName,omitempty"`
+	// Text of TEXT items and value of DATE, TIME, DATETIME, UIDREF and
+	// PNAME items.
+	Text string `json:"text,omitempty"`
+	// Value and Units of NUM items.
+	Value *float64 `json:"value,omitempty"`
+	Units *Code    `json:"units,omitempty"`
+	// Code of CODE items.
+	Code *Code `json:"code,omitempty"`
+	// GraphicType and GraphicData of SCOORD and SCOORD3D items.
+	GraphicType string    `json:"graphicType,omitempty"`
+	GraphicData []float64 `json:"graphicData,omitempty"`
+	// References of IMAGE, COMPOSITE and WAVEFORM items.
+	References []Reference `json:"references,omitempty"`
+	// ContinuityOfContent of CONTAINER items, SEPARATE or CONTINUOUS.
+	ContinuityOfContent string         `json:"continuityOfContent,omitempty"`
+	Children            []*ContentItem `json:"children,omitempty"`
+}
+
+// Parse returns the root CONTAINER of the document in df with its content
+// tree.
+func Parse(df *dcmdump.DicomFile) (*ContentItem, error) {
+	if dcmdump.FindElement(df.Elements, "0040A040") == nil || dcmdump.FindElement(df.Elements, "0040A730") == nil {
+		return nil, ErrNotSR
+	}
+	return contentItem(df.Elements)
+}
+
+func contentItem(elements []dcmdump.DataElement) (*ContentItem, error) {
+	c := &ContentItem{
+		ValueType:        dcmdump.ElementString(elements, "0040A040"),
+		RelationshipType: dcmdump.ElementString(elements, "0040A010"),
+		ConceptName:      code(elements, "0040A043"),
+	}
+	switch c.ValueType {
+	case Container:
+		c.ContinuityOfContent = dcmdump.ElementString(elements, "0040A050")
+	case Text:
+		c.Text = dcmdump.ElementString(elements, "0040A160")
+	case Date:
+		c.Text = dcmdump.ElementString(elements, "0040A121")
+	case Time:
+		c.Text = dcmdump.ElementString(elements, "0040A122")
+	case DateTime:
+		c.Text = dcmdump.ElementString(elements, "0040A120")
+	case UIDRef:
+		c.Text = dcmdump.ElementString(elements, "0040A124")
+	case PName:
+		c.Text = dcmdump.ElementString(elements, "0040A123")
+	case Num:
+		if mv := dcmdump.FindElement(elements, "0040A300"); mv != nil && len(mv.Items) > 0 {
+			m := mv.Items[0].Elements
+			if v := dcmdump.ElementString(m, "0040A30A"); v != "" {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, err
+				}
+				c.Value = &f
+			}
+			c.Units = code(m, "004008EA")
+		}
+	case CodeType:
+		c.Code = code(elements, "0040A168")
+	case SCoord, SCoord3D:
+		c.GraphicType = dcmdump.ElementString(elements, "00700023")
+		if de := dcmdump.FindElement(elements, "00700022"); de != nil {
+			c.GraphicData = de.Floats()
+		}
+	case Image, Composite, Waveform:
+		if refs := dcmdump.FindElement(elements, "00081199"); refs != nil {
+			for _, item := range refs.Items {
+				r := Reference{
+					SOPClassUID:    dcmdump.ElementString(item.Elements, "00081150"),
+					SOPInstanceUID: dcmdump.ElementString(item.Elements, "00081155"),
+				}
+				if de := dcmdump.FindElement(item.Elements, "00081160"); de != nil {
+					r.Frames = de.Ints()
+				}
+				c.References = append(c.References, r)
+			}
+		}
+	}
+	if content := dcmdump.FindElement(elements, "0040A730"); content != nil {
+		for _, item := range content.Items {
+			child, err := contentItem(item.Elements)
+			if err != nil {
+				return nil, err
+			}
+			c.Children = append(c.Children, child)
+		}
+	}
+	return c, nil
+}
+
+// code returns the first item of a code sequence.
+func code(elements []dcmdump.DataElement, tagStr string) *Code {
+	de := dcmdump.FindElement(elements, tagStr)
+	if de == nil || len(de.Items) == 0 {
+		return nil
+	}
+	item := de.Items[0].Elements
+	c := &Code{
+		Value:   dcmdump.ElementString(item, "00080100"),
+		Scheme:  dcmdump.ElementString(item, "00080102"),
+		Meaning: dcmdump.ElementString(item, "00080104"),
+	}
+	if c.Value == "" {
+		c.Value = dcmdump.ElementString(item, "00080119")
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package sr
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump"
+)
+
+func element(t *testing.T, tagStr, vr string, value interface{}) dcmdump.DataElement {
+	de, err := dcmdump.NewDataElement(tagStr, vr, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	de.PartOfSQ = true
+	return de
+}
+
+func codeSequence(t *testing.T, tagStr, value, scheme, meaning string) dcmdump.DataElement {
+	return element(t, tagStr, "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{
+		element(t, "00080100", "SH", value),
+		element(t, "00080102", "SH", scheme),
+		element(t, "00080104", "LO", meaning),
+	}}})
+}
+
+func TestParse(t *testing.T) {
+	num := []dcmdump.DataElement{
+		element(t, "0040A010", "CS", "CONTAINS"),
+		element(t, "0040A040", "CS", "NUM"),
+		codeSequence(t, "0040A043", "G-D705", "SRT", "Volume"),
+		element(t, "0040A300", "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{
+			codeSequence(t, "004008EA", "ml", "UCUM", "milliliter"),
+			element(t, "0040A30A", "DS", 12.5),
+		}}}),
+	}
+	text := []dcmdump.DataElement{
+		element(t, "0040A010", "CS", "CONTAINS"),
+		element(t, "0040A040", "CS", "TEXT"),
+		codeSequence(t, "0040A043", "121071", "DCM", "Finding"),
+		element(t, "0040A160", "UT", "Mass <2cm>"),
+	}
+	image := []dcmdump.DataElement{
+		element(t, "00081199", "SQ", []dcmdump.Item{{Elements: []dcmdump.DataElement{
+			element(t, "00081150", "UI", "1.2.840.10008.5.1.4.1.1.2"),
+			element(t, "00081155", "UI", "1.2.3.4"),
+		}}}),
+		element(t, "0040A010", "CS", "INFERRED FROM"),
+		element(t, "0040A040", "CS", "IMAGE"),
+	}
+	num = append(num, element(t, "0040A730", "SQ", []dcmdump.Item{{Elements: image}}))
+
+	df := &dcmdump.DicomFile{}
+	df.SetElement("0040A040", "CS", "CONTAINER")
+	df.SetElement("0040A043", "SQ", codeSequence(t, "0040A043", "126000", "DCM", "Imaging Measurement Report").Items)
+	df.SetElement("0040A050", "CS", "SEPARATE")
+	df.SetElement("0040A730", "SQ", []dcmdump.Item{{Elements: num}, {Elements: text}})
+
+	root, err := Parse(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if root.ValueType != Container || root.ConceptName.Meaning != "Imaging Measurement Report" || len(root.Children) != 2 {
+		t.Fatalf("Wrong root: %+v", root)
+	}
+	n := root.Children[0]
+	if n.Value == nil || *n.Value != 12.5 || n.Units.Value != "ml" || n.String() != "12.5 ml" {
+		t.Errorf("Wrong NUM item: %+v", n)
+	}
+	if len(n.Children) != 1 || n.Children[0].References[0].SOPInstanceUID != "1.2.3.4" {
+		t.Errorf("Wrong IMAGE item: %+v", n.Children)
+	}
+	if root.Children[1].Text != "Mass <2cm>" {
+		t.Errorf("Wrong TEXT item: %+v", root.Children[1])
+	}
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(string(b), `"relationshipType":"INFERRED FROM"`) {
+		t.Errorf("Wrong JSON: %s", b)
+	}
+	var buf bytes.Buffer
+	err = root.WriteHTML(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), "<b>Finding</b>: Mass &lt;2cm&gt;") {
+		t.Errorf("Wrong HTML: %s", buf.String())
+	}
+
+	_, err = Parse(&dcmdump.DicomFile{})
+	if err != ErrNotSR {
+		t.Errorf("Expected ErrNotSR, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// FrameReader - Reads the frames of the pixel data one at a time.
+// With files parsed by ParseFileLazy the PixelData value is left on disk and
+// each frame is read from the file when requested, so only one frame is held
+// in memory at a time.
+type FrameReader struct {
+	Info *PixelDataInfo
+
+	next int
+	// de is the PixelData element, read from its source when deferred.
+	de *DataElement
+	// table and fragments index the encapsulated items of a deferred value.
+	table     []uint32
+	fragments []fragmentRef
+}
+
+// fragmentRef - Position of a fragment value in the source.
+type fragmentRef struct {
+	// offset of the item tag from the first fragment.
+	offset int
+	pos    int64
+	length int
+}
+
+// FrameReader returns a reader over the frames of the file.
+func (df *DicomFile) FrameReader() (*FrameReader, error) {
+	de, err := df.LookupElement("7FE00010")
+	if err != nil {
+		return nil, ErrNoPixelData
+	}
+	pi, err := df.pixelDataInfo(de)
+	if err != nil {
+		return nil, err
+	}
+	fr := &FrameReader{Info: pi, de: de}
+	if de.Deferred && de.UndefinedLen {
+		err = fr.index()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fr, nil
+}
+
+// Next returns the next frame, io.EOF is returned after the last one.
+func (fr *FrameReader) Next() (*Frame, error) {
+	if fr.next >= fr.Info.NumberOfFrames {
+		return nil, io.EOF
+	}
+	frame, err := fr.ReadFrame(fr.next)
+	if err != nil {
+		return nil, err
+	}
+	fr.next++
+	return frame, nil
+}
+
+// ReadFrame returns frame i, starting at 0.
+// The frame data of deferred values is newly allocated on each call.
+func (fr *FrameReader) ReadFrame(i int) (*Frame, error) {
+	if !fr.de.Deferred {
+		return fr.Info.Frame(i)
+	}
+	if i < 0 || i >= fr.Info.NumberOfFrames {
+		return nil, ErrFrameIndex
+	}
+	if fr.de.UndefinedLen {
+		return fr.encapsulatedFrame(i)
+	}
+	start, size, err := fr.Info.frameRange(i, int(fr.de.Len))
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	_, err = fr.de.source.ReadAt(data, fr.de.ValueOffset+int64(start))
+	if err != nil {
+		return nil, err
+	}
+	return &Frame{Data: data, info: fr.Info}, nil
+}
+
+func (fr *FrameReader) encapsulatedFrame(i int) (*Frame, error) {
+	offsets := make([]int, len(fr.fragments))
+	for j, f := range fr.fragments {
+		offsets[j] = f.offset
+	}
+	start, end, err := frameFragments(fr.table, offsets, fr.Info.NumberOfFrames, i)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, f := range fr.fragments[start:end] {
+		size += f.length
+	}
+	data := make([]byte, size)
+	n := 0
+	for _, f := range fr.fragments[start:end] {
+		_, err = fr.de.source.ReadAt(data[n:n+f.length], f.pos)
+		if err != nil {
+			return nil, err
+		}
+		n += f.length
+	}
+	return &Frame{Data: data, info: fr.Info}, nil
+}
+
+// index reads the item headers of the deferred encapsulated value and its
+// Basic Offset Table.
+// Encapsulated pixel data is always little endian.
+func (fr *FrameReader) index() error {
+	header := make([]byte, 8)
+	end := fr.de.ValueOffset + int64(fr.de.Len)
+	offset := 0
+	first := true
+	for pos := fr.de.ValueOffset; pos+8 <= end; {
+		_, err := fr.de.source.ReadAt(header, pos)
+		if err != nil {
+			return err
+		}
+		h, _, _ := DecodeElementHeader(header, false, binary.LittleEndian)
+		if h.Tag() != "FFFEE000" {
+			return &ParseError{Offset: int(pos), Tag: h.Tag(), Err: ErrMissingDelimiter}
+		}
+		l := int(h.Length)
+		pos += 8
+		if pos+int64(l) > end {
+			return &ParseError{Offset: int(pos - 8), Tag: "FFFEE000", Err: io.ErrUnexpectedEOF}
+		}
+		if first {
+			table := make([]byte, l)
+			_, err = fr.de.source.ReadAt(table, pos)
+			if err != nil {
+				return err
+			}
+			fr.table = []uint32{}
+			for n := 0; n+4 <= len(table); n += 4 {
+				fr.table = append(fr.table, binary.LittleEndian.Uint32(table[n:]))
+			}
+			first = false
+		} else {
+			fr.fragments = append(fr.fragments, fragmentRef{offset: offset, pos: pos, length: l})
+			offset += 8 + l
+		}
+		pos += int64(l)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package dcmdump
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestFrameReader(t *testing.T) {
+	item := func(value ...byte) []byte {
+		return append([]byte{0xfe, 0xff, 0x00, 0xe0, byte(len(value)), 0, 0, 0}, value...)
+	}
+	meta := make([]byte, 128)
+	meta = append(meta, "DICM"...)
+	meta = append(meta, explicitElement(0x0002, 0x0000, "UL", []byte{28, 0, 0, 0})...)
+	meta = append(meta, explicitElement(0x0002, 0x0010, "UI", []byte(ts.ExplicitVRLittleEndian+"\x00"))...)
+	meta = append(meta, explicitElement(0x0028, 0x0008, "IS", []byte("2 "))...)
+	meta = append(meta, explicitElement(0x0028, 0x0010, "US", []byte{1, 0})...)
+	meta = append(meta, explicitElement(0x0028, 0x0011, "US", []byte{2, 0})...)
+	meta = append(meta, explicitElement(0x0028, 0x0100, "US", []byte{8, 0})...)
+
+	native := append(append([]byte{}, meta...), explicitElement(0x7FE0, 0x0010, "OB", []byte{1, 2, 3, 4})...)
+	encapsulated := append(append([]byte{}, meta...), 0xe0, 0x7f, 0x10, 0x00, 'O', 'B', 0, 0, 0xff, 0xff, 0xff, 0xff)
+	encapsulated = append(encapsulated, item()...)
+	encapsulated = append(encapsulated, item(1, 2, 3, 4)...)
+	encapsulated = append(encapsulated, item(5, 6)...)
+	encapsulated = append(encapsulated, 0xfe, 0xff, 0xdd, 0xe0, 0, 0, 0, 0)
+
+	for _, c := range []struct {
+		name   string
+		data   []byte
+		frames [][]byte
+	}{
+		{"native", native, [][]byte{{1, 2}, {3, 4}}},
+		{"encapsulated", encapsulated, [][]byte{{1, 2, 3, 4}, {5, 6}}},
+	} {
+		df, err := ParseFileLazy(bytes.NewReader(c.data), 3)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+		de, _ := df.LookupElement("7FE00010")
+		if !de.Deferred || de.Data != nil {
+			t.Errorf("%s: PixelData not deferred", c.name)
+		}
+		fr, err := df.FrameReader()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.name, err)
+		}
+		for i, expected := range c.frames {
+			frame, err := fr.Next()
+			if err != nil || !bytes.Equal(frame.Data, expected) {
+				t.Errorf("%s: wrong frame %d: %v, %v", c.name, i, frame, err)
+			}
+		}
+		if _, err = fr.Next(); err != io.EOF {
+			t.Errorf("%s: expected io.EOF, got %v", c.name, err)
+		}
+		if _, err = fr.ReadFrame(2); err != ErrFrameIndex {
+			t.Errorf("%s: expected ErrFrameIndex, got %v", c.name, err)
+		}
+
+		// The loaded value holds the same frames
+		var buf bytes.Buffer
+		err = df.Write(&buf, ts.ExplicitVRLittleEndian)
+		if err != nil || !bytes.Equal(buf.Bytes(), c.data) {
+			t.Errorf("%s: deferred value not written: %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tag
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Info - Data element dictionary entry.
+// VR and VM are empty for the entries without them in the dictionary.
+type Info struct {
+	Group   uint16
+	Element uint16
+	// Keyword as used in the dictionary, for example PatientName.
+	Keyword string
+	// Name is the keyword split into words, for example Patient Name.
+	Name string
+	VR   string
+	VM   string
+}
+
+// String returns the tag in (gggg,eeee) form.
+func (i Info) String() string {
+	return fmt.Sprintf("(%04X,%04X)", i.Group, i.Element)
+}
+
+// TagStr returns the tag as used for the Tag keys, GGGGEEEE in uppercase.
+func (i Info) TagStr() string {
+	return fmt.Sprintf("%04X%04X", i.Group, i.Element)
+}
+
+var indexOnce sync.Once
+var keywords map[string]string
+var repeating []string
+
+func index() {
+	keywords = map[string]string{}
+	for t, e := range TagRange {
+		keywords[e["name"]] = t
+		repeating = append(repeating, t)
+	}
+	for t, e := range Tag {
+		// Keep the lowest tag of keywords used more than once
+		if k, ok := keywords[e["name"]]; ok && !strings.Contains(k, "X") && k < t {
+			continue
+		}
+		keywords[e["name"]] = t
+	}
+}
+
+// Find returns the dictionary entry of a tag.
+// Repeating groups like curves (50xx) and overlays (60xx) are matched against
+// the TagRange masks.
+func Find(group, element uint16) (Info, bool) {
+	indexOnce.Do(index)
+	t := fmt.Sprintf("%04X%04X", group, element)
+	e, ok := Tag[t]
+	if !ok {
+		for _, mask := range repeating {
+			if matchMask(mask, t) {
+				e, ok = TagRange[mask], true
+				break
+			}
+		}
+	}
+	if !ok {
+		return Info{Group: group, Element: element}, false
+	}
+	return newInfo(group, element, e), true
+}
+
+// ByKeyword returns the dictionary entry for a keyword, for example
+// PatientName.
+// Repeating group entries are returned with their first group.
+func ByKeyword(keyword string) (Info, bool) {
+	indexOnce.Do(index)
+	t, ok := keywords[keyword]
+	if !ok {
+		return Info{}, false
+	}
+	var group, element uint16
+	fmt.Sscanf(strings.Replace(t, "X", "0", -1), "%04X%04X", &group, &element)
+	e, ok := Tag[t]
+	if !ok {
+		e = TagRange[t]
+	}
+	return newInfo(group, element, e), true
+}
+
+func newInfo(group, element uint16, e map[string]string) Info {
+	return Info{
+		Group:   group,
+		Element: element,
+		Keyword: e["name"],
+		Name:    splitKeyword(e["name"]),
+		VR:      e["vr"],
+		VM:      e["vm"],
+	}
+}
+
+func matchMask(mask, t string) bool {
+	for i := range mask {
+		if mask[i] != 'X' && mask[i] != t[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitKeyword splits a keyword on its words, keeping acronyms together:
+// SOPInstanceUID is SOP Instance UID.
+func splitKeyword(keyword string) string {
+	r := []rune(keyword)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(r); i++ {
+		lower := unicode.IsLower(r[i-1]) || unicode.IsDigit(r[i-1])
+		acronymEnd := unicode.IsUpper(r[i-1]) && i+1 < len(r) && unicode.IsLower(r[i+1])
+		if unicode.IsUpper(r[i]) && (lower || acronymEnd) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(r[start:]))
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,56 @@
+package tag
+
+import (
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	i, ok := Find(0x0010, 0x0010)
+	if !ok || i.Keyword != "PatientName" || i.Name != "Patient Name" || i.VR != "PN" || i.VM != "1" {
+		t.Errorf("Wrong PatientName entry: %+v", i)
+	}
+	i, ok = ByKeyword("SOPInstanceUID")
+	if !ok || i.TagStr() != "00080018" || i.Name != "SOP Instance UID" || i.String() != "(0008,0018)" {
+		t.Errorf("Wrong SOPInstanceUID entry: %+v", i)
+	}
+	i, ok = Find(0x5002, 0x0005)
+	if !ok || i.Keyword != "CurveDimensions" || i.Group != 0x5002 {
+		t.Errorf("Repeating group not matched: %+v", i)
+	}
+	_, ok = ByKeyword("NotAKeyword")
+	if ok {
+		t.Errorf("Unexpected entry for unknown keyword")
+	}
+}
+
+func TestFindPrivate(t *testing.T) {
+	i, ok := FindPrivate("SIEMENS CSA HEADER", 0x0029, 0x1110)
+	if !ok || i.Keyword != "CSAImageHeaderInfo" || i.VR != "OB" {
+		t.Errorf("Wrong CSA entry: %+v", i)
+	}
+	err := RegisterPrivate("ACME 1.0", map[string]map[string]string{
+		"0011xx01": {"name": "AcmeValue", "vr": "LO", "vm": "1"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, key := range []string{"0029", "0011xy02", "0011xx0G", "0011xx0200"} {
+		err = RegisterPrivate("ACME 1.0", map[string]map[string]string{
+			"0011xx02": {"name": "AcmeOther", "vr": "LO", "vm": "1"},
+			key:        {"name": "AcmeInvalid", "vr": "LO", "vm": "1"},
+		})
+		if err == nil {
+			t.Errorf("Expected an error for %q", key)
+		}
+	}
+	if _, ok := FindPrivate("ACME 1.0", 0x0011, 0x1202); ok {
+		t.Errorf("Dictionary with invalid keys registered")
+	}
+	i, ok = FindPrivate("ACME 1.0 ", 0x0011, 0x1201)
+	if !ok || i.Keyword != "AcmeValue" {
+		t.Errorf("Registered entry not found: %+v", i)
+	}
+	if block, ok := PrivateBlock(0x0011, 0x1201); !ok || block != 0x12 {
+		t.Errorf("Wrong private block: %02X", block)
+	}
+}
@@ -0,0 +1,122 @@
+package tag
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Private dictionaries are keyed by private creator, their tags use xx for the
+// block reserved by the private creator element, for example "0029xx10".
+var privateMu sync.RWMutex
+var private = map[string]map[string]map[string]string{
+	"SIEMENS CSA HEADER": {
+		"0029xx08": {"name": "CSAImageHeaderType", "vr": "CS", "vm": "1"},
+		"0029xx09": {"name": "CSAImageHeaderVersion", "vr": "LO", "vm": "1"},
+		"0029xx10": {"name": "CSAImageHeaderInfo", "vr": "OB", "vm": "1"},
+		"0029xx18": {"name": "CSASeriesHeaderType", "vr": "CS", "vm": "1"},
+		"0029xx19": {"name": "CSASeriesHeaderVersion", "vr": "LO", "vm": "1"},
+		"0029xx20": {"name": "CSASeriesHeaderInfo", "vr": "OB", "vm": "1"},
+	},
+	"SIEMENS MR HEADER": {
+		"0019xx08": {"name": "CSAImageHeaderType", "vr": "CS", "vm": "1"},
+		"0019xx09": {"name": "CSAImageHeaderVersion", "vr": "LO", "vm": "1"},
+		"0019xx0B": {"name": "SliceMeasurementDuration", "vr": "DS", "vm": "1"},
+		"0019xx0C": {"name": "BValue", "vr": "IS", "vm": "1"},
+		"0019xx0D": {"name": "DiffusionDirectionality", "vr": "CS", "vm": "1"},
+		"0019xx0E": {"name": "DiffusionGradientDirection", "vr": "FD", "vm": "3"},
+		"0019xx27": {"name": "BMatrix", "vr": "FD", "vm": "6"},
+		"0019xx28": {"name": "BandwidthPerPixelPhaseEncode", "vr": "FD", "vm": "1"},
+		"0019xx29": {"name": "MosaicRefAcqTimes", "vr": "FD", "vm": "1-n"},
+		"0051xx0B": {"name": "AcquisitionMatrixText", "vr": "SH", "vm": "1"},
+		"0051xx0F": {"name": "CoilString", "vr": "LO", "vm": "1"},
+	},
+	"GEMS_IDEN_01": {
+		"0009xx01": {"name": "FullFidelity", "vr": "LO", "vm": "1"},
+		"0009xx02": {"name": "SuiteID", "vr": "SH", "vm": "1"},
+	},
+	"GEMS_ACQU_01": {
+		"0019xx9C": {"name": "PulseSequenceName", "vr": "LO", "vm": "1"},
+		"0019xxBB": {"name": "UserData20", "vr": "DS", "vm": "1"},
+		"0019xxBC": {"name": "UserData21", "vr": "DS", "vm": "1"},
+		"0019xxBD": {"name": "UserData22", "vr": "DS", "vm": "1"},
+	},
+	"GEMS_PARM_01": {
+		"0043xx39": {"name": "SliceIntensityScaling", "vr": "IS", "vm": "4"},
+	},
+	"Philips Imaging DD 001": {
+		"2001xx03": {"name": "DiffusionBFactor", "vr": "FL", "vm": "1"},
+		"2001xx04": {"name": "DiffusionDirection", "vr": "CS", "vm": "1"},
+	},
+	"Philips MR Imaging DD 001": {
+		"2005xxB0": {"name": "DiffusionDirectionRL", "vr": "FL", "vm": "1"},
+		"2005xxB1": {"name": "DiffusionDirectionAP", "vr": "FL", "vm": "1"},
+		"2005xxB2": {"name": "DiffusionDirectionFH", "vr": "FL", "vm": "1"},
+	},
+}
+
+// RegisterPrivate adds the private dictionary of a private creator, merged
+// with any dictionary already registered for it.
+// Entries use the Tag format with xx for the private block, for example
+// {"0029xx10": {"name": "CSAImageHeaderInfo", "vr": "OB", "vm": "1"}}.
+// Nothing is registered when a key doesn't have the ggggxxee shape.
+func RegisterPrivate(creator string, dict map[string]map[string]string) error {
+	for t := range dict {
+		if !privateKey(t) {
+			return fmt.Errorf("Invalid private tag '%s', expected ggggxxee", t)
+		}
+	}
+	privateMu.Lock()
+	defer privateMu.Unlock()
+	if private[creator] == nil {
+		private[creator] = map[string]map[string]string{}
+	}
+	for t, e := range dict {
+		private[creator][strings.ToUpper(t[:4])+"xx"+strings.ToUpper(t[6:])] = e
+	}
+	return nil
+}
+
+// privateKey reports whether t is 4 hex digits, xx and 2 hex digits.
+func privateKey(t string) bool {
+	if len(t) != 8 || strings.ToLower(t[4:6]) != "xx" {
+		return false
+	}
+	for _, c := range t[:4] + t[6:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindPrivate returns the dictionary entry of a private tag reserved by the
+// given private creator.
+func FindPrivate(creator string, group, element uint16) (Info, bool) {
+	privateMu.RLock()
+	defer privateMu.RUnlock()
+	dict, ok := private[strings.TrimSpace(creator)]
+	if !ok {
+		return Info{Group: group, Element: element}, false
+	}
+	e, ok := dict[fmt.Sprintf("%04Xxx%02X", group, element&0xff)]
+	if !ok {
+		return Info{Group: group, Element: element}, false
+	}
+	return newInfo(group, element, e), true
+}
+
+// IsPrivateCreator reports whether the tag is a private creator element,
+// (gggg,0010-00FF) on an odd group.
+func IsPrivateCreator(group, element uint16) bool {
+	return group%2 == 1 && element >= 0x10 && element <= 0xff
+}
+
+// PrivateBlock returns the private creator element reserving a private tag,
+// ok is false for tags outside of private blocks.
+func PrivateBlock(group, element uint16) (uint16, bool) {
+	if group%2 == 0 || element < 0x1000 {
+		return 0, false
+	}
+	return element >> 8, true
+}
@@ -0,0 +1,364 @@
+package tag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// vrvm - VR and VM of common data elements, merged into Tag and TagRange as
+// "vr" and "vm".
+// http://dicom.nema.org/medical/dicom/current/output/html/part06.html#chapter_6
+var vrvm = map[string][2]string{
+	"00020000": {"UL", "1"},
+	"00020001": {"OB", "1"},
+	"00020002": {"UI", "1"},
+	"00020003": {"UI", "1"},
+	"00020010": {"UI", "1"},
+	"00020012": {"UI", "1"},
+	"00020013": {"SH", "1"},
+	"00020016": {"AE", "1"},
+	"00020100": {"UI", "1"},
+	"00020102": {"OB", "1"},
+	"00041130": {"CS", "1"},
+	"00041200": {"UL", "1"},
+	"00041202": {"UL", "1"},
+	"00041212": {"US", "1"},
+	"00041220": {"SQ", "1"},
+	"00041400": {"UL", "1"},
+	"00041410": {"US", "1"},
+	"00041420": {"UL", "1"},
+	"00041430": {"CS", "1"},
+	"00041500": {"CS", "1-8"},
+	"00041510": {"UI", "1"},
+	"00041511": {"UI", "1"},
+	"00041512": {"UI", "1"},
+	"00080005": {"CS", "1-n"},
+	"00080008": {"CS", "2-n"},
+	"00080012": {"DA", "1"},
+	"00080013": {"TM", "1"},
+	"00080014": {"UI", "1"},
+	"00080016": {"UI", "1"},
+	"00080018": {"UI", "1"},
+	"00080020": {"DA", "1"},
+	"00080021": {"DA", "1"},
+	"00080022": {"DA", "1"},
+	"00080023": {"DA", "1"},
+	"0008002A": {"DT", "1"},
+	"00080030": {"TM", "1"},
+	"00080031": {"TM", "1"},
+	"00080032": {"TM", "1"},
+	"00080033": {"TM", "1"},
+	"00080050": {"SH", "1"},
+	"00080052": {"CS", "1"},
+	"00080054": {"AE", "1-n"},
+	"00080056": {"CS", "1"},
+	"00080060": {"CS", "1"},
+	"00080061": {"CS", "1-n"},
+	"00080062": {"UI", "1-n"},
+	"00080064": {"CS", "1"},
+	"00080070": {"LO", "1"},
+	"00080080": {"LO", "1"},
+	"00080081": {"ST", "1"},
+	"00080090": {"PN", "1"},
+	"00080092": {"ST", "1"},
+	"00080094": {"SH", "1-n"},
+	"00080096": {"SQ", "1"},
+	"00080100": {"SH", "1"},
+	"00080102": {"SH", "1"},
+	"00080103": {"SH", "1"},
+	"00080104": {"LO", "1"},
+	"00080201": {"SH", "1"},
+	"00081010": {"SH", "1"},
+	"00081030": {"LO", "1"},
+	"00081032": {"SQ", "1"},
+	"0008103E": {"LO", "1"},
+	"00081040": {"LO", "1"},
+	"00081048": {"PN", "1-n"},
+	"00081050": {"PN", "1-n"},
+	"00081060": {"PN", "1-n"},
+	"00081070": {"PN", "1-n"},
+	"00081080": {"LO", "1-n"},
+	"00081090": {"LO", "1"},
+	"00081110": {"SQ", "1"},
+	"00081111": {"SQ", "1"},
+	"00081115": {"SQ", "1"},
+	"00081120": {"SQ", "1"},
+	"00081140": {"SQ", "1"},
+	"00081150": {"UI", "1"},
+	"00081155": {"UI", "1"},
+	"00081160": {"IS", "1-n"},
+	"00081199": {"SQ", "1"},
+	"00082111": {"ST", "1"},
+	"00082112": {"SQ", "1"},
+	"00089007": {"CS", "4"},
+	"00100010": {"PN", "1"},
+	"00100020": {"LO", "1"},
+	"00100021": {"LO", "1"},
+	"00100030": {"DA", "1"},
+	"00100032": {"TM", "1"},
+	"00100040": {"CS", "1"},
+	"00101000": {"LO", "1-n"},
+	"00101001": {"PN", "1-n"},
+	"00101002": {"SQ", "1"},
+	"00101010": {"AS", "1"},
+	"00101020": {"DS", "1"},
+	"00101030": {"DS", "1"},
+	"00101040": {"LO", "1"},
+	"00101060": {"PN", "1"},
+	"00102154": {"SH", "1-n"},
+	"00102160": {"SH", "1"},
+	"00102180": {"SH", "1"},
+	"001021B0": {"LT", "1"},
+	"00104000": {"LT", "1"},
+	"00120062": {"CS", "1"},
+	"00120063": {"LO", "1-n"},
+	"00120064": {"SQ", "1"},
+	"00180010": {"LO", "1"},
+	"00180015": {"CS", "1"},
+	"00180020": {"CS", "1-n"},
+	"00180021": {"CS", "1-n"},
+	"00180022": {"CS", "1-n"},
+	"00180023": {"CS", "1"},
+	"00180024": {"SH", "1"},
+	"00180050": {"DS", "1"},
+	"00180060": {"DS", "1"},
+	"00180080": {"DS", "1"},
+	"00180081": {"DS", "1"},
+	"00180082": {"DS", "1"},
+	"00180083": {"DS", "1"},
+	"00180084": {"DS", "1"},
+	"00180085": {"SH", "1"},
+	"00180086": {"IS", "1-n"},
+	"00180087": {"DS", "1"},
+	"00180088": {"DS", "1"},
+	"00180091": {"IS", "1"},
+	"00181000": {"LO", "1"},
+	"00181010": {"LO", "1"},
+	"00181020": {"LO", "1-n"},
+	"00181030": {"LO", "1"},
+	"00181088": {"IS", "1"},
+	"00181150": {"IS", "1"},
+	"00181151": {"IS", "1"},
+	"00181152": {"IS", "1"},
+	"00181164": {"DS", "2"},
+	"00181210": {"SH", "1-n"},
+	"00181250": {"SH", "1"},
+	"00181310": {"US", "4"},
+	"00181314": {"DS", "1"},
+	"00185100": {"CS", "1"},
+	"0020000D": {"UI", "1"},
+	"0020000E": {"UI", "1"},
+	"00200010": {"SH", "1"},
+	"00200011": {"IS", "1"},
+	"00200012": {"IS", "1"},
+	"00200013": {"IS", "1"},
+	"00200020": {"CS", "2"},
+	"00200032": {"DS", "3"},
+	"00200037": {"DS", "6"},
+	"00200052": {"UI", "1"},
+	"00200060": {"CS", "1"},
+	"00200100": {"IS", "1"},
+	"00200105": {"IS", "1"},
+	"00200200": {"UI", "1"},
+	"00201040": {"LO", "1"},
+	"00201041": {"DS", "1"},
+	"00201200": {"IS", "1"},
+	"00201202": {"IS", "1"},
+	"00201204": {"IS", "1"},
+	"00201206": {"IS", "1"},
+	"00201208": {"IS", "1"},
+	"00201209": {"IS", "1"},
+	"00204000": {"LT", "1"},
+	"00209056": {"SH", "1"},
+	"00209057": {"UL", "1"},
+	"00209071": {"SQ", "1"},
+	"00209111": {"SQ", "1"},
+	"00209113": {"SQ", "1"},
+	"00209116": {"SQ", "1"},
+	"00209157": {"UL", "1-n"},
+	"00209221": {"SQ", "1"},
+	"00209222": {"SQ", "1"},
+	"00280002": {"US", "1"},
+	"00280004": {"CS", "1"},
+	"00280006": {"US", "1"},
+	"00280008": {"IS", "1"},
+	"00280009": {"AT", "1-n"},
+	"00280010": {"US", "1"},
+	"00280011": {"US", "1"},
+	"00280030": {"DS", "2"},
+	"00280034": {"IS", "2"},
+	"00280100": {"US", "1"},
+	"00280101": {"US", "1"},
+	"00280102": {"US", "1"},
+	"00280103": {"US", "1"},
+	"00280106": {"US", "1"},
+	"00280107": {"US", "1"},
+	"00280120": {"US", "1"},
+	"00280301": {"CS", "1"},
+	"00281040": {"CS", "1"},
+	"00281050": {"DS", "1-n"},
+	"00281051": {"DS", "1-n"},
+	"00281052": {"DS", "1"},
+	"00281053": {"DS", "1"},
+	"00281054": {"LO", "1"},
+	"00281055": {"LO", "1-n"},
+	"00281101": {"US", "3"},
+	"00281102": {"US", "3"},
+	"00281103": {"US", "3"},
+	"00281201": {"OW", "1"},
+	"00281202": {"OW", "1"},
+	"00281203": {"OW", "1"},
+	"00282110": {"CS", "1"},
+	"00282112": {"DS", "1-n"},
+	"00282114": {"CS", "1-n"},
+	"00283000": {"SQ", "1"},
+	"00283002": {"US", "3"},
+	"00283003": {"LO", "1"},
+	"00283004": {"LO", "1"},
+	"00283006": {"US", "1-n"},
+	"00283010": {"SQ", "1"},
+	"00289110": {"SQ", "1"},
+	"00289132": {"SQ", "1"},
+	"00289145": {"SQ", "1"},
+	"00321032": {"PN", "1"},
+	"00321060": {"LO", "1"},
+	"00380010": {"LO", "1"},
+	"003A0004": {"CS", "1"},
+	"003A0005": {"US", "1"},
+	"003A0010": {"UL", "1"},
+	"003A001A": {"DS", "1"},
+	"003A0020": {"SH", "1"},
+	"003A0200": {"SQ", "1"},
+	"003A0202": {"IS", "1"},
+	"003A0203": {"SH", "1"},
+	"003A0205": {"CS", "1-n"},
+	"003A0208": {"SQ", "1"},
+	"003A0210": {"DS", "1"},
+	"003A0211": {"SQ", "1"},
+	"003A0212": {"DS", "1"},
+	"003A0213": {"DS", "1"},
+	"003A0214": {"DS", "1"},
+	"003A021A": {"US", "1"},
+	"003A0220": {"DS", "1"},
+	"003A0221": {"DS", "1"},
+	"00400001": {"AE", "1-n"},
+	"00400002": {"DA", "1"},
+	"00400003": {"TM", "1"},
+	"00400006": {"PN", "1"},
+	"00400007": {"LO", "1"},
+	"00400009": {"SH", "1"},
+	"00400010": {"SH", "1-n"},
+	"00400100": {"SQ", "1"},
+	"00400244": {"DA", "1"},
+	"00400245": {"TM", "1"},
+	"00400252": {"CS", "1"},
+	"00400253": {"SH", "1"},
+	"00400254": {"LO", "1"},
+	"00400275": {"SQ", "1"},
+	"004008EA": {"SQ", "1"},
+	"00401001": {"SH", "1"},
+	"0040A010": {"CS", "1"},
+	"0040A040": {"CS", "1"},
+	"0040A043": {"SQ", "1"},
+	"0040A050": {"CS", "1"},
+	"0040A120": {"DT", "1"},
+	"0040A121": {"DA", "1"},
+	"0040A122": {"TM", "1"},
+	"0040A123": {"PN", "1"},
+	"0040A124": {"UI", "1"},
+	"0040A160": {"UT", "1"},
+	"0040A168": {"SQ", "1"},
+	"0040A300": {"SQ", "1"},
+	"0040A30A": {"DS", "1-n"},
+	"0040A730": {"SQ", "1"},
+	"00540081": {"US", "1"},
+	"00700022": {"FL", "2-n"},
+	"00700023": {"CS", "1"},
+	"00880140": {"UI", "1"},
+	"30060002": {"SH", "1"},
+	"30060004": {"LO", "1"},
+	"30060006": {"ST", "1"},
+	"30060016": {"SQ", "1"},
+	"30060020": {"SQ", "1"},
+	"30060022": {"IS", "1"},
+	"30060024": {"UI", "1"},
+	"30060026": {"LO", "1"},
+	"30060028": {"ST", "1"},
+	"3006002A": {"IS", "3"},
+	"30060036": {"CS", "1"},
+	"30060039": {"SQ", "1"},
+	"30060040": {"SQ", "1"},
+	"30060042": {"CS", "1"},
+	"30060046": {"IS", "1"},
+	"30060048": {"IS", "1"},
+	"30060050": {"DS", "3-3n"},
+	"30060080": {"SQ", "1"},
+	"30060084": {"IS", "1"},
+	"300600A4": {"CS", "1"},
+	"52009229": {"SQ", "1"},
+	"52009230": {"SQ", "1"},
+	"54000100": {"SQ", "1"},
+	"54001004": {"US", "1"},
+	"54001006": {"CS", "1"},
+	"54001010": {"OW", "1"},
+	"60XX0010": {"US", "1"},
+	"60XX0011": {"US", "1"},
+	"60XX0015": {"IS", "1"},
+	"60XX0022": {"LO", "1"},
+	"60XX0040": {"CS", "1"},
+	"60XX0045": {"LO", "1"},
+	"60XX0050": {"SS", "2"},
+	"60XX0051": {"US", "1"},
+	"60XX0100": {"US", "1"},
+	"60XX0102": {"US", "1"},
+	"60XX1500": {"LO", "1"},
+	"60XX3000": {"OW", "1"},
+	"7FE00008": {"OF", "1"},
+	"7FE00009": {"OD", "1"},
+	"7FE00010": {"OW", "1"},
+	"FFFEE000": {"", "1"},
+	"FFFEE00D": {"", "1"},
+	"FFFEE0DD": {"", "1"},
+}
+
+func init() {
+	for t, v := range vrvm {
+		e, ok := Tag[t]
+		if !ok {
+			e, ok = TagRange[t]
+		}
+		if !ok {
+			continue
+		}
+		e["vr"] = v[0]
+		e["vm"] = v[1]
+	}
+}
+
+// MatchVM reports whether n values are allowed by a dictionary VM, as in "1",
+// "1-3", "1-n" or "2-2n". Unknown VM forms allow any number of values.
+func MatchVM(vm string, n int) bool {
+	parts := strings.SplitN(vm, "-", 2)
+	min, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return true
+	}
+	if len(parts) == 1 {
+		return n == min
+	}
+	if strings.HasSuffix(parts[1], "n") {
+		step := 1
+		if s := strings.TrimSuffix(parts[1], "n"); s != "" {
+			step, err = strconv.Atoi(s)
+			if err != nil {
+				return true
+			}
+		}
+		return n >= min && n%step == 0
+	}
+	max, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return true
+	}
+	return n >= min && n <= max
+}
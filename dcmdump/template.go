@@ -0,0 +1,86 @@
+package dcmdump
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrTemplate is returned for templates with unbalanced braces or empty
+// fields.
+var ErrTemplate = errors.New("Invalid template")
+
+// ErrOutsideDir is returned by JoinTemplate for expanded names leading out of
+// their directory.
+var ErrOutsideDir = errors.New("Path outside of the destination directory")
+
+// ExpandTemplate replaces the {Field} and {Field:default} fields of template
+// for file names and paths. Fields are looked up in vars first, then as a
+// keyword or GGGGEEEE tag of the top level elements of df; default, or an
+// empty string, replaces fields without a value.
+// Values are made safe for file names: path separators, characters invalid on
+// common file systems and control characters are replaced with _, multiple
+// values are joined with _ and the values . and .. are replaced with _.
+//
+//	{PatientID}/{StudyDate}-{StudyDescription:NoDescription}/{SeriesNumber}/{InstanceNumber}.dcm
+func (df *DicomFile) ExpandTemplate(template string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		open := strings.IndexAny(template, "{}")
+		if open < 0 {
+			b.WriteString(template)
+			return b.String(), nil
+		}
+		if template[open] == '}' {
+			return "", ErrTemplate
+		}
+		b.WriteString(template[:open])
+		template = template[open+1:]
+		end := strings.IndexAny(template, "{}")
+		if end <= 0 || template[end] == '{' {
+			return "", ErrTemplate
+		}
+		field, def := template[:end], ""
+		if i := strings.Index(field, ":"); i >= 0 {
+			field, def = field[:i], field[i+1:]
+		}
+		template = template[end+1:]
+		v, ok := vars[field]
+		if !ok {
+			if de, found := df.Lookup(field); found {
+				v = strings.Join(de.Strings(), `\`)
+			}
+		}
+		v = safeName(v)
+		if v == "" {
+			v = def
+		}
+		b.WriteString(v)
+	}
+}
+
+// JoinTemplate joins dir and a name returned by ExpandTemplate, it returns
+// ErrOutsideDir when the result isn't under dir, as the fixed parts of the
+// template and the values can still add up to a .. path element.
+func JoinTemplate(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(filepath.Clean(dir), path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrOutsideDir
+	}
+	return path, nil
+}
+
+// safeName replaces the characters of v that can't be part of a file name.
+func safeName(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "." || v == ".." {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, v)
+}
@@ -0,0 +1,44 @@
+package dcmdump
+
+import "testing"
+
+func TestExpandTemplate(t *testing.T) {
+	df := &DicomFile{}
+	df.SetElement("00100020", "LO", "ID/1")
+	df.SetElement("00200011", "IS", 3)
+	df.SetElement("00080008", "CS", []string{"ORIGINAL", "PRIMARY"})
+	df.SetElement("0020000D", "UI", "..")
+	df.SetElement("0020000E", "UI", ".")
+	for _, c := range []struct {
+		template, expected string
+	}{
+		{"{PatientID}/{SeriesNumber}-{frame}.{ext}", "ID_1/3-2.png"},
+		{"{StudyDescription:NoDescription}/{00200011}", "NoDescription/3"},
+		{"{ImageType}{SeriesDescription}", "ORIGINAL_PRIMARY"},
+		{"{StudyInstanceUID}/{SeriesInstanceUID}/x", "_/_/x"},
+	} {
+		name, err := df.ExpandTemplate(c.template, map[string]string{"frame": "2", "ext": "png"})
+		if err != nil || name != c.expected {
+			t.Errorf("%s: expected %s, got %s, %v", c.template, c.expected, name, err)
+		}
+	}
+	for _, c := range []struct {
+		name string
+		ok   bool
+	}{
+		{"a/b.dcm", true},
+		{"a/../b.dcm", true},
+		{"../b.dcm", false},
+		{"a/../..", false},
+	} {
+		_, err := JoinTemplate("out", c.name)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+	}
+	for _, template := range []string{"{PatientID", "PatientID}", "{}", "{a{b}}"} {
+		if _, err := df.ExpandTemplate(template, nil); err != ErrTemplate {
+			t.Errorf("%s: expected ErrTemplate, got %v", template, err)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+// valueWidth is the width of the value column, long values are shortened
+// to maxValueLength unless printLong is set.
+const (
+	valueWidth     = 40
+	maxValueLength = 64
+)
+
+// tsNames - dcmtk names of the transfer syntaxes, others use the ts name.
+var tsNames = map[string]string{
+	ts.ImplicitVRLittleEndian:         "Little Endian Implicit",
+	ts.ExplicitVRLittleEndian:         "Little Endian Explicit",
+	ts.ExplicitVRBigEndian:            "Big Endian Explicit",
+	ts.DeflatedExplicitVRLittleEndian: "Deflated Explicit VR Little Endian",
+	ts.JPEGBaseline:                   "JPEG Baseline",
+	ts.RLELossless:                    "RLE Lossless",
+}
+
+// uidNames - dcmtk names printed for known UI values.
+var uidNames = map[string]string{
+	ts.ImplicitVRLittleEndian:         "LittleEndianImplicit",
+	ts.ExplicitVRLittleEndian:         "LittleEndianExplicit",
+	ts.ExplicitVRBigEndian:            "BigEndianExplicit",
+	ts.DeflatedExplicitVRLittleEndian: "DeflatedLittleEndianExplicit",
+	ts.JPEGBaseline:                   "JPEGBaseline",
+	ts.RLELossless:                    "RLELossless",
+	"1.2.840.10008.1.1":               "VerificationSOPClass",
+	"1.2.840.10008.5.1.4.1.1.1":       "ComputedRadiographyImageStorage",
+	"1.2.840.10008.5.1.4.1.1.2":       "CTImageStorage",
+	"1.2.840.10008.5.1.4.1.1.4":       "MRImageStorage",
+	"1.2.840.10008.5.1.4.1.1.7":       "SecondaryCaptureImageStorage",
+	"1.2.840.10008.5.1.4.1.1.88.11":   "BasicTextSRStorage",
+	"1.2.840.10008.5.1.4.1.1.88.22":   "EnhancedSRStorage",
+	"1.2.840.10008.5.1.4.1.1.481.3":   "RTStructureSetStorage",
+}
+
+// textPrinter writes data sets in the dcmdump text format of dcmtk, the
+// text Encoder.
+type textPrinter struct {
+	w         io.Writer
+	printLong bool
+	tags      []string
+}
+
+func newTextEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	return &textPrinter{w: w, printLong: o.Long, tags: o.Tags}, nil
+}
+
+// Encode prints df, or only the elements matching the tags at any level.
+func (p *textPrinter) Encode(df *DicomFile) error {
+	if len(p.tags) == 0 {
+		p.file(df)
+		return nil
+	}
+	var search func(elements []DataElement)
+	search = func(elements []DataElement) {
+		for i := range elements {
+			if elements[i].matches(p.tags) {
+				p.element(&elements[i], 0)
+				continue
+			}
+			for _, item := range elements[i].Items {
+				search(item.Elements)
+			}
+		}
+	}
+	search(df.Elements)
+	return nil
+}
+
+func (p *textPrinter) Close() error {
+	return nil
+}
+
+func tsName(uid string) string {
+	if name, ok := tsNames[uid]; ok {
+		return name
+	}
+	if name, ok := ts.TS[uid]["name"].(string); ok {
+		return name
+	}
+	return uid
+}
+
+// file prints the meta group and the data set of df with their headers.
+func (p *textPrinter) file(df *DicomFile) {
+	meta := []DataElement{}
+	dataset := []DataElement{}
+	for _, de := range df.Elements {
+		if de.TagStr[:4] == "0002" {
+			meta = append(meta, de)
+		} else {
+			dataset = append(dataset, de)
+		}
+	}
+	fmt.Fprintf(p.w, "\n# Dicom-File-Format\n\n")
+	fmt.Fprintf(p.w, "# Dicom-Meta-Information-Header\n")
+	fmt.Fprintf(p.w, "# Used TransferSyntax: %s\n", tsName(ts.ExplicitVRLittleEndian))
+	p.elements(meta, 0)
+	fmt.Fprintf(p.w, "\n# Dicom-Data-Set\n")
+	fmt.Fprintf(p.w, "# Used TransferSyntax: %s\n", tsName(df.TransferSyntax))
+	p.elements(dataset, 0)
+}
+
+func (p *textPrinter) elements(elements []DataElement, level int) {
+	for i := range elements {
+		p.element(&elements[i], level)
+	}
+}
+
+// element prints de and, for sequences, its items.
+func (p *textPrinter) element(de *DataElement, level int) {
+	vr := de.VRStr
+	if vr == "" || vr == "00" {
+		vr = "UN"
+	}
+	switch {
+	case vr == "SQ":
+		p.line(level, de.TagStr, vr, fmt.Sprintf("(Sequence with %s length #=%d)", lengthKind(de.UndefinedLen), len(de.Items)), textLength(de), 1, textName(de))
+		for _, item := range de.Items {
+			p.line(level+1, "FFFEE000", "na", fmt.Sprintf("(Item with %s length #=%d)", lengthKind(item.UndefinedLen), len(item.Elements)), textLength(&DataElement{Len: item.Len, UndefinedLen: item.UndefinedLen}), 1, "Item")
+			p.elements(item.Elements, level+2)
+			if item.UndefinedLen {
+				p.line(level+1, "FFFEE00D", "na", "(ItemDelimitationItem)", "0", 0, "ItemDelimitationItem")
+			}
+		}
+		if de.UndefinedLen {
+			p.line(level, "FFFEE0DD", "na", "(SequenceDelimitationItem)", "0", 0, "SequenceDelimitationItem")
+		}
+	case de.UndefinedLen:
+		p.pixelSequence(de, vr, level)
+	default:
+		value, vm := p.value(de, vr)
+		p.line(level, de.TagStr, vr, value, textLength(de), vm, textName(de))
+	}
+}
+
+// pixelSequence prints encapsulated pixel data with its fragment items.
+func (p *textPrinter) pixelSequence(de *DataElement, vr string, level int) {
+	pi, err := (&DicomFile{Elements: []DataElement{*de}}).PixelDataInfo()
+	var table []uint32
+	var fragments []Fragment
+	if err == nil {
+		table, _ = pi.OffsetTable()
+		fragments, _ = pi.Fragments()
+	}
+	p.line(level, de.TagStr, vr, fmt.Sprintf("(PixelSequence #=%d)", len(fragments)+1), "u/l", 1, textName(de))
+	offsets := make([]byte, 0, 4*len(table))
+	for _, o := range table {
+		offsets = append(offsets, byte(o), byte(o>>8), byte(o>>16), byte(o>>24))
+	}
+	item := func(data []byte) {
+		value := "(no value available)"
+		if len(data) > 0 {
+			value = p.shorten(hexBytes(data))
+		}
+		p.line(level+1, "FFFEE000", "pi", value, fmt.Sprint(len(data)), 1, "Item")
+	}
+	item(offsets)
+	for _, f := range fragments {
+		item(f.Data)
+	}
+	p.line(level, "FFFEE0DD", "na", "(SequenceDelimitationItem)", "0", 0, "SequenceDelimitationItem")
+}
+
+// value returns the printed value of de and its value multiplicity.
+func (p *textPrinter) value(de *DataElement, vr string) (string, int) {
+	if err := de.Load(); err != nil || len(de.Data) == 0 {
+		return "(no value available)", 0
+	}
+	data := de.Data
+	if !p.printLong && len(data) > maxValueLength {
+		// Only the start of long binary values is printed
+		data = data[:maxValueLength]
+	}
+	var s string
+	switch vr {
+	case "OB", "UN":
+		return p.shorten(hexBytes(data)), 1
+	case "OW":
+		order := de.ByteOrder
+		if order == nil {
+			order = binary.LittleEndian
+		}
+		words := []string{}
+		for n := 0; n+2 <= len(data); n += 2 {
+			words = append(words, fmt.Sprintf("%04x", order.Uint16(data[n:])))
+		}
+		return p.shorten(strings.Join(words, "\\")), 1
+	}
+	values := de.Strings()
+	switch vr {
+	case "AT":
+		tags := []string{}
+		for _, v := range values {
+			tags = append(tags, textTag(v))
+		}
+		s = strings.Join(tags, "\\")
+	case "UI":
+		if name, ok := uidNames[values[0]]; ok && len(values) == 1 {
+			s = "=" + name
+			break
+		}
+		s = "[" + strings.Join(values, "\\") + "]"
+	case "US", "SS", "UL", "SL", "SV", "UV", "FL", "FD", "OF", "OD", "OL", "OV":
+		s = strings.Join(values, "\\")
+	default:
+		s = "[" + strings.Join(values, "\\") + "]"
+	}
+	return p.shorten(s), len(values)
+}
+
+func (p *textPrinter) shorten(s string) string {
+	if p.printLong || len(s) <= maxValueLength {
+		return s
+	}
+	return s[:maxValueLength-3] + "..."
+}
+
+// line prints a dcmdump line: tag, VR and value then length, VM and name as
+// a comment.
+func (p *textPrinter) line(level int, tagStr, vr, value, length string, vm int, name string) {
+	if len(value) < valueWidth {
+		value += strings.Repeat(" ", valueWidth-len(value))
+	}
+	fmt.Fprintf(p.w, "%s%s %s %s # %3s, %d %s\n", strings.Repeat("  ", level), textTag(tagStr), vr, value, length, vm, name)
+}
+
+func lengthKind(undefined bool) string {
+	if undefined {
+		return "undefined"
+	}
+	return "explicit"
+}
+
+func textLength(de *DataElement) string {
+	if de.UndefinedLen {
+		return "u/l"
+	}
+	return fmt.Sprint(de.Len)
+}
+
+// textTag formats a GGGGEEEE tag as (gggg,eeee).
+func textTag(tagStr string) string {
+	if len(tagStr) != 8 {
+		return tagStr
+	}
+	return "(" + strings.ToLower(tagStr[:4]) + "," + strings.ToLower(tagStr[4:]) + ")"
+}
+
+func textName(de *DataElement) string {
+	if de.Name != "" {
+		return de.Name
+	}
+	var group, element uint16
+	fmt.Sscanf(de.TagStr, "%04X%04X", &group, &element)
+	switch {
+	case tag.IsPrivateCreator(group, element):
+		return "PrivateCreator"
+	case element == 0:
+		return "GenericGroupLength"
+	}
+	return "Unknown Tag & Data"
+}
+
+func hexBytes(data []byte) string {
+	s := make([]string, len(data))
+	for i, b := range data {
+		s[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(s, "\\")
+}
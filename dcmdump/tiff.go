@@ -0,0 +1,137 @@
+package dcmdump
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// TIFF tags written by writeTIFF
+const (
+	tiffImageWidth                = 256
+	tiffImageLength               = 257
+	tiffBitsPerSample             = 258
+	tiffCompression               = 259
+	tiffPhotometricInterpretation = 262
+	tiffStripOffsets              = 273
+	tiffSamplesPerPixel           = 277
+	tiffRowsPerStrip              = 278
+	tiffStripByteCounts           = 279
+	tiffPlanarConfiguration       = 284
+	tiffSampleFormat              = 339
+)
+
+// tiffEntry - IFD entry with SHORT or LONG values.
+type tiffEntry struct {
+	tag    uint16
+	long   bool
+	values []uint32
+}
+
+// writeTIFF writes img as an uncompressed single strip little endian TIFF.
+// *image.Gray and *image.Gray16 are written as BlackIsZero, other images as 8
+// bit RGB. 16 bit samples are written as two's complement integers when
+// signed is set.
+func writeTIFF(w io.Writer, img image.Image, signed bool) error {
+	b := img.Bounds()
+	var data []byte
+	bits, spp, photometric := 8, 1, uint32(1)
+	switch img := img.(type) {
+	case *image.Gray:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := img.PixOffset(b.Min.X, y)
+			data = append(data, img.Pix[i:i+b.Dx()]...)
+		}
+	case *image.Gray16:
+		bits = 16
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				v := img.Gray16At(x, y).Y
+				data = append(data, byte(v), byte(v>>8))
+			}
+		}
+	default:
+		spp, photometric = 3, 2
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+				data = append(data, byte(r>>8), byte(g>>8), byte(b>>8))
+			}
+		}
+	}
+	format := uint32(1)
+	if signed && bits == 16 {
+		format = 2
+	}
+	repeat := func(v uint32) []uint32 {
+		values := make([]uint32, spp)
+		for i := range values {
+			values[i] = v
+		}
+		return values
+	}
+	entries := []tiffEntry{
+		{tag: tiffImageWidth, long: true, values: []uint32{uint32(b.Dx())}},
+		{tag: tiffImageLength, long: true, values: []uint32{uint32(b.Dy())}},
+		{tag: tiffBitsPerSample, values: repeat(uint32(bits))},
+		{tag: tiffCompression, values: []uint32{1}},
+		{tag: tiffPhotometricInterpretation, values: []uint32{photometric}},
+		{tag: tiffStripOffsets, long: true, values: []uint32{0}},
+		{tag: tiffSamplesPerPixel, values: []uint32{uint32(spp)}},
+		{tag: tiffRowsPerStrip, long: true, values: []uint32{uint32(b.Dy())}},
+		{tag: tiffStripByteCounts, long: true, values: []uint32{uint32(len(data))}},
+		{tag: tiffPlanarConfiguration, values: []uint32{1}},
+		{tag: tiffSampleFormat, values: repeat(format)},
+	}
+	// Header, IFD, values that don't fit in their entry, then the strip
+	ifdSize := 2 + 12*len(entries) + 4
+	extra := []byte{}
+	offset := 8 + ifdSize
+	size := func(e tiffEntry) int {
+		if e.long {
+			return 4 * len(e.values)
+		}
+		return 2 * len(e.values)
+	}
+	for _, e := range entries {
+		if size(e) > 4 {
+			extra = append(extra, make([]byte, size(e))...)
+		}
+	}
+	entries[5].values[0] = uint32(offset + len(extra))
+
+	le := binary.LittleEndian
+	out := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	out = append(out, 0, 0)
+	le.PutUint16(out[8:], uint16(len(entries)))
+	extra = extra[:0]
+	for _, e := range entries {
+		entry := make([]byte, 12)
+		le.PutUint16(entry, e.tag)
+		typ := uint16(3)
+		if e.long {
+			typ = 4
+		}
+		le.PutUint16(entry[2:], typ)
+		le.PutUint32(entry[4:], uint32(len(e.values)))
+		values := make([]byte, size(e))
+		for i, v := range e.values {
+			if e.long {
+				le.PutUint32(values[4*i:], v)
+			} else {
+				le.PutUint16(values[2*i:], uint16(v))
+			}
+		}
+		if len(values) > 4 {
+			le.PutUint32(entry[8:], uint32(offset+len(extra)))
+			extra = append(extra, values...)
+		} else {
+			copy(entry[8:], values)
+		}
+		out = append(out, entry...)
+	}
+	out = append(out, 0, 0, 0, 0)
+	out = append(out, extra...)
+	_, err := w.Write(append(out, data...))
+	return err
+}
@@ -0,0 +1,197 @@
+package dcmdump
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DefaultTreeWidth is the width of the values of a TreePrinter without
+// Width.
+const DefaultTreeWidth = 64
+
+// TreePrinter - Text renderer drawing the data set as a tree: the items of
+// sequences are numbered and their elements indented by depth, each element
+// has its tag, VR, name, length and value, truncated to Width.
+//
+//	(0008,0060) CS Modality [2] CT
+//	(0008,1115) SQ ReferencedSeriesSequence [u/l] 2 items
+//	├── Item 1 [u/l] 1 element
+//	│   └── (0020,000e) UI SeriesInstanceUID [6] 1.2.3
+//	└── Item 2 [14] 1 element
+//	    └── (0020,000e) UI SeriesInstanceUID [6] 1.2.4
+//
+// It is the tree Encoder.
+type TreePrinter struct {
+	// Width of the values, longer ones are cut and end with "...".
+	// DefaultTreeWidth when 0, values are printed whole when negative.
+	Width int
+	// Tags restricts the output to the elements with the given GGGGEEEE tags
+	// or keywords at any level, printed with their nested elements.
+	Tags []string
+
+	w   io.Writer
+	err error
+}
+
+// NewTreePrinter returns a TreePrinter writing to w.
+func NewTreePrinter(w io.Writer) *TreePrinter {
+	return &TreePrinter{w: w}
+}
+
+func newTreeEncoder(w io.Writer, o EncoderOptions) (Encoder, error) {
+	t := NewTreePrinter(w)
+	t.Width, t.Tags = o.Width, o.Tags
+	if o.Long {
+		t.Width = -1
+	}
+	return t, nil
+}
+
+// Encode prints the elements of df.
+func (t *TreePrinter) Encode(df *DicomFile) error {
+	df.mu.RLock()
+	defer df.mu.RUnlock()
+	if len(t.Tags) == 0 {
+		t.elements(df.Elements, "", true)
+		return t.err
+	}
+	var search func(elements []DataElement)
+	search = func(elements []DataElement) {
+		for i := range elements {
+			if elements[i].matches(t.Tags) {
+				t.element(&elements[i], "", "", "")
+				continue
+			}
+			for _, item := range elements[i].Items {
+				search(item.Elements)
+			}
+		}
+	}
+	search(df.Elements)
+	return t.err
+}
+
+// Close ends the output, there is nothing to flush.
+func (t *TreePrinter) Close() error {
+	return nil
+}
+
+// elements prints the elements of a data set, top level ones without
+// branches.
+func (t *TreePrinter) elements(elements []DataElement, prefix string, top bool) {
+	for i := range elements {
+		branch, indent := treeBranches(i == len(elements)-1)
+		if top {
+			branch, indent = "", ""
+		}
+		t.element(&elements[i], prefix, branch, indent)
+	}
+}
+
+// treeBranches returns the branch of a node and the indentation of its
+// children.
+func treeBranches(last bool) (string, string) {
+	if last {
+		return "└── ", "    "
+	}
+	return "├── ", "│   "
+}
+
+// element prints de after prefix and branch, the nested items and fragments
+// after prefix and indent.
+func (t *TreePrinter) element(de *DataElement, prefix, branch, indent string) {
+	vr := de.VRStr
+	if vr == "" || vr == "00" {
+		vr = "UN"
+	}
+	head := fmt.Sprintf("%s %s %s [%s]", textTag(de.TagStr), vr, textName(de), textLength(de))
+	switch {
+	case vr == "SQ":
+		t.line(prefix+branch, head, plural(len(de.Items), "item"))
+		for i, item := range de.Items {
+			b, in := treeBranches(i == len(de.Items)-1)
+			length := textLength(&DataElement{Len: item.Len, UndefinedLen: item.UndefinedLen})
+			t.line(prefix+indent+b, fmt.Sprintf("Item %d [%s]", i+1, length), plural(len(item.Elements), "element"))
+			t.elements(item.Elements, prefix+indent+in, false)
+		}
+	case de.UndefinedLen:
+		pi, err := (&DicomFile{Elements: []DataElement{*de}}).PixelDataInfo()
+		var fragments []Fragment
+		if err == nil {
+			fragments, _ = pi.Fragments()
+		}
+		t.line(prefix+branch, head, plural(len(fragments), "fragment"))
+		for i, f := range fragments {
+			b, _ := treeBranches(i == len(fragments)-1)
+			t.line(prefix+indent+b, fmt.Sprintf("Fragment %d [%d]", i+1, len(f.Data)), t.truncate(hexBytes(t.head(f.Data))))
+		}
+	default:
+		t.line(prefix+branch, head, t.value(de, vr))
+	}
+}
+
+// value returns the printed value of de.
+func (t *TreePrinter) value(de *DataElement, vr string) string {
+	if err := de.Load(); err != nil {
+		return "(" + err.Error() + ")"
+	}
+	if len(de.Data) == 0 {
+		return "(no value)"
+	}
+	switch vr {
+	case "OB", "OW", "UN":
+		return t.truncate(hexBytes(t.head(de.Data)))
+	}
+	e := *de
+	if e.isBinary() {
+		// Only the values that fit are decoded
+		e.Data = t.head(e.Data)
+	}
+	s := strings.Join(e.Strings(), `\`)
+	return t.truncate(strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`).Replace(s))
+}
+
+// head returns the start of data, long enough for the values that fit in
+// the width.
+func (t *TreePrinter) head(data []byte) []byte {
+	if n := t.width() * 8; n > 0 && len(data) > n {
+		return data[:n]
+	}
+	return data
+}
+
+func (t *TreePrinter) width() int {
+	if t.Width == 0 {
+		return DefaultTreeWidth
+	}
+	return t.Width
+}
+
+// truncate cuts s to the width.
+func (t *TreePrinter) truncate(s string) string {
+	width := t.width()
+	r := []rune(s)
+	if width < 0 || len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
+
+func (t *TreePrinter) line(prefix, head, value string) {
+	if t.err != nil {
+		return
+	}
+	_, t.err = fmt.Fprintf(t.w, "%s%s %s\n", prefix, head, value)
+}
+
+// plural returns n with word, pluralized with an s.
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
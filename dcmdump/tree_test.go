@@ -0,0 +1,62 @@
+package dcmdump
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTreePrinter(t *testing.T) {
+	item := func(uid string) Item {
+		de, err := NewDataElement("0020000E", "UI", uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		de.PartOfSQ = true
+		return Item{Len: 14, Elements: []DataElement{de}}
+	}
+	nested, err := NewDataElement("00081115", "SQ", []Item{item("1.2.3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested.PartOfSQ = true
+	df := &DicomFile{}
+	df.SetElement("00080060", "CS", "CT")
+	df.SetElement("00081115", "SQ", []Item{{Elements: []DataElement{nested}}, item("1.2.4")})
+	df.SetElement("00100010", "PN", "DOE^JOHN^ALEXANDER^^")
+	df.Elements[1].UndefinedLen = true
+	df.Elements[1].Items[0].UndefinedLen = true
+
+	var buf bytes.Buffer
+	p := NewTreePrinter(&buf)
+	p.Width = 12
+	if err := p.Encode(df); err != nil {
+		t.Fatal(err)
+	}
+	expected := `(0008,0060) CS Modality [2] CT
+(0008,1115) SQ ReferencedSeriesSequence [u/l] 2 items
+├── Item 1 [u/l] 1 element
+│   └── (0008,1115) SQ ReferencedSeriesSequence [0] 1 item
+│       └── Item 1 [14] 1 element
+│           └── (0020,000e) UI SeriesInstanceUID [6] 1.2.3
+└── Item 2 [14] 1 element
+    └── (0020,000e) UI SeriesInstanceUID [6] 1.2.4
+(0010,0010) PN PatientName [20] DOE^JOHN^...
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	enc, err := NewEncoder("tree", &buf, EncoderOptions{Tags: []string{"SeriesInstanceUID"}, Long: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(df); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[1] != "(0020,000e) UI SeriesInstanceUID [6] 1.2.4" {
+		t.Errorf("wrong search output:\n%s", buf.String())
+	}
+}
@@ -1,5 +1,25 @@
 package ts
 
+import "encoding/binary"
+
+// ImplicitVRLittleEndian = "1.2.840.10008.1.2"
+const ImplicitVRLittleEndian = "1.2.840.10008.1.2"
+
+// ExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+const ExplicitVRLittleEndian = "1.2.840.10008.1.2.1"
+
+// DeflatedExplicitVRLittleEndian = "1.2.840.10008.1.2.1.99"
+const DeflatedExplicitVRLittleEndian = "1.2.840.10008.1.2.1.99"
+
+// ExplicitVRBigEndian = "1.2.840.10008.1.2.2"
+const ExplicitVRBigEndian = "1.2.840.10008.1.2.2"
+
+// JPEGBaseline = "1.2.840.10008.1.2.4.50"
+const JPEGBaseline = "1.2.840.10008.1.2.4.50"
+
+// RLELossless = "1.2.840.10008.1.2.5"
+const RLELossless = "1.2.840.10008.1.2.5"
+
 // TS -
 // http://www.dicomlibrary.com/dicom/transfer-syntax/
 var TS = map[string]map[string]interface{}{
@@ -109,3 +129,16 @@ var TS = map[string]map[string]interface{}{
 		"name": "MPEG-4 AVC/H.264 BD-compatible High Profile / Level 4.1",
 	},
 }
+
+// Explicit reports whether the data set of the transfer syntax uses explicit VR.
+func Explicit(uid string) bool {
+	return uid != ImplicitVRLittleEndian
+}
+
+// ByteOrder returns the byte order of the data set of the transfer syntax.
+func ByteOrder(uid string) binary.ByteOrder {
+	if uid == ExplicitVRBigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
@@ -0,0 +1,22 @@
+package dcmdump
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// ImplementationClassUID identifies this library in the files it writes.
+const ImplementationClassUID = "2.25.131915070186940335101340622264414120726"
+
+// ImplementationVersionName is written along ImplementationClassUID.
+const ImplementationVersionName = "GO-DICOM"
+
+// NewUID returns a random UUID derived UID under the 2.25 root.
+func NewUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	// UUID version 4
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return "2.25." + new(big.Int).SetBytes(b).String()
+}
@@ -0,0 +1,211 @@
+package dcmdump
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// Validation errors, wrapped in a ValidationError
+var (
+	ErrValueTooLong = errors.New("Value exceeds the maximum length of its VR")
+	ErrValueFormat  = errors.New("Value has characters or a format not allowed by its VR")
+	ErrValueRange   = errors.New("Value out of range")
+	ErrValueLength  = errors.New("Length is not a multiple of the VR size")
+	ErrVM           = errors.New("Number of values not allowed by the dictionary VM")
+	ErrTagOrder     = errors.New("Tag is out of order")
+	ErrDuplicateTag = errors.New("Tag is duplicated")
+)
+
+// ValidationError records the element and value that failed validation.
+type ValidationError struct {
+	Tag   string
+	VR    string
+	Value string // Offending value, blank for length and VM errors
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Value != "" {
+		return fmt.Sprintf("(%s) %s %q: %s", e.Tag, e.VR, e.Value, e.Err)
+	}
+	return fmt.Sprintf("(%s) %s: %s", e.Tag, e.VR, e.Err)
+}
+
+// Unwrap returns the underlying cause.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors - All the validation errors of a data set.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	s := make([]string, len(e))
+	for i, v := range e {
+		s[i] = v.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// maxLength - Maximum length in bytes of each value, PS3.5 Table 6.2-1.
+// PN applies to each component group.
+var maxLength = map[string]int{
+	"AE": 16,
+	"AS": 4,
+	"CS": 16,
+	"DA": 8,
+	"DS": 16,
+	"DT": 26,
+	"IS": 12,
+	"LO": 64,
+	"LT": 10240,
+	"PN": 64,
+	"SH": 16,
+	"ST": 1024,
+	"TM": 14,
+	"UI": 64,
+}
+
+// valueFormat - Characters and format allowed in each value.
+var valueFormat = map[string]*regexp.Regexp{
+	"AE": regexp.MustCompile(`^[^\\\x00-\x1f]*$`),
+	"AS": regexp.MustCompile(`^[0-9]{3}[DWMY]$`),
+	"CS": regexp.MustCompile(`^[A-Z0-9 _]*$`),
+	"DA": regexp.MustCompile(`^[0-9]{8}$`),
+	"DS": regexp.MustCompile(`^[+-]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?$`),
+	"DT": regexp.MustCompile(`^[0-9]{4}([0-9]{2}([0-9]{2}([0-9]{2}([0-9]{2}([0-9]{2}(\.[0-9]{1,6})?)?)?)?)?)?([+-][0-9]{4})?$`),
+	"IS": regexp.MustCompile(`^[+-]?[0-9]+$`),
+	"TM": regexp.MustCompile(`^[0-9]{2}([0-9]{2}([0-9]{2}(\.[0-9]{1,6})?)?)?$`),
+	"UI": regexp.MustCompile(`^(0|[1-9][0-9]*)(\.(0|[1-9][0-9]*))*$`),
+}
+
+// valueSize - Size of each value of fixed width binary VRs.
+var valueSize = map[string]int{
+	"AT": 4,
+	"FD": 8,
+	"FL": 4,
+	"OD": 8,
+	"OF": 4,
+	"OL": 4,
+	"OV": 8,
+	"OW": 2,
+	"SL": 4,
+	"SS": 2,
+	"SV": 8,
+	"UL": 4,
+	"US": 2,
+	"UV": 8,
+}
+
+// Validate checks the values of the element against its VR: maximum
+// lengths, allowed characters and formats, IS range and the length of binary
+// values, and its number of values against the dictionary VM.
+// Sequence items are validated as well, the first error is returned.
+func (de *DataElement) Validate() error {
+	errs := ValidationErrors{}
+	de.validate(&errs)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// Validate checks all the elements as described in DataElement.Validate and
+// returns ValidationErrors with every error found.
+// Top level elements are also checked to be in ascending tag order without
+// duplicates.
+func (df *DicomFile) Validate() error {
+	errs := ValidationErrors{}
+	seen := map[string]bool{}
+	last := ""
+	for i := range df.Elements {
+		de := &df.Elements[i]
+		switch {
+		case seen[de.TagStr]:
+			errs = append(errs, &ValidationError{Tag: de.TagStr, VR: de.VRStr, Err: ErrDuplicateTag})
+		case de.TagStr < last:
+			errs = append(errs, &ValidationError{Tag: de.TagStr, VR: de.VRStr, Err: ErrTagOrder})
+		}
+		seen[de.TagStr] = true
+		if de.TagStr > last {
+			last = de.TagStr
+		}
+		de.validate(&errs)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (de *DataElement) validate(errs *ValidationErrors) {
+	fail := func(value string, err error) {
+		*errs = append(*errs, &ValidationError{Tag: de.TagStr, VR: de.VRStr, Value: value, Err: err})
+	}
+	for _, item := range de.Items {
+		for i := range item.Elements {
+			item.Elements[i].validate(errs)
+		}
+	}
+	if size, ok := valueSize[de.VRStr]; ok {
+		if !de.UndefinedLen && len(de.Data)%size != 0 {
+			fail("", ErrValueLength)
+			return
+		}
+	}
+	if _, ok := vri.VR[de.VRStr]; !ok || de.isBinary() || de.VRStr == "SQ" {
+		de.validateVM(fail)
+		return
+	}
+	for _, v := range de.stringValues() {
+		if v == "" {
+			continue
+		}
+		if max, ok := maxLength[de.VRStr]; ok {
+			parts := []string{v}
+			if de.VRStr == "PN" {
+				parts = strings.Split(v, "=")
+			}
+			for _, p := range parts {
+				if len(p) > max {
+					fail(v, ErrValueTooLong)
+				}
+			}
+		}
+		if f, ok := valueFormat[de.VRStr]; ok && !f.MatchString(v) {
+			fail(v, ErrValueFormat)
+			continue
+		}
+		if de.VRStr == "IS" {
+			if _, err := strconv.ParseInt(v, 10, 32); err != nil {
+				fail(v, ErrValueRange)
+			}
+		}
+	}
+	de.validateVM(fail)
+}
+
+// validateVM checks the number of values against the dictionary VM, single
+// valued VRs and empty elements are not checked.
+func (de *DataElement) validateVM(fail func(string, error)) {
+	switch de.VRStr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "UN", "SQ":
+		return
+	}
+	if singleValued.contains(de.VRStr) {
+		return
+	}
+	vm := tag.Tag[de.TagStr]["vm"]
+	if vm == "" || len(de.Data) == 0 {
+		return
+	}
+	if n := len(de.Strings()); !tag.MatchVM(vm, n) {
+		fail("", ErrVM)
+	}
+}
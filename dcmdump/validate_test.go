@@ -0,0 +1,109 @@
+package dcmdump
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestValidate(t *testing.T) {
+	for _, c := range []struct {
+		tag, vr  string
+		value    interface{}
+		expected error
+	}{
+		{"00080060", "CS", "CT", nil},
+		{"00080060", "CS", "ct", ErrValueFormat},
+		{"00080060", "CS", "ABCDEFGHIJKLMNOPQ", ErrValueTooLong},
+		{"00080020", "DA", "20200131", nil},
+		{"00080020", "DA", "2020013A", ErrValueFormat},
+		{"00080030", "TM", "101010.123", nil},
+		{"00080030", "TM", "25:00", ErrValueFormat},
+		{"0008002A", "DT", "20200131101010+0100", nil},
+		{"00080018", "UI", "1.2.840.10008", nil},
+		{"00080018", "UI", "1.02.3", ErrValueFormat},
+		{"00101010", "AS", "045Y", nil},
+		{"00101010", "AS", "45Y", ErrValueFormat},
+		{"00200013", "IS", "-12", nil},
+		{"00200013", "IS", "9999999999", ErrValueRange},
+		{"00281050", "DS", "1.5e3", nil},
+		{"00281050", "DS", "1,5", ErrValueFormat},
+		{"00100010", "PN", "Doe^John", nil},
+		{"00280010", "US", []int{1, 2}, ErrVM},
+		{"00280010", "US", []byte{1, 2, 3}, ErrValueLength},
+		{"00280030", "DS", []string{"0.5", "0.5"}, nil},
+		{"00280030", "DS", "0.5", ErrVM},
+		{"00280010", "US", 512, nil},
+	} {
+		de, err := NewDataElement(c.tag, c.vr, c.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, ok := c.value.([]byte); ok {
+			// Unpadded odd length
+			de.Data = b
+		}
+		err = de.Validate()
+		if !errors.Is(err, c.expected) || (err == nil) != (c.expected == nil) {
+			t.Errorf("%s %s %v: expected %v, got %v", c.tag, c.vr, c.value, c.expected, err)
+		}
+	}
+
+	item := Item{}
+	nested, _ := NewDataElement("00081150", "UI", "1.2.3.")
+	item.Elements = append(item.Elements, nested)
+	df := &DicomFile{}
+	df.SetElement("00080060", "CS", "ct")
+	df.SetElement("00081115", "SQ", []Item{item})
+	err := df.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected 2 validation errors, got %v", err)
+	}
+	if errs[1].Tag != "00081150" {
+		t.Errorf("Expected nested error, got %s", errs[1])
+	}
+}
+
+func TestValidateTagOrder(t *testing.T) {
+	elements := []DataElement{}
+	for _, e := range []struct{ tag, vr, value string }{
+		{"00080060", "CS", "CT"},
+		{"00100010", "PN", "Doe^John"},
+		{"00080020", "DA", "20200131"},
+		{"00100010", "PN", "Doe^Jane"},
+	} {
+		de, err := NewDataElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		elements = append(elements, de)
+	}
+	df := &DicomFile{Elements: elements}
+	errs, ok := df.Validate().(ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Tag != "00080020" || !errors.Is(errs[0], ErrTagOrder) ||
+		errs[1].Tag != "00100010" || !errors.Is(errs[1], ErrDuplicateTag) {
+		t.Fatalf("Wrong order errors: %v", errs)
+	}
+
+	var buf bytes.Buffer
+	err := df.WriteWithOptions(&buf, ts.ExplicitVRLittleEndian, WriteOptions{SortElements: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	p := NewParser(bytes.NewReader(buf.Bytes()[132:]), 132, true, []string{})
+	p.StopAt = ""
+	written := &DicomFile{}
+	written.Elements, err = p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err = written.Validate(); err != nil {
+		t.Errorf("Unexpected validation errors: %v", err)
+	}
+	if v := written.stringValue("00100010"); len(written.Elements) != 5 || v != "Doe^John" {
+		t.Errorf("Wrong sorted elements: %s %v", v, written.Elements)
+	}
+}
@@ -0,0 +1,405 @@
+package dcmdump
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidgamba/go-dicom/dcmdump/charset"
+	"github.com/davidgamba/go-dicom/dcmdump/tag"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// singleValued VRs can't have a value multiplicity above 1, backslash is part
+// of their data.
+var singleValued = stringSlice{"LT", "ST", "UT", "UR"}
+
+// textVRs are decoded with the SpecificCharacterSet.
+var textVRs = stringSlice{"SH", "LO", "ST", "LT", "UC", "UT", "PN"}
+
+// text returns the value decoded to UTF-8.
+// Undecodable bytes are kept as Latin-1.
+func (de *DataElement) text() string {
+	if len(de.CharacterSet) == 0 || !textVRs.contains(de.VRStr) {
+		return string(de.Data)
+	}
+	delimiters := "\\\r\n\t\f"
+	if de.VRStr == "PN" {
+		delimiters = "\\^="
+	}
+	s, _ := charset.Decode(de.Data, de.CharacterSet, delimiters)
+	return s
+}
+
+// trimmedValue returns the value as a string with its padding removed.
+// Leading spaces are only significant for the text VRs.
+func (de *DataElement) trimmedValue() string {
+	s := strings.TrimRight(de.text(), " \x00")
+	switch de.VRStr {
+	case "LT", "ST", "UT", "UR":
+		return s
+	}
+	return strings.TrimLeft(s, " ")
+}
+
+// stringValues splits a string value on the backslash delimiter.
+func (de *DataElement) stringValues() []string {
+	if len(de.Data) == 0 {
+		return []string{}
+	}
+	if singleValued.contains(de.VRStr) {
+		return []string{de.trimmedValue()}
+	}
+	values := strings.Split(strings.TrimRight(de.text(), " \x00"), "\\")
+	for i, v := range values {
+		values[i] = strings.Trim(v, " \x00")
+	}
+	return values
+}
+
+// binaryValues decodes the values of a fixed width binary VR.
+// Integers are returned as int64, floats as float64 and AT as tag strings.
+func (de *DataElement) binaryValues() []interface{} {
+	values := []interface{}{}
+	order := de.order()
+	d := de.Data
+	switch de.VRStr {
+	case "US", "OW":
+		for n := 0; n+2 <= len(d); n += 2 {
+			values = append(values, int64(order.Uint16(d[n:])))
+		}
+	case "SS":
+		for n := 0; n+2 <= len(d); n += 2 {
+			values = append(values, int64(int16(order.Uint16(d[n:]))))
+		}
+	case "UL", "OL":
+		for n := 0; n+4 <= len(d); n += 4 {
+			values = append(values, int64(order.Uint32(d[n:])))
+		}
+	case "SL":
+		for n := 0; n+4 <= len(d); n += 4 {
+			values = append(values, int64(int32(order.Uint32(d[n:]))))
+		}
+	case "SV":
+		for n := 0; n+8 <= len(d); n += 8 {
+			values = append(values, int64(order.Uint64(d[n:])))
+		}
+	case "UV", "OV":
+		for n := 0; n+8 <= len(d); n += 8 {
+			values = append(values, order.Uint64(d[n:]))
+		}
+	case "FL", "OF":
+		for n := 0; n+4 <= len(d); n += 4 {
+			values = append(values, math.Float32frombits(order.Uint32(d[n:])))
+		}
+	case "FD", "OD":
+		for n := 0; n+8 <= len(d); n += 8 {
+			values = append(values, math.Float64frombits(order.Uint64(d[n:])))
+		}
+	case "AT":
+		for n := 0; n+4 <= len(d); n += 4 {
+			values = append(values, formatTag(order.Uint16(d[n:]), order.Uint16(d[n+2:])))
+		}
+	default:
+		for _, b := range d {
+			values = append(values, int64(b))
+		}
+	}
+	return values
+}
+
+// ErrValueType is returned when the VR of an element doesn't hold the
+// requested type of value.
+var ErrValueType = errors.New("Element VR doesn't hold this value type")
+
+// Strings returns the values of the element as strings.
+// String values are split on the backslash delimiter and have their padding
+// removed, binary values are formatted.
+func (de *DataElement) Strings() []string {
+	if !de.isBinary() {
+		return de.stringValues()
+	}
+	values := []string{}
+	for _, v := range de.binaryValues() {
+		switch v := v.(type) {
+		case float32:
+			values = append(values, strconv.FormatFloat(float64(v), 'g', -1, 32))
+		case float64:
+			values = append(values, strconv.FormatFloat(v, 'g', -1, 64))
+		default:
+			values = append(values, fmt.Sprint(v))
+		}
+	}
+	return values
+}
+
+// Tags returns the values of AT elements with their dictionary entry, only
+// Group and Element are set for unknown tags.
+func (de *DataElement) Tags() []tag.Info {
+	tags := []tag.Info{}
+	if de.VRStr != "AT" {
+		return tags
+	}
+	order := de.order()
+	for n := 0; n+4 <= len(de.Data); n += 4 {
+		info, _ := tag.Find(order.Uint16(de.Data[n:]), order.Uint16(de.Data[n+2:]))
+		tags = append(tags, info)
+	}
+	return tags
+}
+
+// ErrValueIndex is returned when requesting a value past the values of an
+// element.
+var ErrValueIndex = errors.New("Value index out of range")
+
+// Values returns the values of the element as Strings does, one for each
+// backslash delimited value. LT, ST, UT and UR hold a single value where
+// backslash is part of the data.
+func (de *DataElement) Values() []string {
+	return de.Strings()
+}
+
+// VM returns the number of values of the element.
+func (de *DataElement) VM() int {
+	return len(de.Strings())
+}
+
+// StringAt returns value i of the element, starting at 0.
+func (de *DataElement) StringAt(i int) (string, error) {
+	values := de.Strings()
+	if i < 0 || i >= len(values) {
+		return "", ErrValueIndex
+	}
+	return values[i], nil
+}
+
+// Ints returns the values of integer VRs (US, SS, UL, SL, SV, UV, IS) and of DS
+// truncated. Values that can't be parsed are returned as 0.
+func (de *DataElement) Ints() []int {
+	values := []int{}
+	if de.isBinary() {
+		for _, v := range de.binaryValues() {
+			switch v := v.(type) {
+			case int64:
+				values = append(values, int(v))
+			case uint64:
+				values = append(values, int(v))
+			case float32:
+				values = append(values, int(v))
+			case float64:
+				values = append(values, int(v))
+			default:
+				values = append(values, 0)
+			}
+		}
+		return values
+	}
+	for _, f := range de.Floats() {
+		values = append(values, int(f))
+	}
+	return values
+}
+
+// Floats returns the values of numeric VRs as float64, FL and OF values are
+// converted from float32.
+// Values that can't be parsed are returned as 0.
+func (de *DataElement) Floats() []float64 {
+	values := []float64{}
+	if de.isBinary() {
+		for _, v := range de.binaryValues() {
+			switch v := v.(type) {
+			case int64:
+				values = append(values, float64(v))
+			case uint64:
+				values = append(values, float64(v))
+			case float32:
+				values = append(values, float64(v))
+			case float64:
+				values = append(values, v)
+			default:
+				values = append(values, 0)
+			}
+		}
+		return values
+	}
+	for _, s := range de.stringValues() {
+		f, _ := strconv.ParseFloat(s, 64)
+		values = append(values, f)
+	}
+	return values
+}
+
+// Time returns the first value of a DA, TM or DT element.
+// Values without a UTC offset are returned in UTC, TM values on year 0.
+func (de *DataElement) Time() (time.Time, error) {
+	values := de.stringValues()
+	if len(values) == 0 || values[0] == "" {
+		return time.Time{}, ErrValueType
+	}
+	switch de.VRStr {
+	case "DA":
+		// ACR-NEMA dates use YYYY.MM.DD
+		return time.Parse("20060102", strings.Replace(values[0], ".", "", -1))
+	case "TM":
+		return parseDateTime(strings.Replace(values[0], ":", "", -1), "150405", "0000")
+	case "DT":
+		return parseDateTime(values[0], "20060102150405", "0101000000")
+	}
+	return time.Time{}, ErrValueType
+}
+
+// parseDateTime parses a DT or TM value, partial values are completed with
+// the given defaults.
+func parseDateTime(s, layout, defaults string) (time.Time, error) {
+	loc := time.UTC
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		var err error
+		loc, err = parseUTCOffset(s[i:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		s = s[:i]
+	}
+	var ns int
+	if i := strings.Index(s, "."); i >= 0 {
+		f := s[i+1:]
+		s = s[:i]
+		if len(f) == 0 || len(f) > 6 {
+			return time.Time{}, fmt.Errorf("Invalid fraction '%s'", f)
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		ns = n * int(math.Pow10(9-len(f)))
+	}
+	first := len(layout) - len(defaults)
+	if len(s) < first || len(s) > len(layout) || len(s)%2 != 0 {
+		return time.Time{}, fmt.Errorf("Invalid date time '%s'", s)
+	}
+	t, err := time.ParseInLocation(layout, s+defaults[len(s)-first:], loc)
+	if err != nil {
+		return t, err
+	}
+	return t.Add(time.Duration(ns)), nil
+}
+
+// parseUTCOffset returns the time zone of a &ZZXX UTC offset.
+func parseUTCOffset(z string) (*time.Location, error) {
+	if len(z) != 5 || (z[0] != '+' && z[0] != '-') {
+		return nil, fmt.Errorf("Invalid UTC offset '%s'", z)
+	}
+	h, err1 := strconv.Atoi(z[1:3])
+	m, err2 := strconv.Atoi(z[3:])
+	if err1 != nil || err2 != nil {
+		return nil, fmt.Errorf("Invalid UTC offset '%s'", z)
+	}
+	offset := h*3600 + m*60
+	if z[0] == '-' {
+		offset = -offset
+	}
+	return time.FixedZone(z, offset), nil
+}
+
+// PersonName - Components of a Person Name (PN) component group.
+type PersonName struct {
+	FamilyName string
+	GivenName  string
+	MiddleName string
+	NamePrefix string
+	NameSuffix string
+}
+
+// PersonName returns the alphabetic components of the first PN value.
+func (de *DataElement) PersonName() (PersonName, error) {
+	if de.VRStr != "PN" {
+		return PersonName{}, ErrValueType
+	}
+	values := de.stringValues()
+	if len(values) == 0 {
+		return PersonName{}, nil
+	}
+	return parsePersonName(strings.SplitN(values[0], "=", 2)[0]), nil
+}
+
+func parsePersonName(group string) PersonName {
+	c := strings.SplitN(group, "^", 5)
+	c = append(c, make([]string, 5-len(c))...)
+	return PersonName{FamilyName: c[0], GivenName: c[1], MiddleName: c[2], NamePrefix: c[3], NameSuffix: c[4]}
+}
+
+// String returns the components with the PN encoding,
+// Family^Given^Middle^Prefix^Suffix without the trailing empty components.
+func (pn PersonName) String() string {
+	s := strings.Join([]string{pn.FamilyName, pn.GivenName, pn.MiddleName, pn.NamePrefix, pn.NameSuffix}, "^")
+	return strings.TrimRight(s, "^")
+}
+
+// Formatted returns the name for display, the prefix, given, middle and
+// family names separated by spaces and the suffix after a comma.
+func (pn PersonName) Formatted() string {
+	parts := []string{}
+	for _, p := range []string{pn.NamePrefix, pn.GivenName, pn.MiddleName, pn.FamilyName} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	s := strings.Join(parts, " ")
+	if pn.NameSuffix != "" {
+		s += ", " + pn.NameSuffix
+	}
+	return s
+}
+
+// IsEmpty reports whether all the components are empty.
+func (pn PersonName) IsEmpty() bool {
+	return pn == PersonName{}
+}
+
+// PersonNameGroups - Component groups of a Person Name (PN) value.
+type PersonNameGroups struct {
+	Alphabetic PersonName
+	// Ideographic holds the name in kanji, hanja or hanzi.
+	Ideographic PersonName
+	// Phonetic holds the name in hiragana, katakana or hangul.
+	Phonetic PersonName
+}
+
+// String returns the groups with the PN encoding, separated by '=' without
+// the trailing empty groups.
+func (g PersonNameGroups) String() string {
+	s := strings.Join([]string{g.Alphabetic.String(), g.Ideographic.String(), g.Phonetic.String()}, "=")
+	return strings.TrimRight(s, "=")
+}
+
+// PersonNames returns the component groups of every PN value, decoded with
+// the SpecificCharacterSet of the element.
+func (de *DataElement) PersonNames() ([]PersonNameGroups, error) {
+	if de.VRStr != "PN" {
+		return nil, ErrValueType
+	}
+	names := []PersonNameGroups{}
+	for _, v := range de.stringValues() {
+		names = append(names, parsePersonNameGroups(v))
+	}
+	return names, nil
+}
+
+func parsePersonNameGroups(v string) PersonNameGroups {
+	g := strings.SplitN(v, "=", 3)
+	g = append(g, make([]string, 3-len(g))...)
+	return PersonNameGroups{
+		Alphabetic:  parsePersonName(g[0]),
+		Ideographic: parsePersonName(g[1]),
+		Phonetic:    parsePersonName(g[2]),
+	}
+}
+
+// isBinary reports whether the element holds fixed width binary values.
+func (de *DataElement) isBinary() bool {
+	_, ok := vri.VR[de.VRStr]["fixed"]
+	return ok && vri.VR[de.VRStr]["fixed"].(bool) && de.VRStr != "AS"
+}
@@ -0,0 +1,225 @@
+package dcmdump
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTypedValues(t *testing.T) {
+	de := DataElement{VRStr: "US", Data: []byte{0x00, 0x02, 0x01, 0x00}}
+	if !reflect.DeepEqual(de.Ints(), []int{512, 1}) {
+		t.Errorf("Wrong US values: %v", de.Ints())
+	}
+	de = DataElement{VRStr: "DS", Data: []byte(" 1.5\\-2e1 ")}
+	if !reflect.DeepEqual(de.Floats(), []float64{1.5, -20}) {
+		t.Errorf("Wrong DS values: %v", de.Floats())
+	}
+	de = DataElement{VRStr: "CS", Data: []byte("ORIGINAL\\PRIMARY ")}
+	if !reflect.DeepEqual(de.Strings(), []string{"ORIGINAL", "PRIMARY"}) {
+		t.Errorf("Wrong CS values: %v", de.Strings())
+	}
+	de = DataElement{VRStr: "PN", Data: []byte("DOE^JOHN^^DR")}
+	pn, err := de.PersonName()
+	if err != nil || pn != (PersonName{FamilyName: "DOE", GivenName: "JOHN", NamePrefix: "DR"}) {
+		t.Errorf("Wrong PN: %v, %v", pn, err)
+	}
+}
+
+func TestValueMultiplicity(t *testing.T) {
+	de := DataElement{VRStr: "CS", Data: []byte("ORIGINAL\\PRIMARY\\AXIAL ")}
+	if v, err := de.StringAt(2); err != nil || v != "AXIAL" || de.VM() != 3 {
+		t.Errorf("Wrong value 2: %s, %v", v, err)
+	}
+	if _, err := de.StringAt(3); err != ErrValueIndex {
+		t.Errorf("Expected ErrValueIndex, got %v", err)
+	}
+	de = DataElement{VRStr: "LT", Data: []byte("C:\\DATA\\NOTE ")}
+	if !reflect.DeepEqual(de.Values(), []string{"C:\\DATA\\NOTE"}) {
+		t.Errorf("Wrong LT values: %q", de.Values())
+	}
+	de = DataElement{VRStr: "DS", Data: []byte{}}
+	if de.VM() != 0 {
+		t.Errorf("Expected no values, got %q", de.Values())
+	}
+}
+
+func TestPersonNames(t *testing.T) {
+	de := DataElement{VRStr: "PN", CharacterSet: []string{"ISO_IR 192"}, Data: []byte("Yamada^Tarou=山田^太郎=やまだ^たろう\\Doe^John^^Dr^Jr")}
+	names, err := de.PersonNames()
+	if err != nil || len(names) != 2 {
+		t.Fatalf("Wrong names: %v, %v", names, err)
+	}
+	expected := PersonNameGroups{
+		Alphabetic:  PersonName{FamilyName: "Yamada", GivenName: "Tarou"},
+		Ideographic: PersonName{FamilyName: "山田", GivenName: "太郎"},
+		Phonetic:    PersonName{FamilyName: "やまだ", GivenName: "たろう"},
+	}
+	if names[0] != expected || names[0].String() != "Yamada^Tarou=山田^太郎=やまだ^たろう" {
+		t.Errorf("Wrong groups: %v", names[0])
+	}
+	if !names[1].Ideographic.IsEmpty() || names[1].String() != "Doe^John^^Dr^Jr" {
+		t.Errorf("Wrong alphabetic only name: %v", names[1])
+	}
+	if s := names[1].Alphabetic.Formatted(); s != "Dr John Doe, Jr" {
+		t.Errorf("Wrong formatted name: %s", s)
+	}
+	de.VRStr = "LO"
+	if _, err = de.PersonNames(); err != ErrValueType {
+		t.Errorf("Expected ErrValueType, got %v", err)
+	}
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		vr, value string
+		expected  time.Time
+	}{
+		{"DA", "20160102", time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"DA", "2016.01.02", time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"TM", "1304", time.Date(0, 1, 1, 13, 4, 0, 0, time.UTC)},
+		{"TM", "13:04:05.25", time.Date(0, 1, 1, 13, 4, 5, 250000000, time.UTC)},
+		{"DT", "2016", time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"DT", "20160102130405.000001-0500", time.Date(2016, 1, 2, 13, 4, 5, 1000, time.FixedZone("", -5*3600))},
+	}
+	for _, test := range tests {
+		de := DataElement{VRStr: test.vr, Data: []byte(test.value)}
+		got, err := de.Time()
+		if err != nil || !got.Equal(test.expected) {
+			t.Errorf("%s '%s': %v, %v", test.vr, test.value, got, err)
+		}
+	}
+	de := DataElement{VRStr: "DT", Data: []byte("201601021")}
+	if _, err := de.Time(); err == nil {
+		t.Errorf("Expected error on odd length DT")
+	}
+}
+
+func TestCharacterSet(t *testing.T) {
+	data := explicitElement(0x0008, 0x0005, "CS", []byte("ISO_IR 100"))
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("Buc\xe9phale^Ren\xe9"))...)
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{"00100010"}).Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	pn, err := elements[0].PersonName()
+	if err != nil || pn.FamilyName != "Bucéphale" || pn.GivenName != "René" {
+		t.Errorf("Wrong person name: %v, %v", pn, err)
+	}
+}
+
+func TestVeryLongValues(t *testing.T) {
+	data := explicitElement(0x0009, 0x1010, "SV", []byte{0xFE, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x03, 0, 0, 0, 0, 0, 0, 0})
+	data = append(data, explicitElement(0x0009, 0x1011, "UV", []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF})...)
+	data = append(data, explicitElement(0x0009, 0x1012, "OV", []byte{0x01, 0, 0, 0, 0, 0, 0, 0})...)
+	elements, err := NewParser(bytes.NewReader(data), 0, true, []string{}).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elements) != 3 {
+		t.Fatalf("expected 3 elements, got %d", len(elements))
+	}
+	for i, c := range []struct {
+		vr         string
+		values     []string
+		stringData string
+	}{
+		{"SV", []string{"-2", "3"}, "-2 3 "},
+		{"UV", []string{"18446744073709551615"}, "18446744073709551615 "},
+		{"OV", []string{"1"}, "1 "},
+	} {
+		de := elements[i]
+		if de.VRStr != c.vr || !reflect.DeepEqual(de.Strings(), c.values) || de.StringData() != c.stringData {
+			t.Errorf("%s: got %s %v %q", c.vr, de.VRStr, de.Strings(), de.StringData())
+		}
+	}
+	if !reflect.DeepEqual(elements[0].Ints(), []int{-2, 3}) {
+		t.Errorf("wrong SV values: %v", elements[0].Ints())
+	}
+
+	de, err := NewDataElement("00091010", "SV", []int{-2, 3})
+	if err != nil || !bytes.Equal(de.Data, elements[0].Data) {
+		t.Errorf("wrong SV encoding: %v %v", de.Data, err)
+	}
+	de, err = NewDataElement("00091011", "UV", uint64(math.MaxUint64))
+	if err != nil || !bytes.Equal(de.Data, elements[1].Data) {
+		t.Errorf("wrong UV encoding: %v %v", de.Data, err)
+	}
+	de, err = NewDataElement("00091010", "SV", []int64{1<<53 + 1, math.MinInt64})
+	if err != nil || !reflect.DeepEqual(de.Strings(), []string{"9007199254740993", "-9223372036854775808"}) {
+		t.Errorf("wrong SV precision: %v %v", de.Strings(), err)
+	}
+	for _, v := range []interface{}{[]int64{-1}, -1, "1"} {
+		if _, err := NewDataElement("00091011", "UV", v); err != ErrValueType {
+			t.Errorf("%v: expected ErrValueType, got %v", v, err)
+		}
+	}
+	if _, err := NewDataElement("00091010", "SV", uint64(math.MaxUint64)); err != ErrValueType {
+		t.Errorf("expected ErrValueType, got %v", err)
+	}
+}
+
+func TestFloatValues(t *testing.T) {
+	fl, err := NewDataElement("00181318", "FL", []float64{0.1, -2.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := NewDataElement("00189087", "FD", []float64{0.1, 1e-300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	of, err := NewDataElement("00091010", "OF", []float64{1.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		de         DataElement
+		values     []string
+		stringData string
+	}{
+		{fl, []string{"0.1", "-2.5"}, "0.1 -2.5 "},
+		{fd, []string{"0.1", "1e-300"}, "0.1 1e-300 "},
+		{of, []string{"1.5"}, "1.5 "},
+	} {
+		if !reflect.DeepEqual(c.de.Strings(), c.values) || c.de.StringData() != c.stringData {
+			t.Errorf("%s: got %v %q", c.de.VRStr, c.de.Strings(), c.de.StringData())
+		}
+	}
+	if v := fl.Floats(); !reflect.DeepEqual(v, []float64{float64(float32(0.1)), -2.5}) {
+		t.Errorf("wrong FL values: %v", v)
+	}
+	df := &DicomFile{Elements: []DataElement{fl}}
+	b, err := df.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"Value":[0.1,-2.5]`)) {
+		t.Errorf("wrong JSON: %s", b)
+	}
+}
+
+func TestAttributeTags(t *testing.T) {
+	de, err := NewDataElement("00209165", "AT", []string{"(0018,1140)", "00191010"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(de.Data, []byte{0x18, 0x00, 0x40, 0x11, 0x19, 0x00, 0x10, 0x10}) {
+		t.Errorf("wrong AT encoding: %v", de.Data)
+	}
+	if !reflect.DeepEqual(de.Strings(), []string{"00181140", "00191010"}) {
+		t.Errorf("wrong AT values: %v", de.Strings())
+	}
+	if de.StringData() != "(0018,1140) (0019,1010) " {
+		t.Errorf("wrong AT string: %q", de.StringData())
+	}
+	tags := de.Tags()
+	if len(tags) != 2 || tags[0].Keyword != "RotationDirection" || tags[1].Group != 0x0019 || tags[1].Element != 0x1010 {
+		t.Errorf("wrong AT tags: %+v", tags)
+	}
+	again, err := NewDataElement("00209165", "AT", tags)
+	if err != nil || !bytes.Equal(again.Data, de.Data) {
+		t.Errorf("wrong AT encoding of tag.Info: %v %v", again.Data, err)
+	}
+}
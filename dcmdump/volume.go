@@ -0,0 +1,173 @@
+package dcmdump
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrVolume is returned for frames that don't share their size, spacing and
+// orientation.
+var ErrVolume = errors.New("Frames don't form a volume")
+
+// ErrSliceGap is returned when slices are missing or overlap in a volume.
+var ErrSliceGap = errors.New("Missing or overlapping slices in volume")
+
+// Volume - Voxels of a series of parallel frames, with the modality
+// transformation applied.
+// Voxels are stored x fastest, then y and z: the value of column x, row y of
+// slice z is at Data[(z*Rows+y)*Columns+x].
+type Volume struct {
+	Columns, Rows, Slices int
+	// Spacing between columns, rows and slices in mm.
+	Spacing [3]float64
+	// Origin is the patient position of the center of the first voxel.
+	Origin [3]float64
+	// Row, Column and Normal are the direction cosines of the x, y and z
+	// axes.
+	Row, Column, Normal [3]float64
+	Data                []float32
+}
+
+// volumeFrame - Frame of a file with its plane.
+type volumeFrame struct {
+	df    *DicomFile
+	frame int
+	plane *Plane
+}
+
+// NewVolume stacks the single frame files of a series sorted along their
+// normal, see SortPlanes. The slices need to be equally spaced within
+// tolerance in mm, ErrSliceGap is returned otherwise.
+func NewVolume(files []*DicomFile, tolerance float64) (*Volume, error) {
+	frames := []volumeFrame{}
+	for _, df := range files {
+		p, err := df.FramePlane(0)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, volumeFrame{df: df, plane: p})
+	}
+	return newVolume(frames, tolerance)
+}
+
+// Volume stacks the frames of an enhanced multi-frame file, see NewVolume.
+func (df *DicomFile) Volume(tolerance float64) (*Volume, error) {
+	planes, err := df.FramePlanes()
+	if err != nil {
+		return nil, err
+	}
+	frames := []volumeFrame{}
+	for i, p := range planes {
+		frames = append(frames, volumeFrame{df: df, frame: i, plane: p})
+	}
+	return newVolume(frames, tolerance)
+}
+
+func newVolume(frames []volumeFrame, tolerance float64) (*Volume, error) {
+	if len(frames) == 0 {
+		return nil, ErrVolume
+	}
+	first := frames[0].plane
+	n := first.Normal()
+	sort.SliceStable(frames, func(i, j int) bool {
+		return dot(frames[i].plane.Position, n) < dot(frames[j].plane.Position, n)
+	})
+	planes := []*Plane{}
+	for _, f := range frames {
+		p := f.plane
+		if p.Rows != first.Rows || p.Columns != first.Columns || p.PixelSpacing != first.PixelSpacing ||
+			dot(p.Row, first.Row) < 1-1e-4 || dot(p.Column, first.Column) < 1-1e-4 {
+			return nil, ErrVolume
+		}
+		planes = append(planes, p)
+	}
+	spacing := CheckSpacing(planes, tolerance)
+	if !spacing.Parallel {
+		return nil, ErrVolume
+	}
+	if len(spacing.Gaps) > 0 || len(spacing.Overlaps) > 0 {
+		return nil, ErrSliceGap
+	}
+	v := &Volume{
+		Columns: first.Columns,
+		Rows:    first.Rows,
+		Slices:  len(frames),
+		Spacing: [3]float64{first.PixelSpacing[1], first.PixelSpacing[0], spacing.Spacing},
+		Origin:  planes[0].Position,
+		Row:     first.Row,
+		Column:  first.Column,
+		Normal:  n,
+	}
+	if len(frames) == 1 {
+		v.Spacing[2] = first.Thickness
+	}
+	v.Data = make([]float32, 0, v.Columns*v.Rows*v.Slices)
+	for _, f := range frames {
+		values, err := f.df.modalityValues(f.frame, v.Columns, v.Rows)
+		if err != nil {
+			return nil, err
+		}
+		v.Data = append(v.Data, values...)
+	}
+	return v, nil
+}
+
+// modalityValues returns the rescaled values of monochrome frame i.
+func (df *DicomFile) modalityValues(i, columns, rows int) ([]float32, error) {
+	pi, err := df.PixelDataInfo()
+	if err != nil {
+		return nil, err
+	}
+	if pi.SamplesPerPixel != 1 || pi.Columns != columns || pi.Rows != rows {
+		return nil, ErrPixelFormat
+	}
+	fa, err := df.FrameAttributes(i)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := pi.Frame(i)
+	if err != nil {
+		return nil, err
+	}
+	img, err := frame.Decode()
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	values := make([]float32, 0, columns*rows)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			values = append(values, float32(storedValue(img.At(x, y), pi)*fa.RescaleSlope+fa.RescaleIntercept))
+		}
+	}
+	return values, nil
+}
+
+// At returns the voxel at column x, row y of slice z, false when out of
+// bounds.
+func (v *Volume) At(x, y, z int) (float32, bool) {
+	if x < 0 || y < 0 || z < 0 || x >= v.Columns || y >= v.Rows || z >= v.Slices {
+		return 0, false
+	}
+	return v.Data[(z*v.Rows+y)*v.Columns+x], true
+}
+
+// Patient returns the patient coordinates of the voxel index x, y, z.
+func (v *Volume) Patient(x, y, z float64) [3]float64 {
+	out := v.Origin
+	for k := range out {
+		out[k] += v.Row[k]*x*v.Spacing[0] + v.Column[k]*y*v.Spacing[1] + v.Normal[k]*z*v.Spacing[2]
+	}
+	return out
+}
+
+// Voxel returns the voxel index of patient coordinates, the nearest voxel is
+// at the rounded index.
+func (v *Volume) Voxel(point [3]float64) (x, y, z float64) {
+	d := [3]float64{point[0] - v.Origin[0], point[1] - v.Origin[1], point[2] - v.Origin[2]}
+	x, y = dot(d, v.Row)/v.Spacing[0], dot(d, v.Column)/v.Spacing[1]
+	if v.Spacing[2] != 0 {
+		z = dot(d, v.Normal) / v.Spacing[2]
+	}
+	return x, y, z
+}
@@ -0,0 +1,57 @@
+package dcmdump
+
+import (
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestVolume(t *testing.T) {
+	slice := func(z float64, value byte) *DicomFile {
+		df := &DicomFile{TransferSyntax: ts.ExplicitVRLittleEndian}
+		df.SetElement("00200032", "DS", []float64{0, 0, z})
+		df.SetElement("00200037", "DS", []float64{1, 0, 0, 0, 1, 0})
+		df.SetElement("00280002", "US", 1)
+		df.SetElement("00280004", "CS", "MONOCHROME2")
+		df.SetElement("00280010", "US", 2)
+		df.SetElement("00280011", "US", 3)
+		df.SetElement("00280030", "DS", []float64{0.5, 0.5})
+		df.SetElement("00280100", "US", 8)
+		df.SetElement("00281052", "DS", -10)
+		df.SetElement("7FE00010", "OB", []byte{value, value, value, value, value, value + 1})
+		return df
+	}
+	v, err := NewVolume([]*DicomFile{slice(5, 30), slice(0, 10), slice(2.5, 20)}, 0.01)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if v.Columns != 3 || v.Rows != 2 || v.Slices != 3 || v.Spacing != [3]float64{0.5, 0.5, 2.5} || len(v.Data) != 18 {
+		t.Fatalf("Wrong volume: %+v", v)
+	}
+	for _, c := range []struct {
+		x, y, z int
+		value   float32
+	}{
+		{0, 0, 0, 0},
+		{2, 1, 1, 11},
+		{1, 1, 2, 20},
+	} {
+		if value, ok := v.At(c.x, c.y, c.z); !ok || value != c.value {
+			t.Errorf("Wrong voxel %d,%d,%d: %v", c.x, c.y, c.z, value)
+		}
+	}
+	if _, ok := v.At(3, 0, 0); ok {
+		t.Errorf("Expected out of bounds voxel")
+	}
+	if p := v.Patient(2, 1, 2); p != [3]float64{1, 0.5, 5} {
+		t.Errorf("Wrong patient coordinates: %v", p)
+	}
+	if x, y, z := v.Voxel([3]float64{1, 0.5, 5}); x != 2 || y != 1 || z != 2 {
+		t.Errorf("Wrong voxel index: %v, %v, %v", x, y, z)
+	}
+
+	_, err = NewVolume([]*DicomFile{slice(0, 10), slice(2.5, 20), slice(7.5, 30), slice(10, 40)}, 0.01)
+	if err != ErrSliceGap {
+		t.Errorf("Expected ErrSliceGap, got %v", err)
+	}
+}
@@ -83,6 +83,11 @@ var VR = map[string]map[string]interface{}{
 		"len":   4, // see Transfer Syntax definition
 		"fixed": true,
 	},
+	"OV": {
+		"name":  "Other 64-bit Very Long",
+		"len":   8, // see Transfer Syntax definition
+		"fixed": true,
+	},
 	"OW": {
 		"name":  "Other Word",
 		"len":   2, // see Transfer Syntax definition
@@ -118,6 +123,11 @@ var VR = map[string]map[string]interface{}{
 		"len":   1024, // maximum
 		"fixed": false,
 	},
+	"SV": {
+		"name":  "Signed 64-bit Very Long",
+		"len":   8,
+		"fixed": true,
+	},
 	"TM": {
 		"name":  "Time",
 		"len":   14, // maximum
@@ -154,9 +164,24 @@ var VR = map[string]map[string]interface{}{
 		"len":   2,
 		"fixed": true,
 	},
+	"UV": {
+		"name":  "Unsigned 64-bit Very Long",
+		"len":   8,
+		"fixed": true,
+	},
 	"UT": {
 		"name":  "Unlimited Text",
 		"len":   2 ^ 32 - 2, // maximum
 		"fixed": false,
 	},
 }
+
+// LongLength reports whether the VR uses a 2 byte reserved field and a 4 byte
+// length in Explicit VR encoding.
+func LongLength(vr string) bool {
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UR", "UT", "UN", "UV":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package dcmdump
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// WalkResult - Result of parsing one file during a walk.
+type WalkResult struct {
+	Path string
+	File *DicomFile
+	Err  error
+}
+
+// WalkStats - Aggregated statistics of a walk.
+type WalkStats struct {
+	Files    int   // DICOM files parsed
+	Skipped  int   // Non DICOM files
+	Filtered int   // DICOM files not matching the filter
+	Errors   int   // Files that failed to parse
+	Bytes    int64 // Size of the DICOM files parsed
+}
+
+// Walker scans a directory tree and parses the DICOM files in it
+// concurrently.
+type Walker struct {
+	Root    string
+	Workers int
+	// Tags kept by ProcessFile, an empty list keeps all elements.
+	Tags []string
+	// Filter, when set, drops the files not matching it from the results.
+	// The elements it uses are parsed even if not in Tags.
+	Filter *Filter
+	// Progress, when set, is called with the statistics of the walk after
+	// each file, from the worker goroutines.
+	Progress func(WalkStats)
+
+	mu    sync.Mutex
+	stats WalkStats
+}
+
+// NewWalker returns a Walker for root with a worker per CPU.
+func NewWalker(root string, tags []string) *Walker {
+	return &Walker{Root: root, Workers: runtime.NumCPU(), Tags: tags}
+}
+
+// Walk starts the walk and streams a result per DICOM file, or per file that
+// couldn't be read. The channel is closed once all files are processed.
+func (w *Walker) Walk() <-chan WalkResult {
+	return w.WalkContext(context.Background())
+}
+
+// WalkContext starts the walk like Walk until ctx is done. The files being
+// parsed then end with the error of ctx, no other file is started and the
+// channel is closed; it still has to be drained.
+func (w *Walker) WalkContext(ctx context.Context) <-chan WalkResult {
+	workers := w.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	w.mu.Lock()
+	w.stats = WalkStats{}
+	w.mu.Unlock()
+	paths := make(chan string, workers)
+	results := make(chan WalkResult, workers)
+	go func() {
+		filepath.Walk(w.Root, func(path string, info os.FileInfo, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				w.add(func(s *WalkStats) { s.Errors++ })
+				results <- WalkResult{Path: path, Err: err}
+				return nil
+			}
+			if info.Mode().IsRegular() {
+				select {
+				case paths <- path:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		close(paths)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				r, ok := w.process(ctx, path)
+				if ok {
+					results <- r
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// Stats returns the statistics of the walk so far.
+func (w *Walker) Stats() WalkStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+func (w *Walker) add(f func(s *WalkStats)) {
+	w.mu.Lock()
+	f(&w.stats)
+	stats := w.stats
+	w.mu.Unlock()
+	if w.Progress != nil {
+		w.Progress(stats)
+	}
+}
+
+// process parses path, ok is false for non DICOM files and files not
+// matching the filter.
+func (w *Walker) process(ctx context.Context, path string) (WalkResult, bool) {
+	r := WalkResult{Path: path}
+	dicm, err := IsDICM(path)
+	if err != nil {
+		r.Err = err
+		w.add(func(s *WalkStats) { s.Errors++ })
+		return r, true
+	}
+	if !dicm {
+		w.add(func(s *WalkStats) { s.Skipped++ })
+		return r, false
+	}
+	tags := w.Tags
+	if w.Filter != nil && len(tags) > 0 {
+		tags = w.Filter.keep(tags)
+	}
+	df, err := ProcessFile(path, WithTags(tags...), WithContext(ctx))
+	if df == nil {
+		df = &DicomFile{Path: path}
+	}
+	r.File, r.Err = df, err
+	if r.Err == nil && w.Filter != nil && !w.Filter.Match(df) {
+		w.add(func(s *WalkStats) { s.Filtered++ })
+		return r, false
+	}
+	var size int64
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+	w.add(func(s *WalkStats) {
+		if r.Err != nil {
+			s.Errors++
+			return
+		}
+		s.Files++
+		s.Bytes += size
+	})
+	return r, true
+}
+
+// IsDICM reports whether the file at path has the DICM prefix after the
+// 128 byte preamble.
+func IsDICM(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	b := make([]byte, 132)
+	_, err = io.ReadFull(f, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return string(b[128:]) == "DICM", nil
+}
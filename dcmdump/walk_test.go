@@ -0,0 +1,93 @@
+package dcmdump
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestWalker(t *testing.T) {
+	dir := t.TempDir()
+	var size int64
+	expected := []string{}
+	for _, name := range []string{"a.dcm", "b.dcm", "sub/c.dcm", "sub/d", "sub/deep/e.dcm"} {
+		df := &DicomFile{}
+		df.SetElement("00020010", "UI", ts.ExplicitVRLittleEndian)
+		df.SetElement("00100020", "LO", name)
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = df.Write(f, ts.ExplicitVRLittleEndian)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, _ := os.Stat(path)
+		size += fi.Size()
+		expected = append(expected, path)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not DICOM"), 0644)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0644)
+	broken := filepath.Join(dir, "sub", "broken.dcm")
+	os.WriteFile(broken, append(make([]byte, 128), "DICM\x02\x00\x10\x00UI\xff\xff"...), 0644)
+	expected = append(expected, broken)
+	sort.Strings(expected)
+
+	w := NewWalker(dir, []string{"00100020"})
+	w.Workers = 3
+	var mu sync.Mutex
+	calls := 0
+	w.Progress = func(WalkStats) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	}
+	paths := []string{}
+	for r := range w.Walk() {
+		paths = append(paths, r.Path)
+		switch {
+		case r.Path == broken:
+			if r.Err == nil {
+				t.Errorf("Expected an error for %s", r.Path)
+			}
+		case r.Err != nil:
+			t.Errorf("%s: unexpected error: %s", r.Path, r.Err)
+		case r.File == nil || len(r.File.Elements) != 1 || r.File.Elements[0].Strings()[0] != filepath.ToSlash(r.Path[len(dir)+1:]):
+			t.Errorf("%s: wrong elements %v", r.Path, r.File)
+		}
+	}
+	sort.Strings(paths)
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, paths)
+	}
+	for i := range paths {
+		if paths[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, paths)
+			break
+		}
+	}
+	stats := w.Stats()
+	if stats != (WalkStats{Files: 5, Skipped: 2, Errors: 1, Bytes: size}) {
+		t.Errorf("Wrong stats %+v", stats)
+	}
+	if calls != 8 {
+		t.Errorf("Expected a progress call per file, got %d", calls)
+	}
+
+	// Roots that can't be read are reported
+	w = NewWalker(filepath.Join(dir, "missing"), nil)
+	results := []WalkResult{}
+	for r := range w.Walk() {
+		results = append(results, r)
+	}
+	if len(results) != 1 || !os.IsNotExist(results[0].Err) || w.Stats().Errors != 1 {
+		t.Errorf("Expected a not exist error, got %+v %+v", results, w.Stats())
+	}
+}
@@ -0,0 +1,260 @@
+package dcmdump
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoWaveform is returned when the file has no WaveformSequence element.
+var ErrNoWaveform = errors.New("No WaveformSequence in file")
+
+// ErrChannelIndex is returned when requesting a channel that is not in the
+// multiplex group.
+var ErrChannelIndex = errors.New("Channel index out of range")
+
+// WaveformChannel - Channel definition of a multiplex group, PS3.3 C.10.9.1.
+type WaveformChannel struct {
+	Number int
+	Label  string
+	Status string
+	// Source is the code meaning of the ChannelSourceSequence.
+	Source string
+	// Sensitivity is the value of one sample unit, in SensitivityUnits.
+	// It is 0 when the channel has no sensitivity.
+	Sensitivity float64
+	// SensitivityUnits is the code value of the
+	// ChannelSensitivityUnitsSequence, for example uV.
+	SensitivityUnits string
+	// CorrectionFactor defaults to 1.
+	CorrectionFactor float64
+	Baseline         float64
+	TimeSkew         float64
+	BitsStored       int
+	FilterLow        float64
+	FilterHigh       float64
+}
+
+// Waveform - Multiplex group of the WaveformSequence (5400,0100).
+type Waveform struct {
+	Label             string
+	Originality       string
+	NumberOfChannels  int
+	NumberOfSamples   int
+	SamplingFrequency float64
+	BitsAllocated     int
+	// SampleInterpretation is SS, US, SB, UB, MB (mu-law) or AB (A-law).
+	SampleInterpretation string
+	Channels             []WaveformChannel
+
+	data *DataElement
+}
+
+// Waveforms returns the multiplex groups of the WaveformSequence.
+func (df *DicomFile) Waveforms() ([]*Waveform, error) {
+	seq, err := df.LookupElement("54000100")
+	if err != nil {
+		return nil, ErrNoWaveform
+	}
+	waveforms := []*Waveform{}
+	for _, item := range seq.Items {
+		w, err := newWaveform(item)
+		if err != nil {
+			return nil, err
+		}
+		waveforms = append(waveforms, w)
+	}
+	return waveforms, nil
+}
+
+func newWaveform(item Item) (*Waveform, error) {
+	w := &Waveform{}
+	for i := range item.Elements {
+		de := &item.Elements[i]
+		err := de.Load()
+		if err != nil {
+			return nil, err
+		}
+		switch de.TagStr {
+		case "003A0004":
+			w.Originality = itemString(de)
+		case "003A0005", "003A0010", "54001004":
+			v, err := de.intValue()
+			if err != nil {
+				return nil, newParseError(de, err)
+			}
+			switch de.TagStr {
+			case "003A0005":
+				w.NumberOfChannels = v
+			case "003A0010":
+				w.NumberOfSamples = v
+			default:
+				w.BitsAllocated = v
+			}
+		case "003A001A":
+			w.SamplingFrequency = itemFloat(de, 0)
+		case "003A0020":
+			w.Label = itemString(de)
+		case "54001006":
+			w.SampleInterpretation = itemString(de)
+		case "003A0200":
+			for _, c := range de.Items {
+				w.Channels = append(w.Channels, newWaveformChannel(c))
+			}
+		case "54001010":
+			w.data = de
+		}
+	}
+	return w, nil
+}
+
+func newWaveformChannel(item Item) WaveformChannel {
+	c := WaveformChannel{CorrectionFactor: 1}
+	for i := range item.Elements {
+		de := &item.Elements[i]
+		switch de.TagStr {
+		case "003A0202":
+			c.Number = int(itemFloat(de, 0))
+		case "003A0203":
+			c.Label = itemString(de)
+		case "003A0205":
+			c.Status = itemString(de)
+		case "003A0208":
+			c.Source = codeElement(de, "00080104")
+		case "003A0210":
+			c.Sensitivity = itemFloat(de, 0)
+		case "003A0211":
+			c.SensitivityUnits = codeElement(de, "00080100")
+		case "003A0212":
+			c.CorrectionFactor = itemFloat(de, 1)
+		case "003A0213":
+			c.Baseline = itemFloat(de, 0)
+		case "003A0214":
+			c.TimeSkew = itemFloat(de, 0)
+		case "003A021A":
+			c.BitsStored = int(itemFloat(de, 0))
+		case "003A0220":
+			c.FilterLow = itemFloat(de, 0)
+		case "003A0221":
+			c.FilterHigh = itemFloat(de, 0)
+		}
+	}
+	return c
+}
+
+// itemString returns the values of de joined with backslashes.
+func itemString(de *DataElement) string {
+	return strings.Join(de.Strings(), "\\")
+}
+
+// itemFloat returns the first numeric value of de or the given default.
+func itemFloat(de *DataElement, def float64) float64 {
+	values := de.Floats()
+	if len(values) == 0 || de.Strings()[0] == "" {
+		return def
+	}
+	return values[0]
+}
+
+// codeElement returns an element of the first item of a code sequence.
+func codeElement(seq *DataElement, tagStr string) string {
+	if len(seq.Items) == 0 {
+		return ""
+	}
+	for i := range seq.Items[0].Elements {
+		if de := &seq.Items[0].Elements[i]; de.TagStr == tagStr {
+			return itemString(de)
+		}
+	}
+	return ""
+}
+
+// RawSamples returns the stored samples of channel i, starting at 0.
+// Mu-law and A-law samples are expanded to 16 bit linear values.
+func (w *Waveform) RawSamples(i int) ([]int, error) {
+	if i < 0 || i >= w.NumberOfChannels {
+		return nil, ErrChannelIndex
+	}
+	if w.data == nil {
+		return nil, fmt.Errorf("No WaveformData in multiplex group '%s'", w.Label)
+	}
+	size := 1
+	switch w.SampleInterpretation {
+	case "SS", "US":
+		size = 2
+	case "SB", "UB", "MB", "AB":
+	default:
+		return nil, fmt.Errorf("Unsupported waveform sample interpretation '%s'", w.SampleInterpretation)
+	}
+	data := w.data.Data
+	order := w.data.order()
+	if w.NumberOfSamples*w.NumberOfChannels*size > len(data) {
+		return nil, fmt.Errorf("WaveformData too short: %d bytes for %d samples of %d channels", len(data), w.NumberOfSamples, w.NumberOfChannels)
+	}
+	samples := make([]int, w.NumberOfSamples)
+	for s := range samples {
+		n := (s*w.NumberOfChannels + i) * size
+		switch w.SampleInterpretation {
+		case "SS":
+			samples[s] = int(int16(order.Uint16(data[n:])))
+		case "US":
+			samples[s] = int(order.Uint16(data[n:]))
+		case "SB":
+			samples[s] = int(int8(data[n]))
+		case "UB":
+			samples[s] = int(data[n])
+		case "MB":
+			samples[s] = muLaw(data[n])
+		case "AB":
+			samples[s] = aLaw(data[n])
+		}
+	}
+	return samples, nil
+}
+
+// Samples returns the samples of channel i, starting at 0, in the channel
+// sensitivity units.
+// Sample values are multiplied by the ChannelSensitivity and its correction
+// factor and offset by the ChannelBaseline, channels without sensitivity
+// return their stored values.
+func (w *Waveform) Samples(i int) ([]float64, error) {
+	raw, err := w.RawSamples(i)
+	if err != nil {
+		return nil, err
+	}
+	scale, baseline := 1.0, 0.0
+	if i < len(w.Channels) && w.Channels[i].Sensitivity != 0 {
+		scale = w.Channels[i].Sensitivity * w.Channels[i].CorrectionFactor
+		baseline = w.Channels[i].Baseline
+	}
+	samples := make([]float64, len(raw))
+	for s, v := range raw {
+		samples[s] = float64(v)*scale + baseline
+	}
+	return samples, nil
+}
+
+// muLaw expands a G.711 mu-law sample.
+func muLaw(b byte) int {
+	u := ^b
+	v := ((int(u&0x0F) << 3) + 0x84) << (u >> 4 & 0x07)
+	v -= 0x84
+	if u&0x80 != 0 {
+		return -v
+	}
+	return v
+}
+
+// aLaw expands a G.711 A-law sample.
+func aLaw(b byte) int {
+	a := b ^ 0x55
+	exponent := a >> 4 & 0x07
+	v := int(a&0x0F)<<4 + 8
+	if exponent > 0 {
+		v = (int(a&0x0F)<<4 + 0x108) << (exponent - 1)
+	}
+	if a&0x80 == 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,87 @@
+package dcmdump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWaveforms(t *testing.T) {
+	item := func(elements ...DataElement) Item {
+		for i := range elements {
+			elements[i].PartOfSQ = true
+		}
+		return Item{Elements: elements}
+	}
+	element := func(tagStr, vr string, value interface{}) DataElement {
+		de, err := NewDataElement(tagStr, vr, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return de
+	}
+	units := element("003A0211", "SQ", []Item{item(element("00080100", "SH", "uV"))})
+	df := &DicomFile{}
+	df.SetElement("54000100", "SQ", []Item{item(
+		element("003A0005", "US", 2),
+		element("003A0010", "UL", 3),
+		element("003A001A", "DS", 500),
+		element("003A0200", "SQ", []Item{
+			item(element("003A0203", "LO", "I"), element("003A0210", "DS", 2), element("003A0213", "DS", -1), units),
+			item(element("003A0203", "LO", "II")),
+		}),
+		element("54001004", "US", 16),
+		element("54001006", "CS", "US"),
+		element("54001010", "OW", []int{1, 10, 2, 20, 3, 30}),
+	)})
+
+	waveforms, err := df.Waveforms()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(waveforms) != 1 {
+		t.Fatalf("Expected 1 waveform, got %d", len(waveforms))
+	}
+	w := waveforms[0]
+	if w.NumberOfChannels != 2 || w.NumberOfSamples != 3 || w.SamplingFrequency != 500 || len(w.Channels) != 2 {
+		t.Errorf("Wrong waveform: %+v", w)
+	}
+	if c := w.Channels[0]; c.Label != "I" || c.SensitivityUnits != "uV" || c.CorrectionFactor != 1 {
+		t.Errorf("Wrong channel: %+v", c)
+	}
+	samples, err := w.Samples(0)
+	if err != nil || !reflect.DeepEqual(samples, []float64{1, 3, 5}) {
+		t.Errorf("Wrong channel 0 samples: %v, %v", samples, err)
+	}
+	samples, err = w.Samples(1)
+	if err != nil || !reflect.DeepEqual(samples, []float64{10, 20, 30}) {
+		t.Errorf("Wrong channel 1 samples: %v, %v", samples, err)
+	}
+	_, err = w.Samples(2)
+	if err != ErrChannelIndex {
+		t.Errorf("Expected ErrChannelIndex, got %v", err)
+	}
+
+	_, err = (&DicomFile{}).Waveforms()
+	if err != ErrNoWaveform {
+		t.Errorf("Expected ErrNoWaveform, got %v", err)
+	}
+}
+
+func TestCompandedSamples(t *testing.T) {
+	for _, c := range []struct {
+		law      func(byte) int
+		b        byte
+		expected int
+	}{
+		{muLaw, 0xFF, 0},
+		{muLaw, 0x80, 32124},
+		{muLaw, 0x00, -32124},
+		{aLaw, 0xD5, 8},
+		{aLaw, 0xAA, 32256},
+		{aLaw, 0x2A, -32256},
+	} {
+		if v := c.law(c.b); v != c.expected {
+			t.Errorf("%02x: expected %d, got %d", c.b, c.expected, v)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package dcmdump
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/davidgamba/go-dicom/dcmdump/dcmwrite"
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// ErrUnsupportedTS is returned when writing or decoding with a transfer syntax
+// that isn't supported.
+var ErrUnsupportedTS = errors.New("Unsupported transfer syntax")
+
+// GroupLength - Handling of the group length (gggg,0000) elements of the
+// data set when writing.
+type GroupLength int
+
+const (
+	// GroupLengthKeep writes the group lengths as read.
+	GroupLengthKeep GroupLength = iota
+	// GroupLengthRecalculate updates the group lengths to the encoded size of
+	// their group.
+	GroupLengthRecalculate
+	// GroupLengthStrip leaves out the group lengths, they are retired outside
+	// of the file meta group.
+	GroupLengthStrip
+)
+
+// WriteOptions - Options of WriteWithOptions.
+type WriteOptions struct {
+	// GroupLength sets how the data set group lengths are written, the file
+	// meta group length is always recalculated.
+	GroupLength GroupLength
+	// SortElements writes the top level elements in ascending tag order,
+	// keeping only the first of duplicated tags.
+	SortElements bool
+	// ValidateNumbers fails the write with a ValidationError when an IS or
+	// DS value doesn't parse, see ParseIS and ParseDS.
+	ValidateNumbers bool
+}
+
+// Write encodes the preamble, the file meta group and all data elements with
+// the given transfer syntax.
+// The file meta group is always written as explicit VR little endian and its
+// TransferSyntaxUID and group length are updated to match.
+// Only elements kept during the parse are written, files should be parsed
+// without a tag list and without StopAt to be saved back whole.
+func (df *DicomFile) Write(w io.Writer, transferSyntax string) error {
+	return df.WriteWithOptions(w, transferSyntax, WriteOptions{})
+}
+
+// WriteWithOptions encodes the file as Write does with the given options.
+func (df *DicomFile) WriteWithOptions(w io.Writer, transferSyntax string, o WriteOptions) error {
+	if o.ValidateNumbers {
+		if err := validateNumbers(df.Elements); err != nil {
+			return err
+		}
+	}
+	err := dcmwrite.WritePreamble(w, df.Preamble)
+	if err != nil {
+		return err
+	}
+
+	meta := []DataElement{}
+	dataset := []DataElement{}
+	hasTS := false
+	for _, de := range df.Elements {
+		switch {
+		case de.TagStr == "00020000":
+		case de.TagStr == "00020010":
+			hasTS = true
+			fallthrough
+		case de.TagStr[:4] == "0002":
+			meta = append(meta, de)
+		default:
+			dataset = append(dataset, de)
+		}
+	}
+	if o.SortElements {
+		meta, dataset = sortElements(meta), sortElements(dataset)
+	}
+	if !hasTS {
+		meta = append(meta, DataElement{
+			TagGroup: []byte{0x02, 0x00},
+			TagElem:  []byte{0x10, 0x00},
+			TagStr:   "00020010",
+			VRStr:    "UI",
+		})
+		sort.SliceStable(meta, func(i, j int) bool { return meta[i].TagStr < meta[j].TagStr })
+	}
+
+	var buf bytes.Buffer
+	enc := dcmwrite.NewEncoder(&buf, true, binary.LittleEndian)
+	for _, de := range meta {
+		if de.TagStr == "00020010" {
+			de.Data = padValue([]byte(transferSyntax), 0x0)
+		}
+		err = writeElement(enc, &de)
+		if err != nil {
+			return err
+		}
+	}
+	length := make([]byte, 4)
+	binary.LittleEndian.PutUint32(length, uint32(buf.Len()))
+	enc = dcmwrite.NewEncoder(w, true, binary.LittleEndian)
+	err = enc.WriteElement(0x0002, 0x0000, "UL", length)
+	if err != nil {
+		return err
+	}
+	_, err = enc.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	dataset, err = groupLengths(dataset, o.GroupLength, ts.Explicit(transferSyntax), ts.ByteOrder(transferSyntax))
+	if err != nil {
+		return err
+	}
+	return writeDataset(w, dataset, transferSyntax)
+}
+
+// WriteDataset encodes the data elements with the given transfer syntax
+// without preamble nor file meta group, as sent over the network.
+func (df *DicomFile) WriteDataset(w io.Writer, transferSyntax string) error {
+	dataset := []DataElement{}
+	for _, de := range df.Elements {
+		if de.TagStr[:4] != "0002" {
+			dataset = append(dataset, de)
+		}
+	}
+	return writeDataset(w, dataset, transferSyntax)
+}
+
+// writeDataset encodes the elements, deflated for the deflated transfer
+// syntax.
+func writeDataset(w io.Writer, dataset []DataElement, transferSyntax string) error {
+	if transferSyntax == ts.DeflatedExplicitVRLittleEndian {
+		fw, err := flate.NewWriter(w, flate.DefaultCompression)
+		if err != nil {
+			return err
+		}
+		err = encodeDataset(fw, dataset, transferSyntax)
+		if err != nil {
+			return err
+		}
+		return fw.Close()
+	}
+	return encodeDataset(w, dataset, transferSyntax)
+}
+
+func encodeDataset(w io.Writer, dataset []DataElement, transferSyntax string) error {
+	enc := dcmwrite.NewEncoder(w, ts.Explicit(transferSyntax), ts.ByteOrder(transferSyntax))
+	for _, de := range dataset {
+		err := writeElement(enc, &de)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortElements returns the elements sorted by tag without duplicates, the
+// first of each tag is kept.
+func sortElements(elements []DataElement) []DataElement {
+	sorted := make([]DataElement, len(elements))
+	copy(sorted, elements)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TagStr < sorted[j].TagStr })
+	out := sorted[:0]
+	for i, de := range sorted {
+		if i == 0 || de.TagStr != sorted[i-1].TagStr {
+			out = append(out, de)
+		}
+	}
+	return out
+}
+
+// groupLengths returns a copy of the elements, and of the items they hold,
+// with the group lengths recalculated or stripped.
+func groupLengths(elements []DataElement, mode GroupLength, explicit bool, order binary.ByteOrder) ([]DataElement, error) {
+	if mode == GroupLengthKeep {
+		return elements, nil
+	}
+	out := make([]DataElement, 0, len(elements))
+	for _, de := range elements {
+		if len(de.Items) > 0 {
+			items := make([]Item, len(de.Items))
+			for i, item := range de.Items {
+				var err error
+				item.Elements, err = groupLengths(item.Elements, mode, explicit, order)
+				if err != nil {
+					return nil, err
+				}
+				items[i] = item
+			}
+			de.Items = items
+		}
+		if isGroupLength(&de) && mode == GroupLengthStrip {
+			continue
+		}
+		out = append(out, de)
+	}
+	if mode != GroupLengthRecalculate {
+		return out, nil
+	}
+	for i := range out {
+		if !isGroupLength(&out[i]) {
+			continue
+		}
+		var buf bytes.Buffer
+		enc := dcmwrite.NewEncoder(&buf, explicit, order)
+		for _, de := range out[i+1:] {
+			if de.TagStr[:4] != out[i].TagStr[:4] {
+				break
+			}
+			err := writeElement(enc, &de)
+			if err != nil {
+				return nil, err
+			}
+		}
+		data := make([]byte, 4)
+		out[i].order().PutUint32(data, uint32(buf.Len()))
+		out[i].VRStr = "UL"
+		out[i].Data = data
+		out[i].Len = 4
+		out[i].Deferred = false
+	}
+	return out, nil
+}
+
+// isGroupLength reports whether de is the group length of a data set group.
+func isGroupLength(de *DataElement) bool {
+	return de.TagStr[4:] == "0000" && de.TagStr[:4] != "0002" && de.TagStr[:4] != "FFFE"
+}
+
+// padding returns the byte padding odd length values of vr to an even length,
+// a space for strings and NUL for UI and binary values.
+func padding(vr string) byte {
+	switch vr {
+	case "AE", "AS", "CS", "DA", "DS", "DT", "IS", "LO", "LT", "PN", "SH", "ST", "TM", "UC", "UR", "UT":
+		return ' '
+	}
+	return 0x0
+}
+
+// padValue returns b padded to an even length, odd values are copied so the
+// padding isn't written to the array of b.
+func padValue(b []byte, pad byte) []byte {
+	if len(b)%2 != 0 {
+		padded := make([]byte, len(b)+1)
+		copy(padded, b)
+		padded[len(b)] = pad
+		return padded
+	}
+	return b
+}
+
+// writeElement encodes de with the encoder's transfer syntax.
+func writeElement(enc *dcmwrite.Encoder, de *DataElement) error {
+	group := de.order().Uint16(de.TagGroup)
+	elem := de.order().Uint16(de.TagElem)
+	vr := de.VRStr
+	if _, ok := vri.VR[vr]; !ok {
+		vr = "UN"
+	}
+	if vr == "SQ" {
+		return writeSequence(enc, de, group, elem)
+	}
+	err := de.Load()
+	if err != nil {
+		return err
+	}
+	if de.UndefinedLen {
+		// Encapsulated pixel data, fragments are kept as read.
+		return writeUndefined(enc, group, elem, vr, de.Data, true, 0xE0DD)
+	}
+	data := de.Data
+	if enc.Order != de.order() {
+		data = dcmwrite.Swap(data, dcmwrite.UnitSize(vr))
+	}
+	// Odd length values read from non conformant files
+	data = padValue(data, padding(vr))
+	return enc.WriteElement(group, elem, vr, data)
+}
+
+// writeSequence encodes the items of a sequence, undefined lengths are kept.
+func writeSequence(enc *dcmwrite.Encoder, de *DataElement, group, elem uint16) error {
+	var seq bytes.Buffer
+	seqEnc := dcmwrite.NewEncoder(&seq, enc.Explicit, enc.Order)
+	for _, item := range de.Items {
+		var content bytes.Buffer
+		itemEnc := dcmwrite.NewEncoder(&content, enc.Explicit, enc.Order)
+		for _, e := range item.Elements {
+			err := writeElement(itemEnc, &e)
+			if err != nil {
+				return err
+			}
+		}
+		err := writeUndefined(seqEnc, 0xFFFE, 0xE000, "", content.Bytes(), item.UndefinedLen, 0xE00D)
+		if err != nil {
+			return err
+		}
+	}
+	return writeUndefined(enc, group, elem, "SQ", seq.Bytes(), de.UndefinedLen, 0xE0DD)
+}
+
+// writeUndefined writes an element with a defined length or, when undefined
+// is set, with undefined length followed by the given delimitation item.
+func writeUndefined(enc *dcmwrite.Encoder, group, elem uint16, vr string, value []byte, undefined bool, delimiter uint16) error {
+	if !undefined {
+		return enc.WriteElement(group, elem, vr, value)
+	}
+	err := enc.WriteHeader(group, elem, vr, dcmwrite.UndefinedLength)
+	if err != nil {
+		return err
+	}
+	_, err = enc.Write(value)
+	if err != nil {
+		return err
+	}
+	return enc.WriteHeader(0xFFFE, delimiter, "", 0)
+}
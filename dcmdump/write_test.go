@@ -0,0 +1,183 @@
+package dcmdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func sampleFile() []byte {
+	data := make([]byte, 128)
+	data = append(data, "DICM"...)
+	data = append(data, explicitElement(0x0002, 0x0000, "UL", []byte{28, 0, 0, 0})...)
+	data = append(data, explicitElement(0x0002, 0x0010, "UI", []byte(ts.ExplicitVRLittleEndian+"\x00"))...)
+	item := explicitElement(0x0008, 0x1150, "UI", []byte("1.2.3\x00"))
+	seq := append([]byte{0xfe, 0xff, 0x00, 0xe0, byte(len(item)), 0, 0, 0}, item...)
+	data = append(data, explicitElement(0x0008, 0x1115, "SQ", seq)...)
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))...)
+	data = append(data, explicitElement(0x0028, 0x0010, "US", []byte{0x00, 0x02})...)
+	data = append(data, explicitElement(0x7FE0, 0x0010, "OW", []byte{1, 2, 3, 4})...)
+	return data
+}
+
+func parseSample(t *testing.T, data []byte, explicit bool) *DicomFile {
+	p := NewParser(bytes.NewReader(data[132:]), 132, explicit, []string{})
+	p.StopAt = ""
+	elements, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	df := &DicomFile{Elements: elements}
+	copy(df.Preamble[:], data)
+	return df
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	data := sampleFile()
+	df := parseSample(t, data, true)
+	var buf bytes.Buffer
+	err := df.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), data) {
+		t.Errorf("Round trip mismatch:\n%x\n%x", buf.Bytes(), data)
+	}
+}
+
+func TestWriteBigEndian(t *testing.T) {
+	df := parseSample(t, sampleFile(), true)
+	var buf bytes.Buffer
+	err := df.Write(&buf, ts.ExplicitVRBigEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := buf.Bytes()
+	// Meta group stays little endian
+	if !bytes.Contains(out, []byte(ts.ExplicitVRBigEndian)) {
+		t.Errorf("Missing transfer syntax")
+	}
+	us := []byte{0x00, 0x28, 0x00, 0x10, 'U', 'S', 0x00, 0x02, 0x02, 0x00}
+	if !bytes.Contains(out, us) {
+		t.Errorf("Missing big endian US element: %x", out)
+	}
+	ow := []byte{0x7F, 0xE0, 0x00, 0x10, 'O', 'W', 0, 0, 0, 0, 0, 4, 2, 1, 4, 3}
+	if !bytes.Contains(out, ow) {
+		t.Errorf("Missing big endian OW element: %x", out)
+	}
+
+	// Transfer syntax is detected from the meta group
+	p := NewParser(bytes.NewReader(out[132:]), 132, true, []string{})
+	p.StopAt = ""
+	elements, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if p.TransferSyntax != ts.ExplicitVRBigEndian || p.ByteOrder != binary.BigEndian {
+		t.Errorf("Transfer syntax not detected: %s", p.TransferSyntax)
+	}
+	if len(elements) != 6 || elements[4].TagStr != "00280010" || elements[4].StringData() != "512 " {
+		t.Fatalf("Wrong big endian parse: %v", elements)
+	}
+	buf.Reset()
+	be := &DicomFile{Elements: elements}
+	err = be.Write(&buf, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(buf.Bytes(), sampleFile()) {
+		t.Errorf("Big endian round trip mismatch:\n%x", buf.Bytes())
+	}
+}
+
+func TestWriteGroupLengths(t *testing.T) {
+	data := sampleFile()[:172]
+	data = append(data, explicitElement(0x0008, 0x0000, "UL", []byte{99, 0, 0, 0})...)
+	data = append(data, explicitElement(0x0008, 0x0060, "CS", []byte("CT"))...)
+	data = append(data, explicitElement(0x0010, 0x0010, "PN", []byte("DOE^JOHN"))...)
+	df := parseSample(t, data, true)
+	for _, c := range []struct {
+		mode     GroupLength
+		expected []byte
+	}{
+		{GroupLengthKeep, []byte{99, 0, 0, 0}},
+		{GroupLengthRecalculate, []byte{10, 0, 0, 0}},
+		{GroupLengthStrip, nil},
+	} {
+		var buf bytes.Buffer
+		err := df.WriteWithOptions(&buf, ts.ExplicitVRLittleEndian, WriteOptions{GroupLength: c.mode})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		gl := []byte{0x08, 0x00, 0x00, 0x00, 'U', 'L', 4, 0}
+		if c.expected == nil {
+			if bytes.Contains(buf.Bytes(), gl) {
+				t.Errorf("Group length not stripped: %x", buf.Bytes())
+			}
+		} else if !bytes.Contains(buf.Bytes(), append(gl, c.expected...)) {
+			t.Errorf("Mode %d: wrong group length: %x", c.mode, buf.Bytes())
+		}
+		// The file meta group length is always written
+		if !bytes.Equal(buf.Bytes()[132:144], data[132:144]) {
+			t.Errorf("Mode %d: wrong meta group length: %x", c.mode, buf.Bytes()[132:144])
+		}
+	}
+	if !bytes.Contains(df.Elements[2].Data, []byte{99}) {
+		t.Errorf("Elements modified: %v", df.Elements[2])
+	}
+}
+
+func TestWriteImplicit(t *testing.T) {
+	df := parseSample(t, sampleFile(), true)
+	var buf bytes.Buffer
+	err := df.Write(&buf, ts.ImplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	out := buf.Bytes()
+	pn := []byte{0x10, 0x00, 0x10, 0x00, 8, 0, 0, 0, 'D', 'O', 'E'}
+	if !bytes.Contains(out, pn) {
+		t.Errorf("Missing implicit PN element: %x", out)
+	}
+	p := NewParser(bytes.NewReader(out[132:]), 132, true, []string{"00100010"})
+	elements, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if p.Explicit || len(elements) != 1 || elements[0].StringData() != "DOE^JOHN" {
+		t.Errorf("Implicit transfer syntax not detected: %v", elements)
+	}
+	item := []byte{0x08, 0x00, 0x50, 0x11, 6, 0, 0, 0, '1', '.', '2'}
+	if !bytes.Contains(out, item) {
+		t.Errorf("Sequence item not re-encoded: %x", out)
+	}
+}
+
+func TestWriteDeflated(t *testing.T) {
+	df := parseSample(t, sampleFile(), true)
+	var buf bytes.Buffer
+	err := df.Write(&buf, ts.DeflatedExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var explicit bytes.Buffer
+	err = df.Write(&explicit, ts.ExplicitVRLittleEndian)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if bytes.HasSuffix(buf.Bytes(), explicit.Bytes()[len(explicit.Bytes())-20:]) {
+		t.Errorf("Data set not deflated: %x", buf.Bytes())
+	}
+	out := parseSample(t, buf.Bytes(), true)
+	if len(out.Elements) != len(df.Elements) {
+		t.Fatalf("Expected %d elements, got %d", len(df.Elements), len(out.Elements))
+	}
+	for i, de := range out.Elements[2:] {
+		if de.TagStr != df.Elements[i+2].TagStr || !bytes.Equal(de.Data, df.Elements[i+2].Data) {
+			t.Errorf("Element %s not inflated: %v", de.TagStr, de)
+		}
+	}
+}
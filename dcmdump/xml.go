@@ -0,0 +1,156 @@
+package dcmdump
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	vri "github.com/davidgamba/go-dicom/dcmdump/vr"
+)
+
+// xmlNativeDicomModel - Native DICOM Model
+// http://dicom.nema.org/medical/dicom/current/output/chtml/part19/chapter_A.html
+type xmlNativeDicomModel struct {
+	XMLName    xml.Name       `xml:"NativeDicomModel"`
+	Space      string         `xml:"xml:space,attr"`
+	Attributes []xmlAttribute `xml:"DicomAttribute"`
+}
+
+// xmlAttribute - Native DICOM Model DicomAttribute
+type xmlAttribute struct {
+	Tag          string          `xml:"tag,attr"`
+	VR           string          `xml:"vr,attr"`
+	Keyword      string          `xml:"keyword,attr,omitempty"`
+	Values       []xmlValue      `xml:"Value"`
+	PersonNames  []xmlPersonName `xml:"PersonName"`
+	Items        []xmlItem       `xml:"Item"`
+	BulkData     *xmlBulkData    `xml:"BulkData"`
+	InlineBinary string          `xml:"InlineBinary,omitempty"`
+}
+
+type xmlValue struct {
+	Number int    `xml:"number,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type xmlItem struct {
+	Number     int            `xml:"number,attr"`
+	Attributes []xmlAttribute `xml:"DicomAttribute"`
+}
+
+type xmlPersonName struct {
+	Number      int         `xml:"number,attr"`
+	Alphabetic  *xmlPNGroup `xml:"Alphabetic"`
+	Ideographic *xmlPNGroup `xml:"Ideographic"`
+	Phonetic    *xmlPNGroup `xml:"Phonetic"`
+}
+
+type xmlPNGroup struct {
+	FamilyName string `xml:",omitempty"`
+	GivenName  string `xml:",omitempty"`
+	MiddleName string `xml:",omitempty"`
+	NamePrefix string `xml:",omitempty"`
+	NameSuffix string `xml:",omitempty"`
+}
+
+type xmlBulkData struct {
+	URI string `xml:"uri,attr"`
+}
+
+// MarshalXML encodes the data set in the Native DICOM Model (PS3.19 Annex A).
+// Binary values are inlined, the file meta group and group lengths are left
+// out like in the JSON output.
+func (df *DicomFile) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(xmlDataset(df.Elements, nil))
+}
+
+// XML returns the Native DICOM Model document of the data set, bulkData is
+// called for each binary element to get its BulkData uri.
+func (df *DicomFile) XML(bulkData BulkDataFunc) ([]byte, error) {
+	b, err := xml.MarshalIndent(xmlDataset(df.Elements, bulkData), "", "  ")
+	if err != nil {
+		return b, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+func xmlDataset(elements []DataElement, bulkData BulkDataFunc) xmlNativeDicomModel {
+	return xmlNativeDicomModel{Space: "preserve", Attributes: xmlAttributes(elements, bulkData)}
+}
+
+func xmlAttributes(elements []DataElement, bulkData BulkDataFunc) []xmlAttribute {
+	attributes := []xmlAttribute{}
+	for i := range elements {
+		de := &elements[i]
+		if de.TagStr[:4] == "0002" || de.TagStr[4:] == "0000" {
+			continue
+		}
+		attributes = append(attributes, xmlElement(de, bulkData))
+	}
+	return attributes
+}
+
+func xmlElement(de *DataElement, bulkData BulkDataFunc) xmlAttribute {
+	vr := de.VRStr
+	if _, ok := vri.VR[vr]; !ok {
+		vr = "UN"
+	}
+	a := xmlAttribute{Tag: de.TagStr, VR: vr, Keyword: de.Name}
+	switch vr {
+	case "SQ":
+		for i, item := range de.Items {
+			a.Items = append(a.Items, xmlItem{Number: i + 1, Attributes: xmlAttributes(item.Elements, bulkData)})
+		}
+	case "OB", "OD", "OF", "OL", "OV", "OW", "UN":
+		if len(de.Data) == 0 {
+			break
+		}
+		if bulkData != nil {
+			if uri := bulkData(de); uri != "" {
+				a.BulkData = &xmlBulkData{URI: uri}
+				break
+			}
+		}
+		a.InlineBinary = base64.StdEncoding.EncodeToString(de.Data)
+	case "PN":
+		for i, v := range de.stringValues() {
+			if v == "" {
+				continue
+			}
+			pn := xmlPersonName{Number: i + 1}
+			groups := strings.SplitN(v, "=", 3)
+			for j, g := range []**xmlPNGroup{&pn.Alphabetic, &pn.Ideographic, &pn.Phonetic} {
+				if j < len(groups) && groups[j] != "" {
+					*g = xmlPN(groups[j])
+				}
+			}
+			a.PersonNames = append(a.PersonNames, pn)
+		}
+	case "US", "SS", "UL", "SL", "SV", "UV", "FL", "FD", "AT":
+		for i, v := range de.binaryValues() {
+			s := fmt.Sprint(v)
+			switch f := v.(type) {
+			case float32:
+				s = strconv.FormatFloat(float64(f), 'g', -1, 32)
+			case float64:
+				s = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			a.Values = append(a.Values, xmlValue{Number: i + 1, Value: s})
+		}
+	default:
+		for i, v := range de.stringValues() {
+			if v == "" {
+				continue
+			}
+			a.Values = append(a.Values, xmlValue{Number: i + 1, Value: v})
+		}
+	}
+	return a
+}
+
+func xmlPN(group string) *xmlPNGroup {
+	pn := xmlPNGroup(parsePersonName(group))
+	return &pn
+}
@@ -0,0 +1,108 @@
+package dcmdump
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/davidgamba/go-dicom/dcmdump/ts"
+)
+
+func TestXML(t *testing.T) {
+	first, _ := NewDataElement("00081150", "UI", "1.2.3")
+	second, _ := NewDataElement("00081150", "UI", "1.2.4")
+	df := &DicomFile{}
+	for _, e := range []struct {
+		tag, vr string
+		value   interface{}
+	}{
+		{"00020010", "UI", ts.ExplicitVRLittleEndian},
+		{"00080000", "UL", 100},
+		{"00080060", "CS", "MR"},
+		{"00081115", "SQ", []Item{{Elements: []DataElement{first}}, {Elements: []DataElement{second}}}},
+		{"00100010", "PN", []string{"Yamada^Tarou=山田^太郎=やまだ^たろう", "", "Doe^John^^Dr"}},
+		{"00280010", "US", []int{512, 256}},
+		{"00291010", "OB", []byte{1, 2, 3}},
+		{"7FE00010", "OW", []byte{1, 2, 3, 4}},
+	} {
+		err := df.SetElement(e.tag, e.vr, e.value)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", e.tag, err)
+		}
+	}
+	b, err := df.XML(func(de *DataElement) string {
+		if de.TagStr == "7FE00010" {
+			return "file.dcm?offset=0&length=4"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// The file meta group and group lengths are left out, empty person
+	// names keep their number
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<NativeDicomModel xml:space="preserve">
+  <DicomAttribute tag="00080060" vr="CS" keyword="Modality">
+    <Value number="1">MR</Value>
+  </DicomAttribute>
+  <DicomAttribute tag="00081115" vr="SQ" keyword="ReferencedSeriesSequence">
+    <Item number="1">
+      <DicomAttribute tag="00081150" vr="UI" keyword="ReferencedSOPClassUID">
+        <Value number="1">1.2.3</Value>
+      </DicomAttribute>
+    </Item>
+    <Item number="2">
+      <DicomAttribute tag="00081150" vr="UI" keyword="ReferencedSOPClassUID">
+        <Value number="1">1.2.4</Value>
+      </DicomAttribute>
+    </Item>
+  </DicomAttribute>
+  <DicomAttribute tag="00100010" vr="PN" keyword="PatientName">
+    <PersonName number="1">
+      <Alphabetic>
+        <FamilyName>Yamada</FamilyName>
+        <GivenName>Tarou</GivenName>
+      </Alphabetic>
+      <Ideographic>
+        <FamilyName>山田</FamilyName>
+        <GivenName>太郎</GivenName>
+      </Ideographic>
+      <Phonetic>
+        <FamilyName>やまだ</FamilyName>
+        <GivenName>たろう</GivenName>
+      </Phonetic>
+    </PersonName>
+    <PersonName number="3">
+      <Alphabetic>
+        <FamilyName>Doe</FamilyName>
+        <GivenName>John</GivenName>
+        <NamePrefix>Dr</NamePrefix>
+      </Alphabetic>
+    </PersonName>
+  </DicomAttribute>
+  <DicomAttribute tag="00280010" vr="US" keyword="Rows">
+    <Value number="1">512</Value>
+    <Value number="2">256</Value>
+  </DicomAttribute>
+  <DicomAttribute tag="00291010" vr="OB">
+    <InlineBinary>AQIDAA==</InlineBinary>
+  </DicomAttribute>
+  <DicomAttribute tag="7FE00010" vr="OW" keyword="PixelData">
+    <BulkData uri="file.dcm?offset=0&amp;length=4"></BulkData>
+  </DicomAttribute>
+</NativeDicomModel>`
+	if string(b) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b)
+	}
+
+	// MarshalXML inlines the binary values
+	b, err = xml.Marshal(df)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(string(b), `<NativeDicomModel xml:space="preserve"><DicomAttribute tag="00080060"`) ||
+		!strings.HasSuffix(string(b), `<InlineBinary>AQIDBA==</InlineBinary></DicomAttribute></NativeDicomModel>`) {
+		t.Errorf("Wrong document %s", b)
+	}
+}
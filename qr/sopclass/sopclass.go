@@ -36,3 +36,15 @@ const StudyRootQRIMMove = "1.2.840.10008.5.1.4.1.2.2.2"
 // StudyRootQRIMGet Study Root Query/Retrieve Information Model – GET
 // 1.2.840.10008.5.1.4.1.2.2.3
 const StudyRootQRIMGet = "1.2.840.10008.5.1.4.1.2.2.3"
+
+// ModalityWorklistIMFind Modality Worklist Information Model – FIND
+// 1.2.840.10008.5.1.4.31
+const ModalityWorklistIMFind = "1.2.840.10008.5.1.4.31"
+
+// ModalityPerformedProcedureStep Modality Performed Procedure Step SOP Class
+// 1.2.840.10008.3.1.2.3.3
+const ModalityPerformedProcedureStep = "1.2.840.10008.3.1.2.3.3"
+
+// StorageCommitmentPushModel Storage Commitment Push Model SOP Class
+// 1.2.840.10008.1.20.1
+const StorageCommitmentPushModel = "1.2.840.10008.1.20.1"